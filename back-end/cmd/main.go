@@ -78,6 +78,9 @@ func main() {
 		if err != nil {
 			log.Fatalf("❌ Erro ao inicializar templates: %v", err)
 		}
+
+	// Converter coordenadas em texto livre de bancos antigos
+	app.MigrarCoordenadas()
 		
 	// Configurar servidor HTTP
 	port := os.Getenv("PORT")
@@ -62,7 +62,10 @@ func createTables(db *sql.DB) error {
 			hectares REAL,
 			municipio TEXT,
 			estado TEXT,
-			coordenadas TEXT,
+			sede_latitude DOUBLE,
+			sede_longitude DOUBLE,
+			limite TEXT,
+			area_limite DOUBLE,
 			FOREIGN KEY (cliente_id) REFERENCES clientes(id)
 		)`,
 		
@@ -93,8 +96,769 @@ func createTables(db *sql.DB) error {
 			recomendacoes TEXT,
 			FOREIGN KEY (propriedade_id) REFERENCES propriedades(id)
 		)`,
+
+		// Rebanho
+		`CREATE SEQUENCE IF NOT EXISTS animais_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS animais (
+			id INTEGER PRIMARY KEY DEFAULT nextval('animais_id_seq'),
+			propriedade_id INTEGER NOT NULL,
+			identificacao TEXT NOT NULL,
+			nome TEXT,
+			especie TEXT DEFAULT 'Bovino',
+			raca TEXT,
+			sexo TEXT,
+			categoria TEXT,
+			lote TEXT,
+			data_nascimento DATE,
+			ativo BOOLEAN DEFAULT true,
+			FOREIGN KEY (propriedade_id) REFERENCES propriedades(id)
+		)`,
+
+		// Manejo reprodutivo
+		`CREATE SEQUENCE IF NOT EXISTS estacoes_monta_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS estacoes_monta (
+			id INTEGER PRIMARY KEY DEFAULT nextval('estacoes_monta_id_seq'),
+			propriedade_id INTEGER NOT NULL,
+			nome TEXT NOT NULL,
+			data_inicio DATE NOT NULL,
+			data_fim DATE NOT NULL,
+			FOREIGN KEY (propriedade_id) REFERENCES propriedades(id)
+		)`,
+
+		`CREATE SEQUENCE IF NOT EXISTS protocolos_iatf_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS protocolos_iatf (
+			id INTEGER PRIMARY KEY DEFAULT nextval('protocolos_iatf_id_seq'),
+			nome TEXT NOT NULL,
+			descricao TEXT
+		)`,
+
+		`CREATE SEQUENCE IF NOT EXISTS protocolo_etapas_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS protocolo_etapas (
+			id INTEGER PRIMARY KEY DEFAULT nextval('protocolo_etapas_id_seq'),
+			protocolo_id INTEGER NOT NULL,
+			dia INTEGER NOT NULL,
+			tarefa TEXT NOT NULL,
+			FOREIGN KEY (protocolo_id) REFERENCES protocolos_iatf(id)
+		)`,
+
+		`CREATE SEQUENCE IF NOT EXISTS inseminacoes_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS inseminacoes (
+			id INTEGER PRIMARY KEY DEFAULT nextval('inseminacoes_id_seq'),
+			animal_id INTEGER NOT NULL,
+			estacao_id INTEGER,
+			protocolo_id INTEGER,
+			data DATE NOT NULL,
+			tipo TEXT NOT NULL,
+			touro TEXT,
+			partida_semen TEXT,
+			inseminador TEXT,
+			FOREIGN KEY (animal_id) REFERENCES animais(id),
+			FOREIGN KEY (estacao_id) REFERENCES estacoes_monta(id),
+			FOREIGN KEY (protocolo_id) REFERENCES protocolos_iatf(id)
+		)`,
+
+		`CREATE SEQUENCE IF NOT EXISTS diagnosticos_gestacao_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS diagnosticos_gestacao (
+			id INTEGER PRIMARY KEY DEFAULT nextval('diagnosticos_gestacao_id_seq'),
+			animal_id INTEGER NOT NULL,
+			inseminacao_id INTEGER,
+			data DATE NOT NULL,
+			resultado TEXT NOT NULL,
+			metodo TEXT,
+			dias_gestacao INTEGER,
+			FOREIGN KEY (animal_id) REFERENCES animais(id),
+			FOREIGN KEY (inseminacao_id) REFERENCES inseminacoes(id)
+		)`,
+
+		`CREATE SEQUENCE IF NOT EXISTS partos_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS partos (
+			id INTEGER PRIMARY KEY DEFAULT nextval('partos_id_seq'),
+			animal_id INTEGER NOT NULL,
+			data DATE NOT NULL,
+			observacoes TEXT,
+			FOREIGN KEY (animal_id) REFERENCES animais(id)
+		)`,
+
+		// Sanidade: vacinações, vermifugações e tratamentos
+		`CREATE SEQUENCE IF NOT EXISTS aplicacoes_sanitarias_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS aplicacoes_sanitarias (
+			id INTEGER PRIMARY KEY DEFAULT nextval('aplicacoes_sanitarias_id_seq'),
+			propriedade_id INTEGER NOT NULL,
+			animal_id INTEGER,
+			lote TEXT,
+			tipo TEXT NOT NULL,
+			alvo TEXT,
+			produto TEXT NOT NULL,
+			partida TEXT,
+			dose REAL,
+			unidade TEXT,
+			via TEXT,
+			data DATE NOT NULL,
+			carencia_abate INTEGER DEFAULT 0,
+			carencia_leite INTEGER DEFAULT 0,
+			intervalo_dose INTEGER DEFAULT 0,
+			responsavel TEXT,
+			observacoes TEXT,
+			FOREIGN KEY (propriedade_id) REFERENCES propriedades(id),
+			FOREIGN KEY (animal_id) REFERENCES animais(id)
+		)`,
+
+		`CREATE SEQUENCE IF NOT EXISTS calendario_sanitario_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS calendario_sanitario (
+			id INTEGER PRIMARY KEY DEFAULT nextval('calendario_sanitario_id_seq'),
+			propriedade_id INTEGER NOT NULL,
+			animal_id INTEGER,
+			lote TEXT,
+			tipo TEXT NOT NULL,
+			alvo TEXT,
+			produto TEXT,
+			data_prevista DATE NOT NULL,
+			origem_id INTEGER,
+			realizado_em DATE,
+			aplicacao_id INTEGER,
+			FOREIGN KEY (propriedade_id) REFERENCES propriedades(id)
+		)`,
+
+		// Produção leiteira
+		`CREATE SEQUENCE IF NOT EXISTS controles_leiteiros_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS controles_leiteiros (
+			id INTEGER PRIMARY KEY DEFAULT nextval('controles_leiteiros_id_seq'),
+			animal_id INTEGER NOT NULL,
+			data DATE NOT NULL,
+			litros REAL NOT NULL,
+			ordenhas INTEGER DEFAULT 2,
+			gordura REAL,
+			proteina REAL,
+			ccs REAL,
+			FOREIGN KEY (animal_id) REFERENCES animais(id)
+		)`,
+
+		`CREATE SEQUENCE IF NOT EXISTS analises_tanque_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS analises_tanque (
+			id INTEGER PRIMARY KEY DEFAULT nextval('analises_tanque_id_seq'),
+			propriedade_id INTEGER NOT NULL,
+			data DATE NOT NULL,
+			tanque TEXT,
+			laboratorio TEXT,
+			gordura REAL,
+			proteina REAL,
+			ccs REAL,
+			cbt REAL,
+			FOREIGN KEY (propriedade_id) REFERENCES propriedades(id)
+		)`,
+
+		// Trânsito animal (GTA) e estoque do rebanho por categoria
+		`CREATE SEQUENCE IF NOT EXISTS movimentacoes_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS movimentacoes (
+			id INTEGER PRIMARY KEY DEFAULT nextval('movimentacoes_id_seq'),
+			propriedade_id INTEGER NOT NULL,
+			tipo TEXT NOT NULL,
+			gta_numero TEXT NOT NULL,
+			gta_serie TEXT,
+			gta_uf TEXT NOT NULL,
+			data DATE NOT NULL,
+			origem TEXT NOT NULL,
+			destino TEXT NOT NULL,
+			finalidade TEXT NOT NULL,
+			especie TEXT DEFAULT 'Bovino',
+			FOREIGN KEY (propriedade_id) REFERENCES propriedades(id)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS movimentacao_categorias (
+			movimentacao_id INTEGER NOT NULL,
+			categoria TEXT NOT NULL,
+			quantidade INTEGER NOT NULL,
+			PRIMARY KEY (movimentacao_id, categoria),
+			FOREIGN KEY (movimentacao_id) REFERENCES movimentacoes(id)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS rebanho_estoque (
+			propriedade_id INTEGER NOT NULL,
+			categoria TEXT NOT NULL,
+			quantidade INTEGER NOT NULL DEFAULT 0,
+			atualizado_em TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (propriedade_id, categoria),
+			FOREIGN KEY (propriedade_id) REFERENCES propriedades(id)
+		)`,
+
+		// Talhões
+		`CREATE SEQUENCE IF NOT EXISTS talhoes_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS talhoes (
+			id INTEGER PRIMARY KEY DEFAULT nextval('talhoes_id_seq'),
+			propriedade_id INTEGER NOT NULL,
+			nome TEXT NOT NULL,
+			area_hectares REAL NOT NULL,
+			uso TEXT DEFAULT 'Lavoura',
+			observacoes TEXT,
+			FOREIGN KEY (propriedade_id) REFERENCES propriedades(id)
+		)`,
+
+		// Pastejo rotacionado
+		`CREATE SEQUENCE IF NOT EXISTS piquetes_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS piquetes (
+			id INTEGER PRIMARY KEY DEFAULT nextval('piquetes_id_seq'),
+			talhao_id INTEGER NOT NULL,
+			numero INTEGER NOT NULL,
+			area_hectares REAL NOT NULL,
+			FOREIGN KEY (talhao_id) REFERENCES talhoes(id)
+		)`,
+
+		`CREATE SEQUENCE IF NOT EXISTS planos_pastejo_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS planos_pastejo (
+			id INTEGER PRIMARY KEY DEFAULT nextval('planos_pastejo_id_seq'),
+			talhao_id INTEGER NOT NULL,
+			lote TEXT NOT NULL,
+			ua REAL NOT NULL,
+			forrageira TEXT,
+			dias_descanso INTEGER NOT NULL,
+			dias_ocupacao INTEGER NOT NULL,
+			data_inicio DATE NOT NULL,
+			ciclos INTEGER DEFAULT 1,
+			FOREIGN KEY (talhao_id) REFERENCES talhoes(id)
+		)`,
+
+		`CREATE SEQUENCE IF NOT EXISTS ocupacoes_piquete_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS ocupacoes_piquete (
+			id INTEGER PRIMARY KEY DEFAULT nextval('ocupacoes_piquete_id_seq'),
+			plano_id INTEGER NOT NULL,
+			piquete_id INTEGER NOT NULL,
+			lote TEXT NOT NULL,
+			ciclo INTEGER NOT NULL,
+			entrada_prevista DATE NOT NULL,
+			saida_prevista DATE NOT NULL,
+			entrada_real DATE,
+			saida_real DATE,
+			FOREIGN KEY (plano_id) REFERENCES planos_pastejo(id),
+			FOREIGN KEY (piquete_id) REFERENCES piquetes(id)
+		)`,
+
+		// Formulação de dietas
+		`CREATE SEQUENCE IF NOT EXISTS ingredientes_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS ingredientes (
+			id INTEGER PRIMARY KEY DEFAULT nextval('ingredientes_id_seq'),
+			nome TEXT NOT NULL,
+			materia_seca DOUBLE NOT NULL,
+			proteina_bruta DOUBLE DEFAULT 0,
+			ndt DOUBLE DEFAULT 0,
+			fdn DOUBLE DEFAULT 0,
+			calcio DOUBLE DEFAULT 0,
+			fosforo DOUBLE DEFAULT 0,
+			preco_kg DOUBLE DEFAULT 0,
+			atualizado_em TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		`CREATE SEQUENCE IF NOT EXISTS dietas_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS dietas (
+			id INTEGER PRIMARY KEY DEFAULT nextval('dietas_id_seq'),
+			propriedade_id INTEGER NOT NULL,
+			lote TEXT NOT NULL,
+			sistema TEXT NOT NULL,
+			peso_vivo DOUBLE NOT NULL,
+			ganho_diario DOUBLE NOT NULL,
+			consumo_ms DOUBLE NOT NULL,
+			rendimento_carcaca DOUBLE NOT NULL,
+			ndt DOUBLE,
+			proteina_bruta DOUBLE,
+			custo_cabeca_dia DOUBLE,
+			custo_arroba DOUBLE,
+			criado_em TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (propriedade_id) REFERENCES propriedades(id)
+		)`,
+
+		`CREATE SEQUENCE IF NOT EXISTS dieta_itens_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS dieta_itens (
+			id INTEGER PRIMARY KEY DEFAULT nextval('dieta_itens_id_seq'),
+			dieta_id INTEGER NOT NULL,
+			ingrediente_id INTEGER NOT NULL,
+			proporcao DOUBLE NOT NULL,
+			kg_ms DOUBLE NOT NULL,
+			kg_mn DOUBLE NOT NULL,
+			custo DOUBLE NOT NULL,
+			FOREIGN KEY (dieta_id) REFERENCES dietas(id),
+			FOREIGN KEY (ingrediente_id) REFERENCES ingredientes(id)
+		)`,
+
+		// Planejamento de safra por talhão
+		`CREATE SEQUENCE IF NOT EXISTS safras_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS safras (
+			id INTEGER PRIMARY KEY DEFAULT nextval('safras_id_seq'),
+			talhao_id INTEGER NOT NULL,
+			rotulo TEXT NOT NULL,
+			cultura TEXT NOT NULL,
+			cultivar TEXT,
+			area_hectares DOUBLE NOT NULL,
+			plantio_previsto DATE NOT NULL,
+			colheita_prevista DATE NOT NULL,
+			plantio_real DATE,
+			colheita_real DATE,
+			produtividade_alvo DOUBLE,
+			unidade TEXT DEFAULT 'sc/ha',
+			observacoes TEXT,
+			data_cadastro TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (talhao_id) REFERENCES talhoes(id)
+		)`,
+
+		// Recomendações (consultas) vinculadas a uma safra
+		`ALTER TABLE consultas ADD COLUMN IF NOT EXISTS safra_id INTEGER`,
+
+		// Registro de aplicações de insumos por talhão e safra
+		`CREATE SEQUENCE IF NOT EXISTS aplicacoes_insumos_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS aplicacoes_insumos (
+			id INTEGER PRIMARY KEY DEFAULT nextval('aplicacoes_insumos_id_seq'),
+			safra_id INTEGER NOT NULL,
+			talhao_id INTEGER NOT NULL,
+			data TIMESTAMP NOT NULL,
+			produto TEXT NOT NULL,
+			principio_ativo TEXT,
+			dose DOUBLE NOT NULL,
+			unidade_dose TEXT NOT NULL,
+			area_aplicada DOUBLE NOT NULL,
+			operador TEXT,
+			equipamento TEXT,
+			temperatura DOUBLE,
+			umidade_relativa DOUBLE,
+			vento_kmh DOUBLE,
+			clima TEXT,
+			intervalo_reentrada INTEGER DEFAULT 0,
+			carencia_dias INTEGER DEFAULT 0,
+			observacoes TEXT,
+			FOREIGN KEY (safra_id) REFERENCES safras(id),
+			FOREIGN KEY (talhao_id) REFERENCES talhoes(id)
+		)`,
+
+		// Produtos recomendados nas consultas e receituários emitidos
+		`CREATE SEQUENCE IF NOT EXISTS consulta_produtos_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS consulta_produtos (
+			id INTEGER PRIMARY KEY DEFAULT nextval('consulta_produtos_id_seq'),
+			consulta_id INTEGER NOT NULL,
+			produto TEXT NOT NULL,
+			principio_ativo TEXT,
+			registro_mapa TEXT,
+			classe_toxicologica TEXT,
+			praga TEXT NOT NULL,
+			dose DOUBLE NOT NULL,
+			unidade_dose TEXT NOT NULL,
+			volume_calda DOUBLE,
+			modo_aplicacao TEXT,
+			numero_aplicacoes INTEGER DEFAULT 1,
+			intervalo_reentrada INTEGER DEFAULT 0,
+			carencia_dias INTEGER DEFAULT 0,
+			observacoes TEXT,
+			FOREIGN KEY (consulta_id) REFERENCES consultas(id)
+		)`,
+
+		`CREATE SEQUENCE IF NOT EXISTS receituarios_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS receituarios (
+			id INTEGER PRIMARY KEY DEFAULT nextval('receituarios_id_seq'),
+			numero INTEGER NOT NULL,
+			ano INTEGER NOT NULL,
+			consulta_id INTEGER NOT NULL,
+			data_emissao DATE NOT NULL,
+			agronomo_nome TEXT NOT NULL,
+			crea TEXT NOT NULL,
+			cultura TEXT,
+			area_hectares DOUBLE,
+			pdf BLOB NOT NULL,
+			criado_em TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (ano, numero),
+			FOREIGN KEY (consulta_id) REFERENCES consultas(id)
+		)`,
+
+		// Cargas colhidas por safra (peso de balança, umidade e impurezas)
+		`CREATE SEQUENCE IF NOT EXISTS colheitas_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS colheitas (
+			id INTEGER PRIMARY KEY DEFAULT nextval('colheitas_id_seq'),
+			safra_id INTEGER NOT NULL,
+			data DATE NOT NULL,
+			area_colhida DOUBLE,
+			peso_bruto_kg DOUBLE NOT NULL,
+			umidade DOUBLE,
+			impureza DOUBLE,
+			observacoes TEXT,
+			criado_em TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (safra_id) REFERENCES safras(id)
+		)`,
+
+		// Livro de custos por safra com categorias configuráveis
+		`CREATE SEQUENCE IF NOT EXISTS categorias_custo_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS categorias_custo (
+			id INTEGER PRIMARY KEY DEFAULT nextval('categorias_custo_id_seq'),
+			nome TEXT NOT NULL,
+			grupo TEXT NOT NULL,
+			ativa BOOLEAN DEFAULT true
+		)`,
+
+		`CREATE SEQUENCE IF NOT EXISTS lancamentos_custo_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS lancamentos_custo (
+			id INTEGER PRIMARY KEY DEFAULT nextval('lancamentos_custo_id_seq'),
+			safra_id INTEGER NOT NULL,
+			categoria_id INTEGER NOT NULL,
+			data DATE NOT NULL,
+			descricao TEXT,
+			valor DOUBLE NOT NULL,
+			aplicacao_id INTEGER,
+			criado_em TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (safra_id) REFERENCES safras(id),
+			FOREIGN KEY (categoria_id) REFERENCES categorias_custo(id),
+			FOREIGN KEY (aplicacao_id) REFERENCES aplicacoes_insumos(id)
+		)`,
+
+		// Cotações de venda importadas (R$ por saca, tonelada ou arroba)
+		`CREATE SEQUENCE IF NOT EXISTS precos_venda_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS precos_venda (
+			id INTEGER PRIMARY KEY DEFAULT nextval('precos_venda_id_seq'),
+			cultura TEXT NOT NULL,
+			data DATE NOT NULL,
+			preco DOUBLE NOT NULL,
+			unidade TEXT NOT NULL DEFAULT 'sc',
+			praca TEXT,
+			importado_em TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Análises químicas de solo por talhão
+		`CREATE SEQUENCE IF NOT EXISTS analises_solo_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS analises_solo (
+			id INTEGER PRIMARY KEY DEFAULT nextval('analises_solo_id_seq'),
+			talhao_id INTEGER NOT NULL,
+			data_amostra DATE NOT NULL,
+			laboratorio TEXT,
+			profundidade TEXT,
+			ph DOUBLE,
+			materia_organica DOUBLE,
+			fosforo DOUBLE,
+			potassio DOUBLE,
+			calcio DOUBLE,
+			magnesio DOUBLE,
+			aluminio DOUBLE,
+			h_al DOUBLE,
+			argila DOUBLE,
+			observacoes TEXT,
+			criado_em TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (talhao_id) REFERENCES talhoes(id)
+		)`,
+
+		// Chuva diária registrada por propriedade
+		`CREATE SEQUENCE IF NOT EXISTS chuvas_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS chuvas (
+			id INTEGER PRIMARY KEY DEFAULT nextval('chuvas_id_seq'),
+			propriedade_id INTEGER NOT NULL,
+			data DATE NOT NULL,
+			milimetros DOUBLE NOT NULL,
+			observacoes TEXT,
+			criado_em TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (propriedade_id) REFERENCES propriedades(id)
+		)`,
+
+		// Níveis de ação do manejo integrado de pragas (MIP) por cultura e alvo
+		`CREATE SEQUENCE IF NOT EXISTS niveis_acao_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS niveis_acao (
+			id INTEGER PRIMARY KEY DEFAULT nextval('niveis_acao_id_seq'),
+			cultura TEXT NOT NULL,
+			alvo TEXT NOT NULL,
+			tipo TEXT NOT NULL,
+			medida TEXT NOT NULL,
+			limite DOUBLE NOT NULL,
+			estadio TEXT,
+			observacoes TEXT,
+			ativo BOOLEAN DEFAULT true
+		)`,
+
+		// Monitoramentos de pragas e doenças (visitas de amostragem) por safra
+		`CREATE SEQUENCE IF NOT EXISTS monitoramentos_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS monitoramentos (
+			id INTEGER PRIMARY KEY DEFAULT nextval('monitoramentos_id_seq'),
+			safra_id INTEGER NOT NULL,
+			data DATE NOT NULL,
+			estadio TEXT,
+			responsavel TEXT,
+			observacoes TEXT,
+			criado_em TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (safra_id) REFERENCES safras(id)
+		)`,
+
+		`CREATE SEQUENCE IF NOT EXISTS ocorrencias_pragas_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS ocorrencias_pragas (
+			id INTEGER PRIMARY KEY DEFAULT nextval('ocorrencias_pragas_id_seq'),
+			monitoramento_id INTEGER NOT NULL,
+			alvo TEXT NOT NULL,
+			tipo TEXT NOT NULL,
+			medida TEXT NOT NULL,
+			pontos INTEGER NOT NULL,
+			valor DOUBLE NOT NULL,
+			FOREIGN KEY (monitoramento_id) REFERENCES monitoramentos(id)
+		)`,
+
+		// Dados meteorológicos diários por propriedade (estação ou importação)
+		`CREATE SEQUENCE IF NOT EXISTS clima_diario_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS clima_diario (
+			id INTEGER PRIMARY KEY DEFAULT nextval('clima_diario_id_seq'),
+			propriedade_id INTEGER NOT NULL,
+			data DATE NOT NULL,
+			temp_maxima DOUBLE,
+			temp_minima DOUBLE,
+			umidade_relativa DOUBLE,
+			vento_ms DOUBLE,
+			radiacao DOUBLE,
+			eto_informada DOUBLE,
+			FOREIGN KEY (propriedade_id) REFERENCES propriedades(id)
+		)`,
+
+		// Parâmetros de irrigação do talhão para o balanço hídrico
+		`CREATE SEQUENCE IF NOT EXISTS config_irrigacao_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS config_irrigacao (
+			id INTEGER PRIMARY KEY DEFAULT nextval('config_irrigacao_id_seq'),
+			talhao_id INTEGER NOT NULL,
+			sistema TEXT NOT NULL,
+			eficiencia DOUBLE NOT NULL,
+			agua_disponivel DOUBLE NOT NULL,
+			profundidade_raiz DOUBLE NOT NULL,
+			fator_deplecao DOUBLE NOT NULL,
+			lamina_maxima DOUBLE,
+			latitude DOUBLE,
+			altitude DOUBLE,
+			FOREIGN KEY (talhao_id) REFERENCES talhoes(id)
+		)`,
+
+		// Irrigações aplicadas por safra (lâmina bruta)
+		`CREATE SEQUENCE IF NOT EXISTS irrigacoes_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS irrigacoes (
+			id INTEGER PRIMARY KEY DEFAULT nextval('irrigacoes_id_seq'),
+			safra_id INTEGER NOT NULL,
+			data DATE NOT NULL,
+			lamina DOUBLE NOT NULL,
+			duracao DOUBLE,
+			observacoes TEXT,
+			criado_em TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (safra_id) REFERENCES safras(id)
+		)`,
+
+		// Consultores da equipe; o token identifica o feed iCalendar de cada um
+		`CREATE SEQUENCE IF NOT EXISTS consultores_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS consultores (
+			id INTEGER PRIMARY KEY DEFAULT nextval('consultores_id_seq'),
+			nome TEXT NOT NULL,
+			email TEXT,
+			telefone TEXT,
+			token TEXT NOT NULL,
+			ativo BOOLEAN DEFAULT true
+		)`,
+
+		// Consultor responsável pelo cliente e consultor que realizou a consulta
+		`ALTER TABLE clientes ADD COLUMN IF NOT EXISTS consultor_id INTEGER`,
+		`ALTER TABLE consultas ADD COLUMN IF NOT EXISTS consultor_id INTEGER`,
+
+		// Máquinas da propriedade com horímetro ou hodômetro
+		`CREATE SEQUENCE IF NOT EXISTS maquinas_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS maquinas (
+			id INTEGER PRIMARY KEY DEFAULT nextval('maquinas_id_seq'),
+			propriedade_id INTEGER NOT NULL,
+			nome TEXT NOT NULL,
+			tipo TEXT NOT NULL,
+			marca TEXT,
+			modelo TEXT,
+			ano INTEGER,
+			unidade TEXT DEFAULT 'h',
+			medidor DOUBLE DEFAULT 0,
+			capacidade_operacional DOUBLE,
+			ativo BOOLEAN DEFAULT true,
+			FOREIGN KEY (propriedade_id) REFERENCES propriedades(id)
+		)`,
+
+		// Planos de manutenção por intervalo de uso e/ou de calendário
+		`CREATE SEQUENCE IF NOT EXISTS planos_manutencao_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS planos_manutencao (
+			id INTEGER PRIMARY KEY DEFAULT nextval('planos_manutencao_id_seq'),
+			maquina_id INTEGER NOT NULL,
+			descricao TEXT NOT NULL,
+			intervalo_medidor DOUBLE DEFAULT 0,
+			intervalo_dias INTEGER DEFAULT 0,
+			ultimo_medidor DOUBLE DEFAULT 0,
+			ultima_data DATE NOT NULL,
+			ativo BOOLEAN DEFAULT true,
+			FOREIGN KEY (maquina_id) REFERENCES maquinas(id)
+		)`,
+
+		// Serviços de manutenção executados
+		`CREATE SEQUENCE IF NOT EXISTS manutencoes_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS manutencoes (
+			id INTEGER PRIMARY KEY DEFAULT nextval('manutencoes_id_seq'),
+			maquina_id INTEGER NOT NULL,
+			plano_id INTEGER,
+			data DATE NOT NULL,
+			medidor DOUBLE,
+			descricao TEXT NOT NULL,
+			pecas TEXT,
+			custo DOUBLE DEFAULT 0,
+			responsavel TEXT,
+			observacoes TEXT,
+			FOREIGN KEY (maquina_id) REFERENCES maquinas(id)
+		)`,
+
+		// Avanço do horímetro/hodômetro (manual ou pelas aplicações de insumos)
+		`CREATE SEQUENCE IF NOT EXISTS leituras_medidor_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS leituras_medidor (
+			id INTEGER PRIMARY KEY DEFAULT nextval('leituras_medidor_id_seq'),
+			maquina_id INTEGER NOT NULL,
+			data DATE NOT NULL,
+			medidor DOUBLE NOT NULL,
+			incremento DOUBLE NOT NULL,
+			origem TEXT DEFAULT 'manual',
+			aplicacao_id INTEGER,
+			FOREIGN KEY (maquina_id) REFERENCES maquinas(id)
+		)`,
+
+		// Estoque de insumos: cadastro, recebimentos por lote e consumo
+		`CREATE SEQUENCE IF NOT EXISTS insumos_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS insumos (
+			id INTEGER PRIMARY KEY DEFAULT nextval('insumos_id_seq'),
+			propriedade_id INTEGER NOT NULL,
+			nome TEXT NOT NULL,
+			categoria TEXT NOT NULL,
+			unidade TEXT NOT NULL,
+			principio_ativo TEXT,
+			estoque_minimo DOUBLE DEFAULT 0,
+			ativo BOOLEAN DEFAULT true,
+			FOREIGN KEY (propriedade_id) REFERENCES propriedades(id)
+		)`,
+
+		`CREATE SEQUENCE IF NOT EXISTS entradas_insumo_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS entradas_insumo (
+			id INTEGER PRIMARY KEY DEFAULT nextval('entradas_insumo_id_seq'),
+			insumo_id INTEGER NOT NULL,
+			data DATE NOT NULL,
+			fornecedor TEXT,
+			nota_fiscal TEXT,
+			lote TEXT,
+			validade DATE,
+			quantidade DOUBLE NOT NULL,
+			custo_unitario DOUBLE DEFAULT 0,
+			observacoes TEXT,
+			FOREIGN KEY (insumo_id) REFERENCES insumos(id)
+		)`,
+
+		`CREATE SEQUENCE IF NOT EXISTS saidas_insumo_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS saidas_insumo (
+			id INTEGER PRIMARY KEY DEFAULT nextval('saidas_insumo_id_seq'),
+			insumo_id INTEGER NOT NULL,
+			data DATE NOT NULL,
+			quantidade DOUBLE NOT NULL,
+			lote TEXT,
+			origem TEXT DEFAULT 'ajuste',
+			aplicacao_id INTEGER,
+			aplicacao_sanitaria_id INTEGER,
+			descricao TEXT,
+			FOREIGN KEY (insumo_id) REFERENCES insumos(id)
+		)`,
+
+		// Sede (graus decimais SIRGAS 2000) e limite da propriedade em GeoJSON.
+		// A antiga coluna coordenadas (texto livre) continua nos bancos
+		// existentes porque o DuckDB não remove colunas de tabelas referenciadas;
+		// seu conteúdo é migrado para a sede na inicialização
+		`ALTER TABLE propriedades ADD COLUMN IF NOT EXISTS sede_latitude DOUBLE`,
+		`ALTER TABLE propriedades ADD COLUMN IF NOT EXISTS sede_longitude DOUBLE`,
+		`ALTER TABLE propriedades ADD COLUMN IF NOT EXISTS limite TEXT`,
+		`ALTER TABLE propriedades ADD COLUMN IF NOT EXISTS area_limite DOUBLE`,
+
+		// Limite do talhão importado de KML, GeoJSON ou Shapefile
+		`ALTER TABLE talhoes ADD COLUMN IF NOT EXISTS limite TEXT`,
+		`ALTER TABLE talhoes ADD COLUMN IF NOT EXISTS area_limite DOUBLE`,
+
+		// Grades de amostragem de solo geradas a partir do limite do talhão
+		`CREATE SEQUENCE IF NOT EXISTS grades_amostragem_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS grades_amostragem (
+			id INTEGER PRIMARY KEY DEFAULT nextval('grades_amostragem_id_seq'),
+			talhao_id INTEGER NOT NULL,
+			metodo TEXT NOT NULL,
+			hectares_por_ponto DOUBLE,
+			faixas INTEGER,
+			profundidade TEXT,
+			observacoes TEXT,
+			criado_em TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (talhao_id) REFERENCES talhoes(id)
+		)`,
+
+		// Pontos numerados da grade; o código é o ID da amostra no laboratório
+		`CREATE SEQUENCE IF NOT EXISTS pontos_amostragem_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS pontos_amostragem (
+			id INTEGER PRIMARY KEY DEFAULT nextval('pontos_amostragem_id_seq'),
+			grade_id INTEGER NOT NULL,
+			numero INTEGER NOT NULL,
+			codigo TEXT NOT NULL UNIQUE,
+			faixa INTEGER DEFAULT 0,
+			latitude DOUBLE NOT NULL,
+			longitude DOUBLE NOT NULL,
+			FOREIGN KEY (grade_id) REFERENCES grades_amostragem(id)
+		)`,
+
+		// Laudo ligado ao ponto da grade pelo ID da amostra
+		`ALTER TABLE analises_solo ADD COLUMN IF NOT EXISTS ponto_id INTEGER`,
+		`ALTER TABLE analises_solo ADD COLUMN IF NOT EXISTS codigo_amostra TEXT`,
+
+		// Lote e identificações dos animais que embarcam na GTA
+		`ALTER TABLE movimentacoes ADD COLUMN IF NOT EXISTS lote TEXT`,
+		`ALTER TABLE movimentacoes ADD COLUMN IF NOT EXISTS animais TEXT`,
+
+		// Saldo inicial e ajustes de inventário do estoque do rebanho
+		// (nascimentos, mortes, contagens), fora das GTAs
+		`CREATE SEQUENCE IF NOT EXISTS rebanho_ajustes_id_seq START 1`,
+
+		`CREATE TABLE IF NOT EXISTS rebanho_ajustes (
+			id INTEGER PRIMARY KEY DEFAULT nextval('rebanho_ajustes_id_seq'),
+			propriedade_id INTEGER NOT NULL,
+			data DATE NOT NULL,
+			motivo TEXT NOT NULL,
+			categoria TEXT NOT NULL,
+			anterior INTEGER NOT NULL,
+			quantidade INTEGER NOT NULL,
+			FOREIGN KEY (propriedade_id) REFERENCES propriedades(id)
+		)`,
 	}
-	
+
 	for i, tableSQL := range tables {
 		log.Printf("📝 Criando: %s", strings.Split(tableSQL, " ")[1])
 		_, err := db.Exec(tableSQL)
@@ -0,0 +1,481 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// carregarGradesAmostragem lista as grades do talhão, da mais recente para
+// a mais antiga, com o total de pontos e de pontos com laudo
+func (app *Application) carregarGradesAmostragem(talhaoID int) ([]models.GradeAmostragem, error) {
+	rows, err := app.DB.Query(`
+		SELECT g.id, g.talhao_id, g.metodo, COALESCE(g.hectares_por_ponto, 0), COALESCE(g.faixas, 0),
+		       COALESCE(g.profundidade, ''), COALESCE(g.observacoes, ''), g.criado_em,
+		       (SELECT count(*) FROM pontos_amostragem pa WHERE pa.grade_id = g.id),
+		       (SELECT count(DISTINCT a.ponto_id) FROM analises_solo a
+		        JOIN pontos_amostragem pa ON pa.id = a.ponto_id WHERE pa.grade_id = g.id)
+		FROM grades_amostragem g
+		WHERE g.talhao_id = ?
+		ORDER BY g.criado_em DESC, g.id DESC`, talhaoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grades []models.GradeAmostragem
+	for rows.Next() {
+		var g models.GradeAmostragem
+		if err := rows.Scan(&g.ID, &g.TalhaoID, &g.Metodo, &g.HectaresPorPonto, &g.Faixas, &g.Profundidade,
+			&g.Observacoes, &g.CriadoEm, &g.TotalPontos, &g.ComResultado); err != nil {
+			return nil, err
+		}
+		grades = append(grades, g)
+	}
+	return grades, rows.Err()
+}
+
+// carregarPontosAmostragem lista os pontos com o último laudo ligado a cada
+// um. O filtro é aplicado sobre pontos_amostragem pa, grades_amostragem g,
+// talhoes t, propriedades p e clientes c.
+func (app *Application) carregarPontosAmostragem(filtro string, args ...any) ([]models.PontoAmostragem, error) {
+	rows, err := app.DB.Query(`
+		SELECT pa.id, pa.grade_id, pa.numero, pa.codigo, COALESCE(pa.faixa, 0), pa.latitude, pa.longitude,
+		       COALESCE(an.analise_id, 0), an.data_amostra
+		FROM pontos_amostragem pa
+		JOIN grades_amostragem g ON g.id = pa.grade_id
+		JOIN talhoes t ON t.id = g.talhao_id
+		JOIN propriedades p ON p.id = t.propriedade_id
+		JOIN clientes c ON c.id = p.cliente_id
+		LEFT JOIN (
+			SELECT ponto_id, arg_max(id, data_amostra) AS analise_id, max(data_amostra) AS data_amostra
+			FROM analises_solo WHERE ponto_id IS NOT NULL GROUP BY ponto_id
+		) an ON an.ponto_id = pa.id
+		`+filtro+`
+		ORDER BY pa.grade_id, pa.numero`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pontos []models.PontoAmostragem
+	for rows.Next() {
+		var p models.PontoAmostragem
+		var data sql.NullTime
+		if err := rows.Scan(&p.ID, &p.GradeID, &p.Numero, &p.Codigo, &p.Faixa, &p.Latitude, &p.Longitude,
+			&p.AnaliseID, &data); err != nil {
+			return nil, err
+		}
+		p.DataAnalise = data.Time
+		pontos = append(pontos, p)
+	}
+	return pontos, rows.Err()
+}
+
+// buscarGradeAmostragem carrega a grade do talhão com os pontos
+func (app *Application) buscarGradeAmostragem(talhaoID, gradeID int) (models.GradeAmostragem, error) {
+	grades, err := app.carregarGradesAmostragem(talhaoID)
+	if err != nil {
+		return models.GradeAmostragem{}, err
+	}
+	for _, g := range grades {
+		if g.ID == gradeID {
+			g.Pontos, err = app.carregarPontosAmostragem("WHERE pa.grade_id = ?", g.ID)
+			return g, err
+		}
+	}
+	return models.GradeAmostragem{}, sql.ErrNoRows
+}
+
+// buscarPontoAmostragem localiza o ponto pelo ID da amostra entre as
+// grades do talhão
+func (app *Application) buscarPontoAmostragem(talhaoID int, codigo string) (models.PontoAmostragem, error) {
+	pontos, err := app.carregarPontosAmostragem("WHERE g.talhao_id = ? AND upper(pa.codigo) = upper(?)", talhaoID, codigo)
+	if err != nil {
+		return models.PontoAmostragem{}, err
+	}
+	if len(pontos) == 0 {
+		return models.PontoAmostragem{}, sql.ErrNoRows
+	}
+	return pontos[0], nil
+}
+
+// faixasDaGrade refaz os contornos das faixas a partir do limite do talhão
+func faixasDaGrade(talhao models.Talhao, g models.GradeAmostragem) [][]models.Ponto {
+	if g.Metodo != models.MetodoGradeFaixas {
+		return nil
+	}
+	faixas, err := services.FaixasAmostragem(talhao.Limite, g.Faixas)
+	if err != nil {
+		return nil
+	}
+	return faixas
+}
+
+// AmostragemTalhao mostra as grades de amostragem do talhão; a grade
+// escolhida (ou a mais recente) aparece no mapa com os pontos numerados
+func (app *Application) AmostragemTalhao(w http.ResponseWriter, r *http.Request) {
+	talhao, err := app.buscarTalhao(formInt(r, "talhao_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	propriedade, err := app.buscarPropriedade(talhao.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	grades, err := app.carregarGradesAmostragem(talhao.ID)
+	if err != nil {
+		log.Printf("❌ Erro ao carregar grades de amostragem: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	var grade *models.GradeAmostragem
+	gradeID := formInt(r, "grade_id")
+	if gradeID == 0 && len(grades) > 0 {
+		gradeID = grades[0].ID
+	}
+	if gradeID > 0 {
+		g, err := app.buscarGradeAmostragem(talhao.ID, gradeID)
+		if err != nil && err != sql.ErrNoRows {
+			app.serverError(w, r, err)
+			return
+		}
+		if err == nil {
+			grade = &g
+		}
+	}
+
+	// Camadas do mapa: limite, faixas e pontos
+	camadas := services.NovaColecao()
+	if len(talhao.Limite) > 0 {
+		camadas.AdicionarPoligono(talhao.Limite, map[string]any{"tipo": "limite", "nome": talhao.Nome})
+	}
+	if grade != nil {
+		for i, f := range faixasDaGrade(talhao, *grade) {
+			camadas.AdicionarPoligono(f, map[string]any{"tipo": "faixa", "faixa": i + 1})
+		}
+		for _, p := range grade.Pontos {
+			atributos := map[string]any{"tipo": "ponto", "numero": p.Numero, "codigo": p.Codigo, "faixa": p.Faixa}
+			if p.TemResultado() {
+				atributos["data_analise"] = p.DataAnalise.Format("02/01/2006")
+			}
+			camadas.AdicionarPonto(p.Ponto, atributos)
+		}
+	}
+
+	data := map[string]interface{}{
+		"Propriedade":         propriedade,
+		"Talhao":              talhao,
+		"Grades":              grades,
+		"Grade":               grade,
+		"Camadas":             camadas,
+		"MetodoGrade":         models.MetodoGradeRegular,
+		"MetodoFaixas":        models.MetodoGradeFaixas,
+		"MinHectaresPorPonto": services.MinHectaresPorPonto,
+		"MaxHectaresPorPonto": services.MaxHectaresPorPonto,
+		"MaxFaixas":           services.MaxFaixasAmostragem,
+		"Hoje":                horaLocal().Format("2006-01-02"),
+		"Title":               "Amostragem de Solo",
+	}
+	app.renderTemplate(w, r, "talhoes/amostragem.html", data)
+}
+
+// GerarGradeAmostragem cria a grade regular (N ha por ponto) ou em faixas a
+// partir do limite do talhão e numera os pontos com o ID da amostra
+func (app *Application) GerarGradeAmostragem(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	talhao, err := app.buscarTalhao(formInt(r, "talhao_id"))
+	if err != nil {
+		app.clientError(w, "Talhão não encontrado.")
+		return
+	}
+	if len(talhao.Limite) == 0 {
+		app.clientError(w, "Cadastre ou importe o limite do talhão antes de gerar a grade.")
+		return
+	}
+
+	g := models.GradeAmostragem{
+		TalhaoID:     talhao.ID,
+		Metodo:       r.FormValue("metodo"),
+		Profundidade: strings.TrimSpace(r.FormValue("profundidade")),
+		Observacoes:  strings.TrimSpace(r.FormValue("observacoes")),
+	}
+	var pontos []models.PontoAmostragem
+	switch g.Metodo {
+	case models.MetodoGradeRegular:
+		g.HectaresPorPonto = formFloat(r, "hectares_por_ponto")
+		pontos, err = services.GerarGradeRegular(talhao.Limite, g.HectaresPorPonto)
+	case models.MetodoGradeFaixas:
+		g.Faixas = formInt(r, "faixas")
+		pontos, err = services.GerarGradeFaixas(talhao.Limite, g.Faixas)
+	default:
+		app.clientError(w, "Escolha o método de amostragem.")
+		return
+	}
+	if err != nil {
+		app.clientError(w, "Não foi possível gerar a grade: "+err.Error()+".")
+		return
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
+		`INSERT INTO grades_amostragem (talhao_id, metodo, hectares_por_ponto, faixas, profundidade, observacoes)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id`,
+		g.TalhaoID, g.Metodo, g.HectaresPorPonto, g.Faixas, g.Profundidade, g.Observacoes,
+	).Scan(&g.ID)
+	for _, p := range pontos {
+		if err != nil {
+			break
+		}
+		_, err = tx.Exec(`INSERT INTO pontos_amostragem (grade_id, numero, codigo, faixa, latitude, longitude) VALUES (?, ?, ?, ?, ?, ?)`,
+			g.ID, p.Numero, models.CodigoAmostra(g.ID, p.Numero), p.Faixa, p.Latitude, p.Longitude)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("❌ Erro ao gravar grade de amostragem: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	r.Form.Set("grade_id", fmt.Sprint(g.ID))
+	setToast(w, fmt.Sprintf("Grade gerada com %d ponto(s) (%s).", len(pontos), g.Descricao()), "success")
+	app.AmostragemTalhao(w, r)
+}
+
+// ExcluirGradeAmostragem remove a grade e os pontos, desde que nenhum laudo
+// esteja ligado a ela
+func (app *Application) ExcluirGradeAmostragem(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	g, err := app.buscarGradeAmostragem(formInt(r, "talhao_id"), formInt(r, "grade_id"))
+	if err != nil {
+		app.clientError(w, "Grade de amostragem não encontrada.")
+		return
+	}
+	if g.ComResultado > 0 {
+		app.clientError(w, "A grade tem laudos ligados aos pontos e não pode ser excluída.")
+		return
+	}
+
+	// Fora de transação: o DuckDB só libera a chave estrangeira da grade
+	// depois que a exclusão dos pontos é confirmada
+	_, err = app.DB.Exec(`DELETE FROM pontos_amostragem WHERE grade_id = ?`, g.ID)
+	if err == nil {
+		_, err = app.DB.Exec(`DELETE FROM grades_amostragem WHERE id = ?`, g.ID)
+	}
+	if err != nil {
+		log.Printf("❌ Erro ao excluir grade de amostragem: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	r.Form.Del("grade_id")
+	setToast(w, "Grade de amostragem excluída.", "success")
+	app.AmostragemTalhao(w, r)
+}
+
+// ExportarGradeAmostragem baixa os pontos em GPX ou KML para o GPS da
+// equipe de campo, ou o mapa de amostragem em PDF para impressão
+func (app *Application) ExportarGradeAmostragem(w http.ResponseWriter, r *http.Request) {
+	talhao, err := app.buscarTalhao(formInt(r, "talhao_id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	g, err := app.buscarGradeAmostragem(talhao.ID, formInt(r, "grade_id"))
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	propriedade, err := app.buscarPropriedade(talhao.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	nome := fmt.Sprintf("%s - %s - %s", propriedade.Nome, talhao.Nome, g.Descricao())
+	arquivo := fmt.Sprintf("amostragem_g%d", g.ID)
+	switch r.FormValue("formato") {
+	case "gpx":
+		w.Header().Set("Content-Type", "application/gpx+xml")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+arquivo+`.gpx"`)
+		w.Write(services.ExportarGPX(nome, g.Pontos))
+	case "kml":
+		w.Header().Set("Content-Type", "application/vnd.google-earth.kml+xml")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+arquivo+`.kml"`)
+		w.Write(services.ExportarKMLAmostragem(nome, talhao.Limite, faixasDaGrade(talhao, g), g.Pontos))
+	default:
+		pdf := services.GerarMapaAmostragemPDF(services.MapaAmostragem{
+			Cliente:     propriedade.ClienteNome,
+			Propriedade: propriedade.Nome,
+			Talhao:      talhao.Nome,
+			AreaHa:      talhao.AreaHectares,
+			Grade:       g,
+			Anel:        talhao.Limite,
+			Faixas:      faixasDaGrade(talhao, g),
+		})
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", `inline; filename="`+arquivo+`.pdf"`)
+		w.Write(pdf)
+	}
+}
+
+// ImportarResultadosAmostragem lê o laudo do laboratório em CSV e grava uma
+// análise por ponto, ligada pelo ID da amostra. Um novo laudo do mesmo
+// ponto e data substitui o anterior.
+func (app *Application) ImportarResultadosAmostragem(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		app.clientError(w, "Envie o laudo em CSV (até 10 MB).")
+		return
+	}
+
+	talhao, err := app.buscarTalhao(formInt(r, "talhao_id"))
+	if err != nil {
+		app.clientError(w, "Talhão não encontrado.")
+		return
+	}
+	g, err := app.buscarGradeAmostragem(talhao.ID, formInt(r, "grade_id"))
+	if err != nil {
+		app.clientError(w, "Grade de amostragem não encontrada.")
+		return
+	}
+	data, ok := formData(r, "data_amostra")
+	if !ok {
+		app.clientError(w, "Informe a data da amostragem.")
+		return
+	}
+	arquivo, _, err := r.FormFile("arquivo")
+	if err != nil {
+		app.clientError(w, "Selecione o arquivo do laudo.")
+		return
+	}
+	defer arquivo.Close()
+
+	resultados, erros := services.LerResultadosLaboratorio(arquivo)
+	porCodigo := make(map[string]models.PontoAmostragem, len(g.Pontos))
+	for _, p := range g.Pontos {
+		porCodigo[strings.ToUpper(p.Codigo)] = p
+	}
+	var desconhecidos []string
+	var ligados []models.AnaliseSolo
+	for _, res := range resultados {
+		ponto, ok := porCodigo[res.Codigo]
+		if !ok {
+			desconhecidos = append(desconhecidos, res.Codigo)
+			continue
+		}
+		a := res.Analise
+		a.TalhaoID = talhao.ID
+		a.DataAmostra = data
+		a.Laboratorio = strings.TrimSpace(r.FormValue("laboratorio"))
+		a.Profundidade = g.Profundidade
+		a.PontoID = ponto.ID
+		a.CodigoAmostra = ponto.Codigo
+		ligados = append(ligados, a)
+	}
+	if len(ligados) == 0 {
+		msg := "Nenhum resultado do laudo corresponde aos pontos desta grade."
+		if len(erros) > 0 {
+			msg += " " + erros[0]
+		}
+		app.clientError(w, msg)
+		return
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+	for _, a := range ligados {
+		_, err = tx.Exec(`DELETE FROM analises_solo WHERE ponto_id = ? AND data_amostra = ?`, a.PontoID, a.DataAmostra)
+		if err == nil {
+			err = inserirAnaliseSolo(tx, a)
+		}
+		if err != nil {
+			log.Printf("❌ Erro ao importar laudo de amostragem: %v", err)
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	msg := fmt.Sprintf("%d resultado(s) ligado(s) aos pontos da grade.", len(ligados))
+	tipo := "success"
+	if len(desconhecidos) > 0 {
+		msg += fmt.Sprintf(" %d ID(s) de amostra fora da grade: %s.", len(desconhecidos), strings.Join(desconhecidos, ", "))
+		tipo = "warning"
+	}
+	if len(erros) > 0 {
+		msg += fmt.Sprintf(" %d linha(s) ignorada(s): %s.", len(erros), erros[0])
+		tipo = "warning"
+	}
+	setToast(w, msg, tipo)
+	app.AmostragemTalhao(w, r)
+}
+
+// GeoAmostras devolve como GeoJSON os pontos da grade mais recente de cada
+// talhão, com os filtros de GeoPropriedades
+func (app *Application) GeoAmostras(w http.ResponseWriter, r *http.Request) {
+	filtro, args := filtroMapa(r)
+	pontos, err := app.carregarPontosAmostragem(filtro+`
+		AND g.id IN (SELECT max(id) FROM grades_amostragem GROUP BY talhao_id)`, args...)
+	if err != nil {
+		log.Printf("❌ Erro ao carregar pontos de amostragem do mapa: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	colecao := services.NovaColecao()
+	for _, p := range pontos {
+		atributos := map[string]any{
+			"id":       p.ID,
+			"grade_id": p.GradeID,
+			"numero":   p.Numero,
+			"codigo":   p.Codigo,
+			"faixa":    p.Faixa,
+		}
+		if p.TemResultado() {
+			atributos["analise_id"] = p.AnaliseID
+			atributos["data_analise"] = p.DataAnalise.Format("2006-01-02")
+		}
+		colecao.AdicionarPonto(p.Ponto, atributos)
+	}
+	escreverGeoJSON(w, colecao)
+}
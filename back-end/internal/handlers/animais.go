@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// carregarAnimais lista os animais ativos de uma propriedade
+func (app *Application) carregarAnimais(propriedadeID int) ([]models.Animal, error) {
+	rows, err := app.DB.Query(`
+		SELECT id, propriedade_id, identificacao, COALESCE(nome, ''), COALESCE(especie, ''),
+		       COALESCE(raca, ''), COALESCE(sexo, ''), COALESCE(categoria, ''), COALESCE(lote, ''),
+		       data_nascimento, ativo
+		FROM animais
+		WHERE propriedade_id = ? AND ativo
+		ORDER BY identificacao`, propriedadeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var animais []models.Animal
+	for rows.Next() {
+		var a models.Animal
+		if err := rows.Scan(&a.ID, &a.PropriedadeID, &a.Identificacao, &a.Nome, &a.Especie,
+			&a.Raca, &a.Sexo, &a.Categoria, &a.Lote, &a.DataNascimento, &a.Ativo); err != nil {
+			return nil, err
+		}
+		animais = append(animais, a)
+	}
+	return animais, rows.Err()
+}
+
+// ListaAnimais exibe o rebanho cadastrado de uma propriedade
+func (app *Application) ListaAnimais(w http.ResponseWriter, r *http.Request) {
+	propriedadeID := formInt(r, "propriedade_id")
+	propriedade, err := app.buscarPropriedade(propriedadeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	animais, err := app.carregarAnimais(propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Propriedade": propriedade,
+		"Animais":     animais,
+		"Title":       "Rebanho",
+	}
+	app.renderTemplate(w, r, "animais/lista.html", data)
+}
+
+// SalvarAnimal cadastra ou atualiza um animal
+func (app *Application) SalvarAnimal(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	id := formInt(r, "id")
+	propriedadeID := formInt(r, "propriedade_id")
+	identificacao := strings.TrimSpace(r.FormValue("identificacao"))
+	if propriedadeID == 0 || identificacao == "" {
+		app.clientError(w, "Informe a propriedade e a identificação do animal.")
+		return
+	}
+
+	nascimento := formDataOpcional(r, "data_nascimento")
+	var err error
+	if id == 0 {
+		_, err = app.DB.Exec(
+			`INSERT INTO animais
+			(propriedade_id, identificacao, nome, especie, raca, sexo, categoria, lote, data_nascimento)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			propriedadeID, identificacao, r.FormValue("nome"), r.FormValue("especie"), r.FormValue("raca"),
+			r.FormValue("sexo"), r.FormValue("categoria"), r.FormValue("lote"), nullData(nascimento),
+		)
+	} else {
+		var res sql.Result
+		res, err = app.DB.Exec(
+			`UPDATE animais SET
+			identificacao=?, nome=?, especie=?, raca=?, sexo=?, categoria=?, lote=?, data_nascimento=?
+			WHERE id=? AND propriedade_id=?`,
+			identificacao, r.FormValue("nome"), r.FormValue("especie"), r.FormValue("raca"),
+			r.FormValue("sexo"), r.FormValue("categoria"), r.FormValue("lote"), nullData(nascimento), id, propriedadeID,
+		)
+		if err == nil {
+			if n, _ := res.RowsAffected(); n == 0 {
+				app.clientError(w, "Animal não encontrado nesta propriedade.")
+				return
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("❌ Erro ao salvar animal: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Animal salvo com sucesso.", "success")
+	app.ListaAnimais(w, r)
+}
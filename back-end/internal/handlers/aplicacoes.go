@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// carregarAplicacoesInsumos lista as aplicações com o filtro informado (sobre aplicacoes_insumos a)
+func (app *Application) carregarAplicacoesInsumos(filtro string, args ...any) ([]models.AplicacaoInsumo, error) {
+	rows, err := app.DB.Query(`
+		SELECT a.id, a.safra_id, a.talhao_id, t.nome, a.data, a.produto, COALESCE(a.principio_ativo, ''),
+		       a.dose, a.unidade_dose, a.area_aplicada, COALESCE(a.operador, ''), COALESCE(a.equipamento, ''),
+		       COALESCE(a.temperatura, 0), COALESCE(a.umidade_relativa, 0), COALESCE(a.vento_kmh, 0),
+		       COALESCE(a.clima, ''), COALESCE(a.intervalo_reentrada, 0), COALESCE(a.carencia_dias, 0),
+		       COALESCE(a.observacoes, '')
+		FROM aplicacoes_insumos a
+		JOIN talhoes t ON t.id = a.talhao_id
+		`+filtro+`
+		ORDER BY a.data DESC, a.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aplicacoes []models.AplicacaoInsumo
+	for rows.Next() {
+		var a models.AplicacaoInsumo
+		if err := rows.Scan(&a.ID, &a.SafraID, &a.TalhaoID, &a.TalhaoNome, &a.Data, &a.Produto, &a.PrincipioAtivo,
+			&a.Dose, &a.UnidadeDose, &a.AreaAplicada, &a.Operador, &a.Equipamento, &a.Temperatura,
+			&a.UmidadeRelativa, &a.VentoKmh, &a.Clima, &a.IntervaloReentrada, &a.CarenciaDias,
+			&a.Observacoes); err != nil {
+			return nil, err
+		}
+		aplicacoes = append(aplicacoes, a)
+	}
+	return aplicacoes, rows.Err()
+}
+
+// SalvarAplicacaoInsumo registra uma aplicação na safra, respeitando a
+// carência e o intervalo de reentrada em relação à colheita prevista
+func (app *Application) SalvarAplicacaoInsumo(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	safra, err := app.buscarSafra(formInt(r, "safra_id"))
+	if err != nil {
+		app.clientError(w, "Safra não encontrada.")
+		return
+	}
+
+	a := models.AplicacaoInsumo{
+		SafraID:            safra.ID,
+		TalhaoID:           safra.TalhaoID,
+		Produto:            strings.TrimSpace(r.FormValue("produto")),
+		PrincipioAtivo:     strings.TrimSpace(r.FormValue("principio_ativo")),
+		Dose:               formFloat(r, "dose"),
+		UnidadeDose:        r.FormValue("unidade_dose"),
+		AreaAplicada:       formFloat(r, "area_aplicada"),
+		Operador:           strings.TrimSpace(r.FormValue("operador")),
+		Equipamento:        strings.TrimSpace(r.FormValue("equipamento")),
+		Temperatura:        formFloat(r, "temperatura"),
+		UmidadeRelativa:    formFloat(r, "umidade_relativa"),
+		VentoKmh:           formFloat(r, "vento_kmh"),
+		Clima:              r.FormValue("clima"),
+		IntervaloReentrada: formInt(r, "intervalo_reentrada"),
+		CarenciaDias:       formInt(r, "carencia_dias"),
+		Observacoes:        r.FormValue("observacoes"),
+	}
+	// Insumo do estoque: preenche o produto e dá baixa na quantidade aplicada
+	var insumo models.Insumo
+	if id := formInt(r, "insumo_id"); id > 0 {
+		if insumo, err = app.buscarInsumo(id); err != nil || insumo.PropriedadeID != safra.PropriedadeID {
+			app.clientError(w, "Insumo não encontrado no estoque da propriedade.")
+			return
+		}
+		if a.Produto == "" {
+			a.Produto = insumo.Nome
+		}
+		if a.PrincipioAtivo == "" {
+			a.PrincipioAtivo = insumo.PrincipioAtivo
+		}
+	}
+	dia, ok := formData(r, "data")
+	if !ok || a.Produto == "" || a.Dose <= 0 {
+		app.clientError(w, "Informe a data, o produto e a dose por hectare.")
+		return
+	}
+	a.Data = dia
+	if hora, err := time.Parse("15:04", r.FormValue("hora")); err == nil {
+		a.Data = dia.Add(time.Duration(hora.Hour())*time.Hour + time.Duration(hora.Minute())*time.Minute)
+	}
+	valida := false
+	for _, u := range models.UnidadesDose {
+		if a.UnidadeDose == u {
+			valida = true
+		}
+	}
+	if !valida {
+		a.UnidadeDose = models.UnidadesDose[0]
+	}
+	if a.AreaAplicada <= 0 {
+		a.AreaAplicada = safra.AreaHectares
+	}
+	if a.AreaAplicada > safra.AreaHectares*1.001 {
+		app.clientError(w, "A área aplicada excede a área da safra.")
+		return
+	}
+	if a.IntervaloReentrada < 0 || a.CarenciaDias < 0 {
+		app.clientError(w, "Intervalos de reentrada e carência não podem ser negativos.")
+		return
+	}
+	if err := services.ValidarIntervalosAplicacao(a, safra); err != nil {
+		app.clientError(w, "Aplicação não permitida: "+err.Error()+".")
+		return
+	}
+
+	// Máquinas usadas: o horímetro avança pelas horas informadas ou pela
+	// área aplicada dividida pela capacidade operacional de cada uma
+	maquinas, err := app.carregarMaquinas("WHERE m.propriedade_id = ?", safra.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	selecionadas := map[string]bool{}
+	for _, id := range r.Form["maquinas"] {
+		selecionadas[id] = true
+	}
+	var usadas []models.Maquina
+	var nomes []string
+	for _, m := range maquinas {
+		if selecionadas[fmt.Sprint(m.ID)] {
+			usadas = append(usadas, m)
+			nomes = append(nomes, m.Nome)
+		}
+	}
+	if a.Equipamento == "" {
+		a.Equipamento = strings.Join(nomes, ", ")
+	}
+	horasInformadas := formFloat(r, "horas_maquina")
+	if horasInformadas < 0 {
+		app.clientError(w, "As horas de máquina não podem ser negativas.")
+		return
+	}
+	consumo := formFloat(r, "quantidade_estoque")
+	if insumo.ID > 0 && consumo <= 0 {
+		var convertido bool
+		if consumo, convertido = services.QuantidadeAplicada(a.Dose, a.UnidadeDose, a.AreaAplicada, insumo.Unidade); !convertido {
+			app.clientError(w, fmt.Sprintf("Informe a quantidade a baixar do estoque, em %s.", insumo.Unidade))
+			return
+		}
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var aplicacaoID int
+	err = tx.QueryRow(
+		`INSERT INTO aplicacoes_insumos
+		(safra_id, talhao_id, data, produto, principio_ativo, dose, unidade_dose, area_aplicada, operador,
+		 equipamento, temperatura, umidade_relativa, vento_kmh, clima, intervalo_reentrada, carencia_dias, observacoes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`,
+		a.SafraID, a.TalhaoID, a.Data, a.Produto, a.PrincipioAtivo, a.Dose, a.UnidadeDose, a.AreaAplicada,
+		a.Operador, a.Equipamento, a.Temperatura, a.UmidadeRelativa, a.VentoKmh, a.Clima,
+		a.IntervaloReentrada, a.CarenciaDias, a.Observacoes,
+	).Scan(&aplicacaoID)
+	if err != nil {
+		log.Printf("❌ Erro ao registrar aplicação: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+	var semHoras []string
+	for _, m := range usadas {
+		if m.Unidade != models.MedidorHoras {
+			continue
+		}
+		horas := horasInformadas
+		if horas == 0 {
+			horas = services.HorasAplicacao(a.AreaAplicada, m.CapacidadeOperacional)
+		}
+		if horas == 0 {
+			semHoras = append(semHoras, m.Nome)
+			continue
+		}
+		if err := avancarMedidor(tx, m, horas, dia, "aplicacao", aplicacaoID); err != nil {
+			log.Printf("❌ Erro ao avançar horímetro: %v", err)
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	if insumo.ID > 0 {
+		if err := baixarEstoque(tx, models.SaidaInsumo{
+			InsumoID:    insumo.ID,
+			Data:        dia,
+			Quantidade:  consumo,
+			Origem:      models.SaidaAplicacao,
+			AplicacaoID: aplicacaoID,
+			Descricao:   fmt.Sprintf("Aplicação – safra %s %s, %s", safra.Rotulo, safra.Cultura, safra.TalhaoNome),
+		}); err != nil {
+			log.Printf("❌ Erro ao baixar estoque: %v", err)
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	r.Form.Set("id", r.FormValue("safra_id"))
+	mensagem, tipo := "Aplicação registrada.", "success"
+	if len(semHoras) > 0 {
+		mensagem += " Horímetro não avançado (informe as horas ou a capacidade operacional): " + strings.Join(semHoras, ", ") + "."
+		tipo = "warning"
+	}
+	if insumo.ID > 0 {
+		if aviso := app.avisoSaldoNegativo(insumo.ID); aviso != "" {
+			mensagem += aviso
+			tipo = "warning"
+		}
+	}
+	setToast(w, mensagem, tipo)
+	app.DetalheSafra(w, r)
+}
@@ -13,6 +13,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/services"
 )
 
 type Application struct {
@@ -63,8 +65,9 @@ func (app *Application) ReloadTemplates() error {
     return date.Format(format)
 },
 "formatCurrency": func(value float64) string {
-		return "R$ "
+		return "R$ " + services.FormatarDecimal(value, 2)
 	},
+	"formatDecimal": services.FormatarDecimal,
 	"formatArea": func(area float64) string {
 		return " ha"
 	},	
@@ -94,15 +97,7 @@ func (app *Application) ReloadTemplates() error {
 		return phone
 	},
 	
-	"formatCPFCNPJ": func(doc string) string {
-		// Formatação de CPF/CNPJ
-		if len(doc) == 11 {
-			return fmt.Sprintf("%s.%s.%s-%s", doc[:3], doc[3:6], doc[6:9], doc[9:])
-		} else if len(doc) == 14 {
-			return fmt.Sprintf("%s.%s.%s/%s-%s", doc[:2], doc[2:5], doc[5:8], doc[8:12], doc[12:])
-		}
-		return doc
-	},
+	"formatCPFCNPJ": formatarDocumento,
 	
 	"truncate": func(s string, length int) string {
 		if len(s) <= length {
@@ -213,7 +208,150 @@ func (app *Application) Routes() http.Handler {
     mux.HandleFunc("/clientes/salvar", app.SalvarCliente)
     mux.HandleFunc("/clientes/detalhes", app.DetalhesCliente)
     mux.HandleFunc("/clientes/excluir", app.ExcluirCliente)
-    
+
+    // Rebanho e manejo reprodutivo
+    mux.HandleFunc("/animais", app.ListaAnimais)
+    mux.HandleFunc("/animais/salvar", app.SalvarAnimal)
+    mux.HandleFunc("/reproducao", app.PainelReproducao)
+    mux.HandleFunc("/reproducao/estacoes/salvar", app.SalvarEstacaoMonta)
+    mux.HandleFunc("/reproducao/protocolos/salvar", app.SalvarProtocoloIATF)
+    mux.HandleFunc("/reproducao/protocolos/agenda", app.AgendaProtocoloIATF)
+    mux.HandleFunc("/reproducao/inseminacoes/salvar", app.SalvarInseminacao)
+    mux.HandleFunc("/reproducao/diagnosticos/salvar", app.SalvarDiagnostico)
+    mux.HandleFunc("/reproducao/partos/salvar", app.SalvarParto)
+
+    // Sanidade animal
+    mux.HandleFunc("/sanidade", app.CalendarioSanitario)
+    mux.HandleFunc("/sanidade/aplicacoes/salvar", app.SalvarAplicacaoSanitaria)
+    mux.HandleFunc("/sanidade/calendario/agendar", app.AgendarEventoSanitario)
+    mux.HandleFunc("/sanidade/pendencias", app.PendenciasSanitarias)
+
+    // Produção leiteira
+    mux.HandleFunc("/leite", app.RelatorioLeiteiro)
+    mux.HandleFunc("/leite/controles/salvar", app.SalvarControleLeiteiro)
+    mux.HandleFunc("/leite/tanque/salvar", app.SalvarAnaliseTanque)
+
+    // Trânsito animal (GTA)
+    mux.HandleFunc("/movimentacoes", app.ListaMovimentacoes)
+    mux.HandleFunc("/movimentacoes/salvar", app.SalvarMovimentacao)
+    mux.HandleFunc("/movimentacoes/ajustar", app.AjustarEstoqueRebanho)
+    mux.HandleFunc("/movimentacoes/exportar", app.ExportarRastreabilidade)
+
+    // Talhões e pastejo rotacionado
+    mux.HandleFunc("/talhoes", app.ListaTalhoes)
+    mux.HandleFunc("/talhoes/salvar", app.SalvarTalhao)
+    mux.HandleFunc("/pastejo", app.PlanejadorPastejo)
+    mux.HandleFunc("/pastejo/piquetes/gerar", app.GerarPiquetes)
+    mux.HandleFunc("/pastejo/planos/salvar", app.SalvarPlanoPastejo)
+    mux.HandleFunc("/pastejo/ocupacoes/registrar", app.RegistrarOcupacao)
+    mux.HandleFunc("/pastejo/alertas", app.AlertasPastejo)
+
+    // Formulação de dietas (confinamento e suplementação)
+    mux.HandleFunc("/dietas", app.FormuladorDieta)
+    mux.HandleFunc("/dietas/ingredientes/salvar", app.SalvarIngrediente)
+    mux.HandleFunc("/dietas/ingredientes/referencia", app.ImportarIngredientesReferencia)
+    mux.HandleFunc("/dietas/formular", app.FormularDieta)
+
+    // Planejamento de safras
+    mux.HandleFunc("/safras", app.PlanejamentoSafras)
+    mux.HandleFunc("/safras/salvar", app.SalvarSafra)
+    mux.HandleFunc("/safras/detalhes", app.DetalheSafra)
+    mux.HandleFunc("/safras/realizado", app.RegistrarRealizadoSafra)
+    mux.HandleFunc("/safras/aplicacoes/salvar", app.SalvarAplicacaoInsumo)
+    mux.HandleFunc("/safras/colheitas/salvar", app.SalvarColheita)
+    mux.HandleFunc("/relatorios/produtividade", app.RelatorioProdutividade)
+
+    // Custos de produção, cotações e ROI
+    mux.HandleFunc("/custos/safra", app.CustosSafra)
+    mux.HandleFunc("/custos/lancamentos/salvar", app.SalvarLancamentoCusto)
+    mux.HandleFunc("/custos/lancamentos/excluir", app.ExcluirLancamentoCusto)
+    mux.HandleFunc("/custos/aplicacoes/importar", app.ImportarAplicacoesCusto)
+    mux.HandleFunc("/custos/configuracao", app.ConfiguracaoCustos)
+    mux.HandleFunc("/custos/categorias/salvar", app.SalvarCategoriaCusto)
+    mux.HandleFunc("/custos/categorias/padrao", app.ImportarCategoriasPadrao)
+    mux.HandleFunc("/custos/precos/importar", app.ImportarPrecos)
+    mux.HandleFunc("/relatorios/custos", app.RelatorioCustos)
+
+    // Consultas e receituário agronômico
+    mux.HandleFunc("/consultas/salvar", app.SalvarConsulta)
+    mux.HandleFunc("/consultas/receituario", app.ReceituarioConsulta)
+    mux.HandleFunc("/consultas/produtos/salvar", app.SalvarProdutoRecomendado)
+    mux.HandleFunc("/receituarios/emitir", app.EmitirReceituario)
+    mux.HandleFunc("/receituarios/pdf", app.BaixarReceituario)
+
+    // Análises de solo, amostragem, mapas de fertilidade, prescrição em taxa variável e comparativo entre safras
+    mux.HandleFunc("/talhoes/solo", app.AnalisesSoloTalhao)
+    mux.HandleFunc("/talhoes/solo/salvar", app.SalvarAnaliseSolo)
+    mux.HandleFunc("/talhoes/amostragem", app.AmostragemTalhao)
+    mux.HandleFunc("/talhoes/amostragem/gerar", app.GerarGradeAmostragem)
+    mux.HandleFunc("/talhoes/amostragem/excluir", app.ExcluirGradeAmostragem)
+    mux.HandleFunc("/talhoes/amostragem/exportar", app.ExportarGradeAmostragem)
+    mux.HandleFunc("/talhoes/amostragem/resultados", app.ImportarResultadosAmostragem)
+    mux.HandleFunc("/talhoes/fertilidade", app.MapaFertilidade)
+    mux.HandleFunc("/talhoes/fertilidade/png", app.ImagemFertilidade)
+    mux.HandleFunc("/talhoes/prescricao", app.PrescricaoTalhao)
+    mux.HandleFunc("/talhoes/prescricao/png", app.ImagemPrescricao)
+    mux.HandleFunc("/talhoes/prescricao/exportar", app.ExportarPrescricao)
+    mux.HandleFunc("/relatorios/comparativo", app.RelatorioComparativo)
+
+    // Chuvas e previsão de colheita
+    mux.HandleFunc("/chuvas/salvar", app.SalvarChuva)
+    mux.HandleFunc("/relatorios/previsao", app.RelatorioPrevisao)
+
+    // Manejo integrado de pragas (MIP)
+    mux.HandleFunc("/mip", app.MonitoramentoSafra)
+    mux.HandleFunc("/mip/monitoramentos/salvar", app.SalvarMonitoramento)
+    mux.HandleFunc("/mip/niveis", app.ConfiguracaoMIP)
+    mux.HandleFunc("/mip/niveis/salvar", app.SalvarNivelAcao)
+    mux.HandleFunc("/mip/niveis/padrao", app.ImportarNiveisPadrao)
+    mux.HandleFunc("/mip/alertas", app.AlertasPragas)
+
+    // Irrigação e clima
+    mux.HandleFunc("/irrigacao", app.IrrigacaoSafra)
+    mux.HandleFunc("/irrigacao/configuracao/salvar", app.SalvarConfigIrrigacao)
+    mux.HandleFunc("/irrigacao/registrar", app.RegistrarIrrigacao)
+    mux.HandleFunc("/clima", app.ClimaPropriedade)
+    mux.HandleFunc("/clima/importar", app.ImportarClima)
+    mux.HandleFunc("/chuvas/grafico", app.GraficoChuvas)
+    mux.HandleFunc("/chuvas/preencher", app.PreencherChuvas)
+
+    // Calendário agregado e feed iCalendar por consultor
+    mux.HandleFunc("/calendario", app.Calendario)
+    mux.HandleFunc("/calendario/consultores/salvar", app.SalvarConsultor)
+    mux.HandleFunc("/calendario/consultores/token", app.RenovarTokenConsultor)
+    mux.HandleFunc("/calendario/consultores/clientes", app.AtribuirClientesConsultor)
+    mux.HandleFunc("/calendario/feed/", app.FeedCalendario)
+
+    // Máquinas e manutenção
+    mux.HandleFunc("/maquinas", app.MaquinasPropriedade)
+    mux.HandleFunc("/maquinas/salvar", app.SalvarMaquina)
+    mux.HandleFunc("/maquinas/detalhes", app.DetalheMaquina)
+    mux.HandleFunc("/maquinas/planos/salvar", app.SalvarPlanoManutencao)
+    mux.HandleFunc("/maquinas/manutencoes/salvar", app.RegistrarManutencao)
+    mux.HandleFunc("/maquinas/leituras/salvar", app.RegistrarLeituraMedidor)
+    mux.HandleFunc("/maquinas/alertas", app.AlertasManutencao)
+
+    // Estoque de insumos por lote e validade
+    mux.HandleFunc("/estoque", app.EstoquePropriedade)
+    mux.HandleFunc("/estoque/insumos/salvar", app.SalvarInsumo)
+    mux.HandleFunc("/estoque/insumo", app.DetalheInsumo)
+    mux.HandleFunc("/estoque/entradas/salvar", app.RegistrarEntradaInsumo)
+    mux.HandleFunc("/estoque/saidas/salvar", app.RegistrarSaidaInsumo)
+    mux.HandleFunc("/estoque/alertas", app.AlertasEstoque)
+
+    // Localização e limites da propriedade
+    mux.HandleFunc("/propriedades/localizacao", app.LocalizacaoPropriedade)
+    mux.HandleFunc("/propriedades/sede/salvar", app.SalvarSedePropriedade)
+    mux.HandleFunc("/propriedades/limite/salvar", app.SalvarLimitePropriedade)
+    mux.HandleFunc("/propriedades/limites/importar", app.ImportarLimites)
+    mux.HandleFunc("/propriedades/limites/confirmar", app.ConfirmarImportacaoLimites)
+
+    // Mapa da carteira e API GeoJSON
+    mux.HandleFunc("/mapa", app.MapaCarteira)
+    mux.HandleFunc("/api/geo/propriedades", app.GeoPropriedades)
+    mux.HandleFunc("/api/geo/talhoes", app.GeoTalhoes)
+    mux.HandleFunc("/api/geo/amostras", app.GeoAmostras)
+
     // Rota para recarregar templates em desenvolvimento
     if app.Env == "development" {
         mux.HandleFunc("/reload-templates", app.ReloadTemplatesHandler)
@@ -0,0 +1,586 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// Janela do feed iCalendar: histórico recente e compromissos do próximo ano
+const (
+	diasFeedPassado = 90
+	diasFeedFuturo  = 365
+)
+
+// filtroCalendario restringe os eventos ao período (fim inclusivo) e,
+// quando informados, ao cliente e ao consultor. Os eventos das propriedades
+// pertencem ao consultor responsável pelo cliente.
+type filtroCalendario struct {
+	Inicio      time.Time
+	Fim         time.Time
+	ClienteID   int
+	ConsultorID int
+}
+
+// condicoes monta o WHERE do filtro. Com várias colunas de data, basta uma
+// delas cair no período; sem colunas de data, só cliente e consultor filtram.
+func (f filtroCalendario) condicoes(colunaCliente, colunaConsultor string, colunasData ...string) (string, []any) {
+	var periodo, condicoes []string
+	var args []any
+	for _, coluna := range colunasData {
+		periodo = append(periodo, fmt.Sprintf("(%s >= ? AND %s < ?)", coluna, coluna))
+		args = append(args, f.Inicio, f.Fim.AddDate(0, 0, 1))
+	}
+	if len(periodo) > 0 {
+		condicoes = append(condicoes, "("+strings.Join(periodo, " OR ")+")")
+	}
+	if f.ClienteID > 0 {
+		condicoes = append(condicoes, colunaCliente+" = ?")
+		args = append(args, f.ClienteID)
+	}
+	if f.ConsultorID > 0 {
+		condicoes = append(condicoes, colunaConsultor+" = ?")
+		args = append(args, f.ConsultorID)
+	}
+	if len(condicoes) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(condicoes, " AND "), args
+}
+
+// noPeriodo indica se a data cai no período do filtro
+func (f filtroCalendario) noPeriodo(t time.Time) bool {
+	return !t.Before(f.Inicio) && t.Before(f.Fim.AddDate(0, 0, 1))
+}
+
+// eventosCalendario reúne os eventos de todos os módulos que alimentam o calendário
+func (app *Application) eventosCalendario(f filtroCalendario) ([]models.EventoCalendario, error) {
+	fontes := []func(filtroCalendario) ([]models.EventoCalendario, error){
+		app.eventosConsultas,
+		app.eventosSafras,
+		app.eventosSanitarios,
+		app.eventosAplicacoes,
+		app.eventosManutencoes,
+	}
+	var eventos []models.EventoCalendario
+	for _, fonte := range fontes {
+		e, err := fonte(f)
+		if err != nil {
+			return nil, err
+		}
+		eventos = append(eventos, e...)
+	}
+	services.OrdenarEventos(eventos)
+	return eventos, nil
+}
+
+// eventosConsultas lista as consultas; sem consultor informado na consulta,
+// vale o responsável pelo cliente
+func (app *Application) eventosConsultas(f filtroCalendario) ([]models.EventoCalendario, error) {
+	filtro, args := f.condicoes("c.cliente_id", "COALESCE(c.consultor_id, cl.consultor_id)", "c.data_consulta")
+	rows, err := app.DB.Query(`
+		SELECT c.id, c.data_consulta, COALESCE(c.tipo_consulta, ''), COALESCE(c.observacoes, ''),
+		       c.cliente_id, cl.nome, COALESCE(p.nome, ''), COALESCE(co.nome, '')
+		FROM consultas c
+		JOIN clientes cl ON cl.id = c.cliente_id
+		LEFT JOIN propriedades p ON p.id = c.propriedade_id
+		LEFT JOIN consultores co ON co.id = COALESCE(c.consultor_id, cl.consultor_id)
+		`+filtro, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hoje := horaLocal().Truncate(24 * time.Hour)
+	var eventos []models.EventoCalendario
+	for rows.Next() {
+		var id int
+		var tipo string
+		e := models.EventoCalendario{Tipo: models.CalendarioConsulta, DiaInteiro: true}
+		if err := rows.Scan(&id, &e.Inicio, &tipo, &e.Descricao, &e.ClienteID, &e.ClienteNome,
+			&e.PropriedadeNome, &e.ConsultorNome); err != nil {
+			return nil, err
+		}
+		e.UID = fmt.Sprintf("consulta-%d", id)
+		e.Titulo = "Consulta"
+		if tipo != "" {
+			e.Titulo += " (" + tipo + ")"
+		}
+		e.Titulo += " – " + e.ClienteNome
+		e.Concluido = e.Inicio.Before(hoje)
+		e.Link = fmt.Sprintf("/consultas/receituario?consulta_id=%d", id)
+		eventos = append(eventos, e)
+	}
+	return eventos, rows.Err()
+}
+
+// eventosSafras gera o plantio e a colheita de cada safra: a data prevista
+// enquanto não há registro e a data real depois dele
+func (app *Application) eventosSafras(f filtroCalendario) ([]models.EventoCalendario, error) {
+	filtro, args := f.condicoes("cl.id", "cl.consultor_id",
+		"s.plantio_previsto", "s.colheita_prevista", "s.plantio_real", "s.colheita_real")
+	rows, err := app.DB.Query(`
+		SELECT s.id, s.rotulo, s.cultura, s.area_hectares, t.nome, p.nome, cl.id, cl.nome, COALESCE(co.nome, ''),
+		       s.plantio_previsto, s.colheita_prevista, s.plantio_real, s.colheita_real
+		FROM safras s
+		JOIN talhoes t ON t.id = s.talhao_id
+		JOIN propriedades p ON p.id = t.propriedade_id
+		JOIN clientes cl ON cl.id = p.cliente_id
+		LEFT JOIN consultores co ON co.id = cl.consultor_id
+		`+filtro, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var eventos []models.EventoCalendario
+	for rows.Next() {
+		var id int
+		var rotulo, cultura, talhao string
+		var area float64
+		var base models.EventoCalendario
+		var plantioPrevisto, colheitaPrevista time.Time
+		var plantioReal, colheitaReal *time.Time
+		if err := rows.Scan(&id, &rotulo, &cultura, &area, &talhao, &base.PropriedadeNome, &base.ClienteID,
+			&base.ClienteNome, &base.ConsultorNome, &plantioPrevisto, &colheitaPrevista, &plantioReal, &colheitaReal); err != nil {
+			return nil, err
+		}
+		base.DiaInteiro = true
+		base.Link = fmt.Sprintf("/safras/detalhes?id=%d", id)
+		base.Descricao = fmt.Sprintf("%s %s – %s (%s ha)", cultura, rotulo, talhao, services.FormatarDecimal(area, 1))
+
+		etapas := []struct {
+			tipo, realizado, previsto string
+			prevista                  time.Time
+			real                      *time.Time
+		}{
+			{models.CalendarioPlantio, "Plantio", "Plantio previsto", plantioPrevisto, plantioReal},
+			{models.CalendarioColheita, "Colheita", "Colheita prevista", colheitaPrevista, colheitaReal},
+		}
+		for _, etapa := range etapas {
+			e := base
+			e.Tipo = etapa.tipo
+			e.UID = fmt.Sprintf("%s-%d", etapa.tipo, id)
+			e.Inicio = etapa.prevista
+			e.Titulo = etapa.previsto
+			if etapa.real != nil {
+				e.Inicio = *etapa.real
+				e.Titulo = etapa.realizado
+				e.Concluido = true
+			}
+			if !f.noPeriodo(e.Inicio) {
+				continue
+			}
+			e.Titulo += fmt.Sprintf(" – %s (%s)", cultura, talhao)
+			eventos = append(eventos, e)
+		}
+	}
+	return eventos, rows.Err()
+}
+
+// eventosSanitarios lista os vencimentos do calendário sanitário
+func (app *Application) eventosSanitarios(f filtroCalendario) ([]models.EventoCalendario, error) {
+	filtro, args := f.condicoes("cl.id", "cl.consultor_id", "c.data_prevista")
+	rows, err := app.DB.Query(`
+		SELECT c.id, c.propriedade_id, c.tipo, COALESCE(c.alvo, ''), COALESCE(c.produto, ''), COALESCE(c.lote, ''),
+		       COALESCE(a.identificacao, ''), c.data_prevista, c.realizado_em, p.nome, cl.id, cl.nome, COALESCE(co.nome, '')
+		FROM calendario_sanitario c
+		JOIN propriedades p ON p.id = c.propriedade_id
+		JOIN clientes cl ON cl.id = p.cliente_id
+		LEFT JOIN animais a ON a.id = c.animal_id
+		LEFT JOIN consultores co ON co.id = cl.consultor_id
+		`+filtro, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var eventos []models.EventoCalendario
+	for rows.Next() {
+		var id, propriedadeID int
+		var tipo, alvo, produto, lote, brinco string
+		var realizado *time.Time
+		e := models.EventoCalendario{Tipo: models.CalendarioSanitario, DiaInteiro: true}
+		if err := rows.Scan(&id, &propriedadeID, &tipo, &alvo, &produto, &lote, &brinco, &e.Inicio, &realizado,
+			&e.PropriedadeNome, &e.ClienteID, &e.ClienteNome, &e.ConsultorNome); err != nil {
+			return nil, err
+		}
+		e.UID = fmt.Sprintf("sanidade-%d", id)
+		e.Titulo = tipo
+		if alvo != "" {
+			e.Titulo += " – " + alvo
+		}
+		var detalhes []string
+		if produto != "" {
+			detalhes = append(detalhes, "Produto: "+produto)
+		}
+		if lote != "" {
+			detalhes = append(detalhes, "Lote: "+lote)
+		}
+		if brinco != "" {
+			detalhes = append(detalhes, "Animal: "+brinco)
+		}
+		e.Descricao = strings.Join(detalhes, "\n")
+		e.Concluido = realizado != nil
+		e.Link = fmt.Sprintf("/sanidade?propriedade_id=%d", propriedadeID)
+		eventos = append(eventos, e)
+	}
+	return eventos, rows.Err()
+}
+
+// eventosAplicacoes lista as aplicações de insumos realizadas nas safras
+func (app *Application) eventosAplicacoes(f filtroCalendario) ([]models.EventoCalendario, error) {
+	filtro, args := f.condicoes("cl.id", "cl.consultor_id", "a.data")
+	rows, err := app.DB.Query(`
+		SELECT a.id, a.safra_id, a.data, a.produto, a.dose, a.unidade_dose, a.area_aplicada,
+		       COALESCE(a.carencia_dias, 0), COALESCE(a.operador, ''), t.nome, p.nome, cl.id, cl.nome, COALESCE(co.nome, '')
+		FROM aplicacoes_insumos a
+		JOIN talhoes t ON t.id = a.talhao_id
+		JOIN propriedades p ON p.id = t.propriedade_id
+		JOIN clientes cl ON cl.id = p.cliente_id
+		LEFT JOIN consultores co ON co.id = cl.consultor_id
+		`+filtro, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var eventos []models.EventoCalendario
+	for rows.Next() {
+		var id, safraID, carencia int
+		var produto, unidade, operador, talhao string
+		var dose, area float64
+		e := models.EventoCalendario{Tipo: models.CalendarioAplicacao, Concluido: true}
+		if err := rows.Scan(&id, &safraID, &e.Inicio, &produto, &dose, &unidade, &area, &carencia, &operador,
+			&talhao, &e.PropriedadeNome, &e.ClienteID, &e.ClienteNome, &e.ConsultorNome); err != nil {
+			return nil, err
+		}
+		e.UID = fmt.Sprintf("aplicacao-%d", id)
+		e.Titulo = fmt.Sprintf("Aplicação – %s (%s)", produto, talhao)
+		// Sem hora registrada, a aplicação ocupa o dia inteiro
+		e.DiaInteiro = e.Inicio.Equal(e.Inicio.Truncate(24 * time.Hour))
+		detalhes := []string{fmt.Sprintf("Dose: %s %s em %s ha", services.FormatarDecimal(dose, 2), unidade,
+			services.FormatarDecimal(area, 1))}
+		if carencia > 0 {
+			detalhes = append(detalhes, "Carência até "+e.Inicio.AddDate(0, 0, carencia).Format("02/01/2006"))
+		}
+		if operador != "" {
+			detalhes = append(detalhes, "Operador: "+operador)
+		}
+		e.Descricao = strings.Join(detalhes, "\n")
+		e.Link = fmt.Sprintf("/safras/detalhes?id=%d", safraID)
+		eventos = append(eventos, e)
+	}
+	return eventos, rows.Err()
+}
+
+// carregarConsultores lista os consultores cadastrados
+func (app *Application) carregarConsultores(apenasAtivos bool) ([]models.Consultor, error) {
+	filtro := ""
+	if apenasAtivos {
+		filtro = "WHERE COALESCE(ativo, true)"
+	}
+	rows, err := app.DB.Query(`
+		SELECT id, nome, COALESCE(email, ''), COALESCE(telefone, ''), token, COALESCE(ativo, true)
+		FROM consultores
+		` + filtro + `
+		ORDER BY nome`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var consultores []models.Consultor
+	for rows.Next() {
+		var c models.Consultor
+		if err := rows.Scan(&c.ID, &c.Nome, &c.Email, &c.Telefone, &c.Token, &c.Ativo); err != nil {
+			return nil, err
+		}
+		consultores = append(consultores, c)
+	}
+	return consultores, rows.Err()
+}
+
+// carregarOpcoesClientes lista id, nome e consultor responsável dos clientes
+// ativos para os filtros
+func (app *Application) carregarOpcoesClientes() ([]Cliente, error) {
+	rows, err := app.DB.Query(`
+		SELECT id, nome, COALESCE(consultor_id, 0)
+		FROM clientes
+		WHERE COALESCE(ativo, true)
+		ORDER BY nome`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clientes []Cliente
+	for rows.Next() {
+		var c Cliente
+		if err := rows.Scan(&c.ID, &c.Nome, &c.ConsultorID); err != nil {
+			return nil, err
+		}
+		clientes = append(clientes, c)
+	}
+	return clientes, rows.Err()
+}
+
+// gerarTokenFeed sorteia o identificador secreto da URL do feed
+func gerarTokenFeed() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// urlFeed monta o endereço absoluto do feed do consultor
+func urlFeed(r *http.Request, token string) string {
+	esquema := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		esquema = "https"
+	}
+	return fmt.Sprintf("%s://%s/calendario/feed/%s.ics", esquema, r.Host, token)
+}
+
+// Calendario exibe os eventos agregados em visão mensal, semanal ou de
+// agenda, filtrados por consultor e cliente
+func (app *Application) Calendario(w http.ResponseWriter, r *http.Request) {
+	visao := r.FormValue("visao")
+	if visao != services.VisaoSemana && visao != services.VisaoAgenda {
+		visao = services.VisaoMes
+	}
+	hoje := horaLocal().Truncate(24 * time.Hour)
+	ref, ok := formData(r, "data")
+	if !ok {
+		ref = hoje
+	}
+
+	filtro := filtroCalendario{ClienteID: formInt(r, "cliente_id"), ConsultorID: formInt(r, "consultor_id")}
+	filtro.Inicio, filtro.Fim = services.PeriodoCalendario(visao, ref)
+	eventos, err := app.eventosCalendario(filtro)
+	if err != nil {
+		log.Printf("❌ Erro ao carregar eventos do calendário: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+	consultores, err := app.carregarConsultores(false)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	clientes, err := app.carregarOpcoesClientes()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	var mes time.Month
+	if visao == services.VisaoMes {
+		mes = ref.Month()
+	}
+	dias := services.MontarDiasCalendario(filtro.Inicio, filtro.Fim, mes, eventos, hoje)
+	anterior, proximo := services.NavegarCalendario(visao, ref)
+
+	atrasados := 0
+	for _, e := range eventos {
+		if e.Atrasado(hoje) {
+			atrasados++
+		}
+	}
+
+	var consultor models.Consultor
+	feed := ""
+	for _, c := range consultores {
+		if c.ID == filtro.ConsultorID {
+			consultor = c
+			feed = urlFeed(r, c.Token)
+		}
+	}
+
+	data := map[string]interface{}{
+		"Visao":       visao,
+		"Referencia":  ref,
+		"Periodo":     services.TituloPeriodo(visao, ref),
+		"Anterior":    anterior,
+		"Proximo":     proximo,
+		"Hoje":        hoje,
+		"Semanas":     services.SemanasCalendario(dias),
+		"Dias":        dias,
+		"Agenda":      services.DiasComEventos(dias),
+		"Total":       len(eventos),
+		"Atrasados":   atrasados,
+		"Consultores": consultores,
+		"Clientes":    clientes,
+		"ConsultorID": filtro.ConsultorID,
+		"ClienteID":   filtro.ClienteID,
+		"Consultor":   consultor,
+		"FeedURL":     feed,
+		"FeedWebcal":  strings.Replace(strings.Replace(feed, "https://", "webcal://", 1), "http://", "webcal://", 1),
+		"Title":       "Calendário",
+	}
+	app.renderTemplate(w, r, "calendario/calendario.html", data)
+}
+
+// SalvarConsultor cadastra um consultor e gera o token do seu feed
+func (app *Application) SalvarConsultor(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	nome := strings.TrimSpace(r.FormValue("nome"))
+	if nome == "" {
+		app.clientError(w, "Informe o nome do consultor.")
+		return
+	}
+	token, err := gerarTokenFeed()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	var id int
+	err = app.DB.QueryRow(
+		`INSERT INTO consultores (nome, email, telefone, token) VALUES (?, ?, ?, ?) RETURNING id`,
+		nome, strings.TrimSpace(r.FormValue("email")), strings.TrimSpace(r.FormValue("telefone")), token,
+	).Scan(&id)
+	if err != nil {
+		log.Printf("❌ Erro ao salvar consultor: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	r.Form.Set("consultor_id", fmt.Sprint(id))
+	setToast(w, "Consultor cadastrado. Assine o calendário pelo link do feed.", "success")
+	app.Calendario(w, r)
+}
+
+// RenovarTokenConsultor troca o token do feed, invalidando a URL anterior
+// (por exemplo, quando o celular foi perdido)
+func (app *Application) RenovarTokenConsultor(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	token, err := gerarTokenFeed()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	res, err := app.DB.Exec(`UPDATE consultores SET token = ? WHERE id = ?`, token, formInt(r, "consultor_id"))
+	if err != nil {
+		log.Printf("❌ Erro ao renovar token do consultor: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		app.clientError(w, "Consultor não encontrado.")
+		return
+	}
+
+	setToast(w, "Link do feed renovado. Assine novamente no celular.", "warning")
+	app.Calendario(w, r)
+}
+
+// AtribuirClientesConsultor define a carteira do consultor: os clientes
+// marcados passam a ser de sua responsabilidade e os desmarcados ficam sem
+// responsável. Só a coluna consultor_id é alterada, pois o DuckDB recusa
+// atualizar colunas indexadas de clientes referenciados por propriedades.
+func (app *Application) AtribuirClientesConsultor(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	consultorID := formInt(r, "consultor_id")
+	if consultorID == 0 {
+		app.clientError(w, "Selecione o consultor.")
+		return
+	}
+	marcados := map[int]bool{}
+	for _, v := range r.Form["clientes"] {
+		var id int
+		if _, err := fmt.Sscan(v, &id); err == nil {
+			marcados[id] = true
+		}
+	}
+	clientes, err := app.carregarOpcoesClientes()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+	for _, c := range clientes {
+		switch {
+		case marcados[c.ID] && c.ConsultorID != consultorID:
+			_, err = tx.Exec(`UPDATE clientes SET consultor_id = ? WHERE id = ?`, consultorID, c.ID)
+		case !marcados[c.ID] && c.ConsultorID == consultorID:
+			_, err = tx.Exec(`UPDATE clientes SET consultor_id = NULL WHERE id = ?`, c.ID)
+		}
+		if err != nil {
+			log.Printf("❌ Erro ao atribuir clientes ao consultor: %v", err)
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, fmt.Sprintf("Carteira atualizada: %d cliente(s).", len(marcados)), "success")
+	app.Calendario(w, r)
+}
+
+// FeedCalendario publica o iCalendar do consultor em
+// /calendario/feed/{token}.ics. A URL não exige login para que os
+// aplicativos de agenda consigam atualizá-la; o token é o segredo.
+func (app *Application) FeedCalendario(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/calendario/feed/"), ".ics")
+	if token == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var consultor models.Consultor
+	err := app.DB.QueryRow(
+		`SELECT id, nome FROM consultores WHERE token = ? AND COALESCE(ativo, true)`, token,
+	).Scan(&consultor.ID, &consultor.Nome)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	hoje := horaLocal().Truncate(24 * time.Hour)
+	eventos, err := app.eventosCalendario(filtroCalendario{
+		Inicio:      hoje.AddDate(0, 0, -diasFeedPassado),
+		Fim:         hoje.AddDate(0, 0, diasFeedFuturo),
+		ConsultorID: consultor.ID,
+	})
+	if err != nil {
+		log.Printf("❌ Erro ao gerar feed do calendário: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="agenda.ics"`)
+	w.Write([]byte(services.GerarICS("AgroConsultoria – "+consultor.Nome, eventos, time.Now())))
+}
@@ -21,6 +21,7 @@ type Cliente struct {
 	Estado       string    `json:"estado"`
 	Observacoes  string    `json:"observacoes"`
 	Ativo        bool      `json:"ativo"`
+	ConsultorID  int       `json:"consultor_id"`
 }
 
 type ClienteResumo struct {
@@ -184,8 +185,8 @@ func (app *Application) FormCliente(w http.ResponseWriter, r *http.Request) {
             return
         }
 
-        row := app.DB.QueryRow("SELECT id, nome, email, telefone, cpf_cnpj FROM clientes WHERE id = ?", id)
-        err = row.Scan(&cliente.ID, &cliente.Nome, &cliente.Email, &cliente.Telefone, &cliente.CpfCnpj)
+        row := app.DB.QueryRow("SELECT id, nome, email, telefone, cpf_cnpj, COALESCE(consultor_id, 0) FROM clientes WHERE id = ?", id)
+        err = row.Scan(&cliente.ID, &cliente.Nome, &cliente.Email, &cliente.Telefone, &cliente.CpfCnpj, &cliente.ConsultorID)
         if err != nil && err != sql.ErrNoRows {
             app.serverError(w, r, err)
             return
@@ -198,10 +199,17 @@ func (app *Application) FormCliente(w http.ResponseWriter, r *http.Request) {
     // Lista de estados para o select
     estados := []string{"AC", "AL", "AP", "AM", "BA", "CE", "DF", "ES", "GO", "MA", "MT", "MS", "MG", "PA", "PB", "PR", "PE", "PI", "RJ", "RN", "RS", "RO", "RR", "SC", "SP", "SE", "TO"}
 
+    consultores, err := app.carregarConsultores(true)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
     data := map[string]interface{}{
-        "Cliente": cliente,
-        "Estados": estados,
-        "Title":   title,
+        "Cliente":     cliente,
+        "Estados":     estados,
+        "Consultores": consultores,
+        "Title":       title,
     }
 
   log.Printf("🔍 DEBUG: FormCliente chamado, renderizando clientes/formulario.html")
@@ -223,6 +231,7 @@ func (app *Application) SalvarCliente(w http.ResponseWriter, r *http.Request) {
     cidade := r.Form.Get("cidade")
     estado := r.Form.Get("estado")
     observacoes := r.Form.Get("observacoes")
+    consultorID := nullInt(formInt(r, "consultor_id"))
 
     var result sql.Result
     var err error
@@ -231,9 +240,9 @@ func (app *Application) SalvarCliente(w http.ResponseWriter, r *http.Request) {
         // Inserir novo cliente
         result, err = app.DB.Exec(
             `INSERT INTO clientes 
-            (nome, email, telefone, cpf_cnpj, endereco, cidade, estado, observacoes) 
-            VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-            nome, email, telefone, cpfCnpj, endereco, cidade, estado, observacoes,
+            (nome, email, telefone, cpf_cnpj, endereco, cidade, estado, observacoes, consultor_id) 
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+            nome, email, telefone, cpfCnpj, endereco, cidade, estado, observacoes, consultorID,
         )
         if err != nil {
             log.Printf("❌ Erro ao inserir cliente: %v", err)
@@ -275,9 +284,9 @@ func (app *Application) SalvarCliente(w http.ResponseWriter, r *http.Request) {
         result, err = app.DB.Exec(
             `UPDATE clientes SET 
             nome=?, email=?, telefone=?, cpf_cnpj=?, 
-            endereco=?, cidade=?, estado=?, observacoes=? 
+            endereco=?, cidade=?, estado=?, observacoes=?, consultor_id=? 
             WHERE id=?`,
-            nome, email, telefone, cpfCnpj, endereco, cidade, estado, observacoes, idInt,
+            nome, email, telefone, cpfCnpj, endereco, cidade, estado, observacoes, consultorID, idInt,
         )
         if err != nil {
             log.Printf("❌ Erro ao atualizar cliente: %v", err)
@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// Dias exibidos na tabela de dados meteorológicos da propriedade
+const diasTabelaClima = 60
+
+// carregarClimaDiario lista os dados meteorológicos com o filtro informado (sobre clima_diario c)
+func (app *Application) carregarClimaDiario(filtro string, args ...any) ([]models.ClimaDiario, error) {
+	rows, err := app.DB.Query(`
+		SELECT c.id, c.propriedade_id, c.data, COALESCE(c.temp_maxima, 0), COALESCE(c.temp_minima, 0),
+		       COALESCE(c.umidade_relativa, 0), COALESCE(c.vento_ms, 0), COALESCE(c.radiacao, 0), COALESCE(c.eto_informada, 0)
+		FROM clima_diario c
+		`+filtro+`
+		ORDER BY c.data DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dias []models.ClimaDiario
+	for rows.Next() {
+		var d models.ClimaDiario
+		if err := rows.Scan(&d.ID, &d.PropriedadeID, &d.Data, &d.TempMaxima, &d.TempMinima,
+			&d.UmidadeRelativa, &d.VentoMS, &d.Radiacao, &d.ETOInformada); err != nil {
+			return nil, err
+		}
+		dias = append(dias, d)
+	}
+	return dias, rows.Err()
+}
+
+// localizacaoClima devolve a latitude e a altitude usadas no cálculo da ET0
+// da propriedade, tiradas do primeiro talhão irrigado configurado
+func (app *Application) localizacaoClima(propriedadeID int) (float64, float64, error) {
+	latitude, altitude := configIrrigacaoPadrao.Latitude, configIrrigacaoPadrao.Altitude
+	err := app.DB.QueryRow(`
+		SELECT COALESCE(c.latitude, 0), COALESCE(c.altitude, 0)
+		FROM config_irrigacao c
+		JOIN talhoes t ON t.id = c.talhao_id
+		WHERE t.propriedade_id = ?
+		ORDER BY c.id
+		LIMIT 1`, propriedadeID).Scan(&latitude, &altitude)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, 0, err
+	}
+	return latitude, altitude, nil
+}
+
+// climaDia é o dia da tabela meteorológica: leitura da estação, ET0
+// calculada e chuva registrada
+type climaDia struct {
+	models.ClimaDiario
+	TemClima bool
+	ETo      float64
+	TemETo   bool
+	Chuva    float64
+	TemChuva bool
+}
+
+// ClimaPropriedade exibe os dados meteorológicos e as chuvas dos últimos
+// dias da propriedade, os dias sem leitura do pluviômetro e a importação
+func (app *Application) ClimaPropriedade(w http.ResponseWriter, r *http.Request) {
+	propriedade, err := app.buscarPropriedade(formInt(r, "propriedade_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	latitude, altitude, err := app.localizacaoClima(propriedade.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	hoje := horaLocal().Truncate(24 * time.Hour)
+	inicio := hoje.AddDate(0, 0, -(diasTabelaClima - 1))
+	clima, err := app.carregarClimaDiario("WHERE c.propriedade_id = ? AND c.data >= ?", propriedade.ID, inicio)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	chuvas, err := app.carregarChuvas("WHERE c.propriedade_id = ? AND c.data >= ?", propriedade.ID, inicio)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	porDia := make(map[string]*climaDia)
+	for _, c := range clima {
+		d := &climaDia{ClimaDiario: c, TemClima: true}
+		d.ETo, d.TemETo = services.ETo(c, latitude, altitude)
+		porDia[c.Data.Format("2006-01-02")] = d
+	}
+	for _, c := range chuvas {
+		chave := c.Data.Format("2006-01-02")
+		d, ok := porDia[chave]
+		if !ok {
+			d = &climaDia{ClimaDiario: models.ClimaDiario{Data: c.Data}}
+			porDia[chave] = d
+		}
+		d.Chuva += c.Milimetros
+		d.TemChuva = true
+	}
+	var dias []climaDia
+	for d := hoje; !d.Before(inicio); d = d.AddDate(0, 0, -1) {
+		if dia, ok := porDia[d.Format("2006-01-02")]; ok {
+			dias = append(dias, *dia)
+		}
+	}
+
+	semLeitura := services.DiasSemChuvaRegistrada(chuvas, inicio, hoje)
+	data := map[string]interface{}{
+		"Propriedade":  propriedade,
+		"Dias":         dias,
+		"SemLeitura":   semLeitura,
+		"DiasTabela":   diasTabelaClima,
+		"InicioTabela": inicio,
+		"Latitude":     latitude,
+		"Altitude":     altitude,
+		"SafraID":      r.FormValue("safra_id"),
+		"Title":        "Dados Meteorológicos",
+	}
+	app.renderTemplate(w, r, "clima/propriedade.html", data)
+}
+
+// GraficoChuvas exibe os gráficos de chuva mensal e acumulada da
+// propriedade no período escolhido (fragmento carregado nas páginas)
+func (app *Application) GraficoChuvas(w http.ResponseWriter, r *http.Request) {
+	propriedadeID := formInt(r, "propriedade_id")
+	hoje := horaLocal()
+	periodo := r.FormValue("periodo")
+	var inicio time.Time
+	switch periodo {
+	case "ano-agricola":
+		// Ano agrícola de julho a junho, como nos boletins de chuva do Centro-Oeste
+		ano := hoje.Year()
+		if hoje.Month() < time.July {
+			ano--
+		}
+		inicio = time.Date(ano, time.July, 1, 0, 0, 0, 0, time.UTC)
+	case "24m":
+		inicio = time.Date(hoje.Year(), hoje.Month()-23, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		periodo = "12m"
+		inicio = time.Date(hoje.Year(), hoje.Month()-11, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	chuvas, err := app.carregarChuvas("WHERE c.propriedade_id = ? AND c.data >= ?", propriedadeID, inicio)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	meses := services.ChuvaPorMes(chuvas, inicio, hoje)
+
+	data := map[string]interface{}{
+		"PropriedadeID": propriedadeID,
+		"Periodo":       periodo,
+		"Meses":         meses,
+		"Grafico":       services.MontarGraficoChuva(meses),
+		"TemChuva":      len(chuvas) > 0,
+	}
+	app.renderTemplate(w, r, "clima/grafico_chuva.html", data)
+}
+
+// PreencherChuvas registra 0 mm nos dias do período sem leitura do
+// pluviômetro, para quem anota apenas os dias de chuva
+func (app *Application) PreencherChuvas(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	propriedade, err := app.buscarPropriedade(formInt(r, "propriedade_id"))
+	if err != nil {
+		app.clientError(w, "Propriedade não encontrada.")
+		return
+	}
+	inicio, ok1 := formData(r, "inicio")
+	fim, ok2 := formData(r, "fim")
+	if !ok1 || !ok2 || fim.Before(inicio) {
+		app.clientError(w, "Informe o período a preencher.")
+		return
+	}
+	if hoje := horaLocal(); fim.After(hoje) {
+		fim = hoje
+	}
+
+	chuvas, err := app.carregarChuvas("WHERE c.propriedade_id = ? AND c.data BETWEEN ? AND ?", propriedade.ID, inicio, fim)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	faltantes := services.DiasSemChuvaRegistrada(chuvas, inicio, fim)
+	if len(faltantes) == 0 {
+		setToast(w, "Não há dias sem leitura no período.", "success")
+		app.ClimaPropriedade(w, r)
+		return
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	for _, d := range faltantes {
+		if _, err := tx.Exec(`INSERT INTO chuvas (propriedade_id, data, milimetros, observacoes) VALUES (?, ?, 0, ?)`,
+			propriedade.ID, d, "Sem chuva (preenchido)"); err != nil {
+			log.Printf("❌ Erro ao preencher chuvas: %v", err)
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, fmt.Sprintf("%d dia(s) sem leitura registrados com 0 mm.", len(faltantes)), "success")
+	app.ClimaPropriedade(w, r)
+}
+
+// ImportarClima importa dados meteorológicos de um arquivo CSV ou do texto
+// colado, nos layouts do INMET, de estações automáticas ou de planilha.
+// Leituras e chuvas da mesma data são substituídas; dias sem chuva válida
+// não são gravados e ficam como sem leitura.
+func (app *Application) ImportarClima(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(10 << 20); err != nil && err != http.ErrNotMultipart {
+		app.clientError(w, "Arquivo inválido.")
+		return
+	}
+
+	propriedade, err := app.buscarPropriedade(formInt(r, "propriedade_id"))
+	if err != nil {
+		app.clientError(w, "Propriedade não encontrada.")
+		return
+	}
+	var origem io.Reader = strings.NewReader(r.FormValue("conteudo"))
+	if arquivo, _, err := r.FormFile("arquivo"); err == nil {
+		defer arquivo.Close()
+		origem = arquivo
+	}
+	imp := services.ImportarClimaCSV(origem)
+	if len(imp.Dias) == 0 && len(imp.Chuvas) == 0 {
+		msg := "Nenhuma leitura válida encontrada."
+		if len(imp.Erros) > 0 {
+			msg += " " + imp.Erros[0]
+		}
+		app.clientError(w, msg)
+		return
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	for _, d := range imp.Dias {
+		_, err := tx.Exec(`DELETE FROM clima_diario WHERE propriedade_id = ? AND data = ?`, propriedade.ID, d.Data)
+		if err == nil {
+			_, err = tx.Exec(`
+				INSERT INTO clima_diario (propriedade_id, data, temp_maxima, temp_minima, umidade_relativa, vento_ms, radiacao, eto_informada)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				propriedade.ID, d.Data, d.TempMaxima, d.TempMinima, d.UmidadeRelativa, d.VentoMS, d.Radiacao, d.ETOInformada)
+		}
+		if err != nil {
+			log.Printf("❌ Erro ao importar clima: %v", err)
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	for _, c := range imp.Chuvas {
+		_, err := tx.Exec(`DELETE FROM chuvas WHERE propriedade_id = ? AND data = ?`, propriedade.ID, c.Data)
+		if err == nil {
+			_, err = tx.Exec(`INSERT INTO chuvas (propriedade_id, data, milimetros, observacoes) VALUES (?, ?, ?, ?)`,
+				propriedade.ID, c.Data, c.Milimetros, c.Observacoes)
+		}
+		if err != nil {
+			log.Printf("❌ Erro ao importar chuva: %v", err)
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	msg := fmt.Sprintf("%s: %d dia(s) com dados meteorológicos e %d com chuva importados.", imp.Origem(), len(imp.Dias), len(imp.Chuvas))
+	tipo := "success"
+	if len(imp.SemLeitura) > 0 {
+		msg += fmt.Sprintf(" %d dia(s) sem leitura de chuva no arquivo.", len(imp.SemLeitura))
+		tipo = "warning"
+	}
+	if len(imp.Erros) > 0 {
+		msg += fmt.Sprintf(" %d linha(s) ignorada(s): %s", len(imp.Erros), imp.Erros[0])
+		tipo = "warning"
+	}
+	setToast(w, msg, tipo)
+	app.ClimaPropriedade(w, r)
+}
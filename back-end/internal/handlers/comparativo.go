@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// Quantidade de safras selecionadas quando nenhuma é informada
+const safrasComparativoPadrao = 2
+
+// RelatorioComparativo coloca lado a lado as safras selecionadas (safra_id
+// repetido) de um talhão, propriedade ou cliente: produtividade, custos,
+// margem, insumos e solo no início de cada safra, com as variações e os
+// principais fatores da diferença de margem
+func (app *Application) RelatorioComparativo(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title": "Comparativo entre Safras",
+	}
+
+	var filtro string
+	var id int
+	if talhaoID := formInt(r, "talhao_id"); talhaoID > 0 {
+		talhao, err := app.buscarTalhao(talhaoID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				http.NotFound(w, r)
+			} else {
+				app.serverError(w, r, err)
+			}
+			return
+		}
+		propriedade, err := app.buscarPropriedade(talhao.PropriedadeID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		data["Talhao"] = talhao
+		data["Propriedade"] = propriedade
+		filtro, id = "t.id = ?", talhaoID
+	} else {
+		var ok bool
+		filtro, id, ok = app.escopoRelatorio(w, r, data)
+		if !ok {
+			return
+		}
+	}
+
+	safras, err := app.carregarSafras("WHERE "+filtro, id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	colheitas, err := app.carregarColheitas("WHERE "+filtro, id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	selecionadas := make(map[int]bool)
+	for _, v := range r.Form["safra_id"] {
+		if n, err := strconv.Atoi(v); err == nil {
+			selecionadas[n] = true
+		}
+	}
+	if len(selecionadas) == 0 {
+		selecionadas = safrasPadraoComparativo(safras, colheitas)
+	}
+
+	lancamentos, err := app.carregarLancamentosCusto("WHERE "+filtro, id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	aplicacoes, err := app.carregarAplicacoesInsumos("WHERE "+filtro, id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	analises, err := app.carregarAnalisesSolo("WHERE "+filtro, id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	precos, err := app.carregarPrecosVenda()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	var colunas []services.ColunaComparativo
+	for _, s := range safras {
+		if !selecionadas[s.ID] {
+			continue
+		}
+		var daSafra []models.AplicacaoInsumo
+		for _, a := range aplicacoes {
+			if a.SafraID == s.ID {
+				daSafra = append(daSafra, a)
+			}
+		}
+		col := services.ColunaComparativo{
+			Resultado: services.CalcularResultado(s, lancamentos, colheitas, precos),
+			Insumos:   services.TotalizarProdutos(daSafra, s.AreaHectares),
+		}
+		col.Solo, col.TemSolo = services.AnaliseInicial(analises, s)
+		colunas = append(colunas, col)
+	}
+
+	data["Safras"] = safras
+	data["Selecionadas"] = selecionadas
+	data["Comparativo"] = services.CompararSafras(colunas)
+	app.renderTemplate(w, r, "relatorios/comparativo.html", data)
+}
+
+// safrasPadraoComparativo seleciona as safras mais recentes, dando
+// preferência às que já têm colheita registrada
+func safrasPadraoComparativo(safras []models.Safra, colheitas []models.Colheita) map[int]bool {
+	colhidas := make(map[int]bool)
+	for _, c := range colheitas {
+		colhidas[c.SafraID] = true
+	}
+
+	selecionadas := make(map[int]bool)
+	for _, apenasColhidas := range []bool{true, false} {
+		for _, s := range safras {
+			if len(selecionadas) == safrasComparativoPadrao {
+				return selecionadas
+			}
+			if apenasColhidas && !colhidas[s.ID] {
+				continue
+			}
+			selecionadas[s.ID] = true
+		}
+	}
+	return selecionadas
+}
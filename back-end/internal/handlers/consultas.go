@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// carregarConsultas lista as consultas com o filtro informado (sobre consultas c)
+func (app *Application) carregarConsultas(filtro string, args ...any) ([]models.Consulta, error) {
+	rows, err := app.DB.Query(`
+		SELECT c.id, c.cliente_id, COALESCE(c.propriedade_id, 0), COALESCE(c.safra_id, 0), COALESCE(c.consultor_id, 0), c.data_consulta,
+		       COALESCE(c.tipo_consulta, ''), COALESCE(c.observacoes, ''), COALESCE(c.resultado, '')
+		FROM consultas c
+		`+filtro+`
+		ORDER BY c.data_consulta DESC, c.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var consultas []models.Consulta
+	for rows.Next() {
+		var c models.Consulta
+		if err := rows.Scan(&c.ID, &c.ClienteID, &c.PropriedadeID, &c.SafraID, &c.ConsultorID, &c.DataConsulta,
+			&c.TipoConsulta, &c.Observacoes, &c.Resultado); err != nil {
+			return nil, err
+		}
+		consultas = append(consultas, c)
+	}
+	return consultas, rows.Err()
+}
+
+func (app *Application) carregarProdutosRecomendados(consultaID int) ([]models.ProdutoRecomendado, error) {
+	rows, err := app.DB.Query(`
+		SELECT id, consulta_id, produto, COALESCE(principio_ativo, ''), COALESCE(registro_mapa, ''),
+		       COALESCE(classe_toxicologica, ''), praga, dose, unidade_dose, COALESCE(volume_calda, 0),
+		       COALESCE(modo_aplicacao, ''), COALESCE(numero_aplicacoes, 1), COALESCE(intervalo_reentrada, 0),
+		       COALESCE(carencia_dias, 0), COALESCE(observacoes, '')
+		FROM consulta_produtos
+		WHERE consulta_id = ?
+		ORDER BY id`, consultaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var produtos []models.ProdutoRecomendado
+	for rows.Next() {
+		var p models.ProdutoRecomendado
+		if err := rows.Scan(&p.ID, &p.ConsultaID, &p.Produto, &p.PrincipioAtivo, &p.RegistroMAPA,
+			&p.ClasseToxicologica, &p.Praga, &p.Dose, &p.UnidadeDose, &p.VolumeCalda, &p.ModoAplicacao,
+			&p.NumeroAplicacoes, &p.IntervaloReentrada, &p.CarenciaDias, &p.Observacoes); err != nil {
+			return nil, err
+		}
+		produtos = append(produtos, p)
+	}
+	return produtos, rows.Err()
+}
+
+func (app *Application) buscarConsulta(id int) (models.Consulta, error) {
+	consultas, err := app.carregarConsultas("WHERE c.id = ?", id)
+	if err != nil {
+		return models.Consulta{}, err
+	}
+	if len(consultas) == 0 {
+		return models.Consulta{}, sql.ErrNoRows
+	}
+	c := consultas[0]
+	c.Produtos, err = app.carregarProdutosRecomendados(c.ID)
+	return c, err
+}
+
+// carregarReceituarios lista os receituários emitidos (sem o PDF)
+func (app *Application) carregarReceituarios(filtro string, args ...any) ([]models.Receituario, error) {
+	rows, err := app.DB.Query(`
+		SELECT r.id, r.numero, r.ano, r.consulta_id, r.data_emissao, r.agronomo_nome, r.crea,
+		       COALESCE(r.cultura, ''), COALESCE(r.area_hectares, 0)
+		FROM receituarios r
+		`+filtro+`
+		ORDER BY r.ano DESC, r.numero DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var receituarios []models.Receituario
+	for rows.Next() {
+		var r models.Receituario
+		if err := rows.Scan(&r.ID, &r.Numero, &r.Ano, &r.ConsultaID, &r.DataEmissao, &r.AgronomoNome,
+			&r.CREA, &r.Cultura, &r.AreaHectares); err != nil {
+			return nil, err
+		}
+		receituarios = append(receituarios, r)
+	}
+	return receituarios, rows.Err()
+}
+
+// SalvarConsulta registra uma consulta técnica vinculada à safra
+func (app *Application) SalvarConsulta(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	safra, err := app.buscarSafra(formInt(r, "safra_id"))
+	if err != nil {
+		app.clientError(w, "Safra não encontrada.")
+		return
+	}
+	propriedade, err := app.buscarPropriedade(safra.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	data, ok := formData(r, "data_consulta")
+	if !ok {
+		app.clientError(w, "Informe a data da consulta.")
+		return
+	}
+
+	var consultaID int
+	err = app.DB.QueryRow(
+		`INSERT INTO consultas (cliente_id, propriedade_id, safra_id, consultor_id, data_consulta, tipo_consulta, observacoes)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`,
+		propriedade.ClienteID, propriedade.ID, safra.ID, nullInt(formInt(r, "consultor_id")), data,
+		strings.TrimSpace(r.FormValue("tipo_consulta")), r.FormValue("observacoes"),
+	).Scan(&consultaID)
+	if err != nil {
+		log.Printf("❌ Erro ao salvar consulta: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	r.Form.Set("consulta_id", fmt.Sprint(consultaID))
+	setToast(w, "Consulta registrada. Inclua os produtos recomendados.", "success")
+	app.ReceituarioConsulta(w, r)
+}
+
+// ReceituarioConsulta exibe os produtos recomendados na consulta e os
+// receituários já emitidos
+func (app *Application) ReceituarioConsulta(w http.ResponseWriter, r *http.Request) {
+	consulta, err := app.buscarConsulta(formInt(r, "consulta_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	propriedade, err := app.buscarPropriedade(consulta.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	var safra models.Safra
+	if consulta.SafraID > 0 {
+		if safra, err = app.buscarSafra(consulta.SafraID); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	receituarios, err := app.carregarReceituarios("WHERE r.consulta_id = ?", consulta.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Confere se o estoque da propriedade cobre a recomendação na área da safra
+	var disponibilidade []services.DisponibilidadeProduto
+	if safra.AreaHectares > 0 && len(consulta.Produtos) > 0 {
+		saldos, err := app.saldosEstoque("WHERE i.propriedade_id = ?", consulta.PropriedadeID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		disponibilidade = services.DisponibilidadeRecomendacao(consulta.Produtos, safra.AreaHectares, saldos)
+	}
+
+	// Sugere o último agrônomo que emitiu receituário
+	var agronomo, crea string
+	app.DB.QueryRow(`SELECT agronomo_nome, crea FROM receituarios ORDER BY id DESC LIMIT 1`).Scan(&agronomo, &crea)
+
+	data := map[string]interface{}{
+		"Propriedade":     propriedade,
+		"Consulta":        consulta,
+		"Safra":           safra,
+		"Receituarios":    receituarios,
+		"Disponibilidade": disponibilidade,
+		"UnidadesDose":    models.UnidadesDose,
+		"Agronomo":        agronomo,
+		"CREA":            crea,
+		"Title":           "Receituário Agronômico",
+	}
+	app.renderTemplate(w, r, "consultas/receituario.html", data)
+}
+
+// SalvarProdutoRecomendado inclui um defensivo recomendado na consulta
+func (app *Application) SalvarProdutoRecomendado(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	p := models.ProdutoRecomendado{
+		ConsultaID:         formInt(r, "consulta_id"),
+		Produto:            strings.TrimSpace(r.FormValue("produto")),
+		PrincipioAtivo:     strings.TrimSpace(r.FormValue("principio_ativo")),
+		RegistroMAPA:       strings.TrimSpace(r.FormValue("registro_mapa")),
+		ClasseToxicologica: r.FormValue("classe_toxicologica"),
+		Praga:              strings.TrimSpace(r.FormValue("praga")),
+		Dose:               formFloat(r, "dose"),
+		UnidadeDose:        r.FormValue("unidade_dose"),
+		VolumeCalda:        formFloat(r, "volume_calda"),
+		ModoAplicacao:      strings.TrimSpace(r.FormValue("modo_aplicacao")),
+		NumeroAplicacoes:   formInt(r, "numero_aplicacoes"),
+		IntervaloReentrada: formInt(r, "intervalo_reentrada"),
+		CarenciaDias:       formInt(r, "carencia_dias"),
+		Observacoes:        r.FormValue("observacoes"),
+	}
+	if p.Produto == "" || p.Praga == "" || p.Dose <= 0 {
+		app.clientError(w, "Informe o produto, o alvo e a dose.")
+		return
+	}
+	// A baixa no estoque converte a dose pela unidade, que precisa ser conhecida
+	if !contem(models.UnidadesDose, p.UnidadeDose) {
+		app.clientError(w, fmt.Sprintf("Unidade de dose inválida: use %s.", strings.Join(models.UnidadesDose, ", ")))
+		return
+	}
+	if p.NumeroAplicacoes < 1 {
+		p.NumeroAplicacoes = 1
+	}
+
+	_, err := app.DB.Exec(
+		`INSERT INTO consulta_produtos
+		(consulta_id, produto, principio_ativo, registro_mapa, classe_toxicologica, praga, dose, unidade_dose,
+		 volume_calda, modo_aplicacao, numero_aplicacoes, intervalo_reentrada, carencia_dias, observacoes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.ConsultaID, p.Produto, p.PrincipioAtivo, p.RegistroMAPA, p.ClasseToxicologica, p.Praga, p.Dose,
+		p.UnidadeDose, p.VolumeCalda, p.ModoAplicacao, p.NumeroAplicacoes, p.IntervaloReentrada,
+		p.CarenciaDias, p.Observacoes,
+	)
+	if err != nil {
+		log.Printf("❌ Erro ao salvar produto recomendado: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Produto incluído na recomendação.", "success")
+	app.ReceituarioConsulta(w, r)
+}
+
+// EmitirReceituario numera, gera o PDF e arquiva o receituário da consulta
+func (app *Application) EmitirReceituario(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	consulta, err := app.buscarConsulta(formInt(r, "consulta_id"))
+	if err != nil {
+		app.clientError(w, "Consulta não encontrada.")
+		return
+	}
+	if len(consulta.Produtos) == 0 {
+		app.clientError(w, "Inclua ao menos um produto recomendado.")
+		return
+	}
+	agronomo := strings.TrimSpace(r.FormValue("agronomo_nome"))
+	crea := strings.TrimSpace(r.FormValue("crea"))
+	if agronomo == "" || crea == "" {
+		app.clientError(w, "Informe o nome e o CREA do engenheiro agrônomo.")
+		return
+	}
+	area := formFloat(r, "area_hectares")
+	if area <= 0 {
+		app.clientError(w, "Informe a área a ser tratada.")
+		return
+	}
+
+	rec := models.Receituario{
+		ConsultaID:   consulta.ID,
+		DataEmissao:  time.Now(),
+		Cultura:      strings.TrimSpace(r.FormValue("cultura")),
+		AreaHectares: area,
+		Diagnostico:  consulta.Observacoes,
+		AgronomoNome: agronomo,
+		CREA:         crea,
+		Produtos:     consulta.Produtos,
+	}
+	var endereco, cidade, estado string
+	err = app.DB.QueryRow(`
+		SELECT c.nome, COALESCE(c.cpf_cnpj, ''), COALESCE(c.endereco, ''), COALESCE(c.cidade, ''),
+		       COALESCE(c.estado, ''), p.nome, COALESCE(p.municipio, ''), COALESCE(p.estado, '')
+		FROM propriedades p
+		JOIN clientes c ON c.id = p.cliente_id
+		WHERE p.id = ?`, consulta.PropriedadeID,
+	).Scan(&rec.ClienteNome, &rec.ClienteDocumento, &endereco, &cidade, &estado,
+		&rec.PropriedadeNome, &rec.Municipio, &rec.Estado)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	rec.ClienteDocumento = formatarDocumento(rec.ClienteDocumento)
+	rec.ClienteEndereco = strings.Trim(strings.Join([]string{endereco, strings.Trim(cidade+"/"+estado, "/")}, " – "), " –")
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	rec.Ano = rec.DataEmissao.Year()
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(numero), 0) + 1 FROM receituarios WHERE ano = ?`, rec.Ano).Scan(&rec.Numero); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	pdf := services.GerarReceituarioPDF(rec)
+	_, err = tx.Exec(
+		`INSERT INTO receituarios (numero, ano, consulta_id, data_emissao, agronomo_nome, crea, cultura, area_hectares, pdf)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rec.Numero, rec.Ano, rec.ConsultaID, rec.DataEmissao, rec.AgronomoNome, rec.CREA, rec.Cultura,
+		rec.AreaHectares, pdf,
+	)
+	if err != nil {
+		log.Printf("❌ Erro ao arquivar receituário: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Receituário nº "+rec.Identificacao()+" emitido.", "success")
+	app.ReceituarioConsulta(w, r)
+}
+
+// BaixarReceituario devolve o PDF arquivado no momento da emissão
+func (app *Application) BaixarReceituario(w http.ResponseWriter, r *http.Request) {
+	var numero, ano int
+	var pdf []byte
+	err := app.DB.QueryRow(`SELECT numero, ano, pdf FROM receituarios WHERE id = ?`, formInt(r, "id")).Scan(&numero, &ano, &pdf)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="receituario-%06d-%d.pdf"`, numero, ano))
+	w.Write(pdf)
+}
@@ -0,0 +1,498 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+func (app *Application) carregarCategoriasCusto(apenasAtivas bool) ([]models.CategoriaCusto, error) {
+	filtro := ""
+	if apenasAtivas {
+		filtro = "WHERE COALESCE(ativa, true)"
+	}
+	rows, err := app.DB.Query(`
+		SELECT id, nome, grupo, COALESCE(ativa, true)
+		FROM categorias_custo
+		` + filtro + `
+		ORDER BY grupo, nome`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categorias []models.CategoriaCusto
+	for rows.Next() {
+		var c models.CategoriaCusto
+		if err := rows.Scan(&c.ID, &c.Nome, &c.Grupo, &c.Ativa); err != nil {
+			return nil, err
+		}
+		categorias = append(categorias, c)
+	}
+	return categorias, rows.Err()
+}
+
+// carregarLancamentosCusto lista os lançamentos com o filtro informado
+// (sobre lancamentos_custo l, safras s e talhoes t)
+func (app *Application) carregarLancamentosCusto(filtro string, args ...any) ([]models.LancamentoCusto, error) {
+	rows, err := app.DB.Query(`
+		SELECT l.id, l.safra_id, l.categoria_id, c.nome, c.grupo, l.data, COALESCE(l.descricao, ''),
+		       l.valor, COALESCE(l.aplicacao_id, 0)
+		FROM lancamentos_custo l
+		JOIN categorias_custo c ON c.id = l.categoria_id
+		JOIN safras s ON s.id = l.safra_id
+		JOIN talhoes t ON t.id = s.talhao_id
+		`+filtro+`
+		ORDER BY l.data DESC, l.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lancamentos []models.LancamentoCusto
+	for rows.Next() {
+		var l models.LancamentoCusto
+		if err := rows.Scan(&l.ID, &l.SafraID, &l.CategoriaID, &l.CategoriaNome, &l.Grupo, &l.Data,
+			&l.Descricao, &l.Valor, &l.AplicacaoID); err != nil {
+			return nil, err
+		}
+		lancamentos = append(lancamentos, l)
+	}
+	return lancamentos, rows.Err()
+}
+
+func (app *Application) carregarPrecosVenda() ([]models.PrecoVenda, error) {
+	rows, err := app.DB.Query(`
+		SELECT id, cultura, data, preco, unidade, COALESCE(praca, '')
+		FROM precos_venda
+		ORDER BY data DESC, cultura`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var precos []models.PrecoVenda
+	for rows.Next() {
+		var p models.PrecoVenda
+		if err := rows.Scan(&p.ID, &p.Cultura, &p.Data, &p.Preco, &p.Unidade, &p.Praca); err != nil {
+			return nil, err
+		}
+		precos = append(precos, p)
+	}
+	return precos, rows.Err()
+}
+
+// CustosSafra exibe o livro de custos da safra e o resultado econômico
+func (app *Application) CustosSafra(w http.ResponseWriter, r *http.Request) {
+	safra, err := app.buscarSafra(formInt(r, "id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	propriedade, err := app.buscarPropriedade(safra.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	lancamentos, err := app.carregarLancamentosCusto("WHERE l.safra_id = ?", safra.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	categorias, err := app.carregarCategoriasCusto(true)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	colheitas, err := app.carregarColheitas("WHERE h.safra_id = ?", safra.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	precos, err := app.carregarPrecosVenda()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Aplicações ainda não lançadas no livro de custos
+	aplicacoes, err := app.carregarAplicacoesInsumos(`WHERE a.safra_id = ?
+		AND a.id NOT IN (SELECT aplicacao_id FROM lancamentos_custo WHERE aplicacao_id IS NOT NULL)`, safra.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Propriedade":         propriedade,
+		"Safra":               safra,
+		"Lancamentos":         lancamentos,
+		"Categorias":          categorias,
+		"Resultado":           services.CalcularResultado(safra, lancamentos, colheitas, precos),
+		"AplicacoesPendentes": aplicacoes,
+		"Title":               "Custos da Safra " + safra.Rotulo,
+	}
+	app.renderTemplate(w, r, "custos/safra.html", data)
+}
+
+// SalvarLancamentoCusto registra uma despesa na safra
+func (app *Application) SalvarLancamentoCusto(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	safra, err := app.buscarSafra(formInt(r, "safra_id"))
+	if err != nil {
+		app.clientError(w, "Safra não encontrada.")
+		return
+	}
+	l := models.LancamentoCusto{
+		SafraID:     safra.ID,
+		CategoriaID: formInt(r, "categoria_id"),
+		Descricao:   strings.TrimSpace(r.FormValue("descricao")),
+		Valor:       formFloat(r, "valor"),
+	}
+	var ok bool
+	l.Data, ok = formData(r, "data")
+	if !ok || l.CategoriaID == 0 || l.Valor <= 0 {
+		app.clientError(w, "Informe a data, a categoria e o valor.")
+		return
+	}
+	// Valor informado por hectare é convertido para o total da safra
+	if r.FormValue("base") == "hectare" {
+		l.Valor *= safra.AreaHectares
+	}
+
+	_, err = app.DB.Exec(
+		`INSERT INTO lancamentos_custo (safra_id, categoria_id, data, descricao, valor)
+		VALUES (?, ?, ?, ?, ?)`,
+		l.SafraID, l.CategoriaID, l.Data, l.Descricao, l.Valor,
+	)
+	if err != nil {
+		log.Printf("❌ Erro ao lançar custo: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	r.Form.Set("id", r.FormValue("safra_id"))
+	setToast(w, "Custo lançado.", "success")
+	app.CustosSafra(w, r)
+}
+
+// ExcluirLancamentoCusto remove um lançamento da safra
+func (app *Application) ExcluirLancamentoCusto(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	_, err := app.DB.Exec(`DELETE FROM lancamentos_custo WHERE id = ? AND safra_id = ?`,
+		formInt(r, "lancamento_id"), formInt(r, "safra_id"))
+	if err != nil {
+		log.Printf("❌ Erro ao excluir lançamento: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	r.Form.Set("id", r.FormValue("safra_id"))
+	setToast(w, "Lançamento excluído.", "success")
+	app.CustosSafra(w, r)
+}
+
+// ImportarAplicacoesCusto lança no livro de custos as aplicações da safra
+// com preço unitário informado (preco_<id da aplicação>)
+func (app *Application) ImportarAplicacoesCusto(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	safra, err := app.buscarSafra(formInt(r, "safra_id"))
+	if err != nil {
+		app.clientError(w, "Safra não encontrada.")
+		return
+	}
+	categoriaID := formInt(r, "categoria_id")
+	if categoriaID == 0 {
+		app.clientError(w, "Selecione a categoria dos insumos.")
+		return
+	}
+	aplicacoes, err := app.carregarAplicacoesInsumos(`WHERE a.safra_id = ?
+		AND a.id NOT IN (SELECT aplicacao_id FROM lancamentos_custo WHERE aplicacao_id IS NOT NULL)`, safra.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	lancadas := 0
+	for _, a := range aplicacoes {
+		preco := formFloat(r, "preco_"+strconv.Itoa(a.ID))
+		if preco <= 0 {
+			continue
+		}
+		descricao := fmt.Sprintf("%s – %s %s × %s", a.Produto, services.FormatarDecimal(a.Quantidade(), 2),
+			a.UnidadeTotal(), services.FormatarDecimal(preco, 2))
+		_, err := tx.Exec(
+			`INSERT INTO lancamentos_custo (safra_id, categoria_id, data, descricao, valor, aplicacao_id)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			safra.ID, categoriaID, a.Data, descricao, a.Quantidade()*preco, a.ID,
+		)
+		if err != nil {
+			log.Printf("❌ Erro ao lançar aplicação %d: %v", a.ID, err)
+			app.serverError(w, r, err)
+			return
+		}
+		lancadas++
+	}
+	if lancadas == 0 {
+		app.clientError(w, "Informe o preço unitário de ao menos uma aplicação.")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	r.Form.Set("id", r.FormValue("safra_id"))
+	setToast(w, fmt.Sprintf("%d aplicação(ões) lançada(s) no custo.", lancadas), "success")
+	app.CustosSafra(w, r)
+}
+
+// ConfiguracaoCustos exibe as categorias de custo e as cotações importadas
+func (app *Application) ConfiguracaoCustos(w http.ResponseWriter, r *http.Request) {
+	categorias, err := app.carregarCategoriasCusto(false)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	precos, err := app.carregarPrecosVenda()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Categorias":    categorias,
+		"Grupos":        models.GruposCusto,
+		"Precos":        precos,
+		"UnidadesPreco": []string{models.UnidadePrecoSaca, models.UnidadePrecoTonelada, models.UnidadePrecoArroba},
+		"Title":         "Categorias de Custo e Cotações",
+	}
+	app.renderTemplate(w, r, "custos/configuracao.html", data)
+}
+
+// SalvarCategoriaCusto cadastra, renomeia ou ativa/desativa uma categoria
+func (app *Application) SalvarCategoriaCusto(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	c := models.CategoriaCusto{
+		ID:    formInt(r, "id"),
+		Nome:  strings.TrimSpace(r.FormValue("nome")),
+		Grupo: r.FormValue("grupo"),
+		Ativa: r.FormValue("ativa") != "false",
+	}
+	grupoValido := false
+	for _, g := range models.GruposCusto {
+		if c.Grupo == g {
+			grupoValido = true
+		}
+	}
+	if c.Nome == "" || !grupoValido {
+		app.clientError(w, "Informe o nome e o grupo da categoria.")
+		return
+	}
+	var duplicados int
+	app.DB.QueryRow(`SELECT COUNT(*) FROM categorias_custo WHERE lower(nome) = lower(?) AND id <> ?`, c.Nome, c.ID).Scan(&duplicados)
+	if duplicados > 0 {
+		app.clientError(w, "Já existe uma categoria com esse nome.")
+		return
+	}
+
+	var err error
+	if c.ID == 0 {
+		_, err = app.DB.Exec(`INSERT INTO categorias_custo (nome, grupo, ativa) VALUES (?, ?, ?)`, c.Nome, c.Grupo, c.Ativa)
+	} else {
+		_, err = app.DB.Exec(`UPDATE categorias_custo SET nome=?, grupo=?, ativa=? WHERE id=?`, c.Nome, c.Grupo, c.Ativa, c.ID)
+	}
+	if err != nil {
+		log.Printf("❌ Erro ao salvar categoria de custo: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Categoria salva.", "success")
+	app.ConfiguracaoCustos(w, r)
+}
+
+// ImportarCategoriasPadrao inclui as categorias sugeridas que ainda não existem
+func (app *Application) ImportarCategoriasPadrao(w http.ResponseWriter, r *http.Request) {
+	for _, c := range services.CategoriasCustoPadrao {
+		_, err := app.DB.Exec(
+			`INSERT INTO categorias_custo (nome, grupo)
+			SELECT ?, ?
+			WHERE NOT EXISTS (SELECT 1 FROM categorias_custo WHERE lower(nome) = lower(?))`,
+			c.Nome, c.Grupo, c.Nome,
+		)
+		if err != nil {
+			log.Printf("❌ Erro ao importar categoria %s: %v", c.Nome, err)
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	setToast(w, "Categorias padrão incluídas.", "success")
+	app.ConfiguracaoCustos(w, r)
+}
+
+// ImportarPrecos importa cotações de um arquivo CSV ou do texto colado no
+// formulário. Cotações da mesma cultura, data, unidade e praça são substituídas.
+func (app *Application) ImportarPrecos(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(10 << 20); err != nil && err != http.ErrNotMultipart {
+		app.clientError(w, "Arquivo inválido.")
+		return
+	}
+
+	var origem io.Reader = strings.NewReader(r.FormValue("conteudo"))
+	if arquivo, _, err := r.FormFile("arquivo"); err == nil {
+		defer arquivo.Close()
+		origem = arquivo
+	}
+	precos, erros := services.ImportarPrecosCSV(origem)
+	if len(precos) == 0 {
+		msg := "Nenhuma cotação válida encontrada."
+		if len(erros) > 0 {
+			msg += " " + erros[0]
+		}
+		app.clientError(w, msg)
+		return
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	for _, p := range precos {
+		_, err := tx.Exec(`DELETE FROM precos_venda WHERE lower(cultura) = lower(?) AND data = ? AND unidade = ? AND COALESCE(praca, '') = ?`,
+			p.Cultura, p.Data, p.Unidade, p.Praca)
+		if err == nil {
+			_, err = tx.Exec(`INSERT INTO precos_venda (cultura, data, preco, unidade, praca) VALUES (?, ?, ?, ?, ?)`,
+				p.Cultura, p.Data, p.Preco, p.Unidade, p.Praca)
+		}
+		if err != nil {
+			log.Printf("❌ Erro ao importar cotação: %v", err)
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	msg := fmt.Sprintf("%d cotação(ões) importada(s).", len(precos))
+	tipo := "success"
+	if len(erros) > 0 {
+		msg += fmt.Sprintf(" %d linha(s) ignorada(s): %s", len(erros), erros[0])
+		tipo = "warning"
+	}
+	setToast(w, msg, tipo)
+	app.ConfiguracaoCustos(w, r)
+}
+
+// RelatorioCustos exibe custo por hectare, custo por saca e arroba, margem
+// bruta e ROI de cada safra, por propriedade ou cliente
+func (app *Application) RelatorioCustos(w http.ResponseWriter, r *http.Request) {
+	rotulo := r.FormValue("rotulo")
+	data := map[string]interface{}{
+		"Rotulo": rotulo,
+		"Title":  "Custo de Produção e ROI",
+	}
+
+	filtro, id, ok := app.escopoRelatorio(w, r, data)
+	if !ok {
+		return
+	}
+
+	safras, err := app.carregarSafras("WHERE "+filtro, id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	lancamentos, err := app.carregarLancamentosCusto("WHERE "+filtro, id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	colheitas, err := app.carregarColheitas("WHERE "+filtro, id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	precos, err := app.carregarPrecosVenda()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	vistos := make(map[string]bool)
+	var rotulos []string
+	var resultados []services.ResultadoSafra
+	for _, s := range safras {
+		if !vistos[s.Rotulo] {
+			vistos[s.Rotulo] = true
+			rotulos = append(rotulos, s.Rotulo)
+		}
+		if rotulo != "" && s.Rotulo != rotulo {
+			continue
+		}
+		resultados = append(resultados, services.CalcularResultado(s, lancamentos, colheitas, precos))
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(rotulos)))
+
+	// Totais apenas das safras com custos lançados, para a margem não
+	// considerar receita sem o custo correspondente
+	var custo, receita, area float64
+	for _, res := range resultados {
+		if res.CustoTotal == 0 {
+			continue
+		}
+		custo += res.CustoTotal
+		receita += res.ReceitaBruta()
+		area += res.Safra.AreaHectares
+	}
+
+	data["Rotulos"] = rotulos
+	data["Resultados"] = resultados
+	data["CustoTotal"] = custo
+	data["ReceitaTotal"] = receita
+	data["MargemTotal"] = receita - custo
+	data["AreaTotal"] = area
+	app.renderTemplate(w, r, "relatorios/custos.html", data)
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// FormuladorDieta exibe a biblioteca de ingredientes e as dietas salvas por lote
+func (app *Application) FormuladorDieta(w http.ResponseWriter, r *http.Request) {
+	propriedadeID := formInt(r, "propriedade_id")
+	propriedade, err := app.buscarPropriedade(propriedadeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	ingredientes, err := app.carregarIngredientes()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	dietas, err := app.carregarDietas(propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Propriedade":  propriedade,
+		"Ingredientes": ingredientes,
+		"Dietas":       dietas,
+		"Sistemas":     []string{models.SistemaConfinamento, models.SistemaSuplementacao},
+		"Title":        "Formulação de Dietas",
+	}
+	app.renderTemplate(w, r, "dietas/formulador.html", data)
+}
+
+func (app *Application) carregarIngredientes() ([]models.Ingrediente, error) {
+	rows, err := app.DB.Query(`
+		SELECT id, nome, materia_seca, COALESCE(proteina_bruta, 0), COALESCE(ndt, 0), COALESCE(fdn, 0),
+		       COALESCE(calcio, 0), COALESCE(fosforo, 0), COALESCE(preco_kg, 0), atualizado_em
+		FROM ingredientes
+		ORDER BY nome`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ingredientes []models.Ingrediente
+	for rows.Next() {
+		var i models.Ingrediente
+		if err := rows.Scan(&i.ID, &i.Nome, &i.MateriaSeca, &i.ProteinaBruta, &i.NDT, &i.FDN,
+			&i.Calcio, &i.Fosforo, &i.PrecoKg, &i.AtualizadoEm); err != nil {
+			return nil, err
+		}
+		ingredientes = append(ingredientes, i)
+	}
+	return ingredientes, rows.Err()
+}
+
+func (app *Application) carregarDietas(propriedadeID int) ([]models.Dieta, error) {
+	rows, err := app.DB.Query(`
+		SELECT d.id, d.propriedade_id, d.lote, d.sistema, d.peso_vivo, d.ganho_diario, d.consumo_ms,
+		       d.rendimento_carcaca, COALESCE(d.ndt, 0), COALESCE(d.proteina_bruta, 0),
+		       COALESCE(d.custo_cabeca_dia, 0), COALESCE(d.custo_arroba, 0), d.criado_em,
+		       i.ingrediente_id, g.nome, i.proporcao, i.kg_ms, i.kg_mn, i.custo
+		FROM dietas d
+		JOIN dieta_itens i ON i.dieta_id = d.id
+		JOIN ingredientes g ON g.id = i.ingrediente_id
+		WHERE d.propriedade_id = ?
+		ORDER BY d.criado_em DESC, d.id DESC, i.proporcao DESC`, propriedadeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dietas []models.Dieta
+	for rows.Next() {
+		var d models.Dieta
+		var item models.DietaItem
+		if err := rows.Scan(&d.ID, &d.PropriedadeID, &d.Lote, &d.Sistema, &d.PesoVivo, &d.GanhoDiario,
+			&d.ConsumoMS, &d.RendimentoCarcaca, &d.NDT, &d.ProteinaBruta, &d.CustoCabecaDia,
+			&d.CustoArroba, &d.CriadoEm, &item.IngredienteID, &item.IngredienteNome, &item.Proporcao,
+			&item.KgMS, &item.KgMN, &item.Custo); err != nil {
+			return nil, err
+		}
+		if n := len(dietas); n > 0 && dietas[n-1].ID == d.ID {
+			dietas[n-1].Itens = append(dietas[n-1].Itens, item)
+			continue
+		}
+		d.Itens = []models.DietaItem{item}
+		dietas = append(dietas, d)
+	}
+	return dietas, rows.Err()
+}
+
+// SalvarIngrediente cadastra ou atualiza um ingrediente da biblioteca
+func (app *Application) SalvarIngrediente(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	i := models.Ingrediente{
+		ID:            formInt(r, "id"),
+		Nome:          strings.TrimSpace(r.FormValue("nome")),
+		MateriaSeca:   formFloat(r, "materia_seca"),
+		ProteinaBruta: formFloat(r, "proteina_bruta"),
+		NDT:           formFloat(r, "ndt"),
+		FDN:           formFloat(r, "fdn"),
+		Calcio:        formFloat(r, "calcio"),
+		Fosforo:       formFloat(r, "fosforo"),
+		PrecoKg:       formFloat(r, "preco_kg"),
+	}
+	if i.Nome == "" || i.MateriaSeca <= 0 || i.MateriaSeca > 100 {
+		app.clientError(w, "Informe o nome e a matéria seca (entre 0 e 100%) do ingrediente.")
+		return
+	}
+	if i.NDT > 100 || i.FDN > 100 || i.PrecoKg < 0 {
+		app.clientError(w, "Valores de composição ou preço inválidos.")
+		return
+	}
+
+	// Sem índice único em nome: o DuckDB não atualiza colunas indexadas de
+	// linhas referenciadas por chave estrangeira (dieta_itens)
+	var duplicados int
+	app.DB.QueryRow(`SELECT COUNT(*) FROM ingredientes WHERE lower(nome) = lower(?) AND id <> ?`, i.Nome, i.ID).Scan(&duplicados)
+	if duplicados > 0 {
+		app.clientError(w, "Já existe um ingrediente com esse nome.")
+		return
+	}
+
+	var err error
+	if i.ID == 0 {
+		_, err = app.DB.Exec(
+			`INSERT INTO ingredientes (nome, materia_seca, proteina_bruta, ndt, fdn, calcio, fosforo, preco_kg)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			i.Nome, i.MateriaSeca, i.ProteinaBruta, i.NDT, i.FDN, i.Calcio, i.Fosforo, i.PrecoKg,
+		)
+	} else {
+		_, err = app.DB.Exec(
+			`UPDATE ingredientes SET nome=?, materia_seca=?, proteina_bruta=?, ndt=?, fdn=?, calcio=?,
+			fosforo=?, preco_kg=?, atualizado_em=now() WHERE id=?`,
+			i.Nome, i.MateriaSeca, i.ProteinaBruta, i.NDT, i.FDN, i.Calcio, i.Fosforo, i.PrecoKg, i.ID,
+		)
+	}
+	if err != nil {
+		log.Printf("❌ Erro ao salvar ingrediente: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Ingrediente salvo.", "success")
+	app.FormuladorDieta(w, r)
+}
+
+// ImportarIngredientesReferencia inclui na biblioteca os ingredientes de
+// referência que ainda não foram cadastrados
+func (app *Application) ImportarIngredientesReferencia(w http.ResponseWriter, r *http.Request) {
+	for _, i := range services.IngredientesReferencia {
+		_, err := app.DB.Exec(
+			`INSERT INTO ingredientes (nome, materia_seca, proteina_bruta, ndt, fdn, calcio, fosforo, preco_kg)
+			SELECT ?, ?, ?, ?, ?, ?, ?, ?
+			WHERE NOT EXISTS (SELECT 1 FROM ingredientes WHERE lower(nome) = lower(?))`,
+			i.Nome, i.MateriaSeca, i.ProteinaBruta, i.NDT, i.FDN, i.Calcio, i.Fosforo, i.PrecoKg, i.Nome,
+		)
+		if err != nil {
+			log.Printf("❌ Erro ao importar ingrediente %s: %v", i.Nome, err)
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	setToast(w, "Ingredientes de referência incluídos. Atualize os preços antes de formular.", "success")
+	app.FormuladorDieta(w, r)
+}
+
+// FormularDieta calcula a dieta de menor custo para o lote e salva a fórmula
+func (app *Application) FormularDieta(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	propriedadeID := formInt(r, "propriedade_id")
+	lote := strings.TrimSpace(r.FormValue("lote"))
+	sistema := r.FormValue("sistema")
+	peso := formFloat(r, "peso_vivo")
+	ganho := formFloat(r, "ganho_diario")
+	rendimento := formFloat(r, "rendimento_carcaca")
+	if propriedadeID == 0 || lote == "" {
+		app.clientError(w, "Informe o lote.")
+		return
+	}
+	if peso <= 0 || ganho < 0 || ganho > 2.5 {
+		app.clientError(w, "Informe o peso vivo e um ganho diário entre 0 e 2,5 kg.")
+		return
+	}
+	if sistema != models.SistemaSuplementacao {
+		sistema = models.SistemaConfinamento
+	}
+	if rendimento <= 0 || rendimento > 100 {
+		rendimento = 55
+	}
+
+	ingredientes, err := app.carregarIngredientes()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	selecionados := make(map[int]bool)
+	for _, v := range r.Form["usar"] {
+		if id, err := strconv.Atoi(v); err == nil {
+			selecionados[id] = true
+		}
+	}
+	var opcoes []services.OpcaoIngrediente
+	for _, i := range ingredientes {
+		if !selecionados[i.ID] {
+			continue
+		}
+		opcoes = append(opcoes, services.OpcaoIngrediente{
+			Ingrediente: i,
+			Minimo:      formFloat(r, fmt.Sprintf("min_%d", i.ID)),
+			Maximo:      formFloat(r, fmt.Sprintf("max_%d", i.ID)),
+		})
+	}
+	if len(opcoes) == 0 {
+		app.clientError(w, "Selecione os ingredientes disponíveis para a dieta.")
+		return
+	}
+
+	exigencia := services.CalcularExigencias(peso, ganho, formFloat(r, "consumo_ms"))
+	exigencia.FDNMinimo = formFloat(r, "fdn_minimo")
+	resultado, ok := services.FormularDieta(exigencia, opcoes, rendimento)
+	if !ok {
+		app.clientError(w, fmt.Sprintf(
+			"Nenhuma combinação atende às exigências (NDT %.1f%%, PB %.1f%%). Revise ingredientes, limites ou a meta de ganho.",
+			exigencia.NDT, exigencia.ProteinaBruta))
+		return
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var dietaID int
+	err = tx.QueryRow(
+		`INSERT INTO dietas
+		(propriedade_id, lote, sistema, peso_vivo, ganho_diario, consumo_ms, rendimento_carcaca,
+		 ndt, proteina_bruta, custo_cabeca_dia, custo_arroba)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`,
+		propriedadeID, lote, sistema, peso, ganho, exigencia.ConsumoMS, rendimento,
+		resultado.NDT, resultado.ProteinaBruta, resultado.CustoCabecaDia, resultado.CustoArroba,
+	).Scan(&dietaID)
+	if err != nil {
+		log.Printf("❌ Erro ao salvar dieta: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+	for _, item := range resultado.Itens {
+		if _, err := tx.Exec(
+			`INSERT INTO dieta_itens (dieta_id, ingrediente_id, proporcao, kg_ms, kg_mn, custo)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			dietaID, item.IngredienteID, item.Proporcao, item.KgMS, item.KgMN, item.Custo,
+		); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, fmt.Sprintf("Dieta do lote %s salva: R$ %s por cabeça/dia.", lote, services.FormatarDecimal(resultado.CustoCabecaDia, 2)), "success")
+	app.FormuladorDieta(w, r)
+}
@@ -0,0 +1,434 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// carregarInsumos lista os insumos com o filtro informado (sobre insumos i
+// e propriedades p)
+func (app *Application) carregarInsumos(filtro string, args ...any) ([]models.Insumo, error) {
+	rows, err := app.DB.Query(`
+		SELECT i.id, i.propriedade_id, p.nome, i.nome, i.categoria, i.unidade, COALESCE(i.principio_ativo, ''),
+		       COALESCE(i.estoque_minimo, 0), COALESCE(i.ativo, true)
+		FROM insumos i
+		JOIN propriedades p ON p.id = i.propriedade_id
+		`+filtro+`
+		ORDER BY i.categoria, i.nome`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var insumos []models.Insumo
+	for rows.Next() {
+		var i models.Insumo
+		if err := rows.Scan(&i.ID, &i.PropriedadeID, &i.PropriedadeNome, &i.Nome, &i.Categoria, &i.Unidade, &i.PrincipioAtivo,
+			&i.EstoqueMinimo, &i.Ativo); err != nil {
+			return nil, err
+		}
+		insumos = append(insumos, i)
+	}
+	return insumos, rows.Err()
+}
+
+func (app *Application) buscarInsumo(id int) (models.Insumo, error) {
+	insumos, err := app.carregarInsumos("WHERE i.id = ?", id)
+	if err != nil {
+		return models.Insumo{}, err
+	}
+	if len(insumos) == 0 {
+		return models.Insumo{}, sql.ErrNoRows
+	}
+	return insumos[0], nil
+}
+
+// carregarEntradasInsumo lista os recebimentos com o filtro informado (sobre
+// entradas_insumo e e insumos i)
+func (app *Application) carregarEntradasInsumo(filtro string, args ...any) ([]models.EntradaInsumo, error) {
+	rows, err := app.DB.Query(`
+		SELECT e.id, e.insumo_id, e.data, COALESCE(e.fornecedor, ''), COALESCE(e.nota_fiscal, ''),
+		       COALESCE(e.lote, ''), e.validade, e.quantidade, COALESCE(e.custo_unitario, 0), COALESCE(e.observacoes, '')
+		FROM entradas_insumo e
+		JOIN insumos i ON i.id = e.insumo_id
+		`+filtro+`
+		ORDER BY e.data, e.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entradas []models.EntradaInsumo
+	for rows.Next() {
+		var e models.EntradaInsumo
+		if err := rows.Scan(&e.ID, &e.InsumoID, &e.Data, &e.Fornecedor, &e.NotaFiscal, &e.Lote, &e.Validade,
+			&e.Quantidade, &e.CustoUnitario, &e.Observacoes); err != nil {
+			return nil, err
+		}
+		entradas = append(entradas, e)
+	}
+	return entradas, rows.Err()
+}
+
+// carregarSaidasInsumo lista os consumos com o filtro informado (sobre
+// saidas_insumo s e insumos i)
+func (app *Application) carregarSaidasInsumo(filtro string, args ...any) ([]models.SaidaInsumo, error) {
+	rows, err := app.DB.Query(`
+		SELECT s.id, s.insumo_id, s.data, s.quantidade, COALESCE(s.lote, ''), COALESCE(s.origem, 'ajuste'),
+		       COALESCE(s.aplicacao_id, 0), COALESCE(s.aplicacao_sanitaria_id, 0), COALESCE(s.descricao, '')
+		FROM saidas_insumo s
+		JOIN insumos i ON i.id = s.insumo_id
+		`+filtro+`
+		ORDER BY s.data, s.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var saidas []models.SaidaInsumo
+	for rows.Next() {
+		var s models.SaidaInsumo
+		if err := rows.Scan(&s.ID, &s.InsumoID, &s.Data, &s.Quantidade, &s.Lote, &s.Origem,
+			&s.AplicacaoID, &s.AplicacaoSanitariaID, &s.Descricao); err != nil {
+			return nil, err
+		}
+		saidas = append(saidas, s)
+	}
+	return saidas, rows.Err()
+}
+
+// saldosEstoque consolida o saldo dos insumos do filtro (sobre insumos i)
+func (app *Application) saldosEstoque(filtro string, args ...any) ([]services.SaldoInsumo, error) {
+	insumos, err := app.carregarInsumos(filtro, args...)
+	if err != nil {
+		return nil, err
+	}
+	entradas, err := app.carregarEntradasInsumo(filtro, args...)
+	if err != nil {
+		return nil, err
+	}
+	saidas, err := app.carregarSaidasInsumo(filtro, args...)
+	if err != nil {
+		return nil, err
+	}
+	return services.CalcularSaldos(insumos, entradas, saidas, horaLocal()), nil
+}
+
+// executor é o que baixarEstoque precisa de *sql.DB ou *sql.Tx
+type executor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// baixarEstoque lança o consumo do insumo, na transação da aplicação que o
+// originou quando houver
+func baixarEstoque(db executor, s models.SaidaInsumo) error {
+	_, err := db.Exec(
+		`INSERT INTO saidas_insumo (insumo_id, data, quantidade, lote, origem, aplicacao_id, aplicacao_sanitaria_id, descricao)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.InsumoID, s.Data, s.Quantidade, s.Lote, s.Origem, nullInt(s.AplicacaoID), nullInt(s.AplicacaoSanitariaID), s.Descricao,
+	)
+	return err
+}
+
+// avisoSaldoNegativo devolve o texto de alerta quando o consumo deixou o
+// insumo com saldo negativo, ou "" quando o estoque cobriu a baixa
+func (app *Application) avisoSaldoNegativo(insumoID int) string {
+	saldos, err := app.saldosEstoque("WHERE i.id = ?", insumoID)
+	if err != nil || len(saldos) == 0 || saldos[0].Situacao() != "negativo" {
+		return ""
+	}
+	s := saldos[0]
+	return fmt.Sprintf(" Estoque de %s ficou negativo (%s %s): lance o recebimento.",
+		s.Insumo.Nome, services.FormatarDecimal(s.Saldo, 2), s.Insumo.Unidade)
+}
+
+// EstoquePropriedade exibe os saldos e alertas do estoque de insumos da propriedade
+func (app *Application) EstoquePropriedade(w http.ResponseWriter, r *http.Request) {
+	propriedade, err := app.buscarPropriedade(formInt(r, "propriedade_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	saldos, err := app.saldosEstoque("WHERE i.propriedade_id = ?", propriedade.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	valor := 0.0
+	for _, s := range saldos {
+		valor += s.Valor()
+	}
+
+	data := map[string]interface{}{
+		"Propriedade": propriedade,
+		"Saldos":      saldos,
+		"Alertas":     services.AlertasEstoque(saldos),
+		"ValorTotal":  valor,
+		"Categorias":  models.CategoriasInsumo,
+		"Unidades":    models.UnidadesEstoque,
+		"Title":       "Estoque de Insumos",
+	}
+	app.renderTemplate(w, r, "estoque/lista.html", data)
+}
+
+// SalvarInsumo cadastra o insumo ou atualiza o cadastro existente. A unidade
+// não muda depois do cadastro para não invalidar as movimentações.
+func (app *Application) SalvarInsumo(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	i := models.Insumo{
+		ID:             formInt(r, "insumo_id"),
+		PropriedadeID:  formInt(r, "propriedade_id"),
+		Nome:           strings.TrimSpace(r.FormValue("nome")),
+		Categoria:      r.FormValue("categoria"),
+		Unidade:        r.FormValue("unidade"),
+		PrincipioAtivo: strings.TrimSpace(r.FormValue("principio_ativo")),
+		EstoqueMinimo:  formFloat(r, "estoque_minimo"),
+		Ativo:          formAtivo(r),
+	}
+	if i.Nome == "" {
+		app.clientError(w, "Informe o nome do insumo.")
+		return
+	}
+	if !contem(models.CategoriasInsumo, i.Categoria) {
+		i.Categoria = models.CategoriasInsumo[len(models.CategoriasInsumo)-1]
+	}
+	if i.EstoqueMinimo < 0 {
+		app.clientError(w, "O estoque mínimo não pode ser negativo.")
+		return
+	}
+
+	if i.ID > 0 {
+		atual, err := app.buscarInsumo(i.ID)
+		if err != nil {
+			app.clientError(w, "Insumo não encontrado.")
+			return
+		}
+		if _, err := app.DB.Exec(
+			`UPDATE insumos SET nome = ?, categoria = ?, principio_ativo = ?, estoque_minimo = ?, ativo = ? WHERE id = ?`,
+			i.Nome, i.Categoria, i.PrincipioAtivo, i.EstoqueMinimo, i.Ativo, atual.ID,
+		); err != nil {
+			log.Printf("❌ Erro ao atualizar insumo: %v", err)
+			app.serverError(w, r, err)
+			return
+		}
+		setToast(w, "Cadastro do insumo atualizado.", "success")
+		app.DetalheInsumo(w, r)
+		return
+	}
+
+	if _, err := app.buscarPropriedade(i.PropriedadeID); err != nil {
+		app.clientError(w, "Propriedade não encontrada.")
+		return
+	}
+	if !contem(models.UnidadesEstoque, i.Unidade) {
+		app.clientError(w, "Unidade de estoque inválida.")
+		return
+	}
+	var existentes int
+	app.DB.QueryRow(`SELECT COUNT(*) FROM insumos WHERE propriedade_id = ? AND lower(trim(nome)) = lower(?)`,
+		i.PropriedadeID, i.Nome).Scan(&existentes)
+	if existentes > 0 {
+		app.clientError(w, "Já existe um insumo com esse nome na propriedade.")
+		return
+	}
+
+	if _, err := app.DB.Exec(
+		`INSERT INTO insumos (propriedade_id, nome, categoria, unidade, principio_ativo, estoque_minimo)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		i.PropriedadeID, i.Nome, i.Categoria, i.Unidade, i.PrincipioAtivo, i.EstoqueMinimo,
+	); err != nil {
+		log.Printf("❌ Erro ao cadastrar insumo: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Insumo cadastrado.", "success")
+	app.EstoquePropriedade(w, r)
+}
+
+// formAtivo lê o checkbox "ativo"; formulários de cadastro novo não o enviam
+func formAtivo(r *http.Request) bool {
+	if _, enviado := r.Form["ativo_enviado"]; !enviado {
+		return true
+	}
+	return r.FormValue("ativo") != ""
+}
+
+// contem indica se o valor está entre as opções
+func contem(opcoes []string, valor string) bool {
+	for _, o := range opcoes {
+		if o == valor {
+			return true
+		}
+	}
+	return false
+}
+
+// DetalheInsumo exibe os lotes, o extrato e os formulários de recebimento e ajuste
+func (app *Application) DetalheInsumo(w http.ResponseWriter, r *http.Request) {
+	insumo, err := app.buscarInsumo(formInt(r, "insumo_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	propriedade, err := app.buscarPropriedade(insumo.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	entradas, err := app.carregarEntradasInsumo("WHERE e.insumo_id = ?", insumo.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	saidas, err := app.carregarSaidasInsumo("WHERE s.insumo_id = ?", insumo.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	hoje := horaLocal()
+	saldo := services.CalcularSaldo(insumo, entradas, saidas, hoje)
+	data := map[string]interface{}{
+		"Propriedade": propriedade,
+		"Insumo":      insumo,
+		"Saldo":       saldo,
+		"Alertas":     services.AlertasEstoque([]services.SaldoInsumo{saldo}),
+		"Extrato":     services.ExtratoInsumo(entradas, saidas),
+		"Categorias":  models.CategoriasInsumo,
+		"Hoje":        hoje,
+		"Title":       insumo.Nome + " – Estoque",
+	}
+	app.renderTemplate(w, r, "estoque/insumo.html", data)
+}
+
+// RegistrarEntradaInsumo lança o recebimento de um lote do insumo
+func (app *Application) RegistrarEntradaInsumo(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	insumo, err := app.buscarInsumo(formInt(r, "insumo_id"))
+	if err != nil {
+		app.clientError(w, "Insumo não encontrado.")
+		return
+	}
+	e := models.EntradaInsumo{
+		InsumoID:      insumo.ID,
+		Fornecedor:    strings.TrimSpace(r.FormValue("fornecedor")),
+		NotaFiscal:    strings.TrimSpace(r.FormValue("nota_fiscal")),
+		Lote:          strings.TrimSpace(r.FormValue("lote")),
+		Validade:      formDataOpcional(r, "validade"),
+		Quantidade:    formFloat(r, "quantidade"),
+		CustoUnitario: formFloat(r, "custo_unitario"),
+		Observacoes:   r.FormValue("observacoes"),
+	}
+	var ok bool
+	if e.Data, ok = formData(r, "data"); !ok || e.Quantidade <= 0 {
+		app.clientError(w, "Informe a data e a quantidade recebida.")
+		return
+	}
+	if e.CustoUnitario < 0 {
+		app.clientError(w, "O custo unitário não pode ser negativo.")
+		return
+	}
+	if e.Validade != nil && e.Validade.Before(e.Data) {
+		app.clientError(w, "A validade do lote é anterior à data de recebimento.")
+		return
+	}
+
+	if _, err := app.DB.Exec(
+		`INSERT INTO entradas_insumo (insumo_id, data, fornecedor, nota_fiscal, lote, validade, quantidade, custo_unitario, observacoes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.InsumoID, e.Data, e.Fornecedor, e.NotaFiscal, e.Lote, nullData(e.Validade), e.Quantidade, e.CustoUnitario, e.Observacoes,
+	); err != nil {
+		log.Printf("❌ Erro ao registrar recebimento de insumo: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Recebimento registrado.", "success")
+	app.DetalheInsumo(w, r)
+}
+
+// RegistrarSaidaInsumo lança um consumo avulso ou ajuste de inventário
+// (perda, vencimento, contagem física)
+func (app *Application) RegistrarSaidaInsumo(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	insumo, err := app.buscarInsumo(formInt(r, "insumo_id"))
+	if err != nil {
+		app.clientError(w, "Insumo não encontrado.")
+		return
+	}
+	s := models.SaidaInsumo{
+		InsumoID:   insumo.ID,
+		Quantidade: formFloat(r, "quantidade"),
+		Lote:       strings.TrimSpace(r.FormValue("lote")),
+		Origem:     models.SaidaAjuste,
+		Descricao:  strings.TrimSpace(r.FormValue("descricao")),
+	}
+	var ok bool
+	if s.Data, ok = formData(r, "data"); !ok || s.Quantidade <= 0 {
+		app.clientError(w, "Informe a data e a quantidade baixada.")
+		return
+	}
+	if s.Descricao == "" {
+		s.Descricao = "Ajuste de estoque"
+	}
+
+	if err := baixarEstoque(app.DB, s); err != nil {
+		log.Printf("❌ Erro ao registrar saída de insumo: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	if aviso := app.avisoSaldoNegativo(insumo.ID); aviso != "" {
+		setToast(w, "Saída registrada."+aviso, "warning")
+	} else {
+		setToast(w, "Saída registrada.", "success")
+	}
+	app.DetalheInsumo(w, r)
+}
+
+// AlertasEstoque lista lotes vencidos ou a vencer e saldos negativos ou
+// abaixo do mínimo, de uma propriedade ou de todas
+func (app *Application) AlertasEstoque(w http.ResponseWriter, r *http.Request) {
+	filtro, args := "", []any{}
+	if id := formInt(r, "propriedade_id"); id > 0 {
+		filtro, args = "WHERE i.propriedade_id = ?", []any{id}
+	}
+	saldos, err := app.saldosEstoque(filtro, args...)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Alertas": services.AlertasEstoque(saldos),
+	}
+	app.renderTemplate(w, r, "estoque/alertas.html", data)
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// analisePonto é o laudo ligado a um ponto de amostragem georreferenciado
+type analisePonto struct {
+	Ponto   models.PontoAmostragem
+	Analise models.AnaliseSolo
+}
+
+// carregarCampanhaSolo lista as datas de amostragem com laudos ligados a
+// pontos do talhão e devolve as análises da data pedida (ou da mais
+// recente), uma por ponto
+func (app *Application) carregarCampanhaSolo(talhaoID int, data time.Time) ([]time.Time, time.Time, []analisePonto, error) {
+	analises, err := app.carregarAnalisesSolo("WHERE a.talhao_id = ? AND a.ponto_id IS NOT NULL", talhaoID)
+	if err != nil {
+		return nil, data, nil, err
+	}
+	pontos, err := app.carregarPontosAmostragem("WHERE g.talhao_id = ?", talhaoID)
+	if err != nil {
+		return nil, data, nil, err
+	}
+	porID := make(map[int]models.PontoAmostragem, len(pontos))
+	for _, p := range pontos {
+		porID[p.ID] = p
+	}
+
+	// As análises vêm da data mais recente para a mais antiga
+	var campanhas []time.Time
+	for _, a := range analises {
+		if len(campanhas) == 0 || !campanhas[len(campanhas)-1].Equal(a.DataAmostra) {
+			campanhas = append(campanhas, a.DataAmostra)
+		}
+	}
+	if len(campanhas) == 0 {
+		return nil, data, nil, nil
+	}
+	escolhida := campanhas[0]
+	for _, c := range campanhas {
+		if c.Equal(data) {
+			escolhida = c
+		}
+	}
+
+	var resultado []analisePonto
+	vistos := map[int]bool{}
+	for _, a := range analises {
+		p, ok := porID[a.PontoID]
+		if !ok || vistos[a.PontoID] || !a.DataAmostra.Equal(escolhida) {
+			continue
+		}
+		vistos[a.PontoID] = true
+		resultado = append(resultado, analisePonto{Ponto: p, Analise: a})
+	}
+	return campanhas, escolhida, resultado, nil
+}
+
+// consultaFertilidade reúne o talhão e as escolhas do mapa de fertilidade
+type consultaFertilidade struct {
+	Talhao    models.Talhao
+	Parametro services.ParametroSolo
+	Metodo    string
+	Resolucao float64
+	Campanhas []time.Time
+	Data      time.Time
+	Analises  []analisePonto
+}
+
+// lerConsultaFertilidade interpreta talhao_id, parametro, metodo, resolucao
+// e data, usados tanto pela página quanto pela imagem do mapa
+func (app *Application) lerConsultaFertilidade(r *http.Request) (consultaFertilidade, error) {
+	var c consultaFertilidade
+	talhao, err := app.buscarTalhao(formInt(r, "talhao_id"))
+	if err != nil {
+		return c, err
+	}
+	c.Talhao = talhao
+	parametro, ok := services.BuscarParametroSolo(r.FormValue("parametro"))
+	if !ok {
+		parametro = services.ParametrosSolo[0]
+	}
+	c.Parametro = parametro
+	c.Metodo = services.InterpolacaoIDW
+	if r.FormValue("metodo") == services.InterpolacaoKrigagem {
+		c.Metodo = services.InterpolacaoKrigagem
+	}
+	// Abaixo de 2 m o mapa fica pesado sem ganho real sobre a grade de amostras
+	if c.Resolucao = formFloat(r, "resolucao"); c.Resolucao < 2 {
+		c.Resolucao = 0
+	}
+	data, _ := formData(r, "data")
+	c.Campanhas, c.Data, c.Analises, err = app.carregarCampanhaSolo(talhao.ID, data)
+	return c, err
+}
+
+// interpolar monta o mapa do parâmetro com os laudos da campanha
+func (c consultaFertilidade) interpolar() (services.MapaInterpolado, error) {
+	return c.interpolarValores(func(a models.AnaliseSolo) float64 {
+		return services.ValorParametro(a, c.Parametro.Chave)
+	})
+}
+
+// interpolarValores monta o mapa de qualquer valor derivado do laudo, na
+// mesma malha do parâmetro (a prescrição de calagem também usa a CTC)
+func (c consultaFertilidade) interpolarValores(valor func(models.AnaliseSolo) float64) (services.MapaInterpolado, error) {
+	amostras := make([]services.AmostraInterpolacao, len(c.Analises))
+	for i, a := range c.Analises {
+		amostras[i] = services.AmostraInterpolacao{Ponto: a.Ponto.Ponto, Valor: valor(a.Analise)}
+	}
+	return services.InterpolarTalhao(c.Talhao.Limite, amostras, c.Metodo, c.Resolucao)
+}
+
+// query repete as escolhas da consulta no endereço da imagem do mapa
+func (c consultaFertilidade) query() string {
+	v := url.Values{}
+	v.Set("talhao_id", fmt.Sprint(c.Talhao.ID))
+	v.Set("parametro", c.Parametro.Chave)
+	v.Set("metodo", c.Metodo)
+	if c.Resolucao > 0 {
+		v.Set("resolucao", fmt.Sprint(c.Resolucao))
+	}
+	if !c.Data.IsZero() {
+		v.Set("data", c.Data.Format("2006-01-02"))
+	}
+	return v.Encode()
+}
+
+// MapaFertilidade mostra o mapa interpolado de um parâmetro (pH, P, K ou
+// V%) sobre o talhão, com a área de cada classe de interpretação
+func (app *Application) MapaFertilidade(w http.ResponseWriter, r *http.Request) {
+	c, err := app.lerConsultaFertilidade(r)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		log.Printf("❌ Erro ao carregar laudos georreferenciados: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+	propriedade, err := app.buscarPropriedade(c.Talhao.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	camadas := services.NovaColecao()
+	if len(c.Talhao.Limite) > 0 {
+		camadas.AdicionarPoligono(c.Talhao.Limite, map[string]any{"tipo": "limite", "nome": c.Talhao.Nome})
+	}
+	for _, a := range c.Analises {
+		valor := services.ValorParametro(a.Analise, c.Parametro.Chave)
+		camadas.AdicionarPonto(a.Ponto.Ponto, map[string]any{
+			"tipo":   "ponto",
+			"codigo": a.Ponto.Codigo,
+			"valor":  services.FormatarDecimal(valor, c.Parametro.Casas),
+			"cor":    services.CoresFertilidade[c.Parametro.Classe(valor)],
+		})
+	}
+
+	data := map[string]interface{}{
+		"Propriedade": propriedade,
+		"Talhao":      c.Talhao,
+		"Parametro":   c.Parametro,
+		"Parametros":  services.ParametrosSolo,
+		"Metodo":      c.Metodo,
+		"Resolucao":   c.Resolucao,
+		"Resolucoes":  []float64{5, 10, 20, 30},
+		"Campanhas":   c.Campanhas,
+		"Data":        c.Data,
+		"Amostras":    len(c.Analises),
+		"Camadas":     camadas,
+		"MetodoIDW":   services.InterpolacaoIDW,
+		"MetodoKrige": services.InterpolacaoKrigagem,
+		"Title":       "Mapa de Fertilidade",
+	}
+	if len(c.Analises) > 0 {
+		mapa, err := c.interpolar()
+		if err != nil {
+			data["Erro"] = err.Error()
+		} else {
+			minimo, maximo, media := mapa.Estatisticas()
+			data["Mapa"] = mapa
+			data["Areas"] = mapa.AreasPorClasse(c.Parametro)
+			data["Minimo"], data["Maximo"], data["Media"] = minimo, maximo, media
+			data["Imagem"] = "/talhoes/fertilidade/png?" + c.query()
+			data["Limites"] = [2][2]float64{{mapa.Sul, mapa.Oeste}, {mapa.Norte, mapa.Leste}}
+		}
+	}
+	app.renderTemplate(w, r, "talhoes/fertilidade.html", data)
+}
+
+// ImagemFertilidade entrega o mapa interpolado em PNG transparente para
+// sobrepor ao mapa de satélite
+func (app *Application) ImagemFertilidade(w http.ResponseWriter, r *http.Request) {
+	c, err := app.lerConsultaFertilidade(r)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	mapa, err := c.interpolar()
+	if err != nil {
+		app.clientError(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(mapa.PNG(c.Parametro))
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// formInt lê um campo inteiro do formulário, retornando 0 quando vazio ou inválido
+func formInt(r *http.Request, campo string) int {
+	v, err := strconv.Atoi(strings.TrimSpace(r.FormValue(campo)))
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// formFloat lê um campo decimal aceitando vírgula como separador (padrão brasileiro)
+func formFloat(r *http.Request, campo string) float64 {
+	return services.LerDecimal(r.FormValue(campo))
+}
+
+// formatarDocumento aplica a máscara de CPF (11 dígitos) ou CNPJ (14 dígitos)
+func formatarDocumento(doc string) string {
+	if len(doc) == 11 {
+		return fmt.Sprintf("%s.%s.%s-%s", doc[:3], doc[3:6], doc[6:9], doc[9:])
+	} else if len(doc) == 14 {
+		return fmt.Sprintf("%s.%s.%s/%s-%s", doc[:2], doc[2:5], doc[5:8], doc[8:12], doc[12:])
+	}
+	return doc
+}
+
+// formData lê uma data no formato do input HTML (AAAA-MM-DD)
+func formData(r *http.Request, campo string) (time.Time, bool) {
+	t, err := time.Parse("2006-01-02", strings.TrimSpace(r.FormValue(campo)))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// formDataOpcional retorna nil quando o campo não foi preenchido
+func formDataOpcional(r *http.Request, campo string) *time.Time {
+	t, ok := formData(r, campo)
+	if !ok {
+		return nil
+	}
+	return &t
+}
+
+// horaLocal devolve o horário de parede atual marcado como UTC, mesmo
+// critério usado ao gravar data e hora informadas no formulário
+func horaLocal() time.Time {
+	t := time.Now()
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+// nullInt converte IDs opcionais (0 = ausente) para NULL no banco
+func nullInt(v int) any {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
+// nullData converte datas opcionais para NULL no banco
+func nullData(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+// setToast dispara o toast do front-end via HX-Trigger
+func setToast(w http.ResponseWriter, mensagem, tipo string) {
+	payload, _ := json.Marshal(map[string]any{
+		"showToast": map[string]string{"message": mensagem, "type": tipo},
+	})
+	w.Header().Set("HX-Trigger", string(payload))
+}
+
+// clientError responde com erro de validação exibido como toast
+func (app *Application) clientError(w http.ResponseWriter, mensagem string) {
+	setToast(w, mensagem, "error")
+	w.WriteHeader(http.StatusBadRequest)
+}
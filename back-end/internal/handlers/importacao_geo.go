@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// ImportarLimites lê um arquivo KML, KMZ, GeoJSON ou Shapefile compactado e
+// mostra as feições encontradas para que cada uma seja associada ao limite
+// da propriedade ou a um talhão. Nada é gravado nesta etapa.
+func (app *Application) ImportarLimites(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(20 << 20); err != nil {
+		app.clientError(w, "Envie o arquivo de limites (até 20 MB).")
+		return
+	}
+
+	propriedade, err := app.buscarPropriedade(formInt(r, "propriedade_id"))
+	if err != nil {
+		app.clientError(w, "Propriedade não encontrada.")
+		return
+	}
+	arquivo, cabecalho, err := r.FormFile("arquivo")
+	if err != nil {
+		app.clientError(w, "Selecione o arquivo de limites.")
+		return
+	}
+	defer arquivo.Close()
+	conteudo, err := io.ReadAll(arquivo)
+	if err != nil {
+		app.clientError(w, "Não foi possível ler o arquivo.")
+		return
+	}
+
+	imp, err := services.ImportarGeometria(cabecalho.Filename, conteudo, r.FormValue("fuso"))
+	if err != nil {
+		app.clientError(w, "Importação recusada: "+err.Error()+".")
+		return
+	}
+	if imp.Validas() == 0 {
+		f := imp.Feicoes[0]
+		app.clientError(w, fmt.Sprintf("Nenhum polígono válido no arquivo. %s: %s.", f.Nome, f.Erro))
+		return
+	}
+
+	talhoes, err := app.carregarTalhoes(propriedade.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	var desenhos []services.PoligonoNomeado
+	for _, f := range imp.Feicoes {
+		if f.Valida() {
+			desenhos = append(desenhos, services.PoligonoNomeado{Nome: f.Nome, Anel: f.Anel})
+		}
+	}
+
+	if rejeitadas := len(imp.Feicoes) - imp.Validas(); rejeitadas > 0 {
+		setToast(w, fmt.Sprintf("%d feição(ões) com geometria inválida não poderão ser gravadas.", rejeitadas), "warning")
+	}
+	data := map[string]interface{}{
+		"Propriedade": propriedade,
+		"Arquivo":     cabecalho.Filename,
+		"Importacao":  imp,
+		"Destinos":    services.SugerirDestinos(imp.Feicoes, talhoes),
+		"Talhoes":     talhoes,
+		"Desenho":     services.DesenharPropriedade(nil, desenhos, nil, 480, 320),
+		"Title":       "Importar Limites",
+	}
+	app.renderTemplate(w, r, "propriedades/importacao.html", data)
+}
+
+// ConfirmarImportacaoLimites grava as feições conforme o destino escolhido.
+// As geometrias voltam do formulário e são validadas novamente.
+func (app *Application) ConfirmarImportacaoLimites(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	propriedade, err := app.buscarPropriedade(formInt(r, "propriedade_id"))
+	if err != nil {
+		app.clientError(w, "Propriedade não encontrada.")
+		return
+	}
+	talhoes, err := app.carregarTalhoes(propriedade.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	porID := make(map[int]models.Talhao, len(talhoes))
+	for _, t := range talhoes {
+		porID[t.ID] = t
+	}
+
+	type gravacao struct {
+		destino string
+		nome    string
+		anel    []models.Ponto
+	}
+	var gravacoes []gravacao
+	usados := make(map[string]bool)
+	for i := 0; i < formInt(r, "feicoes"); i++ {
+		destino := r.FormValue(fmt.Sprintf("destino_%d", i))
+		if destino == "" || destino == services.DestinoIgnorar {
+			continue
+		}
+		nome := strings.TrimSpace(r.FormValue(fmt.Sprintf("nome_%d", i)))
+		anel, err := services.LerPoligonoGeoJSON(r.FormValue(fmt.Sprintf("geometria_%d", i)))
+		if err == nil {
+			anel, err = services.ValidarPoligono(anel)
+		}
+		if err != nil {
+			rotulo := nome
+			if rotulo == "" {
+				rotulo = fmt.Sprintf("feição %d", i+1)
+			}
+			app.clientError(w, fmt.Sprintf("Geometria de %s inválida: %v.", rotulo, err))
+			return
+		}
+
+		switch {
+		case destino == services.DestinoPropriedade, destino == services.DestinoNovoTalhao:
+		case porID[services.TalhaoDoDestino(destino)].ID != 0:
+		default:
+			app.clientError(w, "Talhão de destino não encontrado.")
+			return
+		}
+		if destino == services.DestinoNovoTalhao && nome == "" {
+			app.clientError(w, "Informe o nome dos novos talhões.")
+			return
+		}
+		if destino != services.DestinoNovoTalhao && usados[destino] {
+			app.clientError(w, "Cada talhão e o limite da propriedade só podem receber uma feição.")
+			return
+		}
+		usados[destino] = true
+		gravacoes = append(gravacoes, gravacao{destino: destino, nome: nome, anel: anel})
+	}
+	if len(gravacoes) == 0 {
+		app.clientError(w, "Escolha o destino de ao menos uma feição.")
+		return
+	}
+	atualizarAreas := r.FormValue("atualizar_areas") == "1"
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var limitePropriedade bool
+	var atualizados, novos, divergentes int
+	for _, g := range gravacoes {
+		area := services.AreaGeodesica(g.anel)
+		geometria := services.PoligonoGeoJSON(g.anel)
+		switch g.destino {
+		case services.DestinoPropriedade:
+			err = gravarLimite(tx, propriedade, g.anel, atualizarAreas)
+			limitePropriedade = true
+			if !atualizarAreas && services.ConferirArea(area, propriedade.Hectares).ForaDaTolerancia() {
+				divergentes++
+			}
+		case services.DestinoNovoTalhao:
+			_, err = tx.Exec(
+				`INSERT INTO talhoes (propriedade_id, nome, area_hectares, uso, limite, area_limite) VALUES (?, ?, ?, ?, ?, ?)`,
+				propriedade.ID, g.nome, area, models.UsoLavoura, geometria, area)
+			novos++
+		default:
+			t := porID[services.TalhaoDoDestino(g.destino)]
+			hectares := t.AreaHectares
+			if atualizarAreas {
+				hectares = area
+			} else if services.ConferirArea(area, t.AreaHectares).ForaDaTolerancia() {
+				divergentes++
+			}
+			_, err = tx.Exec(`UPDATE talhoes SET limite = ?, area_limite = ?, area_hectares = ? WHERE id = ? AND propriedade_id = ?`,
+				geometria, area, hectares, t.ID, propriedade.ID)
+			atualizados++
+		}
+		if err != nil {
+			log.Printf("❌ Erro ao gravar limite importado: %v", err)
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	var partes []string
+	if limitePropriedade {
+		partes = append(partes, "limite da propriedade")
+	}
+	if atualizados > 0 {
+		partes = append(partes, fmt.Sprintf("%d talhão(ões) atualizado(s)", atualizados))
+	}
+	if novos > 0 {
+		partes = append(partes, fmt.Sprintf("%d talhão(ões) criado(s)", novos))
+	}
+	msg := "Importação concluída: " + strings.Join(partes, ", ") + "."
+	tipo := "success"
+	if divergentes > 0 {
+		msg += fmt.Sprintf(" %d área(s) diverge(m) da cadastrada em mais de %s%%.",
+			divergentes, services.FormatarDecimal(services.ToleranciaAreaPercentual, 0))
+		tipo = "warning"
+	}
+	setToast(w, msg, tipo)
+	app.LocalizacaoPropriedade(w, r)
+}
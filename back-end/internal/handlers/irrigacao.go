@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// Parâmetros adotados enquanto o talhão não tem configuração de irrigação
+// (solo argiloso de cerrado irrigado por pivô)
+var configIrrigacaoPadrao = models.ConfigIrrigacao{
+	Sistema:          "Pivô central",
+	Eficiencia:       85,
+	AguaDisponivel:   1.2,
+	ProfundidadeRaiz: 40,
+	FatorDeplecao:    0.5,
+	LaminaMaxima:     30,
+	Latitude:         -15.8,
+	Altitude:         700,
+}
+
+// carregarIrrigacoes lista as irrigações aplicadas com o filtro informado (sobre irrigacoes i)
+func (app *Application) carregarIrrigacoes(filtro string, args ...any) ([]models.Irrigacao, error) {
+	rows, err := app.DB.Query(`
+		SELECT i.id, i.safra_id, i.data, i.lamina, COALESCE(i.duracao, 0), COALESCE(i.observacoes, '')
+		FROM irrigacoes i
+		`+filtro+`
+		ORDER BY i.data DESC, i.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var irrigacoes []models.Irrigacao
+	for rows.Next() {
+		var i models.Irrigacao
+		if err := rows.Scan(&i.ID, &i.SafraID, &i.Data, &i.Lamina, &i.Duracao, &i.Observacoes); err != nil {
+			return nil, err
+		}
+		irrigacoes = append(irrigacoes, i)
+	}
+	return irrigacoes, rows.Err()
+}
+
+// buscarConfigIrrigacao devolve os parâmetros de irrigação do talhão ou os
+// padrões, indicando se o talhão já foi configurado
+func (app *Application) buscarConfigIrrigacao(talhaoID int) (models.ConfigIrrigacao, bool, error) {
+	c := models.ConfigIrrigacao{TalhaoID: talhaoID}
+	err := app.DB.QueryRow(`
+		SELECT sistema, eficiencia, agua_disponivel, profundidade_raiz, fator_deplecao,
+		       COALESCE(lamina_maxima, 0), COALESCE(latitude, 0), COALESCE(altitude, 0)
+		FROM config_irrigacao
+		WHERE talhao_id = ?
+		ORDER BY id DESC
+		LIMIT 1`, talhaoID).Scan(&c.Sistema, &c.Eficiencia, &c.AguaDisponivel, &c.ProfundidadeRaiz,
+		&c.FatorDeplecao, &c.LaminaMaxima, &c.Latitude, &c.Altitude)
+	if err == sql.ErrNoRows {
+		padrao := configIrrigacaoPadrao
+		padrao.TalhaoID = talhaoID
+		return padrao, false, nil
+	}
+	return c, err == nil, err
+}
+
+// IrrigacaoSafra exibe o balanço hídrico diário da safra, a recomendação
+// da próxima irrigação e as irrigações aplicadas
+func (app *Application) IrrigacaoSafra(w http.ResponseWriter, r *http.Request) {
+	safra, err := app.buscarSafra(formInt(r, "safra_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	propriedade, err := app.buscarPropriedade(safra.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	cfg, configurado, err := app.buscarConfigIrrigacao(safra.TalhaoID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	clima, err := app.carregarClimaDiario("WHERE c.propriedade_id = ?", safra.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	chuvas, err := app.carregarChuvas("WHERE c.propriedade_id = ?", safra.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	irrigacoes, err := app.carregarIrrigacoes("WHERE i.safra_id = ?", safra.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	hoje := horaLocal()
+	balanco := services.BalancoHidrico(safra, cfg, clima, chuvas, irrigacoes, hoje)
+
+	// Últimos 30 dias, do mais recente ao mais antigo
+	var recentes []services.DiaBalanco
+	for i := len(balanco) - 1; i >= 0 && len(recentes) < 30; i-- {
+		recentes = append(recentes, balanco[i])
+	}
+	var atual services.DiaBalanco
+	if len(balanco) > 0 {
+		atual = balanco[len(balanco)-1]
+	}
+
+	data := map[string]interface{}{
+		"Propriedade":  propriedade,
+		"Safra":        safra,
+		"Config":       cfg,
+		"Configurado":  configurado,
+		"Sistemas":     models.SistemasIrrigacao,
+		"Balanco":      recentes,
+		"Atual":        atual,
+		"Resumo":       services.ResumirBalanco(balanco),
+		"Recomendacao": services.RecomendarIrrigacao(safra, cfg, balanco, hoje),
+		"Irrigacoes":   irrigacoes,
+		"Estadio":      services.EstadioSafra(safra, hoje),
+		"Title":        "Irrigação",
+	}
+	app.renderTemplate(w, r, "irrigacao/safra.html", data)
+}
+
+// SalvarConfigIrrigacao grava os parâmetros de solo, raiz e sistema do talhão
+func (app *Application) SalvarConfigIrrigacao(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	talhao, err := app.buscarTalhao(formInt(r, "talhao_id"))
+	if err != nil {
+		app.clientError(w, "Talhão não encontrado.")
+		return
+	}
+	c := models.ConfigIrrigacao{
+		TalhaoID:         talhao.ID,
+		Sistema:          r.FormValue("sistema"),
+		Eficiencia:       formFloat(r, "eficiencia"),
+		AguaDisponivel:   formFloat(r, "agua_disponivel"),
+		ProfundidadeRaiz: formFloat(r, "profundidade_raiz"),
+		FatorDeplecao:    formFloat(r, "fator_deplecao"),
+		LaminaMaxima:     formFloat(r, "lamina_maxima"),
+		Latitude:         formFloat(r, "latitude"),
+		Altitude:         formFloat(r, "altitude"),
+	}
+	if _, ok := models.SistemasIrrigacao[c.Sistema]; !ok {
+		app.clientError(w, "Selecione o sistema de irrigação.")
+		return
+	}
+	if c.Eficiencia <= 0 {
+		c.Eficiencia = models.SistemasIrrigacao[c.Sistema]
+	}
+	switch {
+	case c.Eficiencia > 100:
+		app.clientError(w, "A eficiência deve estar entre 1 e 100%.")
+		return
+	case c.AguaDisponivel <= 0 || c.ProfundidadeRaiz <= 0:
+		app.clientError(w, "Informe a água disponível do solo e a profundidade das raízes.")
+		return
+	case c.FatorDeplecao <= 0 || c.FatorDeplecao >= 1:
+		app.clientError(w, "O fator de depleção deve estar entre 0 e 1.")
+		return
+	case c.Latitude < -90 || c.Latitude > 90 || c.LaminaMaxima < 0:
+		app.clientError(w, "Latitude ou lâmina máxima inválida.")
+		return
+	}
+
+	var existe int
+	err = app.DB.QueryRow(`SELECT COUNT(*) FROM config_irrigacao WHERE talhao_id = ?`, talhao.ID).Scan(&existe)
+	if err == nil && existe > 0 {
+		_, err = app.DB.Exec(`
+			UPDATE config_irrigacao SET sistema = ?, eficiencia = ?, agua_disponivel = ?, profundidade_raiz = ?,
+			       fator_deplecao = ?, lamina_maxima = ?, latitude = ?, altitude = ?
+			WHERE talhao_id = ?`,
+			c.Sistema, c.Eficiencia, c.AguaDisponivel, c.ProfundidadeRaiz, c.FatorDeplecao, c.LaminaMaxima, c.Latitude, c.Altitude, talhao.ID)
+	} else if err == nil {
+		_, err = app.DB.Exec(`
+			INSERT INTO config_irrigacao (talhao_id, sistema, eficiencia, agua_disponivel, profundidade_raiz, fator_deplecao, lamina_maxima, latitude, altitude)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			talhao.ID, c.Sistema, c.Eficiencia, c.AguaDisponivel, c.ProfundidadeRaiz, c.FatorDeplecao, c.LaminaMaxima, c.Latitude, c.Altitude)
+	}
+	if err != nil {
+		log.Printf("❌ Erro ao salvar configuração de irrigação: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Configuração de irrigação salva.", "success")
+	app.IrrigacaoSafra(w, r)
+}
+
+// RegistrarIrrigacao registra a lâmina bruta aplicada na safra
+func (app *Application) RegistrarIrrigacao(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	safra, err := app.buscarSafra(formInt(r, "safra_id"))
+	if err != nil {
+		app.clientError(w, "Safra não encontrada.")
+		return
+	}
+	dia, ok := formData(r, "data")
+	lamina := formFloat(r, "lamina")
+	duracao := formFloat(r, "duracao")
+	if !ok || lamina <= 0 || duracao < 0 {
+		app.clientError(w, "Informe a data e a lâmina aplicada em mm.")
+		return
+	}
+	if dia.After(horaLocal()) {
+		app.clientError(w, "A data da irrigação não pode ser futura.")
+		return
+	}
+
+	_, err = app.DB.Exec(`INSERT INTO irrigacoes (safra_id, data, lamina, duracao, observacoes) VALUES (?, ?, ?, ?, ?)`,
+		safra.ID, dia, lamina, duracao, strings.TrimSpace(r.FormValue("observacoes")))
+	if err != nil {
+		log.Printf("❌ Erro ao registrar irrigação: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Irrigação registrada.", "success")
+	app.IrrigacaoSafra(w, r)
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// RelatorioLeiteiro resume o rebanho em lactação e a qualidade do leite do tanque
+func (app *Application) RelatorioLeiteiro(w http.ResponseWriter, r *http.Request) {
+	propriedadeID := formInt(r, "propriedade_id")
+	propriedade, err := app.buscarPropriedade(propriedadeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	animais, err := app.carregarAnimais(propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	partos, err := app.carregarPartos(propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	controles, err := app.carregarControlesLeiteiros(propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	analises, err := app.carregarAnalisesTanque(propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Último parto de cada vaca marca o início da lactação corrente
+	// (partos vêm ordenados do mais recente para o mais antigo)
+	hoje := time.Now()
+	ultimoParto := make(map[int]time.Time)
+	for _, p := range partos {
+		if _, ok := ultimoParto[p.AnimalID]; !ok && !p.Data.After(hoje) {
+			ultimoParto[p.AnimalID] = p.Data
+		}
+	}
+
+	var femeas []models.Animal
+	var lactacoes []services.Lactacao
+	for _, a := range animais {
+		if a.Sexo != "F" {
+			continue
+		}
+		femeas = append(femeas, a)
+		parto, ok := ultimoParto[a.ID]
+		if !ok {
+			continue
+		}
+		l := services.AnalisarLactacao(a, parto, controles, hoje)
+		if len(l.Pontos) > 0 {
+			lactacoes = append(lactacoes, l)
+		}
+	}
+	sort.Slice(lactacoes, func(i, j int) bool { return lactacoes[i].Producao305 > lactacoes[j].Producao305 })
+
+	data := map[string]interface{}{
+		"Propriedade":     propriedade,
+		"Femeas":          femeas,
+		"Lactacoes":       lactacoes,
+		"Resumo":          services.ResumirRebanhoLeiteiro(lactacoes),
+		"Tanque":          services.AvaliarTanque(analises),
+		"LimiteCCSVaca":   services.LimiteCCSVaca,
+		"LimiteCCSTanque": services.LimiteCCSTanque,
+		"LimiteCBTTanque": services.LimiteCBTTanque,
+		"Title":           "Produção Leiteira",
+	}
+	app.renderTemplate(w, r, "leite/relatorio.html", data)
+}
+
+func (app *Application) carregarControlesLeiteiros(propriedadeID int) ([]models.ControleLeiteiro, error) {
+	rows, err := app.DB.Query(`
+		SELECT c.id, c.animal_id, c.data, c.litros, COALESCE(c.ordenhas, 2), COALESCE(c.gordura, 0),
+		       COALESCE(c.proteina, 0), COALESCE(c.ccs, 0), a.identificacao
+		FROM controles_leiteiros c
+		JOIN animais a ON a.id = c.animal_id
+		WHERE a.propriedade_id = ?
+		ORDER BY c.data`, propriedadeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var controles []models.ControleLeiteiro
+	for rows.Next() {
+		var c models.ControleLeiteiro
+		if err := rows.Scan(&c.ID, &c.AnimalID, &c.Data, &c.Litros, &c.Ordenhas, &c.Gordura,
+			&c.Proteina, &c.CCS, &c.Identificacao); err != nil {
+			return nil, err
+		}
+		controles = append(controles, c)
+	}
+	return controles, rows.Err()
+}
+
+func (app *Application) carregarAnalisesTanque(propriedadeID int) ([]models.AnaliseTanque, error) {
+	rows, err := app.DB.Query(`
+		SELECT id, propriedade_id, data, COALESCE(tanque, ''), COALESCE(laboratorio, ''),
+		       COALESCE(gordura, 0), COALESCE(proteina, 0), COALESCE(ccs, 0), COALESCE(cbt, 0)
+		FROM analises_tanque
+		WHERE propriedade_id = ?
+		ORDER BY data DESC`, propriedadeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var analises []models.AnaliseTanque
+	for rows.Next() {
+		var a models.AnaliseTanque
+		if err := rows.Scan(&a.ID, &a.PropriedadeID, &a.Data, &a.Tanque, &a.Laboratorio,
+			&a.Gordura, &a.Proteina, &a.CCS, &a.CBT); err != nil {
+			return nil, err
+		}
+		analises = append(analises, a)
+	}
+	return analises, rows.Err()
+}
+
+// SalvarControleLeiteiro registra a pesagem de leite de uma vaca
+func (app *Application) SalvarControleLeiteiro(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	propriedadeID := formInt(r, "propriedade_id")
+	animalID := formInt(r, "animal_id")
+	litros := formFloat(r, "litros")
+	data, ok := formData(r, "data")
+	daPropriedade, err := app.animalDaPropriedade(animalID, propriedadeID)
+
+	if err != nil {
+
+		app.serverError(w, r, err)
+
+		return
+
+	}
+
+	if !ok || !daPropriedade {
+		app.clientError(w, "Selecione a vaca e a data do controle.")
+		return
+	}
+	if litros <= 0 {
+		app.clientError(w, "Informe a produção em litros.")
+		return
+	}
+
+	ordenhas := formInt(r, "ordenhas")
+	if ordenhas == 0 {
+		ordenhas = 2
+	}
+
+	_, err = app.DB.Exec(
+		`INSERT INTO controles_leiteiros (animal_id, data, litros, ordenhas, gordura, proteina, ccs)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		animalID, data, litros, ordenhas, formFloat(r, "gordura"), formFloat(r, "proteina"), formFloat(r, "ccs"),
+	)
+	if err != nil {
+		log.Printf("❌ Erro ao inserir controle leiteiro: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Controle leiteiro registrado.", "success")
+	app.RelatorioLeiteiro(w, r)
+}
+
+// SalvarAnaliseTanque registra o laudo de qualidade do leite do tanque
+func (app *Application) SalvarAnaliseTanque(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	propriedadeID := formInt(r, "propriedade_id")
+	data, ok := formData(r, "data")
+	if propriedadeID == 0 || !ok {
+		app.clientError(w, "Informe a data da coleta.")
+		return
+	}
+
+	_, err := app.DB.Exec(
+		`INSERT INTO analises_tanque (propriedade_id, data, tanque, laboratorio, gordura, proteina, ccs, cbt)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		propriedadeID, data, r.FormValue("tanque"), r.FormValue("laboratorio"),
+		formFloat(r, "gordura"), formFloat(r, "proteina"), formFloat(r, "ccs"), formFloat(r, "cbt"),
+	)
+	if err != nil {
+		log.Printf("❌ Erro ao inserir análise de tanque: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Análise do tanque registrada.", "success")
+	app.RelatorioLeiteiro(w, r)
+}
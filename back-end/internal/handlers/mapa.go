@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// colorirCultura colore os talhões pela cultura; as demais opções são as
+// chaves de services.ParametrosSolo
+const colorirCultura = "cultura"
+
+// propriedadeMapa é a propriedade com o consultor responsável pelo cliente
+type propriedadeMapa struct {
+	models.Propriedade
+	ConsultorNome string
+}
+
+// filtroMapa monta o WHERE (sobre propriedades p e clientes c) com os filtros
+// de cliente, estado e consultor da requisição
+func filtroMapa(r *http.Request) (string, []any) {
+	condicoes := []string{"COALESCE(c.ativo, true)"}
+	var args []any
+	if id := formInt(r, "cliente_id"); id > 0 {
+		condicoes = append(condicoes, "p.cliente_id = ?")
+		args = append(args, id)
+	}
+	if estado := strings.ToUpper(strings.TrimSpace(r.FormValue("estado"))); estado != "" {
+		condicoes = append(condicoes, "upper(trim(p.estado)) = ?")
+		args = append(args, estado)
+	}
+	if id := formInt(r, "consultor_id"); id > 0 {
+		condicoes = append(condicoes, "c.consultor_id = ?")
+		args = append(args, id)
+	}
+	if id := formInt(r, "propriedade_id"); id > 0 {
+		condicoes = append(condicoes, "p.id = ?")
+		args = append(args, id)
+	}
+	return "WHERE " + strings.Join(condicoes, " AND "), args
+}
+
+// carregarPropriedadesMapa lista as propriedades com geometria e consultor
+func (app *Application) carregarPropriedadesMapa(filtro string, args ...any) ([]propriedadeMapa, error) {
+	rows, err := app.DB.Query(`
+		SELECT p.id, p.cliente_id, c.nome, p.nome, COALESCE(p.hectares, 0),
+		       COALESCE(p.municipio, ''), COALESCE(p.estado, ''),
+		       p.sede_latitude, p.sede_longitude, COALESCE(p.limite, ''), COALESCE(p.area_limite, 0),
+		       COALESCE(k.nome, '')
+		FROM propriedades p
+		JOIN clientes c ON c.id = p.cliente_id
+		LEFT JOIN consultores k ON k.id = c.consultor_id
+		`+filtro+`
+		ORDER BY c.nome, p.nome`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var propriedades []propriedadeMapa
+	for rows.Next() {
+		var p propriedadeMapa
+		var latitude, longitude sql.NullFloat64
+		var limite string
+		if err := rows.Scan(&p.ID, &p.ClienteID, &p.ClienteNome, &p.Nome, &p.Hectares, &p.Municipio, &p.Estado,
+			&latitude, &longitude, &limite, &p.AreaLimite, &p.ConsultorNome); err != nil {
+			return nil, err
+		}
+		if latitude.Valid && longitude.Valid {
+			p.Sede = &models.Ponto{Latitude: latitude.Float64, Longitude: longitude.Float64}
+		}
+		if p.Limite, err = services.LerPoligonoGeoJSON(limite); err != nil {
+			p.Limite = nil
+		}
+		propriedades = append(propriedades, p)
+	}
+	return propriedades, rows.Err()
+}
+
+// talhaoMapa é o talhão com limite, a cultura da safra mais recente e a
+// última análise de solo
+type talhaoMapa struct {
+	models.Talhao
+	PropriedadeNome string
+	ClienteNome     string
+	Cultura         string
+	Safra           string
+	Analise         *models.AnaliseSolo
+}
+
+// carregarTalhoesMapa lista os talhões com limite das propriedades do filtro
+func (app *Application) carregarTalhoesMapa(filtro string, args ...any) ([]talhaoMapa, error) {
+	rows, err := app.DB.Query(`
+		SELECT t.id, t.propriedade_id, t.nome, t.area_hectares, COALESCE(t.uso, ''), t.limite, COALESCE(t.area_limite, 0),
+		       p.nome, c.nome, COALESCE(s.cultura, ''), COALESCE(s.rotulo, '')
+		FROM talhoes t
+		JOIN propriedades p ON p.id = t.propriedade_id
+		JOIN clientes c ON c.id = p.cliente_id
+		LEFT JOIN (
+			SELECT talhao_id, arg_max(cultura, plantio_previsto) AS cultura, arg_max(rotulo, plantio_previsto) AS rotulo
+			FROM safras GROUP BY talhao_id
+		) s ON s.talhao_id = t.id
+		`+filtro+` AND t.limite IS NOT NULL
+		ORDER BY p.nome, t.nome`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var talhoes []talhaoMapa
+	indice := make(map[int]int)
+	for rows.Next() {
+		var t talhaoMapa
+		var limite string
+		if err := rows.Scan(&t.ID, &t.PropriedadeID, &t.Nome, &t.AreaHectares, &t.Uso, &limite, &t.AreaLimite,
+			&t.PropriedadeNome, &t.ClienteNome, &t.Cultura, &t.Safra); err != nil {
+			return nil, err
+		}
+		lerLimiteTalhao(&t.Talhao, limite)
+		if len(t.Limite) == 0 {
+			continue
+		}
+		indice[t.ID] = len(talhoes)
+		talhoes = append(talhoes, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Análises vêm da mais recente para a mais antiga; fica a primeira de cada talhão
+	analises, err := app.carregarAnalisesSolo(`
+		WHERE t.propriedade_id IN (SELECT p.id FROM propriedades p JOIN clientes c ON c.id = p.cliente_id `+filtro+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range analises {
+		if i, ok := indice[a.TalhaoID]; ok && talhoes[i].Analise == nil {
+			talhoes[i].Analise = &a
+		}
+	}
+	return talhoes, nil
+}
+
+// escreverGeoJSON responde a coleção como application/geo+json
+func escreverGeoJSON(w http.ResponseWriter, colecao services.ColecaoGeoJSON) {
+	w.Header().Set("Content-Type", "application/geo+json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(colecao); err != nil {
+		log.Printf("❌ Erro ao escrever GeoJSON: %v", err)
+	}
+}
+
+// GeoPropriedades devolve as propriedades como GeoJSON: o limite quando
+// existe, senão a sede. Filtros: cliente_id, estado, consultor_id.
+func (app *Application) GeoPropriedades(w http.ResponseWriter, r *http.Request) {
+	filtro, args := filtroMapa(r)
+	propriedades, err := app.carregarPropriedadesMapa(filtro, args...)
+	if err != nil {
+		log.Printf("❌ Erro ao carregar propriedades do mapa: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	colecao := services.NovaColecao()
+	for _, p := range propriedades {
+		atributos := map[string]any{
+			"id":          p.ID,
+			"nome":        p.Nome,
+			"cliente_id":  p.ClienteID,
+			"cliente":     p.ClienteNome,
+			"consultor":   p.ConsultorNome,
+			"municipio":   p.Municipio,
+			"estado":      p.Estado,
+			"hectares":    p.Hectares,
+			"area_limite": p.AreaLimite,
+		}
+		switch {
+		case len(p.Limite) > 0:
+			colecao.AdicionarPoligono(p.Limite, atributos)
+		case p.Sede != nil:
+			colecao.AdicionarPonto(*p.Sede, atributos)
+		}
+	}
+	escreverGeoJSON(w, colecao)
+}
+
+// GeoTalhoes devolve os talhões com limite como GeoJSON, com a cultura da
+// safra mais recente e os parâmetros da última análise de solo (valor,
+// classe e cor). Aceita os filtros de GeoPropriedades e propriedade_id.
+func (app *Application) GeoTalhoes(w http.ResponseWriter, r *http.Request) {
+	filtro, args := filtroMapa(r)
+	talhoes, err := app.carregarTalhoesMapa(filtro, args...)
+	if err != nil {
+		log.Printf("❌ Erro ao carregar talhões do mapa: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	colecao := services.NovaColecao()
+	for _, t := range talhoes {
+		atributos := map[string]any{
+			"id":             t.ID,
+			"nome":           t.Nome,
+			"propriedade_id": t.PropriedadeID,
+			"propriedade":    t.PropriedadeNome,
+			"cliente":        t.ClienteNome,
+			"uso":            t.Uso,
+			"area_hectares":  t.AreaHectares,
+			"area_limite":    t.AreaLimite,
+			"cultura":        t.Cultura,
+			"safra":          t.Safra,
+			"cor_cultura":    services.CorCultura(t.Cultura),
+		}
+		if t.Analise != nil {
+			atributos["data_analise"] = t.Analise.DataAmostra.Format("2006-01-02")
+			for _, p := range services.ParametrosSolo {
+				valor := services.ValorParametro(*t.Analise, p.Chave)
+				classe := p.Classe(valor)
+				atributos[p.Chave] = valor
+				atributos[p.Chave+"_classe"] = services.ClassesFertilidade[classe]
+				atributos[p.Chave+"_cor"] = services.CoresFertilidade[classe]
+			}
+		}
+		colecao.AdicionarPoligono(t.Limite, atributos)
+	}
+	escreverGeoJSON(w, colecao)
+}
+
+// MapaCarteira mostra as propriedades e talhões da carteira em um mapa,
+// com filtros de cliente, estado e consultor e a coloração dos talhões por
+// cultura ou parâmetro de solo
+func (app *Application) MapaCarteira(w http.ResponseWriter, r *http.Request) {
+	filtro, args := filtroMapa(r)
+	propriedades, err := app.carregarPropriedadesMapa(filtro, args...)
+	if err != nil {
+		log.Printf("❌ Erro ao carregar mapa: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+	clientes, err := app.carregarOpcoesClientes()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	consultores, err := app.carregarConsultores(false)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	var estados []string
+	rows, err := app.DB.Query(`SELECT DISTINCT upper(trim(estado)) FROM propriedades WHERE trim(COALESCE(estado, '')) <> '' ORDER BY 1`)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	for rows.Next() {
+		var e string
+		if rows.Scan(&e) == nil {
+			estados = append(estados, e)
+		}
+	}
+	rows.Close()
+
+	colorir := r.FormValue("colorir")
+	if _, ok := services.BuscarParametroSolo(colorir); !ok {
+		colorir = colorirCultura
+	}
+
+	// Repassa os filtros às chamadas da API feitas pelo mapa
+	consulta := url.Values{}
+	for _, campo := range []string{"cliente_id", "estado", "consultor_id", "propriedade_id"} {
+		if v := strings.TrimSpace(r.FormValue(campo)); v != "" {
+			consulta.Set(campo, v)
+		}
+	}
+
+	var semLocalizacao []propriedadeMapa
+	area := 0.0
+	for _, p := range propriedades {
+		area += p.Hectares
+		if len(p.Limite) == 0 && p.Sede == nil {
+			semLocalizacao = append(semLocalizacao, p)
+		}
+	}
+
+	legenda := make([]map[string]string, len(services.ClassesFertilidade))
+	for i, classe := range services.ClassesFertilidade {
+		legenda[i] = map[string]string{"classe": classe, "cor": services.CoresFertilidade[i]}
+	}
+
+	data := map[string]interface{}{
+		"Propriedades":   propriedades,
+		"SemLocalizacao": semLocalizacao,
+		"AreaTotal":      area,
+		"Clientes":       clientes,
+		"Consultores":    consultores,
+		"Estados":        estados,
+		"Parametros":     services.ParametrosSolo,
+		"Legenda":        legenda,
+		"Colorir":        colorir,
+		"Consulta":       consulta.Encode(),
+		"ClienteID":      formInt(r, "cliente_id"),
+		"ConsultorID":    formInt(r, "consultor_id"),
+		"Estado":         strings.ToUpper(strings.TrimSpace(r.FormValue("estado"))),
+		"PropriedadeID":  formInt(r, "propriedade_id"),
+		"Title":          "Mapa da Carteira",
+	}
+	app.renderTemplate(w, r, "mapa/carteira.html", data)
+}
@@ -0,0 +1,609 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// carregarMaquinas lista as máquinas com o filtro informado (sobre maquinas m
+// e propriedades p)
+func (app *Application) carregarMaquinas(filtro string, args ...any) ([]models.Maquina, error) {
+	rows, err := app.DB.Query(`
+		SELECT m.id, m.propriedade_id, p.nome, m.nome, m.tipo, COALESCE(m.marca, ''), COALESCE(m.modelo, ''),
+		       COALESCE(m.ano, 0), COALESCE(m.unidade, 'h'), COALESCE(m.medidor, 0),
+		       COALESCE(m.capacidade_operacional, 0), COALESCE(m.ativo, true)
+		FROM maquinas m
+		JOIN propriedades p ON p.id = m.propriedade_id
+		`+filtro+`
+		ORDER BY m.tipo, m.nome`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var maquinas []models.Maquina
+	for rows.Next() {
+		var m models.Maquina
+		if err := rows.Scan(&m.ID, &m.PropriedadeID, &m.PropriedadeNome, &m.Nome, &m.Tipo, &m.Marca, &m.Modelo,
+			&m.Ano, &m.Unidade, &m.Medidor, &m.CapacidadeOperacional, &m.Ativo); err != nil {
+			return nil, err
+		}
+		maquinas = append(maquinas, m)
+	}
+	return maquinas, rows.Err()
+}
+
+func (app *Application) buscarMaquina(id int) (models.Maquina, error) {
+	maquinas, err := app.carregarMaquinas("WHERE m.id = ?", id)
+	if err != nil {
+		return models.Maquina{}, err
+	}
+	if len(maquinas) == 0 {
+		return models.Maquina{}, sql.ErrNoRows
+	}
+	return maquinas[0], nil
+}
+
+// carregarPlanosManutencao lista os planos com o filtro informado (sobre planos_manutencao pl)
+func (app *Application) carregarPlanosManutencao(filtro string, args ...any) ([]models.PlanoManutencao, error) {
+	rows, err := app.DB.Query(`
+		SELECT pl.id, pl.maquina_id, pl.descricao, COALESCE(pl.intervalo_medidor, 0), COALESCE(pl.intervalo_dias, 0),
+		       COALESCE(pl.ultimo_medidor, 0), pl.ultima_data, COALESCE(pl.ativo, true)
+		FROM planos_manutencao pl
+		`+filtro+`
+		ORDER BY pl.descricao`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var planos []models.PlanoManutencao
+	for rows.Next() {
+		var p models.PlanoManutencao
+		if err := rows.Scan(&p.ID, &p.MaquinaID, &p.Descricao, &p.IntervaloMedidor, &p.IntervaloDias,
+			&p.UltimoMedidor, &p.UltimaData, &p.Ativo); err != nil {
+			return nil, err
+		}
+		planos = append(planos, p)
+	}
+	return planos, rows.Err()
+}
+
+// carregarManutencoes lista os serviços com o filtro informado (sobre manutencoes mn)
+func (app *Application) carregarManutencoes(filtro string, args ...any) ([]models.Manutencao, error) {
+	rows, err := app.DB.Query(`
+		SELECT mn.id, mn.maquina_id, COALESCE(mn.plano_id, 0), mn.data, COALESCE(mn.medidor, 0), mn.descricao,
+		       COALESCE(mn.pecas, ''), COALESCE(mn.custo, 0), COALESCE(mn.responsavel, ''), COALESCE(mn.observacoes, '')
+		FROM manutencoes mn
+		`+filtro+`
+		ORDER BY mn.data DESC, mn.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var manutencoes []models.Manutencao
+	for rows.Next() {
+		var m models.Manutencao
+		if err := rows.Scan(&m.ID, &m.MaquinaID, &m.PlanoID, &m.Data, &m.Medidor, &m.Descricao,
+			&m.Pecas, &m.Custo, &m.Responsavel, &m.Observacoes); err != nil {
+			return nil, err
+		}
+		manutencoes = append(manutencoes, m)
+	}
+	return manutencoes, rows.Err()
+}
+
+// carregarLeiturasMedidor lista as leituras com o filtro informado (sobre leituras_medidor l)
+func (app *Application) carregarLeiturasMedidor(filtro string, args ...any) ([]models.LeituraMedidor, error) {
+	rows, err := app.DB.Query(`
+		SELECT l.id, l.maquina_id, l.data, l.medidor, l.incremento, COALESCE(l.origem, 'manual'), COALESCE(l.aplicacao_id, 0)
+		FROM leituras_medidor l
+		`+filtro+`
+		ORDER BY l.data DESC, l.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leituras []models.LeituraMedidor
+	for rows.Next() {
+		var l models.LeituraMedidor
+		if err := rows.Scan(&l.ID, &l.MaquinaID, &l.Data, &l.Medidor, &l.Incremento, &l.Origem, &l.AplicacaoID); err != nil {
+			return nil, err
+		}
+		leituras = append(leituras, l)
+	}
+	return leituras, rows.Err()
+}
+
+// situacoesManutencao avalia os planos das máquinas ativas do filtro (sobre
+// maquinas m e propriedades p)
+func (app *Application) situacoesManutencao(filtro string, args ...any) ([]services.SituacaoManutencao, error) {
+	todas, err := app.carregarMaquinas(filtro, args...)
+	if err != nil {
+		return nil, err
+	}
+	var maquinas []models.Maquina
+	var ids []string
+	for _, m := range todas {
+		if m.Ativo {
+			maquinas = append(maquinas, m)
+			ids = append(ids, fmt.Sprint(m.ID))
+		}
+	}
+	if len(maquinas) == 0 {
+		return nil, nil
+	}
+	lista := strings.Join(ids, ", ")
+
+	planos, err := app.carregarPlanosManutencao("WHERE pl.maquina_id IN (" + lista + ")")
+	if err != nil {
+		return nil, err
+	}
+	hoje := horaLocal().Truncate(24 * time.Hour)
+	leituras, err := app.carregarLeiturasMedidor("WHERE l.maquina_id IN ("+lista+") AND l.data >= ?",
+		hoje.AddDate(0, 0, -services.JanelaTaxaUsoDias))
+	if err != nil {
+		return nil, err
+	}
+	return services.AvaliarManutencoes(maquinas, planos, leituras, hoje), nil
+}
+
+// MaquinasPropriedade exibe o cadastro de máquinas da propriedade com a
+// situação dos planos de manutenção
+func (app *Application) MaquinasPropriedade(w http.ResponseWriter, r *http.Request) {
+	propriedade, err := app.buscarPropriedade(formInt(r, "propriedade_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	maquinas, err := app.carregarMaquinas("WHERE m.propriedade_id = ?", propriedade.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	situacoes, err := app.situacoesManutencao("WHERE m.propriedade_id = ?", propriedade.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Propriedade":  propriedade,
+		"Maquinas":     maquinas,
+		"Situacoes":    situacoes,
+		"Lembretes":    services.LembretesManutencao(situacoes),
+		"TiposMaquina": models.TiposMaquina,
+		"Title":        "Máquinas",
+	}
+	app.renderTemplate(w, r, "maquinas/lista.html", data)
+}
+
+// SalvarMaquina cadastra uma máquina com a leitura inicial do medidor
+func (app *Application) SalvarMaquina(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	propriedade, err := app.buscarPropriedade(formInt(r, "propriedade_id"))
+	if err != nil {
+		app.clientError(w, "Propriedade não encontrada.")
+		return
+	}
+	m := models.Maquina{
+		PropriedadeID:         propriedade.ID,
+		Nome:                  strings.TrimSpace(r.FormValue("nome")),
+		Tipo:                  r.FormValue("tipo"),
+		Marca:                 strings.TrimSpace(r.FormValue("marca")),
+		Modelo:                strings.TrimSpace(r.FormValue("modelo")),
+		Ano:                   formInt(r, "ano"),
+		Unidade:               r.FormValue("unidade"),
+		Medidor:               formFloat(r, "medidor"),
+		CapacidadeOperacional: formFloat(r, "capacidade_operacional"),
+	}
+	if m.Nome == "" {
+		app.clientError(w, "Informe o nome ou a identificação da máquina.")
+		return
+	}
+	if m.Medidor < 0 || m.CapacidadeOperacional < 0 {
+		app.clientError(w, "Medidor e capacidade operacional não podem ser negativos.")
+		return
+	}
+	valido := false
+	for _, t := range models.TiposMaquina {
+		if m.Tipo == t {
+			valido = true
+		}
+	}
+	if !valido {
+		m.Tipo = "Outro"
+	}
+	if m.Unidade != models.MedidorKm {
+		m.Unidade = models.MedidorHoras
+	}
+
+	_, err = app.DB.Exec(
+		`INSERT INTO maquinas (propriedade_id, nome, tipo, marca, modelo, ano, unidade, medidor, capacidade_operacional)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		m.PropriedadeID, m.Nome, m.Tipo, m.Marca, m.Modelo, nullInt(m.Ano), m.Unidade, m.Medidor,
+		m.CapacidadeOperacional,
+	)
+	if err != nil {
+		log.Printf("❌ Erro ao salvar máquina: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Máquina cadastrada. Inclua os planos de manutenção.", "success")
+	app.MaquinasPropriedade(w, r)
+}
+
+// DetalheMaquina exibe planos, serviços executados e leituras do medidor
+func (app *Application) DetalheMaquina(w http.ResponseWriter, r *http.Request) {
+	maquina, err := app.buscarMaquina(formInt(r, "maquina_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	situacoes, err := app.situacoesManutencao("WHERE m.id = ?", maquina.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	planos, err := app.carregarPlanosManutencao("WHERE pl.maquina_id = ?", maquina.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	manutencoes, err := app.carregarManutencoes("WHERE mn.maquina_id = ?", maquina.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	leituras, err := app.carregarLeiturasMedidor("WHERE l.maquina_id = ?", maquina.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	nomesPlanos := map[int]string{}
+	for _, p := range planos {
+		nomesPlanos[p.ID] = p.Descricao
+	}
+	hoje := horaLocal().Truncate(24 * time.Hour)
+
+	data := map[string]interface{}{
+		"Maquina":     maquina,
+		"Situacoes":   situacoes,
+		"Planos":      planos,
+		"NomesPlanos": nomesPlanos,
+		"Manutencoes": manutencoes,
+		"CustoTotal":  services.CustoManutencoes(manutencoes),
+		"Leituras":    leituras,
+		"TaxaUso":     services.TaxaUsoDiaria(leituras, hoje),
+		"Hoje":        hoje,
+		"Title":       maquina.Nome,
+	}
+	app.renderTemplate(w, r, "maquinas/detalhes.html", data)
+}
+
+// SalvarPlanoManutencao cria um plano; a última execução informada (ou a
+// leitura atual) é a base do primeiro vencimento
+func (app *Application) SalvarPlanoManutencao(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	maquina, err := app.buscarMaquina(formInt(r, "maquina_id"))
+	if err != nil {
+		app.clientError(w, "Máquina não encontrada.")
+		return
+	}
+	p := models.PlanoManutencao{
+		MaquinaID:        maquina.ID,
+		Descricao:        strings.TrimSpace(r.FormValue("descricao")),
+		IntervaloMedidor: formFloat(r, "intervalo_medidor"),
+		IntervaloDias:    formInt(r, "intervalo_dias"),
+		UltimoMedidor:    maquina.Medidor,
+		UltimaData:       horaLocal().Truncate(24 * time.Hour),
+	}
+	if p.Descricao == "" {
+		app.clientError(w, "Descreva o serviço (ex.: troca de óleo do motor).")
+		return
+	}
+	if p.IntervaloMedidor <= 0 && p.IntervaloDias <= 0 {
+		app.clientError(w, "Informe o intervalo em "+maquina.Unidade+", em dias ou ambos.")
+		return
+	}
+	if p.IntervaloMedidor < 0 || p.IntervaloDias < 0 {
+		app.clientError(w, "Os intervalos não podem ser negativos.")
+		return
+	}
+	if strings.TrimSpace(r.FormValue("ultimo_medidor")) != "" {
+		p.UltimoMedidor = formFloat(r, "ultimo_medidor")
+	}
+	if d, ok := formData(r, "ultima_data"); ok {
+		p.UltimaData = d
+	}
+	if p.UltimoMedidor > maquina.Medidor {
+		app.clientError(w, "A leitura da última execução é maior que a leitura atual da máquina.")
+		return
+	}
+
+	_, err = app.DB.Exec(
+		`INSERT INTO planos_manutencao (maquina_id, descricao, intervalo_medidor, intervalo_dias, ultimo_medidor, ultima_data)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		p.MaquinaID, p.Descricao, p.IntervaloMedidor, p.IntervaloDias, p.UltimoMedidor, p.UltimaData,
+	)
+	if err != nil {
+		log.Printf("❌ Erro ao salvar plano de manutenção: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Plano de manutenção criado.", "success")
+	app.DetalheMaquina(w, r)
+}
+
+// RegistrarManutencao grava o serviço executado. Quando cumpre um plano, a
+// execução passa a ser a base do próximo vencimento; leitura maior que a
+// atual também avança o medidor da máquina.
+func (app *Application) RegistrarManutencao(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	maquina, err := app.buscarMaquina(formInt(r, "maquina_id"))
+	if err != nil {
+		app.clientError(w, "Máquina não encontrada.")
+		return
+	}
+	m := models.Manutencao{
+		MaquinaID:   maquina.ID,
+		PlanoID:     formInt(r, "plano_id"),
+		Medidor:     maquina.Medidor,
+		Descricao:   strings.TrimSpace(r.FormValue("descricao")),
+		Pecas:       strings.TrimSpace(r.FormValue("pecas")),
+		Custo:       formFloat(r, "custo"),
+		Responsavel: strings.TrimSpace(r.FormValue("responsavel")),
+		Observacoes: r.FormValue("observacoes"),
+	}
+	data, ok := formData(r, "data")
+	if !ok {
+		app.clientError(w, "Informe a data do serviço.")
+		return
+	}
+	m.Data = data
+	if strings.TrimSpace(r.FormValue("medidor")) != "" {
+		m.Medidor = formFloat(r, "medidor")
+	}
+	if m.Custo < 0 || m.Medidor < 0 {
+		app.clientError(w, "Custo e leitura do medidor não podem ser negativos.")
+		return
+	}
+
+	var plano models.PlanoManutencao
+	if m.PlanoID > 0 {
+		planos, err := app.carregarPlanosManutencao("WHERE pl.id = ? AND pl.maquina_id = ?", m.PlanoID, maquina.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		if len(planos) == 0 {
+			app.clientError(w, "Plano de manutenção não encontrado.")
+			return
+		}
+		plano = planos[0]
+		if m.Descricao == "" {
+			m.Descricao = plano.Descricao
+		}
+	}
+	if m.Descricao == "" {
+		app.clientError(w, "Descreva o serviço executado.")
+		return
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`INSERT INTO manutencoes (maquina_id, plano_id, data, medidor, descricao, pecas, custo, responsavel, observacoes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		m.MaquinaID, nullInt(m.PlanoID), m.Data, m.Medidor, m.Descricao, m.Pecas, m.Custo, m.Responsavel, m.Observacoes,
+	)
+	if err == nil && m.PlanoID > 0 && !m.Data.Before(plano.UltimaData) {
+		_, err = tx.Exec(`UPDATE planos_manutencao SET ultimo_medidor = ?, ultima_data = ? WHERE id = ?`,
+			m.Medidor, m.Data, m.PlanoID)
+	}
+	if err == nil && m.Medidor > maquina.Medidor {
+		err = avancarMedidor(tx, maquina, m.Medidor-maquina.Medidor, m.Data, "manual", 0)
+	}
+	if err != nil {
+		log.Printf("❌ Erro ao registrar manutenção: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Manutenção registrada.", "success")
+	app.DetalheMaquina(w, r)
+}
+
+// RegistrarLeituraMedidor atualiza o horímetro/hodômetro com a leitura do painel
+func (app *Application) RegistrarLeituraMedidor(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	maquina, err := app.buscarMaquina(formInt(r, "maquina_id"))
+	if err != nil {
+		app.clientError(w, "Máquina não encontrada.")
+		return
+	}
+	data, ok := formData(r, "data")
+	if !ok {
+		app.clientError(w, "Informe a data da leitura.")
+		return
+	}
+	leitura := formFloat(r, "medidor")
+	if leitura <= maquina.Medidor {
+		app.clientError(w, fmt.Sprintf("A leitura deve ser maior que a atual (%s %s).",
+			services.FormatarDecimal(maquina.Medidor, 1), maquina.Unidade))
+		return
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+	if err := avancarMedidor(tx, maquina, leitura-maquina.Medidor, data, "manual", 0); err != nil {
+		log.Printf("❌ Erro ao registrar leitura do medidor: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Leitura registrada.", "success")
+	app.DetalheMaquina(w, r)
+}
+
+// avancarMedidor soma o uso ao medidor da máquina e registra a leitura
+func avancarMedidor(tx *sql.Tx, maquina models.Maquina, incremento float64, data time.Time, origem string, aplicacaoID int) error {
+	// UPDATE ... RETURNING em linha referenciada por chave estrangeira
+	// falha no DuckDB; a leitura resultante é consultada em seguida
+	if _, err := tx.Exec(`UPDATE maquinas SET medidor = COALESCE(medidor, 0) + ? WHERE id = ?`, incremento, maquina.ID); err != nil {
+		return err
+	}
+	var medidor float64
+	if err := tx.QueryRow(`SELECT medidor FROM maquinas WHERE id = ?`, maquina.ID).Scan(&medidor); err != nil {
+		return err
+	}
+	_, err := tx.Exec(
+		`INSERT INTO leituras_medidor (maquina_id, data, medidor, incremento, origem, aplicacao_id) VALUES (?, ?, ?, ?, ?, ?)`,
+		maquina.ID, data, medidor, incremento, origem, nullInt(aplicacaoID),
+	)
+	return err
+}
+
+// AlertasManutencao lista os planos vencidos ou próximos, de uma
+// propriedade ou de todas
+func (app *Application) AlertasManutencao(w http.ResponseWriter, r *http.Request) {
+	filtro, args := "", []any{}
+	if id := formInt(r, "propriedade_id"); id > 0 {
+		filtro, args = "WHERE m.propriedade_id = ?", []any{id}
+	}
+	situacoes, err := app.situacoesManutencao(filtro, args...)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Lembretes": services.LembretesManutencao(situacoes),
+	}
+	app.renderTemplate(w, r, "maquinas/alertas.html", data)
+}
+
+// eventosManutencoes alimenta o calendário com os vencimentos previstos dos
+// planos e os serviços executados no período
+func (app *Application) eventosManutencoes(f filtroCalendario) ([]models.EventoCalendario, error) {
+	filtro, args := f.condicoes("cl.id", "cl.consultor_id", "mn.data")
+	rows, err := app.DB.Query(`
+		SELECT mn.id, mn.data, mn.descricao, COALESCE(mn.custo, 0), COALESCE(mn.pecas, ''),
+		       m.id, m.nome, p.nome, cl.id, cl.nome, COALESCE(co.nome, '')
+		FROM manutencoes mn
+		JOIN maquinas m ON m.id = mn.maquina_id
+		JOIN propriedades p ON p.id = m.propriedade_id
+		JOIN clientes cl ON cl.id = p.cliente_id
+		LEFT JOIN consultores co ON co.id = cl.consultor_id
+		`+filtro, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var eventos []models.EventoCalendario
+	for rows.Next() {
+		var id, maquinaID int
+		var descricao, pecas, maquina string
+		var custo float64
+		e := models.EventoCalendario{Tipo: models.CalendarioManutencao, DiaInteiro: true, Concluido: true}
+		if err := rows.Scan(&id, &e.Inicio, &descricao, &custo, &pecas, &maquinaID, &maquina,
+			&e.PropriedadeNome, &e.ClienteID, &e.ClienteNome, &e.ConsultorNome); err != nil {
+			return nil, err
+		}
+		e.UID = fmt.Sprintf("manutencao-%d", id)
+		e.Titulo = fmt.Sprintf("%s – %s", descricao, maquina)
+		e.Descricao = "Custo: R$ " + services.FormatarDecimal(custo, 2)
+		if pecas != "" {
+			e.Descricao += "\nPeças: " + pecas
+		}
+		e.Link = fmt.Sprintf("/maquinas/detalhes?maquina_id=%d", maquinaID)
+		eventos = append(eventos, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	filtro, args = f.condicoes("cl.id", "cl.consultor_id")
+	situacoes, err := app.situacoesManutencao("JOIN clientes cl ON cl.id = p.cliente_id "+filtro, args...)
+	if err != nil {
+		return nil, err
+	}
+	hoje := horaLocal().Truncate(24 * time.Hour)
+	for _, s := range situacoes {
+		data := s.DataPrevista
+		// Vencidos sem data aparecem hoje, para não sumirem da agenda
+		if s.Situacao == services.ManutencaoVencida && data.Before(hoje) {
+			data = hoje
+		}
+		if data.IsZero() || !f.noPeriodo(data) {
+			continue
+		}
+		e := models.EventoCalendario{
+			UID:             fmt.Sprintf("plano-manutencao-%d", s.Plano.ID),
+			Tipo:            models.CalendarioManutencao,
+			Titulo:          fmt.Sprintf("%s – %s", s.Plano.Descricao, s.Maquina.Nome),
+			Inicio:          data,
+			DiaInteiro:      true,
+			PropriedadeNome: s.Maquina.PropriedadeNome,
+			Link:            fmt.Sprintf("/maquinas/detalhes?maquina_id=%d", s.Maquina.ID),
+		}
+		if s.PorMedidor() {
+			e.Descricao = fmt.Sprintf("Vence com %s %s (atual %s)", services.FormatarDecimal(s.VenceMedidor, 0),
+				s.Maquina.Unidade, services.FormatarDecimal(s.Maquina.Medidor, 0))
+			if s.Projetada {
+				e.Descricao += "\nData estimada pelo ritmo de uso"
+			}
+		}
+		eventos = append(eventos, e)
+	}
+	return eventos, nil
+}
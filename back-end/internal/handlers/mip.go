@@ -0,0 +1,420 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+func (app *Application) carregarNiveisAcao(apenasAtivos bool) ([]models.NivelAcao, error) {
+	filtro := ""
+	if apenasAtivos {
+		filtro = "WHERE COALESCE(ativo, true)"
+	}
+	rows, err := app.DB.Query(`
+		SELECT id, cultura, alvo, tipo, medida, limite, COALESCE(estadio, ''), COALESCE(observacoes, ''), COALESCE(ativo, true)
+		FROM niveis_acao
+		` + filtro + `
+		ORDER BY cultura, alvo, estadio`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var niveis []models.NivelAcao
+	for rows.Next() {
+		var n models.NivelAcao
+		if err := rows.Scan(&n.ID, &n.Cultura, &n.Alvo, &n.Tipo, &n.Medida, &n.Limite, &n.Estadio, &n.Observacoes, &n.Ativo); err != nil {
+			return nil, err
+		}
+		niveis = append(niveis, n)
+	}
+	return niveis, rows.Err()
+}
+
+// carregarMonitoramentos lista os monitoramentos com as ocorrências, com o
+// filtro informado (sobre monitoramentos m, safras s e talhoes t)
+func (app *Application) carregarMonitoramentos(filtro string, args ...any) ([]models.Monitoramento, error) {
+	rows, err := app.DB.Query(`
+		SELECT m.id, m.safra_id, m.data, COALESCE(m.estadio, ''), COALESCE(m.responsavel, ''), COALESCE(m.observacoes, '')
+		FROM monitoramentos m
+		JOIN safras s ON s.id = m.safra_id
+		JOIN talhoes t ON t.id = s.talhao_id
+		`+filtro+`
+		ORDER BY m.data DESC, m.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var monitoramentos []models.Monitoramento
+	indice := make(map[int]int)
+	for rows.Next() {
+		var m models.Monitoramento
+		if err := rows.Scan(&m.ID, &m.SafraID, &m.Data, &m.Estadio, &m.Responsavel, &m.Observacoes); err != nil {
+			return nil, err
+		}
+		indice[m.ID] = len(monitoramentos)
+		monitoramentos = append(monitoramentos, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ocorrencias, err := app.DB.Query(`
+		SELECT o.id, o.monitoramento_id, o.alvo, o.tipo, o.medida, o.pontos, o.valor
+		FROM ocorrencias_pragas o
+		JOIN monitoramentos m ON m.id = o.monitoramento_id
+		JOIN safras s ON s.id = m.safra_id
+		JOIN talhoes t ON t.id = s.talhao_id
+		`+filtro+`
+		ORDER BY o.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer ocorrencias.Close()
+
+	for ocorrencias.Next() {
+		var o models.OcorrenciaPraga
+		if err := ocorrencias.Scan(&o.ID, &o.MonitoramentoID, &o.Alvo, &o.Tipo, &o.Medida, &o.Pontos, &o.Valor); err != nil {
+			return nil, err
+		}
+		if i, ok := indice[o.MonitoramentoID]; ok {
+			monitoramentos[i].Ocorrencias = append(monitoramentos[i].Ocorrencias, o)
+		}
+	}
+	return monitoramentos, ocorrencias.Err()
+}
+
+// alertasMIP calcula os alertas de nível de ação das safras não colhidas
+// do filtro (sobre safras s e talhoes t)
+func (app *Application) alertasMIP(filtro string, args ...any) ([]services.AlertaMIP, error) {
+	filtro = "WHERE s.colheita_real IS NULL" + filtro
+	safras, err := app.carregarSafras(filtro, args...)
+	if err != nil {
+		return nil, err
+	}
+	monitoramentos, err := app.carregarMonitoramentos(filtro, args...)
+	if err != nil {
+		return nil, err
+	}
+	consultas, err := app.carregarConsultas(`WHERE c.safra_id IN (
+		SELECT s.id FROM safras s JOIN talhoes t ON t.id = s.talhao_id `+filtro+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	niveis, err := app.carregarNiveisAcao(true)
+	if err != nil {
+		return nil, err
+	}
+	return services.AlertasMIP(safras, monitoramentos, consultas, niveis, horaLocal()), nil
+}
+
+// MonitoramentoSafra exibe os monitoramentos de pragas e doenças da safra,
+// classificados pelos níveis de ação, e os alertas vigentes
+func (app *Application) MonitoramentoSafra(w http.ResponseWriter, r *http.Request) {
+	safra, err := app.buscarSafra(formInt(r, "safra_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	propriedade, err := app.buscarPropriedade(safra.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	monitoramentos, err := app.carregarMonitoramentos("WHERE m.safra_id = ?", safra.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	todos, err := app.carregarNiveisAcao(true)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	alertas, err := app.alertasMIP(" AND s.id = ?", safra.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	var niveis []models.NivelAcao
+	for _, n := range todos {
+		if strings.EqualFold(n.Cultura, safra.Cultura) {
+			niveis = append(niveis, n)
+		}
+	}
+	avaliacoes := make(map[int][]services.AvaliacaoOcorrencia)
+	for _, m := range monitoramentos {
+		avaliacoes[m.ID] = services.AvaliarOcorrencias(m, safra.Cultura, niveis)
+	}
+
+	data := map[string]interface{}{
+		"Propriedade":    propriedade,
+		"Safra":          safra,
+		"Monitoramentos": monitoramentos,
+		"Avaliacoes":     avaliacoes,
+		"Alertas":        alertas,
+		"Niveis":         niveis,
+		"EstadioAtual":   services.EstadioSafra(safra, horaLocal()),
+		"Estadios":       services.EstadiosMonitoramento,
+		"Medidas":        models.MedidasMonitoramento,
+		"Tipos":          []string{models.AlvoPraga, models.AlvoDoenca},
+		"Title":          "Monitoramento de Pragas",
+	}
+	app.renderTemplate(w, r, "mip/safra.html", data)
+}
+
+// SalvarMonitoramento registra a visita de amostragem com as ocorrências
+// informadas (campos alvo, tipo, medida, pontos e valor repetidos por linha)
+func (app *Application) SalvarMonitoramento(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	safra, err := app.buscarSafra(formInt(r, "safra_id"))
+	if err != nil {
+		app.clientError(w, "Safra não encontrada.")
+		return
+	}
+	m := models.Monitoramento{
+		SafraID:     safra.ID,
+		Estadio:     r.FormValue("estadio"),
+		Responsavel: strings.TrimSpace(r.FormValue("responsavel")),
+		Observacoes: strings.TrimSpace(r.FormValue("observacoes")),
+	}
+	var ok bool
+	m.Data, ok = formData(r, "data")
+	if !ok {
+		app.clientError(w, "Informe a data do monitoramento.")
+		return
+	}
+	if m.Data.After(horaLocal()) {
+		app.clientError(w, "A data do monitoramento não pode ser futura.")
+		return
+	}
+
+	alvos, tipos, medidas := r.Form["alvo"], r.Form["tipo"], r.Form["medida"]
+	pontos, valores := r.Form["pontos"], r.Form["valor"]
+	for i, alvo := range alvos {
+		alvo = strings.TrimSpace(alvo)
+		if alvo == "" {
+			continue
+		}
+		if i >= len(tipos) || i >= len(medidas) || i >= len(pontos) || i >= len(valores) {
+			app.clientError(w, "Formulário de ocorrências incompleto.")
+			return
+		}
+		o := models.OcorrenciaPraga{Alvo: alvo, Tipo: tipos[i], Medida: medidas[i], Valor: services.LerDecimal(valores[i])}
+		o.Pontos, _ = strconv.Atoi(strings.TrimSpace(pontos[i]))
+		if o.Pontos <= 0 || o.Valor < 0 || strings.TrimSpace(valores[i]) == "" {
+			app.clientError(w, "Informe os pontos amostrados e o valor de "+alvo+".")
+			return
+		}
+		if o.Medida == models.MedidaPercentual && o.Valor > 100 {
+			app.clientError(w, "O percentual de "+alvo+" deve estar entre 0 e 100.")
+			return
+		}
+		if o.Tipo != models.AlvoDoenca {
+			o.Tipo = models.AlvoPraga
+		}
+		if o.Medida != models.MedidaPercentual {
+			o.Medida = models.MedidaContagem
+		}
+		m.Ocorrencias = append(m.Ocorrencias, o)
+	}
+	if len(m.Ocorrencias) == 0 {
+		app.clientError(w, "Informe ao menos uma ocorrência (alvo, pontos e valor).")
+		return
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
+		`INSERT INTO monitoramentos (safra_id, data, estadio, responsavel, observacoes)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id`,
+		m.SafraID, m.Data, m.Estadio, m.Responsavel, m.Observacoes,
+	).Scan(&m.ID)
+	for _, o := range m.Ocorrencias {
+		if err != nil {
+			break
+		}
+		_, err = tx.Exec(
+			`INSERT INTO ocorrencias_pragas (monitoramento_id, alvo, tipo, medida, pontos, valor) VALUES (?, ?, ?, ?, ?, ?)`,
+			m.ID, o.Alvo, o.Tipo, o.Medida, o.Pontos, o.Valor,
+		)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("❌ Erro ao salvar monitoramento: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Alvos acima do nível de ação já saem destacados no aviso
+	niveis, err := app.carregarNiveisAcao(true)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	var acima []string
+	for _, a := range services.AvaliarOcorrencias(m, safra.Cultura, niveis) {
+		if a.Situacao == services.SituacaoAcao {
+			acima = append(acima, a.Ocorrencia.Alvo)
+		}
+	}
+	if len(acima) > 0 {
+		setToast(w, "Nível de ação atingido: "+strings.Join(acima, ", ")+". Agende uma consulta.", "warning")
+	} else {
+		setToast(w, "Monitoramento registrado.", "success")
+	}
+	app.MonitoramentoSafra(w, r)
+}
+
+// ConfiguracaoMIP exibe os níveis de ação cadastrados por cultura e alvo
+func (app *Application) ConfiguracaoMIP(w http.ResponseWriter, r *http.Request) {
+	niveis, err := app.carregarNiveisAcao(false)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Niveis":   niveis,
+		"Culturas": models.Culturas,
+		"Estadios": services.EstadiosMonitoramento,
+		"Medidas":  models.MedidasMonitoramento,
+		"Tipos":    []string{models.AlvoPraga, models.AlvoDoenca},
+		"Title":    "Níveis de Ação (MIP)",
+	}
+	app.renderTemplate(w, r, "mip/niveis.html", data)
+}
+
+// SalvarNivelAcao cadastra, altera ou ativa/desativa um nível de ação
+func (app *Application) SalvarNivelAcao(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	n := models.NivelAcao{
+		ID:          formInt(r, "id"),
+		Cultura:     strings.TrimSpace(r.FormValue("cultura")),
+		Alvo:        strings.TrimSpace(r.FormValue("alvo")),
+		Tipo:        r.FormValue("tipo"),
+		Medida:      r.FormValue("medida"),
+		Limite:      formFloat(r, "limite"),
+		Estadio:     r.FormValue("estadio"),
+		Observacoes: strings.TrimSpace(r.FormValue("observacoes")),
+		Ativo:       r.FormValue("ativo") != "false",
+	}
+	if n.Cultura == "" || n.Alvo == "" || n.Limite <= 0 {
+		app.clientError(w, "Informe a cultura, o alvo e o nível de ação.")
+		return
+	}
+	if n.Tipo != models.AlvoDoenca {
+		n.Tipo = models.AlvoPraga
+	}
+	if n.Medida != models.MedidaPercentual {
+		n.Medida = models.MedidaContagem
+	}
+	if n.Medida == models.MedidaPercentual && n.Limite > 100 {
+		app.clientError(w, "O nível em percentual deve estar entre 0 e 100.")
+		return
+	}
+	var duplicados int
+	app.DB.QueryRow(`
+		SELECT COUNT(*) FROM niveis_acao
+		WHERE lower(cultura) = lower(?) AND lower(alvo) = lower(?) AND medida = ? AND COALESCE(estadio, '') = ? AND id <> ?`,
+		n.Cultura, n.Alvo, n.Medida, n.Estadio, n.ID,
+	).Scan(&duplicados)
+	if duplicados > 0 {
+		app.clientError(w, "Já existe um nível para essa cultura, alvo, medida e estádio.")
+		return
+	}
+
+	var err error
+	if n.ID == 0 {
+		_, err = app.DB.Exec(
+			`INSERT INTO niveis_acao (cultura, alvo, tipo, medida, limite, estadio, observacoes, ativo) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			n.Cultura, n.Alvo, n.Tipo, n.Medida, n.Limite, n.Estadio, n.Observacoes, n.Ativo,
+		)
+	} else {
+		_, err = app.DB.Exec(
+			`UPDATE niveis_acao SET cultura=?, alvo=?, tipo=?, medida=?, limite=?, estadio=?, observacoes=?, ativo=? WHERE id=?`,
+			n.Cultura, n.Alvo, n.Tipo, n.Medida, n.Limite, n.Estadio, n.Observacoes, n.Ativo, n.ID,
+		)
+	}
+	if err != nil {
+		log.Printf("❌ Erro ao salvar nível de ação: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Nível de ação salvo.", "success")
+	app.ConfiguracaoMIP(w, r)
+}
+
+// ImportarNiveisPadrao inclui os níveis de referência que ainda não existem
+func (app *Application) ImportarNiveisPadrao(w http.ResponseWriter, r *http.Request) {
+	for _, n := range services.NiveisAcaoPadrao() {
+		_, err := app.DB.Exec(
+			`INSERT INTO niveis_acao (cultura, alvo, tipo, medida, limite, estadio, observacoes)
+			SELECT ?, ?, ?, ?, ?, ?, ?
+			WHERE NOT EXISTS (
+				SELECT 1 FROM niveis_acao
+				WHERE lower(cultura) = lower(?) AND lower(alvo) = lower(?) AND medida = ? AND COALESCE(estadio, '') = ?)`,
+			n.Cultura, n.Alvo, n.Tipo, n.Medida, n.Limite, n.Estadio, n.Observacoes,
+			n.Cultura, n.Alvo, n.Medida, n.Estadio,
+		)
+		if err != nil {
+			log.Printf("❌ Erro ao importar nível de ação %s: %v", n.Alvo, err)
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	setToast(w, "Níveis de referência incluídos.", "success")
+	app.ConfiguracaoMIP(w, r)
+}
+
+// AlertasPragas lista os alvos no nível de ação nas safras em andamento,
+// da propriedade informada ou de todas
+func (app *Application) AlertasPragas(w http.ResponseWriter, r *http.Request) {
+	var alertas []services.AlertaMIP
+	var err error
+	if propriedadeID := formInt(r, "propriedade_id"); propriedadeID > 0 {
+		alertas, err = app.alertasMIP(" AND t.propriedade_id = ?", propriedadeID)
+	} else {
+		alertas, err = app.alertasMIP("")
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Alertas": alertas,
+	}
+	app.renderTemplate(w, r, "mip/alertas.html", data)
+}
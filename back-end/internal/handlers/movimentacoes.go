@@ -0,0 +1,511 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// ListaMovimentacoes exibe as GTAs da propriedade e o estoque atual por categoria
+func (app *Application) ListaMovimentacoes(w http.ResponseWriter, r *http.Request) {
+	propriedadeID := formInt(r, "propriedade_id")
+	propriedade, err := app.buscarPropriedade(propriedadeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	movs, err := app.carregarMovimentacoes(propriedadeID, time.Time{}, time.Time{})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	estoque, err := app.carregarEstoqueRebanho(app.DB, propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	animais, err := app.carregarAnimais(propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	// O cadastro individual serve de conferência do saldo e de base para abri-lo
+	cadastro, semCategoria := services.EstoquePorAnimais(animais, time.Now())
+
+	type SaldoCategoria struct {
+		Categoria  string
+		Quantidade int
+		Cadastro   int
+	}
+	var saldos []SaldoCategoria
+	total, totalCadastro := 0, 0
+	for _, c := range models.CategoriasGTA {
+		saldos = append(saldos, SaldoCategoria{c, estoque[c], cadastro[c]})
+		total += estoque[c]
+		totalCadastro += cadastro[c]
+	}
+	lotes := make(map[string]bool)
+	for _, a := range animais {
+		if a.Lote != "" {
+			lotes[a.Lote] = true
+		}
+	}
+
+	data := map[string]interface{}{
+		"Propriedade":   propriedade,
+		"Lotes":         lotes,
+		"Movimentacoes": movs,
+		"Estoque":       saldos,
+		"TotalCabecas":  total,
+		"TotalCadastro": totalCadastro,
+		"SemCategoria":  semCategoria,
+		"SemSaldo":      len(estoque) == 0,
+		"Motivos":       models.MotivosAjusteRebanho,
+		"Hoje":          time.Now().Format("2006-01-02"),
+		"Categorias":    models.CategoriasGTA,
+		"Finalidades":   models.FinalidadesGTA,
+		"Tipos":         []string{models.MovimentacaoEntrada, models.MovimentacaoSaida},
+		"Title":         "Movimentação Animal",
+	}
+	app.renderTemplate(w, r, "movimentacoes/lista.html", data)
+}
+
+// carregarMovimentacoes lista as GTAs da propriedade; datas zero dispensam o filtro de período
+func (app *Application) carregarMovimentacoes(propriedadeID int, inicio, fim time.Time) ([]models.Movimentacao, error) {
+	query := `
+		SELECT m.id, m.propriedade_id, m.tipo, m.gta_numero, COALESCE(m.gta_serie, ''), m.gta_uf, m.data,
+		       m.origem, m.destino, m.finalidade, COALESCE(m.especie, ''),
+		       COALESCE(m.lote, ''), COALESCE(m.animais, ''),
+		       COALESCE(c.categoria, ''), COALESCE(c.quantidade, 0)
+		FROM movimentacoes m
+		LEFT JOIN movimentacao_categorias c ON c.movimentacao_id = m.id
+		WHERE m.propriedade_id = ?`
+	args := []interface{}{propriedadeID}
+	if !inicio.IsZero() {
+		query += " AND m.data >= ?"
+		args = append(args, inicio)
+	}
+	if !fim.IsZero() {
+		query += " AND m.data <= ?"
+		args = append(args, fim)
+	}
+	query += " ORDER BY m.data DESC, m.id DESC, c.categoria"
+
+	rows, err := app.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movs []models.Movimentacao
+	for rows.Next() {
+		var m models.Movimentacao
+		var item models.MovimentacaoCategoria
+		if err := rows.Scan(&m.ID, &m.PropriedadeID, &m.Tipo, &m.GTANumero, &m.GTASerie, &m.GTAUF, &m.Data,
+			&m.Origem, &m.Destino, &m.Finalidade, &m.Especie, &m.Lote, &m.Animais,
+			&item.Categoria, &item.Quantidade); err != nil {
+			return nil, err
+		}
+		if n := len(movs); n == 0 || movs[n-1].ID != m.ID {
+			movs = append(movs, m)
+		}
+		if item.Categoria != "" {
+			ultima := &movs[len(movs)-1]
+			ultima.Itens = append(ultima.Itens, item)
+		}
+	}
+	return movs, rows.Err()
+}
+
+// consultor é satisfeito por *sql.DB e *sql.Tx
+type consultor interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func (app *Application) carregarEstoqueRebanho(db consultor, propriedadeID int) (map[string]int, error) {
+	rows, err := db.Query(`SELECT categoria, quantidade FROM rebanho_estoque WHERE propriedade_id = ?`, propriedadeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	estoque := make(map[string]int)
+	for rows.Next() {
+		var categoria string
+		var qtd int
+		if err := rows.Scan(&categoria, &qtd); err != nil {
+			return nil, err
+		}
+		estoque[categoria] = qtd
+	}
+	return estoque, rows.Err()
+}
+
+// SalvarMovimentacao registra a GTA e atualiza o estoque do rebanho na mesma transação
+func (app *Application) SalvarMovimentacao(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	mov := models.Movimentacao{
+		PropriedadeID: formInt(r, "propriedade_id"),
+		Tipo:          r.FormValue("tipo"),
+		GTANumero:     strings.TrimSpace(r.FormValue("gta_numero")),
+		GTASerie:      strings.ToUpper(strings.TrimSpace(r.FormValue("gta_serie"))),
+		GTAUF:         strings.ToUpper(strings.TrimSpace(r.FormValue("gta_uf"))),
+		Origem:        strings.TrimSpace(r.FormValue("origem")),
+		Destino:       strings.TrimSpace(r.FormValue("destino")),
+		Finalidade:    r.FormValue("finalidade"),
+		Especie:       "Bovino",
+		Lote:          strings.TrimSpace(r.FormValue("lote")),
+		Animais:       strings.Join(listarIdentificacoes(r.FormValue("animais")), ", "),
+	}
+	var ok bool
+	mov.Data, ok = formData(r, "data")
+	if mov.PropriedadeID == 0 || !ok || mov.GTANumero == "" || len(mov.GTAUF) != 2 {
+		app.clientError(w, "Informe número, UF da GTA e data do trânsito.")
+		return
+	}
+	if mov.Origem == "" || mov.Destino == "" || mov.Finalidade == "" {
+		app.clientError(w, "Origem, destino e finalidade são obrigatórios.")
+		return
+	}
+	for i, c := range models.CategoriasGTA {
+		if qtd := formInt(r, fmt.Sprintf("qtd_%d", i+1)); qtd > 0 {
+			mov.Itens = append(mov.Itens, models.MovimentacaoCategoria{Categoria: c, Quantidade: qtd})
+		}
+	}
+	if mov.TotalCabecas() == 0 {
+		app.clientError(w, "Informe a quantidade de cabeças por categoria.")
+		return
+	}
+
+	var duplicada int
+	app.DB.QueryRow(
+		`SELECT COUNT(*) FROM movimentacoes WHERE propriedade_id = ? AND tipo = ? AND gta_uf = ? AND COALESCE(gta_serie, '') = ? AND gta_numero = ?`,
+		mov.PropriedadeID, mov.Tipo, mov.GTAUF, mov.GTASerie, mov.GTANumero,
+	).Scan(&duplicada)
+	if duplicada > 0 {
+		app.clientError(w, "Esta GTA já foi registrada para a propriedade.")
+		return
+	}
+
+	// Venda e abate só saem com os animais da GTA fora da carência de abate
+	var embarcados []models.Animal
+	if mov.Tipo == models.MovimentacaoSaida {
+		var bloqueio string
+		var err error
+		embarcados, bloqueio, err = app.conferirSaida(mov)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		if bloqueio != "" {
+			app.clientError(w, bloqueio)
+			return
+		}
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	estoque, err := app.carregarEstoqueRebanho(tx, mov.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	novoEstoque, err := services.AplicarMovimentacao(estoque, mov)
+	if err != nil {
+		app.clientError(w, fmt.Sprintf("GTA não registrada: %v. Confira o saldo inicial ou lance um ajuste de inventário.", err))
+		return
+	}
+
+	err = tx.QueryRow(
+		`INSERT INTO movimentacoes
+		(propriedade_id, tipo, gta_numero, gta_serie, gta_uf, data, origem, destino, finalidade, especie, lote, animais)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`,
+		mov.PropriedadeID, mov.Tipo, mov.GTANumero, mov.GTASerie, mov.GTAUF, mov.Data,
+		mov.Origem, mov.Destino, mov.Finalidade, mov.Especie, mov.Lote, mov.Animais,
+	).Scan(&mov.ID)
+	if err != nil {
+		log.Printf("❌ Erro ao inserir movimentação: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	for _, item := range mov.Itens {
+		if _, err := tx.Exec(
+			`INSERT INTO movimentacao_categorias (movimentacao_id, categoria, quantidade) VALUES (?, ?, ?)`,
+			mov.ID, item.Categoria, item.Quantidade,
+		); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO rebanho_estoque (propriedade_id, categoria, quantidade) VALUES (?, ?, ?)
+			ON CONFLICT (propriedade_id, categoria) DO UPDATE SET quantidade = excluded.quantidade, atualizado_em = now()`,
+			mov.PropriedadeID, item.Categoria, novoEstoque[item.Categoria],
+		); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	// Os animais que saem deixam o rebanho ativo da propriedade
+	for _, a := range embarcados {
+		if _, err := tx.Exec(
+			`UPDATE animais SET ativo = false WHERE id = ? AND propriedade_id = ?`, a.ID, mov.PropriedadeID,
+		); err != nil {
+			log.Printf("❌ Erro ao baixar animal %s: %v", a.Identificacao, err)
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	log.Printf("✅ GTA %s/%s registrada - %s de %d cabeças", mov.GTAUF, mov.GTANumero, mov.Tipo, mov.TotalCabecas())
+	mensagem := "Movimentação registrada e estoque atualizado."
+	if len(embarcados) > 0 {
+		mensagem = fmt.Sprintf("Movimentação registrada, estoque atualizado e %d animais baixados do cadastro.", len(embarcados))
+	}
+	setToast(w, mensagem, "success")
+	app.ListaMovimentacoes(w, r)
+}
+
+// conferirSaida reúne os animais que saem na GTA (os do lote informado e os
+// listados por identificação) e confere a carência de abate de cada um. Sem
+// lote nem animais, qualquer carência vigente na propriedade impede a saída,
+// pois não há como saber quem embarca. Devolve os embarcados e a mensagem do
+// bloqueio, vazia quando a saída pode ser registrada.
+func (app *Application) conferirSaida(mov models.Movimentacao) ([]models.Animal, string, error) {
+	aplicacoes, err := app.carregarAplicacoesSanitarias(mov.PropriedadeID)
+	if err != nil {
+		return nil, "", err
+	}
+	animais, err := app.carregarAnimais(mov.PropriedadeID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	listados := listarIdentificacoes(mov.Animais)
+	if mov.Lote == "" && len(listados) == 0 {
+		for _, c := range services.CarenciasAtivas(aplicacoes, mov.Data) {
+			if c.BloqueiaAbate {
+				return nil, fmt.Sprintf("Há carência de %s até %s na propriedade: informe o lote ou os animais da GTA.",
+					c.Aplicacao.Produto, c.LiberaAbate.Format("02/01/2006")), nil
+			}
+		}
+		return nil, "", nil
+	}
+
+	porIdentificacao := make(map[string]models.Animal, len(animais))
+	for _, a := range animais {
+		porIdentificacao[strings.ToUpper(a.Identificacao)] = a
+	}
+	var embarcados []models.Animal
+	incluidos := make(map[int]bool)
+	if mov.Lote != "" {
+		for _, a := range animais {
+			if a.Lote == mov.Lote {
+				embarcados = append(embarcados, a)
+				incluidos[a.ID] = true
+			}
+		}
+		if len(embarcados) == 0 {
+			return nil, fmt.Sprintf("Nenhum animal ativo no lote %s.", mov.Lote), nil
+		}
+	}
+	for _, id := range listados {
+		a, ok := porIdentificacao[strings.ToUpper(id)]
+		if !ok {
+			return nil, fmt.Sprintf("Animal %s não encontrado na propriedade.", id), nil
+		}
+		if !incluidos[a.ID] {
+			embarcados = append(embarcados, a)
+			incluidos[a.ID] = true
+		}
+	}
+	if len(embarcados) > mov.TotalCabecas() {
+		return nil, fmt.Sprintf("A GTA acoberta %d cabeças, mas %d animais foram informados.",
+			mov.TotalCabecas(), len(embarcados)), nil
+	}
+
+	for _, a := range embarcados {
+		if libera, bloqueado := services.LiberacaoAbate(a, aplicacoes, mov.Data); bloqueado {
+			return nil, fmt.Sprintf("Saída bloqueada: animal %s em carência até %s.",
+				a.Identificacao, libera.Format("02/01/2006")), nil
+		}
+	}
+	return embarcados, "", nil
+}
+
+// listarIdentificacoes separa as identificações digitadas por vírgula,
+// ponto e vírgula ou quebra de linha
+func listarIdentificacoes(texto string) []string {
+	var ids []string
+	for _, id := range strings.FieldsFunc(texto, func(c rune) bool {
+		return c == ',' || c == ';' || c == '\n' || c == '\r'
+	}) {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// AjustarEstoqueRebanho lança o saldo inicial ou um ajuste de inventário por
+// categoria (nascimentos, mortes, contagem). Com origem=cadastro, o saldo de
+// todas as categorias passa a ser a contagem dos animais ativos do cadastro.
+// Cada categoria alterada fica registrada com o saldo anterior.
+func (app *Application) AjustarEstoqueRebanho(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	propriedadeID := formInt(r, "propriedade_id")
+	data, ok := formData(r, "data")
+	motivo := r.FormValue("motivo")
+	if propriedadeID == 0 || !ok || !contem(models.MotivosAjusteRebanho, motivo) {
+		app.clientError(w, "Informe a data e o motivo do ajuste.")
+		return
+	}
+
+	var cadastro map[string]int
+	if r.FormValue("origem") == "cadastro" {
+		animais, err := app.carregarAnimais(propriedadeID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		cadastro, _ = services.EstoquePorAnimais(animais, data)
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	estoque, err := app.carregarEstoqueRebanho(tx, propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	alteradas := 0
+	for i, c := range models.CategoriasGTA {
+		novo := estoque[c]
+		campo := fmt.Sprintf("saldo_%d", i+1)
+		switch {
+		case cadastro != nil:
+			novo = cadastro[c]
+		case strings.TrimSpace(r.FormValue(campo)) != "":
+			novo = formInt(r, campo)
+		}
+		if novo < 0 {
+			app.clientError(w, fmt.Sprintf("Saldo negativo em %s.", c))
+			return
+		}
+		if novo == estoque[c] {
+			continue
+		}
+
+		if _, err := tx.Exec(
+			`INSERT INTO rebanho_ajustes (propriedade_id, data, motivo, categoria, anterior, quantidade) VALUES (?, ?, ?, ?, ?, ?)`,
+			propriedadeID, data, motivo, c, estoque[c], novo,
+		); err != nil {
+			log.Printf("❌ Erro ao registrar ajuste do rebanho: %v", err)
+			app.serverError(w, r, err)
+			return
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO rebanho_estoque (propriedade_id, categoria, quantidade) VALUES (?, ?, ?)
+			ON CONFLICT (propriedade_id, categoria) DO UPDATE SET quantidade = excluded.quantidade, atualizado_em = now()`,
+			propriedadeID, c, novo,
+		); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		alteradas++
+	}
+	if alteradas == 0 {
+		app.clientError(w, "Nenhuma categoria teve o saldo alterado.")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	log.Printf("✅ Estoque do rebanho ajustado (%s) - %d categorias", motivo, alteradas)
+	setToast(w, "Estoque do rebanho ajustado.", "success")
+	app.ListaMovimentacoes(w, r)
+}
+
+// ExportarRastreabilidade baixa o arquivo de largura fixa com as GTAs do período
+func (app *Application) ExportarRastreabilidade(w http.ResponseWriter, r *http.Request) {
+	propriedadeID := formInt(r, "propriedade_id")
+	propriedade, err := app.buscarPropriedade(propriedadeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	inicio, ok := formData(r, "inicio")
+	if !ok {
+		inicio = time.Date(time.Now().Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	fim, ok := formData(r, "fim")
+	if !ok {
+		fim = time.Now()
+	}
+
+	var cpfCnpj string
+	app.DB.QueryRow("SELECT COALESCE(cpf_cnpj, '') FROM clientes WHERE id = ?", propriedade.ClienteID).Scan(&cpfCnpj)
+
+	movs, err := app.carregarMovimentacoes(propriedadeID, inicio, fim)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	// O arquivo segue a ordem cronológica
+	for i, j := 0, len(movs)-1; i < j; i, j = i+1, j-1 {
+		movs[i], movs[j] = movs[j], movs[i]
+	}
+
+	arquivo := services.ExportarRastreabilidade(cpfCnpj, propriedade, movs, inicio, fim, time.Now())
+	nome := fmt.Sprintf("rastreabilidade_%d_%s_%s.txt", propriedadeID, inicio.Format("20060102"), fim.Format("20060102"))
+
+	w.Header().Set("Content-Type", "text/plain; charset=us-ascii")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+nome+`"`)
+	w.Write([]byte(arquivo))
+}
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// PlanejadorPastejo exibe os piquetes do talhão, a escala de ocupação e os alertas de permanência
+func (app *Application) PlanejadorPastejo(w http.ResponseWriter, r *http.Request) {
+	talhaoID := formInt(r, "talhao_id")
+	talhao, err := app.buscarTalhao(talhaoID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	propriedade, err := app.buscarPropriedade(talhao.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	piquetes, err := app.carregarPiquetes(talhaoID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	planos, err := app.carregarPlanosPastejo(talhaoID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	ocupacoes, err := app.carregarOcupacoes("WHERE p.talhao_id = ?", talhaoID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	var planoAtual models.PlanoPastejo
+	var lotacaoMedia, lotacaoInstantanea float64
+	if len(planos) > 0 {
+		planoAtual = planos[0]
+		lotacaoMedia, lotacaoInstantanea = services.TaxaLotacao(planoAtual.UA, piquetes)
+	}
+
+	data := map[string]interface{}{
+		"Propriedade":        propriedade,
+		"Talhao":             talhao,
+		"Piquetes":           piquetes,
+		"Planos":             planos,
+		"PlanoAtual":         planoAtual,
+		"Ocupacoes":          ocupacoes,
+		"Alertas":            services.VerificarPermanencia(ocupacoes, time.Now()),
+		"LotacaoMedia":       lotacaoMedia,
+		"LotacaoInstantanea": lotacaoInstantanea,
+		"Forrageiras":        services.Forrageiras,
+		"Title":              "Pastejo Rotacionado",
+	}
+	app.renderTemplate(w, r, "pastejo/planejador.html", data)
+}
+
+func (app *Application) carregarPiquetes(talhaoID int) ([]models.Piquete, error) {
+	rows, err := app.DB.Query(`
+		SELECT id, talhao_id, numero, area_hectares FROM piquetes WHERE talhao_id = ? ORDER BY numero`, talhaoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var piquetes []models.Piquete
+	for rows.Next() {
+		var p models.Piquete
+		if err := rows.Scan(&p.ID, &p.TalhaoID, &p.Numero, &p.AreaHectares); err != nil {
+			return nil, err
+		}
+		piquetes = append(piquetes, p)
+	}
+	return piquetes, rows.Err()
+}
+
+func (app *Application) carregarPlanosPastejo(talhaoID int) ([]models.PlanoPastejo, error) {
+	rows, err := app.DB.Query(`
+		SELECT id, talhao_id, lote, ua, COALESCE(forrageira, ''), dias_descanso, dias_ocupacao, data_inicio, ciclos
+		FROM planos_pastejo
+		WHERE talhao_id = ?
+		ORDER BY data_inicio DESC, id DESC`, talhaoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var planos []models.PlanoPastejo
+	for rows.Next() {
+		var p models.PlanoPastejo
+		if err := rows.Scan(&p.ID, &p.TalhaoID, &p.Lote, &p.UA, &p.Forrageira, &p.DiasDescanso,
+			&p.DiasOcupacao, &p.DataInicio, &p.Ciclos); err != nil {
+			return nil, err
+		}
+		planos = append(planos, p)
+	}
+	return planos, rows.Err()
+}
+
+// carregarOcupacoes lista a escala com o filtro informado (sobre piquetes p e ocupacoes o)
+func (app *Application) carregarOcupacoes(filtro string, args ...any) ([]models.OcupacaoPiquete, error) {
+	rows, err := app.DB.Query(`
+		SELECT o.id, o.plano_id, o.piquete_id, p.numero, o.lote, o.ciclo,
+		       o.entrada_prevista, o.saida_prevista, o.entrada_real, o.saida_real
+		FROM ocupacoes_piquete o
+		JOIN piquetes p ON p.id = o.piquete_id
+		`+filtro+`
+		ORDER BY o.entrada_prevista, o.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ocupacoes []models.OcupacaoPiquete
+	for rows.Next() {
+		var o models.OcupacaoPiquete
+		if err := rows.Scan(&o.ID, &o.PlanoID, &o.PiqueteID, &o.PiqueteNumero, &o.Lote, &o.Ciclo,
+			&o.EntradaPrevista, &o.SaidaPrevista, &o.EntradaReal, &o.SaidaReal); err != nil {
+			return nil, err
+		}
+		ocupacoes = append(ocupacoes, o)
+	}
+	return ocupacoes, rows.Err()
+}
+
+// GerarPiquetes divide o talhão em piquetes de mesma área (ou com a área informada).
+// A divisão só pode ser refeita enquanto nenhum piquete tiver ocupação registrada.
+func (app *Application) GerarPiquetes(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	talhaoID := formInt(r, "talhao_id")
+	talhao, err := app.buscarTalhao(talhaoID)
+	if err != nil {
+		app.clientError(w, "Talhão não encontrado.")
+		return
+	}
+	quantidade := formInt(r, "quantidade")
+	if quantidade < 2 || quantidade > 200 {
+		app.clientError(w, "Informe entre 2 e 200 piquetes.")
+		return
+	}
+	area := formFloat(r, "area_piquete")
+	if area <= 0 {
+		area = talhao.AreaHectares / float64(quantidade)
+	}
+	if area*float64(quantidade) > talhao.AreaHectares*1.001 {
+		app.clientError(w, "A soma das áreas dos piquetes excede a área do talhão.")
+		return
+	}
+
+	var ocupados int
+	app.DB.QueryRow(
+		`SELECT COUNT(*) FROM ocupacoes_piquete o JOIN piquetes p ON p.id = o.piquete_id WHERE p.talhao_id = ?`,
+		talhaoID,
+	).Scan(&ocupados)
+	if ocupados > 0 {
+		app.clientError(w, "O talhão já possui escala de pastejo; os piquetes não podem ser refeitos.")
+		return
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM piquetes WHERE talhao_id = ?`, talhaoID); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	for i := 1; i <= quantidade; i++ {
+		if _, err := tx.Exec(
+			`INSERT INTO piquetes (talhao_id, numero, area_hectares) VALUES (?, ?, ?)`,
+			talhaoID, i, area,
+		); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Piquetes gerados.", "success")
+	app.PlanejadorPastejo(w, r)
+}
+
+// SalvarPlanoPastejo grava os parâmetros do plano e gera a escala de ocupação
+func (app *Application) SalvarPlanoPastejo(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	plano := models.PlanoPastejo{
+		TalhaoID:     formInt(r, "talhao_id"),
+		Lote:         strings.TrimSpace(r.FormValue("lote")),
+		UA:           formFloat(r, "ua"),
+		Forrageira:   r.FormValue("forrageira"),
+		DiasDescanso: formInt(r, "dias_descanso"),
+		DiasOcupacao: formInt(r, "dias_ocupacao"),
+		Ciclos:       formInt(r, "ciclos"),
+	}
+	var ok bool
+	plano.DataInicio, ok = formData(r, "data_inicio")
+	if plano.TalhaoID == 0 || plano.Lote == "" || !ok {
+		app.clientError(w, "Informe o lote e a data de início do pastejo.")
+		return
+	}
+	if plano.UA <= 0 || plano.DiasDescanso <= 0 {
+		app.clientError(w, "UA do lote e período de descanso devem ser maiores que zero.")
+		return
+	}
+	if plano.Ciclos < 1 {
+		plano.Ciclos = 1
+	}
+
+	piquetes, err := app.carregarPiquetes(plano.TalhaoID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if len(piquetes) < 2 {
+		app.clientError(w, "Divida o talhão em piquetes antes de planejar o pastejo.")
+		return
+	}
+	if plano.DiasOcupacao <= 0 {
+		plano.DiasOcupacao = services.DiasOcupacao(plano.DiasDescanso, len(piquetes))
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
+		`INSERT INTO planos_pastejo
+		(talhao_id, lote, ua, forrageira, dias_descanso, dias_ocupacao, data_inicio, ciclos)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`,
+		plano.TalhaoID, plano.Lote, plano.UA, plano.Forrageira, plano.DiasDescanso,
+		plano.DiasOcupacao, plano.DataInicio, plano.Ciclos,
+	).Scan(&plano.ID)
+	if err != nil {
+		log.Printf("❌ Erro ao inserir plano de pastejo: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	for _, o := range services.GerarEscalaPastejo(plano, piquetes) {
+		if _, err := tx.Exec(
+			`INSERT INTO ocupacoes_piquete (plano_id, piquete_id, lote, ciclo, entrada_prevista, saida_prevista)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			o.PlanoID, o.PiqueteID, o.Lote, o.Ciclo, o.EntradaPrevista, o.SaidaPrevista,
+		); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Escala de pastejo gerada.", "success")
+	app.PlanejadorPastejo(w, r)
+}
+
+// RegistrarOcupacao grava as datas reais de entrada e saída do lote no piquete
+func (app *Application) RegistrarOcupacao(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	ocupacaoID := formInt(r, "ocupacao_id")
+	talhaoID := formInt(r, "talhao_id")
+	entrada := formDataOpcional(r, "entrada_real")
+	saida := formDataOpcional(r, "saida_real")
+	if entrada == nil && saida != nil {
+		app.clientError(w, "Informe a entrada antes da saída.")
+		return
+	}
+	if entrada != nil && saida != nil && saida.Before(*entrada) {
+		app.clientError(w, "A saída não pode ser anterior à entrada.")
+		return
+	}
+
+	res, err := app.DB.Exec(
+		`UPDATE ocupacoes_piquete SET entrada_real = ?, saida_real = ?
+		WHERE id = ? AND piquete_id IN (SELECT id FROM piquetes WHERE talhao_id = ?)`,
+		nullData(entrada), nullData(saida), ocupacaoID, talhaoID,
+	)
+	if err == nil {
+		if n, _ := res.RowsAffected(); n == 0 {
+			app.clientError(w, "Ocupação não encontrada neste talhão.")
+			return
+		}
+	}
+	if err != nil {
+		log.Printf("❌ Erro ao registrar ocupação: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Ocupação atualizada.", "success")
+	app.PlanejadorPastejo(w, r)
+}
+
+// AlertasPastejo lista os lotes que excederam a permanência nos piquetes de uma propriedade
+func (app *Application) AlertasPastejo(w http.ResponseWriter, r *http.Request) {
+	propriedadeID := formInt(r, "propriedade_id")
+	ocupacoes, err := app.carregarOcupacoes(
+		`JOIN talhoes t ON t.id = p.talhao_id WHERE t.propriedade_id = ? AND o.entrada_real IS NOT NULL`,
+		propriedadeID,
+	)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Alertas": services.VerificarPermanencia(ocupacoes, time.Now()),
+	}
+	app.renderTemplate(w, r, "pastejo/alertas.html", data)
+}
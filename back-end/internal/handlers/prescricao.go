@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// resolucaoPrescricao é a célula padrão do mapa de aplicação, próxima da
+// largura de trabalho dos distribuidores a lanço
+const resolucaoPrescricao = 20
+
+// tiposPrescricao são as opções da página, na ordem dos botões
+var tiposPrescricao = []struct{ Chave, Nome string }{
+	{services.PrescricaoCalcario, "Calagem"},
+	{services.PrescricaoFosforo, "Fósforo"},
+	{services.PrescricaoPotassio, "Potássio"},
+}
+
+// consultaPrescricao soma à consulta do mapa de fertilidade os parâmetros
+// da recomendação e o insumo do estoque usado no custo
+type consultaPrescricao struct {
+	consultaFertilidade
+	Parametros services.ParametrosPrescricao
+	InsumoID   int
+	// PrecoEstoque indica que o preço veio do custo médio do insumo e deve
+	// ser recalculado, não repetido, nas próximas consultas
+	PrecoEstoque bool
+}
+
+// lerConsultaPrescricao parte dos valores padrão do tipo e aplica os campos
+// preenchidos no formulário
+func (app *Application) lerConsultaPrescricao(r *http.Request) (consultaPrescricao, error) {
+	var c consultaPrescricao
+	fertilidade, err := app.lerConsultaFertilidade(r)
+	if err != nil {
+		return c, err
+	}
+	c.consultaFertilidade = fertilidade
+	if c.Resolucao == 0 {
+		c.Resolucao = resolucaoPrescricao
+	}
+
+	p := services.ParametrosPrescricaoPadrao(r.FormValue("tipo"))
+	decimal := func(campo string, destino *float64) {
+		if strings.TrimSpace(r.FormValue(campo)) != "" {
+			*destino = formFloat(r, campo)
+		}
+	}
+	if produto := strings.TrimSpace(r.FormValue("produto")); produto != "" {
+		p.Produto = produto
+	}
+	decimal("v_desejada", &p.VDesejada)
+	decimal("prnt", &p.PRNT)
+	decimal("profundidade", &p.Profundidade)
+	for i := range p.Doses {
+		decimal(fmt.Sprintf("dose_%d", i+1), &p.Doses[i])
+	}
+	decimal("teor", &p.Teor)
+	decimal("passo", &p.Passo)
+	decimal("preco", &p.PrecoTonelada)
+	c.Parametros = p
+
+	// O custo médio do insumo escolhido vale quando o preço não foi digitado
+	if c.InsumoID = formInt(r, "insumo_id"); c.InsumoID > 0 {
+		saldos, err := app.saldosEstoque("WHERE i.id = ? AND i.propriedade_id = ?", c.InsumoID, c.Talhao.PropriedadeID)
+		if err != nil {
+			return c, err
+		}
+		if len(saldos) == 1 {
+			c.Parametros.Produto = saldos[0].Insumo.Nome
+			if c.Parametros.PrecoTonelada == 0 {
+				c.Parametros.PrecoTonelada = precoTonelada(saldos[0])
+				c.PrecoEstoque = true
+			}
+		}
+	}
+	return c, nil
+}
+
+// precoTonelada converte o custo médio do insumo controlado em kg ou t
+func precoTonelada(s services.SaldoInsumo) float64 {
+	if s.Insumo.Unidade == "kg" {
+		return s.CustoMedio * 1000
+	}
+	return s.CustoMedio
+}
+
+// prescrever interpola os mapas que o tipo pede e calcula as zonas
+func (c consultaPrescricao) prescrever() (services.Prescricao, error) {
+	if err := c.Parametros.Validar(); err != nil {
+		return services.Prescricao{}, err
+	}
+	if c.Parametros.Tipo == services.PrescricaoCalcario {
+		c.Parametro, _ = services.BuscarParametroSolo(services.ParametroSaturacao)
+		v, err := c.interpolar()
+		if err != nil {
+			return services.Prescricao{}, err
+		}
+		ctc, err := c.interpolarValores(models.AnaliseSolo.CTC)
+		if err != nil {
+			return services.Prescricao{}, err
+		}
+		return services.PrescreverCalagem(v, ctc, c.Parametros)
+	}
+	c.Parametro, _ = services.BuscarParametroSolo(c.Parametros.Tipo)
+	mapa, err := c.interpolar()
+	if err != nil {
+		return services.Prescricao{}, err
+	}
+	return services.PrescreverAdubacao(mapa, c.Parametro, c.Parametros)
+}
+
+// valores repete a consulta para a imagem e para a exportação
+func (c consultaPrescricao) valores() url.Values {
+	p := c.Parametros
+	v := url.Values{}
+	v.Set("talhao_id", fmt.Sprint(c.Talhao.ID))
+	v.Set("tipo", p.Tipo)
+	v.Set("metodo", c.Metodo)
+	v.Set("resolucao", fmt.Sprint(c.Resolucao))
+	if !c.Data.IsZero() {
+		v.Set("data", c.Data.Format("2006-01-02"))
+	}
+	v.Set("produto", p.Produto)
+	if c.InsumoID > 0 {
+		v.Set("insumo_id", fmt.Sprint(c.InsumoID))
+	}
+	if p.Tipo == services.PrescricaoCalcario {
+		v.Set("v_desejada", fmt.Sprint(p.VDesejada))
+		v.Set("prnt", fmt.Sprint(p.PRNT))
+		v.Set("profundidade", fmt.Sprint(p.Profundidade))
+	} else {
+		for i, d := range p.Doses {
+			v.Set(fmt.Sprintf("dose_%d", i+1), fmt.Sprint(d))
+		}
+		v.Set("teor", fmt.Sprint(p.Teor))
+	}
+	v.Set("passo", fmt.Sprint(p.Passo))
+	if !c.PrecoEstoque {
+		v.Set("preco", fmt.Sprint(p.PrecoTonelada))
+	}
+	return v
+}
+
+// PrescricaoTalhao mostra as zonas de aplicação em taxa variável do
+// talhão (calcário, fósforo ou potássio), com dose, área, total de produto
+// e custo por zona
+func (app *Application) PrescricaoTalhao(w http.ResponseWriter, r *http.Request) {
+	c, err := app.lerConsultaPrescricao(r)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		log.Printf("❌ Erro ao carregar dados da prescrição: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+	propriedade, err := app.buscarPropriedade(c.Talhao.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	insumos, err := app.saldosEstoque("WHERE i.propriedade_id = ? AND COALESCE(i.ativo, true) AND i.unidade IN ('kg', 't')", propriedade.ID)
+	if err != nil {
+		log.Printf("❌ Erro ao carregar insumos: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	camadas := services.NovaColecao()
+	if len(c.Talhao.Limite) > 0 {
+		camadas.AdicionarPoligono(c.Talhao.Limite, map[string]any{"tipo": "limite", "nome": c.Talhao.Nome})
+	}
+	for _, a := range c.Analises {
+		camadas.AdicionarPonto(a.Ponto.Ponto, map[string]any{"tipo": "ponto", "codigo": a.Ponto.Codigo})
+	}
+
+	// Faixas das classes do nutriente, para rotular as doses da adubação
+	var faixas []string
+	if parametro, ok := services.BuscarParametroSolo(c.Parametros.Tipo); ok {
+		for i := range services.ClassesFertilidade {
+			faixas = append(faixas, parametro.Faixa(i)+" "+parametro.Unidade)
+		}
+	}
+
+	valores := c.valores()
+	data := map[string]interface{}{
+		"Propriedade":  propriedade,
+		"Talhao":       c.Talhao,
+		"Tipos":        tiposPrescricao,
+		"Tipo":         c.Parametros.Tipo,
+		"Calcario":     services.PrescricaoCalcario,
+		"Parametros":   c.Parametros,
+		"Classes":      services.ClassesFertilidade,
+		"Faixas":       faixas,
+		"Metodo":       c.Metodo,
+		"MetodoIDW":    services.InterpolacaoIDW,
+		"MetodoKrige":  services.InterpolacaoKrigagem,
+		"Resolucao":    c.Resolucao,
+		"Resolucoes":   []float64{10, 20, 30, 50},
+		"Campanhas":    c.Campanhas,
+		"Data":         c.Data,
+		"Amostras":     len(c.Analises),
+		"Insumos":      insumos,
+		"InsumoID":     c.InsumoID,
+		"PrecoEstoque": c.PrecoEstoque,
+		"Consulta":     valores,
+		"Camadas":      camadas,
+		"Title":        "Prescrição em Taxa Variável",
+	}
+	if len(c.Analises) > 0 {
+		prescricao, err := c.prescrever()
+		if err != nil {
+			data["Erro"] = err.Error()
+		} else {
+			data["Prescricao"] = prescricao
+			data["Imagem"] = "/talhoes/prescricao/png?" + valores.Encode()
+			data["Limites"] = [2][2]float64{{prescricao.Mapa.Sul, prescricao.Mapa.Oeste}, {prescricao.Mapa.Norte, prescricao.Mapa.Leste}}
+		}
+	}
+	app.renderTemplate(w, r, "talhoes/prescricao.html", data)
+}
+
+// ImagemPrescricao entrega as zonas em PNG transparente para o mapa
+func (app *Application) ImagemPrescricao(w http.ResponseWriter, r *http.Request) {
+	c, err := app.lerConsultaPrescricao(r)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	prescricao, err := c.prescrever()
+	if err != nil {
+		app.clientError(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(prescricao.PNG())
+}
+
+// ExportarPrescricao baixa as zonas em Shapefile ou ISO-XML (TASKDATA do
+// ISOBUS), ambos com o resumo por zona em CSV, ou só o resumo
+func (app *Application) ExportarPrescricao(w http.ResponseWriter, r *http.Request) {
+	c, err := app.lerConsultaPrescricao(r)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	propriedade, err := app.buscarPropriedade(c.Talhao.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	prescricao, err := c.prescrever()
+	if err != nil {
+		app.clientError(w, err.Error())
+		return
+	}
+
+	arquivo := fmt.Sprintf("prescricao_%s_t%d", c.Parametros.Tipo, c.Talhao.ID)
+	var conteudo []byte
+	tipo, nome := "application/zip", arquivo
+	switch r.FormValue("formato") {
+	case "shp":
+		conteudo, err = services.ExportarShapefilePrescricao(arquivo, prescricao)
+		nome += "_shp.zip"
+	case "isoxml":
+		conteudo, err = services.ExportarISOXML(services.TarefaISOXML{
+			Cliente:     propriedade.ClienteNome,
+			Propriedade: propriedade.Nome,
+			Talhao:      c.Talhao.Nome,
+			Descricao:   fmt.Sprintf("%s - %s", c.Parametros.Produto, c.Talhao.Nome),
+		}, c.Talhao.Limite, prescricao)
+		nome += "_isoxml.zip"
+	default:
+		conteudo = services.ResumoPrescricaoCSV(prescricao)
+		tipo, nome = "text/csv; charset=utf-8", arquivo+"_resumo.csv"
+	}
+	if err != nil {
+		app.clientError(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", tipo)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+nome+`"`)
+	w.Write(conteudo)
+}
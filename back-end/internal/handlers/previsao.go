@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// carregarChuvas lista os registros de chuva com o filtro informado (sobre chuvas c)
+func (app *Application) carregarChuvas(filtro string, args ...any) ([]models.Chuva, error) {
+	rows, err := app.DB.Query(`
+		SELECT c.id, c.propriedade_id, c.data, c.milimetros, COALESCE(c.observacoes, '')
+		FROM chuvas c
+		`+filtro+`
+		ORDER BY c.data DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chuvas []models.Chuva
+	for rows.Next() {
+		var c models.Chuva
+		if err := rows.Scan(&c.ID, &c.PropriedadeID, &c.Data, &c.Milimetros, &c.Observacoes); err != nil {
+			return nil, err
+		}
+		chuvas = append(chuvas, c)
+	}
+	return chuvas, rows.Err()
+}
+
+// previsoesColheita calcula a previsão de colheita de todas as safras do
+// filtro (sobre talhoes t), usando o histórico dos mesmos talhões, a chuva
+// registrada nas propriedades e os estádios vistos no monitoramento
+func (app *Application) previsoesColheita(filtro string, id int) ([]services.PrevisaoColheita, error) {
+	safras, err := app.carregarSafras("WHERE "+filtro, id)
+	if err != nil {
+		return nil, err
+	}
+	colheitas, err := app.carregarColheitas("WHERE "+filtro, id)
+	if err != nil {
+		return nil, err
+	}
+	chuvas, err := app.carregarChuvas(`WHERE c.propriedade_id IN (SELECT t.propriedade_id FROM talhoes t WHERE `+filtro+`)`, id)
+	if err != nil {
+		return nil, err
+	}
+	monitoramentos, err := app.carregarMonitoramentos("WHERE "+filtro, id)
+	if err != nil {
+		return nil, err
+	}
+
+	produtividades := make([]services.ProdutividadeSafra, 0, len(safras))
+	for _, s := range safras {
+		produtividades = append(produtividades, services.CalcularProdutividade(s, colheitas))
+	}
+	hoje := horaLocal()
+	previsoes := make([]services.PrevisaoColheita, 0, len(safras))
+	for _, s := range safras {
+		previsoes = append(previsoes, services.PreverColheita(s, produtividades, colheitas, chuvas, monitoramentos, hoje))
+	}
+	return previsoes, nil
+}
+
+// SalvarChuva registra a chuva do dia na propriedade, substituindo o
+// registro anterior da mesma data
+func (app *Application) SalvarChuva(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	propriedade, err := app.buscarPropriedade(formInt(r, "propriedade_id"))
+	if err != nil {
+		app.clientError(w, "Propriedade não encontrada.")
+		return
+	}
+	dia, ok := formData(r, "data")
+	milimetros := formFloat(r, "milimetros")
+	if !ok || milimetros < 0 || strings.TrimSpace(r.FormValue("milimetros")) == "" {
+		app.clientError(w, "Informe a data e a chuva em mm.")
+		return
+	}
+	if dia.After(horaLocal()) {
+		app.clientError(w, "A data da chuva não pode ser futura.")
+		return
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM chuvas WHERE propriedade_id = ? AND data = ?`, propriedade.ID, dia)
+	if err == nil {
+		_, err = tx.Exec(`INSERT INTO chuvas (propriedade_id, data, milimetros, observacoes) VALUES (?, ?, ?, ?)`,
+			propriedade.ID, dia, milimetros, strings.TrimSpace(r.FormValue("observacoes")))
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("❌ Erro ao registrar chuva: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Chuva registrada.", "success")
+	if r.FormValue("origem") == "clima" {
+		app.ClimaPropriedade(w, r)
+		return
+	}
+	r.Form.Set("id", r.FormValue("safra_id"))
+	app.DetalheSafra(w, r)
+}
+
+// RelatorioPrevisao consolida a previsão de colheita das safras ainda não
+// colhidas, por propriedade ou cliente, com os volumes por mês e cultura
+func (app *Application) RelatorioPrevisao(w http.ResponseWriter, r *http.Request) {
+	data := map[string]interface{}{
+		"Title": "Previsão de Colheita",
+	}
+
+	filtro, id, ok := app.escopoRelatorio(w, r, data)
+	if !ok {
+		return
+	}
+
+	todas, err := app.previsoesColheita(filtro, id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	var previsoes []services.PrevisaoColheita
+	var area, toneladas, minima, maxima float64
+	for _, p := range todas {
+		if p.Estadio == services.EstadioColhida {
+			continue
+		}
+		previsoes = append(previsoes, p)
+		if p.Disponivel() {
+			area += p.Safra.AreaHectares
+			toneladas += p.Toneladas()
+			minima += p.ToneladasMinima()
+			maxima += p.ToneladasMaxima()
+		}
+	}
+
+	sort.SliceStable(previsoes, func(i, j int) bool {
+		return previsoes[i].Safra.ColheitaPrevista.Before(previsoes[j].Safra.ColheitaPrevista)
+	})
+
+	data["Previsoes"] = previsoes
+	data["Resumo"] = services.ConsolidarPrevisoes(previsoes)
+	data["AreaTotal"] = area
+	data["Toneladas"] = toneladas
+	data["ToneladasMinima"] = minima
+	data["ToneladasMaxima"] = maxima
+	app.renderTemplate(w, r, "relatorios/previsao.html", data)
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// carregarColheitas lista as cargas colhidas com o filtro informado (sobre
+// colheitas h, safras s e talhoes t)
+func (app *Application) carregarColheitas(filtro string, args ...any) ([]models.Colheita, error) {
+	rows, err := app.DB.Query(`
+		SELECT h.id, h.safra_id, h.data, COALESCE(h.area_colhida, 0), h.peso_bruto_kg,
+		       COALESCE(h.umidade, 0), COALESCE(h.impureza, 0), COALESCE(h.observacoes, '')
+		FROM colheitas h
+		JOIN safras s ON s.id = h.safra_id
+		JOIN talhoes t ON t.id = s.talhao_id
+		`+filtro+`
+		ORDER BY h.data, h.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var colheitas []models.Colheita
+	for rows.Next() {
+		var c models.Colheita
+		if err := rows.Scan(&c.ID, &c.SafraID, &c.Data, &c.AreaColhida, &c.PesoBrutoKg,
+			&c.Umidade, &c.Impureza, &c.Observacoes); err != nil {
+			return nil, err
+		}
+		colheitas = append(colheitas, c)
+	}
+	return colheitas, rows.Err()
+}
+
+// SalvarColheita registra uma carga colhida na safra
+func (app *Application) SalvarColheita(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	safra, err := app.buscarSafra(formInt(r, "safra_id"))
+	if err != nil {
+		app.clientError(w, "Safra não encontrada.")
+		return
+	}
+
+	c := models.Colheita{
+		SafraID:     safra.ID,
+		AreaColhida: formFloat(r, "area_colhida"),
+		PesoBrutoKg: formFloat(r, "peso_bruto_kg"),
+		Umidade:     formFloat(r, "umidade"),
+		Impureza:    formFloat(r, "impureza"),
+		Observacoes: strings.TrimSpace(r.FormValue("observacoes")),
+	}
+	var ok bool
+	c.Data, ok = formData(r, "data")
+	if !ok || c.PesoBrutoKg <= 0 {
+		app.clientError(w, "Informe a data e o peso bruto da carga.")
+		return
+	}
+	if c.Umidade < 0 || c.Umidade >= 50 || c.Impureza < 0 || c.Impureza >= 50 {
+		app.clientError(w, "Umidade e impureza devem estar entre 0 e 50%.")
+		return
+	}
+	plantio := safra.PlantioPrevisto
+	if safra.PlantioReal != nil {
+		plantio = *safra.PlantioReal
+	}
+	if !c.Data.After(plantio) {
+		app.clientError(w, "A colheita deve ser posterior ao plantio.")
+		return
+	}
+
+	// A soma das áreas informadas nas cargas não pode passar da área plantada
+	var areaColhida float64
+	app.DB.QueryRow(`SELECT COALESCE(SUM(area_colhida), 0) FROM colheitas WHERE safra_id = ?`, safra.ID).Scan(&areaColhida)
+	if areaColhida+c.AreaColhida > safra.AreaHectares*1.001 {
+		app.clientError(w, "A área colhida excede a área da safra.")
+		return
+	}
+
+	_, err = app.DB.Exec(
+		`INSERT INTO colheitas (safra_id, data, area_colhida, peso_bruto_kg, umidade, impureza, observacoes)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		c.SafraID, c.Data, c.AreaColhida, c.PesoBrutoKg, c.Umidade, c.Impureza, c.Observacoes,
+	)
+	if err != nil {
+		log.Printf("❌ Erro ao registrar colheita: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	r.Form.Set("id", r.FormValue("safra_id"))
+	setToast(w, "Carga registrada.", "success")
+	app.DetalheSafra(w, r)
+}
+
+// escopoRelatorio identifica se o relatório é de uma propriedade ou, com
+// cliente_id, de todas as propriedades do cliente. Preenche o cabeçalho em
+// data e devolve o filtro sobre talhoes t. Em caso de erro já respondeu.
+func (app *Application) escopoRelatorio(w http.ResponseWriter, r *http.Request, data map[string]interface{}) (string, int, bool) {
+	if propriedadeID := formInt(r, "propriedade_id"); propriedadeID > 0 {
+		propriedade, err := app.buscarPropriedade(propriedadeID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				http.NotFound(w, r)
+			} else {
+				app.serverError(w, r, err)
+			}
+			return "", 0, false
+		}
+		data["Propriedade"] = propriedade
+		return "t.propriedade_id = ?", propriedadeID, true
+	}
+
+	clienteID := formInt(r, "cliente_id")
+	var nome string
+	err := app.DB.QueryRow(`SELECT nome FROM clientes WHERE id = ?`, clienteID).Scan(&nome)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return "", 0, false
+	}
+	data["ClienteID"] = clienteID
+	data["ClienteNome"] = nome
+	return "t.propriedade_id IN (SELECT id FROM propriedades WHERE cliente_id = ?)", clienteID, true
+}
+
+// RelatorioProdutividade exibe o ranking de produtividade dos talhões, a
+// comparação com a meta e a tendência entre safras, por propriedade ou,
+// com cliente_id, de todas as propriedades do cliente
+func (app *Application) RelatorioProdutividade(w http.ResponseWriter, r *http.Request) {
+	cultura := r.FormValue("cultura")
+
+	data := map[string]interface{}{
+		"Cultura": cultura,
+		"Title":   "Produtividade por Talhão",
+	}
+
+	filtro, id, ok := app.escopoRelatorio(w, r, data)
+	if !ok {
+		return
+	}
+
+	safras, err := app.carregarSafras("WHERE "+filtro, id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	colheitas, err := app.carregarColheitas("WHERE "+filtro, id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Culturas com colheita registrada, para o filtro
+	vistas := make(map[string]bool)
+	var culturas []string
+	var produtividades []services.ProdutividadeSafra
+	for _, s := range safras {
+		p := services.CalcularProdutividade(s, colheitas)
+		if p.Cargas == 0 {
+			continue
+		}
+		if !vistas[s.Cultura] {
+			vistas[s.Cultura] = true
+			culturas = append(culturas, s.Cultura)
+		}
+		if cultura == "" || s.Cultura == cultura {
+			produtividades = append(produtividades, p)
+		}
+	}
+	sort.Strings(culturas)
+
+	data["Culturas"] = culturas
+	data["Ranking"] = services.RankingTalhoes(produtividades)
+	data["Tendencias"] = services.TendenciaTalhoes(produtividades)
+	app.renderTemplate(w, r, "relatorios/produtividade.html", data)
+}
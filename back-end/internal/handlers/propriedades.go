@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// buscarPropriedade carrega a propriedade com o nome do cliente, usada no
+// cabeçalho das telas de cada módulo
+func (app *Application) buscarPropriedade(id int) (models.Propriedade, error) {
+	var p models.Propriedade
+	var latitude, longitude sql.NullFloat64
+	var limite string
+	err := app.DB.QueryRow(`
+		SELECT p.id, p.cliente_id, c.nome, p.nome, COALESCE(p.hectares, 0),
+		       COALESCE(p.municipio, ''), COALESCE(p.estado, ''),
+		       p.sede_latitude, p.sede_longitude, COALESCE(p.limite, ''), COALESCE(p.area_limite, 0)
+		FROM propriedades p
+		JOIN clientes c ON c.id = p.cliente_id
+		WHERE p.id = ?`, id,
+	).Scan(&p.ID, &p.ClienteID, &p.ClienteNome, &p.Nome, &p.Hectares, &p.Municipio, &p.Estado,
+		&latitude, &longitude, &limite, &p.AreaLimite)
+	if err != nil {
+		return p, err
+	}
+	if latitude.Valid && longitude.Valid {
+		p.Sede = &models.Ponto{Latitude: latitude.Float64, Longitude: longitude.Float64}
+	}
+	if p.Limite, err = services.LerPoligonoGeoJSON(limite); err != nil {
+		log.Printf("⚠️  Limite inválido na propriedade %d: %v", p.ID, err)
+		p.Limite, p.AreaLimite = nil, 0
+	}
+	return p, nil
+}
+
+// conferenciaTalhao compara o limite importado do talhão com a área cadastrada
+type conferenciaTalhao struct {
+	Talhao      models.Talhao
+	Conferencia services.ConferenciaArea
+	Divergente  bool
+}
+
+// LocalizacaoPropriedade mostra a sede em graus decimais, GMS e UTM, o
+// limite com a área calculada e a conferência com a área declarada
+func (app *Application) LocalizacaoPropriedade(w http.ResponseWriter, r *http.Request) {
+	propriedade, err := app.buscarPropriedade(formInt(r, "propriedade_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	talhoes, err := app.carregarTalhoes(propriedade.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	var desenhos []services.PoligonoNomeado
+	var conferencias []conferenciaTalhao
+	for _, t := range talhoes {
+		if len(t.Limite) == 0 {
+			continue
+		}
+		desenhos = append(desenhos, services.PoligonoNomeado{Nome: t.Nome, Anel: t.Limite})
+		c := services.ConferirArea(t.AreaLimite, t.AreaHectares)
+		conferencias = append(conferencias, conferenciaTalhao{Talhao: t, Conferencia: c, Divergente: c.ForaDaTolerancia()})
+	}
+
+	data := map[string]interface{}{
+		"Propriedade": propriedade,
+		"Talhoes":     conferencias,
+		"SemLimite":   len(talhoes) - len(conferencias),
+		"Desenho":     services.DesenharPropriedade(propriedade.Limite, desenhos, propriedade.Sede, 480, 320),
+		"Tolerancia":  services.ToleranciaAreaPercentual,
+		"Title":       "Localização da Propriedade",
+	}
+	if propriedade.Sede != nil {
+		data["SedeDecimal"] = services.FormatarDecimalGraus(*propriedade.Sede)
+		data["SedeGMS"] = services.FormatarGMS(*propriedade.Sede)
+		data["SedeUTM"] = services.ParaUTM(*propriedade.Sede).String()
+		data["ForaDoBrasil"] = !services.NoBrasil(*propriedade.Sede)
+	}
+	if len(propriedade.Limite) > 0 {
+		conferencia := services.ConferirArea(propriedade.AreaLimite, propriedade.Hectares)
+		data["Conferencia"] = conferencia
+		data["AreaDivergente"] = conferencia.ForaDaTolerancia()
+
+		// Vértices em graus decimais, um por linha, para edição
+		linhas := make([]string, len(propriedade.Limite))
+		for i, p := range propriedade.Limite {
+			linhas[i] = services.FormatarDecimalGraus(p)
+		}
+		data["LimiteTexto"] = strings.Join(linhas, "\n")
+	}
+	app.renderTemplate(w, r, "propriedades/localizacao.html", data)
+}
+
+// SalvarSedePropriedade grava a sede a partir de uma coordenada em graus
+// decimais, GMS ou UTM; coordenada vazia remove a sede
+func (app *Application) SalvarSedePropriedade(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	propriedade, err := app.buscarPropriedade(formInt(r, "propriedade_id"))
+	if err != nil {
+		app.clientError(w, "Propriedade não encontrada.")
+		return
+	}
+
+	texto := strings.TrimSpace(r.FormValue("coordenada"))
+	if texto == "" {
+		if _, err := app.DB.Exec(`UPDATE propriedades SET sede_latitude = NULL, sede_longitude = NULL WHERE id = ?`, propriedade.ID); err != nil {
+			log.Printf("❌ Erro ao remover sede: %v", err)
+			app.serverError(w, r, err)
+			return
+		}
+		setToast(w, "Sede removida.", "success")
+		app.LocalizacaoPropriedade(w, r)
+		return
+	}
+
+	sede, formato, err := services.LerPonto(texto)
+	if err != nil {
+		app.clientError(w, "Coordenada inválida: "+err.Error()+".")
+		return
+	}
+	if _, err := app.DB.Exec(`UPDATE propriedades SET sede_latitude = ?, sede_longitude = ? WHERE id = ?`,
+		sede.Latitude, sede.Longitude, propriedade.ID); err != nil {
+		log.Printf("❌ Erro ao salvar sede: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !services.NoBrasil(sede) {
+		setToast(w, "Sede salva, mas a coordenada fica fora do Brasil. Confira sinais e hemisférios.", "warning")
+	} else {
+		setToast(w, fmt.Sprintf("Sede salva (lida em %s).", formato), "success")
+	}
+	app.LocalizacaoPropriedade(w, r)
+}
+
+// SalvarLimitePropriedade valida o polígono do limite, grava em GeoJSON com
+// a área geodésica e avisa quando ela diverge dos hectares declarados
+func (app *Application) SalvarLimitePropriedade(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	propriedade, err := app.buscarPropriedade(formInt(r, "propriedade_id"))
+	if err != nil {
+		app.clientError(w, "Propriedade não encontrada.")
+		return
+	}
+
+	if r.FormValue("acao") == "remover" {
+		if _, err := app.DB.Exec(`UPDATE propriedades SET limite = NULL, area_limite = NULL WHERE id = ?`, propriedade.ID); err != nil {
+			log.Printf("❌ Erro ao remover limite: %v", err)
+			app.serverError(w, r, err)
+			return
+		}
+		setToast(w, "Limite removido.", "success")
+		app.LocalizacaoPropriedade(w, r)
+		return
+	}
+
+	limite, err := services.LerPoligono(r.FormValue("limite"))
+	if err != nil {
+		app.clientError(w, "Limite inválido: "+err.Error()+".")
+		return
+	}
+	area := services.AreaGeodesica(limite)
+	if err := gravarLimite(app.DB, propriedade, limite, r.FormValue("atualizar_hectares") == "1"); err != nil {
+		log.Printf("❌ Erro ao salvar limite: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	conferencia := services.ConferirArea(area, propriedade.Hectares)
+	switch {
+	case r.FormValue("atualizar_hectares") == "1":
+		setToast(w, fmt.Sprintf("Limite salvo; área declarada atualizada para %s ha.", services.FormatarDecimal(area, 2)), "success")
+	case conferencia.ForaDaTolerancia():
+		setToast(w, fmt.Sprintf("Limite salvo com %s ha: %s.", services.FormatarDecimal(area, 2), conferencia.Texto()), "warning")
+	default:
+		setToast(w, fmt.Sprintf("Limite salvo com %s ha.", services.FormatarDecimal(area, 2)), "success")
+	}
+	app.LocalizacaoPropriedade(w, r)
+}
+
+// gravarLimite grava o polígono já validado e sua área; com
+// atualizarHectares a área declarada passa a ser a calculada
+func gravarLimite(db executor, propriedade models.Propriedade, limite []models.Ponto, atualizarHectares bool) error {
+	area := services.AreaGeodesica(limite)
+	hectares := propriedade.Hectares
+	if atualizarHectares {
+		hectares = area
+	}
+	_, err := db.Exec(`UPDATE propriedades SET limite = ?, area_limite = ?, hectares = ? WHERE id = ?`,
+		services.PoligonoGeoJSON(limite), area, hectares, propriedade.ID)
+	return err
+}
+
+// MigrarCoordenadas converte o texto livre da antiga coluna coordenadas em
+// sede. Valores que não forem reconhecidos permanecem na coluna e são
+// informados no log para correção manual.
+func (app *Application) MigrarCoordenadas() {
+	var existe int
+	app.DB.QueryRow(`SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_name = 'propriedades' AND column_name = 'coordenadas'`).Scan(&existe)
+	if existe == 0 {
+		return
+	}
+
+	rows, err := app.DB.Query(`SELECT id, coordenadas FROM propriedades
+		WHERE sede_latitude IS NULL AND trim(COALESCE(coordenadas, '')) <> ''`)
+	if err != nil {
+		log.Printf("❌ Erro ao ler coordenadas antigas: %v", err)
+		return
+	}
+	type antiga struct {
+		id    int
+		texto string
+	}
+	var pendentes []antiga
+	for rows.Next() {
+		var a antiga
+		if err := rows.Scan(&a.id, &a.texto); err == nil {
+			pendentes = append(pendentes, a)
+		}
+	}
+	rows.Close()
+
+	for _, a := range pendentes {
+		sede, _, err := services.LerPonto(a.texto)
+		if err != nil {
+			log.Printf("⚠️  Coordenadas da propriedade %d não reconhecidas (%q): %v", a.id, a.texto, err)
+			continue
+		}
+		if _, err := app.DB.Exec(`UPDATE propriedades SET sede_latitude = ?, sede_longitude = ?, coordenadas = NULL WHERE id = ?`,
+			sede.Latitude, sede.Longitude, a.id); err != nil {
+			log.Printf("❌ Erro ao migrar coordenadas da propriedade %d: %v", a.id, err)
+			continue
+		}
+		log.Printf("📍 Coordenadas da propriedade %d migradas para a sede", a.id)
+	}
+}
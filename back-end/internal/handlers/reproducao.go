@@ -0,0 +1,521 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// IndicadoresEstacao agrupa a estação de monta com seus indicadores calculados
+type IndicadoresEstacao struct {
+	Estacao     models.EstacaoMonta
+	Indicadores services.IndicadoresReprodutivos
+}
+
+// PainelReproducao exibe estações de monta, coberturas e indicadores de uma propriedade
+func (app *Application) PainelReproducao(w http.ResponseWriter, r *http.Request) {
+	propriedadeID := formInt(r, "propriedade_id")
+	propriedade, err := app.buscarPropriedade(propriedadeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	animais, err := app.carregarAnimais(propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	var femeas []models.Animal
+	for _, a := range animais {
+		if a.Sexo == "F" {
+			femeas = append(femeas, a)
+		}
+	}
+
+	estacoes, err := app.carregarEstacoesMonta(propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	protocolos, err := app.carregarProtocolosIATF()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	inseminacoes, err := app.carregarInseminacoes(propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	diagnosticos, err := app.carregarDiagnosticos(propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	partos, err := app.carregarPartos(propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	var indicadores []IndicadoresEstacao
+	for _, e := range estacoes {
+		indicadores = append(indicadores, IndicadoresEstacao{
+			Estacao:     e,
+			Indicadores: services.CalcularIndicadoresReprodutivos(e, inseminacoes, diagnosticos, partos),
+		})
+	}
+	iep, intervalos := services.IntervaloEntrePartos(partos)
+
+	data := map[string]interface{}{
+		"Propriedade":     propriedade,
+		"Femeas":          femeas,
+		"Estacoes":        indicadores,
+		"Protocolos":      protocolos,
+		"Inseminacoes":    inseminacoes,
+		"Partos":          partos,
+		"IntervaloPartos": iep,
+		"Intervalos":      intervalos,
+		"TiposCobertura":  []string{models.CoberturaIA, models.CoberturaIATF, models.CoberturaMonta},
+		"Title":           "Manejo Reprodutivo",
+	}
+	app.renderTemplate(w, r, "reproducao/painel.html", data)
+}
+
+func (app *Application) carregarEstacoesMonta(propriedadeID int) ([]models.EstacaoMonta, error) {
+	rows, err := app.DB.Query(`
+		SELECT id, propriedade_id, nome, data_inicio, data_fim
+		FROM estacoes_monta
+		WHERE propriedade_id = ?
+		ORDER BY data_inicio DESC`, propriedadeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var estacoes []models.EstacaoMonta
+	for rows.Next() {
+		var e models.EstacaoMonta
+		if err := rows.Scan(&e.ID, &e.PropriedadeID, &e.Nome, &e.DataInicio, &e.DataFim); err != nil {
+			return nil, err
+		}
+		estacoes = append(estacoes, e)
+	}
+	return estacoes, rows.Err()
+}
+
+func (app *Application) carregarProtocolosIATF() ([]models.ProtocoloIATF, error) {
+	rows, err := app.DB.Query(`
+		SELECT p.id, p.nome, COALESCE(p.descricao, ''), COALESCE(e.id, 0), COALESCE(e.dia, 0), COALESCE(e.tarefa, '')
+		FROM protocolos_iatf p
+		LEFT JOIN protocolo_etapas e ON e.protocolo_id = p.id
+		ORDER BY p.nome, p.id, e.dia`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var protocolos []models.ProtocoloIATF
+	for rows.Next() {
+		var p models.ProtocoloIATF
+		var e models.EtapaProtocolo
+		if err := rows.Scan(&p.ID, &p.Nome, &p.Descricao, &e.ID, &e.Dia, &e.Tarefa); err != nil {
+			return nil, err
+		}
+		if n := len(protocolos); n == 0 || protocolos[n-1].ID != p.ID {
+			protocolos = append(protocolos, p)
+		}
+		if e.ID != 0 {
+			e.ProtocoloID = p.ID
+			ultimo := &protocolos[len(protocolos)-1]
+			ultimo.Etapas = append(ultimo.Etapas, e)
+		}
+	}
+	return protocolos, rows.Err()
+}
+
+func (app *Application) carregarInseminacoes(propriedadeID int) ([]models.Inseminacao, error) {
+	rows, err := app.DB.Query(`
+		SELECT i.id, i.animal_id, COALESCE(i.estacao_id, 0), COALESCE(i.protocolo_id, 0), i.data, i.tipo,
+		       COALESCE(i.touro, ''), COALESCE(i.partida_semen, ''), COALESCE(i.inseminador, ''), a.identificacao
+		FROM inseminacoes i
+		JOIN animais a ON a.id = i.animal_id
+		WHERE a.propriedade_id = ?
+		ORDER BY i.data DESC`, propriedadeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var inseminacoes []models.Inseminacao
+	for rows.Next() {
+		var i models.Inseminacao
+		if err := rows.Scan(&i.ID, &i.AnimalID, &i.EstacaoID, &i.ProtocoloID, &i.Data, &i.Tipo,
+			&i.Touro, &i.PartidaSemen, &i.Inseminador, &i.Identificacao); err != nil {
+			return nil, err
+		}
+		inseminacoes = append(inseminacoes, i)
+	}
+	return inseminacoes, rows.Err()
+}
+
+func (app *Application) carregarDiagnosticos(propriedadeID int) ([]models.DiagnosticoGestacao, error) {
+	rows, err := app.DB.Query(`
+		SELECT d.id, d.animal_id, COALESCE(d.inseminacao_id, 0), d.data, d.resultado,
+		       COALESCE(d.metodo, ''), COALESCE(d.dias_gestacao, 0)
+		FROM diagnosticos_gestacao d
+		JOIN animais a ON a.id = d.animal_id
+		WHERE a.propriedade_id = ?
+		ORDER BY d.data`, propriedadeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var diagnosticos []models.DiagnosticoGestacao
+	for rows.Next() {
+		var d models.DiagnosticoGestacao
+		if err := rows.Scan(&d.ID, &d.AnimalID, &d.InseminacaoID, &d.Data, &d.Resultado, &d.Metodo, &d.DiasGestacao); err != nil {
+			return nil, err
+		}
+		diagnosticos = append(diagnosticos, d)
+	}
+	return diagnosticos, rows.Err()
+}
+
+func (app *Application) carregarPartos(propriedadeID int) ([]models.Parto, error) {
+	rows, err := app.DB.Query(`
+		SELECT p.id, p.animal_id, p.data, COALESCE(p.observacoes, '')
+		FROM partos p
+		JOIN animais a ON a.id = p.animal_id
+		WHERE a.propriedade_id = ?
+		ORDER BY p.data DESC`, propriedadeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var partos []models.Parto
+	for rows.Next() {
+		var p models.Parto
+		if err := rows.Scan(&p.ID, &p.AnimalID, &p.Data, &p.Observacoes); err != nil {
+			return nil, err
+		}
+		partos = append(partos, p)
+	}
+	return partos, rows.Err()
+}
+
+// animalDaPropriedade confere se o animal pertence à propriedade do formulário
+func (app *Application) animalDaPropriedade(animalID, propriedadeID int) (bool, error) {
+	var count int
+	err := app.DB.QueryRow("SELECT COUNT(*) FROM animais WHERE id = ? AND propriedade_id = ?", animalID, propriedadeID).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// SalvarEstacaoMonta cadastra uma nova estação de monta
+func (app *Application) SalvarEstacaoMonta(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	propriedadeID := formInt(r, "propriedade_id")
+	nome := strings.TrimSpace(r.FormValue("nome"))
+	inicio, okInicio := formData(r, "data_inicio")
+	fim, okFim := formData(r, "data_fim")
+	if propriedadeID == 0 || nome == "" || !okInicio || !okFim {
+		app.clientError(w, "Informe o nome e o período da estação de monta.")
+		return
+	}
+	if fim.Before(inicio) {
+		app.clientError(w, "A data final da estação deve ser posterior à inicial.")
+		return
+	}
+
+	_, err := app.DB.Exec(
+		`INSERT INTO estacoes_monta (propriedade_id, nome, data_inicio, data_fim) VALUES (?, ?, ?, ?)`,
+		propriedadeID, nome, inicio, fim,
+	)
+	if err != nil {
+		log.Printf("❌ Erro ao inserir estação de monta: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Estação de monta cadastrada.", "success")
+	app.PainelReproducao(w, r)
+}
+
+// SalvarProtocoloIATF cadastra um modelo de protocolo com as etapas por dia.
+// As etapas chegam em pares de campos repetidos etapa_dia / etapa_tarefa.
+func (app *Application) SalvarProtocoloIATF(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	nome := strings.TrimSpace(r.FormValue("nome"))
+	dias := r.Form["etapa_dia"]
+	tarefas := r.Form["etapa_tarefa"]
+	if nome == "" {
+		app.clientError(w, "Informe o nome do protocolo.")
+		return
+	}
+
+	var etapas []models.EtapaProtocolo
+	for i := range dias {
+		if i >= len(tarefas) || strings.TrimSpace(tarefas[i]) == "" {
+			continue
+		}
+		dia, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(dias[i])), "D"))
+		if err != nil {
+			app.clientError(w, "Dia inválido na etapa "+strconv.Itoa(i+1)+" (use 0, 7, 9...).")
+			return
+		}
+		etapas = append(etapas, models.EtapaProtocolo{Dia: dia, Tarefa: strings.TrimSpace(tarefas[i])})
+	}
+	if len(etapas) == 0 {
+		app.clientError(w, "O protocolo precisa de pelo menos uma etapa.")
+		return
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var protocoloID int
+	err = tx.QueryRow(
+		`INSERT INTO protocolos_iatf (nome, descricao) VALUES (?, ?) RETURNING id`,
+		nome, r.FormValue("descricao"),
+	).Scan(&protocoloID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	for _, e := range etapas {
+		if _, err := tx.Exec(
+			`INSERT INTO protocolo_etapas (protocolo_id, dia, tarefa) VALUES (?, ?, ?)`,
+			protocoloID, e.Dia, e.Tarefa,
+		); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	log.Printf("✅ Protocolo IATF %q cadastrado com %d etapas", nome, len(etapas))
+	setToast(w, "Protocolo cadastrado.", "success")
+	app.PainelReproducao(w, r)
+}
+
+// AgendaProtocoloIATF mostra as tarefas do protocolo datadas a partir do D0
+func (app *Application) AgendaProtocoloIATF(w http.ResponseWriter, r *http.Request) {
+	protocoloID := formInt(r, "protocolo_id")
+	d0, ok := formData(r, "d0")
+	if !ok {
+		app.clientError(w, "Informe a data do D0.")
+		return
+	}
+
+	protocolos, err := app.carregarProtocolosIATF()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	for _, p := range protocolos {
+		if p.ID == protocoloID {
+			data := map[string]interface{}{
+				"Protocolo": p,
+				"Agenda":    services.AgendaProtocolo(p, d0),
+			}
+			app.renderTemplate(w, r, "reproducao/agenda.html", data)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// SalvarInseminacao registra uma cobertura (IA, IATF ou monta natural)
+func (app *Application) SalvarInseminacao(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	propriedadeID := formInt(r, "propriedade_id")
+	animalID := formInt(r, "animal_id")
+	tipo := r.FormValue("tipo")
+	data, ok := formData(r, "data")
+	daPropriedade, err := app.animalDaPropriedade(animalID, propriedadeID)
+
+	if err != nil {
+
+		app.serverError(w, r, err)
+
+		return
+
+	}
+
+	if !ok || !daPropriedade {
+		app.clientError(w, "Selecione a matriz e a data da cobertura.")
+		return
+	}
+	if tipo != models.CoberturaIA && tipo != models.CoberturaIATF && tipo != models.CoberturaMonta {
+		app.clientError(w, "Tipo de cobertura inválido.")
+		return
+	}
+	protocoloID := formInt(r, "protocolo_id")
+	if tipo != models.CoberturaIATF {
+		protocoloID = 0
+	}
+
+	// Associa automaticamente à estação de monta que contém a data
+	var estacaoID int
+	app.DB.QueryRow(
+		`SELECT id FROM estacoes_monta WHERE propriedade_id = ? AND ? BETWEEN data_inicio AND data_fim LIMIT 1`,
+		propriedadeID, data,
+	).Scan(&estacaoID)
+
+	_, err = app.DB.Exec(
+		`INSERT INTO inseminacoes
+		(animal_id, estacao_id, protocolo_id, data, tipo, touro, partida_semen, inseminador)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		animalID, nullInt(estacaoID), nullInt(protocoloID), data, tipo,
+		r.FormValue("touro"), r.FormValue("partida_semen"), r.FormValue("inseminador"),
+	)
+	if err != nil {
+		log.Printf("❌ Erro ao inserir inseminação: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Cobertura registrada.", "success")
+	app.PainelReproducao(w, r)
+}
+
+// SalvarDiagnostico registra o diagnóstico de gestação. Sem inseminação
+// informada, usa a última cobertura do animal anterior à data do exame.
+func (app *Application) SalvarDiagnostico(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	propriedadeID := formInt(r, "propriedade_id")
+	animalID := formInt(r, "animal_id")
+	resultado := r.FormValue("resultado")
+	data, ok := formData(r, "data")
+	daPropriedade, err := app.animalDaPropriedade(animalID, propriedadeID)
+
+	if err != nil {
+
+		app.serverError(w, r, err)
+
+		return
+
+	}
+
+	if !ok || !daPropriedade {
+		app.clientError(w, "Selecione a matriz e a data do diagnóstico.")
+		return
+	}
+	if resultado != models.DiagnosticoPrenhe && resultado != models.DiagnosticoVazia {
+		app.clientError(w, "Resultado do diagnóstico inválido.")
+		return
+	}
+
+	inseminacaoID := formInt(r, "inseminacao_id")
+	if inseminacaoID != 0 {
+		// A cobertura diagnosticada tem de ser da mesma matriz
+		var count int
+		if err := app.DB.QueryRow(
+			`SELECT COUNT(*) FROM inseminacoes WHERE id = ? AND animal_id = ?`, inseminacaoID, animalID,
+		).Scan(&count); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		if count == 0 {
+			app.clientError(w, "A cobertura informada não é desta matriz.")
+			return
+		}
+	} else {
+		app.DB.QueryRow(
+			`SELECT id FROM inseminacoes WHERE animal_id = ? AND data <= ? ORDER BY data DESC LIMIT 1`,
+			animalID, data,
+		).Scan(&inseminacaoID)
+	}
+
+	_, err = app.DB.Exec(
+		`INSERT INTO diagnosticos_gestacao
+		(animal_id, inseminacao_id, data, resultado, metodo, dias_gestacao)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		animalID, nullInt(inseminacaoID), data, resultado, r.FormValue("metodo"), nullInt(formInt(r, "dias_gestacao")),
+	)
+	if err != nil {
+		log.Printf("❌ Erro ao inserir diagnóstico: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Diagnóstico registrado.", "success")
+	app.PainelReproducao(w, r)
+}
+
+// SalvarParto registra um parto, base do intervalo entre partos
+func (app *Application) SalvarParto(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	propriedadeID := formInt(r, "propriedade_id")
+	animalID := formInt(r, "animal_id")
+	data, ok := formData(r, "data")
+	daPropriedade, err := app.animalDaPropriedade(animalID, propriedadeID)
+
+	if err != nil {
+
+		app.serverError(w, r, err)
+
+		return
+
+	}
+
+	if !ok || !daPropriedade {
+		app.clientError(w, "Selecione a matriz e a data do parto.")
+		return
+	}
+
+	_, err = app.DB.Exec(
+		`INSERT INTO partos (animal_id, data, observacoes) VALUES (?, ?, ?)`,
+		animalID, data, r.FormValue("observacoes"),
+	)
+	if err != nil {
+		log.Printf("❌ Erro ao inserir parto: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Parto registrado.", "success")
+	app.PainelReproducao(w, r)
+}
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// carregarSafras lista as safras com o filtro informado (sobre safras s,
+// talhoes t e propriedades p)
+func (app *Application) carregarSafras(filtro string, args ...any) ([]models.Safra, error) {
+	rows, err := app.DB.Query(`
+		SELECT s.id, s.talhao_id, t.nome, t.propriedade_id, p.nome, s.rotulo, s.cultura, COALESCE(s.cultivar, ''),
+		       s.area_hectares, s.plantio_previsto, s.colheita_prevista, s.plantio_real, s.colheita_real,
+		       COALESCE(s.produtividade_alvo, 0), COALESCE(s.unidade, 'sc/ha'), COALESCE(s.observacoes, '')
+		FROM safras s
+		JOIN talhoes t ON t.id = s.talhao_id
+		JOIN propriedades p ON p.id = t.propriedade_id
+		`+filtro+`
+		ORDER BY s.plantio_previsto DESC, t.nome`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var safras []models.Safra
+	for rows.Next() {
+		var s models.Safra
+		if err := rows.Scan(&s.ID, &s.TalhaoID, &s.TalhaoNome, &s.PropriedadeID, &s.PropriedadeNome, &s.Rotulo,
+			&s.Cultura, &s.Cultivar, &s.AreaHectares, &s.PlantioPrevisto, &s.ColheitaPrevista, &s.PlantioReal,
+			&s.ColheitaReal, &s.ProdutividadeAlvo, &s.Unidade, &s.Observacoes); err != nil {
+			return nil, err
+		}
+		safras = append(safras, s)
+	}
+	return safras, rows.Err()
+}
+
+func (app *Application) buscarSafra(id int) (models.Safra, error) {
+	safras, err := app.carregarSafras("WHERE s.id = ?", id)
+	if err != nil {
+		return models.Safra{}, err
+	}
+	if len(safras) == 0 {
+		return models.Safra{}, sql.ErrNoRows
+	}
+	return safras[0], nil
+}
+
+// PlanejamentoSafras exibe o planejamento de safras de uma propriedade ou,
+// com cliente_id, de todas as propriedades do cliente
+func (app *Application) PlanejamentoSafras(w http.ResponseWriter, r *http.Request) {
+	propriedadeID := formInt(r, "propriedade_id")
+	clienteID := formInt(r, "cliente_id")
+	rotulo := r.FormValue("rotulo")
+
+	data := map[string]interface{}{
+		"Culturas": models.Culturas,
+		"Unidades": []string{models.UnidadeSacasHa, models.UnidadeToneladaHa},
+		"Rotulo":   rotulo,
+		"Title":    "Planejamento de Safras",
+	}
+
+	var safras []models.Safra
+	var err error
+	if propriedadeID > 0 {
+		var propriedade models.Propriedade
+		propriedade, err = app.buscarPropriedade(propriedadeID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				http.NotFound(w, r)
+				return
+			}
+			app.serverError(w, r, err)
+			return
+		}
+		var talhoes []models.Talhao
+		talhoes, err = app.carregarTalhoes(propriedadeID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		data["Propriedade"] = propriedade
+		data["Talhoes"] = talhoes
+		safras, err = app.carregarSafras("WHERE t.propriedade_id = ?", propriedadeID)
+	} else {
+		var nome string
+		err = app.DB.QueryRow(`SELECT nome FROM clientes WHERE id = ?`, clienteID).Scan(&nome)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				http.NotFound(w, r)
+				return
+			}
+			app.serverError(w, r, err)
+			return
+		}
+		data["ClienteID"] = clienteID
+		data["ClienteNome"] = nome
+		safras, err = app.carregarSafras("WHERE p.cliente_id = ?", clienteID)
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Anos agrícolas disponíveis para o filtro
+	vistos := make(map[string]bool)
+	var rotulos []string
+	for _, s := range safras {
+		if !vistos[s.Rotulo] {
+			vistos[s.Rotulo] = true
+			rotulos = append(rotulos, s.Rotulo)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(rotulos)))
+	if rotulo != "" {
+		var filtradas []models.Safra
+		for _, s := range safras {
+			if s.Rotulo == rotulo {
+				filtradas = append(filtradas, s)
+			}
+		}
+		safras = filtradas
+	}
+
+	data["Safras"] = safras
+	data["Rotulos"] = rotulos
+	data["Resumo"] = services.ResumirSafras(safras)
+	app.renderTemplate(w, r, "safras/planejamento.html", data)
+}
+
+// DetalheSafra exibe a safra e os registros vinculados a ela
+func (app *Application) DetalheSafra(w http.ResponseWriter, r *http.Request) {
+	safra, err := app.buscarSafra(formInt(r, "id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	propriedade, err := app.buscarPropriedade(safra.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	aplicacoes, err := app.carregarAplicacoesInsumos("WHERE a.safra_id = ?", safra.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	consultas, err := app.carregarConsultas("WHERE c.safra_id = ?", safra.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	consultores, err := app.carregarConsultores(true)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	maquinas, err := app.carregarMaquinas("WHERE m.propriedade_id = ? AND COALESCE(m.ativo, true)", safra.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	estoque, err := app.saldosEstoque("WHERE i.propriedade_id = ? AND COALESCE(i.ativo, true) AND i.categoria <> 'Veterinário'", safra.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	colheitas, err := app.carregarColheitas("WHERE h.safra_id = ?", safra.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	previsoes, err := app.previsoesColheita("t.id = ?", safra.TalhaoID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	var previsao services.PrevisaoColheita
+	for _, p := range previsoes {
+		if p.Safra.ID == safra.ID {
+			previsao = p
+		}
+	}
+	chuvas, err := app.carregarChuvas("WHERE c.propriedade_id = ? AND c.data >= ?", safra.PropriedadeID, safra.PlantioPrevisto)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Propriedade":      propriedade,
+		"Safra":            safra,
+		"Aplicacoes":       aplicacoes,
+		"TotaisProdutos":   services.TotalizarProdutos(aplicacoes, safra.AreaHectares),
+		"Reentradas":       services.ReentradasAtivas(aplicacoes, horaLocal()),
+		"ColheitaLiberada": services.ColheitaLiberadaSafra(aplicacoes),
+		"UnidadesDose":     models.UnidadesDose,
+		"Consultas":        consultas,
+		"Consultores":      consultores,
+		"Maquinas":         maquinas,
+		"Estoque":          estoque,
+		"Colheitas":        colheitas,
+		"Produtividade":    services.CalcularProdutividade(safra, colheitas),
+		"UmidadePadrao":    services.UmidadePadraoCultura(safra.Cultura),
+		"Previsao":         previsao,
+		"Chuvas":           chuvas,
+		"Title":            "Safra " + safra.Rotulo,
+	}
+	app.renderTemplate(w, r, "safras/detalhes.html", data)
+}
+
+// SalvarSafra cadastra ou atualiza o planejamento de uma safra
+func (app *Application) SalvarSafra(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	s := models.Safra{
+		ID:                formInt(r, "id"),
+		TalhaoID:          formInt(r, "talhao_id"),
+		Rotulo:            strings.TrimSpace(r.FormValue("rotulo")),
+		Cultura:           strings.TrimSpace(r.FormValue("cultura")),
+		Cultivar:          strings.TrimSpace(r.FormValue("cultivar")),
+		AreaHectares:      formFloat(r, "area_hectares"),
+		ProdutividadeAlvo: formFloat(r, "produtividade_alvo"),
+		Unidade:           r.FormValue("unidade"),
+		Observacoes:       r.FormValue("observacoes"),
+	}
+	var okPlantio, okColheita bool
+	s.PlantioPrevisto, okPlantio = formData(r, "plantio_previsto")
+	s.ColheitaPrevista, okColheita = formData(r, "colheita_prevista")
+	if s.Cultura == "" || !okPlantio || !okColheita {
+		app.clientError(w, "Informe a cultura e as datas previstas de plantio e colheita.")
+		return
+	}
+	if !s.ColheitaPrevista.After(s.PlantioPrevisto) {
+		app.clientError(w, "A colheita prevista deve ser posterior ao plantio.")
+		return
+	}
+
+	talhao, err := app.buscarTalhao(s.TalhaoID)
+	if err != nil || talhao.PropriedadeID != formInt(r, "propriedade_id") {
+		app.clientError(w, "Selecione um talhão da propriedade.")
+		return
+	}
+	// O talhão fica fixo depois do cadastro: aplicações e colheitas da
+	// safra já foram lançadas para ele
+	if s.ID > 0 {
+		atual, err := app.buscarSafra(s.ID)
+		if err != nil {
+			app.clientError(w, "Safra não encontrada.")
+			return
+		}
+		if atual.TalhaoID != s.TalhaoID {
+			app.clientError(w, "O talhão de uma safra cadastrada não pode ser alterado. Cadastre uma nova safra no outro talhão.")
+			return
+		}
+	}
+	if s.AreaHectares <= 0 {
+		s.AreaHectares = talhao.AreaHectares
+	}
+	if s.AreaHectares > talhao.AreaHectares*1.001 {
+		app.clientError(w, fmt.Sprintf("A área da safra excede a área do talhão (%.2f ha).", talhao.AreaHectares))
+		return
+	}
+	if s.Rotulo == "" {
+		s.Rotulo = services.RotuloSafra(s.PlantioPrevisto)
+	}
+	if s.Unidade != models.UnidadeToneladaHa {
+		s.Unidade = models.UnidadeSacasHa
+	}
+
+	existentes, err := app.carregarSafras("WHERE s.talhao_id = ?", s.TalhaoID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if outra, ok := services.SobreposicaoSafra(s, existentes); ok {
+		app.clientError(w, fmt.Sprintf("O período se sobrepõe à safra %s de %s neste talhão.", outra.Rotulo, outra.Cultura))
+		return
+	}
+
+	if s.ID == 0 {
+		_, err = app.DB.Exec(
+			`INSERT INTO safras
+			(talhao_id, rotulo, cultura, cultivar, area_hectares, plantio_previsto, colheita_prevista,
+			 produtividade_alvo, unidade, observacoes)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			s.TalhaoID, s.Rotulo, s.Cultura, s.Cultivar, s.AreaHectares, s.PlantioPrevisto, s.ColheitaPrevista,
+			s.ProdutividadeAlvo, s.Unidade, s.Observacoes,
+		)
+	} else {
+		var res sql.Result
+		res, err = app.DB.Exec(
+			`UPDATE safras SET rotulo=?, cultura=?, cultivar=?, area_hectares=?, plantio_previsto=?,
+			colheita_prevista=?, produtividade_alvo=?, unidade=?, observacoes=? WHERE id=? AND talhao_id=?`,
+			s.Rotulo, s.Cultura, s.Cultivar, s.AreaHectares, s.PlantioPrevisto, s.ColheitaPrevista,
+			s.ProdutividadeAlvo, s.Unidade, s.Observacoes, s.ID, s.TalhaoID,
+		)
+		if err == nil {
+			if n, _ := res.RowsAffected(); n == 0 {
+				app.clientError(w, "Safra não encontrada neste talhão.")
+				return
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("❌ Erro ao salvar safra: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Safra salva com sucesso.", "success")
+	app.PlanejamentoSafras(w, r)
+}
+
+// RegistrarRealizadoSafra grava as datas reais de plantio e colheita
+func (app *Application) RegistrarRealizadoSafra(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	plantio := formDataOpcional(r, "plantio_real")
+	colheita := formDataOpcional(r, "colheita_real")
+	if plantio == nil && colheita != nil {
+		app.clientError(w, "Informe o plantio antes da colheita.")
+		return
+	}
+	if plantio != nil && colheita != nil && !colheita.After(*plantio) {
+		app.clientError(w, "A colheita deve ser posterior ao plantio.")
+		return
+	}
+
+	_, err := app.DB.Exec(
+		`UPDATE safras SET plantio_real = ?, colheita_real = ? WHERE id = ?`,
+		nullData(plantio), nullData(colheita), formInt(r, "id"),
+	)
+	if err != nil {
+		log.Printf("❌ Erro ao registrar datas da safra: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Datas realizadas atualizadas.", "success")
+	app.DetalheSafra(w, r)
+}
@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+// AlvosSanitarios são as opções sugeridas no formulário de aplicação
+var AlvosSanitarios = []string{"Aftosa", "Brucelose", "Raiva", "Clostridioses", "Verminose", "Carrapato", "Mastite", "Outro"}
+
+// CalendarioSanitario exibe as aplicações, carências vigentes e próximas doses da propriedade
+func (app *Application) CalendarioSanitario(w http.ResponseWriter, r *http.Request) {
+	propriedadeID := formInt(r, "propriedade_id")
+	propriedade, err := app.buscarPropriedade(propriedadeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	animais, err := app.carregarAnimais(propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	aplicacoes, err := app.carregarAplicacoesSanitarias(propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	eventos, err := app.carregarEventosSanitarios("WHERE c.propriedade_id = ?", propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	hoje := time.Now()
+	var pendentes []models.EventoSanitario
+	for _, e := range eventos {
+		if e.RealizadoEm == nil {
+			pendentes = append(pendentes, e)
+		}
+	}
+
+	lotes := make(map[string]bool)
+	for _, a := range animais {
+		if a.Lote != "" {
+			lotes[a.Lote] = true
+		}
+	}
+
+	estoque, err := app.saldosEstoque("WHERE i.propriedade_id = ? AND COALESCE(i.ativo, true) AND i.categoria IN ('Veterinário', 'Outro')", propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Propriedade": propriedade,
+		"Estoque":     estoque,
+		"Animais":     animais,
+		"Lotes":       lotes,
+		"Aplicacoes":  aplicacoes,
+		"Carencias":   services.CarenciasAtivas(aplicacoes, hoje),
+		"Pendentes":   pendentes,
+		"Atrasados":   len(services.EventosAtrasados(pendentes, hoje)),
+		"Hoje":        hoje,
+		"Tipos":       []string{models.SanitarioVacina, models.SanitarioVermifugo, models.SanitarioTratamento},
+		"Alvos":       AlvosSanitarios,
+		"Title":       "Calendário Sanitário",
+	}
+	app.renderTemplate(w, r, "sanidade/calendario.html", data)
+}
+
+func (app *Application) carregarAplicacoesSanitarias(propriedadeID int) ([]models.AplicacaoSanitaria, error) {
+	rows, err := app.DB.Query(`
+		SELECT s.id, s.propriedade_id, COALESCE(s.animal_id, 0), COALESCE(s.lote, ''), s.tipo, COALESCE(s.alvo, ''),
+		       s.produto, COALESCE(s.partida, ''), COALESCE(s.dose, 0), COALESCE(s.unidade, ''), COALESCE(s.via, ''),
+		       s.data, COALESCE(s.carencia_abate, 0), COALESCE(s.carencia_leite, 0), COALESCE(s.intervalo_dose, 0),
+		       COALESCE(s.responsavel, ''), COALESCE(s.observacoes, ''), COALESCE(a.identificacao, '')
+		FROM aplicacoes_sanitarias s
+		LEFT JOIN animais a ON a.id = s.animal_id
+		WHERE s.propriedade_id = ?
+		ORDER BY s.data DESC, s.id DESC`, propriedadeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aplicacoes []models.AplicacaoSanitaria
+	for rows.Next() {
+		var a models.AplicacaoSanitaria
+		if err := rows.Scan(&a.ID, &a.PropriedadeID, &a.AnimalID, &a.Lote, &a.Tipo, &a.Alvo,
+			&a.Produto, &a.Partida, &a.Dose, &a.Unidade, &a.Via,
+			&a.Data, &a.CarenciaAbate, &a.CarenciaLeite, &a.IntervaloDose,
+			&a.Responsavel, &a.Observacoes, &a.Identificacao); err != nil {
+			return nil, err
+		}
+		aplicacoes = append(aplicacoes, a)
+	}
+	return aplicacoes, rows.Err()
+}
+
+// carregarEventosSanitarios lista eventos do calendário com o filtro informado
+func (app *Application) carregarEventosSanitarios(filtro string, args ...any) ([]models.EventoSanitario, error) {
+	rows, err := app.DB.Query(`
+		SELECT c.id, c.propriedade_id, p.nome, COALESCE(c.animal_id, 0), COALESCE(c.lote, ''), c.tipo,
+		       COALESCE(c.alvo, ''), COALESCE(c.produto, ''), c.data_prevista, COALESCE(c.origem_id, 0),
+		       c.realizado_em, COALESCE(c.aplicacao_id, 0)
+		FROM calendario_sanitario c
+		JOIN propriedades p ON p.id = c.propriedade_id
+		`+filtro+`
+		ORDER BY c.data_prevista`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var eventos []models.EventoSanitario
+	for rows.Next() {
+		var e models.EventoSanitario
+		if err := rows.Scan(&e.ID, &e.PropriedadeID, &e.PropriedadeNome, &e.AnimalID, &e.Lote, &e.Tipo,
+			&e.Alvo, &e.Produto, &e.DataPrevista, &e.OrigemID,
+			&e.RealizadoEm, &e.AplicacaoID); err != nil {
+			return nil, err
+		}
+		eventos = append(eventos, e)
+	}
+	return eventos, rows.Err()
+}
+
+// SalvarAplicacaoSanitaria registra a aplicação, baixa o evento previsto que
+// ela cumpre e agenda o reforço no calendário sanitário
+func (app *Application) SalvarAplicacaoSanitaria(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	a := models.AplicacaoSanitaria{
+		PropriedadeID: formInt(r, "propriedade_id"),
+		AnimalID:      formInt(r, "animal_id"),
+		Lote:          strings.TrimSpace(r.FormValue("lote")),
+		Tipo:          r.FormValue("tipo"),
+		Alvo:          r.FormValue("alvo"),
+		Produto:       strings.TrimSpace(r.FormValue("produto")),
+		Partida:       strings.TrimSpace(r.FormValue("partida")),
+		Dose:          formFloat(r, "dose"),
+		Unidade:       r.FormValue("unidade"),
+		Via:           r.FormValue("via"),
+		CarenciaAbate: formInt(r, "carencia_abate"),
+		CarenciaLeite: formInt(r, "carencia_leite"),
+		IntervaloDose: formInt(r, "intervalo_dose"),
+		Responsavel:   r.FormValue("responsavel"),
+		Observacoes:   r.FormValue("observacoes"),
+	}
+	var insumo models.Insumo
+	if id := formInt(r, "insumo_id"); id > 0 {
+		var err error
+		if insumo, err = app.buscarInsumo(id); err != nil || insumo.PropriedadeID != a.PropriedadeID {
+			app.clientError(w, "Insumo não encontrado no estoque da propriedade.")
+			return
+		}
+		if a.Produto == "" {
+			a.Produto = insumo.Nome
+		}
+	}
+	var ok bool
+	a.Data, ok = formData(r, "data")
+	if a.PropriedadeID == 0 || !ok || a.Produto == "" {
+		app.clientError(w, "Informe a data e o produto aplicado.")
+		return
+	}
+	if a.AnimalID == 0 && a.Lote == "" {
+		app.clientError(w, "Informe o animal ou o lote que recebeu a aplicação.")
+		return
+	}
+	if a.AnimalID != 0 {
+		daPropriedade, err := app.animalDaPropriedade(a.AnimalID, a.PropriedadeID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		if !daPropriedade {
+			app.clientError(w, "Animal não pertence à propriedade.")
+			return
+		}
+		a.Lote = ""
+	}
+	if a.Partida == "" || a.Dose <= 0 {
+		app.clientError(w, "Partida e dose são obrigatórias para rastreabilidade.")
+		return
+	}
+	if a.CarenciaAbate < 0 || a.CarenciaLeite < 0 || a.IntervaloDose < 0 {
+		app.clientError(w, "Carências e intervalo não podem ser negativos.")
+		return
+	}
+
+	// Baixa no estoque: dose por animal vezes os animais tratados, na
+	// partida do produto (lote do fabricante)
+	consumo := formFloat(r, "quantidade_estoque")
+	if insumo.ID > 0 && consumo <= 0 {
+		animais := 1
+		if a.AnimalID == 0 {
+			app.DB.QueryRow(`SELECT COUNT(*) FROM animais WHERE propriedade_id = ? AND lote = ? AND COALESCE(ativo, true)`,
+				a.PropriedadeID, a.Lote).Scan(&animais)
+		}
+		var convertido bool
+		if consumo, convertido = services.QuantidadeSanitaria(a.Dose, a.Unidade, animais, insumo.Unidade); !convertido {
+			app.clientError(w, fmt.Sprintf("Informe a quantidade a baixar do estoque, em %s.", insumo.Unidade))
+			return
+		}
+	}
+
+	tx, err := app.DB.Begin()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
+		`INSERT INTO aplicacoes_sanitarias
+		(propriedade_id, animal_id, lote, tipo, alvo, produto, partida, dose, unidade, via, data,
+		 carencia_abate, carencia_leite, intervalo_dose, responsavel, observacoes)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`,
+		a.PropriedadeID, nullInt(a.AnimalID), a.Lote, a.Tipo, a.Alvo, a.Produto, a.Partida, a.Dose, a.Unidade, a.Via, a.Data,
+		a.CarenciaAbate, a.CarenciaLeite, a.IntervaloDose, a.Responsavel, a.Observacoes,
+	).Scan(&a.ID)
+	if err != nil {
+		log.Printf("❌ Erro ao inserir aplicação sanitária: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Baixar a dose prevista mais antiga que esta aplicação cumpre
+	pendentes, err := app.carregarEventosSanitarios("WHERE c.propriedade_id = ? AND c.realizado_em IS NULL", a.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	for _, e := range pendentes {
+		if services.CumpreEvento(e, a) {
+			if _, err := tx.Exec(
+				`UPDATE calendario_sanitario SET realizado_em = ?, aplicacao_id = ? WHERE id = ?`,
+				a.Data, a.ID, e.ID,
+			); err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+			break
+		}
+	}
+
+	if proxima, ok := services.ProximaDose(a); ok {
+		if _, err := tx.Exec(
+			`INSERT INTO calendario_sanitario
+			(propriedade_id, animal_id, lote, tipo, alvo, produto, data_prevista, origem_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			proxima.PropriedadeID, nullInt(proxima.AnimalID), proxima.Lote, proxima.Tipo, proxima.Alvo,
+			proxima.Produto, proxima.DataPrevista, proxima.OrigemID,
+		); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	if insumo.ID > 0 {
+		descricao := "Aplicação sanitária – lote " + a.Lote
+		if a.AnimalID != 0 {
+			descricao = "Aplicação sanitária – animal"
+			app.DB.QueryRow(`SELECT 'Aplicação sanitária – animal ' || identificacao FROM animais WHERE id = ?`, a.AnimalID).Scan(&descricao)
+		}
+		if err := baixarEstoque(tx, models.SaidaInsumo{
+			InsumoID:             insumo.ID,
+			Data:                 a.Data,
+			Quantidade:           consumo,
+			Lote:                 a.Partida,
+			Origem:               models.SaidaSanitaria,
+			AplicacaoSanitariaID: a.ID,
+			Descricao:            descricao,
+		}); err != nil {
+			log.Printf("❌ Erro ao baixar estoque: %v", err)
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if aviso := app.avisoSaldoNegativo(insumo.ID); insumo.ID > 0 && aviso != "" {
+		setToast(w, "Aplicação registrada."+aviso, "warning")
+	} else {
+		setToast(w, "Aplicação registrada.", "success")
+	}
+	app.CalendarioSanitario(w, r)
+}
+
+// AgendarEventoSanitario inclui manualmente uma dose prevista (ex.: campanha oficial de vacinação)
+func (app *Application) AgendarEventoSanitario(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	propriedadeID := formInt(r, "propriedade_id")
+	animalID := formInt(r, "animal_id")
+	lote := strings.TrimSpace(r.FormValue("lote"))
+	prevista, ok := formData(r, "data_prevista")
+	if propriedadeID == 0 || !ok || (animalID == 0 && lote == "") {
+		app.clientError(w, "Informe a data prevista e o animal ou lote.")
+		return
+	}
+	if animalID != 0 {
+		lote = ""
+	}
+
+	_, err := app.DB.Exec(
+		`INSERT INTO calendario_sanitario
+		(propriedade_id, animal_id, lote, tipo, alvo, produto, data_prevista)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		propriedadeID, nullInt(animalID), lote, r.FormValue("tipo"), r.FormValue("alvo"),
+		r.FormValue("produto"), prevista,
+	)
+	if err != nil {
+		log.Printf("❌ Erro ao agendar evento sanitário: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Dose agendada no calendário.", "success")
+	app.CalendarioSanitario(w, r)
+}
+
+// PendenciasSanitarias lista as doses atrasadas de todas as propriedades para o dashboard
+func (app *Application) PendenciasSanitarias(w http.ResponseWriter, r *http.Request) {
+	eventos, err := app.carregarEventosSanitarios("WHERE c.realizado_em IS NULL AND c.data_prevista < CURRENT_DATE")
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Atrasados": services.EventosAtrasados(eventos, time.Now()),
+	}
+	app.renderTemplate(w, r, "sanidade/pendencias.html", data)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// carregarAnalisesSolo lista as análises de solo com o filtro informado
+// (sobre analises_solo a e talhoes t)
+func (app *Application) carregarAnalisesSolo(filtro string, args ...any) ([]models.AnaliseSolo, error) {
+	rows, err := app.DB.Query(`
+		SELECT a.id, a.talhao_id, t.nome, a.data_amostra, COALESCE(a.laboratorio, ''), COALESCE(a.profundidade, ''),
+		       COALESCE(a.ph, 0), COALESCE(a.materia_organica, 0), COALESCE(a.fosforo, 0), COALESCE(a.potassio, 0),
+		       COALESCE(a.calcio, 0), COALESCE(a.magnesio, 0), COALESCE(a.aluminio, 0), COALESCE(a.h_al, 0),
+		       COALESCE(a.argila, 0), COALESCE(a.observacoes, ''), COALESCE(a.ponto_id, 0), COALESCE(a.codigo_amostra, '')
+		FROM analises_solo a
+		JOIN talhoes t ON t.id = a.talhao_id
+		`+filtro+`
+		ORDER BY a.data_amostra DESC, a.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var analises []models.AnaliseSolo
+	for rows.Next() {
+		var a models.AnaliseSolo
+		if err := rows.Scan(&a.ID, &a.TalhaoID, &a.TalhaoNome, &a.DataAmostra, &a.Laboratorio, &a.Profundidade,
+			&a.PH, &a.MateriaOrganica, &a.Fosforo, &a.Potassio, &a.Calcio, &a.Magnesio, &a.Aluminio, &a.HAl,
+			&a.Argila, &a.Observacoes, &a.PontoID, &a.CodigoAmostra); err != nil {
+			return nil, err
+		}
+		analises = append(analises, a)
+	}
+	return analises, rows.Err()
+}
+
+// AnalisesSoloTalhao exibe o histórico de análises de solo do talhão
+func (app *Application) AnalisesSoloTalhao(w http.ResponseWriter, r *http.Request) {
+	talhao, err := app.buscarTalhao(formInt(r, "talhao_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+	propriedade, err := app.buscarPropriedade(talhao.PropriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	analises, err := app.carregarAnalisesSolo("WHERE a.talhao_id = ?", talhao.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Propriedade": propriedade,
+		"Talhao":      talhao,
+		"Analises":    analises,
+		"Title":       "Análises de Solo",
+	}
+	app.renderTemplate(w, r, "talhoes/solo.html", data)
+}
+
+// SalvarAnaliseSolo registra o laudo de uma análise de solo do talhão
+func (app *Application) SalvarAnaliseSolo(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	talhao, err := app.buscarTalhao(formInt(r, "talhao_id"))
+	if err != nil {
+		app.clientError(w, "Talhão não encontrado.")
+		return
+	}
+
+	a := models.AnaliseSolo{
+		TalhaoID:        talhao.ID,
+		Laboratorio:     strings.TrimSpace(r.FormValue("laboratorio")),
+		Profundidade:    strings.TrimSpace(r.FormValue("profundidade")),
+		PH:              formFloat(r, "ph"),
+		MateriaOrganica: formFloat(r, "materia_organica"),
+		Fosforo:         formFloat(r, "fosforo"),
+		Potassio:        formFloat(r, "potassio"),
+		Calcio:          formFloat(r, "calcio"),
+		Magnesio:        formFloat(r, "magnesio"),
+		Aluminio:        formFloat(r, "aluminio"),
+		HAl:             formFloat(r, "h_al"),
+		Argila:          formFloat(r, "argila"),
+		Observacoes:     strings.TrimSpace(r.FormValue("observacoes")),
+		CodigoAmostra:   strings.ToUpper(strings.TrimSpace(r.FormValue("codigo_amostra"))),
+	}
+	var ok bool
+	a.DataAmostra, ok = formData(r, "data_amostra")
+	if !ok {
+		app.clientError(w, "Informe a data da amostragem.")
+		return
+	}
+	if a.PH < 0 || a.PH > 14 {
+		app.clientError(w, "O pH deve estar entre 0 e 14.")
+		return
+	}
+	if a.MateriaOrganica < 0 || a.Fosforo < 0 || a.Potassio < 0 || a.Calcio < 0 || a.Magnesio < 0 ||
+		a.Aluminio < 0 || a.HAl < 0 || a.Argila < 0 || a.Argila > 100 {
+		app.clientError(w, "Os teores não podem ser negativos e a argila deve estar entre 0 e 100%.")
+		return
+	}
+	if a.CTC() == 0 {
+		app.clientError(w, "Informe ao menos as bases trocáveis ou a acidez potencial (H+Al).")
+		return
+	}
+
+	if a.CodigoAmostra != "" {
+		ponto, err := app.buscarPontoAmostragem(talhao.ID, a.CodigoAmostra)
+		if err == sql.ErrNoRows {
+			app.clientError(w, "ID de amostra não encontrado nas grades de amostragem deste talhão.")
+			return
+		}
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		a.PontoID = ponto.ID
+	}
+
+	err = inserirAnaliseSolo(app.DB, a)
+	if err != nil {
+		log.Printf("❌ Erro ao salvar análise de solo: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Análise de solo registrada.", "success")
+	app.AnalisesSoloTalhao(w, r)
+}
+
+// inserirAnaliseSolo grava o laudo; o ponto da grade fica nulo quando a
+// amostra não é georreferenciada
+func inserirAnaliseSolo(db executor, a models.AnaliseSolo) error {
+	_, err := db.Exec(
+		`INSERT INTO analises_solo (talhao_id, data_amostra, laboratorio, profundidade, ph, materia_organica,
+			fosforo, potassio, calcio, magnesio, aluminio, h_al, argila, observacoes, ponto_id, codigo_amostra)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.TalhaoID, a.DataAmostra, a.Laboratorio, a.Profundidade, a.PH, a.MateriaOrganica,
+		a.Fosforo, a.Potassio, a.Calcio, a.Magnesio, a.Aluminio, a.HAl, a.Argila, a.Observacoes,
+		nullInt(a.PontoID), a.CodigoAmostra,
+	)
+	return err
+}
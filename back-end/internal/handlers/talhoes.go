@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+	"AGR_Consulta-Pec/back-end/internal/services"
+)
+
+func (app *Application) carregarTalhoes(propriedadeID int) ([]models.Talhao, error) {
+	rows, err := app.DB.Query(`
+		SELECT id, propriedade_id, nome, area_hectares, COALESCE(uso, ''), COALESCE(observacoes, ''),
+		       COALESCE(limite, ''), COALESCE(area_limite, 0)
+		FROM talhoes
+		WHERE propriedade_id = ?
+		ORDER BY nome`, propriedadeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var talhoes []models.Talhao
+	for rows.Next() {
+		var t models.Talhao
+		var limite string
+		if err := rows.Scan(&t.ID, &t.PropriedadeID, &t.Nome, &t.AreaHectares, &t.Uso, &t.Observacoes,
+			&limite, &t.AreaLimite); err != nil {
+			return nil, err
+		}
+		lerLimiteTalhao(&t, limite)
+		talhoes = append(talhoes, t)
+	}
+	return talhoes, rows.Err()
+}
+
+func (app *Application) buscarTalhao(id int) (models.Talhao, error) {
+	var t models.Talhao
+	var limite string
+	err := app.DB.QueryRow(`
+		SELECT id, propriedade_id, nome, area_hectares, COALESCE(uso, ''), COALESCE(observacoes, ''),
+		       COALESCE(limite, ''), COALESCE(area_limite, 0)
+		FROM talhoes WHERE id = ?`, id,
+	).Scan(&t.ID, &t.PropriedadeID, &t.Nome, &t.AreaHectares, &t.Uso, &t.Observacoes, &limite, &t.AreaLimite)
+	if err == nil {
+		lerLimiteTalhao(&t, limite)
+	}
+	return t, err
+}
+
+// lerLimiteTalhao decodifica o limite GeoJSON gravado no talhão
+func lerLimiteTalhao(t *models.Talhao, limite string) {
+	var err error
+	if t.Limite, err = services.LerPoligonoGeoJSON(limite); err != nil {
+		log.Printf("⚠️  Limite inválido no talhão %d: %v", t.ID, err)
+		t.Limite, t.AreaLimite = nil, 0
+	}
+}
+
+// ListaTalhoes exibe os talhões da propriedade
+func (app *Application) ListaTalhoes(w http.ResponseWriter, r *http.Request) {
+	propriedadeID := formInt(r, "propriedade_id")
+	propriedade, err := app.buscarPropriedade(propriedadeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.NotFound(w, r)
+			return
+		}
+		app.serverError(w, r, err)
+		return
+	}
+
+	talhoes, err := app.carregarTalhoes(propriedadeID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	var areaTotal float64
+	for _, t := range talhoes {
+		areaTotal += t.AreaHectares
+	}
+
+	data := map[string]interface{}{
+		"Propriedade": propriedade,
+		"Talhoes":     talhoes,
+		"AreaTotal":   areaTotal,
+		"Usos":        []string{models.UsoLavoura, models.UsoPastagem},
+		"Title":       "Talhões",
+	}
+	app.renderTemplate(w, r, "talhoes/lista.html", data)
+}
+
+// SalvarTalhao cadastra ou atualiza um talhão
+func (app *Application) SalvarTalhao(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	id := formInt(r, "id")
+	propriedadeID := formInt(r, "propriedade_id")
+	nome := strings.TrimSpace(r.FormValue("nome"))
+	area := formFloat(r, "area_hectares")
+	uso := r.FormValue("uso")
+	if propriedadeID == 0 || nome == "" || area <= 0 {
+		app.clientError(w, "Informe o nome e a área do talhão.")
+		return
+	}
+	if uso != models.UsoLavoura && uso != models.UsoPastagem {
+		uso = models.UsoLavoura
+	}
+
+	var err error
+	if id == 0 {
+		_, err = app.DB.Exec(
+			`INSERT INTO talhoes (propriedade_id, nome, area_hectares, uso, observacoes) VALUES (?, ?, ?, ?, ?)`,
+			propriedadeID, nome, area, uso, r.FormValue("observacoes"),
+		)
+	} else {
+		_, err = app.DB.Exec(
+			`UPDATE talhoes SET nome=?, area_hectares=?, uso=?, observacoes=? WHERE id=? AND propriedade_id=?`,
+			nome, area, uso, r.FormValue("observacoes"), id, propriedadeID,
+		)
+	}
+	if err != nil {
+		log.Printf("❌ Erro ao salvar talhão: %v", err)
+		app.serverError(w, r, err)
+		return
+	}
+
+	setToast(w, "Talhão salvo com sucesso.", "success")
+	app.ListaTalhoes(w, r)
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Métodos de geração da grade de amostragem de solo
+const (
+	MetodoGradeRegular = "grade"  // um ponto a cada N hectares
+	MetodoGradeFaixas  = "faixas" // um ponto composto por faixa de área igual
+)
+
+// GradeAmostragem é o plano de amostragem de solo georreferenciado de um
+// talhão, gerado a partir do limite
+type GradeAmostragem struct {
+	ID               int               `json:"id"`
+	TalhaoID         int               `json:"talhao_id"`
+	Metodo           string            `json:"metodo"`
+	HectaresPorPonto float64           `json:"hectares_por_ponto"`
+	Faixas           int               `json:"faixas"`
+	Profundidade     string            `json:"profundidade"`
+	Observacoes      string            `json:"observacoes"`
+	CriadoEm         time.Time         `json:"criado_em"`
+	Pontos           []PontoAmostragem `json:"pontos,omitempty"`
+	TotalPontos      int               `json:"total_pontos"`
+	ComResultado     int               `json:"com_resultado"`
+}
+
+// Descricao resume o método para listas e impressos
+func (g GradeAmostragem) Descricao() string {
+	if g.Metodo == MetodoGradeFaixas {
+		return fmt.Sprintf("%d faixas de mesma área", g.Faixas)
+	}
+	hectares := strings.Replace(strconv.FormatFloat(g.HectaresPorPonto, 'f', -1, 64), ".", ",", 1)
+	return fmt.Sprintf("Grade de %s ha/ponto", hectares)
+}
+
+// PontoAmostragem é um ponto numerado da grade. O código é o ID da amostra
+// enviado ao laboratório e liga o laudo de volta ao ponto.
+type PontoAmostragem struct {
+	ID      int    `json:"id"`
+	GradeID int    `json:"grade_id"`
+	Numero  int    `json:"numero"`
+	Codigo  string `json:"codigo"`
+	Faixa   int    `json:"faixa"` // 0 na grade regular
+	Ponto
+	AnaliseID   int       `json:"analise_id"` // último laudo ligado ao ponto
+	DataAnalise time.Time `json:"data_analise"`
+}
+
+// TemResultado indica se há laudo ligado ao ponto
+func (p PontoAmostragem) TemResultado() bool {
+	return p.AnaliseID > 0
+}
+
+// CodigoAmostra monta o ID da amostra a partir da grade e do número do
+// ponto (ex.: G12-007)
+func CodigoAmostra(gradeID, numero int) string {
+	return fmt.Sprintf("G%d-%03d", gradeID, numero)
+}
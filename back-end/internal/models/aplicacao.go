@@ -0,0 +1,53 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Unidades de dose por hectare aceitas no registro de aplicações
+var UnidadesDose = []string{"L/ha", "mL/ha", "kg/ha", "g/ha"}
+
+// AplicacaoInsumo é o registro de uma aplicação de defensivo, fertilizante
+// ou adjuvante em um talhão durante a safra
+type AplicacaoInsumo struct {
+	ID                 int       `json:"id"`
+	SafraID            int       `json:"safra_id"`
+	TalhaoID           int       `json:"talhao_id"`
+	TalhaoNome         string    `json:"talhao_nome"`
+	Data               time.Time `json:"data"`
+	Produto            string    `json:"produto"`
+	PrincipioAtivo     string    `json:"principio_ativo"`
+	Dose               float64   `json:"dose"` // por hectare
+	UnidadeDose        string    `json:"unidade_dose"`
+	AreaAplicada       float64   `json:"area_aplicada"`
+	Operador           string    `json:"operador"`
+	Equipamento        string    `json:"equipamento"`
+	Temperatura        float64   `json:"temperatura"`      // °C
+	UmidadeRelativa    float64   `json:"umidade_relativa"` // %
+	VentoKmh           float64   `json:"vento_kmh"`
+	Clima              string    `json:"clima"`
+	IntervaloReentrada int       `json:"intervalo_reentrada"` // horas
+	CarenciaDias       int       `json:"carencia_dias"`
+	Observacoes        string    `json:"observacoes"`
+}
+
+// Quantidade é o total de produto aplicado (dose × área)
+func (a AplicacaoInsumo) Quantidade() float64 {
+	return a.Dose * a.AreaAplicada
+}
+
+// UnidadeTotal é a unidade da quantidade total (L, mL, kg ou g)
+func (a AplicacaoInsumo) UnidadeTotal() string {
+	return strings.TrimSuffix(a.UnidadeDose, "/ha")
+}
+
+// ReentradaLiberada é o momento a partir do qual pessoas podem entrar na área
+func (a AplicacaoInsumo) ReentradaLiberada() time.Time {
+	return a.Data.Add(time.Duration(a.IntervaloReentrada) * time.Hour)
+}
+
+// ColheitaLiberada é a primeira data em que a colheita respeita a carência
+func (a AplicacaoInsumo) ColheitaLiberada() time.Time {
+	return a.Data.AddDate(0, 0, a.CarenciaDias)
+}
@@ -0,0 +1,103 @@
+package models
+
+import "time"
+
+// Consultor é o técnico da equipe que atende os clientes. O token compõe a
+// URL do feed iCalendar assinado no celular.
+type Consultor struct {
+	ID       int    `json:"id"`
+	Nome     string `json:"nome"`
+	Email    string `json:"email"`
+	Telefone string `json:"telefone"`
+	Token    string `json:"-"`
+	Ativo    bool   `json:"ativo"`
+}
+
+// Tipos de evento do calendário
+const (
+	CalendarioConsulta   = "consulta"
+	CalendarioPlantio    = "plantio"
+	CalendarioColheita   = "colheita"
+	CalendarioSanitario  = "sanidade"
+	CalendarioAplicacao  = "aplicacao"
+	CalendarioManutencao = "manutencao"
+)
+
+// EventoCalendario é um compromisso agregado dos demais módulos (consultas,
+// safras, sanidade, aplicações). Eventos sem horário ocupam o dia inteiro;
+// Fim, quando informado, é o último dia (ou o horário de término).
+type EventoCalendario struct {
+	UID             string     `json:"uid"` // estável entre atualizações do feed
+	Tipo            string     `json:"tipo"`
+	Titulo          string     `json:"titulo"`
+	Descricao       string     `json:"descricao"`
+	Inicio          time.Time  `json:"inicio"`
+	Fim             *time.Time `json:"fim"`
+	DiaInteiro      bool       `json:"dia_inteiro"`
+	Concluido       bool       `json:"concluido"`
+	ClienteID       int        `json:"cliente_id"`
+	ClienteNome     string     `json:"cliente_nome"`
+	PropriedadeNome string     `json:"propriedade_nome"`
+	ConsultorNome   string     `json:"consultor_nome"`
+	Link            string     `json:"link"`
+}
+
+// Atrasado indica evento previsto que passou da data sem ser concluído
+func (e EventoCalendario) Atrasado(hoje time.Time) bool {
+	return !e.Concluido && e.Tipo != CalendarioConsulta && e.Tipo != CalendarioAplicacao && e.Inicio.Before(hoje)
+}
+
+// Rotulo é o nome do tipo exibido na legenda
+func (e EventoCalendario) Rotulo() string {
+	switch e.Tipo {
+	case CalendarioConsulta:
+		return "Consulta"
+	case CalendarioPlantio:
+		return "Plantio"
+	case CalendarioColheita:
+		return "Colheita"
+	case CalendarioSanitario:
+		return "Sanidade"
+	case CalendarioAplicacao:
+		return "Aplicação"
+	case CalendarioManutencao:
+		return "Manutenção"
+	}
+	return e.Tipo
+}
+
+// Cor é a classe de cor Bootstrap do tipo de evento
+func (e EventoCalendario) Cor() string {
+	switch e.Tipo {
+	case CalendarioConsulta:
+		return "primary"
+	case CalendarioPlantio:
+		return "success"
+	case CalendarioColheita:
+		return "warning"
+	case CalendarioSanitario:
+		return "danger"
+	case CalendarioAplicacao:
+		return "info"
+	}
+	return "secondary"
+}
+
+// Icone é o ícone Font Awesome do tipo de evento
+func (e EventoCalendario) Icone() string {
+	switch e.Tipo {
+	case CalendarioConsulta:
+		return "fa-user-tie"
+	case CalendarioPlantio:
+		return "fa-seedling"
+	case CalendarioColheita:
+		return "fa-tractor"
+	case CalendarioSanitario:
+		return "fa-syringe"
+	case CalendarioAplicacao:
+		return "fa-spray-can"
+	case CalendarioManutencao:
+		return "fa-wrench"
+	}
+	return "fa-calendar"
+}
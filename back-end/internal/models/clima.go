@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Chuva é a precipitação registrada em um dia na propriedade
+type Chuva struct {
+	ID            int       `json:"id"`
+	PropriedadeID int       `json:"propriedade_id"`
+	Data          time.Time `json:"data"`
+	Milimetros    float64   `json:"milimetros"`
+	Observacoes   string    `json:"observacoes"`
+}
+
+// ClimaDiario são as variáveis meteorológicas diárias da propriedade, de
+// estação própria ou importadas. Campos zerados indicam ausência de leitura.
+type ClimaDiario struct {
+	ID              int       `json:"id"`
+	PropriedadeID   int       `json:"propriedade_id"`
+	Data            time.Time `json:"data"`
+	TempMaxima      float64   `json:"temp_maxima"`      // °C
+	TempMinima      float64   `json:"temp_minima"`      // °C
+	UmidadeRelativa float64   `json:"umidade_relativa"` // % média
+	VentoMS         float64   `json:"vento_ms"`         // m/s a 2 m
+	Radiacao        float64   `json:"radiacao"`         // MJ/m²/dia
+	ETOInformada    float64   `json:"eto_informada"`    // mm, quando a estação já calcula
+}
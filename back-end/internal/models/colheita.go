@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Peso da saca de grãos (kg)
+const KgSaca = 60.0
+
+// UmidadePadrao é a umidade de comercialização (%) usada na correção do
+// peso colhido. Culturas fora da lista usam 13%.
+var UmidadePadrao = map[string]float64{
+	"Soja":           14,
+	"Milho":          14,
+	"Milho safrinha": 14,
+	"Sorgo":          14,
+	"Feijão":         14,
+	"Trigo":          13,
+	"Aveia":          13,
+	"Arroz":          13,
+	"Girassol":       11,
+	"Café":           12,
+	"Algodão":        8,
+}
+
+// ImpurezaTolerada é o teor de impurezas (%) sem desconto no peso
+const ImpurezaTolerada = 1.0
+
+// Colheita é uma carga ou lote colhido em uma safra, com a umidade e as
+// impurezas medidas na balança
+type Colheita struct {
+	ID          int       `json:"id"`
+	SafraID     int       `json:"safra_id"`
+	Data        time.Time `json:"data"`
+	AreaColhida float64   `json:"area_colhida"` // ha
+	PesoBrutoKg float64   `json:"peso_bruto_kg"`
+	Umidade     float64   `json:"umidade"`  // %
+	Impureza    float64   `json:"impureza"` // %
+	Observacoes string    `json:"observacoes"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Consulta é a visita ou atendimento técnico ao cliente, opcionalmente
+// vinculado a uma propriedade e a uma safra
+type Consulta struct {
+	ID            int                  `json:"id"`
+	ClienteID     int                  `json:"cliente_id"`
+	PropriedadeID int                  `json:"propriedade_id"`
+	SafraID       int                  `json:"safra_id"`
+	ConsultorID   int                  `json:"consultor_id"`
+	DataConsulta  time.Time            `json:"data_consulta"`
+	TipoConsulta  string               `json:"tipo_consulta"`
+	Observacoes   string               `json:"observacoes"`
+	Resultado     string               `json:"resultado"`
+	Produtos      []ProdutoRecomendado `json:"produtos"`
+}
+
+// ProdutoRecomendado é um defensivo recomendado na consulta, com os dados
+// exigidos no receituário agronômico
+type ProdutoRecomendado struct {
+	ID                 int     `json:"id"`
+	ConsultaID         int     `json:"consulta_id"`
+	Produto            string  `json:"produto"`
+	PrincipioAtivo     string  `json:"principio_ativo"`
+	RegistroMAPA       string  `json:"registro_mapa"`
+	ClasseToxicologica string  `json:"classe_toxicologica"`
+	Praga              string  `json:"praga"`
+	Dose               float64 `json:"dose"`
+	UnidadeDose        string  `json:"unidade_dose"`
+	VolumeCalda        float64 `json:"volume_calda"` // L/ha
+	ModoAplicacao      string  `json:"modo_aplicacao"`
+	NumeroAplicacoes   int     `json:"numero_aplicacoes"`
+	IntervaloReentrada int     `json:"intervalo_reentrada"` // horas
+	CarenciaDias       int     `json:"carencia_dias"`
+	Observacoes        string  `json:"observacoes"`
+}
+
+// Receituario é o receituário agronômico emitido a partir de uma consulta.
+// O PDF emitido fica arquivado junto com o número sequencial do ano.
+type Receituario struct {
+	ID               int                  `json:"id"`
+	Numero           int                  `json:"numero"`
+	Ano              int                  `json:"ano"`
+	ConsultaID       int                  `json:"consulta_id"`
+	DataEmissao      time.Time            `json:"data_emissao"`
+	ClienteNome      string               `json:"cliente_nome"`
+	ClienteDocumento string               `json:"cliente_documento"`
+	ClienteEndereco  string               `json:"cliente_endereco"`
+	PropriedadeNome  string               `json:"propriedade_nome"`
+	Municipio        string               `json:"municipio"`
+	Estado           string               `json:"estado"`
+	Cultura          string               `json:"cultura"`
+	AreaHectares     float64              `json:"area_hectares"`
+	Diagnostico      string               `json:"diagnostico"`
+	AgronomoNome     string               `json:"agronomo_nome"`
+	CREA             string               `json:"crea"`
+	Produtos         []ProdutoRecomendado `json:"produtos"`
+}
+
+// Identificacao formata o número do receituário (000123/2026)
+func (r Receituario) Identificacao() string {
+	return fmt.Sprintf("%06d/%d", r.Numero, r.Ano)
+}
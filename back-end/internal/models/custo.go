@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Grupos em que as categorias de custo são consolidadas nos relatórios
+var GruposCusto = []string{"Insumos", "Operações", "Mão de obra", "Arrendamento", "Serviços", "Outros"}
+
+// Unidades de comercialização aceitas nas cotações (R$ por unidade)
+const (
+	UnidadePrecoSaca     = "sc"
+	UnidadePrecoTonelada = "t"
+	UnidadePrecoArroba   = "@"
+)
+
+// CategoriaCusto é uma categoria configurável do livro de custos
+type CategoriaCusto struct {
+	ID    int    `json:"id"`
+	Nome  string `json:"nome"`
+	Grupo string `json:"grupo"`
+	Ativa bool   `json:"ativa"`
+}
+
+// LancamentoCusto é uma despesa da safra. Lançamentos gerados a partir de
+// uma aplicação guardam o ID da aplicação para não serem duplicados.
+type LancamentoCusto struct {
+	ID            int       `json:"id"`
+	SafraID       int       `json:"safra_id"`
+	CategoriaID   int       `json:"categoria_id"`
+	CategoriaNome string    `json:"categoria_nome"`
+	Grupo         string    `json:"grupo"`
+	Data          time.Time `json:"data"`
+	Descricao     string    `json:"descricao"`
+	Valor         float64   `json:"valor"`
+	AplicacaoID   int       `json:"aplicacao_id"`
+}
+
+// PrecoVenda é uma cotação importada para calcular receita e ROI
+type PrecoVenda struct {
+	ID      int       `json:"id"`
+	Cultura string    `json:"cultura"`
+	Data    time.Time `json:"data"`
+	Preco   float64   `json:"preco"`
+	Unidade string    `json:"unidade"` // sc, t ou @
+	Praca   string    `json:"praca"`
+}
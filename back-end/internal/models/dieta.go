@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Sistemas de alimentação atendidos pelo formulador
+const (
+	SistemaConfinamento  = "Confinamento"
+	SistemaSuplementacao = "Suplementação a pasto"
+)
+
+// Ingrediente da biblioteca de alimentos. Nutrientes em % da matéria seca,
+// matéria seca em % da matéria natural e preço por kg de matéria natural.
+type Ingrediente struct {
+	ID            int       `json:"id"`
+	Nome          string    `json:"nome"`
+	MateriaSeca   float64   `json:"materia_seca"`
+	ProteinaBruta float64   `json:"proteina_bruta"`
+	NDT           float64   `json:"ndt"`
+	FDN           float64   `json:"fdn"`
+	Calcio        float64   `json:"calcio"`
+	Fosforo       float64   `json:"fosforo"`
+	PrecoKg       float64   `json:"preco_kg"`
+	AtualizadoEm  time.Time `json:"atualizado_em"`
+}
+
+// Dieta é uma fórmula calculada e salva para um lote
+type Dieta struct {
+	ID                int         `json:"id"`
+	PropriedadeID     int         `json:"propriedade_id"`
+	Lote              string      `json:"lote"`
+	Sistema           string      `json:"sistema"`
+	PesoVivo          float64     `json:"peso_vivo"`
+	GanhoDiario       float64     `json:"ganho_diario"`
+	ConsumoMS         float64     `json:"consumo_ms"`
+	RendimentoCarcaca float64     `json:"rendimento_carcaca"`
+	NDT               float64     `json:"ndt"`
+	ProteinaBruta     float64     `json:"proteina_bruta"`
+	CustoCabecaDia    float64     `json:"custo_cabeca_dia"`
+	CustoArroba       float64     `json:"custo_arroba"`
+	CriadoEm          time.Time   `json:"criado_em"`
+	Itens             []DietaItem `json:"itens"`
+}
+
+// DietaItem é a participação de um ingrediente na dieta
+type DietaItem struct {
+	IngredienteID   int     `json:"ingrediente_id"`
+	IngredienteNome string  `json:"ingrediente_nome"`
+	Proporcao       float64 `json:"proporcao"` // % da matéria seca
+	KgMS            float64 `json:"kg_ms"`     // kg de MS por cabeça/dia
+	KgMN            float64 `json:"kg_mn"`     // kg de matéria natural por cabeça/dia
+	Custo           float64 `json:"custo"`     // R$ por cabeça/dia
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// CategoriasInsumo são as categorias do cadastro de insumos do estoque
+var CategoriasInsumo = []string{"Semente", "Fertilizante", "Defensivo", "Veterinário", "Outro"}
+
+// UnidadesEstoque são as unidades em que o saldo do insumo é controlado
+var UnidadesEstoque = []string{"kg", "L", "g", "mL", "t", "sc", "dose", "un"}
+
+// Origens da saída de estoque
+const (
+	SaidaAplicacao = "aplicacao"
+	SaidaSanitaria = "sanitaria"
+	SaidaAjuste    = "ajuste"
+)
+
+// Insumo é um produto controlado no estoque da propriedade (semente,
+// fertilizante, defensivo ou produto veterinário)
+type Insumo struct {
+	ID              int     `json:"id"`
+	PropriedadeID   int     `json:"propriedade_id"`
+	PropriedadeNome string  `json:"propriedade_nome"`
+	Nome            string  `json:"nome"`
+	Categoria       string  `json:"categoria"`
+	Unidade         string  `json:"unidade"`
+	PrincipioAtivo  string  `json:"principio_ativo"`
+	EstoqueMinimo   float64 `json:"estoque_minimo"` // 0 = sem mínimo
+	Ativo           bool    `json:"ativo"`
+}
+
+// EntradaInsumo é o recebimento de um lote do insumo (nota do fornecedor)
+type EntradaInsumo struct {
+	ID            int        `json:"id"`
+	InsumoID      int        `json:"insumo_id"`
+	Data          time.Time  `json:"data"`
+	Fornecedor    string     `json:"fornecedor"`
+	NotaFiscal    string     `json:"nota_fiscal"`
+	Lote          string     `json:"lote"`
+	Validade      *time.Time `json:"validade"`
+	Quantidade    float64    `json:"quantidade"`
+	CustoUnitario float64    `json:"custo_unitario"`
+	Observacoes   string     `json:"observacoes"`
+}
+
+// SaidaInsumo é o consumo do insumo, vinculado à aplicação na safra, à
+// aplicação sanitária ou lançado como ajuste (perda, inventário). Sem lote
+// informado, a baixa segue o lote de validade mais próxima.
+type SaidaInsumo struct {
+	ID                   int       `json:"id"`
+	InsumoID             int       `json:"insumo_id"`
+	Data                 time.Time `json:"data"`
+	Quantidade           float64   `json:"quantidade"`
+	Lote                 string    `json:"lote"`
+	Origem               string    `json:"origem"`
+	AplicacaoID          int       `json:"aplicacao_id"`
+	AplicacaoSanitariaID int       `json:"aplicacao_sanitaria_id"`
+	Descricao            string    `json:"descricao"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Sistemas de irrigação com a eficiência de aplicação típica (%)
+var SistemasIrrigacao = map[string]float64{
+	"Pivô central":          85,
+	"Aspersão convencional": 75,
+	"Gotejamento":           90,
+	"Autopropelido":         70,
+}
+
+// ConfigIrrigacao reúne os parâmetros do talhão irrigado para o balanço
+// hídrico: solo, raízes, sistema e localização para o cálculo da ET0
+type ConfigIrrigacao struct {
+	TalhaoID         int     `json:"talhao_id"`
+	Sistema          string  `json:"sistema"`
+	Eficiencia       float64 `json:"eficiencia"`        // %
+	AguaDisponivel   float64 `json:"agua_disponivel"`   // mm de água por cm de solo
+	ProfundidadeRaiz float64 `json:"profundidade_raiz"` // cm
+	FatorDeplecao    float64 `json:"fator_deplecao"`    // fração da CTA consumida sem estresse
+	LaminaMaxima     float64 `json:"lamina_maxima"`     // mm brutos por irrigação
+	Latitude         float64 `json:"latitude"`          // graus decimais (sul negativo)
+	Altitude         float64 `json:"altitude"`          // m
+}
+
+// CTA é a capacidade total de água disponível na zona radicular (mm)
+func (c ConfigIrrigacao) CTA() float64 {
+	return c.AguaDisponivel * c.ProfundidadeRaiz
+}
+
+// AFD é a água facilmente disponível, consumida sem estresse (mm)
+func (c ConfigIrrigacao) AFD() float64 {
+	return c.CTA() * c.FatorDeplecao
+}
+
+// Irrigacao é uma irrigação aplicada na safra, em lâmina bruta
+type Irrigacao struct {
+	ID          int       `json:"id"`
+	SafraID     int       `json:"safra_id"`
+	Data        time.Time `json:"data"`
+	Lamina      float64   `json:"lamina"`  // mm brutos
+	Duracao     float64   `json:"duracao"` // horas
+	Observacoes string    `json:"observacoes"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// ControleLeiteiro é uma pesagem de leite individual (controle leiteiro)
+type ControleLeiteiro struct {
+	ID            int       `json:"id"`
+	AnimalID      int       `json:"animal_id"`
+	Data          time.Time `json:"data"`
+	Litros        float64   `json:"litros"`
+	Ordenhas      int       `json:"ordenhas"`
+	Gordura       float64   `json:"gordura"`  // %
+	Proteina      float64   `json:"proteina"` // %
+	CCS           float64   `json:"ccs"`      // mil células/mL; 0 = não medida
+	Identificacao string    `json:"identificacao"`
+}
+
+// AnaliseTanque é o resultado laboratorial do leite do tanque de expansão
+type AnaliseTanque struct {
+	ID            int       `json:"id"`
+	PropriedadeID int       `json:"propriedade_id"`
+	Data          time.Time `json:"data"`
+	Tanque        string    `json:"tanque"`
+	Laboratorio   string    `json:"laboratorio"`
+	Gordura       float64   `json:"gordura"`  // %
+	Proteina      float64   `json:"proteina"` // %
+	CCS           float64   `json:"ccs"`      // mil células/mL
+	CBT           float64   `json:"cbt"`      // mil UFC/mL
+}
@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// TiposMaquina são os tipos de máquina e implemento do cadastro
+var TiposMaquina = []string{"Trator", "Pulverizador", "Plantadeira", "Colhedora", "Caminhão", "Implemento", "Outro"}
+
+// Unidades do medidor: horímetro (horas) ou hodômetro (km)
+const (
+	MedidorHoras = "h"
+	MedidorKm    = "km"
+)
+
+// Maquina é uma máquina da propriedade com horímetro ou hodômetro. A
+// capacidade operacional (ha/h) converte a área aplicada em horas de uso
+// quando a aplicação não informa as horas trabalhadas.
+type Maquina struct {
+	ID                    int     `json:"id"`
+	PropriedadeID         int     `json:"propriedade_id"`
+	PropriedadeNome       string  `json:"propriedade_nome"`
+	Nome                  string  `json:"nome"`
+	Tipo                  string  `json:"tipo"`
+	Marca                 string  `json:"marca"`
+	Modelo                string  `json:"modelo"`
+	Ano                   int     `json:"ano"`
+	Unidade               string  `json:"unidade"`                // h ou km
+	Medidor               float64 `json:"medidor"`                // leitura atual
+	CapacidadeOperacional float64 `json:"capacidade_operacional"` // ha/h
+	Ativo                 bool    `json:"ativo"`
+}
+
+// PlanoManutencao é um serviço periódico da máquina, por intervalo do
+// medidor, por dias corridos ou pelo que vencer primeiro. A última execução
+// é a base do próximo vencimento.
+type PlanoManutencao struct {
+	ID               int       `json:"id"`
+	MaquinaID        int       `json:"maquina_id"`
+	Descricao        string    `json:"descricao"`
+	IntervaloMedidor float64   `json:"intervalo_medidor"` // 0 = sem intervalo de uso
+	IntervaloDias    int       `json:"intervalo_dias"`    // 0 = sem intervalo de calendário
+	UltimoMedidor    float64   `json:"ultimo_medidor"`
+	UltimaData       time.Time `json:"ultima_data"`
+	Ativo            bool      `json:"ativo"`
+}
+
+// Manutencao é o registro de um serviço executado na máquina
+type Manutencao struct {
+	ID          int       `json:"id"`
+	MaquinaID   int       `json:"maquina_id"`
+	PlanoID     int       `json:"plano_id"`
+	Data        time.Time `json:"data"`
+	Medidor     float64   `json:"medidor"`
+	Descricao   string    `json:"descricao"`
+	Pecas       string    `json:"pecas"`
+	Custo       float64   `json:"custo"`
+	Responsavel string    `json:"responsavel"`
+	Observacoes string    `json:"observacoes"`
+}
+
+// LeituraMedidor registra o avanço do horímetro/hodômetro, informado
+// manualmente ou gerado por uma aplicação de insumos
+type LeituraMedidor struct {
+	ID          int       `json:"id"`
+	MaquinaID   int       `json:"maquina_id"`
+	Data        time.Time `json:"data"`
+	Medidor     float64   `json:"medidor"`    // leitura após o uso
+	Incremento  float64   `json:"incremento"` // uso no período
+	Origem      string    `json:"origem"`     // manual ou aplicacao
+	AplicacaoID int       `json:"aplicacao_id"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// Tipos de alvo do monitoramento
+const (
+	AlvoPraga  = "Praga"
+	AlvoDoenca = "Doença"
+)
+
+// Formas de medir a ocorrência no monitoramento
+const (
+	MedidaContagem   = "Contagem"   // indivíduos por ponto amostral (pano de batida, metro, planta)
+	MedidaPercentual = "Percentual" // incidência, severidade ou desfolha em %
+)
+
+// Medidas aceitas no cadastro de níveis e ocorrências
+var MedidasMonitoramento = []string{MedidaContagem, MedidaPercentual}
+
+// NivelAcao é o nível de controle (nível de ação econômica) de um alvo em
+// uma cultura. Estádio vazio vale para todo o ciclo.
+type NivelAcao struct {
+	ID          int     `json:"id"`
+	Cultura     string  `json:"cultura"`
+	Alvo        string  `json:"alvo"`
+	Tipo        string  `json:"tipo"`
+	Medida      string  `json:"medida"`
+	Limite      float64 `json:"limite"`
+	Estadio     string  `json:"estadio"`
+	Observacoes string  `json:"observacoes"`
+	Ativo       bool    `json:"ativo"`
+}
+
+// UnidadeMedida descreve a unidade do limite
+func (n NivelAcao) UnidadeMedida() string {
+	return UnidadeMedida(n.Medida)
+}
+
+// UnidadeMedida descreve a unidade de uma medida de monitoramento
+func UnidadeMedida(medida string) string {
+	if medida == MedidaPercentual {
+		return "%"
+	}
+	return "por ponto"
+}
+
+// Monitoramento é uma visita de amostragem de pragas e doenças (MIP) em
+// uma safra
+type Monitoramento struct {
+	ID          int               `json:"id"`
+	SafraID     int               `json:"safra_id"`
+	Data        time.Time         `json:"data"`
+	Estadio     string            `json:"estadio"`
+	Responsavel string            `json:"responsavel"`
+	Observacoes string            `json:"observacoes"`
+	Ocorrencias []OcorrenciaPraga `json:"ocorrencias"`
+}
+
+// OcorrenciaPraga é o resultado da amostragem de um alvo no monitoramento.
+// Em contagem, Valor é o total de indivíduos nos pontos; em percentual, a
+// média dos pontos.
+type OcorrenciaPraga struct {
+	ID              int     `json:"id"`
+	MonitoramentoID int     `json:"monitoramento_id"`
+	Alvo            string  `json:"alvo"`
+	Tipo            string  `json:"tipo"`
+	Medida          string  `json:"medida"`
+	Pontos          int     `json:"pontos"`
+	Valor           float64 `json:"valor"`
+}
+
+// Media é a média por ponto amostral (contagem) ou o percentual médio
+func (o OcorrenciaPraga) Media() float64 {
+	if o.Medida == MedidaPercentual || o.Pontos == 0 {
+		return o.Valor
+	}
+	return o.Valor / float64(o.Pontos)
+}
@@ -0,0 +1,25 @@
+// Package models reúne as entidades de domínio compartilhadas entre
+// handlers e services.
+package models
+
+// Propriedade espelha a tabela propriedades. Sede e Limite usam graus
+// decimais em SIRGAS 2000; AreaLimite é a área geodésica do limite (ha).
+type Propriedade struct {
+	ID          int     `json:"id"`
+	ClienteID   int     `json:"cliente_id"`
+	ClienteNome string  `json:"cliente_nome"`
+	Nome        string  `json:"nome"`
+	Hectares    float64 `json:"hectares"`
+	Municipio   string  `json:"municipio"`
+	Estado      string  `json:"estado"`
+	Sede        *Ponto  `json:"sede"`
+	Limite      []Ponto `json:"limite"` // anel externo, sem repetir o primeiro vértice
+	AreaLimite  float64 `json:"area_limite"`
+}
+
+// Ponto é uma posição em graus decimais (latitude negativa ao sul,
+// longitude negativa a oeste)
+type Ponto struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// Sentido da movimentação em relação à propriedade
+const (
+	MovimentacaoEntrada = "Entrada"
+	MovimentacaoSaida   = "Saída"
+)
+
+// CategoriasGTA são as estratificações por sexo e idade usadas na GTA de bovinos
+var CategoriasGTA = []string{
+	"Machos 0-12 meses",
+	"Fêmeas 0-12 meses",
+	"Machos 13-24 meses",
+	"Fêmeas 13-24 meses",
+	"Machos 25-36 meses",
+	"Fêmeas 25-36 meses",
+	"Machos acima de 36 meses",
+	"Fêmeas acima de 36 meses",
+}
+
+// FinalidadesGTA são as finalidades de trânsito aceitas
+var FinalidadesGTA = []string{"Abate", "Engorda", "Cria", "Recria", "Reprodução", "Exposição/Leilão", "Outros"}
+
+// MotivosAjusteRebanho são os lançamentos de estoque que não passam por GTA
+var MotivosAjusteRebanho = []string{"Saldo inicial", "Inventário", "Nascimentos", "Mortes", "Outros"}
+
+// Movimentacao é o trânsito de animais acobertado por uma GTA
+type Movimentacao struct {
+	ID            int                     `json:"id"`
+	PropriedadeID int                     `json:"propriedade_id"`
+	Tipo          string                  `json:"tipo"`
+	GTANumero     string                  `json:"gta_numero"`
+	GTASerie      string                  `json:"gta_serie"`
+	GTAUF         string                  `json:"gta_uf"`
+	Data          time.Time               `json:"data"`
+	Origem        string                  `json:"origem"`
+	Destino       string                  `json:"destino"`
+	Finalidade    string                  `json:"finalidade"`
+	Especie       string                  `json:"especie"`
+	Lote          string                  `json:"lote"`
+	Animais       string                  `json:"animais"`
+	Itens         []MovimentacaoCategoria `json:"itens"`
+}
+
+// MovimentacaoCategoria é a quantidade de cabeças de uma categoria na GTA
+type MovimentacaoCategoria struct {
+	Categoria  string `json:"categoria"`
+	Quantidade int    `json:"quantidade"`
+}
+
+// TotalCabecas soma as cabeças de todas as categorias da GTA
+func (m Movimentacao) TotalCabecas() int {
+	total := 0
+	for _, i := range m.Itens {
+		total += i.Quantidade
+	}
+	return total
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Piquete é a divisão de um talhão de pastagem para pastejo rotacionado
+type Piquete struct {
+	ID           int     `json:"id"`
+	TalhaoID     int     `json:"talhao_id"`
+	Numero       int     `json:"numero"`
+	AreaHectares float64 `json:"area_hectares"`
+}
+
+// PlanoPastejo guarda os parâmetros usados para gerar a escala de ocupação
+type PlanoPastejo struct {
+	ID           int       `json:"id"`
+	TalhaoID     int       `json:"talhao_id"`
+	Lote         string    `json:"lote"`
+	UA           float64   `json:"ua"`
+	Forrageira   string    `json:"forrageira"`
+	DiasDescanso int       `json:"dias_descanso"`
+	DiasOcupacao int       `json:"dias_ocupacao"`
+	DataInicio   time.Time `json:"data_inicio"`
+	Ciclos       int       `json:"ciclos"`
+}
+
+// OcupacaoPiquete é uma entrada prevista (e realizada) do lote em um piquete
+type OcupacaoPiquete struct {
+	ID              int        `json:"id"`
+	PlanoID         int        `json:"plano_id"`
+	PiqueteID       int        `json:"piquete_id"`
+	PiqueteNumero   int        `json:"piquete_numero"`
+	Lote            string     `json:"lote"`
+	Ciclo           int        `json:"ciclo"`
+	EntradaPrevista time.Time  `json:"entrada_prevista"`
+	SaidaPrevista   time.Time  `json:"saida_prevista"`
+	EntradaReal     *time.Time `json:"entrada_real"`
+	SaidaReal       *time.Time `json:"saida_real"`
+}
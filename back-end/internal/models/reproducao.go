@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+// Animal é o registro individual do rebanho de uma propriedade
+type Animal struct {
+	ID             int        `json:"id"`
+	PropriedadeID  int        `json:"propriedade_id"`
+	Identificacao  string     `json:"identificacao"`
+	Nome           string     `json:"nome"`
+	Especie        string     `json:"especie"`
+	Raca           string     `json:"raca"`
+	Sexo           string     `json:"sexo"`
+	Categoria      string     `json:"categoria"`
+	Lote           string     `json:"lote"`
+	DataNascimento *time.Time `json:"data_nascimento"`
+	Ativo          bool       `json:"ativo"`
+}
+
+// EstacaoMonta delimita a janela da estação de monta de uma propriedade
+type EstacaoMonta struct {
+	ID            int       `json:"id"`
+	PropriedadeID int       `json:"propriedade_id"`
+	Nome          string    `json:"nome"`
+	DataInicio    time.Time `json:"data_inicio"`
+	DataFim       time.Time `json:"data_fim"`
+}
+
+// ProtocoloIATF é um modelo de protocolo com as tarefas de cada dia (D0, D7, D9...)
+type ProtocoloIATF struct {
+	ID        int              `json:"id"`
+	Nome      string           `json:"nome"`
+	Descricao string           `json:"descricao"`
+	Etapas    []EtapaProtocolo `json:"etapas"`
+}
+
+type EtapaProtocolo struct {
+	ID          int    `json:"id"`
+	ProtocoloID int    `json:"protocolo_id"`
+	Dia         int    `json:"dia"`
+	Tarefa      string `json:"tarefa"`
+}
+
+// Tipos de cobertura aceitos em Inseminacao.Tipo
+const (
+	CoberturaIA    = "IA"
+	CoberturaIATF  = "IATF"
+	CoberturaMonta = "Monta natural"
+)
+
+type Inseminacao struct {
+	ID            int       `json:"id"`
+	AnimalID      int       `json:"animal_id"`
+	EstacaoID     int       `json:"estacao_id"`
+	ProtocoloID   int       `json:"protocolo_id"`
+	Data          time.Time `json:"data"`
+	Tipo          string    `json:"tipo"`
+	Touro         string    `json:"touro"`
+	PartidaSemen  string    `json:"partida_semen"`
+	Inseminador   string    `json:"inseminador"`
+	Identificacao string    `json:"identificacao"` // identificação do animal, preenchida nas consultas
+}
+
+// Resultados possíveis do diagnóstico de gestação
+const (
+	DiagnosticoPrenhe = "Prenhe"
+	DiagnosticoVazia  = "Vazia"
+)
+
+type DiagnosticoGestacao struct {
+	ID            int       `json:"id"`
+	AnimalID      int       `json:"animal_id"`
+	InseminacaoID int       `json:"inseminacao_id"`
+	Data          time.Time `json:"data"`
+	Resultado     string    `json:"resultado"`
+	Metodo        string    `json:"metodo"`
+	DiasGestacao  int       `json:"dias_gestacao"`
+}
+
+type Parto struct {
+	ID          int       `json:"id"`
+	AnimalID    int       `json:"animal_id"`
+	Data        time.Time `json:"data"`
+	Observacoes string    `json:"observacoes"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// Situação da safra, derivada das datas realizadas
+const (
+	SafraPlanejada   = "Planejada"
+	SafraEmAndamento = "Em andamento"
+	SafraColhida     = "Colhida"
+)
+
+// Unidades de produtividade aceitas
+const (
+	UnidadeSacasHa    = "sc/ha"
+	UnidadeToneladaHa = "t/ha"
+)
+
+// Culturas sugeridas no cadastro de safra
+var Culturas = []string{
+	"Soja", "Milho", "Milho safrinha", "Feijão", "Algodão", "Trigo",
+	"Sorgo", "Arroz", "Café", "Cana-de-açúcar", "Girassol", "Aveia",
+}
+
+// Safra é o ciclo de uma cultura em um talhão. Recomendações, aplicações e
+// produtividade ficam vinculadas a ela.
+type Safra struct {
+	ID                int        `json:"id"`
+	TalhaoID          int        `json:"talhao_id"`
+	TalhaoNome        string     `json:"talhao_nome"`
+	PropriedadeID     int        `json:"propriedade_id"`
+	PropriedadeNome   string     `json:"propriedade_nome"`
+	Rotulo            string     `json:"rotulo"` // ex.: 2025/26
+	Cultura           string     `json:"cultura"`
+	Cultivar          string     `json:"cultivar"`
+	AreaHectares      float64    `json:"area_hectares"`
+	PlantioPrevisto   time.Time  `json:"plantio_previsto"`
+	ColheitaPrevista  time.Time  `json:"colheita_prevista"`
+	PlantioReal       *time.Time `json:"plantio_real"`
+	ColheitaReal      *time.Time `json:"colheita_real"`
+	ProdutividadeAlvo float64    `json:"produtividade_alvo"`
+	Unidade           string     `json:"unidade"`
+	Observacoes       string     `json:"observacoes"`
+}
+
+// Situacao indica se a safra está planejada, em andamento ou colhida
+func (s Safra) Situacao() string {
+	switch {
+	case s.ColheitaReal != nil:
+		return SafraColhida
+	case s.PlantioReal != nil:
+		return SafraEmAndamento
+	default:
+		return SafraPlanejada
+	}
+}
+
+// ProducaoPrevista é a meta de produção total (alvo × área) na unidade da safra
+func (s Safra) ProducaoPrevista() float64 {
+	return s.ProdutividadeAlvo * s.AreaHectares
+}
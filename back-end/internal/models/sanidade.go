@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Tipos de manejo sanitário
+const (
+	SanitarioVacina     = "Vacina"
+	SanitarioVermifugo  = "Vermifugação"
+	SanitarioTratamento = "Tratamento"
+)
+
+// AplicacaoSanitaria registra uma vacinação, vermifugação ou tratamento
+// aplicado a um animal ou a um lote inteiro da propriedade
+type AplicacaoSanitaria struct {
+	ID            int       `json:"id"`
+	PropriedadeID int       `json:"propriedade_id"`
+	AnimalID      int       `json:"animal_id"` // 0 quando aplicado ao lote
+	Lote          string    `json:"lote"`
+	Tipo          string    `json:"tipo"`
+	Alvo          string    `json:"alvo"` // aftosa, brucelose, raiva, clostridioses, verminose...
+	Produto       string    `json:"produto"`
+	Partida       string    `json:"partida"`
+	Dose          float64   `json:"dose"`
+	Unidade       string    `json:"unidade"`
+	Via           string    `json:"via"`
+	Data          time.Time `json:"data"`
+	CarenciaAbate int       `json:"carencia_abate"` // dias
+	CarenciaLeite int       `json:"carencia_leite"` // dias
+	IntervaloDose int       `json:"intervalo_dose"` // dias até o reforço/próxima dose; 0 = dose única
+	Responsavel   string    `json:"responsavel"`
+	Observacoes   string    `json:"observacoes"`
+	Identificacao string    `json:"identificacao"` // identificação do animal, quando houver
+}
+
+// EventoSanitario é uma dose prevista no calendário sanitário da propriedade
+type EventoSanitario struct {
+	ID              int        `json:"id"`
+	PropriedadeID   int        `json:"propriedade_id"`
+	PropriedadeNome string     `json:"propriedade_nome"`
+	AnimalID        int        `json:"animal_id"`
+	Lote            string     `json:"lote"`
+	Tipo            string     `json:"tipo"`
+	Alvo            string     `json:"alvo"`
+	Produto         string     `json:"produto"`
+	DataPrevista    time.Time  `json:"data_prevista"`
+	OrigemID        int        `json:"origem_id"` // aplicação que gerou o evento
+	RealizadoEm     *time.Time `json:"realizado_em"`
+	AplicacaoID     int        `json:"aplicacao_id"` // aplicação que cumpriu o evento
+}
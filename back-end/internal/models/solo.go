@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// AnaliseSolo é o laudo de análise química de solo de um talhão. Bases
+// trocáveis, alumínio e acidez potencial em cmolc/dm³, P em mg/dm³
+// (Mehlich-1 ou resina), matéria orgânica em g/dm³ e argila em %.
+type AnaliseSolo struct {
+	ID              int       `json:"id"`
+	TalhaoID        int       `json:"talhao_id"`
+	TalhaoNome      string    `json:"talhao_nome"`
+	DataAmostra     time.Time `json:"data_amostra"`
+	Laboratorio     string    `json:"laboratorio"`
+	Profundidade    string    `json:"profundidade"` // ex.: 0-20 cm
+	PH              float64   `json:"ph"`           // CaCl2
+	MateriaOrganica float64   `json:"materia_organica"`
+	Fosforo         float64   `json:"fosforo"`
+	Potassio        float64   `json:"potassio"`
+	Calcio          float64   `json:"calcio"`
+	Magnesio        float64   `json:"magnesio"`
+	Aluminio        float64   `json:"aluminio"`
+	HAl             float64   `json:"h_al"`
+	Argila          float64   `json:"argila"`
+	Observacoes     string    `json:"observacoes"`
+	PontoID         int       `json:"ponto_id"`       // ponto da grade de amostragem, quando georreferenciada
+	CodigoAmostra   string    `json:"codigo_amostra"` // ID da amostra no laboratório
+}
+
+// SomaBases é Ca + Mg + K (cmolc/dm³)
+func (a AnaliseSolo) SomaBases() float64 {
+	return a.Calcio + a.Magnesio + a.Potassio
+}
+
+// CTC é a capacidade de troca de cátions a pH 7 (SB + H+Al)
+func (a AnaliseSolo) CTC() float64 {
+	return a.SomaBases() + a.HAl
+}
+
+// SaturacaoBases é o V% (SB / CTC)
+func (a AnaliseSolo) SaturacaoBases() float64 {
+	if a.CTC() == 0 {
+		return 0
+	}
+	return a.SomaBases() / a.CTC() * 100
+}
+
+// SaturacaoAluminio é o m% (Al / (SB + Al))
+func (a AnaliseSolo) SaturacaoAluminio() float64 {
+	if a.SomaBases()+a.Aluminio == 0 {
+		return 0
+	}
+	return a.Aluminio / (a.SomaBases() + a.Aluminio) * 100
+}
@@ -0,0 +1,19 @@
+package models
+
+// Usos possíveis de um talhão
+const (
+	UsoLavoura  = "Lavoura"
+	UsoPastagem = "Pastagem"
+)
+
+// Talhao é a subdivisão da área produtiva de uma propriedade
+type Talhao struct {
+	ID            int     `json:"id"`
+	PropriedadeID int     `json:"propriedade_id"`
+	Nome          string  `json:"nome"`
+	AreaHectares  float64 `json:"area_hectares"`
+	Uso           string  `json:"uso"`
+	Observacoes   string  `json:"observacoes"`
+	Limite        []Ponto `json:"limite"`      // anel externo em graus decimais, quando importado
+	AreaLimite    float64 `json:"area_limite"` // área geodésica do limite (ha)
+}
@@ -0,0 +1,660 @@
+package services
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// Limites da geração da grade de amostragem
+const (
+	MinHectaresPorPonto = 0.5
+	MaxHectaresPorPonto = 50.0
+	MaxFaixasAmostragem = 30
+	MaxPontosAmostragem = 2000
+)
+
+// planoLocal é o anel projetado em UTM no fuso do primeiro vértice, onde a
+// grade é montada em metros
+type planoLocal struct {
+	fuso int
+	sul  bool
+	anel [][2]float64
+}
+
+func projetarPlano(anel []models.Ponto) planoLocal {
+	fuso := ParaUTM(anel[0]).Fuso
+	p := planoLocal{fuso: fuso, sul: anel[0].Latitude < 0}
+	for _, v := range anel {
+		u := ParaUTMNoFuso(v, fuso)
+		p.anel = append(p.anel, [2]float64{u.Leste, u.Norte})
+	}
+	return p
+}
+
+func (p planoLocal) geografico(x, y float64) models.Ponto {
+	return DeUTM(UTM{Fuso: p.fuso, Sul: p.sul, Leste: x, Norte: y})
+}
+
+func (p planoLocal) anelGeografico(anel [][2]float64) []models.Ponto {
+	pontos := make([]models.Ponto, len(anel))
+	for i, v := range anel {
+		pontos[i] = p.geografico(v[0], v[1])
+	}
+	return pontos
+}
+
+// caixaPlana devolve x mínimo, y mínimo, x máximo e y máximo do anel
+func caixaPlana(anel [][2]float64) (float64, float64, float64, float64) {
+	x0, y0, x1, y1 := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, v := range anel {
+		x0, y0 = math.Min(x0, v[0]), math.Min(y0, v[1])
+		x1, y1 = math.Max(x1, v[0]), math.Max(y1, v[1])
+	}
+	return x0, y0, x1, y1
+}
+
+// dentroAnel testa se o ponto está no interior (regra par-ímpar)
+func dentroAnel(x, y float64, anel [][2]float64) bool {
+	dentro := false
+	for i, j := 0, len(anel)-1; i < len(anel); j, i = i, i+1 {
+		a, b := anel[i], anel[j]
+		if (a[1] > y) != (b[1] > y) && x < (b[0]-a[0])*(y-a[1])/(b[1]-a[1])+a[0] {
+			dentro = !dentro
+		}
+	}
+	return dentro
+}
+
+// recortarSemiplano mantém a parte do anel com a coordenada do eixo (0 = x,
+// 1 = y) acima ou abaixo do valor (Sutherland–Hodgman). Em anéis côncavos
+// o resultado pode ter arestas sobrepostas, sem efeito na área.
+func recortarSemiplano(anel [][2]float64, eixo int, valor float64, acima bool) [][2]float64 {
+	dentro := func(v [2]float64) bool {
+		if acima {
+			return v[eixo] >= valor
+		}
+		return v[eixo] <= valor
+	}
+	var saida [][2]float64
+	for i := range anel {
+		atual, anterior := anel[i], anel[(i+len(anel)-1)%len(anel)]
+		if dentro(atual) != dentro(anterior) {
+			t := (valor - anterior[eixo]) / (atual[eixo] - anterior[eixo])
+			saida = append(saida, [2]float64{
+				anterior[0] + t*(atual[0]-anterior[0]),
+				anterior[1] + t*(atual[1]-anterior[1]),
+			})
+		}
+		if dentro(atual) {
+			saida = append(saida, atual)
+		}
+	}
+	return saida
+}
+
+// centroidePlano é o centro de massa do anel
+func centroidePlano(anel [][2]float64) (float64, float64) {
+	var cx, cy, soma float64
+	for i := range anel {
+		a, b := anel[i], anel[(i+1)%len(anel)]
+		f := a[0]*b[1] - b[0]*a[1]
+		cx += (a[0] + b[0]) * f
+		cy += (a[1] + b[1]) * f
+		soma += f
+	}
+	if soma == 0 {
+		x0, y0, x1, y1 := caixaPlana(anel)
+		return (x0 + x1) / 2, (y0 + y1) / 2
+	}
+	return cx / (3 * soma), cy / (3 * soma)
+}
+
+// pontoInterno escolhe um ponto dentro da região e do talhão: o centroide
+// quando ele cai dentro; senão, o ponto de uma malha fina mais próximo dele
+// (regiões em L ou em meia-lua)
+func pontoInterno(talhao, regiao [][2]float64) [2]float64 {
+	cx, cy := centroidePlano(regiao)
+	if dentroAnel(cx, cy, talhao) && dentroAnel(cx, cy, regiao) {
+		return [2]float64{cx, cy}
+	}
+	x0, y0, x1, y1 := caixaPlana(regiao)
+	const passos = 40
+	melhor, distancia := [2]float64{cx, cy}, math.Inf(1)
+	for i := 0; i < passos; i++ {
+		for j := 0; j < passos; j++ {
+			x := x0 + (float64(i)+0.5)*(x1-x0)/passos
+			y := y0 + (float64(j)+0.5)*(y1-y0)/passos
+			if !dentroAnel(x, y, regiao) || !dentroAnel(x, y, talhao) {
+				continue
+			}
+			if d := math.Hypot(x-cx, y-cy); d < distancia {
+				melhor, distancia = [2]float64{x, y}, d
+			}
+		}
+	}
+	return melhor
+}
+
+// GerarGradeRegular divide o talhão em células quadradas de N hectares e põe
+// um ponto em cada célula com ao menos metade da área dentro do talhão (no
+// centro da célula, ou no centroide da parte interna nas bordas). A malha é
+// centrada no talhão e os pontos são numerados em zigue-zague de norte a
+// sul, que é a ordem de caminhamento da equipe de campo. Talhões menores
+// que meia célula recebem um único ponto.
+func GerarGradeRegular(anel []models.Ponto, hectaresPorPonto float64) ([]models.PontoAmostragem, error) {
+	if len(anel) < 3 {
+		return nil, errors.New("o talhão não tem limite cadastrado")
+	}
+	if hectaresPorPonto < MinHectaresPorPonto || hectaresPorPonto > MaxHectaresPorPonto {
+		return nil, fmt.Errorf("a densidade deve ficar entre %s e %s ha por ponto",
+			FormatarDecimal(MinHectaresPorPonto, 1), FormatarDecimal(MaxHectaresPorPonto, 0))
+	}
+	if AreaGeodesica(anel)/hectaresPorPonto > MaxPontosAmostragem {
+		return nil, fmt.Errorf("a grade teria mais de %d pontos; aumente a área por ponto", MaxPontosAmostragem)
+	}
+
+	plano := projetarPlano(anel)
+	lado := math.Sqrt(hectaresPorPonto * 10000)
+	x0, y0, x1, y1 := caixaPlana(plano.anel)
+	colunas := max(int(math.Ceil((x1-x0)/lado)), 1)
+	linhas := max(int(math.Ceil((y1-y0)/lado)), 1)
+	inicioX := (x0+x1)/2 - float64(colunas)*lado/2
+	topo := (y0+y1)/2 + float64(linhas)*lado/2
+
+	var pontos []models.PontoAmostragem
+	for l := 0; l < linhas; l++ {
+		y := topo - (float64(l)+0.5)*lado
+		for k := 0; k < colunas; k++ {
+			c := k
+			if l%2 == 1 {
+				c = colunas - 1 - k
+			}
+			x := inicioX + float64(c)*lado
+			celula := recortarSemiplano(plano.anel, 0, x, true)
+			celula = recortarSemiplano(celula, 0, x+lado, false)
+			celula = recortarSemiplano(celula, 1, y-lado/2, true)
+			celula = recortarSemiplano(celula, 1, y+lado/2, false)
+			if len(celula) < 3 || math.Abs(areaPlana(celula)) < lado*lado/2 {
+				continue
+			}
+			p := pontoInterno(plano.anel, celula)
+			pontos = append(pontos, models.PontoAmostragem{Ponto: plano.geografico(p[0], p[1])})
+		}
+	}
+	if len(pontos) == 0 {
+		p := pontoInterno(plano.anel, plano.anel)
+		pontos = append(pontos, models.PontoAmostragem{Ponto: plano.geografico(p[0], p[1])})
+	}
+	for i := range pontos {
+		pontos[i].Numero = i + 1
+	}
+	return pontos, nil
+}
+
+// faixasIguais divide o talhão em n faixas de mesma área, cortadas na
+// direção da maior dimensão, de oeste para leste ou de norte para sul
+func faixasIguais(plano planoLocal, n int) [][][2]float64 {
+	x0, y0, x1, y1 := caixaPlana(plano.anel)
+	eixo, inicio, fim := 0, x0, x1
+	if y1-y0 > x1-x0 {
+		eixo, inicio, fim = 1, y0, y1
+	}
+	total := math.Abs(areaPlana(plano.anel))
+
+	cortes := []float64{inicio}
+	for k := 1; k < n; k++ {
+		alvo := total * float64(k) / float64(n)
+		a, b := cortes[k-1], fim
+		for i := 0; i < 60; i++ {
+			meio := (a + b) / 2
+			if math.Abs(areaPlana(recortarSemiplano(plano.anel, eixo, meio, false))) < alvo {
+				a = meio
+			} else {
+				b = meio
+			}
+		}
+		cortes = append(cortes, (a+b)/2)
+	}
+	cortes = append(cortes, fim)
+
+	faixas := make([][][2]float64, n)
+	for k := 0; k < n; k++ {
+		faixa := recortarSemiplano(plano.anel, eixo, cortes[k], true)
+		faixas[k] = recortarSemiplano(faixa, eixo, cortes[k+1], false)
+	}
+	if eixo == 1 {
+		for i, j := 0, n-1; i < j; i, j = i+1, j-1 {
+			faixas[i], faixas[j] = faixas[j], faixas[i]
+		}
+	}
+	return faixas
+}
+
+func validarFaixas(anel []models.Ponto, faixas int) error {
+	if len(anel) < 3 {
+		return errors.New("o talhão não tem limite cadastrado")
+	}
+	if faixas < 2 || faixas > MaxFaixasAmostragem {
+		return fmt.Errorf("o número de faixas deve ficar entre 2 e %d", MaxFaixasAmostragem)
+	}
+	return nil
+}
+
+// FaixasAmostragem devolve os contornos das faixas usadas por
+// GerarGradeFaixas, na mesma numeração
+func FaixasAmostragem(anel []models.Ponto, faixas int) ([][]models.Ponto, error) {
+	if err := validarFaixas(anel, faixas); err != nil {
+		return nil, err
+	}
+	plano := projetarPlano(anel)
+	var contornos [][]models.Ponto
+	for _, f := range faixasIguais(plano, faixas) {
+		contornos = append(contornos, plano.anelGeografico(f))
+	}
+	return contornos, nil
+}
+
+// GerarGradeFaixas faz a amostragem em faixas: corta o talhão em faixas de
+// mesma área ao longo da maior dimensão e põe o ponto da amostra composta
+// de cada faixa no centroide (ou no ponto interno mais próximo dele). As
+// faixas são só geométricas, não zonas de manejo; estas saem dos mapas de
+// fertilidade interpolados a partir de uma grade regular.
+func GerarGradeFaixas(anel []models.Ponto, faixas int) ([]models.PontoAmostragem, error) {
+	if err := validarFaixas(anel, faixas); err != nil {
+		return nil, err
+	}
+	plano := projetarPlano(anel)
+	var pontos []models.PontoAmostragem
+	for i, f := range faixasIguais(plano, faixas) {
+		p := pontoInterno(plano.anel, f)
+		pontos = append(pontos, models.PontoAmostragem{Numero: i + 1, Faixa: i + 1, Ponto: plano.geografico(p[0], p[1])})
+	}
+	return pontos, nil
+}
+
+// escaparXML escapa o texto para conteúdo e atributos XML
+func escaparXML(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// ExportarGPX gera os pontos como waypoints e uma rota na ordem de
+// numeração (GPX 1.1), aceito pelos GPS de mão e aplicativos de campo
+func ExportarGPX(nome string, pontos []models.PontoAmostragem) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<gpx version="1.1" creator="AGR Consulta Pec" xmlns="http://www.topografix.com/GPX/1/1">` + "\n")
+	fmt.Fprintf(&b, "  <metadata><name>%s</name></metadata>\n", escaparXML(nome))
+	for _, p := range pontos {
+		fmt.Fprintf(&b, "  <wpt lat=\"%.7f\" lon=\"%.7f\"><name>%s</name><desc>Ponto %d%s</desc><sym>Flag, Blue</sym></wpt>\n",
+			p.Latitude, p.Longitude, escaparXML(p.Codigo), p.Numero, descricaoFaixa(p))
+	}
+	fmt.Fprintf(&b, "  <rte><name>%s</name>\n", escaparXML(nome))
+	for _, p := range pontos {
+		fmt.Fprintf(&b, "    <rtept lat=\"%.7f\" lon=\"%.7f\"><name>%s</name></rtept>\n", p.Latitude, p.Longitude, escaparXML(p.Codigo))
+	}
+	b.WriteString("  </rte>\n</gpx>\n")
+	return b.Bytes()
+}
+
+// ExportarKMLAmostragem gera o KML com o limite do talhão, as faixas (se
+// houver) e os pontos numerados
+func ExportarKMLAmostragem(nome string, anel []models.Ponto, faixas [][]models.Ponto, pontos []models.PontoAmostragem) []byte {
+	coordenadas := func(anel []models.Ponto) string {
+		var c strings.Builder
+		for i, p := range append(anel, anel[0]) {
+			if i > 0 {
+				c.WriteByte(' ')
+			}
+			fmt.Fprintf(&c, "%.7f,%.7f,0", p.Longitude, p.Latitude)
+		}
+		return c.String()
+	}
+	poligono := func(b *bytes.Buffer, nome, estilo string, anel []models.Ponto) {
+		fmt.Fprintf(b, "    <Placemark><name>%s</name><styleUrl>#%s</styleUrl><Polygon><outerBoundaryIs><LinearRing><coordinates>%s</coordinates></LinearRing></outerBoundaryIs></Polygon></Placemark>\n",
+			escaparXML(nome), estilo, coordenadas(anel))
+	}
+
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<kml xmlns="http://www.opengis.net/kml/2.2">` + "\n")
+	fmt.Fprintf(&b, "  <Document>\n    <name>%s</name>\n", escaparXML(nome))
+	b.WriteString(`    <Style id="limite"><LineStyle><color>ff00ffff</color><width>2</width></LineStyle><PolyStyle><fill>0</fill></PolyStyle></Style>` + "\n")
+	b.WriteString(`    <Style id="faixa"><LineStyle><color>ffffffff</color><width>1</width></LineStyle><PolyStyle><fill>0</fill></PolyStyle></Style>` + "\n")
+	b.WriteString(`    <Style id="ponto"><IconStyle><color>ff0000ff</color><Icon><href>http://maps.google.com/mapfiles/kml/paddle/red-circle.png</href></Icon></IconStyle></Style>` + "\n")
+	if len(anel) >= 3 {
+		poligono(&b, nome, "limite", anel)
+	}
+	for i, f := range faixas {
+		if len(f) >= 3 {
+			poligono(&b, fmt.Sprintf("Faixa %d", i+1), "faixa", f)
+		}
+	}
+	for _, p := range pontos {
+		fmt.Fprintf(&b, "    <Placemark><name>%s</name><description>Ponto %d%s</description><styleUrl>#ponto</styleUrl><Point><coordinates>%.7f,%.7f,0</coordinates></Point></Placemark>\n",
+			escaparXML(p.Codigo), p.Numero, descricaoFaixa(p), p.Longitude, p.Latitude)
+	}
+	b.WriteString("  </Document>\n</kml>\n")
+	return b.Bytes()
+}
+
+func descricaoFaixa(p models.PontoAmostragem) string {
+	if p.Faixa > 0 {
+		return fmt.Sprintf(" • faixa %d", p.Faixa)
+	}
+	return ""
+}
+
+// MapaAmostragem reúne os dados do mapa de amostragem impresso
+type MapaAmostragem struct {
+	Cliente     string
+	Propriedade string
+	Talhao      string
+	AreaHa      float64
+	Grade       models.GradeAmostragem
+	Anel        []models.Ponto
+	Faixas      [][]models.Ponto
+}
+
+// GerarMapaAmostragemPDF desenha o talhão com os pontos numerados em
+// escala, com norte e barra de escala, seguido da tabela de coordenadas
+// para conferência e anotação da coleta
+func GerarMapaAmostragemPDF(m MapaAmostragem) []byte {
+	const (
+		margem = 40.0
+		topo   = 96.0
+		altura = 440.0
+		limite = alturaA4 - 50
+	)
+	largura := larguraA4 - 2*margem
+	d := novoDocumentoPDF()
+
+	d.textoCentralizado(46, 15, true, "MAPA DE AMOSTRAGEM DE SOLO")
+	d.textoCentralizado(64, 10, false, fmt.Sprintf("%s • %s • %s (%s ha)",
+		m.Cliente, m.Propriedade, m.Talhao, FormatarDecimal(m.AreaHa, 2)))
+	d.textoCentralizado(78, 9, false, fmt.Sprintf("%s • %d pontos • Profundidade %s • Gerada em %s",
+		m.Grade.Descricao(), len(m.Grade.Pontos), textoOuTraco(m.Grade.Profundidade), m.Grade.CriadoEm.Format("02/01/2006")))
+	d.retangulo(margem, topo, largura, altura)
+
+	if len(m.Anel) >= 3 {
+		plano := projetarPlano(m.Anel)
+		x0, y0, x1, y1 := caixaPlana(plano.anel)
+		const folga = 24.0
+		escala := math.Min((largura-2*folga)/math.Max(x1-x0, 1), (altura-2*folga)/math.Max(y1-y0, 1))
+		desX := margem + (largura-(x1-x0)*escala)/2
+		desY := topo + (altura-(y1-y0)*escala)/2
+		tela := func(p models.Ponto) [2]float64 {
+			u := ParaUTMNoFuso(p, plano.fuso)
+			return [2]float64{desX + (u.Leste-x0)*escala, desY + (y1-u.Norte)*escala}
+		}
+		desenhar := func(anel []models.Ponto, espessura, cinza float64) {
+			var pontos [][2]float64
+			for _, p := range anel {
+				pontos = append(pontos, tela(p))
+			}
+			d.poligono(pontos, espessura, cinza)
+		}
+		for _, f := range m.Faixas {
+			desenhar(f, 0.6, 0.55)
+		}
+		desenhar(m.Anel, 1.4, 0)
+		for _, p := range m.Grade.Pontos {
+			t := tela(p.Ponto)
+			d.circulo(t[0], t[1], 2.2)
+			d.texto(t[0]+3.5, t[1]-3, 7, true, fmt.Sprint(p.Numero))
+		}
+
+		// Norte e barra de escala
+		nx, ny := margem+largura-22, topo+16.0
+		d.linha(nx, ny+26, nx, ny)
+		d.linha(nx, ny, nx-4, ny+8)
+		d.linha(nx, ny, nx+4, ny+8)
+		d.texto(nx-3.5, ny+36, 9, true, "N")
+		metros := 10.0
+		for _, candidato := range []float64{20, 50, 100, 200, 500, 1000, 2000, 5000} {
+			if candidato*escala <= largura/4 {
+				metros = candidato
+			}
+		}
+		bx, by := margem+12, topo+altura-14
+		d.linha(bx, by, bx+metros*escala, by)
+		d.linha(bx, by-3, bx, by+3)
+		d.linha(bx+metros*escala, by-3, bx+metros*escala, by+3)
+		rotulo := FormatarDecimal(metros, 0) + " m"
+		if metros >= 1000 {
+			rotulo = FormatarDecimal(metros/1000, 0) + " km"
+		}
+		d.texto(bx+metros*escala+5, by+3, 8, false, rotulo)
+	}
+
+	// Tabela de coordenadas
+	colunas := []struct {
+		titulo string
+		x      float64
+	}{{"Ponto", margem}, {"Amostra", margem + 38}, {"Latitude", margem + 100}, {"Longitude", margem + 172}, {"UTM (SIRGAS 2000)", margem + 250}, {"Coletado", margem + 440}}
+	y := topo + altura + 22
+	cabecalho := func() {
+		for _, c := range colunas {
+			d.texto(c.x, y, 8, true, c.titulo)
+		}
+		d.linha(margem, y+4, larguraA4-margem, y+4)
+		y += 15
+	}
+	cabecalho()
+	for _, p := range m.Grade.Pontos {
+		if y > limite {
+			d.novaPagina()
+			d.texto(margem, 40, 9, true, fmt.Sprintf("MAPA DE AMOSTRAGEM • %s • %s (continuação)", m.Propriedade, m.Talhao))
+			y = 64
+			cabecalho()
+		}
+		numero := fmt.Sprint(p.Numero)
+		if p.Faixa > 0 {
+			numero += fmt.Sprintf(" (F%d)", p.Faixa)
+		}
+		d.texto(colunas[0].x, y, 8, false, numero)
+		d.texto(colunas[1].x, y, 8, true, p.Codigo)
+		d.texto(colunas[2].x, y, 8, false, FormatarDecimal(p.Latitude, 6))
+		d.texto(colunas[3].x, y, 8, false, FormatarDecimal(p.Longitude, 6))
+		d.texto(colunas[4].x, y, 8, false, ParaUTM(p.Ponto).String())
+		d.retangulo(colunas[5].x+12, y-7, 8, 8)
+		y += 13
+	}
+	if y+30 > limite {
+		d.novaPagina()
+		y = 50
+	}
+	for _, l := range quebrarTexto("Colete de 15 a 20 subamostras em um raio de 10 m de cada ponto (ou em zigue-zague na faixa), "+
+		"misture em balde limpo e identifique o saco com o código da amostra. O mesmo código liga o laudo do laboratório ao ponto.",
+		largura, 8, false) {
+		y += 12
+		d.texto(margem, y, 8, false, l)
+	}
+	return d.bytes()
+}
+
+func textoOuTraco(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "—"
+	}
+	return s
+}
+
+// ResultadoLaboratorio é uma linha do laudo do laboratório com o ID da
+// amostra que identifica o ponto da grade
+type ResultadoLaboratorio struct {
+	Linha   int
+	Codigo  string
+	Analise models.AnaliseSolo
+}
+
+// colunaLaudo identifica o campo da análise pelo cabeçalho da planilha do
+// laboratório e o fator que converte a unidade informada para a usada no
+// sistema (K em mg/dm³, bases em mmolc/dm³, MO em % e argila em g/kg)
+func colunaLaudo(cabecalho string) (string, float64) {
+	h := normalizarCabecalho(cabecalho)
+	compacto := strings.NewReplacer(" ", "", "(", "", ")", "", ":", "").Replace(h)
+	nome := ""
+	if campos := strings.Fields(strings.NewReplacer("(", " ", ")", " ", ":", " ", "-", " ").Replace(h)); len(campos) > 0 {
+		nome = campos[0]
+	}
+	mmol := strings.Contains(h, "mmol")
+
+	switch {
+	case strings.Contains(h, "amostra") || strings.Contains(h, "codigo") || nome == "id" || nome == "identificacao" || nome == "ponto":
+		return "codigo", 1
+	case nome == "ph" || strings.HasPrefix(compacto, "phcacl"):
+		if strings.Contains(h, "agua") || strings.Contains(h, "h2o") || strings.Contains(h, "smp") {
+			return "", 0
+		}
+		return "ph", 1
+	case nome == "mo" || nome == "m.o." || strings.HasPrefix(h, "materia organica"):
+		if strings.Contains(h, "%") || strings.Contains(h, "dag") {
+			return "materia_organica", 10
+		}
+		return "materia_organica", 1
+	case strings.HasPrefix(compacto, "h+al") || nome == "hal" || strings.HasPrefix(h, "acidez potencial"):
+		if mmol {
+			return "h_al", 0.1
+		}
+		return "h_al", 1
+	case nome == "p" || nome == "fosforo" || strings.HasPrefix(compacto, "pmehlich") || strings.HasPrefix(compacto, "presina"):
+		return "fosforo", 1
+	case nome == "k" || nome == "potassio":
+		switch {
+		case mmol:
+			return "potassio", 0.1
+		case strings.Contains(h, "mg"):
+			return "potassio", 1.0 / 391
+		}
+		return "potassio", 1
+	case nome == "ca" || nome == "calcio":
+		if strings.HasPrefix(compacto, "ca+") || strings.HasPrefix(compacto, "ca/") {
+			return "", 0
+		}
+		if mmol {
+			return "calcio", 0.1
+		}
+		return "calcio", 1
+	case nome == "mg" || nome == "magnesio":
+		if mmol {
+			return "magnesio", 0.1
+		}
+		return "magnesio", 1
+	case nome == "al" || nome == "al3+" || nome == "aluminio":
+		if mmol {
+			return "aluminio", 0.1
+		}
+		return "aluminio", 1
+	case nome == "argila":
+		if strings.Contains(h, "g/kg") || strings.Contains(h, "g kg") {
+			return "argila", 0.1
+		}
+		return "argila", 1
+	}
+	return "", 0
+}
+
+// LerResultadosLaboratorio lê o laudo em CSV exportado pelo laboratório.
+// A primeira linha é o cabeçalho e precisa ter a coluna do ID da amostra;
+// as demais colunas são reconhecidas pelo nome (pH, MO, P, K, Ca, Mg, Al,
+// H+Al, argila) e convertidas para as unidades do sistema. Linhas
+// inválidas são relatadas sem interromper a leitura.
+func LerResultadosLaboratorio(r io.Reader) ([]ResultadoLaboratorio, []string) {
+	leitor, err := novoLeitorCSV(r)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+	cabecalho, err := leitor.Read()
+	if err != nil {
+		return nil, []string{"arquivo vazio ou ilegível"}
+	}
+	type coluna struct {
+		campo string
+		fator float64
+	}
+	colunas := make([]coluna, len(cabecalho))
+	usados := map[string]bool{}
+	for i, nome := range cabecalho {
+		campo, fator := colunaLaudo(nome)
+		if campo == "" || usados[campo] {
+			continue
+		}
+		usados[campo] = true
+		colunas[i] = coluna{campo, fator}
+	}
+	if !usados["codigo"] {
+		return nil, []string{"cabeçalho sem a coluna do ID da amostra (Amostra, Código ou ID)"}
+	}
+
+	var resultados []ResultadoLaboratorio
+	var erros []string
+	for {
+		campos, err := leitor.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			erros = append(erros, err.Error())
+			continue
+		}
+		linha, _ := leitor.FieldPos(0)
+		res := ResultadoLaboratorio{Linha: linha}
+		a := &res.Analise
+		vazia := true
+		for i, valor := range campos {
+			if i >= len(colunas) || colunas[i].campo == "" || strings.TrimSpace(valor) == "" {
+				continue
+			}
+			vazia = false
+			if colunas[i].campo == "codigo" {
+				res.Codigo = strings.ToUpper(strings.TrimSpace(valor))
+				continue
+			}
+			v := LerDecimal(valor) * colunas[i].fator
+			switch colunas[i].campo {
+			case "ph":
+				a.PH = v
+			case "materia_organica":
+				a.MateriaOrganica = v
+			case "fosforo":
+				a.Fosforo = v
+			case "potassio":
+				a.Potassio = v
+			case "calcio":
+				a.Calcio = v
+			case "magnesio":
+				a.Magnesio = v
+			case "aluminio":
+				a.Aluminio = v
+			case "h_al":
+				a.HAl = v
+			case "argila":
+				a.Argila = v
+			}
+		}
+		switch {
+		case vazia:
+			continue
+		case res.Codigo == "":
+			erros = append(erros, fmt.Sprintf("linha %d: sem ID da amostra", linha))
+			continue
+		case a.PH < 0 || a.PH > 14:
+			erros = append(erros, fmt.Sprintf("linha %d (%s): pH fora da faixa", linha, res.Codigo))
+			continue
+		case a.CTC() == 0:
+			erros = append(erros, fmt.Sprintf("linha %d (%s): sem bases trocáveis nem H+Al", linha, res.Codigo))
+			continue
+		}
+		resultados = append(resultados, res)
+	}
+	return resultados, erros
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// TotalProduto consolida as aplicações de um produto na safra
+type TotalProduto struct {
+	Produto        string  `json:"produto"`
+	PrincipioAtivo string  `json:"principio_ativo"`
+	Unidade        string  `json:"unidade"`
+	Aplicacoes     int     `json:"aplicacoes"`
+	Quantidade     float64 `json:"quantidade"`
+	PorHectare     float64 `json:"por_hectare"` // quantidade / área da safra
+}
+
+// TotalizarProdutos soma as quantidades por produto e unidade e calcula a
+// quantidade média por hectare da safra
+func TotalizarProdutos(aplicacoes []models.AplicacaoInsumo, areaSafra float64) []TotalProduto {
+	indice := make(map[string]int)
+	var totais []TotalProduto
+	for _, a := range aplicacoes {
+		chave := a.Produto + "|" + a.UnidadeTotal()
+		i, ok := indice[chave]
+		if !ok {
+			i = len(totais)
+			indice[chave] = i
+			totais = append(totais, TotalProduto{
+				Produto:        a.Produto,
+				PrincipioAtivo: a.PrincipioAtivo,
+				Unidade:        a.UnidadeTotal(),
+			})
+		}
+		totais[i].Aplicacoes++
+		totais[i].Quantidade += a.Quantidade()
+	}
+	for i := range totais {
+		if areaSafra > 0 {
+			totais[i].PorHectare = totais[i].Quantidade / areaSafra
+		}
+	}
+	sort.Slice(totais, func(i, j int) bool { return totais[i].Produto < totais[j].Produto })
+	return totais
+}
+
+// ValidarIntervalosAplicacao confere a aplicação contra a colheita da safra:
+// a carência (dias) e o intervalo de reentrada (horas) precisam terminar até
+// a colheita prevista, e não se registra aplicação após a colheita realizada.
+func ValidarIntervalosAplicacao(a models.AplicacaoInsumo, safra models.Safra) error {
+	if safra.ColheitaReal != nil && a.Data.After(*safra.ColheitaReal) {
+		return fmt.Errorf("a safra já foi colhida em %s", safra.ColheitaReal.Format("02/01/2006"))
+	}
+	if a.Data.Before(safra.PlantioPrevisto.AddDate(0, 0, -60)) {
+		return fmt.Errorf("a data é anterior ao período da safra")
+	}
+	// A carência conta em dias corridos a partir da data da aplicação; a hora
+	// registrada não pode empurrar a liberação para depois da colheita
+	if liberada := inicioDoDia(a.Data).AddDate(0, 0, a.CarenciaDias); liberada.After(safra.ColheitaPrevista) {
+		return fmt.Errorf("carência de %d dias libera a colheita só em %s, após a colheita prevista (%s)",
+			a.CarenciaDias, liberada.Format("02/01/2006"), safra.ColheitaPrevista.Format("02/01/2006"))
+	}
+	if reentrada := a.ReentradaLiberada(); reentrada.After(safra.ColheitaPrevista) {
+		return fmt.Errorf("intervalo de reentrada de %dh termina após a colheita prevista (%s)",
+			a.IntervaloReentrada, safra.ColheitaPrevista.Format("02/01/2006"))
+	}
+	return nil
+}
+
+// ReentradasAtivas retorna as aplicações cuja área ainda está interditada
+func ReentradasAtivas(aplicacoes []models.AplicacaoInsumo, agora time.Time) []models.AplicacaoInsumo {
+	var ativas []models.AplicacaoInsumo
+	for _, a := range aplicacoes {
+		if a.IntervaloReentrada > 0 && a.ReentradaLiberada().After(agora) {
+			ativas = append(ativas, a)
+		}
+	}
+	return ativas
+}
+
+// ColheitaLiberadaSafra é a data mais tardia de liberação de colheita entre
+// as aplicações da safra (zero quando não há carência a cumprir)
+func ColheitaLiberadaSafra(aplicacoes []models.AplicacaoInsumo) time.Time {
+	var liberada time.Time
+	for _, a := range aplicacoes {
+		if d := a.ColheitaLiberada(); a.CarenciaDias > 0 && d.After(liberada) {
+			liberada = d
+		}
+	}
+	return liberada
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+func TestValidarIntervalosAplicacao(t *testing.T) {
+	safra := models.Safra{
+		PlantioPrevisto:  time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		ColheitaPrevista: time.Date(2027, 2, 10, 0, 0, 0, 0, time.UTC),
+	}
+	aplicacao := func(data time.Time, carencia, reentrada int) models.AplicacaoInsumo {
+		return models.AplicacaoInsumo{Data: data, CarenciaDias: carencia, IntervaloReentrada: reentrada}
+	}
+	colhida := safra
+	colheitaReal := time.Date(2027, 2, 5, 0, 0, 0, 0, time.UTC)
+	colhida.ColheitaReal = &colheitaReal
+
+	casos := []struct {
+		nome  string
+		a     models.AplicacaoInsumo
+		safra models.Safra
+		ok    bool
+	}{
+		{"carência termina antes da colheita", aplicacao(time.Date(2027, 1, 1, 9, 0, 0, 0, time.UTC), 30, 24), safra, true},
+		// 11/01 + 30 dias = 10/02, o próprio dia da colheita prevista
+		{"carência termina no dia da colheita, aplicada à tarde", aplicacao(time.Date(2027, 1, 11, 16, 30, 0, 0, time.UTC), 30, 0), safra, true},
+		{"carência termina depois da colheita", aplicacao(time.Date(2027, 1, 12, 8, 0, 0, 0, time.UTC), 30, 0), safra, false},
+		{"reentrada invade a colheita", aplicacao(time.Date(2027, 2, 9, 8, 0, 0, 0, time.UTC), 0, 24), safra, false},
+		{"antes do período da safra", aplicacao(time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), 0, 0), safra, false},
+		{"depois da colheita realizada", aplicacao(time.Date(2027, 2, 6, 0, 0, 0, 0, time.UTC), 0, 0), colhida, false},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			err := ValidarIntervalosAplicacao(c.a, c.safra)
+			if (err == nil) != c.ok {
+				t.Errorf("erro = %v, esperado ok = %v", err, c.ok)
+			}
+		})
+	}
+}
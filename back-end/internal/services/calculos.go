@@ -0,0 +1,266 @@
+// Package services concentra os cálculos agronômicos e zootécnicos do
+// sistema. As funções recebem entidades de models já carregadas do banco
+// e não acessam o DuckDB diretamente.
+package services
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// percentual retorna parte/total em %, evitando divisão por zero
+func percentual(parte, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(parte) / float64(total) * 100
+}
+
+// inicioDoDia normaliza a data para 00:00 UTC, mesmo fuso das colunas DATE do DuckDB
+func inicioDoDia(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// FormatarDecimal formata no padrão brasileiro: milhar com ponto e decimais com vírgula
+func FormatarDecimal(v float64, casas int) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', casas, 64)
+	inteiro, decimal, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	if v < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, c := range inteiro {
+		if i > 0 && (len(inteiro)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(c)
+	}
+	if decimal != "" {
+		b.WriteByte(',')
+		b.WriteString(decimal)
+	}
+	return b.String()
+}
+
+// LerDecimal converte texto em número aceitando vírgula como separador
+// decimal (padrão brasileiro). Retorna 0 quando o valor é inválido.
+func LerDecimal(s string) float64 {
+	v, _ := lerDecimal(s)
+	return v
+}
+
+// lerDecimal é LerDecimal indicando se o texto era um número, para os
+// arquivos em que a célula vazia é ausência e não zero
+func lerDecimal(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, ",") {
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.ReplaceAll(s, ",", ".")
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// resolverSistemaLinear resolve m·x = v por eliminação de Gauss com pivotamento
+// parcial. Retorna false quando o sistema é singular.
+func resolverSistemaLinear(m [][]float64, v []float64) ([]float64, bool) {
+	n := len(v)
+	a := make([][]float64, n)
+	for i := range m {
+		a[i] = append(append([]float64{}, m[i]...), v[i])
+	}
+
+	for col := 0; col < n; col++ {
+		pivo := col
+		for i := col + 1; i < n; i++ {
+			if math.Abs(a[i][col]) > math.Abs(a[pivo][col]) {
+				pivo = i
+			}
+		}
+		if math.Abs(a[pivo][col]) < 1e-12 {
+			return nil, false
+		}
+		a[col], a[pivo] = a[pivo], a[col]
+		for i := col + 1; i < n; i++ {
+			f := a[i][col] / a[col][col]
+			for j := col; j <= n; j++ {
+				a[i][j] -= f * a[col][j]
+			}
+		}
+	}
+
+	x := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		soma := a[i][n]
+		for j := i + 1; j < n; j++ {
+			soma -= a[i][j] * x[j]
+		}
+		x[i] = soma / a[i][i]
+	}
+	return x, true
+}
+
+// Sentido das restrições de minimizarLP
+const (
+	menorOuIgual = -1
+	igual        = 0
+	maiorOuIgual = 1
+)
+
+// restricaoLP representa coef·x (≤, = ou ≥) valor
+type restricaoLP struct {
+	coef  []float64
+	tipo  int
+	valor float64
+}
+
+// minimizarLP resolve min custo·x sujeito às restrições e x ≥ 0 pelo simplex
+// de duas fases (regra de Bland). Retorna false se o problema for inviável ou
+// ilimitado.
+func minimizarLP(custo []float64, restricoes []restricaoLP) ([]float64, bool) {
+	const eps = 1e-9
+	n, m := len(custo), len(restricoes)
+
+	// Normaliza os termos independentes para valores não negativos
+	rs := make([]restricaoLP, m)
+	var nFolga, nArtificial int
+	for i, r := range restricoes {
+		coef := append([]float64(nil), r.coef...)
+		tipo, valor := r.tipo, r.valor
+		if valor < 0 {
+			for j := range coef {
+				coef[j] = -coef[j]
+			}
+			tipo, valor = -tipo, -valor
+		}
+		rs[i] = restricaoLP{coef, tipo, valor}
+		if tipo != igual {
+			nFolga++
+		}
+		if tipo != menorOuIgual {
+			nArtificial++
+		}
+	}
+
+	colunas := n + nFolga + nArtificial
+	inicioArtificial := n + nFolga
+	tab := make([][]float64, m)
+	base := make([]int, m)
+	folga, artificial := n, inicioArtificial
+	for i, r := range rs {
+		tab[i] = make([]float64, colunas+1)
+		copy(tab[i], r.coef)
+		tab[i][colunas] = r.valor
+		switch r.tipo {
+		case menorOuIgual:
+			tab[i][folga] = 1
+			base[i] = folga
+			folga++
+		case maiorOuIgual:
+			tab[i][folga] = -1
+			folga++
+			fallthrough
+		default:
+			tab[i][artificial] = 1
+			base[i] = artificial
+			artificial++
+		}
+	}
+
+	pivotear := func(linha, coluna int) {
+		p := tab[linha][coluna]
+		for j := range tab[linha] {
+			tab[linha][j] /= p
+		}
+		for i := range tab {
+			if i == linha || tab[i][coluna] == 0 {
+				continue
+			}
+			f := tab[i][coluna]
+			for j := range tab[i] {
+				tab[i][j] -= f * tab[linha][j]
+			}
+		}
+		base[linha] = coluna
+	}
+
+	otimizar := func(c []float64, limite int) bool {
+		for iter := 0; iter < 5000; iter++ {
+			entra := -1
+			for j := 0; j < limite; j++ {
+				reduzido := c[j]
+				for i := range tab {
+					reduzido -= c[base[i]] * tab[i][j]
+				}
+				if reduzido < -eps {
+					entra = j
+					break
+				}
+			}
+			if entra < 0 {
+				return true
+			}
+			sai := -1
+			var menor float64
+			for i := range tab {
+				if tab[i][entra] > eps {
+					razao := tab[i][colunas] / tab[i][entra]
+					if sai < 0 || razao < menor-eps || (razao < menor+eps && base[i] < base[sai]) {
+						sai, menor = i, razao
+					}
+				}
+			}
+			if sai < 0 {
+				return false
+			}
+			pivotear(sai, entra)
+		}
+		return false
+	}
+
+	// Fase 1: minimiza a soma das variáveis artificiais
+	if nArtificial > 0 {
+		c := make([]float64, colunas)
+		for j := inicioArtificial; j < colunas; j++ {
+			c[j] = 1
+		}
+		otimizar(c, colunas)
+		for i := range tab {
+			if base[i] >= inicioArtificial && tab[i][colunas] > 1e-7 {
+				return nil, false
+			}
+		}
+		// Retira da base as artificiais que ficaram com valor zero
+		for i := range tab {
+			if base[i] < inicioArtificial {
+				continue
+			}
+			for j := 0; j < inicioArtificial; j++ {
+				if math.Abs(tab[i][j]) > eps {
+					pivotear(i, j)
+					break
+				}
+			}
+		}
+	}
+
+	// Fase 2: custo original, sem deixar artificiais entrarem na base
+	c := make([]float64, colunas)
+	copy(c, custo)
+	if !otimizar(c, inicioArtificial) {
+		return nil, false
+	}
+
+	x := make([]float64, n)
+	for i, b := range base {
+		if b < n {
+			x[b] = tab[i][colunas]
+		}
+	}
+	return x, true
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLerDecimal(t *testing.T) {
+	casos := []struct {
+		texto  string
+		valor  float64
+		numero bool
+	}{
+		{"12.5", 12.5, true},
+		{"12,5", 12.5, true},
+		{" 1.234,56 ", 1234.56, true},
+		{"-0,75", -0.75, true},
+		{"", 0, false},
+		{"abc", 0, false},
+	}
+	for _, c := range casos {
+		v, ok := lerDecimal(c.texto)
+		if v != c.valor || ok != c.numero {
+			t.Errorf("lerDecimal(%q) = %v, %v; esperado %v, %v", c.texto, v, ok, c.valor, c.numero)
+		}
+		if LerDecimal(c.texto) != c.valor {
+			t.Errorf("LerDecimal(%q) = %v; esperado %v", c.texto, LerDecimal(c.texto), c.valor)
+		}
+	}
+}
+
+func TestFormatarDecimal(t *testing.T) {
+	casos := []struct {
+		valor float64
+		casas int
+		texto string
+	}{
+		{1234567.891, 2, "1.234.567,89"},
+		{-1234.5, 1, "-1.234,5"},
+		{999, 0, "999"},
+		{-0.001, 2, "0,00"},
+	}
+	for _, c := range casos {
+		if s := FormatarDecimal(c.valor, c.casas); s != c.texto {
+			t.Errorf("FormatarDecimal(%v, %d) = %q; esperado %q", c.valor, c.casas, s, c.texto)
+		}
+	}
+}
+
+func TestResolverSistemaLinear(t *testing.T) {
+	// 2x + y − z = 8; −3x − y + 2z = −11; −2x + y + 2z = −3 → (2, 3, −1)
+	x, ok := resolverSistemaLinear([][]float64{{2, 1, -1}, {-3, -1, 2}, {-2, 1, 2}}, []float64{8, -11, -3})
+	if !ok {
+		t.Fatal("sistema tratado como singular")
+	}
+	for i, esperado := range []float64{2, 3, -1} {
+		if math.Abs(x[i]-esperado) > 1e-9 {
+			t.Errorf("x[%d] = %v, esperado %v", i, x[i], esperado)
+		}
+	}
+	if _, ok := resolverSistemaLinear([][]float64{{1, 2}, {2, 4}}, []float64{3, 6}); ok {
+		t.Error("sistema singular aceito")
+	}
+}
+
+func TestMinimizarLP(t *testing.T) {
+	casos := []struct {
+		nome       string
+		custo      []float64
+		restricoes []restricaoLP
+		x          []float64
+		ok         bool
+	}{
+		// Wyndor Glass (Hillier e Lieberman): máx 3x1 + 5x2 → x = (2, 6), Z = 36
+		{"só restrições ≤", []float64{-3, -5}, []restricaoLP{
+			{[]float64{1, 0}, menorOuIgual, 4},
+			{[]float64{0, 2}, menorOuIgual, 12},
+			{[]float64{3, 2}, menorOuIgual, 18},
+		}, []float64{2, 6}, true},
+		// Radioterapia (Hillier e Lieberman): mín 0,4x1 + 0,5x2 → x = (7,5, 4,5), Z = 5,25
+		{"duas fases com = e ≥", []float64{0.4, 0.5}, []restricaoLP{
+			{[]float64{0.3, 0.1}, menorOuIgual, 2.7},
+			{[]float64{0.5, 0.5}, igual, 6},
+			{[]float64{0.6, 0.4}, maiorOuIgual, 6},
+		}, []float64{7.5, 4.5}, true},
+		{"termo independente negativo", []float64{1}, []restricaoLP{
+			{[]float64{-1}, menorOuIgual, -3},
+		}, []float64{3}, true},
+		{"inviável", []float64{1}, []restricaoLP{
+			{[]float64{1}, menorOuIgual, 1},
+			{[]float64{1}, maiorOuIgual, 2},
+		}, nil, false},
+		{"ilimitado", []float64{-1, 0}, []restricaoLP{
+			{[]float64{1, -1}, menorOuIgual, 1},
+		}, nil, false},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			x, ok := minimizarLP(c.custo, c.restricoes)
+			if ok != c.ok {
+				t.Fatalf("ok = %v, esperado %v (x = %v)", ok, c.ok, x)
+			}
+			for i := range c.x {
+				if math.Abs(x[i]-c.x[i]) > 1e-7 {
+					t.Errorf("x = %v, esperado %v", x, c.x)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// Visões do calendário
+const (
+	VisaoMes    = "mes"
+	VisaoSemana = "semana"
+	VisaoAgenda = "agenda"
+)
+
+// DiasAgenda é o horizonte da visão de agenda a partir da data de referência
+const DiasAgenda = 30
+
+// DiaCalendario agrupa os eventos de um dia da grade
+type DiaCalendario struct {
+	Data          time.Time
+	Eventos       []models.EventoCalendario
+	ForaDoPeriodo bool // dia de outro mês completando a semana
+	Hoje          bool
+}
+
+// DiaSemana é a abreviatura do dia da semana (Dom a Sáb)
+func (d DiaCalendario) DiaSemana() string {
+	return diasSemana[d.Data.Weekday()]
+}
+
+var diasSemana = [...]string{"Dom", "Seg", "Ter", "Qua", "Qui", "Sex", "Sáb"}
+
+// PeriodoCalendario devolve o primeiro e o último dia exibidos na visão. O
+// mês é completado até domingo no início e sábado no fim; a semana começa
+// no domingo.
+func PeriodoCalendario(visao string, ref time.Time) (time.Time, time.Time) {
+	ref = inicioDoDia(ref)
+	switch visao {
+	case VisaoSemana:
+		inicio := ref.AddDate(0, 0, -int(ref.Weekday()))
+		return inicio, inicio.AddDate(0, 0, 6)
+	case VisaoAgenda:
+		return ref, ref.AddDate(0, 0, DiasAgenda-1)
+	}
+	primeiro := time.Date(ref.Year(), ref.Month(), 1, 0, 0, 0, 0, time.UTC)
+	ultimo := primeiro.AddDate(0, 1, -1)
+	return primeiro.AddDate(0, 0, -int(primeiro.Weekday())), ultimo.AddDate(0, 0, 6-int(ultimo.Weekday()))
+}
+
+// NavegarCalendario devolve as datas de referência do período anterior e do
+// seguinte na mesma visão
+func NavegarCalendario(visao string, ref time.Time) (time.Time, time.Time) {
+	ref = inicioDoDia(ref)
+	switch visao {
+	case VisaoSemana:
+		return ref.AddDate(0, 0, -7), ref.AddDate(0, 0, 7)
+	case VisaoAgenda:
+		return ref.AddDate(0, 0, -DiasAgenda), ref.AddDate(0, 0, DiasAgenda)
+	}
+	primeiro := time.Date(ref.Year(), ref.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return primeiro.AddDate(0, -1, 0), primeiro.AddDate(0, 1, 0)
+}
+
+// MontarDiasCalendario distribui os eventos pelos dias de inicio a fim.
+// Eventos de vários dias aparecem em cada dia do intervalo. Com mes
+// informado, os dias de outros meses são marcados como fora do período.
+func MontarDiasCalendario(inicio, fim time.Time, mes time.Month, eventos []models.EventoCalendario, hoje time.Time) []DiaCalendario {
+	inicio, fim, hoje = inicioDoDia(inicio), inicioDoDia(fim), inicioDoDia(hoje)
+	var dias []DiaCalendario
+	for d := inicio; !d.After(fim); d = d.AddDate(0, 0, 1) {
+		dias = append(dias, DiaCalendario{
+			Data:          d,
+			ForaDoPeriodo: mes != 0 && d.Month() != mes,
+			Hoje:          d.Equal(hoje),
+		})
+	}
+	for _, e := range eventos {
+		primeiro := inicioDoDia(e.Inicio)
+		ultimo := primeiro
+		if e.Fim != nil && e.Fim.After(e.Inicio) {
+			ultimo = inicioDoDia(*e.Fim)
+		}
+		if primeiro.Before(inicio) {
+			primeiro = inicio
+		}
+		if ultimo.After(fim) {
+			ultimo = fim
+		}
+		for d := primeiro; !d.After(ultimo); d = d.AddDate(0, 0, 1) {
+			i := int(d.Sub(inicio).Hours() / 24)
+			dias[i].Eventos = append(dias[i].Eventos, e)
+		}
+	}
+	for i := range dias {
+		OrdenarEventos(dias[i].Eventos)
+	}
+	return dias
+}
+
+// OrdenarEventos coloca os eventos de dia inteiro antes dos com horário e,
+// entre estes, pela hora de início
+func OrdenarEventos(eventos []models.EventoCalendario) {
+	sort.SliceStable(eventos, func(i, j int) bool {
+		a, b := eventos[i], eventos[j]
+		if a.DiaInteiro != b.DiaInteiro {
+			return a.DiaInteiro
+		}
+		if !a.Inicio.Equal(b.Inicio) {
+			return a.Inicio.Before(b.Inicio)
+		}
+		if a.Tipo != b.Tipo {
+			return a.Tipo < b.Tipo
+		}
+		return a.Titulo < b.Titulo
+	})
+}
+
+// SemanasCalendario quebra os dias da grade mensal em semanas
+func SemanasCalendario(dias []DiaCalendario) [][]DiaCalendario {
+	var semanas [][]DiaCalendario
+	for i := 0; i < len(dias); i += 7 {
+		fim := i + 7
+		if fim > len(dias) {
+			fim = len(dias)
+		}
+		semanas = append(semanas, dias[i:fim])
+	}
+	return semanas
+}
+
+// DiasComEventos filtra os dias que têm ao menos um evento (visão de agenda)
+func DiasComEventos(dias []DiaCalendario) []DiaCalendario {
+	var resultado []DiaCalendario
+	for _, d := range dias {
+		if len(d.Eventos) > 0 {
+			resultado = append(resultado, d)
+		}
+	}
+	return resultado
+}
+
+// FusoICS é o fuso das datas gravadas no banco (horário de parede). Desde
+// 2019 o horário de Brasília não tem horário de verão.
+const FusoICS = "America/Sao_Paulo"
+
+// GerarICS monta o calendário no formato iCalendar (RFC 5545) para
+// assinatura em aplicativos de agenda. Eventos de dia inteiro usam datas
+// (DTEND exclusivo) e os demais o fuso de Brasília.
+func GerarICS(nome string, eventos []models.EventoCalendario, geradoEm time.Time) string {
+	var b strings.Builder
+	linha := func(s string) {
+		b.WriteString(dobrarLinhaICS(s))
+		b.WriteString("\r\n")
+	}
+
+	linha("BEGIN:VCALENDAR")
+	linha("VERSION:2.0")
+	linha("PRODID:-//AgroConsultoria//Calendario//PT-BR")
+	linha("CALSCALE:GREGORIAN")
+	linha("METHOD:PUBLISH")
+	linha("X-WR-CALNAME:" + escaparICS(nome))
+	linha("X-WR-TIMEZONE:" + FusoICS)
+	linha("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	linha("X-PUBLISHED-TTL:PT1H")
+	linha("BEGIN:VTIMEZONE")
+	linha("TZID:" + FusoICS)
+	linha("BEGIN:STANDARD")
+	linha("DTSTART:19700101T000000")
+	linha("TZOFFSETFROM:-0300")
+	linha("TZOFFSETTO:-0300")
+	linha("TZNAME:-03")
+	linha("END:STANDARD")
+	linha("END:VTIMEZONE")
+
+	carimbo := geradoEm.UTC().Format("20060102T150405Z")
+	for _, e := range eventos {
+		linha("BEGIN:VEVENT")
+		linha("UID:" + e.UID + "@agroconsultoria")
+		linha("DTSTAMP:" + carimbo)
+		if e.DiaInteiro {
+			fim := inicioDoDia(e.Inicio)
+			if e.Fim != nil && e.Fim.After(e.Inicio) {
+				fim = inicioDoDia(*e.Fim)
+			}
+			linha("DTSTART;VALUE=DATE:" + e.Inicio.Format("20060102"))
+			linha("DTEND;VALUE=DATE:" + fim.AddDate(0, 0, 1).Format("20060102"))
+			linha("TRANSP:TRANSPARENT")
+		} else {
+			fim := e.Inicio.Add(time.Hour)
+			if e.Fim != nil && e.Fim.After(e.Inicio) {
+				fim = *e.Fim
+			}
+			linha("DTSTART;TZID=" + FusoICS + ":" + e.Inicio.Format("20060102T150405"))
+			linha("DTEND;TZID=" + FusoICS + ":" + fim.Format("20060102T150405"))
+		}
+		linha("SUMMARY:" + escaparICS(e.Titulo))
+		if descricao := descricaoICS(e); descricao != "" {
+			linha("DESCRIPTION:" + escaparICS(descricao))
+		}
+		if e.PropriedadeNome != "" {
+			linha("LOCATION:" + escaparICS(e.PropriedadeNome))
+		}
+		linha("CATEGORIES:" + escaparICS(e.Rotulo()))
+		if e.Concluido {
+			linha("STATUS:CONFIRMED")
+		} else {
+			linha("STATUS:TENTATIVE")
+		}
+		linha("END:VEVENT")
+	}
+	linha("END:VCALENDAR")
+	return b.String()
+}
+
+// descricaoICS junta cliente, consultor e detalhes do evento
+func descricaoICS(e models.EventoCalendario) string {
+	var partes []string
+	if e.ClienteNome != "" {
+		partes = append(partes, "Cliente: "+e.ClienteNome)
+	}
+	if e.ConsultorNome != "" {
+		partes = append(partes, "Consultor: "+e.ConsultorNome)
+	}
+	if e.Descricao != "" {
+		partes = append(partes, e.Descricao)
+	}
+	if e.Concluido {
+		partes = append(partes, "Concluído")
+	}
+	return strings.Join(partes, "\n")
+}
+
+// escaparICS aplica o escape de texto da RFC 5545
+var escaparICS = strings.NewReplacer(
+	`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`,
+).Replace
+
+// dobrarLinhaICS quebra linhas acima de 75 octetos sem partir caracteres
+// UTF-8; as continuações começam com espaço
+func dobrarLinhaICS(s string) string {
+	if len(s) <= 75 {
+		return s
+	}
+	var b strings.Builder
+	limite, tamanho := 75, 0
+	for _, r := range s {
+		n := len(string(r))
+		if tamanho+n > limite {
+			b.WriteString("\r\n ")
+			limite, tamanho = 74, 0
+		}
+		b.WriteRune(r)
+		tamanho += n
+	}
+	return b.String()
+}
+
+// TituloPeriodo descreve o período exibido na visão (ex.: "outubro de 2026")
+func TituloPeriodo(visao string, ref time.Time) string {
+	inicio, fim := PeriodoCalendario(visao, ref)
+	switch visao {
+	case VisaoSemana, VisaoAgenda:
+		return fmt.Sprintf("%s a %s", inicio.Format("02/01"), fim.Format("02/01/2006"))
+	}
+	return fmt.Sprintf("%s de %d", mesesExtenso[ref.Month()-1], ref.Year())
+}
+
+var mesesExtenso = [...]string{
+	"janeiro", "fevereiro", "março", "abril", "maio", "junho",
+	"julho", "agosto", "setembro", "outubro", "novembro", "dezembro",
+}
@@ -0,0 +1,671 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// Layouts de arquivo reconhecidos na importação de dados meteorológicos
+const (
+	LayoutClimaSimples      = "Planilha simples"
+	LayoutClimaPlanilha     = "Planilha diária"
+	LayoutINMETDiario       = "INMET diário"
+	LayoutINMETHorario      = "INMET horário"
+	LayoutEstacaoAutomatica = "Estação automática"
+)
+
+const (
+	// Fração mínima de leituras horárias válidas para aceitar o total do dia
+	fracaoLeiturasDia = 0.8
+	// Conversão do vento medido a 10 m (INMET) para 2 m (FAO-56, eq. 47)
+	fatorVento10m = 0.748
+	// Fuso de Brasília em relação ao UTC dos arquivos horários do INMET
+	fusoBrasilia = -3 * time.Hour
+)
+
+// ImportacaoClima é o resultado da leitura de um arquivo meteorológico:
+// leituras diárias, chuvas e os dias do período sem chuva válida
+type ImportacaoClima struct {
+	Layout     string               `json:"layout"`
+	Estacao    string               `json:"estacao"`
+	Latitude   float64              `json:"latitude"`
+	Altitude   float64              `json:"altitude"`
+	TemLocal   bool                 `json:"tem_local"`
+	Dias       []models.ClimaDiario `json:"dias"`
+	Chuvas     []models.Chuva       `json:"chuvas"`
+	SemLeitura []time.Time          `json:"sem_leitura"`
+	Erros      []string             `json:"erros"`
+}
+
+// Origem descreve a fonte para as observações dos registros importados
+func (i ImportacaoClima) Origem() string {
+	if i.Estacao != "" {
+		return i.Layout + " – " + i.Estacao
+	}
+	return i.Layout
+}
+
+// colunasSimples é a ordem do layout simples (data;tmax;tmin;umidade;vento;radiação;chuva;eto)
+var colunasSimples = []string{"data", "tmax", "tmin", "umidade", "vento", "radiacao", "chuva", "eto"}
+
+// normalizarCabecalho deixa o nome da coluna em minúsculas, sem acentos
+// e sem espaços repetidos
+func normalizarCabecalho(s string) string {
+	s = strings.ToLower(acentos.Replace(strings.TrimSpace(s)))
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func contemAlgum(s string, termos ...string) bool {
+	for _, t := range termos {
+		if strings.Contains(s, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// variavelColuna identifica a variável meteorológica pelo nome da coluna
+// nos layouts do INMET, de estações automáticas (Davis, Campbell, Onset)
+// e de planilhas
+func variavelColuna(nome string) string {
+	n := normalizarCabecalho(nome)
+	switch {
+	case n == "":
+		return ""
+	case strings.HasPrefix(n, "data") && !strings.Contains(n, "fundacao"), strings.HasPrefix(n, "date"), strings.HasPrefix(n, "timestamp"), n == "dia":
+		return "data"
+	case strings.HasPrefix(n, "hora"), strings.HasPrefix(n, "time"), n == "hr":
+		return "hora"
+	case contemAlgum(n, "precipitacao", "chuva", "rain", "pluv") && !contemAlgum(n, "rate", "intens", "taxa"):
+		return "chuva"
+	case n == "eto", n == "et0", n == "et", strings.HasPrefix(n, "et "), strings.HasPrefix(n, "eto "), strings.HasPrefix(n, "et0 "), strings.Contains(n, "evapotransp"):
+		return "eto"
+	case contemAlgum(n, "orvalho", "dew", "inside", "indoor", "interna", "in temp", "temp in", "in hum", "hum in"):
+		return ""
+	case n == "tmax", contemAlgum(n, "temp") && contemAlgum(n, "max", "high", "hi "), strings.HasPrefix(n, "hi temp"):
+		return "tmax"
+	case n == "tmin", contemAlgum(n, "temp") && contemAlgum(n, "min", "low"):
+		return "tmin"
+	case strings.HasPrefix(n, "temp"), strings.Contains(n, "bulbo seco"), n == "tmed":
+		return "temp"
+	case n == "ur", n == "umidade", contemAlgum(n, "umidade", "hum") && !contemAlgum(n, "max", "min", "in hum", "hum in", "solo", "soil", "folha", "leaf"):
+		return "umidade"
+	case n == "vento", contemAlgum(n, "vento", "wind") && contemAlgum(n, "veloc", "speed") && !contemAlgum(n, "rajada", "max", "gust", "high", "hi "):
+		return "vento"
+	case contemAlgum(n, "radiacao", "solar rad") && !contemAlgum(n, "high", "hi ", "max"):
+		return "radiacao"
+	}
+	return ""
+}
+
+// lerValorClima interpreta a leitura aceitando vírgula ou ponto decimal;
+// vazios, "null" e -9999 (falha do sensor no INMET) são ausências
+func lerValorClima(s string) (float64, bool) {
+	v, ok := lerDecimal(s)
+	if !ok || v <= -9999 || math.IsNaN(v) {
+		return 0, false
+	}
+	return v, true
+}
+
+// lerDataClima aceita as datas dos arquivos de estação, com ou sem hora
+// na mesma coluna, devolvendo a data e o texto da hora
+func lerDataClima(s string) (time.Time, string, bool) {
+	s = strings.TrimSpace(s)
+	dia, hora, _ := strings.Cut(s, " ")
+	if d, ok := lerData(dia); ok {
+		return d, hora, true
+	}
+	for _, layout := range []string{"2006/01/02", "02/01/06", "02-01-2006", "2006-1-2", "2/1/2006"} {
+		if t, err := time.Parse(layout, dia); err == nil {
+			return t, hora, true
+		}
+	}
+	return time.Time{}, "", false
+}
+
+// lerHora devolve a hora do dia de textos como "1300 UTC", "13:00" ou "13"
+func lerHora(s string) (time.Duration, bool) {
+	var digitos strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digitos.WriteRune(r)
+		} else if r == ':' || r == 'h' {
+			continue
+		} else if digitos.Len() > 0 {
+			break
+		}
+	}
+	d := digitos.String()
+	switch {
+	case d == "":
+		return 0, false
+	case len(d) <= 2:
+		d += "00"
+	case len(d) == 3:
+		d = "0" + d
+	}
+	h, _ := strconv.Atoi(d[:2])
+	m, _ := strconv.Atoi(d[2:4])
+	if h > 24 || m > 59 {
+		return 0, false
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, true
+}
+
+// acumuladorDia agrega as leituras de um dia (uma linha nos arquivos
+// diários, várias nos horários)
+type acumuladorDia struct {
+	linhas  int
+	soma    map[string]float64
+	validas map[string]int
+	maximo  map[string]float64
+	minimo  map[string]float64
+}
+
+func novoAcumuladorDia() *acumuladorDia {
+	return &acumuladorDia{
+		soma:    make(map[string]float64),
+		validas: make(map[string]int),
+		maximo:  make(map[string]float64),
+		minimo:  make(map[string]float64),
+	}
+}
+
+func (a *acumuladorDia) adicionar(variavel string, v float64) {
+	if a.validas[variavel] == 0 || v > a.maximo[variavel] {
+		a.maximo[variavel] = v
+	}
+	if a.validas[variavel] == 0 || v < a.minimo[variavel] {
+		a.minimo[variavel] = v
+	}
+	a.soma[variavel] += v
+	a.validas[variavel]++
+}
+
+func (a *acumuladorDia) media(variavel string) float64 {
+	return a.soma[variavel] / float64(a.validas[variavel])
+}
+
+// ImportarClimaCSV lê dados meteorológicos e identifica o layout pelo
+// cabeçalho: arquivos diários e horários do INMET (BDMEP e estações
+// automáticas), exportações de estações automáticas com data e hora e
+// planilhas com colunas nomeadas. Sem cabeçalho reconhecido, usa o layout
+// simples data;tmax;tmin;umidade;vento;radiação;chuva;eto. Leituras
+// horárias são agregadas por dia e só entram quando ao menos 80% das
+// horas têm leitura; dias sem chuva válida no período são listados em
+// SemLeitura em vez de gravados como zero.
+func ImportarClimaCSV(r io.Reader) ImportacaoClima {
+	var imp ImportacaoClima
+	conteudo, err := io.ReadAll(r)
+	if err != nil {
+		imp.Erros = []string{err.Error()}
+		return imp
+	}
+	texto := strings.TrimPrefix(string(conteudo), "\ufeff")
+	if !utf8.ValidString(texto) {
+		// Arquivos do INMET vêm em ISO-8859-1
+		runas := make([]rune, len(conteudo))
+		for i, b := range conteudo {
+			runas[i] = rune(b)
+		}
+		texto = string(runas)
+	}
+	leitor := csv.NewReader(strings.NewReader(texto))
+	leitor.Comma = separadorClima(texto)
+	leitor.FieldsPerRecord = -1
+	leitor.LazyQuotes = true
+	leitor.TrimLeadingSpace = true
+
+	// Metadados e cabeçalho
+	var linhas [][]string
+	var posicoes []int
+	colunas := map[string]int{}
+	cabecalho := -1
+	var nomes []string
+	inmet := false
+	for {
+		campos, err := leitor.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			imp.Erros = append(imp.Erros, err.Error())
+			continue
+		}
+		linha, _ := leitor.FieldPos(0)
+		if cabecalho < 0 {
+			if m := metadadoClima(campos); m != nil {
+				switch m[0] {
+				case "estacao", "nome":
+					imp.Estacao = m[1]
+				case "codigo estacao", "codigo (wmo)", "codigo":
+					inmet = true
+					if imp.Estacao == "" {
+						imp.Estacao = m[1]
+					} else {
+						imp.Estacao += " (" + m[1] + ")"
+					}
+				case "latitude":
+					if v, ok := lerValorClima(m[1]); ok {
+						imp.Latitude, imp.TemLocal = v, true
+					}
+				case "altitude":
+					if v, ok := lerValorClima(m[1]); ok {
+						imp.Altitude = v
+					}
+				}
+				continue
+			}
+			if c := mapearColunasClima(campos); c != nil {
+				colunas, cabecalho, nomes = c, len(linhas), campos
+				continue
+			}
+		}
+		linhas = append(linhas, campos)
+		posicoes = append(posicoes, linha)
+	}
+
+	if cabecalho < 0 {
+		imp.Layout = LayoutClimaSimples
+		for i, nome := range colunasSimples {
+			colunas[nome] = i
+		}
+		nomes = colunasSimples
+	}
+
+	unidade := func(variavel string) string {
+		if i, ok := colunas[variavel]; ok && i < len(nomes) {
+			return normalizarCabecalho(nomes[i])
+		}
+		return ""
+	}
+
+	// Agregação por dia
+	dias := make(map[time.Time]*acumuladorDia)
+	horario := false
+	_, temHora := colunas["hora"]
+	utc := strings.Contains(unidade("hora"), "utc")
+	for i, campos := range linhas {
+		iData := colunas["data"]
+		if iData >= len(campos) {
+			continue
+		}
+		data, horaTexto, ok := lerDataClima(campos[iData])
+		if !ok {
+			if strings.TrimSpace(strings.Join(campos, "")) == "" {
+				continue
+			}
+			if cabecalho < 0 && i == 0 {
+				continue // cabeçalho do layout simples
+			}
+			imp.Erros = append(imp.Erros, fmt.Sprintf("linha %d: data inválida %q", posicoes[i], campos[iData]))
+			continue
+		}
+		if temHora && colunas["hora"] < len(campos) {
+			horaTexto = campos[colunas["hora"]]
+			utc = utc || strings.Contains(strings.ToLower(horaTexto), "utc")
+		}
+		if h, ok := lerHora(horaTexto); ok && horaTexto != "" {
+			horario = true
+			momento := data.Add(h)
+			if utc {
+				momento = momento.Add(fusoBrasilia)
+			}
+			data = inicioDoDia(momento)
+		}
+
+		a, existe := dias[data]
+		if !existe {
+			a = novoAcumuladorDia()
+			dias[data] = a
+		}
+		a.linhas++
+		for variavel, indice := range colunas {
+			if variavel == "data" || variavel == "hora" || indice >= len(campos) {
+				continue
+			}
+			if v, ok := lerValorClima(campos[indice]); ok {
+				a.adicionar(variavel, v)
+			}
+		}
+	}
+
+	switch {
+	case imp.Layout != "":
+	case inmet && horario:
+		imp.Layout = LayoutINMETHorario
+	case inmet:
+		imp.Layout = LayoutINMETDiario
+	case horario:
+		imp.Layout = LayoutEstacaoAutomatica
+	default:
+		imp.Layout = LayoutClimaPlanilha
+	}
+
+	// Leituras esperadas por dia: a maior quantidade vista no arquivo
+	esperadas := 1
+	for _, a := range dias {
+		if a.linhas > esperadas {
+			esperadas = a.linhas
+		}
+	}
+	minimo := 1
+	if horario {
+		minimo = int(math.Ceil(float64(esperadas) * fracaoLeiturasDia))
+	}
+
+	datas := make([]time.Time, 0, len(dias))
+	for d := range dias {
+		datas = append(datas, d)
+	}
+	sort.Slice(datas, func(i, j int) bool { return datas[i].Before(datas[j]) })
+
+	fatorVento := 1.0
+	if inmet {
+		fatorVento = fatorVento10m
+	}
+	if strings.Contains(unidade("vento"), "km/h") {
+		fatorVento /= 3.6
+	}
+	radiacao := unidade("radiacao")
+
+	comChuva := make(map[time.Time]bool)
+	for _, data := range datas {
+		a := dias[data]
+		completo := func(variavel string) bool { return a.validas[variavel] >= minimo }
+
+		// As temperaturas só valem em par: a ETo precisa das duas, e uma
+		// coluna vazia é ausência, não 0 °C
+		d := models.ClimaDiario{Data: data}
+		temperatura := true
+		switch {
+		case completo("tmax") && completo("tmin"):
+			d.TempMaxima, d.TempMinima = a.maximo["tmax"], a.minimo["tmin"]
+		case completo("temp"):
+			d.TempMaxima, d.TempMinima = a.maximo["temp"], a.minimo["temp"]
+		default:
+			temperatura = false
+		}
+		if completo("umidade") {
+			d.UmidadeRelativa = math.Min(100, a.media("umidade"))
+		}
+		if completo("vento") {
+			d.VentoMS = a.media("vento") * fatorVento
+		}
+		// A radiação não é registrada à noite em parte das estações
+		if a.validas["radiacao"] >= int(math.Ceil(float64(minimo)/2)) {
+			switch {
+			case strings.Contains(radiacao, "kj"):
+				d.Radiacao = a.soma["radiacao"] / 1000
+			case strings.Contains(radiacao, "w/m"):
+				d.Radiacao = a.media("radiacao") * 0.0864
+			default:
+				d.Radiacao = a.soma["radiacao"]
+			}
+			d.Radiacao = math.Max(0, d.Radiacao)
+		}
+		if completo("eto") {
+			d.ETOInformada = a.soma["eto"]
+		}
+		if temperatura || d.UmidadeRelativa != 0 || d.VentoMS != 0 || d.Radiacao != 0 || d.ETOInformada != 0 {
+			if temperatura && d.TempMaxima < d.TempMinima || d.ETOInformada < 0 {
+				imp.Erros = append(imp.Erros, fmt.Sprintf("%s: valores fora da faixa esperada", data.Format("02/01/2006")))
+			} else {
+				imp.Dias = append(imp.Dias, d)
+			}
+		}
+
+		if completo("chuva") && a.soma["chuva"] >= 0 {
+			imp.Chuvas = append(imp.Chuvas, models.Chuva{Data: data, Milimetros: a.soma["chuva"], Observacoes: "Importado: " + imp.Origem()})
+			comChuva[data] = true
+		}
+	}
+
+	// Dias sem chuva válida entre a primeira e a última data do arquivo,
+	// incluindo os que nem aparecem nele
+	if _, temChuva := colunas["chuva"]; temChuva && len(datas) > 0 {
+		for d := datas[0]; !d.After(datas[len(datas)-1]); d = d.AddDate(0, 0, 1) {
+			if !comChuva[d] {
+				imp.SemLeitura = append(imp.SemLeitura, d)
+			}
+		}
+	}
+	return imp
+}
+
+// separadorClima escolhe ponto e vírgula, tabulação ou vírgula pelas
+// primeiras linhas (os metadados do INMET não têm separador)
+func separadorClima(texto string) rune {
+	inicio := texto
+	if linhas := strings.SplitN(texto, "\n", 21); len(linhas) > 20 {
+		inicio = strings.Join(linhas[:20], "\n")
+	}
+	switch {
+	case strings.Contains(inicio, ";"):
+		return ';'
+	case strings.Contains(inicio, "\t"):
+		return '\t'
+	}
+	return ','
+}
+
+// metadadoClima reconhece linhas "Chave: valor" ou "CHAVE:;valor" do
+// início dos arquivos do INMET, devolvendo a chave normalizada e o valor
+func metadadoClima(campos []string) []string {
+	texto := strings.TrimSpace(strings.Join(campos, " "))
+	chave, valor, ok := strings.Cut(texto, ":")
+	if !ok || len(campos) > 2 {
+		return nil
+	}
+	chave = normalizarCabecalho(chave)
+	if chave == "" || strings.ContainsAny(chave, "0123456789") && !strings.Contains(chave, "wmo") {
+		return nil
+	}
+	return []string{chave, strings.TrimSpace(valor)}
+}
+
+// mapearColunasClima devolve o índice de cada variável quando a linha é
+// um cabeçalho: tem coluna de data e ao menos uma variável reconhecida
+func mapearColunasClima(campos []string) map[string]int {
+	if len(campos) < 2 {
+		return nil
+	}
+	colunas := make(map[string]int)
+	for i, c := range campos {
+		if v := variavelColuna(c); v != "" {
+			if _, existe := colunas[v]; !existe {
+				colunas[v] = i
+			}
+		}
+	}
+	if _, ok := colunas["data"]; !ok || len(colunas) < 2 {
+		return nil
+	}
+	if _, _, ok := lerDataClima(campos[colunas["data"]]); ok {
+		return nil
+	}
+	return colunas
+}
+
+// ChuvaMensal é o total de chuva do mês, o acumulado desde o início do
+// período e a cobertura das leituras
+type ChuvaMensal struct {
+	Mes            time.Time `json:"mes"`
+	Milimetros     float64   `json:"milimetros"`
+	Acumulado      float64   `json:"acumulado"`
+	DiasComChuva   int       `json:"dias_com_chuva"`
+	DiasLidos      int       `json:"dias_lidos"`
+	DiasSemLeitura int       `json:"dias_sem_leitura"`
+}
+
+// Incompleto indica que há dias do mês (até hoje) sem leitura
+func (m ChuvaMensal) Incompleto() bool {
+	return m.DiasSemLeitura > 0
+}
+
+// ChuvaPorMes totaliza as chuvas da propriedade mês a mês entre as datas
+// informadas, contando os dias sem leitura até a data final
+func ChuvaPorMes(chuvas []models.Chuva, inicio, fim time.Time) []ChuvaMensal {
+	inicio, fim = inicioDoDia(inicio), inicioDoDia(fim)
+	porDia := make(map[time.Time]float64)
+	for _, c := range chuvas {
+		d := inicioDoDia(c.Data)
+		if d.Before(inicio) || d.After(fim) {
+			continue
+		}
+		porDia[d] += c.Milimetros
+	}
+
+	var meses []ChuvaMensal
+	acumulado := 0.0
+	mes := time.Date(inicio.Year(), inicio.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for !mes.After(fim) {
+		m := ChuvaMensal{Mes: mes}
+		for d := mes; d.Month() == mes.Month() && !d.After(fim); d = d.AddDate(0, 0, 1) {
+			if d.Before(inicio) {
+				continue
+			}
+			mm, lido := porDia[d]
+			switch {
+			case !lido:
+				m.DiasSemLeitura++
+			case mm > 0:
+				m.DiasComChuva++
+				fallthrough
+			default:
+				m.DiasLidos++
+				m.Milimetros += mm
+			}
+		}
+		acumulado += m.Milimetros
+		m.Acumulado = acumulado
+		meses = append(meses, m)
+		mes = mes.AddDate(0, 1, 0)
+	}
+	return meses
+}
+
+// DiasSemChuvaRegistrada lista os dias do período sem nenhum registro de chuva
+func DiasSemChuvaRegistrada(chuvas []models.Chuva, inicio, fim time.Time) []time.Time {
+	lidos := make(map[time.Time]bool)
+	for _, c := range chuvas {
+		lidos[inicioDoDia(c.Data)] = true
+	}
+	var dias []time.Time
+	for d := inicioDoDia(inicio); !d.After(inicioDoDia(fim)); d = d.AddDate(0, 0, 1) {
+		if !lidos[d] {
+			dias = append(dias, d)
+		}
+	}
+	return dias
+}
+
+// BarraGrafico é uma barra (ou ponto) do gráfico em coordenadas SVG
+type BarraGrafico struct {
+	X          float64 `json:"x"`
+	Y          float64 `json:"y"`
+	Largura    float64 `json:"largura"`
+	Altura     float64 `json:"altura"`
+	Rotulo     string  `json:"rotulo"`
+	Valor      float64 `json:"valor"`
+	Incompleto bool    `json:"incompleto"`
+	ComRotulo  bool    `json:"com_rotulo"` // em períodos longos, só meses alternados
+	XRotulo    float64 `json:"x_rotulo"`
+}
+
+// MarcaEixo é uma linha de referência do eixo vertical
+type MarcaEixo struct {
+	Y     float64 `json:"y"`
+	Valor float64 `json:"valor"`
+}
+
+// GraficoChuva guarda a geometria dos gráficos de chuva mensal (barras)
+// e acumulada (linha), desenhados em SVG pelo template
+type GraficoChuva struct {
+	Largura          float64        `json:"largura"`
+	Altura           float64        `json:"altura"`
+	Margem           float64        `json:"margem"`
+	XEixo            float64        `json:"x_eixo"`    // valores do eixo vertical
+	YRotulos         float64        `json:"y_rotulos"` // rótulos dos meses
+	Barras           []BarraGrafico `json:"barras"`
+	EixoMensal       []MarcaEixo    `json:"eixo_mensal"`
+	Pontos           []BarraGrafico `json:"pontos"`
+	Linha            string         `json:"linha"`
+	EixoAcumulado    []MarcaEixo    `json:"eixo_acumulado"`
+	Total            float64        `json:"total"`
+	MesesIncompletos int            `json:"meses_incompletos"`
+}
+
+// escalaGrafico arredonda o máximo para um passo "redondo" com quatro marcas
+func escalaGrafico(maximo float64) (float64, float64) {
+	if maximo <= 0 {
+		return 100, 25
+	}
+	bruto := maximo / 4
+	potencia := math.Pow(10, math.Floor(math.Log10(bruto)))
+	passo := potencia * 10
+	for _, f := range []float64{1, 2, 2.5, 5, 10} {
+		if bruto <= f*potencia {
+			passo = f * potencia
+			break
+		}
+	}
+	return passo * 4, passo
+}
+
+// MontarGraficoChuva calcula barras, linha e eixos para os meses informados
+func MontarGraficoChuva(meses []ChuvaMensal) GraficoChuva {
+	g := GraficoChuva{Largura: 640, Altura: 200, Margem: 36}
+	g.XEixo, g.YRotulos = g.Margem-4, g.Altura-4
+	if len(meses) == 0 {
+		return g
+	}
+	// Folga de 12 no topo e 20 na base para os rótulos dos meses
+	area := g.Altura - 32
+	base := 12 + area
+	passoX := (g.Largura - g.Margem) / float64(len(meses))
+
+	maxMensal := 0.0
+	for _, m := range meses {
+		maxMensal = math.Max(maxMensal, m.Milimetros)
+	}
+	topoMensal, passoMensal := escalaGrafico(maxMensal)
+	topoAcumulado, passoAcumulado := escalaGrafico(meses[len(meses)-1].Acumulado)
+	for v := 0.0; v <= topoMensal+1e-9; v += passoMensal {
+		g.EixoMensal = append(g.EixoMensal, MarcaEixo{Y: base - v/topoMensal*area, Valor: v})
+	}
+	for v := 0.0; v <= topoAcumulado+1e-9; v += passoAcumulado {
+		g.EixoAcumulado = append(g.EixoAcumulado, MarcaEixo{Y: base - v/topoAcumulado*area, Valor: v})
+	}
+
+	var linha []string
+	for i, m := range meses {
+		x := g.Margem + float64(i)*passoX
+		altura := m.Milimetros / topoMensal * area
+		rotulo := m.Mes.Format("01/06")
+		g.Barras = append(g.Barras, BarraGrafico{
+			X: x + passoX*0.15, Y: base - altura, Largura: passoX * 0.7, Altura: altura,
+			Rotulo: rotulo, Valor: m.Milimetros, Incompleto: m.Incompleto(),
+			ComRotulo: len(meses) <= 12 || i%2 == 0, XRotulo: x + passoX/2,
+		})
+		p := BarraGrafico{X: x + passoX/2, Y: base - m.Acumulado/topoAcumulado*area, Rotulo: rotulo, Valor: m.Acumulado, Incompleto: m.Incompleto()}
+		g.Pontos = append(g.Pontos, p)
+		linha = append(linha, fmt.Sprintf("%.1f,%.1f", p.X, p.Y))
+		if m.Incompleto() {
+			g.MesesIncompletos++
+		}
+	}
+	g.Linha = strings.Join(linha, " ")
+	g.Total = meses[len(meses)-1].Acumulado
+	return g
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportarClimaTemperaturaIncompleta(t *testing.T) {
+	arquivo := strings.Join([]string{
+		"2026-01-01;32;20;70;2;20;5;",
+		"2026-01-02;;18;65;;;0;", // só a mínima: o dia entra sem temperatura
+		"2026-01-03;15;22;;;;;",  // máxima abaixo da mínima
+		"2026-01-04;;17;;;;;",    // só a mínima e nada mais
+	}, "\n")
+	imp := ImportarClimaCSV(strings.NewReader(arquivo))
+
+	if imp.Layout != LayoutClimaSimples {
+		t.Fatalf("layout = %q", imp.Layout)
+	}
+	if len(imp.Erros) != 1 || !strings.HasPrefix(imp.Erros[0], "03/01/2026") {
+		t.Fatalf("erros = %v, esperado só o do dia 03/01", imp.Erros)
+	}
+	if len(imp.Dias) != 2 {
+		t.Fatalf("dias = %d, esperado 2", len(imp.Dias))
+	}
+	if d := imp.Dias[0]; d.TempMaxima != 32 || d.TempMinima != 20 {
+		t.Errorf("01/01: tmax %.1f tmin %.1f", d.TempMaxima, d.TempMinima)
+	}
+	if d := imp.Dias[1]; d.TempMaxima != 0 || d.TempMinima != 0 || d.UmidadeRelativa != 65 {
+		t.Errorf("02/01: tmax %.1f tmin %.1f umidade %.0f, esperado só a umidade", d.TempMaxima, d.TempMinima, d.UmidadeRelativa)
+	}
+	if len(imp.Chuvas) != 2 {
+		t.Errorf("chuvas = %d, esperado 2", len(imp.Chuvas))
+	}
+	semLeitura := []time.Time{time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC)}
+	if len(imp.SemLeitura) != len(semLeitura) {
+		t.Fatalf("sem leitura = %v", imp.SemLeitura)
+	}
+	for i, d := range semLeitura {
+		if !imp.SemLeitura[i].Equal(d) {
+			t.Errorf("sem leitura[%d] = %s, esperado %s", i, imp.SemLeitura[i].Format("02/01"), d.Format("02/01"))
+		}
+	}
+}
+
+// inmetHorarioTeste monta um arquivo horário de estação automática do
+// INMET, em ISO-8859-1, de 01/01/2026 00 UTC a 03/01/2026 02 UTC
+func inmetHorarioTeste() []byte {
+	linhas := []string{
+		"REGIAO:;CO",
+		"UF:;DF",
+		"ESTACAO:;BRASILIA",
+		"CODIGO (WMO):;A001",
+		"LATITUDE:;-15,78944444",
+		"LONGITUDE:;-47,92583332",
+		"ALTITUDE:;1160,96",
+		"DATA DE FUNDACAO:;07/05/00",
+		"Data;Hora UTC;PRECIPITAÇÃO TOTAL, HORÁRIO (mm);RADIACAO GLOBAL (Kj/m²);TEMPERATURA DO AR - BULBO SECO, HORARIA (°C);" +
+			"TEMPERATURA MÁXIMA NA HORA ANT. (AUT) (°C);TEMPERATURA MÍNIMA NA HORA ANT. (AUT) (°C);" +
+			"UMIDADE REL. MAX. NA HORA ANT. (AUT) (%);UMIDADE RELATIVA DO AR, HORARIA (%);VENTO, RAJADA MAXIMA (m/s);VENTO, VELOCIDADE HORARIA (m/s);",
+	}
+	inicio := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for h := 0; h < 51; h++ {
+		utc := inicio.Add(time.Duration(h) * time.Hour)
+		local := utc.Add(-3 * time.Hour)
+		temp := 20 + float64(local.Hour())/2
+		if h == 2 {
+			temp = 40 // 23 h de 31/12 em Brasília
+		}
+		chuva := "0"
+		switch {
+		case h == 25:
+			chuva = "2,4" // 22 h de 01/01 em Brasília
+		case h >= 30 && h < 35:
+			chuva = "-9999" // pluviômetro em falha em 02/01
+		}
+		radiacao := ""
+		if local.Hour() >= 6 && local.Hour() < 18 {
+			radiacao = "1000"
+		}
+		linhas = append(linhas, strings.Join([]string{
+			utc.Format("2006/01/02"), utc.Format("1504") + " UTC", chuva, radiacao,
+			FormatarDecimal(temp, 1), FormatarDecimal(temp+0.5, 1), FormatarDecimal(temp-0.5, 1),
+			"95", "70", "9", "2",
+		}, ";")+";")
+	}
+	var latin1 []byte
+	for _, r := range strings.Join(linhas, "\r\n") {
+		latin1 = append(latin1, byte(r))
+	}
+	return latin1
+}
+
+func TestImportarClimaINMETHorario(t *testing.T) {
+	imp := ImportarClimaCSV(bytes.NewReader(inmetHorarioTeste()))
+	if len(imp.Erros) > 0 {
+		t.Fatalf("erros = %v", imp.Erros)
+	}
+	if imp.Layout != LayoutINMETHorario || imp.Estacao != "BRASILIA (A001)" {
+		t.Errorf("layout %q, estação %q", imp.Layout, imp.Estacao)
+	}
+	if !imp.TemLocal || math.Abs(imp.Latitude+15.78944444) > 1e-9 || math.Abs(imp.Altitude-1160.96) > 1e-9 {
+		t.Errorf("latitude %.8f, altitude %.2f", imp.Latitude, imp.Altitude)
+	}
+
+	// 31/12 só tem 3 horas (00–02 UTC): fica de fora pelos 80% de leituras
+	dia := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	casos := []struct {
+		data   time.Time
+		tmax   float64
+		tmin   float64
+		vento  float64
+		radiac float64
+	}{
+		// 24 horas de 03 UTC a 02 UTC: 20 °C à 0 h até 31,5 °C às 23 h
+		{dia(1), 32, 19.5, 2 * fatorVento10m, 12},
+		{dia(2), 32, 19.5, 2 * fatorVento10m, 12},
+	}
+	if len(imp.Dias) != len(casos) {
+		t.Fatalf("dias = %d, esperado %d", len(imp.Dias), len(casos))
+	}
+	for i, c := range casos {
+		d := imp.Dias[i]
+		if !d.Data.Equal(c.data) || d.TempMaxima != c.tmax || d.TempMinima != c.tmin || d.UmidadeRelativa != 70 ||
+			math.Abs(d.VentoMS-c.vento) > 1e-9 || math.Abs(d.Radiacao-c.radiac) > 1e-9 {
+			t.Errorf("dia %d: %+v", i, d)
+		}
+	}
+
+	// A chuva das 01 UTC de 02/01 é da noite de 01/01; 02/01 tem só 19
+	// horas válidas de chuva
+	if len(imp.Chuvas) != 1 || !imp.Chuvas[0].Data.Equal(dia(1)) || imp.Chuvas[0].Milimetros != 2.4 {
+		t.Errorf("chuvas = %+v", imp.Chuvas)
+	}
+	if len(imp.SemLeitura) != 2 || !imp.SemLeitura[0].Equal(dia(0)) || !imp.SemLeitura[1].Equal(dia(2)) {
+		t.Errorf("sem leitura = %v", imp.SemLeitura)
+	}
+}
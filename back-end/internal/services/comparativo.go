@@ -0,0 +1,303 @@
+package services
+
+import (
+	"math"
+	"sort"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// Quantidade de fatores exibidos como principais causas da variação da margem
+const maxFatoresComparativo = 5
+
+// Sentido indica se o aumento do indicador é desejável
+const (
+	sentidoNeutro = 0
+	sentidoMaior  = 1
+	sentidoMenor  = -1
+)
+
+// ColunaComparativo reúne os dados de uma safra para o comparativo
+type ColunaComparativo struct {
+	Resultado ResultadoSafra     `json:"resultado"`
+	Insumos   []TotalProduto     `json:"insumos"`
+	Solo      models.AnaliseSolo `json:"solo"`
+	TemSolo   bool               `json:"tem_solo"`
+}
+
+// ValorComparativo é o valor de um indicador em uma safra
+type ValorComparativo struct {
+	Valor    float64 `json:"valor"`
+	Presente bool    `json:"presente"`
+}
+
+// LinhaComparativo é um indicador com os valores de cada safra e a
+// variação entre a primeira e a última
+type LinhaComparativo struct {
+	Secao           string             `json:"secao"`
+	Indicador       string             `json:"indicador"`
+	Unidade         string             `json:"unidade"`
+	Casas           int                `json:"casas"`
+	Valores         []ValorComparativo `json:"valores"`
+	TemDelta        bool               `json:"tem_delta"`
+	Delta           float64            `json:"delta"`
+	DeltaPercentual float64            `json:"delta_percentual"`
+	Sentido         int                `json:"sentido"`
+}
+
+// Melhorou indica se a variação foi favorável (falso para indicadores neutros)
+func (l LinhaComparativo) Melhorou() bool {
+	return l.TemDelta && l.Delta*float64(l.Sentido) > 0
+}
+
+// Piorou indica se a variação foi desfavorável
+func (l LinhaComparativo) Piorou() bool {
+	return l.TemDelta && l.Delta*float64(l.Sentido) < 0
+}
+
+// FatorComparativo é a contribuição de um item para a variação da margem
+// bruta por hectare entre a primeira e a última safra
+type FatorComparativo struct {
+	Descricao string  `json:"descricao"`
+	Impacto   float64 `json:"impacto"` // R$/ha
+}
+
+// Comparativo é o relatório de safras lado a lado
+type Comparativo struct {
+	Colunas        []ColunaComparativo `json:"colunas"`
+	Linhas         []LinhaComparativo  `json:"linhas"`
+	Fatores        []FatorComparativo  `json:"fatores"`
+	VariacaoMargem float64             `json:"variacao_margem"`
+	TemMargem      bool                `json:"tem_margem"`
+}
+
+// AnaliseInicial devolve a análise de solo mais recente do talhão feita até
+// o plantio, que representa a condição de início da safra
+func AnaliseInicial(analises []models.AnaliseSolo, safra models.Safra) (models.AnaliseSolo, bool) {
+	inicio := safra.PlantioPrevisto
+	if safra.PlantioReal != nil {
+		inicio = *safra.PlantioReal
+	}
+	var escolhida models.AnaliseSolo
+	achou := false
+	for _, a := range analises {
+		if a.TalhaoID != safra.TalhaoID || a.DataAmostra.After(inicio) {
+			continue
+		}
+		if !achou || a.DataAmostra.After(escolhida.DataAmostra) {
+			escolhida, achou = a, true
+		}
+	}
+	return escolhida, achou
+}
+
+// ProdutividadePreco é a produtividade na unidade da cotação por hectare
+func (r ResultadoSafra) ProdutividadePreco() float64 {
+	kg := KgUnidadePreco[r.Preco.Unidade]
+	if kg == 0 || r.Safra.AreaHectares == 0 {
+		return 0
+	}
+	return r.ProducaoKg() / kg / r.Safra.AreaHectares
+}
+
+// ReceitaHectare é a receita bruta por hectare plantado
+func (r ResultadoSafra) ReceitaHectare() float64 {
+	if r.Safra.AreaHectares == 0 {
+		return 0
+	}
+	return r.ReceitaBruta() / r.Safra.AreaHectares
+}
+
+// CompararSafras monta os indicadores de produção, economia, custos por
+// grupo, insumos e solo das safras, da mais antiga para a mais recente, e
+// decompõe a variação da margem por hectare entre a primeira e a última
+// em efeito produtividade, efeito preço e variação de cada grupo de custo
+func CompararSafras(colunas []ColunaComparativo) Comparativo {
+	sort.SliceStable(colunas, func(i, j int) bool {
+		return colunas[i].Resultado.Safra.PlantioPrevisto.Before(colunas[j].Resultado.Safra.PlantioPrevisto)
+	})
+	c := Comparativo{Colunas: colunas}
+	if len(colunas) == 0 {
+		return c
+	}
+
+	adicionar := func(secao, indicador, unidade string, casas, sentido int, valor func(ColunaComparativo) (float64, bool)) {
+		l := LinhaComparativo{Secao: secao, Indicador: indicador, Unidade: unidade, Casas: casas, Sentido: sentido}
+		for _, col := range colunas {
+			v, ok := valor(col)
+			l.Valores = append(l.Valores, ValorComparativo{Valor: v, Presente: ok})
+		}
+		primeiro, ultimo := l.Valores[0], l.Valores[len(l.Valores)-1]
+		if len(l.Valores) > 1 && primeiro.Presente && ultimo.Presente {
+			l.TemDelta = true
+			l.Delta = ultimo.Valor - primeiro.Valor
+			if primeiro.Valor != 0 {
+				l.DeltaPercentual = l.Delta / math.Abs(primeiro.Valor) * 100
+			}
+		}
+		c.Linhas = append(c.Linhas, l)
+	}
+
+	// Produção: só é comparável quando todas as safras usam a mesma unidade
+	unidade := colunas[0].Resultado.Safra.Unidade
+	mesmaUnidade := true
+	for _, col := range colunas {
+		if col.Resultado.Safra.Unidade != unidade {
+			mesmaUnidade = false
+		}
+	}
+	if mesmaUnidade {
+		adicionar("Produção", "Produtividade", unidade, 1, sentidoMaior, func(col ColunaComparativo) (float64, bool) {
+			r := col.Resultado
+			return r.Produtividade.Produtividade, !r.Projetado
+		})
+		adicionar("Produção", "Meta", unidade, 1, sentidoNeutro, func(col ColunaComparativo) (float64, bool) {
+			return col.Resultado.Safra.ProdutividadeAlvo, col.Resultado.Safra.ProdutividadeAlvo > 0
+		})
+	}
+	adicionar("Produção", "Atingimento da meta", "%", 0, sentidoMaior, func(col ColunaComparativo) (float64, bool) {
+		r := col.Resultado
+		return r.Produtividade.PercentualAlvo(), !r.Projetado && r.Safra.ProdutividadeAlvo > 0
+	})
+
+	temCusto := func(col ColunaComparativo) bool { return col.Resultado.CustoTotal > 0 }
+	temMargem := func(col ColunaComparativo) bool { return temCusto(col) && col.Resultado.TemPreco }
+	adicionar("Economia", "Custo por hectare", "R$/ha", 2, sentidoMenor, func(col ColunaComparativo) (float64, bool) {
+		return col.Resultado.CustoHectare(), temCusto(col)
+	})
+	adicionar("Economia", "Custo por saca", "R$/sc", 2, sentidoMenor, func(col ColunaComparativo) (float64, bool) {
+		return col.Resultado.CustoSaca(), temCusto(col) && col.Resultado.ProducaoKg() > 0
+	})
+	adicionar("Economia", "Receita por hectare", "R$/ha", 2, sentidoMaior, func(col ColunaComparativo) (float64, bool) {
+		return col.Resultado.ReceitaHectare(), col.Resultado.TemPreco
+	})
+	adicionar("Economia", "Margem bruta por hectare", "R$/ha", 2, sentidoMaior, func(col ColunaComparativo) (float64, bool) {
+		return col.Resultado.MargemHectare(), temMargem(col)
+	})
+	adicionar("Economia", "ROI", "%", 1, sentidoMaior, func(col ColunaComparativo) (float64, bool) {
+		return col.Resultado.ROI(), temMargem(col)
+	})
+
+	// Custos por grupo, na ordem padrão
+	for _, grupo := range gruposPresentes(colunas) {
+		grupo := grupo
+		adicionar("Custos por hectare", grupo, "R$/ha", 2, sentidoMenor, func(col ColunaComparativo) (float64, bool) {
+			if !temCusto(col) {
+				return 0, false
+			}
+			for _, g := range col.Resultado.Grupos {
+				if g.Grupo == grupo {
+					return g.PorHectare, true
+				}
+			}
+			return 0, true
+		})
+	}
+
+	// Insumos aplicados por hectare
+	type chaveInsumo struct{ produto, unidade string }
+	var insumos []chaveInsumo
+	vistos := make(map[chaveInsumo]bool)
+	for _, col := range colunas {
+		for _, t := range col.Insumos {
+			k := chaveInsumo{t.Produto, t.Unidade}
+			if !vistos[k] {
+				vistos[k] = true
+				insumos = append(insumos, k)
+			}
+		}
+	}
+	sort.Slice(insumos, func(i, j int) bool { return insumos[i].produto < insumos[j].produto })
+	for _, k := range insumos {
+		k := k
+		adicionar("Insumos aplicados", k.produto, k.unidade+"/ha", 2, sentidoNeutro, func(col ColunaComparativo) (float64, bool) {
+			for _, t := range col.Insumos {
+				if t.Produto == k.produto && t.Unidade == k.unidade {
+					return t.PorHectare, true
+				}
+			}
+			return 0, true
+		})
+	}
+
+	// Solo no início de cada safra
+	solo := func(indicador, unidade string, casas, sentido int, valor func(models.AnaliseSolo) float64) {
+		adicionar("Solo no início da safra", indicador, unidade, casas, sentido, func(col ColunaComparativo) (float64, bool) {
+			return valor(col.Solo), col.TemSolo
+		})
+	}
+	solo("pH (CaCl₂)", "", 1, sentidoNeutro, func(a models.AnaliseSolo) float64 { return a.PH })
+	solo("Matéria orgânica", "g/dm³", 1, sentidoMaior, func(a models.AnaliseSolo) float64 { return a.MateriaOrganica })
+	solo("Fósforo", "mg/dm³", 1, sentidoMaior, func(a models.AnaliseSolo) float64 { return a.Fosforo })
+	solo("Potássio", "cmolc/dm³", 2, sentidoMaior, func(a models.AnaliseSolo) float64 { return a.Potassio })
+	solo("CTC", "cmolc/dm³", 1, sentidoNeutro, func(a models.AnaliseSolo) float64 { return a.CTC() })
+	solo("Saturação por bases", "V%", 0, sentidoMaior, func(a models.AnaliseSolo) float64 { return a.SaturacaoBases() })
+	solo("Saturação por alumínio", "m%", 0, sentidoMenor, func(a models.AnaliseSolo) float64 { return a.SaturacaoAluminio() })
+
+	c.Fatores, c.VariacaoMargem, c.TemMargem = fatoresMargem(colunas[0], colunas[len(colunas)-1])
+	return c
+}
+
+// gruposPresentes lista os grupos de custo lançados em alguma das safras
+func gruposPresentes(colunas []ColunaComparativo) []string {
+	presentes := make(map[string]bool)
+	for _, col := range colunas {
+		for _, g := range col.Resultado.Grupos {
+			presentes[g.Grupo] = true
+		}
+	}
+	var grupos []string
+	for _, g := range models.GruposCusto {
+		if presentes[g] {
+			grupos = append(grupos, g)
+			delete(presentes, g)
+		}
+	}
+	var extras []string
+	for g := range presentes {
+		extras = append(extras, g)
+	}
+	sort.Strings(extras)
+	return append(grupos, extras...)
+}
+
+// fatoresMargem decompõe a variação da margem por hectare. Com a mesma
+// unidade de cotação, a variação da receita é separada em efeito
+// produtividade ((Y₂ − Y₁) × P₁) e efeito preço ((P₂ − P₁) × Y₂).
+func fatoresMargem(antes, depois ColunaComparativo) ([]FatorComparativo, float64, bool) {
+	a, d := antes.Resultado, depois.Resultado
+	if a.Safra.ID == d.Safra.ID || a.CustoTotal == 0 || d.CustoTotal == 0 || !a.TemPreco || !d.TemPreco {
+		return nil, 0, false
+	}
+
+	var fatores []FatorComparativo
+	if a.Preco.Unidade == d.Preco.Unidade {
+		fatores = append(fatores,
+			FatorComparativo{"Produtividade", (d.ProdutividadePreco() - a.ProdutividadePreco()) * a.Preco.Preco},
+			FatorComparativo{"Preço de venda", (d.Preco.Preco - a.Preco.Preco) * d.ProdutividadePreco()},
+		)
+	} else {
+		fatores = append(fatores, FatorComparativo{"Receita", d.ReceitaHectare() - a.ReceitaHectare()})
+	}
+
+	porHectare := func(r ResultadoSafra, grupo string) float64 {
+		for _, g := range r.Grupos {
+			if g.Grupo == grupo {
+				return g.PorHectare
+			}
+		}
+		return 0
+	}
+	for _, grupo := range gruposPresentes([]ColunaComparativo{antes, depois}) {
+		fatores = append(fatores, FatorComparativo{"Custo com " + grupo, -(porHectare(d, grupo) - porHectare(a, grupo))})
+	}
+
+	sort.SliceStable(fatores, func(i, j int) bool { return math.Abs(fatores[i].Impacto) > math.Abs(fatores[j].Impacto) })
+	var principais []FatorComparativo
+	for _, f := range fatores {
+		if math.Abs(f.Impacto) >= 0.5 && len(principais) < maxFatoresComparativo {
+			principais = append(principais, f)
+		}
+	}
+	return principais, d.MargemHectare() - a.MargemHectare(), true
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// colunaTeste monta uma safra de 100 ha com a produtividade em sc/ha, a
+// cotação por saca e os custos por hectare de cada grupo
+func colunaTeste(id, ano int, scHa, preco float64, unidadePreco string, custos map[string]float64) ColunaComparativo {
+	safra := models.Safra{ID: id, Cultura: "Soja", Unidade: models.UnidadeSacasHa, AreaHectares: 100,
+		PlantioPrevisto: time.Date(ano, 10, 1, 0, 0, 0, 0, time.UTC)}
+	r := ResultadoSafra{
+		Safra:         safra,
+		Produtividade: ProdutividadeSafra{Safra: safra, PesoCorrigidoKg: scHa * models.KgSaca * 100},
+		Preco:         models.PrecoVenda{Preco: preco, Unidade: unidadePreco},
+		TemPreco:      preco > 0,
+	}
+	for _, g := range models.GruposCusto {
+		if v, ok := custos[g]; ok {
+			r.Grupos = append(r.Grupos, CustoGrupo{Grupo: g, Valor: v * 100, PorHectare: v})
+			r.CustoTotal += v * 100
+		}
+	}
+	return ColunaComparativo{Resultado: r}
+}
+
+func TestCompararSafrasFatoresMargem(t *testing.T) {
+	// 60 sc/ha a R$ 120 com R$ 4.000/ha de custo: margem de R$ 3.200/ha
+	antes := colunaTeste(1, 2024, 60, 120, models.UnidadePrecoSaca, map[string]float64{"Insumos": 3000, "Operações": 1000})
+	// 70 sc/ha a R$ 110 com R$ 4.900/ha de custo: margem de R$ 2.800/ha
+	depois := colunaTeste(2, 2025, 70, 110, models.UnidadePrecoSaca, map[string]float64{"Insumos": 3500, "Operações": 900, "Arrendamento": 500})
+
+	casos := []struct {
+		nome     string
+		colunas  []ColunaComparativo
+		variacao float64
+		fatores  map[string]float64
+	}{
+		// Produtividade (70 − 60) × 120 = +1.200; preço (110 − 120) × 70 = −700
+		{"mesma unidade de cotação", []ColunaComparativo{depois, antes}, -400, map[string]float64{
+			"Produtividade":          1200,
+			"Preço de venda":         -700,
+			"Custo com Insumos":      -500,
+			"Custo com Arrendamento": -500,
+			"Custo com Operações":    100,
+		}},
+		// Cotação em toneladas: 70 sc/ha = 4,2 t/ha × R$ 1.800 = R$ 7.560/ha
+		{"unidades diferentes", []ColunaComparativo{antes,
+			colunaTeste(2, 2025, 70, 1800, models.UnidadePrecoTonelada, map[string]float64{"Insumos": 3000, "Operações": 1000})},
+			360, map[string]float64{"Receita": 360}},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			comp := CompararSafras(c.colunas)
+			if !comp.TemMargem {
+				t.Fatal("margem não decomposta")
+			}
+			if comp.Colunas[0].Resultado.Safra.ID != 1 {
+				t.Error("safras fora da ordem de plantio")
+			}
+			if math.Abs(comp.VariacaoMargem-c.variacao) > 1e-6 {
+				t.Errorf("variação da margem = %.2f, esperado %.2f", comp.VariacaoMargem, c.variacao)
+			}
+			if len(comp.Fatores) != len(c.fatores) {
+				t.Fatalf("fatores = %+v", comp.Fatores)
+			}
+			soma := 0.0
+			for i, f := range comp.Fatores {
+				soma += f.Impacto
+				if esperado, ok := c.fatores[f.Descricao]; !ok || math.Abs(f.Impacto-esperado) > 1e-6 {
+					t.Errorf("%s = %.2f, esperado %.2f", f.Descricao, f.Impacto, esperado)
+				}
+				if i > 0 && math.Abs(f.Impacto) > math.Abs(comp.Fatores[i-1].Impacto) {
+					t.Errorf("fator %s fora da ordem de impacto", f.Descricao)
+				}
+			}
+			// A decomposição é exata: os efeitos somam a variação da margem
+			if math.Abs(soma-c.variacao) > 1e-6 {
+				t.Errorf("soma dos fatores = %.2f, esperado %.2f", soma, c.variacao)
+			}
+		})
+	}
+
+	semPreco := colunaTeste(2, 2025, 70, 0, models.UnidadePrecoSaca, map[string]float64{"Insumos": 3000})
+	if comp := CompararSafras([]ColunaComparativo{antes, semPreco}); comp.TemMargem || comp.Fatores != nil {
+		t.Errorf("safra sem cotação decomposta: %+v", comp.Fatores)
+	}
+}
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// KgUnidadePreco converte a unidade de comercialização das cotações em quilos
+var KgUnidadePreco = map[string]float64{
+	models.UnidadePrecoSaca:     models.KgSaca,
+	models.UnidadePrecoTonelada: 1000,
+	models.UnidadePrecoArroba:   KgArroba,
+}
+
+// CategoriasCustoPadrao são as categorias sugeridas para o livro de custos
+var CategoriasCustoPadrao = []models.CategoriaCusto{
+	{Nome: "Sementes", Grupo: "Insumos"},
+	{Nome: "Fertilizantes e corretivos", Grupo: "Insumos"},
+	{Nome: "Defensivos", Grupo: "Insumos"},
+	{Nome: "Operações mecanizadas", Grupo: "Operações"},
+	{Nome: "Combustível", Grupo: "Operações"},
+	{Nome: "Mão de obra", Grupo: "Mão de obra"},
+	{Nome: "Arrendamento", Grupo: "Arrendamento"},
+	{Nome: "Assistência técnica", Grupo: "Serviços"},
+	{Nome: "Frete e armazenagem", Grupo: "Serviços"},
+	{Nome: "Seguro agrícola", Grupo: "Serviços"},
+	{Nome: "Outras despesas", Grupo: "Outros"},
+}
+
+// CustoGrupo é o total de um grupo de categorias na safra
+type CustoGrupo struct {
+	Grupo      string  `json:"grupo"`
+	Valor      float64 `json:"valor"`
+	PorHectare float64 `json:"por_hectare"`
+	Percentual float64 `json:"percentual"`
+}
+
+// ResultadoSafra é o resultado econômico de uma safra: custos, receita pela
+// cotação de referência, margem bruta e ROI. Sem colheita registrada, a
+// produção é projetada pela meta de produtividade.
+type ResultadoSafra struct {
+	Safra         models.Safra       `json:"safra"`
+	Produtividade ProdutividadeSafra `json:"produtividade"`
+	Projetado     bool               `json:"projetado"`
+	Grupos        []CustoGrupo       `json:"grupos"`
+	CustoTotal    float64            `json:"custo_total"`
+	Preco         models.PrecoVenda  `json:"preco"`
+	TemPreco      bool               `json:"tem_preco"`
+}
+
+// ProducaoKg é a produção corrigida (ou projetada) em quilos
+func (r ResultadoSafra) ProducaoKg() float64 {
+	if !r.Projetado {
+		return r.Produtividade.PesoCorrigidoKg
+	}
+	kg := models.KgSaca
+	if r.Safra.Unidade == models.UnidadeToneladaHa {
+		kg = 1000
+	}
+	return r.Safra.ProducaoPrevista() * kg
+}
+
+// CustoHectare é o custo total dividido pela área plantada
+func (r ResultadoSafra) CustoHectare() float64 {
+	if r.Safra.AreaHectares == 0 {
+		return 0
+	}
+	return r.CustoTotal / r.Safra.AreaHectares
+}
+
+// custoPorKg é a base dos custos por saca e por arroba
+func (r ResultadoSafra) custoPorKg() float64 {
+	if r.ProducaoKg() == 0 {
+		return 0
+	}
+	return r.CustoTotal / r.ProducaoKg()
+}
+
+// CustoSaca é o custo de produção de uma saca de 60 kg
+func (r ResultadoSafra) CustoSaca() float64 {
+	return r.custoPorKg() * models.KgSaca
+}
+
+// CustoArroba é o custo de produção de uma arroba de 15 kg
+func (r ResultadoSafra) CustoArroba() float64 {
+	return r.custoPorKg() * KgArroba
+}
+
+// CustoUnidadePreco é o custo por unidade da cotação, comparável ao preço
+func (r ResultadoSafra) CustoUnidadePreco() float64 {
+	return r.custoPorKg() * KgUnidadePreco[r.Preco.Unidade]
+}
+
+// ReceitaBruta é a produção valorizada pela cotação de referência
+func (r ResultadoSafra) ReceitaBruta() float64 {
+	if !r.TemPreco {
+		return 0
+	}
+	return r.ProducaoKg() / KgUnidadePreco[r.Preco.Unidade] * r.Preco.Preco
+}
+
+// MargemBruta é a receita bruta menos os custos lançados
+func (r ResultadoSafra) MargemBruta() float64 {
+	return r.ReceitaBruta() - r.CustoTotal
+}
+
+// MargemHectare é a margem bruta por hectare plantado
+func (r ResultadoSafra) MargemHectare() float64 {
+	if r.Safra.AreaHectares == 0 {
+		return 0
+	}
+	return r.MargemBruta() / r.Safra.AreaHectares
+}
+
+// ROI é a margem bruta em relação ao custo (%)
+func (r ResultadoSafra) ROI() float64 {
+	if r.CustoTotal == 0 {
+		return 0
+	}
+	return r.MargemBruta() / r.CustoTotal * 100
+}
+
+// PontoEquilibrio é a produtividade, na unidade da cotação por hectare,
+// que paga o custo por hectare
+func (r ResultadoSafra) PontoEquilibrio() float64 {
+	if !r.TemPreco || r.Preco.Preco == 0 {
+		return 0
+	}
+	return r.CustoHectare() / r.Preco.Preco
+}
+
+// PrecoReferencia escolhe a cotação mais recente da cultura até a data
+// informada (colheita). Sem cotação anterior, usa a mais antiga disponível.
+func PrecoReferencia(cultura string, data time.Time, precos []models.PrecoVenda) (models.PrecoVenda, bool) {
+	var anterior, posterior *models.PrecoVenda
+	for i := range precos {
+		p := &precos[i]
+		if !strings.EqualFold(p.Cultura, cultura) {
+			continue
+		}
+		if !p.Data.After(data) {
+			if anterior == nil || p.Data.After(anterior.Data) {
+				anterior = p
+			}
+		} else if posterior == nil || p.Data.Before(posterior.Data) {
+			posterior = p
+		}
+	}
+	switch {
+	case anterior != nil:
+		return *anterior, true
+	case posterior != nil:
+		return *posterior, true
+	}
+	return models.PrecoVenda{}, false
+}
+
+// CalcularResultado consolida os lançamentos da safra por grupo e valoriza
+// a produção pela cotação de referência na data da colheita
+func CalcularResultado(safra models.Safra, lancamentos []models.LancamentoCusto, colheitas []models.Colheita, precos []models.PrecoVenda) ResultadoSafra {
+	r := ResultadoSafra{Safra: safra, Produtividade: CalcularProdutividade(safra, colheitas)}
+	r.Projetado = r.Produtividade.Cargas == 0
+
+	totais := make(map[string]float64)
+	for _, l := range lancamentos {
+		if l.SafraID != safra.ID {
+			continue
+		}
+		totais[l.Grupo] += l.Valor
+		r.CustoTotal += l.Valor
+	}
+	for _, g := range models.GruposCusto {
+		if v, ok := totais[g]; ok {
+			r.Grupos = append(r.Grupos, CustoGrupo{Grupo: g, Valor: v})
+			delete(totais, g)
+		}
+	}
+	// Grupos fora da lista padrão (categorias antigas) vão ao final
+	var extras []string
+	for g := range totais {
+		extras = append(extras, g)
+	}
+	sort.Strings(extras)
+	for _, g := range extras {
+		r.Grupos = append(r.Grupos, CustoGrupo{Grupo: g, Valor: totais[g]})
+	}
+	for i := range r.Grupos {
+		if safra.AreaHectares > 0 {
+			r.Grupos[i].PorHectare = r.Grupos[i].Valor / safra.AreaHectares
+		}
+		if r.CustoTotal > 0 {
+			r.Grupos[i].Percentual = r.Grupos[i].Valor / r.CustoTotal * 100
+		}
+	}
+
+	data := safra.ColheitaPrevista
+	if safra.ColheitaReal != nil {
+		data = *safra.ColheitaReal
+	}
+	r.Preco, r.TemPreco = PrecoReferencia(safra.Cultura, data, precos)
+	return r
+}
+
+// ImportarPrecosCSV lê cotações no formato data;cultura;preço[;unidade[;praça]],
+// com datas em DD/MM/AAAA ou AAAA-MM-DD e preços com vírgula decimal. O
+// separador é ponto e vírgula sempre que aparece na primeira linha; com
+// vírgula, um preço decimal sem aspas quebraria a linha em um campo a mais,
+// por isso toda linha precisa ter tantos campos quanto a primeira. A linha
+// de cabeçalho é ignorada. Linhas inválidas são relatadas sem interromper a
+// importação.
+func ImportarPrecosCSV(r io.Reader) ([]models.PrecoVenda, []string) {
+	leitor, err := novoLeitorCSV(r)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+
+	var precos []models.PrecoVenda
+	var erros []string
+	colunas := 0
+	for primeiro := true; ; primeiro = false {
+		campos, err := leitor.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			erros = append(erros, err.Error())
+			continue
+		}
+		linha, _ := leitor.FieldPos(0)
+		if leitor.Comma == ',' {
+			if primeiro {
+				colunas = len(campos)
+			} else if len(campos) != colunas {
+				erros = append(erros, fmt.Sprintf("linha %d: %d campos, a primeira linha tem %d (preço com vírgula decimal precisa de aspas ou separador ponto e vírgula)",
+					linha, len(campos), colunas))
+				continue
+			}
+		}
+		if len(campos) < 3 {
+			erros = append(erros, fmt.Sprintf("linha %d: esperado data, cultura e preço", linha))
+			continue
+		}
+		data, ok := lerData(campos[0])
+		if !ok {
+			if primeiro {
+				continue // cabeçalho
+			}
+			erros = append(erros, fmt.Sprintf("linha %d: data inválida %q", linha, campos[0]))
+			continue
+		}
+		p := models.PrecoVenda{
+			Data:    data,
+			Cultura: strings.TrimSpace(campos[1]),
+			Preco:   LerDecimal(strings.TrimPrefix(strings.TrimSpace(campos[2]), "R$")),
+			Unidade: models.UnidadePrecoSaca,
+		}
+		if len(campos) > 3 && strings.TrimSpace(campos[3]) != "" {
+			p.Unidade = strings.TrimSpace(campos[3])
+		}
+		if len(campos) > 4 {
+			p.Praca = strings.TrimSpace(campos[4])
+		}
+		if p.Cultura == "" || p.Preco <= 0 {
+			erros = append(erros, fmt.Sprintf("linha %d: cultura ou preço inválido", linha))
+			continue
+		}
+		if _, ok := KgUnidadePreco[p.Unidade]; !ok {
+			erros = append(erros, fmt.Sprintf("linha %d: unidade %q não reconhecida (use sc, t ou @)", linha, p.Unidade))
+			continue
+		}
+		precos = append(precos, p)
+	}
+	return precos, erros
+}
+
+// novoLeitorCSV prepara a leitura de CSV exportado por planilhas: remove o
+// BOM e detecta o separador (ponto e vírgula ou vírgula) pela primeira linha
+func novoLeitorCSV(r io.Reader) (*csv.Reader, error) {
+	conteudo, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	texto := strings.TrimPrefix(string(conteudo), "\ufeff")
+	primeira, _, _ := strings.Cut(texto, "\n")
+
+	leitor := csv.NewReader(strings.NewReader(texto))
+	leitor.Comma = ';'
+	if !strings.Contains(primeira, ";") {
+		leitor.Comma = ','
+	}
+	leitor.FieldsPerRecord = -1
+	leitor.TrimLeadingSpace = true
+	return leitor, nil
+}
+
+func lerData(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"02/01/2006", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
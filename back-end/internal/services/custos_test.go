@@ -0,0 +1,68 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportarPrecosCSV(t *testing.T) {
+	casos := []struct {
+		nome    string
+		arquivo string
+		precos  []float64
+		erros   []string
+	}{
+		{
+			nome:    "ponto e vírgula com cabeçalho",
+			arquivo: "\ufeffdata;cultura;preço;unidade\n15/03/2026;Soja;R$ 120,50;sc\n2026-03-16;Milho;62,3\n",
+			precos:  []float64{120.5, 62.3},
+		},
+		{
+			nome:    "vírgula com preços entre aspas",
+			arquivo: "data,cultura,preço\n15/03/2026,Soja,\"120,50\"\n16/03/2026,Milho,62\n",
+			precos:  []float64{120.5, 62},
+		},
+		{
+			nome:    "vírgula com vírgula decimal sem aspas",
+			arquivo: "data,cultura,preço\n15/03/2026,Soja,120,50\n16/03/2026,Milho,62\n",
+			precos:  []float64{62},
+			erros:   []string{"linha 2: 4 campos, a primeira linha tem 3"},
+		},
+		{
+			nome:    "ponto e vírgula no cabeçalho decide o separador",
+			arquivo: "data;cultura;preço;unidade;praça\n15/03/2026;Soja;120,50;sc;Rio Verde, GO\n",
+			precos:  []float64{120.5},
+		},
+		{
+			nome:    "linhas inválidas",
+			arquivo: "data;cultura;preço;unidade\n31/02/2026;Soja;120;sc\n15/03/2026;Soja;0;sc\n15/03/2026;Soja;120;kg\n15/03/2026;Soja\n",
+			erros: []string{
+				"linha 2: data inválida",
+				"linha 3: cultura ou preço inválido",
+				"linha 4: unidade \"kg\" não reconhecida",
+				"linha 5: esperado data, cultura e preço",
+			},
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			precos, erros := ImportarPrecosCSV(strings.NewReader(c.arquivo))
+			if len(precos) != len(c.precos) {
+				t.Fatalf("%d preços, esperado %d (erros: %v)", len(precos), len(c.precos), erros)
+			}
+			for i, p := range precos {
+				if p.Preco != c.precos[i] {
+					t.Errorf("preço %d = %v, esperado %v", i, p.Preco, c.precos[i])
+				}
+			}
+			if len(erros) != len(c.erros) {
+				t.Fatalf("erros = %q, esperado %q", erros, c.erros)
+			}
+			for i, e := range erros {
+				if !strings.HasPrefix(e, c.erros[i]) {
+					t.Errorf("erro %d = %q, esperado começar com %q", i, e, c.erros[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"math"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// KgArroba é o peso de carcaça de uma arroba
+const KgArroba = 15.0
+
+// IngredientesReferencia é a composição média usada para iniciar a
+// biblioteca de alimentos (valores de tabelas brasileiras, % da MS).
+// Os preços ficam zerados para o consultor informar a cotação local.
+var IngredientesReferencia = []models.Ingrediente{
+	{Nome: "Milho grão moído", MateriaSeca: 88, ProteinaBruta: 9, NDT: 85, FDN: 12, Calcio: 0.03, Fosforo: 0.27},
+	{Nome: "Farelo de soja", MateriaSeca: 89, ProteinaBruta: 49, NDT: 81, FDN: 14, Calcio: 0.35, Fosforo: 0.64},
+	{Nome: "Farelo de algodão 38%", MateriaSeca: 90, ProteinaBruta: 40, NDT: 68, FDN: 32, Calcio: 0.22, Fosforo: 1.05},
+	{Nome: "Casca de soja", MateriaSeca: 90, ProteinaBruta: 12, NDT: 72, FDN: 64, Calcio: 0.55, Fosforo: 0.17},
+	{Nome: "Silagem de milho", MateriaSeca: 32, ProteinaBruta: 7, NDT: 65, FDN: 50, Calcio: 0.25, Fosforo: 0.20},
+	{Nome: "Silagem de sorgo", MateriaSeca: 30, ProteinaBruta: 7, NDT: 58, FDN: 58, Calcio: 0.30, Fosforo: 0.18},
+	{Nome: "Bagaço de cana in natura", MateriaSeca: 50, ProteinaBruta: 2, NDT: 45, FDN: 80, Calcio: 0.15, Fosforo: 0.05},
+	{Nome: "Pasto (Brachiaria, águas)", MateriaSeca: 25, ProteinaBruta: 9, NDT: 58, FDN: 65, Calcio: 0.35, Fosforo: 0.18},
+	{Nome: "Ureia pecuária", MateriaSeca: 98, ProteinaBruta: 281, NDT: 0, FDN: 0, Calcio: 0, Fosforo: 0},
+	{Nome: "Calcário calcítico", MateriaSeca: 99, ProteinaBruta: 0, NDT: 0, FDN: 0, Calcio: 37, Fosforo: 0},
+	{Nome: "Fosfato bicálcico", MateriaSeca: 99, ProteinaBruta: 0, NDT: 0, FDN: 0, Calcio: 24, Fosforo: 18},
+}
+
+// ExigenciaNutricional resume a demanda diária do animal. As
+// concentrações são em % da matéria seca da dieta.
+type ExigenciaNutricional struct {
+	PesoVivo        float64 `json:"peso_vivo"`
+	GanhoDiario     float64 `json:"ganho_diario"`
+	ConsumoMS       float64 `json:"consumo_ms"`       // kg/dia
+	EnergiaMantenca float64 `json:"energia_mantenca"` // Mcal ELm/dia
+	EnergiaGanho    float64 `json:"energia_ganho"`    // Mcal ELg/dia
+	ProteinaRetida  float64 `json:"proteina_retida"`  // g/dia
+	NDT             float64 `json:"ndt"`
+	ProteinaBruta   float64 `json:"proteina_bruta"`
+	Calcio          float64 `json:"calcio"`
+	Fosforo         float64 `json:"fosforo"`
+	FDNMinimo       float64 `json:"fdn_minimo"`
+}
+
+// CalcularExigencias estima consumo e exigências de bovinos de corte em
+// crescimento. O consumo segue a equação do BR-CORTE para zebuínos
+// confinados (quando consumoMS ≤ 0); energia, proteína e minerais seguem
+// o NRC (2000) a partir do peso corporal em jejum (96% do peso vivo).
+func CalcularExigencias(pesoVivo, ganho, consumoMS float64) ExigenciaNutricional {
+	if consumoMS <= 0 {
+		consumoMS = -2.7878 + 0.08789*math.Pow(pesoVivo, 0.75) + 5.0487*ganho - 1.6835*ganho*ganho
+		if consumoMS < 0.015*pesoVivo {
+			consumoMS = 0.015 * pesoVivo
+		}
+	}
+
+	pcj := 0.96 * pesoVivo
+	elm := 0.077 * math.Pow(pcj, 0.75)
+	var elg, pr float64
+	if ganho > 0 {
+		pcvz := 0.891 * pcj
+		gpcvz := 0.956 * ganho
+		elg = 0.0635 * math.Pow(pcvz, 0.75) * math.Pow(gpcvz, 1.097)
+		pr = ganho * (268 - 29.4*elg/ganho)
+		if pr < 0 {
+			pr = 0
+		}
+	}
+
+	// Proteína metabolizável (g/dia) convertida em PB assumindo 67% de eficiência
+	eficiencia := 0.834 - 0.00114*pcj
+	if eficiencia < 0.492 {
+		eficiencia = 0.492
+	}
+	pm := 3.8*math.Pow(pcj, 0.75) + pr/eficiencia
+	pb := pm / 0.67
+
+	calcio := 0.0154*pcj + 0.071*pr
+	fosforo := 0.016*pcj + 0.039*pr
+
+	return ExigenciaNutricional{
+		PesoVivo:        pesoVivo,
+		GanhoDiario:     ganho,
+		ConsumoMS:       consumoMS,
+		EnergiaMantenca: elm,
+		EnergiaGanho:    elg,
+		ProteinaRetida:  pr,
+		NDT:             ndtNecessario(elm, elg, consumoMS),
+		ProteinaBruta:   pb / 1000 / consumoMS * 100,
+		Calcio:          calcio / 1000 / consumoMS * 100,
+		Fosforo:         fosforo / 1000 / consumoMS * 100,
+	}
+}
+
+// energiaLiquida converte o NDT da dieta (%) em Mcal de ELm e ELg por kg de MS
+func energiaLiquida(ndt float64) (elm, elg float64) {
+	em := 0.82 * 0.04409 * ndt
+	elm = 1.37*em - 0.138*em*em + 0.0105*em*em*em - 1.12
+	elg = 1.42*em - 0.174*em*em + 0.0122*em*em*em - 1.65
+	return elm, elg
+}
+
+// ndtNecessario encontra, por bisseção, o menor NDT da dieta que atende
+// mantença e ganho dentro do consumo de MS. Retorna acima de 100 quando a
+// meta de ganho não é atingível com esse consumo.
+func ndtNecessario(elm, elg, consumoMS float64) float64 {
+	atende := func(ndt float64) bool {
+		cm, cg := energiaLiquida(ndt)
+		if cm <= 0 || (elg > 0 && cg <= 0) {
+			return false
+		}
+		consumo := elm / cm
+		if elg > 0 {
+			consumo += elg / cg
+		}
+		return consumo <= consumoMS
+	}
+	if !atende(100) {
+		return 101
+	}
+	baixo, alto := 30.0, 100.0
+	for i := 0; i < 50; i++ {
+		meio := (baixo + alto) / 2
+		if atende(meio) {
+			alto = meio
+		} else {
+			baixo = meio
+		}
+	}
+	return alto
+}
+
+// OpcaoIngrediente é um ingrediente disponível para a fórmula com os
+// limites de inclusão (% da MS) definidos pelo consultor
+type OpcaoIngrediente struct {
+	Ingrediente models.Ingrediente
+	Minimo      float64
+	Maximo      float64
+}
+
+// ResultadoDieta traz a fórmula de menor custo e a composição obtida
+type ResultadoDieta struct {
+	Exigencia      ExigenciaNutricional `json:"exigencia"`
+	Itens          []models.DietaItem   `json:"itens"`
+	NDT            float64              `json:"ndt"`
+	ProteinaBruta  float64              `json:"proteina_bruta"`
+	FDN            float64              `json:"fdn"`
+	Calcio         float64              `json:"calcio"`
+	Fosforo        float64              `json:"fosforo"`
+	ConsumoMN      float64              `json:"consumo_mn"`
+	CustoKgMS      float64              `json:"custo_kg_ms"`
+	CustoCabecaDia float64              `json:"custo_cabeca_dia"`
+	CustoArroba    float64              `json:"custo_arroba"`
+}
+
+// FormularDieta minimiza o custo por kg de MS atendendo NDT, PB, Ca, P e
+// FDN mínimos e os limites de cada ingrediente. O custo da arroba produzida
+// considera o ganho de carcaça (ganho × rendimento) em arrobas de 15 kg.
+// Retorna false quando nenhuma combinação atende às exigências.
+func FormularDieta(exig ExigenciaNutricional, opcoes []OpcaoIngrediente, rendimentoCarcaca float64) (ResultadoDieta, bool) {
+	n := len(opcoes)
+	if n == 0 || exig.NDT > 100 {
+		return ResultadoDieta{}, false
+	}
+
+	custo := make([]float64, n)
+	uns := make([]float64, n)
+	ndt := make([]float64, n)
+	pb := make([]float64, n)
+	ca := make([]float64, n)
+	p := make([]float64, n)
+	fdn := make([]float64, n)
+	for i, o := range opcoes {
+		ing := o.Ingrediente
+		if ing.MateriaSeca > 0 {
+			custo[i] = ing.PrecoKg / (ing.MateriaSeca / 100)
+		}
+		uns[i] = 1
+		ndt[i], pb[i], ca[i], p[i], fdn[i] = ing.NDT/100, ing.ProteinaBruta/100, ing.Calcio/100, ing.Fosforo/100, ing.FDN/100
+	}
+
+	restricoes := []restricaoLP{
+		{uns, igual, 1},
+		{ndt, maiorOuIgual, exig.NDT / 100},
+		{pb, maiorOuIgual, exig.ProteinaBruta / 100},
+		{ca, maiorOuIgual, exig.Calcio / 100},
+		{p, maiorOuIgual, exig.Fosforo / 100},
+	}
+	if exig.FDNMinimo > 0 {
+		restricoes = append(restricoes, restricaoLP{fdn, maiorOuIgual, exig.FDNMinimo / 100})
+	}
+	for i, o := range opcoes {
+		if o.Minimo > 0 {
+			coef := make([]float64, n)
+			coef[i] = 1
+			restricoes = append(restricoes, restricaoLP{coef, maiorOuIgual, o.Minimo / 100})
+		}
+		if o.Maximo > 0 && o.Maximo < 100 {
+			coef := make([]float64, n)
+			coef[i] = 1
+			restricoes = append(restricoes, restricaoLP{coef, menorOuIgual, o.Maximo / 100})
+		}
+	}
+
+	x, ok := minimizarLP(custo, restricoes)
+	if !ok {
+		return ResultadoDieta{}, false
+	}
+
+	res := ResultadoDieta{Exigencia: exig}
+	for i, o := range opcoes {
+		if x[i] < 1e-6 {
+			continue
+		}
+		ing := o.Ingrediente
+		kgMS := x[i] * exig.ConsumoMS
+		item := models.DietaItem{
+			IngredienteID:   ing.ID,
+			IngredienteNome: ing.Nome,
+			Proporcao:       x[i] * 100,
+			KgMS:            kgMS,
+		}
+		if ing.MateriaSeca > 0 {
+			item.KgMN = kgMS / (ing.MateriaSeca / 100)
+		}
+		item.Custo = item.KgMN * ing.PrecoKg
+		res.Itens = append(res.Itens, item)
+
+		res.NDT += x[i] * ing.NDT
+		res.ProteinaBruta += x[i] * ing.ProteinaBruta
+		res.FDN += x[i] * ing.FDN
+		res.Calcio += x[i] * ing.Calcio
+		res.Fosforo += x[i] * ing.Fosforo
+		res.ConsumoMN += item.KgMN
+		res.CustoCabecaDia += item.Custo
+	}
+	res.CustoKgMS = res.CustoCabecaDia / exig.ConsumoMS
+
+	arrobasDia := exig.GanhoDiario * rendimentoCarcaca / 100 / KgArroba
+	if arrobasDia > 0 {
+		res.CustoArroba = res.CustoCabecaDia / arrobasDia
+	}
+	return res, true
+}
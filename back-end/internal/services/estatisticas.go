@@ -0,0 +1,289 @@
+package services
+
+import (
+	"sort"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// Variação mínima da tendência, em % da média por safra, para ser
+// considerada alta ou queda
+const limiteTendencia = 2.0
+
+// UmidadePadraoCultura devolve a umidade de comercialização da cultura
+func UmidadePadraoCultura(cultura string) float64 {
+	if u, ok := models.UmidadePadrao[cultura]; ok {
+		return u
+	}
+	return 13
+}
+
+// PesoCorrigido converte o peso bruto da carga para a umidade padrão da
+// cultura e desconta as impurezas acima da tolerância. A correção vale nos
+// dois sentidos para que safras colhidas secas ou úmidas sejam comparáveis.
+func PesoCorrigido(c models.Colheita, cultura string) float64 {
+	peso := c.PesoBrutoKg
+	if c.Umidade > 0 && c.Umidade < 100 {
+		peso *= (100 - c.Umidade) / (100 - UmidadePadraoCultura(cultura))
+	}
+	if c.Impureza > models.ImpurezaTolerada {
+		peso *= 1 - (c.Impureza-models.ImpurezaTolerada)/100
+	}
+	return peso
+}
+
+// ProdutividadeSafra é a produtividade apurada de uma safra a partir das
+// cargas colhidas
+type ProdutividadeSafra struct {
+	Safra           models.Safra `json:"safra"`
+	Cargas          int          `json:"cargas"`
+	AreaColhida     float64      `json:"area_colhida"`
+	PesoBrutoKg     float64      `json:"peso_bruto_kg"`
+	PesoCorrigidoKg float64      `json:"peso_corrigido_kg"`
+	Producao        float64      `json:"producao"`      // sacas ou toneladas
+	Produtividade   float64      `json:"produtividade"` // na unidade da safra
+	Posicao         int          `json:"posicao"`       // no ranking do ano agrícola
+}
+
+// PercentualAlvo é a produtividade obtida em relação à meta da safra
+func (p ProdutividadeSafra) PercentualAlvo() float64 {
+	if p.Safra.ProdutividadeAlvo == 0 {
+		return 0
+	}
+	return p.Produtividade / p.Safra.ProdutividadeAlvo * 100
+}
+
+// DiferencaAlvo é a produtividade obtida menos a meta, na unidade da safra
+func (p ProdutividadeSafra) DiferencaAlvo() float64 {
+	return p.Produtividade - p.Safra.ProdutividadeAlvo
+}
+
+// CalcularProdutividade soma as cargas da safra e converte para sc/ha ou
+// t/ha. A área colhida das cargas só vale quando todas a informam; basta uma
+// carga sem área para usar a área plantada, senão a produção inteira seria
+// dividida por parte da área.
+func CalcularProdutividade(safra models.Safra, colheitas []models.Colheita) ProdutividadeSafra {
+	p := ProdutividadeSafra{Safra: safra}
+	areaCompleta := true
+	for _, c := range colheitas {
+		if c.SafraID != safra.ID {
+			continue
+		}
+		p.Cargas++
+		p.AreaColhida += c.AreaColhida
+		p.PesoBrutoKg += c.PesoBrutoKg
+		p.PesoCorrigidoKg += PesoCorrigido(c, safra.Cultura)
+		if c.AreaColhida <= 0 {
+			areaCompleta = false
+		}
+	}
+	if !areaCompleta || p.AreaColhida == 0 {
+		p.AreaColhida = safra.AreaHectares
+	}
+	if safra.Unidade == models.UnidadeToneladaHa {
+		p.Producao = p.PesoCorrigidoKg / 1000
+	} else {
+		p.Producao = p.PesoCorrigidoKg / models.KgSaca
+	}
+	if p.AreaColhida > 0 {
+		p.Produtividade = p.Producao / p.AreaColhida
+	}
+	return p
+}
+
+// RankingProdutividade agrupa os talhões colhidos de uma cultura em um ano
+// agrícola, do mais produtivo para o menos produtivo
+type RankingProdutividade struct {
+	Rotulo        string               `json:"rotulo"`
+	Cultura       string               `json:"cultura"`
+	Unidade       string               `json:"unidade"`
+	Talhoes       []ProdutividadeSafra `json:"talhoes"`
+	AreaColhida   float64              `json:"area_colhida"`
+	Producao      float64              `json:"producao"`
+	MetaPonderada float64              `json:"meta_ponderada"` // meta × área
+	AtingiramMeta int                  `json:"atingiram_meta"`
+}
+
+// Produtividade é a média ponderada pela área colhida
+func (r RankingProdutividade) Produtividade() float64 {
+	if r.AreaColhida == 0 {
+		return 0
+	}
+	return r.Producao / r.AreaColhida
+}
+
+// Meta é a meta média ponderada pela área colhida
+func (r RankingProdutividade) Meta() float64 {
+	if r.AreaColhida == 0 {
+		return 0
+	}
+	return r.MetaPonderada / r.AreaColhida
+}
+
+// PercentualMeta é a produtividade média em relação à meta média
+func (r RankingProdutividade) PercentualMeta() float64 {
+	if r.Meta() == 0 {
+		return 0
+	}
+	return r.Produtividade() / r.Meta() * 100
+}
+
+// RankingTalhoes ordena os talhões colhidos por produtividade dentro de cada
+// ano agrícola e cultura, do ano mais recente para o mais antigo
+func RankingTalhoes(produtividades []ProdutividadeSafra) []RankingProdutividade {
+	indice := make(map[string]int)
+	var grupos []RankingProdutividade
+	for _, p := range produtividades {
+		if p.Cargas == 0 {
+			continue
+		}
+		s := p.Safra
+		chave := s.Rotulo + "|" + s.Cultura + "|" + s.Unidade
+		i, ok := indice[chave]
+		if !ok {
+			i = len(grupos)
+			indice[chave] = i
+			grupos = append(grupos, RankingProdutividade{Rotulo: s.Rotulo, Cultura: s.Cultura, Unidade: s.Unidade})
+		}
+		g := &grupos[i]
+		g.Talhoes = append(g.Talhoes, p)
+		g.AreaColhida += p.AreaColhida
+		g.Producao += p.Producao
+		g.MetaPonderada += s.ProdutividadeAlvo * p.AreaColhida
+		if s.ProdutividadeAlvo > 0 && p.Produtividade >= s.ProdutividadeAlvo {
+			g.AtingiramMeta++
+		}
+	}
+	for i := range grupos {
+		talhoes := grupos[i].Talhoes
+		sort.SliceStable(talhoes, func(a, b int) bool {
+			return talhoes[a].Produtividade > talhoes[b].Produtividade
+		})
+		for j := range talhoes {
+			talhoes[j].Posicao = j + 1
+		}
+	}
+	sort.SliceStable(grupos, func(i, j int) bool {
+		if grupos[i].Rotulo != grupos[j].Rotulo {
+			return grupos[i].Rotulo > grupos[j].Rotulo
+		}
+		return grupos[i].Cultura < grupos[j].Cultura
+	})
+	return grupos
+}
+
+// PontoTendencia é a produtividade de um talhão em um ano agrícola
+type PontoTendencia struct {
+	Rotulo        string  `json:"rotulo"`
+	Produtividade float64 `json:"produtividade"`
+	Meta          float64 `json:"meta"`
+}
+
+// TendenciaTalhao é a série de produtividade de uma cultura em um talhão
+// ao longo das safras
+type TendenciaTalhao struct {
+	TalhaoID        int              `json:"talhao_id"`
+	TalhaoNome      string           `json:"talhao_nome"`
+	PropriedadeNome string           `json:"propriedade_nome"`
+	Cultura         string           `json:"cultura"`
+	Unidade         string           `json:"unidade"`
+	Pontos          []PontoTendencia `json:"pontos"`
+	Media           float64          `json:"media"`
+	Variacao        float64          `json:"variacao"` // inclinação por safra, na unidade da cultura
+}
+
+// Direcao classifica a tendência em alta, queda ou estável
+func (t TendenciaTalhao) Direcao() string {
+	if len(t.Pontos) < 2 || t.Media == 0 {
+		return "Sem histórico"
+	}
+	relativa := t.Variacao / t.Media * 100
+	switch {
+	case relativa >= limiteTendencia:
+		return "Alta"
+	case relativa <= -limiteTendencia:
+		return "Queda"
+	default:
+		return "Estável"
+	}
+}
+
+// TendenciaTalhoes monta a série histórica de cada talhão e cultura, com a
+// inclinação da reta de mínimos quadrados entre safras consecutivas. Safras
+// de um mesmo ano agrícola (ex.: duas colheitas de milho) são somadas pela
+// média ponderada pela área.
+func TendenciaTalhoes(produtividades []ProdutividadeSafra) []TendenciaTalhao {
+	type acumulado struct{ producao, area, meta float64 }
+	type chaveTalhao struct {
+		talhao           int
+		cultura, unidade string
+	}
+	indice := make(map[chaveTalhao]int)
+	var tendencias []TendenciaTalhao
+	var anos []map[string]*acumulado
+	for _, p := range produtividades {
+		if p.Cargas == 0 {
+			continue
+		}
+		s := p.Safra
+		chave := chaveTalhao{s.TalhaoID, s.Cultura, s.Unidade}
+		i, ok := indice[chave]
+		if !ok {
+			i = len(tendencias)
+			indice[chave] = i
+			tendencias = append(tendencias, TendenciaTalhao{
+				TalhaoID: s.TalhaoID, TalhaoNome: s.TalhaoNome, PropriedadeNome: s.PropriedadeNome,
+				Cultura: s.Cultura, Unidade: s.Unidade,
+			})
+			anos = append(anos, make(map[string]*acumulado))
+		}
+		a, ok := anos[i][s.Rotulo]
+		if !ok {
+			a = &acumulado{}
+			anos[i][s.Rotulo] = a
+		}
+		a.producao += p.Producao
+		a.area += p.AreaColhida
+		a.meta += s.ProdutividadeAlvo * p.AreaColhida
+	}
+
+	for i := range tendencias {
+		t := &tendencias[i]
+		for rotulo, a := range anos[i] {
+			if a.area == 0 {
+				continue
+			}
+			t.Pontos = append(t.Pontos, PontoTendencia{
+				Rotulo: rotulo, Produtividade: a.producao / a.area, Meta: a.meta / a.area,
+			})
+		}
+		sort.Slice(t.Pontos, func(a, b int) bool { return t.Pontos[a].Rotulo < t.Pontos[b].Rotulo })
+
+		n := float64(len(t.Pontos))
+		if n == 0 {
+			continue
+		}
+		var somaX, somaY, somaXY, somaXX float64
+		for x, p := range t.Pontos {
+			fx := float64(x)
+			somaX += fx
+			somaY += p.Produtividade
+			somaXY += fx * p.Produtividade
+			somaXX += fx * fx
+		}
+		t.Media = somaY / n
+		if den := n*somaXX - somaX*somaX; den > 0 {
+			t.Variacao = (n*somaXY - somaX*somaY) / den
+		}
+	}
+	sort.SliceStable(tendencias, func(i, j int) bool {
+		if tendencias[i].PropriedadeNome != tendencias[j].PropriedadeNome {
+			return tendencias[i].PropriedadeNome < tendencias[j].PropriedadeNome
+		}
+		if tendencias[i].TalhaoNome != tendencias[j].TalhaoNome {
+			return tendencias[i].TalhaoNome < tendencias[j].TalhaoNome
+		}
+		return tendencias[i].Cultura < tendencias[j].Cultura
+	})
+	return tendencias
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+func TestPesoCorrigido(t *testing.T) {
+	casos := []struct {
+		nome    string
+		carga   models.Colheita
+		cultura string
+		peso    float64
+	}{
+		{"soja úmida", models.Colheita{PesoBrutoKg: 10000, Umidade: 18}, "Soja", 10000 * 82.0 / 86},
+		{"soja seca", models.Colheita{PesoBrutoKg: 10000, Umidade: 12}, "Soja", 10000 * 88.0 / 86},
+		{"cultura sem padrão usa 13%", models.Colheita{PesoBrutoKg: 10000, Umidade: 15}, "Amendoim", 10000 * 85.0 / 87},
+		{"impureza dentro da tolerância", models.Colheita{PesoBrutoKg: 10000, Impureza: 1}, "Soja", 10000},
+		{"impureza acima da tolerância", models.Colheita{PesoBrutoKg: 10000, Umidade: 14, Impureza: 3}, "Soja", 9800},
+		{"umidade e impureza", models.Colheita{PesoBrutoKg: 10000, Umidade: 18, Impureza: 2.5}, "Soja", 10000 * 82.0 / 86 * 0.985},
+		{"umidade não medida", models.Colheita{PesoBrutoKg: 10000}, "Soja", 10000},
+	}
+	for _, c := range casos {
+		if p := PesoCorrigido(c.carga, c.cultura); math.Abs(p-c.peso) > 1e-6 {
+			t.Errorf("%s: %.3f kg, esperado %.3f kg", c.nome, p, c.peso)
+		}
+	}
+}
+
+func TestCalcularProdutividade(t *testing.T) {
+	safra := models.Safra{ID: 1, Cultura: "Soja", Unidade: models.UnidadeSacasHa, AreaHectares: 100}
+	// 36.000 kg a 14% de umidade = 600 sacas
+	carga := func(safraID int, area float64) models.Colheita {
+		return models.Colheita{SafraID: safraID, PesoBrutoKg: 36000, Umidade: 14, AreaColhida: area}
+	}
+
+	casos := []struct {
+		nome          string
+		safra         models.Safra
+		cargas        []models.Colheita
+		area          float64
+		produtividade float64
+	}{
+		{"área de todas as cargas", safra, []models.Colheita{carga(1, 5), carga(1, 5)}, 10, 120},
+		// Uma carga sem área: a produção de 20 ha não pode ser dividida por 5 ha
+		{"carga sem área usa a área plantada", safra, []models.Colheita{carga(1, 5), carga(1, 0)}, 100, 12},
+		{"nenhuma carga com área", safra, []models.Colheita{carga(1, 0)}, 100, 6},
+		{"cargas de outra safra", safra, []models.Colheita{carga(1, 10), carga(2, 0)}, 10, 60},
+		{"toneladas por hectare", models.Safra{ID: 1, Cultura: "Soja", Unidade: models.UnidadeToneladaHa, AreaHectares: 100},
+			[]models.Colheita{carga(1, 12)}, 12, 3},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			p := CalcularProdutividade(c.safra, c.cargas)
+			if p.AreaColhida != c.area {
+				t.Errorf("área colhida = %.1f ha, esperado %.1f ha", p.AreaColhida, c.area)
+			}
+			if math.Abs(p.Produtividade-c.produtividade) > 1e-9 {
+				t.Errorf("produtividade = %.3f, esperado %.3f", p.Produtividade, c.produtividade)
+			}
+		})
+	}
+}
@@ -0,0 +1,428 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// AntecedenciaValidadeDias é o prazo em que o lote passa a ser alertado
+// como próximo do vencimento
+const AntecedenciaValidadeDias = 60
+
+// Tipos de alerta do estoque
+const (
+	AlertaLoteVencido   = "vencido"
+	AlertaLoteVencendo  = "vencendo"
+	AlertaSaldoNegativo = "negativo"
+	AlertaAbaixoMinimo  = "minimo"
+)
+
+// SaldoLote é o saldo de um lote recebido. Lotes sem identificação no
+// recebimento ficam agrupados com Lote vazio.
+type SaldoLote struct {
+	Lote         string     `json:"lote"`
+	Validade     *time.Time `json:"validade"`
+	Recebido     time.Time  `json:"recebido"`
+	Entrada      float64    `json:"entrada"`
+	Saida        float64    `json:"saida"`
+	Saldo        float64    `json:"saldo"`
+	DiasValidade int        `json:"dias_validade"` // negativo quando vencido
+}
+
+// Vencido indica lote com validade anterior a hoje
+func (l SaldoLote) Vencido() bool {
+	return l.Validade != nil && l.DiasValidade < 0
+}
+
+// Vencendo indica lote dentro do prazo de alerta de validade
+func (l SaldoLote) Vencendo() bool {
+	return l.Validade != nil && l.DiasValidade >= 0 && l.DiasValidade <= AntecedenciaValidadeDias
+}
+
+// SaldoInsumo é a posição do insumo: total recebido, consumido, saldo por
+// lote e custo médio ponderado dos recebimentos
+type SaldoInsumo struct {
+	Insumo     models.Insumo `json:"insumo"`
+	Entradas   float64       `json:"entradas"`
+	Saidas     float64       `json:"saidas"`
+	Saldo      float64       `json:"saldo"`
+	Lotes      []SaldoLote   `json:"lotes"`
+	CustoMedio float64       `json:"custo_medio"`
+}
+
+// Valor é o saldo valorizado pelo custo médio (zero quando negativo)
+func (s SaldoInsumo) Valor() float64 {
+	if s.Saldo <= 0 {
+		return 0
+	}
+	return s.Saldo * s.CustoMedio
+}
+
+// LotesComSaldo filtra os lotes que ainda têm quantidade em estoque
+func (s SaldoInsumo) LotesComSaldo() []SaldoLote {
+	var lotes []SaldoLote
+	for _, l := range s.Lotes {
+		if l.Saldo > 0.0001 {
+			lotes = append(lotes, l)
+		}
+	}
+	return lotes
+}
+
+// Situacao resume o saldo para a listagem
+func (s SaldoInsumo) Situacao() string {
+	switch {
+	case s.Saldo < -0.0001:
+		return "negativo"
+	case s.Insumo.EstoqueMinimo > 0 && s.Saldo < s.Insumo.EstoqueMinimo:
+		return "abaixo do mínimo"
+	case s.Saldo <= 0.0001:
+		return "zerado"
+	}
+	return "ok"
+}
+
+// Cor é a classe Bootstrap da situação do saldo
+func (s SaldoInsumo) Cor() string {
+	switch s.Situacao() {
+	case "negativo":
+		return "danger"
+	case "abaixo do mínimo":
+		return "warning"
+	case "zerado":
+		return "secondary"
+	}
+	return "success"
+}
+
+// chaveLote compara lotes sem diferenciar caixa e espaços
+func chaveLote(lote string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(lote), ""))
+}
+
+// CalcularSaldo consolida as movimentações de um insumo. As saídas com lote
+// conhecido baixam aquele lote; as demais seguem o lote de validade mais
+// próxima (PVPS), e o que exceder os lotes disponíveis deixa o saldo negativo.
+func CalcularSaldo(insumo models.Insumo, entradas []models.EntradaInsumo, saidas []models.SaidaInsumo, hoje time.Time) SaldoInsumo {
+	hoje = inicioDoDia(hoje)
+	s := SaldoInsumo{Insumo: insumo}
+
+	indice := map[string]int{}
+	custoTotal, qtdCusto := 0.0, 0.0
+	for _, e := range entradas {
+		if e.InsumoID != insumo.ID {
+			continue
+		}
+		s.Entradas += e.Quantidade
+		if e.CustoUnitario > 0 {
+			custoTotal += e.Quantidade * e.CustoUnitario
+			qtdCusto += e.Quantidade
+		}
+		k := chaveLote(e.Lote)
+		i, ok := indice[k]
+		if !ok {
+			i = len(s.Lotes)
+			indice[k] = i
+			s.Lotes = append(s.Lotes, SaldoLote{Lote: strings.TrimSpace(e.Lote), Recebido: e.Data})
+		}
+		l := &s.Lotes[i]
+		l.Entrada += e.Quantidade
+		if e.Data.Before(l.Recebido) {
+			l.Recebido = e.Data
+		}
+		if e.Validade != nil && (l.Validade == nil || e.Validade.Before(*l.Validade)) {
+			v := inicioDoDia(*e.Validade)
+			l.Validade = &v
+		}
+	}
+	if qtdCusto > 0 {
+		s.CustoMedio = custoTotal / qtdCusto
+	}
+
+	// PVPS: primeiro a vencer, primeiro a sair; sem validade vai por último
+	sort.SliceStable(s.Lotes, func(i, j int) bool {
+		a, b := s.Lotes[i], s.Lotes[j]
+		if (a.Validade == nil) != (b.Validade == nil) {
+			return a.Validade != nil
+		}
+		if a.Validade != nil && !a.Validade.Equal(*b.Validade) {
+			return a.Validade.Before(*b.Validade)
+		}
+		return a.Recebido.Before(b.Recebido)
+	})
+	for i, l := range s.Lotes {
+		indice[chaveLote(l.Lote)] = i
+	}
+
+	ordenadas := make([]models.SaidaInsumo, 0, len(saidas))
+	for _, sd := range saidas {
+		if sd.InsumoID == insumo.ID {
+			ordenadas = append(ordenadas, sd)
+		}
+	}
+	sort.SliceStable(ordenadas, func(i, j int) bool { return ordenadas[i].Data.Before(ordenadas[j].Data) })
+
+	for _, sd := range ordenadas {
+		s.Saidas += sd.Quantidade
+		if i, ok := indice[chaveLote(sd.Lote)]; ok && strings.TrimSpace(sd.Lote) != "" {
+			s.Lotes[i].Saida += sd.Quantidade
+			continue
+		}
+		resta := sd.Quantidade
+		for i := range s.Lotes {
+			disponivel := s.Lotes[i].Entrada - s.Lotes[i].Saida
+			if disponivel <= 0 || resta <= 0 {
+				continue
+			}
+			baixa := min(disponivel, resta)
+			s.Lotes[i].Saida += baixa
+			resta -= baixa
+		}
+	}
+
+	for i := range s.Lotes {
+		l := &s.Lotes[i]
+		l.Saldo = l.Entrada - l.Saida
+		if l.Validade != nil {
+			l.DiasValidade = int(l.Validade.Sub(hoje).Hours() / 24)
+		}
+	}
+	s.Saldo = s.Entradas - s.Saidas
+	return s
+}
+
+// CalcularSaldos consolida o estoque de todos os insumos informados
+func CalcularSaldos(insumos []models.Insumo, entradas []models.EntradaInsumo, saidas []models.SaidaInsumo, hoje time.Time) []SaldoInsumo {
+	saldos := make([]SaldoInsumo, 0, len(insumos))
+	for _, i := range insumos {
+		saldos = append(saldos, CalcularSaldo(i, entradas, saidas, hoje))
+	}
+	return saldos
+}
+
+// AlertaEstoque aponta um lote vencido ou próximo da validade, um saldo
+// negativo (consumo sem recebimento lançado) ou um saldo abaixo do mínimo
+type AlertaEstoque struct {
+	Insumo     models.Insumo `json:"insumo"`
+	Tipo       string        `json:"tipo"`
+	Lote       string        `json:"lote"`
+	Validade   *time.Time    `json:"validade"`
+	Quantidade float64       `json:"quantidade"`
+	Dias       int           `json:"dias"`
+}
+
+// Texto descreve o alerta para listas e toasts
+func (a AlertaEstoque) Texto() string {
+	qtd := FormatarDecimal(a.Quantidade, 2) + " " + a.Insumo.Unidade
+	lote := "sem lote"
+	if a.Lote != "" {
+		lote = "lote " + a.Lote
+	}
+	switch a.Tipo {
+	case AlertaLoteVencido:
+		return fmt.Sprintf("%s vencido em %s (%s)", lote, a.Validade.Format("02/01/2006"), qtd)
+	case AlertaLoteVencendo:
+		return fmt.Sprintf("%s vence em %d dia(s) (%s)", lote, a.Dias, qtd)
+	case AlertaSaldoNegativo:
+		return "saldo negativo: " + qtd
+	}
+	return fmt.Sprintf("saldo %s, mínimo %s", qtd, FormatarDecimal(a.Insumo.EstoqueMinimo, 2))
+}
+
+// Cor é a classe Bootstrap do alerta
+func (a AlertaEstoque) Cor() string {
+	if a.Tipo == AlertaLoteVencido || a.Tipo == AlertaSaldoNegativo {
+		return "danger"
+	}
+	return "warning"
+}
+
+// AlertasEstoque lista os alertas dos insumos ativos, os mais graves primeiro
+func AlertasEstoque(saldos []SaldoInsumo) []AlertaEstoque {
+	var alertas []AlertaEstoque
+	for _, s := range saldos {
+		if !s.Insumo.Ativo {
+			continue
+		}
+		for _, l := range s.LotesComSaldo() {
+			switch {
+			case l.Vencido():
+				alertas = append(alertas, AlertaEstoque{Insumo: s.Insumo, Tipo: AlertaLoteVencido, Lote: l.Lote,
+					Validade: l.Validade, Quantidade: l.Saldo, Dias: l.DiasValidade})
+			case l.Vencendo():
+				alertas = append(alertas, AlertaEstoque{Insumo: s.Insumo, Tipo: AlertaLoteVencendo, Lote: l.Lote,
+					Validade: l.Validade, Quantidade: l.Saldo, Dias: l.DiasValidade})
+			}
+		}
+		switch s.Situacao() {
+		case "negativo":
+			alertas = append(alertas, AlertaEstoque{Insumo: s.Insumo, Tipo: AlertaSaldoNegativo, Quantidade: s.Saldo})
+		case "abaixo do mínimo":
+			alertas = append(alertas, AlertaEstoque{Insumo: s.Insumo, Tipo: AlertaAbaixoMinimo, Quantidade: s.Saldo})
+		}
+	}
+
+	ordem := map[string]int{AlertaLoteVencido: 0, AlertaSaldoNegativo: 1, AlertaLoteVencendo: 2, AlertaAbaixoMinimo: 3}
+	sort.SliceStable(alertas, func(i, j int) bool {
+		if ordem[alertas[i].Tipo] != ordem[alertas[j].Tipo] {
+			return ordem[alertas[i].Tipo] < ordem[alertas[j].Tipo]
+		}
+		return alertas[i].Dias < alertas[j].Dias
+	})
+	return alertas
+}
+
+// MovimentoEstoque é uma linha do extrato do insumo com o saldo acumulado
+type MovimentoEstoque struct {
+	Data      time.Time `json:"data"`
+	Descricao string    `json:"descricao"`
+	Lote      string    `json:"lote"`
+	Entrada   float64   `json:"entrada"`
+	Saida     float64   `json:"saida"`
+	Saldo     float64   `json:"saldo"`
+	Origem    string    `json:"origem"`
+}
+
+// ExtratoInsumo monta o extrato cronológico de entradas e saídas do insumo,
+// com o mais recente primeiro
+func ExtratoInsumo(entradas []models.EntradaInsumo, saidas []models.SaidaInsumo) []MovimentoEstoque {
+	var movimentos []MovimentoEstoque
+	for _, e := range entradas {
+		descricao := "Recebimento"
+		if e.Fornecedor != "" {
+			descricao += " – " + e.Fornecedor
+		}
+		if e.NotaFiscal != "" {
+			descricao += " (NF " + e.NotaFiscal + ")"
+		}
+		movimentos = append(movimentos, MovimentoEstoque{Data: e.Data, Descricao: descricao, Lote: e.Lote, Entrada: e.Quantidade, Origem: "entrada"})
+	}
+	for _, s := range saidas {
+		movimentos = append(movimentos, MovimentoEstoque{Data: s.Data, Descricao: s.Descricao, Lote: s.Lote, Saida: s.Quantidade, Origem: s.Origem})
+	}
+
+	// Na mesma data, recebimentos antes do consumo
+	sort.SliceStable(movimentos, func(i, j int) bool {
+		if !movimentos[i].Data.Equal(movimentos[j].Data) {
+			return movimentos[i].Data.Before(movimentos[j].Data)
+		}
+		return movimentos[i].Entrada > 0 && movimentos[j].Entrada == 0
+	})
+	saldo := 0.0
+	for i := range movimentos {
+		saldo += movimentos[i].Entrada - movimentos[i].Saida
+		movimentos[i].Saldo = saldo
+	}
+	for i, j := 0, len(movimentos)-1; i < j; i, j = i+1, j-1 {
+		movimentos[i], movimentos[j] = movimentos[j], movimentos[i]
+	}
+	return movimentos
+}
+
+// grandezasUnidade relaciona cada unidade à grandeza e ao fator para a
+// unidade base (kg, L, dose, un)
+var grandezasUnidade = map[string]struct {
+	grandeza string
+	fator    float64
+}{
+	"g":    {"massa", 0.001},
+	"kg":   {"massa", 1},
+	"t":    {"massa", 1000},
+	"mL":   {"volume", 0.001},
+	"L":    {"volume", 1},
+	"dose": {"dose", 1},
+	"un":   {"un", 1},
+}
+
+// ConverterQuantidade converte entre unidades da mesma grandeza (g/kg/t,
+// mL/L). Retorna false quando não há conversão (ex.: kg para sc).
+func ConverterQuantidade(quantidade float64, de, para string) (float64, bool) {
+	if de == para {
+		return quantidade, true
+	}
+	a, okA := grandezasUnidade[de]
+	b, okB := grandezasUnidade[para]
+	if !okA || !okB || a.grandeza != b.grandeza {
+		return 0, false
+	}
+	return quantidade * a.fator / b.fator, true
+}
+
+// QuantidadeAplicada calcula o consumo de uma aplicação por hectare (dose
+// em L/ha, kg/ha etc. vezes a área) na unidade do estoque
+func QuantidadeAplicada(dose float64, unidadeDose string, area float64, unidadeEstoque string) (float64, bool) {
+	unidade, porHectare := strings.CutSuffix(unidadeDose, "/ha")
+	if !porHectare {
+		return 0, false
+	}
+	return ConverterQuantidade(dose*area, unidade, unidadeEstoque)
+}
+
+// QuantidadeSanitaria calcula o consumo de uma aplicação sanitária (dose por
+// animal vezes o número de animais) na unidade do estoque. Doses por peso
+// vivo (mL/kg) não são convertidas.
+func QuantidadeSanitaria(dose float64, unidade string, animais int, unidadeEstoque string) (float64, bool) {
+	if animais <= 0 {
+		return 0, false
+	}
+	return ConverterQuantidade(dose*float64(animais), unidade, unidadeEstoque)
+}
+
+// DisponibilidadeProduto confronta a quantidade que a recomendação exige com
+// o saldo do insumo de mesmo nome no estoque da propriedade
+type DisponibilidadeProduto struct {
+	Produto    string  `json:"produto"`
+	Necessario float64 `json:"necessario"`
+	Unidade    string  `json:"unidade"`
+	Saldo      float64 `json:"saldo"`
+	InsumoID   int     `json:"insumo_id"`
+	Encontrado bool    `json:"encontrado"`
+	Convertido bool    `json:"convertido"` // dose convertida para a unidade do estoque
+	Suficiente bool    `json:"suficiente"`
+}
+
+// Falta é a quantidade que precisa ser comprada
+func (d DisponibilidadeProduto) Falta() float64 {
+	if d.Saldo >= d.Necessario {
+		return 0
+	}
+	return d.Necessario - max(d.Saldo, 0)
+}
+
+// chaveProduto compara nomes de produto sem acentos, caixa e espaços extras
+func chaveProduto(nome string) string {
+	return strings.Join(strings.Fields(strings.ToLower(acentos.Replace(nome))), " ")
+}
+
+// DisponibilidadeRecomendacao verifica se o estoque cobre os produtos
+// recomendados na consulta para a área informada e o número de aplicações
+func DisponibilidadeRecomendacao(produtos []models.ProdutoRecomendado, area float64, saldos []SaldoInsumo) []DisponibilidadeProduto {
+	porNome := map[string]SaldoInsumo{}
+	for _, s := range saldos {
+		if s.Insumo.Ativo {
+			porNome[chaveProduto(s.Insumo.Nome)] = s
+		}
+	}
+
+	var itens []DisponibilidadeProduto
+	for _, p := range produtos {
+		aplicacoes := max(p.NumeroAplicacoes, 1)
+		d := DisponibilidadeProduto{Produto: p.Produto, Unidade: strings.TrimSuffix(p.UnidadeDose, "/ha")}
+		d.Necessario = p.Dose * area * float64(aplicacoes)
+		if s, ok := porNome[chaveProduto(p.Produto)]; ok {
+			d.Encontrado = true
+			d.InsumoID = s.Insumo.ID
+			if q, ok := QuantidadeAplicada(p.Dose, p.UnidadeDose, area*float64(aplicacoes), s.Insumo.Unidade); ok {
+				d.Necessario, d.Unidade, d.Convertido = q, s.Insumo.Unidade, true
+				d.Saldo = s.Saldo
+				d.Suficiente = s.Saldo >= q
+			}
+		}
+		itens = append(itens, d)
+	}
+	return itens
+}
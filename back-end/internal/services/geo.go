@@ -0,0 +1,628 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// Elipsoide GRS80, adotado pelo SIRGAS 2000 (para fins práticos idêntico
+// ao WGS 84)
+const (
+	semiEixoMaior  = 6378137.0
+	achatamento    = 1 / 298.257222101
+	fatorEscalaUTM = 0.9996
+)
+
+var excentricidade2 = achatamento * (2 - achatamento)
+
+// ToleranciaAreaPercentual é a diferença aceita entre a área calculada do
+// limite e os hectares declarados antes de alertar
+const ToleranciaAreaPercentual = 5.0
+
+// Formatos de coordenada reconhecidos
+const (
+	FormatoDecimal = "graus decimais"
+	FormatoGMS     = "graus, minutos e segundos"
+	FormatoUTM     = "UTM"
+)
+
+// UTM é uma coordenada plana no fuso informado, em metros
+type UTM struct {
+	Fuso  int     `json:"fuso"`
+	Sul   bool    `json:"sul"`
+	Leste float64 `json:"leste"`
+	Norte float64 `json:"norte"`
+}
+
+// String formata no padrão usado em mapas e memoriais (ex.: 22S 712345 E 7712345 N)
+func (u UTM) String() string {
+	hemisferio := "N"
+	if u.Sul {
+		hemisferio = "S"
+	}
+	return fmt.Sprintf("%d%s %.0f E %.0f N", u.Fuso, hemisferio, u.Leste, u.Norte)
+}
+
+// ParaUTM projeta o ponto no fuso UTM em que ele está (série de Krüger
+// truncada, precisão milimétrica dentro do fuso)
+func ParaUTM(p models.Ponto) UTM {
+	fuso := int(math.Floor((p.Longitude+180)/6)) + 1
+	fuso = min(max(fuso, 1), 60)
+	return ParaUTMNoFuso(p, fuso)
+}
+
+// ParaUTMNoFuso projeta o ponto em um fuso escolhido, usado para manter
+// todos os vértices de um polígono no mesmo fuso
+func ParaUTMNoFuso(p models.Ponto, fuso int) UTM {
+	e2 := excentricidade2
+	ep2 := e2 / (1 - e2)
+	phi := p.Latitude * math.Pi / 180
+	lambda0 := float64((fuso-1)*6-180+3) * math.Pi / 180
+	lambda := p.Longitude * math.Pi / 180
+
+	sin, cos, tan := math.Sin(phi), math.Cos(phi), math.Tan(phi)
+	n := semiEixoMaior / math.Sqrt(1-e2*sin*sin)
+	t := tan * tan
+	c := ep2 * cos * cos
+	a := cos * (lambda - lambda0)
+	m := semiEixoMaior * ((1-e2/4-3*e2*e2/64-5*e2*e2*e2/256)*phi -
+		(3*e2/8+3*e2*e2/32+45*e2*e2*e2/1024)*math.Sin(2*phi) +
+		(15*e2*e2/256+45*e2*e2*e2/1024)*math.Sin(4*phi) -
+		(35*e2*e2*e2/3072)*math.Sin(6*phi))
+
+	u := UTM{Fuso: fuso, Sul: p.Latitude < 0}
+	u.Leste = fatorEscalaUTM*n*(a+(1-t+c)*math.Pow(a, 3)/6+
+		(5-18*t+t*t+72*c-58*ep2)*math.Pow(a, 5)/120) + 500000
+	u.Norte = fatorEscalaUTM * (m + n*tan*(a*a/2+(5-t+9*c+4*c*c)*math.Pow(a, 4)/24+
+		(61-58*t+t*t+600*c-330*ep2)*math.Pow(a, 6)/720))
+	if u.Sul {
+		u.Norte += 10000000
+	}
+	return u
+}
+
+// DeUTM converte a coordenada UTM (SIRGAS 2000) em graus decimais
+func DeUTM(u UTM) models.Ponto {
+	e2 := excentricidade2
+	ep2 := e2 / (1 - e2)
+	x := u.Leste - 500000
+	y := u.Norte
+	if u.Sul {
+		y -= 10000000
+	}
+	lambda0 := float64((u.Fuso-1)*6-180+3) * math.Pi / 180
+
+	m := y / fatorEscalaUTM
+	mu := m / (semiEixoMaior * (1 - e2/4 - 3*e2*e2/64 - 5*e2*e2*e2/256))
+	e1 := (1 - math.Sqrt(1-e2)) / (1 + math.Sqrt(1-e2))
+	phi1 := mu + (3*e1/2-27*math.Pow(e1, 3)/32)*math.Sin(2*mu) +
+		(21*e1*e1/16-55*math.Pow(e1, 4)/32)*math.Sin(4*mu) +
+		(151*math.Pow(e1, 3)/96)*math.Sin(6*mu) +
+		(1097*math.Pow(e1, 4)/512)*math.Sin(8*mu)
+
+	sin, cos, tan := math.Sin(phi1), math.Cos(phi1), math.Tan(phi1)
+	n1 := semiEixoMaior / math.Sqrt(1-e2*sin*sin)
+	t1 := tan * tan
+	c1 := ep2 * cos * cos
+	r1 := semiEixoMaior * (1 - e2) / math.Pow(1-e2*sin*sin, 1.5)
+	d := x / (n1 * fatorEscalaUTM)
+
+	lat := phi1 - (n1*tan/r1)*(d*d/2-(5+3*t1+10*c1-4*c1*c1-9*ep2)*math.Pow(d, 4)/24+
+		(61+90*t1+298*c1+45*t1*t1-252*ep2-3*c1*c1)*math.Pow(d, 6)/720)
+	lon := lambda0 + (d-(1+2*t1+c1)*math.Pow(d, 3)/6+
+		(5-2*c1+28*t1-3*c1*c1+8*ep2+24*t1*t1)*math.Pow(d, 5)/120)/cos
+
+	return models.Ponto{Latitude: lat * 180 / math.Pi, Longitude: lon * 180 / math.Pi}
+}
+
+var (
+	// Fuso, letra da zona ou hemisfério, leste e norte (ex.: "22K 712345 7712345",
+	// "UTM 23 S E 193000 N 8254000")
+	padraoUTM          = regexp.MustCompile(`^(?:UTM\s*)?(?:FUSO\s*|ZONA\s*)?(\d{1,2})\s*([C-HJ-NP-X])?\s*[,;]?\s*(?:E\s*[:=]?\s*)?(\d{5,7}(?:[.,]\d+)?)\s*(?:M?E)?\s*[,;\s]\s*(?:N\s*[:=]?\s*)?(\d{6,8}(?:[.,]\d+)?)\s*(?:M?N)?$`)
+	padraoNumero       = regexp.MustCompile(`[-+]?\d+(?:[.,]\d+)?`)
+	decimalVirgula     = regexp.MustCompile(`(\d),(\d)`)
+	padraoHemisferio   = regexp.MustCompile(`[NSEWOL]`)
+	palavrasHemisferio = strings.NewReplacer("NORTE", "N", "SUL", "S", "LESTE", "L", "OESTE", "O")
+)
+
+// LerPonto interpreta uma coordenada em graus decimais ("-15.7801,
+// -47.9292"), graus/minutos/segundos (15°46'48"S 47°55'45"O) ou UTM
+// ("23L 193000 8254000"). Retorna também o formato reconhecido. UTM sem
+// hemisfério é considerado no hemisfério sul.
+func LerPonto(texto string) (models.Ponto, string, error) {
+	s := strings.ToUpper(strings.TrimSpace(texto))
+	if s == "" {
+		return models.Ponto{}, "", errors.New("coordenada vazia")
+	}
+	s = acentos.Replace(s)
+
+	if m := padraoUTM.FindStringSubmatch(s); m != nil {
+		fuso, _ := strconv.Atoi(m[1])
+		if fuso < 1 || fuso > 60 {
+			return models.Ponto{}, "", fmt.Errorf("fuso UTM %d inválido", fuso)
+		}
+		u := UTM{Fuso: fuso, Sul: true, Leste: LerDecimal(m[3]), Norte: LerDecimal(m[4])}
+		// Letra S é o hemisfério sul no uso brasileiro; as demais são
+		// zonas de latitude (C a M ao sul, N a X ao norte)
+		if letra := m[2]; letra != "" && letra != "S" {
+			u.Sul = letra < "N"
+		}
+		if u.Leste < 100000 || u.Leste > 900000 || u.Norte < 0 || u.Norte > 10000000 {
+			return models.Ponto{}, "", errors.New("coordenada UTM fora dos limites do fuso")
+		}
+		return DeUTM(u), FormatoUTM, nil
+	}
+
+	s = palavrasHemisferio.Replace(s)
+	if strings.ContainsAny(s, "°º'′\"″") || padraoHemisferio.MatchString(s) {
+		p, err := lerGMS(s)
+		return p, FormatoGMS, err
+	}
+
+	numeros := padraoNumero.FindAllString(s, -1)
+	if len(numeros) != 2 {
+		return models.Ponto{}, "", errors.New("informe latitude e longitude")
+	}
+	p := models.Ponto{Latitude: LerDecimal(numeros[0]), Longitude: LerDecimal(numeros[1])}
+	return p, FormatoDecimal, validarPonto(p)
+}
+
+// grupoGMS é uma coordenada em graus, minutos e segundos em leitura
+type grupoGMS struct {
+	valores    []float64 // com sinal; qualquer valor negativo torna a coordenada negativa
+	hemisferio string
+}
+
+// lerGMS interpreta graus, minutos e segundos com o hemisfério antes ou
+// depois dos números, ou com sinal negativo e o símbolo de graus
+func lerGMS(s string) (models.Ponto, error) {
+	s = decimalVirgula.ReplaceAllString(s, "$1.$2")
+	s = strings.NewReplacer("°", " ° ", "º", " ° ", "''", " ", "'", " ", "′", " ", "\"", " ", "″", " ", ",", " ", ";", " ", ":", " ").Replace(s)
+	s = padraoHemisferio.ReplaceAllString(s, " $0 ")
+	tokens := strings.Fields(s)
+	if len(tokens) == 0 {
+		return models.Ponto{}, errors.New("coordenada vazia")
+	}
+	prefixado := padraoHemisferio.MatchString(tokens[0])
+
+	var grupos []*grupoGMS
+	var atual *grupoGMS
+	novo := func() {
+		atual = &grupoGMS{}
+		grupos = append(grupos, atual)
+	}
+	for _, t := range tokens {
+		switch {
+		case len(t) == 1 && padraoHemisferio.MatchString(t):
+			if prefixado {
+				novo()
+				atual.hemisferio = t
+			} else {
+				if atual == nil || len(atual.valores) == 0 || atual.hemisferio != "" {
+					return models.Ponto{}, errors.New("hemisfério sem coordenada")
+				}
+				atual.hemisferio = t
+				atual = nil
+			}
+		case t == "°":
+			// Graus começam uma nova coordenada quando não há hemisfério separando
+			if atual != nil && len(atual.valores) > 1 {
+				ultimo := atual.valores[len(atual.valores)-1]
+				atual.valores = atual.valores[:len(atual.valores)-1]
+				novo()
+				atual.valores = []float64{ultimo}
+			}
+		default:
+			v, err := strconv.ParseFloat(t, 64)
+			if err != nil {
+				return models.Ponto{}, fmt.Errorf("valor %q não reconhecido", t)
+			}
+			if atual == nil {
+				novo()
+			}
+			atual.valores = append(atual.valores, v)
+		}
+	}
+	if len(grupos) != 2 {
+		return models.Ponto{}, errors.New("informe latitude e longitude")
+	}
+
+	var lat, lon *float64
+	for i, g := range grupos {
+		if len(g.valores) == 0 || len(g.valores) > 3 {
+			return models.Ponto{}, errors.New("use graus, minutos e segundos")
+		}
+		negativo := false
+		for _, v := range g.valores {
+			negativo = negativo || math.Signbit(v)
+		}
+		graus := math.Abs(g.valores[0])
+		for j, divisor := range []float64{60, 3600} {
+			if j+1 < len(g.valores) {
+				if math.Abs(g.valores[j+1]) >= 60 {
+					return models.Ponto{}, errors.New("minutos e segundos devem ser menores que 60")
+				}
+				graus += math.Abs(g.valores[j+1]) / divisor
+			}
+		}
+		if negativo || g.hemisferio == "S" || g.hemisferio == "W" || g.hemisferio == "O" {
+			graus = -graus
+		}
+		v := graus
+		switch {
+		case g.hemisferio == "N" || g.hemisferio == "S":
+			lat = &v
+		case g.hemisferio != "":
+			lon = &v
+		case i == 0 && lat == nil:
+			lat = &v
+		default:
+			lon = &v
+		}
+	}
+	if lat == nil || lon == nil {
+		return models.Ponto{}, errors.New("informe uma latitude (N/S) e uma longitude (L/O)")
+	}
+	p := models.Ponto{Latitude: *lat, Longitude: *lon}
+	return p, validarPonto(p)
+}
+
+func validarPonto(p models.Ponto) error {
+	if math.Abs(p.Latitude) > 90 {
+		return errors.New("latitude fora do intervalo de -90 a 90 graus")
+	}
+	if math.Abs(p.Longitude) > 180 {
+		return errors.New("longitude fora do intervalo de -180 a 180 graus")
+	}
+	return nil
+}
+
+// NoBrasil confere se o ponto está no retângulo envolvente do território
+// brasileiro; fora dele a coordenada provavelmente foi digitada invertida
+// ou sem sinal
+func NoBrasil(p models.Ponto) bool {
+	return p.Latitude >= -34 && p.Latitude <= 5.5 && p.Longitude >= -74.1 && p.Longitude <= -28.8
+}
+
+// FormatarGMS formata o ponto em graus, minutos e segundos com hemisférios
+// em português (ex.: 15°46'48,4"S 47°55'45,1"O)
+func FormatarGMS(p models.Ponto) string {
+	parte := func(v float64, positivo, negativo string) string {
+		h := positivo
+		if v < 0 {
+			h = negativo
+		}
+		v = math.Abs(v)
+		graus := math.Floor(v)
+		minutos := math.Floor((v - graus) * 60)
+		segundos := ((v-graus)*60 - minutos) * 60
+		if math.Round(segundos*10) >= 600 {
+			segundos = 0
+			minutos++
+		}
+		if minutos >= 60 {
+			minutos = 0
+			graus++
+		}
+		return fmt.Sprintf("%.0f°%02.0f'%s\"%s", graus, minutos, strings.Replace(fmt.Sprintf("%04.1f", segundos), ".", ",", 1), h)
+	}
+	return parte(p.Latitude, "N", "S") + " " + parte(p.Longitude, "L", "O")
+}
+
+// FormatarDecimalGraus formata o ponto em graus decimais (6 casas, ~0,1 m)
+func FormatarDecimalGraus(p models.Ponto) string {
+	return fmt.Sprintf("%.6f, %.6f", p.Latitude, p.Longitude)
+}
+
+// LerPoligono interpreta o limite informado em WKT (POLYGON((lon lat, ...)))
+// ou como um vértice por linha em qualquer formato aceito por LerPonto, e
+// valida o anel resultante
+func LerPoligono(texto string) ([]models.Ponto, error) {
+	texto = strings.TrimSpace(texto)
+	var anel []models.Ponto
+	if maiusculo := strings.ToUpper(texto); strings.HasPrefix(maiusculo, "POLYGON") {
+		inicio := strings.Index(texto, "((")
+		fim := strings.Index(texto, ")")
+		if inicio < 0 || fim < inicio {
+			return nil, errors.New("WKT inválido: use POLYGON((lon lat, lon lat, ...))")
+		}
+		for _, par := range strings.Split(texto[inicio+2:fim], ",") {
+			campos := strings.Fields(par)
+			if len(campos) < 2 {
+				return nil, fmt.Errorf("vértice WKT %q inválido", strings.TrimSpace(par))
+			}
+			lon, errLon := strconv.ParseFloat(campos[0], 64)
+			lat, errLat := strconv.ParseFloat(campos[1], 64)
+			if errLon != nil || errLat != nil {
+				return nil, fmt.Errorf("vértice WKT %q inválido", strings.TrimSpace(par))
+			}
+			anel = append(anel, models.Ponto{Latitude: lat, Longitude: lon})
+		}
+	} else {
+		for i, linha := range strings.Split(texto, "\n") {
+			if strings.TrimSpace(linha) == "" {
+				continue
+			}
+			p, _, err := LerPonto(linha)
+			if err != nil {
+				return nil, fmt.Errorf("linha %d: %v", i+1, err)
+			}
+			anel = append(anel, p)
+		}
+	}
+	return ValidarPoligono(anel)
+}
+
+// ValidarPoligono normaliza o anel (sem fechamento repetido nem vértices
+// duplicados em sequência) e rejeita polígonos com menos de três vértices,
+// coordenadas inválidas, autointerseção ou área nula
+func ValidarPoligono(anel []models.Ponto) ([]models.Ponto, error) {
+	var limpo []models.Ponto
+	for _, p := range anel {
+		if err := validarPonto(p); err != nil {
+			return nil, err
+		}
+		if len(limpo) > 0 && mesmoPonto(limpo[len(limpo)-1], p) {
+			continue
+		}
+		limpo = append(limpo, p)
+	}
+	if len(limpo) > 1 && mesmoPonto(limpo[0], limpo[len(limpo)-1]) {
+		limpo = limpo[:len(limpo)-1]
+	}
+	if len(limpo) < 3 {
+		return nil, errors.New("o polígono precisa de ao menos três vértices distintos")
+	}
+
+	n := len(limpo)
+	for i := 0; i < n; i++ {
+		a1, a2 := limpo[i], limpo[(i+1)%n]
+		for j := i + 1; j < n; j++ {
+			// Arestas vizinhas compartilham um vértice
+			if j == i+1 || (i == 0 && j == n-1) {
+				continue
+			}
+			b1, b2 := limpo[j], limpo[(j+1)%n]
+			if segmentosCruzam(a1, a2, b1, b2) {
+				return nil, fmt.Errorf("o limite cruza a si mesmo entre os vértices %d e %d", i+1, j+1)
+			}
+		}
+	}
+	if AreaGeodesica(limpo) < 0.0001 {
+		return nil, errors.New("o polígono não tem área")
+	}
+	return limpo, nil
+}
+
+func mesmoPonto(a, b models.Ponto) bool {
+	return math.Abs(a.Latitude-b.Latitude) < 1e-9 && math.Abs(a.Longitude-b.Longitude) < 1e-9
+}
+
+// segmentosCruzam testa a interseção de dois segmentos no plano lon/lat,
+// suficiente para a escala de uma propriedade
+func segmentosCruzam(p1, p2, p3, p4 models.Ponto) bool {
+	orientacao := func(a, b, c models.Ponto) float64 {
+		return (b.Longitude-a.Longitude)*(c.Latitude-a.Latitude) - (b.Latitude-a.Latitude)*(c.Longitude-a.Longitude)
+	}
+	noSegmento := func(a, b, c models.Ponto) bool {
+		return math.Min(a.Longitude, b.Longitude) <= c.Longitude && c.Longitude <= math.Max(a.Longitude, b.Longitude) &&
+			math.Min(a.Latitude, b.Latitude) <= c.Latitude && c.Latitude <= math.Max(a.Latitude, b.Latitude)
+	}
+	d1, d2 := orientacao(p3, p4, p1), orientacao(p3, p4, p2)
+	d3, d4 := orientacao(p1, p2, p3), orientacao(p1, p2, p4)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return (d1 == 0 && noSegmento(p3, p4, p1)) || (d2 == 0 && noSegmento(p3, p4, p2)) ||
+		(d3 == 0 && noSegmento(p1, p2, p3)) || (d4 == 0 && noSegmento(p1, p2, p4))
+}
+
+// areaAssinada calcula a área (m²) na projeção cilíndrica equivalente do
+// elipsoide, que preserva áreas: positiva quando o anel é anti-horário
+func areaAssinada(anel []models.Ponto) float64 {
+	e := math.Sqrt(excentricidade2)
+	// q(φ) da latitude autálica (Snyder, eq. 3-12)
+	q := func(latitude float64) float64 {
+		s := math.Sin(latitude * math.Pi / 180)
+		return (1 - excentricidade2) * (s/(1-excentricidade2*s*s) - 1/(2*e)*math.Log((1-e*s)/(1+e*s)))
+	}
+	soma := 0.0
+	n := len(anel)
+	for i := 0; i < n; i++ {
+		a, b := anel[i], anel[(i+1)%n]
+		xa := semiEixoMaior * a.Longitude * math.Pi / 180
+		xb := semiEixoMaior * b.Longitude * math.Pi / 180
+		ya := semiEixoMaior * q(a.Latitude) / 2
+		yb := semiEixoMaior * q(b.Latitude) / 2
+		soma += xa*yb - xb*ya
+	}
+	return soma / 2
+}
+
+// AreaGeodesica calcula a área do polígono sobre o elipsoide SIRGAS 2000,
+// em hectares
+func AreaGeodesica(anel []models.Ponto) float64 {
+	if len(anel) < 3 {
+		return 0
+	}
+	return math.Abs(areaAssinada(anel)) / 10000
+}
+
+// ConferenciaArea compara a área calculada do limite com a declarada
+type ConferenciaArea struct {
+	Calculada float64 `json:"calculada"`
+	Declarada float64 `json:"declarada"`
+	Diferenca float64 `json:"diferenca"` // % sobre a declarada
+}
+
+// ConferirArea compara as áreas; sem área declarada a diferença fica zerada
+func ConferirArea(calculada, declarada float64) ConferenciaArea {
+	c := ConferenciaArea{Calculada: calculada, Declarada: declarada}
+	if declarada > 0 {
+		c.Diferenca = (calculada - declarada) / declarada * 100
+	}
+	return c
+}
+
+// ForaDaTolerancia indica diferença maior que ToleranciaAreaPercentual
+func (c ConferenciaArea) ForaDaTolerancia() bool {
+	return c.Declarada > 0 && math.Abs(c.Diferenca) > ToleranciaAreaPercentual
+}
+
+// Texto resume a conferência (ex.: "limite 8,2% maior que a área declarada")
+func (c ConferenciaArea) Texto() string {
+	if c.Declarada <= 0 {
+		return "sem área declarada para comparar"
+	}
+	comparacao := "maior"
+	if c.Diferenca < 0 {
+		comparacao = "menor"
+	}
+	return fmt.Sprintf("limite %s%% %s que a área declarada", FormatarDecimal(math.Abs(c.Diferenca), 1), comparacao)
+}
+
+// PoligonoGeoJSON serializa o anel como geometria GeoJSON Polygon, fechada
+// e no sentido anti-horário (RFC 7946)
+func PoligonoGeoJSON(anel []models.Ponto) string {
+	if len(anel) == 0 {
+		return ""
+	}
+	ordenado := append([]models.Ponto{}, anel...)
+	if areaAssinada(ordenado) < 0 {
+		for i, j := 0, len(ordenado)-1; i < j; i, j = i+1, j-1 {
+			ordenado[i], ordenado[j] = ordenado[j], ordenado[i]
+		}
+	}
+	coordenadas := make([][2]float64, 0, len(ordenado)+1)
+	for _, p := range append(ordenado, ordenado[0]) {
+		coordenadas = append(coordenadas, [2]float64{arredondarGrau(p.Longitude), arredondarGrau(p.Latitude)})
+	}
+	b, _ := json.Marshal(map[string]any{"type": "Polygon", "coordinates": [][][2]float64{coordenadas}})
+	return string(b)
+}
+
+// arredondarGrau limita a 7 casas decimais (~1 cm)
+func arredondarGrau(v float64) float64 {
+	return math.Round(v*1e7) / 1e7
+}
+
+// LerPoligonoGeoJSON lê o anel externo de uma geometria GeoJSON Polygon
+func LerPoligonoGeoJSON(texto string) ([]models.Ponto, error) {
+	if strings.TrimSpace(texto) == "" {
+		return nil, nil
+	}
+	var geometria struct {
+		Type        string         `json:"type"`
+		Coordinates [][][2]float64 `json:"coordinates"`
+	}
+	if err := json.Unmarshal([]byte(texto), &geometria); err != nil {
+		return nil, err
+	}
+	if geometria.Type != "Polygon" || len(geometria.Coordinates) == 0 {
+		return nil, errors.New("geometria não é um polígono")
+	}
+	anel := geometria.Coordinates[0]
+	if len(anel) > 1 && anel[0] == anel[len(anel)-1] {
+		anel = anel[:len(anel)-1]
+	}
+	pontos := make([]models.Ponto, 0, len(anel))
+	for _, c := range anel {
+		pontos = append(pontos, models.Ponto{Latitude: c[1], Longitude: c[0]})
+	}
+	return pontos, nil
+}
+
+// DesenhoSVG é o contorno do limite, dos talhões e a posição da sede em
+// coordenadas de tela, para a pré-visualização sem mapa de fundo
+type DesenhoSVG struct {
+	Largura  float64       `json:"largura"`
+	Altura   float64       `json:"altura"`
+	Contorno string        `json:"contorno"` // atributo points do <polygon>
+	Talhoes  []ContornoSVG `json:"talhoes"`
+	SedeX    float64       `json:"sede_x"`
+	SedeY    float64       `json:"sede_y"`
+	TemSede  bool          `json:"tem_sede"`
+}
+
+// ContornoSVG é um polígono nomeado com o ponto para o rótulo (centro da
+// caixa envolvente)
+type ContornoSVG struct {
+	Nome    string  `json:"nome"`
+	Pontos  string  `json:"pontos"`
+	RotuloX float64 `json:"rotulo_x"`
+	RotuloY float64 `json:"rotulo_y"`
+}
+
+// PoligonoNomeado associa um anel ao nome exibido no desenho
+type PoligonoNomeado struct {
+	Nome string
+	Anel []models.Ponto
+}
+
+// DesenharPropriedade enquadra o limite, os talhões e a sede em um quadro de
+// largura x altura pixels com margem, em projeção equiretangular local
+func DesenharPropriedade(anel []models.Ponto, talhoes []PoligonoNomeado, sede *models.Ponto, largura, altura float64) DesenhoSVG {
+	d := DesenhoSVG{Largura: largura, Altura: altura}
+	pontos := append([]models.Ponto{}, anel...)
+	for _, t := range talhoes {
+		pontos = append(pontos, t.Anel...)
+	}
+	if sede != nil {
+		pontos = append(pontos, *sede)
+	}
+	if len(pontos) == 0 {
+		return d
+	}
+
+	minLat, maxLat := pontos[0].Latitude, pontos[0].Latitude
+	minLon, maxLon := pontos[0].Longitude, pontos[0].Longitude
+	for _, p := range pontos {
+		minLat, maxLat = math.Min(minLat, p.Latitude), math.Max(maxLat, p.Latitude)
+		minLon, maxLon = math.Min(minLon, p.Longitude), math.Max(maxLon, p.Longitude)
+	}
+	fatorLon := math.Cos((minLat + maxLat) / 2 * math.Pi / 180)
+	extX := (maxLon - minLon) * fatorLon
+	extY := maxLat - minLat
+	margem := 12.0
+	escala := 1.0
+	if extX > 0 || extY > 0 {
+		escala = math.Min((largura-2*margem)/math.Max(extX, 1e-12), (altura-2*margem)/math.Max(extY, 1e-12))
+	}
+	desX := (largura - extX*escala) / 2
+	desY := (altura - extY*escala) / 2
+	tela := func(p models.Ponto) (float64, float64) {
+		return desX + (p.Longitude-minLon)*fatorLon*escala, desY + (maxLat-p.Latitude)*escala
+	}
+	contorno := func(anel []models.Ponto) (string, float64, float64) {
+		var b strings.Builder
+		x0, y0, x1, y1 := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+		for i, p := range anel {
+			x, y := tela(p)
+			x0, y0, x1, y1 = math.Min(x0, x), math.Min(y0, y), math.Max(x1, x), math.Max(y1, y)
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			fmt.Fprintf(&b, "%.1f,%.1f", x, y)
+		}
+		return b.String(), (x0 + x1) / 2, (y0 + y1) / 2
+	}
+
+	d.Contorno, _, _ = contorno(anel)
+	for _, t := range talhoes {
+		c := ContornoSVG{Nome: t.Nome}
+		c.Pontos, c.RotuloX, c.RotuloY = contorno(t.Anel)
+		d.Talhoes = append(d.Talhoes, c)
+	}
+	if sede != nil {
+		d.SedeX, d.SedeY = tela(*sede)
+		d.TemSede = true
+	}
+	return d
+}
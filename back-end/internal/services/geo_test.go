@@ -0,0 +1,69 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+func TestParaUTM(t *testing.T) {
+	// Coordenadas de referência calculadas pela série de Krüger até n⁶
+	// (Karney, 2011) no elipsoide GRS80, com erro submilimétrico no fuso
+	casos := []struct {
+		nome  string
+		ponto models.Ponto
+		utm   UTM
+	}{
+		{"Brasília", models.Ponto{Latitude: -15.7801, Longitude: -47.9292}, UTM{23, true, 186142.5171, 8253205.3296}},
+		{"São Paulo", models.Ponto{Latitude: -23.5505, Longitude: -46.6333}, UTM{23, true, 333287.9151, 7394588.3186}},
+		{"Porto Alegre", models.Ponto{Latitude: -30.0346, Longitude: -51.2177}, UTM{22, true, 479010.6010, 6677360.7185}},
+		{"Boa Vista, hemisfério norte", models.Ponto{Latitude: 2.8235, Longitude: -60.6758}, UTM{20, false, 758384.4042, 312342.3811}},
+		{"a 2,95° do meridiano central", models.Ponto{Latitude: -10, Longitude: -47.95}, UTM{23, true, 176558.0348, 8893140.6622}},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			u := ParaUTM(c.ponto)
+			if u.Fuso != c.utm.Fuso || u.Sul != c.utm.Sul {
+				t.Fatalf("fuso %d sul %v, esperado %d sul %v", u.Fuso, u.Sul, c.utm.Fuso, c.utm.Sul)
+			}
+			if math.Abs(u.Leste-c.utm.Leste) > 0.01 || math.Abs(u.Norte-c.utm.Norte) > 0.01 {
+				t.Errorf("E %.4f N %.4f, esperado E %.4f N %.4f", u.Leste, u.Norte, c.utm.Leste, c.utm.Norte)
+			}
+			// Ida e volta: 1e-7° é cerca de 1 cm
+			p := DeUTM(c.utm)
+			if math.Abs(p.Latitude-c.ponto.Latitude) > 1e-7 || math.Abs(p.Longitude-c.ponto.Longitude) > 1e-7 {
+				t.Errorf("DeUTM = %.8f, %.8f; esperado %.8f, %.8f", p.Latitude, p.Longitude, c.ponto.Latitude, c.ponto.Longitude)
+			}
+		})
+	}
+}
+
+func TestLerPonto(t *testing.T) {
+	brasilia := models.Ponto{Latitude: -15.7801, Longitude: -47.9292}
+	casos := []struct {
+		texto   string
+		formato string
+	}{
+		{"-15.7801, -47.9292", FormatoDecimal},
+		{"-15,7801; -47,9292", FormatoDecimal},
+		{`15°46'48.36"S 47°55'45.12"O`, FormatoGMS},
+		{"23L 186142.517 8253205.330", FormatoUTM},
+		{"UTM 23 S E 186142,517 N 8253205,330", FormatoUTM},
+	}
+	for _, c := range casos {
+		p, formato, err := LerPonto(c.texto)
+		if err != nil || formato != c.formato {
+			t.Errorf("%q: formato %q, erro %v; esperado %q", c.texto, formato, err, c.formato)
+			continue
+		}
+		if math.Abs(p.Latitude-brasilia.Latitude) > 1e-7 || math.Abs(p.Longitude-brasilia.Longitude) > 1e-7 {
+			t.Errorf("%q = %.8f, %.8f", c.texto, p.Latitude, p.Longitude)
+		}
+	}
+	for _, texto := range []string{"", "61S 186142 8253205", "23L 50000 8253205", "-15.78"} {
+		if _, _, err := LerPonto(texto); err == nil {
+			t.Errorf("%q aceito", texto)
+		}
+	}
+}
@@ -0,0 +1,825 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// Formatos de arquivo de limites aceitos na importação
+const (
+	FormatoKML       = "KML"
+	FormatoKMZ       = "KMZ"
+	FormatoGeoJSON   = "GeoJSON"
+	FormatoShapefile = "Shapefile"
+)
+
+// Destinos de uma feição importada
+const (
+	DestinoIgnorar     = "ignorar"
+	DestinoPropriedade = "propriedade"
+	DestinoNovoTalhao  = "novo"
+	// Talhão existente: "talhao:<id>"
+	prefixoDestinoTalhao = "talhao:"
+)
+
+// Projecao é o sistema de coordenadas do arquivo: geográfico (graus) ou
+// UTM SIRGAS 2000 em um fuso
+type Projecao struct {
+	UTM  bool
+	Fuso int
+	Sul  bool
+}
+
+// String descreve a projeção (ex.: "SIRGAS 2000 / UTM 22S")
+func (p Projecao) String() string {
+	if !p.UTM {
+		return "SIRGAS 2000 geográfico (graus)"
+	}
+	hemisferio := "N"
+	if p.Sul {
+		hemisferio = "S"
+	}
+	return fmt.Sprintf("SIRGAS 2000 / UTM %d%s", p.Fuso, hemisferio)
+}
+
+// converter leva um par x/y do arquivo para graus decimais
+func (p Projecao) converter(x, y float64) models.Ponto {
+	if p.UTM {
+		return DeUTM(UTM{Fuso: p.Fuso, Sul: p.Sul, Leste: x, Norte: y})
+	}
+	return models.Ponto{Latitude: y, Longitude: x}
+}
+
+// LerFuso interpreta um fuso UTM informado pelo usuário (ex.: "22S", "23",
+// "24 N"); sem hemisfério considera o sul
+func LerFuso(texto string) (Projecao, error) {
+	s := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(texto), " ", ""))
+	if s == "" {
+		return Projecao{}, errors.New("fuso não informado")
+	}
+	sul := true
+	if strings.HasSuffix(s, "N") || strings.HasSuffix(s, "S") {
+		sul = strings.HasSuffix(s, "S")
+		s = s[:len(s)-1]
+	}
+	fuso, err := strconv.Atoi(s)
+	if err != nil || fuso < 1 || fuso > 60 {
+		return Projecao{}, fmt.Errorf("fuso UTM %q inválido", texto)
+	}
+	return Projecao{UTM: true, Fuso: fuso, Sul: sul}, nil
+}
+
+// FeicaoImportada é um polígono lido do arquivo, já em graus decimais
+type FeicaoImportada struct {
+	Indice int            `json:"indice"`
+	Camada string         `json:"camada"`
+	Nome   string         `json:"nome"`
+	Anel   []models.Ponto `json:"anel"`
+	AreaHa float64        `json:"area_ha"`
+	Avisos []string       `json:"avisos"`
+	Erro   string         `json:"erro"` // motivo da rejeição; vazio quando válida
+}
+
+// Valida indica se a feição pode ser gravada
+func (f FeicaoImportada) Valida() bool {
+	return f.Erro == ""
+}
+
+// GeoJSON serializa o anel validado para o formulário de confirmação
+func (f FeicaoImportada) GeoJSON() string {
+	return PoligonoGeoJSON(f.Anel)
+}
+
+// ImportacaoGeo é o resultado da leitura de um arquivo de limites
+type ImportacaoGeo struct {
+	Formato  string            `json:"formato"`
+	Projecao string            `json:"projecao"`
+	Feicoes  []FeicaoImportada `json:"feicoes"`
+}
+
+// Validas conta as feições sem erro
+func (imp ImportacaoGeo) Validas() int {
+	n := 0
+	for _, f := range imp.Feicoes {
+		if f.Valida() {
+			n++
+		}
+	}
+	return n
+}
+
+// poligonoLido é um polígono ainda nas coordenadas do arquivo
+type poligonoLido struct {
+	externo [][2]float64
+	furos   int
+}
+
+// feicaoLida agrupa os polígonos de uma feição antes da reprojeção
+type feicaoLida struct {
+	camada    string
+	nome      string
+	poligonos []poligonoLido
+	erro      string
+}
+
+// ImportarGeometria lê limites em KML, KMZ, GeoJSON ou Shapefile compactado
+// (.zip com .shp, .dbf e .prj). Coordenadas em metros sem projeção
+// declarada usam o fuso informado. Feições inválidas ficam com Erro
+// preenchido; o erro retornado indica arquivo ilegível ou sem polígonos.
+func ImportarGeometria(nomeArquivo string, conteudo []byte, fuso string) (ImportacaoGeo, error) {
+	var imp ImportacaoGeo
+	var lidas []feicaoLida
+	var projecao *Projecao
+	var err error
+
+	extensao := strings.ToLower(path.Ext(nomeArquivo))
+	switch {
+	case extensao == ".kml" || (extensao != ".zip" && extensao != ".kmz" && bytes.Contains(conteudo[:min(len(conteudo), 512)], []byte("<kml"))):
+		imp.Formato = FormatoKML
+		lidas, err = lerKML(bytes.NewReader(conteudo))
+		projecao = &Projecao{}
+	case extensao == ".geojson" || extensao == ".json":
+		imp.Formato = FormatoGeoJSON
+		lidas, projecao, err = lerGeoJSON(conteudo)
+	case extensao == ".kmz" || extensao == ".zip":
+		var arquivos map[string][]byte
+		arquivos, err = descompactar(conteudo)
+		if err != nil {
+			break
+		}
+		if kml := arquivoComExtensao(arquivos, ".kml"); kml != "" {
+			imp.Formato = FormatoKMZ
+			lidas, err = lerKML(bytes.NewReader(arquivos[kml]))
+			projecao = &Projecao{}
+		} else {
+			imp.Formato = FormatoShapefile
+			lidas, projecao, err = lerShapefiles(arquivos)
+		}
+	default:
+		return imp, errors.New("formato não reconhecido: envie .kml, .kmz, .geojson ou Shapefile em .zip")
+	}
+	if err != nil {
+		return imp, err
+	}
+	if len(lidas) == 0 {
+		return imp, errors.New("o arquivo não contém polígonos")
+	}
+
+	// Sem projeção declarada, coordenadas fora do intervalo de graus são UTM
+	if projecao == nil {
+		projecao = &Projecao{}
+		if emMetros(lidas) {
+			p, err := LerFuso(fuso)
+			if err != nil {
+				return imp, errors.New("as coordenadas estão em metros (UTM) e o arquivo não informa a projeção: informe o fuso SIRGAS 2000")
+			}
+			projecao = &p
+		}
+	}
+	imp.Projecao = projecao.String()
+
+	for i, l := range lidas {
+		imp.Feicoes = append(imp.Feicoes, montarFeicao(i, l, *projecao))
+	}
+	return imp, nil
+}
+
+// montarFeicao reprojeta e valida a feição. Multipolígonos usam o maior
+// polígono e furos são desconsiderados, com aviso em ambos os casos.
+func montarFeicao(indice int, l feicaoLida, projecao Projecao) FeicaoImportada {
+	f := FeicaoImportada{Indice: indice, Camada: l.camada, Nome: l.nome}
+	if f.Nome == "" {
+		f.Nome = fmt.Sprintf("Feição %d", indice+1)
+	}
+	if l.erro != "" {
+		f.Erro = l.erro
+		return f
+	}
+	if len(l.poligonos) == 0 {
+		f.Erro = "feição sem polígono (ponto ou linha)"
+		return f
+	}
+
+	var maior []models.Ponto
+	maiorArea := -1.0
+	furos := 0
+	for _, p := range l.poligonos {
+		anel := make([]models.Ponto, 0, len(p.externo))
+		for _, c := range p.externo {
+			anel = append(anel, projecao.converter(c[0], c[1]))
+		}
+		if area := AreaGeodesica(anel); area > maiorArea {
+			maior, maiorArea = anel, area
+		}
+		furos += p.furos
+	}
+	if len(l.poligonos) > 1 {
+		f.Avisos = append(f.Avisos, fmt.Sprintf("multipolígono: usado o maior de %d polígonos", len(l.poligonos)))
+	}
+	if furos > 0 {
+		f.Avisos = append(f.Avisos, fmt.Sprintf("%d área(s) interna(s) excluída(s) não descontada(s)", furos))
+	}
+
+	anel, err := ValidarPoligono(maior)
+	if err != nil {
+		f.Erro = err.Error()
+		return f
+	}
+	for _, p := range anel {
+		if !NoBrasil(p) {
+			f.Erro = "coordenadas fora do território brasileiro; confira a projeção ou o fuso"
+			return f
+		}
+	}
+	f.Anel = anel
+	f.AreaHa = AreaGeodesica(anel)
+	return f
+}
+
+// emMetros indica coordenadas que não cabem em graus
+func emMetros(lidas []feicaoLida) bool {
+	for _, l := range lidas {
+		for _, p := range l.poligonos {
+			for _, c := range p.externo {
+				if math.Abs(c[0]) > 180 || math.Abs(c[1]) > 90 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func descompactar(conteudo []byte) (map[string][]byte, error) {
+	leitor, err := zip.NewReader(bytes.NewReader(conteudo), int64(len(conteudo)))
+	if err != nil {
+		return nil, errors.New("arquivo compactado inválido")
+	}
+	arquivos := make(map[string][]byte)
+	for _, z := range leitor.File {
+		if z.FileInfo().IsDir() || strings.HasPrefix(path.Base(z.Name), ".") || strings.HasPrefix(z.Name, "__MACOSX") {
+			continue
+		}
+		a, err := z.Open()
+		if err != nil {
+			return nil, err
+		}
+		dados, err := io.ReadAll(io.LimitReader(a, 50<<20))
+		a.Close()
+		if err != nil {
+			return nil, err
+		}
+		arquivos[z.Name] = dados
+	}
+	return arquivos, nil
+}
+
+// arquivoComExtensao retorna o primeiro nome (em ordem alfabética) com a extensão
+func arquivoComExtensao(arquivos map[string][]byte, extensao string) string {
+	nomes := make([]string, 0, len(arquivos))
+	for nome := range arquivos {
+		if strings.EqualFold(path.Ext(nome), extensao) {
+			nomes = append(nomes, nome)
+		}
+	}
+	sort.Strings(nomes)
+	if len(nomes) == 0 {
+		return ""
+	}
+	return nomes[0]
+}
+
+// lerKML percorre os Placemark do documento (em qualquer nível de Folder)
+// e lê os polígonos, inclusive dentro de MultiGeometry. KML é sempre
+// WGS 84 em longitude,latitude[,altitude].
+func lerKML(r io.Reader) ([]feicaoLida, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var lidas []feicaoLida
+	var atual *feicaoLida
+	var pasta []string
+	var poligono *poligonoLido
+	externo := false
+	var texto strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("KML inválido: %v", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			texto.Reset()
+			switch t.Name.Local {
+			case "Folder", "Document":
+				pasta = append(pasta, "")
+			case "Placemark":
+				atual = &feicaoLida{}
+				if len(pasta) > 0 {
+					atual.camada = pasta[len(pasta)-1]
+				}
+			case "Polygon":
+				if atual != nil {
+					poligono = &poligonoLido{}
+				}
+			case "outerBoundaryIs":
+				externo = true
+			}
+		case xml.CharData:
+			texto.Write(t)
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "name":
+				nome := strings.TrimSpace(texto.String())
+				if atual != nil {
+					atual.nome = nome
+				} else if len(pasta) > 0 {
+					pasta[len(pasta)-1] = nome
+				}
+			case "Folder", "Document":
+				if len(pasta) > 0 {
+					pasta = pasta[:len(pasta)-1]
+				}
+			case "outerBoundaryIs":
+				externo = false
+			case "innerBoundaryIs":
+				if poligono != nil {
+					poligono.furos++
+				}
+			case "coordinates":
+				if poligono != nil && externo {
+					coordenadas, err := lerCoordenadasKML(texto.String())
+					if err != nil && atual.erro == "" {
+						atual.erro = err.Error()
+					}
+					poligono.externo = coordenadas
+				}
+			case "Polygon":
+				if atual != nil && poligono != nil {
+					atual.poligonos = append(atual.poligonos, *poligono)
+				}
+				poligono = nil
+			case "Placemark":
+				if atual != nil && (len(atual.poligonos) > 0 || atual.erro != "") {
+					lidas = append(lidas, *atual)
+				}
+				atual = nil
+			}
+			texto.Reset()
+		}
+	}
+	return lidas, nil
+}
+
+func lerCoordenadasKML(texto string) ([][2]float64, error) {
+	var coordenadas [][2]float64
+	for _, tupla := range strings.Fields(texto) {
+		partes := strings.Split(tupla, ",")
+		if len(partes) < 2 {
+			return nil, fmt.Errorf("coordenada KML %q inválida", tupla)
+		}
+		x, errX := strconv.ParseFloat(partes[0], 64)
+		y, errY := strconv.ParseFloat(partes[1], 64)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("coordenada KML %q inválida", tupla)
+		}
+		coordenadas = append(coordenadas, [2]float64{x, y})
+	}
+	return coordenadas, nil
+}
+
+// objetoGeoJSON cobre FeatureCollection, Feature e geometrias
+type objetoGeoJSON struct {
+	Type        string          `json:"type"`
+	Features    []objetoGeoJSON `json:"features"`
+	Geometry    *objetoGeoJSON  `json:"geometry"`
+	Geometries  []objetoGeoJSON `json:"geometries"`
+	Properties  map[string]any  `json:"properties"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	CRS         *struct {
+		Properties struct {
+			Name string `json:"name"`
+		} `json:"properties"`
+	} `json:"crs"`
+}
+
+// lerGeoJSON lê o arquivo; o membro crs (GeoJSON 2008) é respeitado quando
+// indica um código EPSG SIRGAS 2000 ou WGS 84
+func lerGeoJSON(conteudo []byte) ([]feicaoLida, *Projecao, error) {
+	var raiz objetoGeoJSON
+	if err := json.Unmarshal(conteudo, &raiz); err != nil {
+		return nil, nil, fmt.Errorf("GeoJSON inválido: %v", err)
+	}
+	var projecao *Projecao
+	if raiz.CRS != nil && raiz.CRS.Properties.Name != "" {
+		p, err := projecaoEPSG(raiz.CRS.Properties.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		projecao = &p
+	}
+
+	var feicoes []objetoGeoJSON
+	switch raiz.Type {
+	case "FeatureCollection":
+		feicoes = raiz.Features
+	case "Feature":
+		feicoes = []objetoGeoJSON{raiz}
+	default:
+		feicoes = []objetoGeoJSON{{Type: "Feature", Geometry: &raiz}}
+	}
+
+	var lidas []feicaoLida
+	for _, f := range feicoes {
+		l := feicaoLida{nome: nomeAtributos(f.Properties)}
+		if f.Geometry == nil {
+			l.erro = "feição sem geometria"
+		} else if err := poligonosGeoJSON(*f.Geometry, &l); err != nil {
+			l.erro = err.Error()
+		}
+		lidas = append(lidas, l)
+	}
+	return lidas, projecao, nil
+}
+
+func poligonosGeoJSON(g objetoGeoJSON, l *feicaoLida) error {
+	switch g.Type {
+	case "Polygon":
+		var aneis [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &aneis); err != nil {
+			return errors.New("coordenadas do polígono inválidas")
+		}
+		return adicionarPoligonoGeoJSON(aneis, l)
+	case "MultiPolygon":
+		var poligonos [][][][]float64
+		if err := json.Unmarshal(g.Coordinates, &poligonos); err != nil {
+			return errors.New("coordenadas do multipolígono inválidas")
+		}
+		for _, aneis := range poligonos {
+			if err := adicionarPoligonoGeoJSON(aneis, l); err != nil {
+				return err
+			}
+		}
+	case "GeometryCollection":
+		for _, filha := range g.Geometries {
+			if err := poligonosGeoJSON(filha, l); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func adicionarPoligonoGeoJSON(aneis [][][]float64, l *feicaoLida) error {
+	if len(aneis) == 0 {
+		return errors.New("polígono sem anel externo")
+	}
+	p := poligonoLido{furos: len(aneis) - 1}
+	for _, c := range aneis[0] {
+		if len(c) < 2 {
+			return errors.New("posição com menos de duas coordenadas")
+		}
+		p.externo = append(p.externo, [2]float64{c[0], c[1]})
+	}
+	l.poligonos = append(l.poligonos, p)
+	return nil
+}
+
+var (
+	padraoEPSG    = regexp.MustCompile(`EPSG:{1,2}(\d{4,5})`)
+	padraoZonaPRJ = regexp.MustCompile(`UTM[ _]?ZONE[ _]?(\d{1,2})([NS])`)
+)
+
+// projecaoEPSG traduz os códigos usados no Brasil: SIRGAS 2000 geográfico
+// (4674) e UTM (31965–31985), WGS 84 (4326) e WGS 84 / UTM (326xx e 327xx).
+// SAD 69 e Córrego Alegre exigem transformação de datum e são recusados.
+func projecaoEPSG(nome string) (Projecao, error) {
+	m := padraoEPSG.FindStringSubmatch(strings.ToUpper(nome))
+	if m == nil {
+		if strings.Contains(strings.ToUpper(nome), "CRS84") {
+			return Projecao{}, nil
+		}
+		return Projecao{}, fmt.Errorf("sistema de coordenadas %q não reconhecido", nome)
+	}
+	codigo, _ := strconv.Atoi(m[1])
+	switch {
+	case codigo == 4674 || codigo == 4326:
+		return Projecao{}, nil
+	case codigo >= 31965 && codigo <= 31976:
+		return Projecao{UTM: true, Fuso: codigo - 31965 + 11}, nil
+	case codigo >= 31977 && codigo <= 31985:
+		return Projecao{UTM: true, Fuso: codigo - 31977 + 17, Sul: true}, nil
+	case codigo > 32600 && codigo <= 32660:
+		return Projecao{UTM: true, Fuso: codigo - 32600}, nil
+	case codigo > 32700 && codigo <= 32760:
+		return Projecao{UTM: true, Fuso: codigo - 32700, Sul: true}, nil
+	case codigo == 4618 || (codigo >= 29168 && codigo <= 29195) || codigo == 4225 || (codigo >= 22521 && codigo <= 22525):
+		return Projecao{}, fmt.Errorf("EPSG:%d usa datum SAD 69 ou Córrego Alegre; reprojete o arquivo para SIRGAS 2000", codigo)
+	}
+	return Projecao{}, fmt.Errorf("EPSG:%d não suportado; use SIRGAS 2000 geográfico ou UTM", codigo)
+}
+
+// projecaoPRJ interpreta o WKT do arquivo .prj do Shapefile
+func projecaoPRJ(wkt string) (Projecao, error) {
+	s := strings.ToUpper(wkt)
+	if (strings.Contains(s, "SAD") && strings.Contains(s, "69")) || strings.Contains(s, "CORREGO") {
+		return Projecao{}, errors.New("o Shapefile usa datum SAD 69 ou Córrego Alegre; reprojete para SIRGAS 2000")
+	}
+	if !strings.HasPrefix(strings.TrimSpace(s), "PROJCS") {
+		return Projecao{}, nil
+	}
+	if m := padraoZonaPRJ.FindStringSubmatch(s); m != nil {
+		fuso, _ := strconv.Atoi(m[1])
+		return Projecao{UTM: true, Fuso: fuso, Sul: m[2] == "S"}, nil
+	}
+	return Projecao{}, errors.New("projeção do Shapefile não suportada; use SIRGAS 2000 geográfico ou UTM")
+}
+
+// lerShapefiles lê todas as camadas de polígonos do pacote (o CAR traz uma
+// por tema: imóvel, APP, reserva legal...)
+func lerShapefiles(arquivos map[string][]byte) ([]feicaoLida, *Projecao, error) {
+	var camadas []string
+	for nome := range arquivos {
+		if strings.EqualFold(path.Ext(nome), ".shp") {
+			camadas = append(camadas, strings.TrimSuffix(nome, path.Ext(nome)))
+		}
+	}
+	if len(camadas) == 0 {
+		return nil, nil, errors.New("o arquivo compactado não contém .shp nem .kml")
+	}
+	sort.Strings(camadas)
+
+	var lidas []feicaoLida
+	var projecao *Projecao
+	for _, base := range camadas {
+		complemento := func(extensao string) []byte {
+			for nome, dados := range arquivos {
+				if strings.EqualFold(nome, base+extensao) {
+					return dados
+				}
+			}
+			return nil
+		}
+		camada := path.Base(base)
+
+		var p *Projecao
+		if prj := complemento(".prj"); prj != nil {
+			lida, err := projecaoPRJ(string(prj))
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %v", camada, err)
+			}
+			p = &lida
+		}
+		if projecao != nil && p != nil && *projecao != *p {
+			return nil, nil, errors.New("as camadas do arquivo usam projeções diferentes; envie uma de cada vez")
+		}
+		if p != nil {
+			projecao = p
+		}
+
+		// Camadas de pontos ou linhas aparecem como feição rejeitada
+		geometrias, err := lerSHP(complemento(".shp"))
+		if err != nil {
+			lidas = append(lidas, feicaoLida{camada: camada, nome: camada, erro: err.Error()})
+			continue
+		}
+		atributos, _ := lerDBF(complemento(".dbf"))
+		for i, g := range geometrias {
+			g.camada = camada
+			if i < len(atributos) {
+				g.nome = nomeAtributos(atributos[i])
+			}
+			if g.nome == "" {
+				g.nome = fmt.Sprintf("%s %d", camada, i+1)
+			}
+			lidas = append(lidas, g)
+		}
+	}
+	return lidas, projecao, nil
+}
+
+// lerSHP lê os registros de polígono (tipos 5, 15 e 25). No Shapefile os
+// anéis externos são horários e os furos anti-horários.
+func lerSHP(dados []byte) ([]feicaoLida, error) {
+	if len(dados) < 100 || binary.BigEndian.Uint32(dados[0:4]) != 9994 {
+		return nil, errors.New("arquivo .shp inválido")
+	}
+	tipo := binary.LittleEndian.Uint32(dados[32:36])
+	if tipo != 5 && tipo != 15 && tipo != 25 {
+		return nil, errors.New("a camada não é de polígonos")
+	}
+
+	var lidas []feicaoLida
+	for pos := 100; pos+8 <= len(dados); {
+		tamanho := int(binary.BigEndian.Uint32(dados[pos+4:pos+8])) * 2
+		inicio := pos + 8
+		pos = inicio + tamanho
+		if pos > len(dados) || tamanho < 4 {
+			return nil, errors.New("registro do .shp truncado")
+		}
+		registro := dados[inicio:pos]
+		if binary.LittleEndian.Uint32(registro[0:4]) == 0 {
+			lidas = append(lidas, feicaoLida{erro: "registro sem geometria"})
+			continue
+		}
+		if len(registro) < 44 {
+			return nil, errors.New("registro do .shp truncado")
+		}
+		partes := int(binary.LittleEndian.Uint32(registro[36:40]))
+		pontos := int(binary.LittleEndian.Uint32(registro[40:44]))
+		fimPartes := 44 + 4*partes
+		if partes <= 0 || pontos <= 0 || fimPartes+16*pontos > len(registro) {
+			return nil, errors.New("registro do .shp inválido")
+		}
+		coordenadas := make([][2]float64, pontos)
+		for i := range coordenadas {
+			o := fimPartes + 16*i
+			coordenadas[i] = [2]float64{
+				math.Float64frombits(binary.LittleEndian.Uint64(registro[o : o+8])),
+				math.Float64frombits(binary.LittleEndian.Uint64(registro[o+8 : o+16])),
+			}
+		}
+
+		var l feicaoLida
+		var furos int
+		for i := 0; i < partes; i++ {
+			de := int(binary.LittleEndian.Uint32(registro[44+4*i:]))
+			ate := pontos
+			if i+1 < partes {
+				ate = int(binary.LittleEndian.Uint32(registro[44+4*(i+1):]))
+			}
+			if de < 0 || ate > pontos || de >= ate {
+				return nil, errors.New("partes do polígono inválidas no .shp")
+			}
+			anel := coordenadas[de:ate]
+			if areaPlana(anel) > 0 {
+				furos++
+				continue
+			}
+			l.poligonos = append(l.poligonos, poligonoLido{externo: anel})
+		}
+		if len(l.poligonos) > 0 {
+			l.poligonos[0].furos = furos
+		} else {
+			l.erro = "polígono sem anel externo (sentido horário)"
+		}
+		lidas = append(lidas, l)
+	}
+	return lidas, nil
+}
+
+// areaPlana é a área assinada no plano x/y (positiva anti-horária)
+func areaPlana(anel [][2]float64) float64 {
+	soma := 0.0
+	for i := range anel {
+		a, b := anel[i], anel[(i+1)%len(anel)]
+		soma += a[0]*b[1] - b[0]*a[1]
+	}
+	return soma / 2
+}
+
+// lerDBF lê os atributos texto e numéricos da tabela do Shapefile. O
+// conteúdo que não for UTF-8 é tratado como Latin-1, comum nos arquivos do CAR.
+func lerDBF(dados []byte) ([]map[string]any, error) {
+	if len(dados) < 32 {
+		return nil, errors.New("arquivo .dbf ausente ou inválido")
+	}
+	registros := int(binary.LittleEndian.Uint32(dados[4:8]))
+	tamanhoCabecalho := int(binary.LittleEndian.Uint16(dados[8:10]))
+	tamanhoRegistro := int(binary.LittleEndian.Uint16(dados[10:12]))
+	if tamanhoRegistro < 1 || tamanhoCabecalho < 32 || tamanhoCabecalho > len(dados) {
+		return nil, errors.New("cabeçalho do arquivo .dbf inválido")
+	}
+	// A contagem do cabeçalho não é confiável: vale o que cabe no arquivo
+	if maximo := (len(dados) - tamanhoCabecalho) / tamanhoRegistro; registros > maximo {
+		registros = maximo
+	}
+
+	type campo struct {
+		nome    string
+		tamanho int
+	}
+	var campos []campo
+	for pos := 32; pos+32 <= len(dados) && dados[pos] != 0x0D; pos += 32 {
+		nome := string(bytes.TrimRight(dados[pos:pos+11], "\x00 "))
+		campos = append(campos, campo{nome: nome, tamanho: int(dados[pos+16])})
+	}
+
+	var linhas []map[string]any
+	for i := 0; i < registros; i++ {
+		inicio := tamanhoCabecalho + i*tamanhoRegistro
+		if inicio+tamanhoRegistro > len(dados) {
+			break
+		}
+		registro := dados[inicio : inicio+tamanhoRegistro]
+		linha := make(map[string]any, len(campos))
+		pos := 1 // byte de exclusão
+		for _, c := range campos {
+			if pos+c.tamanho > len(registro) {
+				break
+			}
+			valor := bytes.TrimSpace(registro[pos : pos+c.tamanho])
+			linha[c.nome] = textoLatin1(valor)
+			pos += c.tamanho
+		}
+		linhas = append(linhas, linha)
+	}
+	return linhas, nil
+}
+
+func textoLatin1(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	runas := make([]rune, len(b))
+	for i, c := range b {
+		runas[i] = rune(c)
+	}
+	return string(runas)
+}
+
+// camposNome são os atributos usados como nome da feição, em ordem de preferência
+var camposNome = []string{"nome", "name", "talhao", "talhão", "descricao", "description", "nom_tema", "tema", "cod_imovel", "id"}
+
+func nomeAtributos(atributos map[string]any) string {
+	for _, preferido := range camposNome {
+		for chave, valor := range atributos {
+			if !strings.EqualFold(chave, preferido) {
+				continue
+			}
+			if texto := strings.TrimSpace(fmt.Sprint(valor)); texto != "" && valor != nil {
+				return texto
+			}
+		}
+	}
+	return ""
+}
+
+// DestinoTalhao monta o valor do destino para um talhão existente
+func DestinoTalhao(id int) string {
+	return prefixoDestinoTalhao + strconv.Itoa(id)
+}
+
+// TalhaoDoDestino extrai o id do talhão de um destino, ou 0
+func TalhaoDoDestino(destino string) int {
+	if !strings.HasPrefix(destino, prefixoDestinoTalhao) {
+		return 0
+	}
+	id, _ := strconv.Atoi(strings.TrimPrefix(destino, prefixoDestinoTalhao))
+	return id
+}
+
+// SugerirDestinos propõe o destino de cada feição válida: talhão de mesmo
+// nome, limite da propriedade para a camada do imóvel ou para uma feição
+// única, e novo talhão para as demais
+func SugerirDestinos(feicoes []FeicaoImportada, talhoes []models.Talhao) []string {
+	destinos := make([]string, len(feicoes))
+	validas := 0
+	for _, f := range feicoes {
+		if f.Valida() {
+			validas++
+		}
+	}
+	propriedadeUsada := false
+	for i, f := range feicoes {
+		destinos[i] = DestinoIgnorar
+		if !f.Valida() {
+			continue
+		}
+		destinos[i] = DestinoNovoTalhao
+		for _, t := range talhoes {
+			if chaveProduto(t.Nome) == chaveProduto(f.Nome) {
+				destinos[i] = DestinoTalhao(t.ID)
+			}
+		}
+		if destinos[i] != DestinoNovoTalhao || propriedadeUsada {
+			continue
+		}
+		rotulo := strings.ToUpper(acentos.Replace(f.Camada + " " + f.Nome))
+		if validas == 1 || strings.Contains(rotulo, "IMOVEL") || strings.Contains(rotulo, "PROPRIEDADE") {
+			destinos[i] = DestinoPropriedade
+			propriedadeUsada = true
+		}
+	}
+	return destinos
+}
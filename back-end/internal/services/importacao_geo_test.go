@@ -0,0 +1,60 @@
+package services
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// dbfTeste monta um .dbf com um campo texto de 4 bytes e os registros dados,
+// declarando no cabeçalho a contagem e o tamanho de registro pedidos
+func dbfTeste(declarados uint32, tamanhoRegistro uint16, registros ...string) []byte {
+	cabecalho := make([]byte, 65)
+	cabecalho[0] = 0x03
+	binary.LittleEndian.PutUint32(cabecalho[4:8], declarados)
+	binary.LittleEndian.PutUint16(cabecalho[8:10], 65)
+	binary.LittleEndian.PutUint16(cabecalho[10:12], tamanhoRegistro)
+	copy(cabecalho[32:], "NOME")
+	cabecalho[43] = 'C'
+	cabecalho[48] = 4
+	cabecalho[64] = 0x0D
+	dados := cabecalho
+	for _, r := range registros {
+		dados = append(dados, ' ')
+		dados = append(dados, r...)
+	}
+	return dados
+}
+
+func TestLerDBF(t *testing.T) {
+	casos := []struct {
+		nome     string
+		dados    []byte
+		linhas   int
+		invalido bool
+	}{
+		{"registros completos", dbfTeste(2, 5, "T1  ", "T2  "), 2, false},
+		{"contagem maior que o arquivo", dbfTeste(4_000_000_000, 5, "T1  "), 1, false},
+		{"registro de tamanho zero", dbfTeste(4_000_000_000, 0), 0, true},
+		{"arquivo curto", []byte{0x03, 0, 0}, 0, true},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			linhas, err := lerDBF(c.dados)
+			if c.invalido {
+				if err == nil {
+					t.Fatal("esperava erro de cabeçalho")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(linhas) != c.linhas {
+				t.Fatalf("linhas = %d, esperado %d", len(linhas), c.linhas)
+			}
+			if linhas[0]["NOME"] != "T1" {
+				t.Errorf("NOME = %v, esperado T1", linhas[0]["NOME"])
+			}
+		})
+	}
+}
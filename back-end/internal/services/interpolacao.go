@@ -0,0 +1,507 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"sort"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// Métodos de interpolação dos mapas de fertilidade
+const (
+	InterpolacaoIDW       = "idw"
+	InterpolacaoKrigagem  = "krigagem"
+	potenciaIDW           = 2.0
+	vizinhosInterpolacao  = 16
+	minAmostrasInterpolar = 3
+	minAmostrasKrigagem   = 10
+	maxCelulasMapa        = 40000
+)
+
+// AmostraInterpolacao é o valor medido em uma posição
+type AmostraInterpolacao struct {
+	models.Ponto
+	Valor float64
+}
+
+// PontoVariograma é uma classe de distância do semivariograma experimental
+type PontoVariograma struct {
+	Distancia     float64 `json:"distancia"` // m
+	Semivariancia float64 `json:"semivariancia"`
+	Pares         int     `json:"pares"`
+}
+
+// Variograma é o modelo esférico ajustado, nas unidades do parâmetro
+type Variograma struct {
+	Pepita       float64           `json:"pepita"`
+	Patamar      float64           `json:"patamar"` // pepita + contribuição
+	Alcance      float64           `json:"alcance"` // m
+	Experimental []PontoVariograma `json:"experimental"`
+}
+
+// valor calcula a semivariância do modelo esférico na distância h
+func (v Variograma) valor(h float64) float64 {
+	if h <= 0 {
+		return 0
+	}
+	if h >= v.Alcance {
+		return v.Patamar
+	}
+	r := h / v.Alcance
+	return v.Pepita + (v.Patamar-v.Pepita)*(1.5*r-0.5*r*r*r)
+}
+
+// GrauDependencia é a razão pepita/patamar em %
+func (v Variograma) GrauDependencia() float64 {
+	if v.Patamar == 0 {
+		return 100
+	}
+	return v.Pepita / v.Patamar * 100
+}
+
+// Dependencia classifica a dependência espacial (Cambardella et al., 1994)
+func (v Variograma) Dependencia() string {
+	switch g := v.GrauDependencia(); {
+	case g <= 25:
+		return "forte"
+	case g <= 75:
+		return "moderada"
+	}
+	return "fraca"
+}
+
+// MapaInterpolado é a superfície estimada em uma malha regular de graus,
+// de norte para sul e de oeste para leste, pronta para sobrepor ao mapa
+type MapaInterpolado struct {
+	Metodo       string      `json:"metodo"`
+	Resolucao    float64     `json:"resolucao"` // lado aproximado da célula, m
+	Oeste        float64     `json:"oeste"`
+	Sul          float64     `json:"sul"`
+	Leste        float64     `json:"leste"`
+	Norte        float64     `json:"norte"`
+	Colunas      int         `json:"colunas"`
+	Linhas       int         `json:"linhas"`
+	Valores      []float64   `json:"-"` // NaN fora do talhão
+	AreaCelulaHa float64     `json:"area_celula_ha"`
+	Amostras     int         `json:"amostras"`
+	Variograma   *Variograma `json:"variograma,omitempty"`
+	ErroMedio    float64     `json:"erro_medio"` // RMSE da validação cruzada
+	Avisos       []string    `json:"avisos"`
+}
+
+// Valor devolve a estimativa da célula (linha, coluna) e se ela está no talhão
+func (m MapaInterpolado) Valor(linha, coluna int) (float64, bool) {
+	v := m.Valores[linha*m.Colunas+coluna]
+	return v, !math.IsNaN(v)
+}
+
+// Centro devolve a posição do centro da célula
+func (m MapaInterpolado) Centro(linha, coluna int) models.Ponto {
+	passoLat := (m.Norte - m.Sul) / float64(m.Linhas)
+	passoLon := (m.Leste - m.Oeste) / float64(m.Colunas)
+	return models.Ponto{
+		Latitude:  m.Norte - (float64(linha)+0.5)*passoLat,
+		Longitude: m.Oeste + (float64(coluna)+0.5)*passoLon,
+	}
+}
+
+// Estatisticas resume as células do talhão: mínimo, máximo e média
+func (m MapaInterpolado) Estatisticas() (float64, float64, float64) {
+	minimo, maximo, soma, n := math.Inf(1), math.Inf(-1), 0.0, 0
+	for _, v := range m.Valores {
+		if math.IsNaN(v) {
+			continue
+		}
+		minimo, maximo = math.Min(minimo, v), math.Max(maximo, v)
+		soma += v
+		n++
+	}
+	if n == 0 {
+		return 0, 0, 0
+	}
+	return minimo, maximo, soma / float64(n)
+}
+
+// estimador calcula o valor em (x, y) a partir das amostras vizinhas
+type estimador func(x, y float64, vizinhos []int) (float64, bool)
+
+// amostraPlana é a amostra projetada em metros
+type amostraPlana struct {
+	x, y, valor float64
+}
+
+// vizinhosMaisProximos devolve os índices das k amostras mais próximas,
+// ignorando a de índice excluir (validação cruzada)
+func vizinhosMaisProximos(amostras []amostraPlana, x, y float64, k, excluir int) []int {
+	type candidato struct {
+		indice    int
+		distancia float64
+	}
+	var melhores []candidato
+	for i, a := range amostras {
+		if i == excluir {
+			continue
+		}
+		d := math.Hypot(a.x-x, a.y-y)
+		if len(melhores) == k && d >= melhores[k-1].distancia {
+			continue
+		}
+		pos := sort.Search(len(melhores), func(j int) bool { return melhores[j].distancia > d })
+		melhores = append(melhores, candidato{})
+		copy(melhores[pos+1:], melhores[pos:])
+		melhores[pos] = candidato{i, d}
+		if len(melhores) > k {
+			melhores = melhores[:k]
+		}
+	}
+	indices := make([]int, len(melhores))
+	for i, c := range melhores {
+		indices[i] = c.indice
+	}
+	return indices
+}
+
+func estimadorIDW(amostras []amostraPlana) estimador {
+	return func(x, y float64, vizinhos []int) (float64, bool) {
+		var soma, pesos float64
+		for _, i := range vizinhos {
+			d := math.Hypot(amostras[i].x-x, amostras[i].y-y)
+			if d < 0.01 {
+				return amostras[i].valor, true
+			}
+			p := 1 / math.Pow(d, potenciaIDW)
+			soma += p * amostras[i].valor
+			pesos += p
+		}
+		return soma / pesos, pesos > 0
+	}
+}
+
+// estimadorKrigagem resolve o sistema da krigagem ordinária com os vizinhos
+// mais próximos; devolve false quando o sistema é singular (amostras
+// coincidentes), para que a célula use o IDW
+func estimadorKrigagem(amostras []amostraPlana, v Variograma) estimador {
+	return func(x, y float64, vizinhos []int) (float64, bool) {
+		n := len(vizinhos)
+		m := make([][]float64, n+1)
+		b := make([]float64, n+1)
+		for i, a := range vizinhos {
+			m[i] = make([]float64, n+1)
+			for j, c := range vizinhos {
+				m[i][j] = v.valor(math.Hypot(amostras[a].x-amostras[c].x, amostras[a].y-amostras[c].y))
+			}
+			m[i][n] = 1
+			b[i] = v.valor(math.Hypot(amostras[a].x-x, amostras[a].y-y))
+		}
+		m[n] = make([]float64, n+1)
+		for j := 0; j < n; j++ {
+			m[n][j] = 1
+		}
+		b[n] = 1
+		pesos, ok := resolverSistemaLinear(m, b)
+		if !ok {
+			return 0, false
+		}
+		soma := 0.0
+		for i, a := range vizinhos {
+			soma += pesos[i] * amostras[a].valor
+		}
+		return soma, true
+	}
+}
+
+// ajustarVariograma calcula o semivariograma experimental em 12 classes de
+// distância até metade da maior distância entre amostras e ajusta o modelo
+// esférico por mínimos quadrados ponderados (pesos N/h²), varrendo o alcance
+func ajustarVariograma(amostras []amostraPlana) (Variograma, error) {
+	maxDist := 0.0
+	for i := range amostras {
+		for j := i + 1; j < len(amostras); j++ {
+			maxDist = math.Max(maxDist, math.Hypot(amostras[i].x-amostras[j].x, amostras[i].y-amostras[j].y))
+		}
+	}
+	const classes = 12
+	limite := maxDist / 2
+	if limite == 0 {
+		return Variograma{}, errors.New("amostras sem distância entre si")
+	}
+	passo := limite / classes
+	soma := make([]float64, classes)
+	distancia := make([]float64, classes)
+	pares := make([]int, classes)
+	for i := range amostras {
+		for j := i + 1; j < len(amostras); j++ {
+			h := math.Hypot(amostras[i].x-amostras[j].x, amostras[i].y-amostras[j].y)
+			if h == 0 || h > limite {
+				continue
+			}
+			k := min(int(h/passo), classes-1)
+			d := amostras[i].valor - amostras[j].valor
+			soma[k] += d * d / 2
+			distancia[k] += h
+			pares[k]++
+		}
+	}
+	var v Variograma
+	for k := range soma {
+		if pares[k] < 3 {
+			continue
+		}
+		v.Experimental = append(v.Experimental, PontoVariograma{
+			Distancia: distancia[k] / float64(pares[k]), Semivariancia: soma[k] / float64(pares[k]), Pares: pares[k],
+		})
+	}
+	if len(v.Experimental) < 3 {
+		return v, errors.New("pares de amostras insuficientes para o semivariograma")
+	}
+
+	melhorErro := math.Inf(1)
+	primeira, ultima := v.Experimental[0].Distancia, v.Experimental[len(v.Experimental)-1].Distancia
+	for passoAlcance := 0; passoAlcance <= 60; passoAlcance++ {
+		alcance := primeira + (ultima*1.5-primeira)*float64(passoAlcance)/60
+		// γ = c0 + c·esf(h/a) é linear em c0 e c para um alcance fixo
+		var s11, s12, s22, t1, t2 float64
+		for _, e := range v.Experimental {
+			w := float64(e.Pares) / (e.Distancia * e.Distancia)
+			f := Variograma{Pepita: 0, Patamar: 1, Alcance: alcance}.valor(e.Distancia)
+			s11 += w
+			s12 += w * f
+			s22 += w * f * f
+			t1 += w * e.Semivariancia
+			t2 += w * e.Semivariancia * f
+		}
+		pepita, contribuicao := 0.0, 0.0
+		if det := s11*s22 - s12*s12; det != 0 {
+			pepita = (t1*s22 - t2*s12) / det
+			contribuicao = (s11*t2 - s12*t1) / det
+		}
+		if pepita < 0 {
+			pepita, contribuicao = 0, t2/s22
+		}
+		if contribuicao < 0 {
+			pepita, contribuicao = t1/s11, 0
+		}
+		modelo := Variograma{Pepita: pepita, Patamar: pepita + contribuicao, Alcance: alcance}
+		erro := 0.0
+		for _, e := range v.Experimental {
+			d := e.Semivariancia - modelo.valor(e.Distancia)
+			erro += float64(e.Pares) / (e.Distancia * e.Distancia) * d * d
+		}
+		if erro < melhorErro {
+			melhorErro = erro
+			v.Pepita, v.Patamar, v.Alcance = modelo.Pepita, modelo.Patamar, modelo.Alcance
+		}
+	}
+	return v, nil
+}
+
+// InterpolarTalhao estima o valor em cada célula do talhão por IDW (potência
+// 2) ou krigagem ordinária com modelo esférico, ambos com os 16 vizinhos
+// mais próximos. Com resolução zero, a célula é escolhida para que o
+// talhão tenha até 40 mil células (mínimo de 5 m). A krigagem exige 10
+// amostras e cai para o IDW quando não há estrutura espacial. O erro médio
+// vem da validação cruzada (deixa-um-fora).
+func InterpolarTalhao(anel []models.Ponto, amostras []AmostraInterpolacao, metodo string, resolucao float64) (MapaInterpolado, error) {
+	m := MapaInterpolado{Metodo: metodo, Amostras: len(amostras)}
+	if len(anel) < 3 {
+		return m, errors.New("o talhão não tem limite cadastrado")
+	}
+	if len(amostras) < minAmostrasInterpolar {
+		return m, fmt.Errorf("são necessárias ao menos %d amostras georreferenciadas", minAmostrasInterpolar)
+	}
+	if metodo != InterpolacaoKrigagem {
+		m.Metodo = InterpolacaoIDW
+	}
+
+	plano := projetarPlano(anel)
+	planas := make([]amostraPlana, len(amostras))
+	var media, variancia float64
+	for i, a := range amostras {
+		u := ParaUTMNoFuso(a.Ponto, plano.fuso)
+		planas[i] = amostraPlana{u.Leste, u.Norte, a.Valor}
+		media += a.Valor
+	}
+	media /= float64(len(amostras))
+	for _, a := range amostras {
+		variancia += (a.Valor - media) * (a.Valor - media)
+	}
+	variancia /= float64(len(amostras) - 1)
+
+	idw := estimadorIDW(planas)
+	estimar := idw
+	if m.Metodo == InterpolacaoKrigagem {
+		switch {
+		case len(amostras) < minAmostrasKrigagem:
+			m.Avisos = append(m.Avisos, fmt.Sprintf("Krigagem exige ao menos %d amostras; usado IDW.", minAmostrasKrigagem))
+			m.Metodo = InterpolacaoIDW
+		case variancia == 0:
+			m.Avisos = append(m.Avisos, "Todas as amostras têm o mesmo valor; usado IDW.")
+			m.Metodo = InterpolacaoIDW
+		default:
+			// O ajuste e o sistema usam valores padronizados (variância 1)
+			// para que parâmetros de ordem 0,01 como o K não fiquem mal
+			// condicionados; a estimativa volta à escala original
+			desvio := math.Sqrt(variancia)
+			padronizadas := make([]amostraPlana, len(planas))
+			for i, a := range planas {
+				padronizadas[i] = amostraPlana{a.x, a.y, (a.valor - media) / desvio}
+			}
+			v, err := ajustarVariograma(padronizadas)
+			switch {
+			case err != nil:
+				m.Avisos = append(m.Avisos, "Semivariograma não ajustado ("+err.Error()+"); usado IDW.")
+				m.Metodo = InterpolacaoIDW
+			case v.Patamar == v.Pepita:
+				m.Avisos = append(m.Avisos, "Efeito pepita puro: sem dependência espacial entre as amostras; usado IDW.")
+				m.Metodo = InterpolacaoIDW
+			default:
+				krigagem := estimadorKrigagem(padronizadas, v)
+				estimar = func(x, y float64, vizinhos []int) (float64, bool) {
+					z, ok := krigagem(x, y, vizinhos)
+					return media + z*desvio, ok
+				}
+				escala := variancia
+				v.Pepita *= escala
+				v.Patamar *= escala
+				for i := range v.Experimental {
+					v.Experimental[i].Semivariancia *= escala
+				}
+				m.Variograma = &v
+			}
+		}
+	}
+	valorEm := func(x, y float64, excluir int) float64 {
+		vizinhos := vizinhosMaisProximos(planas, x, y, vizinhosInterpolacao, excluir)
+		if v, ok := estimar(x, y, vizinhos); ok {
+			return math.Max(v, 0)
+		}
+		v, _ := idw(x, y, vizinhos)
+		return math.Max(v, 0)
+	}
+
+	// Validação cruzada
+	var quadrados float64
+	for i, a := range planas {
+		d := valorEm(a.x, a.y, i) - a.valor
+		quadrados += d * d
+	}
+	m.ErroMedio = math.Sqrt(quadrados / float64(len(planas)))
+
+	// Malha em graus com células de aproximadamente "resolucao" metros
+	area := AreaGeodesica(anel) * 10000
+	if resolucao <= 0 {
+		resolucao = math.Max(5, math.Ceil(math.Sqrt(area/maxCelulasMapa)))
+	}
+	m.Resolucao = resolucao
+	m.Oeste, m.Leste, m.Sul, m.Norte = anel[0].Longitude, anel[0].Longitude, anel[0].Latitude, anel[0].Latitude
+	for _, p := range anel {
+		m.Oeste, m.Leste = math.Min(m.Oeste, p.Longitude), math.Max(m.Leste, p.Longitude)
+		m.Sul, m.Norte = math.Min(m.Sul, p.Latitude), math.Max(m.Norte, p.Latitude)
+	}
+	passoLat := resolucao / 111320
+	passoLon := resolucao / (111320 * math.Cos((m.Sul+m.Norte)/2*math.Pi/180))
+	m.Colunas = max(int(math.Ceil((m.Leste-m.Oeste)/passoLon)), 1)
+	m.Linhas = max(int(math.Ceil((m.Norte-m.Sul)/passoLat)), 1)
+	if m.Colunas*m.Linhas > 4*maxCelulasMapa {
+		return m, errors.New("resolução fina demais para o tamanho do talhão")
+	}
+	m.Leste = m.Oeste + float64(m.Colunas)*passoLon
+	m.Sul = m.Norte - float64(m.Linhas)*passoLat
+
+	m.Valores = make([]float64, m.Colunas*m.Linhas)
+	dentro := 0
+	for l := 0; l < m.Linhas; l++ {
+		for c := 0; c < m.Colunas; c++ {
+			u := ParaUTMNoFuso(m.Centro(l, c), plano.fuso)
+			if !dentroAnel(u.Leste, u.Norte, plano.anel) {
+				m.Valores[l*m.Colunas+c] = math.NaN()
+				continue
+			}
+			m.Valores[l*m.Colunas+c] = valorEm(u.Leste, u.Norte, -1)
+			dentro++
+		}
+	}
+	if dentro == 0 {
+		return m, errors.New("o talhão é menor que uma célula do mapa")
+	}
+	// A área das células é ajustada para somar a área geodésica do talhão
+	m.AreaCelulaHa = area / 10000 / float64(dentro)
+	return m, nil
+}
+
+// AreaClasse é a área do talhão em uma classe de interpretação
+type AreaClasse struct {
+	Classe     string  `json:"classe"`
+	Faixa      string  `json:"faixa"`
+	Cor        string  `json:"cor"`
+	AreaHa     float64 `json:"area_ha"`
+	Percentual float64 `json:"percentual"`
+}
+
+// AreasPorClasse soma a área das células em cada classe de interpretação
+// do parâmetro
+func (m MapaInterpolado) AreasPorClasse(p ParametroSolo) []AreaClasse {
+	areas := make([]AreaClasse, len(ClassesFertilidade))
+	for i := range areas {
+		areas[i] = AreaClasse{Classe: ClassesFertilidade[i], Faixa: p.Faixa(i), Cor: CoresFertilidade[i]}
+	}
+	total := 0.0
+	for _, v := range m.Valores {
+		if math.IsNaN(v) {
+			continue
+		}
+		areas[p.Classe(v)].AreaHa += m.AreaCelulaHa
+		total += m.AreaCelulaHa
+	}
+	for i := range areas {
+		if total > 0 {
+			areas[i].Percentual = areas[i].AreaHa / total * 100
+		}
+	}
+	return areas
+}
+
+// corHex converte #rrggbb em cor
+func corHex(hex string) color.NRGBA {
+	var r, g, b uint8
+	fmt.Sscanf(hex, "#%02x%02x%02x", &r, &g, &b)
+	return color.NRGBA{R: r, G: g, B: b, A: 255}
+}
+
+// PNG desenha o mapa com as cores das classes do parâmetro, uma célula por
+// pixel e transparente fora do talhão, para sobrepor nos limites
+// oeste/sul/leste/norte
+func (m MapaInterpolado) PNG(p ParametroSolo) []byte {
+	cores := make([]color.NRGBA, len(CoresFertilidade))
+	for i, c := range CoresFertilidade {
+		cores[i] = corHex(c)
+	}
+	return desenharPNG(m.Colunas, m.Linhas, func(i int) (color.NRGBA, bool) {
+		if math.IsNaN(m.Valores[i]) {
+			return color.NRGBA{}, false
+		}
+		return cores[p.Classe(m.Valores[i])], true
+	})
+}
+
+// desenharPNG monta a imagem de uma malha de norte para sul; cor recebe o
+// índice da célula e devolve false para deixá-la transparente
+func desenharPNG(colunas, linhas int, cor func(i int) (color.NRGBA, bool)) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, colunas, linhas))
+	for l := 0; l < linhas; l++ {
+		for c := 0; c < colunas; c++ {
+			if v, ok := cor(l*colunas + c); ok {
+				img.SetNRGBA(c, l, v)
+			}
+		}
+	}
+	var b bytes.Buffer
+	png.Encode(&b, img)
+	return b.Bytes()
+}
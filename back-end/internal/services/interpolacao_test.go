@@ -0,0 +1,176 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+func TestVariogramaEsferico(t *testing.T) {
+	v := Variograma{Pepita: 0.2, Patamar: 1.2, Alcance: 100}
+	casos := []struct {
+		h, gama float64
+	}{
+		{0, 0},
+		// 0,2 + 1,0 × (1,5 × 0,5 − 0,5 × 0,5³)
+		{50, 0.8875},
+		{100, 1.2},
+		{250, 1.2},
+	}
+	for _, c := range casos {
+		if g := v.valor(c.h); math.Abs(g-c.gama) > 1e-12 {
+			t.Errorf("γ(%.0f) = %.4f, esperado %.4f", c.h, g, c.gama)
+		}
+	}
+	if v.Dependencia() != "forte" || (Variograma{Pepita: 0.5, Patamar: 1}).Dependencia() != "moderada" ||
+		(Variograma{Pepita: 0.8, Patamar: 1}).Dependencia() != "fraca" {
+		t.Error("classes de dependência espacial de Cambardella incorretas")
+	}
+}
+
+func TestAjustarVariogramaExperimental(t *testing.T) {
+	// Transecto com 25 amostras a cada 10 m e tendência linear de 0,1 por
+	// metro: cada par a h metros tem semivariância (0,1·h)²/2
+	var amostras []amostraPlana
+	for i := 0; i < 25; i++ {
+		amostras = append(amostras, amostraPlana{x: float64(i) * 10, valor: float64(i)})
+	}
+	v, err := ajustarVariograma(amostras)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Limite de 120 m em 12 classes de 10 m; a última junta 110 e 120 m
+	if len(v.Experimental) != 11 {
+		t.Fatalf("classes = %d, esperado 11", len(v.Experimental))
+	}
+	for j, e := range v.Experimental[:10] {
+		h := float64(j+1) * 10
+		if e.Distancia != h || e.Pares != 25-(j+1) || math.Abs(e.Semivariancia-0.01*h*h/2) > 1e-9 {
+			t.Errorf("classe %d: %+v, esperado h = %.0f com %d pares e γ = %.2f", j, e, h, 25-(j+1), 0.01*h*h/2)
+		}
+	}
+	if v.Patamar <= v.Pepita || v.Alcance <= 0 {
+		t.Errorf("modelo ajustado sem estrutura espacial: %+v", v)
+	}
+
+	if _, err := ajustarVariograma([]amostraPlana{{0, 0, 1}, {0, 0, 2}}); err == nil {
+		t.Error("amostras coincidentes aceitas")
+	}
+}
+
+func TestEstimadorKrigagem(t *testing.T) {
+	v := Variograma{Pepita: 0, Patamar: 1, Alcance: 200}
+	esferico := func(h float64) float64 { r := h / 200; return 1.5*r - 0.5*r*r*r }
+
+	// Dois pontos a 100 m e estimativa a 25 m do primeiro: o sistema
+	// γ₁₂·w₂ + μ = γ(25), γ₁₂·w₁ + μ = γ(75), w₁ + w₂ = 1
+	duas := []amostraPlana{{0, 0, 1}, {100, 0, 3}}
+	w1 := 0.5 + (esferico(75)-esferico(25))/esferico(100)/2
+	esperado := w1*1 + (1-w1)*3
+
+	quatro := []amostraPlana{{0, 0, 2}, {100, 0, 4}, {0, 100, 6}, {100, 100, 8}}
+	constante := []amostraPlana{{0, 0, 7}, {80, 10, 7}, {30, 90, 7}, {120, 60, 7}}
+
+	casos := []struct {
+		nome     string
+		amostras []amostraPlana
+		x, y     float64
+		valor    float64
+	}{
+		{"dois pontos", duas, 25, 0, esperado},
+		{"interpolador exato na amostra", duas, 100, 0, 3},
+		{"centro de quatro pontos simétricos", quatro, 50, 50, 5},
+		{"pesos somam um", constante, 55, 40, 7},
+	}
+	for _, c := range casos {
+		vizinhos := make([]int, len(c.amostras))
+		for i := range vizinhos {
+			vizinhos[i] = i
+		}
+		z, ok := estimadorKrigagem(c.amostras, v)(c.x, c.y, vizinhos)
+		if !ok || math.Abs(z-c.valor) > 1e-9 {
+			t.Errorf("%s: %.6f (%v), esperado %.6f", c.nome, z, ok, c.valor)
+		}
+	}
+
+	if _, ok := estimadorKrigagem([]amostraPlana{{0, 0, 1}, {0, 0, 2}}, v)(10, 10, []int{0, 1}); ok {
+		t.Error("amostras coincidentes deveriam tornar o sistema singular")
+	}
+}
+
+func TestInterpolarTalhao(t *testing.T) {
+	// Talhão de cerca de 440 × 440 m em Brasília com grade 6 × 6 de amostras
+	// e tendência leste-oeste de 8 a 18
+	const lado = 0.004
+	anel := []models.Ponto{
+		{Latitude: -15.780, Longitude: -47.930},
+		{Latitude: -15.780, Longitude: -47.930 + lado},
+		{Latitude: -15.780 - lado, Longitude: -47.930 + lado},
+		{Latitude: -15.780 - lado, Longitude: -47.930},
+	}
+	grade := func(n int, valor func(l, c int) float64) []AmostraInterpolacao {
+		var amostras []AmostraInterpolacao
+		for l := 0; l < n; l++ {
+			for c := 0; c < n; c++ {
+				p := models.Ponto{
+					Latitude:  -15.780 - lado*(float64(l)+0.5)/float64(n),
+					Longitude: -47.930 + lado*(float64(c)+0.5)/float64(n),
+				}
+				amostras = append(amostras, AmostraInterpolacao{Ponto: p, Valor: valor(l, c)})
+			}
+		}
+		return amostras
+	}
+	tendencia := func(l, c int) float64 { return 8 + 2*float64(c) }
+
+	casos := []struct {
+		nome     string
+		amostras []AmostraInterpolacao
+		metodo   string
+		usado    string
+		aviso    string
+	}{
+		{"krigagem", grade(6, tendencia), InterpolacaoKrigagem, InterpolacaoKrigagem, ""},
+		{"IDW", grade(6, tendencia), InterpolacaoIDW, InterpolacaoIDW, ""},
+		{"krigagem com poucas amostras", grade(3, tendencia), InterpolacaoKrigagem, InterpolacaoIDW, "exige ao menos 10"},
+		{"amostras iguais", grade(6, func(int, int) float64 { return 12 }), InterpolacaoKrigagem, InterpolacaoIDW, "mesmo valor"},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			m, err := InterpolarTalhao(anel, c.amostras, c.metodo, 20)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Metodo != c.usado || (m.Variograma != nil) != (c.usado == InterpolacaoKrigagem) {
+				t.Errorf("método %s, variograma %v; esperado %s", m.Metodo, m.Variograma != nil, c.usado)
+			}
+			if c.aviso != "" && (len(m.Avisos) != 1 || !strings.Contains(m.Avisos[0], c.aviso)) {
+				t.Errorf("avisos = %v, esperado %q", m.Avisos, c.aviso)
+			}
+			minimo, maximo, _ := m.Estatisticas()
+			menor, maior := math.Inf(1), math.Inf(-1)
+			for _, a := range c.amostras {
+				menor, maior = math.Min(menor, a.Valor), math.Max(maior, a.Valor)
+			}
+			if minimo < menor-1 || maximo > maior+1 {
+				t.Errorf("mapa de %.2f a %.2f, amostras de %.0f a %.0f", minimo, maximo, menor, maior)
+			}
+			// As células somam a área geodésica do talhão
+			dentro := 0
+			for _, v := range m.Valores {
+				if !math.IsNaN(v) {
+					dentro++
+				}
+			}
+			if math.Abs(m.AreaCelulaHa*float64(dentro)-AreaGeodesica(anel)) > 1e-9 {
+				t.Errorf("área das células = %.4f ha, talhão %.4f ha", m.AreaCelulaHa*float64(dentro), AreaGeodesica(anel))
+			}
+		})
+	}
+
+	if _, err := InterpolarTalhao(anel[:2], grade(6, tendencia), InterpolacaoIDW, 20); err == nil {
+		t.Error("talhão sem limite aceito")
+	}
+}
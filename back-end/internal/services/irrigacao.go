@@ -0,0 +1,374 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+const (
+	// ET0 usada nos dias sem leitura e sem histórico recente (mm/dia)
+	etoPadrao = 4.0
+	// Dias anteriores usados para estimar a ET0 de um dia sem leitura
+	diasMediaETo = 7
+	// Chuvas até este valor (mm) ficam na interceptação e não entram no solo
+	chuvaMinimaEfetiva = 2.0
+	// Vento a 2 m adotado quando a estação não informa (m/s)
+	ventoPadrao = 2.0
+	// Constante de Stefan-Boltzmann (MJ/K⁴/m²/dia)
+	stefanBoltzmann = 4.903e-9
+)
+
+// CurvaKc é o coeficiente de cultura da FAO-56: Kc inicial, médio e final
+// e a duração relativa das fases inicial, de desenvolvimento, intermediária
+// e final no ciclo
+type CurvaKc struct {
+	Inicial float64
+	Medio   float64
+	Final   float64
+	Fases   [4]float64
+}
+
+// Curvas de Kc de referência (FAO-56, tabelas 11 e 12)
+var curvasKc = map[string]CurvaKc{
+	"Soja":           {0.40, 1.15, 0.50, [4]float64{0.15, 0.20, 0.45, 0.20}},
+	"Milho":          {0.30, 1.20, 0.35, [4]float64{0.17, 0.28, 0.33, 0.22}},
+	"Milho safrinha": {0.30, 1.20, 0.35, [4]float64{0.17, 0.28, 0.33, 0.22}},
+	"Feijão":         {0.40, 1.15, 0.35, [4]float64{0.20, 0.30, 0.35, 0.15}},
+	"Algodão":        {0.35, 1.15, 0.70, [4]float64{0.17, 0.33, 0.25, 0.25}},
+	"Trigo":          {0.30, 1.15, 0.25, [4]float64{0.15, 0.25, 0.40, 0.20}},
+	"Sorgo":          {0.30, 1.00, 0.55, [4]float64{0.15, 0.28, 0.32, 0.25}},
+	"Café":           {0.90, 0.95, 0.95, [4]float64{0.25, 0.25, 0.25, 0.25}},
+}
+
+// curvaKcPadrao é usada para culturas sem curva cadastrada
+var curvaKcPadrao = CurvaKc{0.40, 1.10, 0.50, [4]float64{0.15, 0.25, 0.40, 0.20}}
+
+// KcCultura interpola o Kc da cultura na fração do ciclo (0 a 1)
+func KcCultura(cultura string, fracao float64) float64 {
+	c, ok := curvasKc[cultura]
+	if !ok {
+		c = curvaKcPadrao
+	}
+	f1 := c.Fases[0]
+	f2 := f1 + c.Fases[1]
+	f3 := f2 + c.Fases[2]
+	switch {
+	case fracao < f1:
+		return c.Inicial
+	case fracao < f2:
+		return c.Inicial + (c.Medio-c.Inicial)*(fracao-f1)/c.Fases[1]
+	case fracao < f3:
+		return c.Medio
+	default:
+		return c.Medio + (c.Final-c.Medio)*math.Min(1, (fracao-f3)/c.Fases[3])
+	}
+}
+
+// pressaoVapor é a pressão de saturação de vapor (kPa) na temperatura (°C)
+func pressaoVapor(t float64) float64 {
+	return 0.6108 * math.Exp(17.27*t/(t+237.3))
+}
+
+// RadiacaoExtraterrestre é a radiação no topo da atmosfera (MJ/m²/dia) na
+// latitude (graus) e data informadas
+func RadiacaoExtraterrestre(latitude float64, data time.Time) float64 {
+	phi := latitude * math.Pi / 180
+	j := float64(data.YearDay())
+	dr := 1 + 0.033*math.Cos(2*math.Pi*j/365)
+	delta := 0.409 * math.Sin(2*math.Pi*j/365-1.39)
+	ws := math.Acos(math.Max(-1, math.Min(1, -math.Tan(phi)*math.Tan(delta))))
+	return 24 * 60 / math.Pi * 0.0820 * dr * (ws*math.Sin(phi)*math.Sin(delta) + math.Cos(phi)*math.Cos(delta)*math.Sin(ws))
+}
+
+// ETo calcula a evapotranspiração de referência do dia (mm) pelo método
+// FAO-56 Penman-Monteith. Sem umidade relativa, usa Hargreaves-Samani;
+// sem radiação medida, estima pela amplitude térmica. Quando a estação já
+// informa a ET0, ela é usada. Retorna falso sem temperaturas.
+func ETo(d models.ClimaDiario, latitude, altitude float64) (float64, bool) {
+	if d.ETOInformada > 0 {
+		return d.ETOInformada, true
+	}
+	if d.TempMaxima == 0 && d.TempMinima == 0 || d.TempMaxima < d.TempMinima {
+		return 0, false
+	}
+	tmed := (d.TempMaxima + d.TempMinima) / 2
+	amplitude := d.TempMaxima - d.TempMinima
+	ra := RadiacaoExtraterrestre(latitude, d.Data)
+
+	if d.UmidadeRelativa <= 0 {
+		return math.Max(0, 0.0023*(tmed+17.8)*math.Sqrt(amplitude)*0.408*ra), true
+	}
+
+	rs := d.Radiacao
+	if rs <= 0 {
+		rs = 0.16 * math.Sqrt(amplitude) * ra
+	}
+	u2 := d.VentoMS
+	if u2 <= 0 {
+		u2 = ventoPadrao
+	}
+	pressao := 101.3 * math.Pow((293-0.0065*altitude)/293, 5.26)
+	gama := 0.000665 * pressao
+	delta := 4098 * pressaoVapor(tmed) / math.Pow(tmed+237.3, 2)
+	es := (pressaoVapor(d.TempMaxima) + pressaoVapor(d.TempMinima)) / 2
+	ea := es * math.Min(100, d.UmidadeRelativa) / 100
+
+	rso := (0.75 + 2e-5*altitude) * ra
+	rns := 0.77 * rs
+	razao := 1.0
+	if rso > 0 {
+		razao = math.Min(1, rs/rso)
+	}
+	rnl := stefanBoltzmann * (math.Pow(d.TempMaxima+273.16, 4) + math.Pow(d.TempMinima+273.16, 4)) / 2 *
+		(0.34 - 0.14*math.Sqrt(ea)) * (1.35*razao - 0.35)
+	rn := rns - rnl
+
+	eto := (0.408*delta*rn + gama*900/(tmed+273)*u2*(es-ea)) / (delta + gama*(1+0.34*u2))
+	return math.Max(0, eto), true
+}
+
+// DiaBalanco é o resultado do balanço hídrico de um dia. A depleção é a
+// água consumida da zona radicular desde a capacidade de campo (mm).
+type DiaBalanco struct {
+	Data             time.Time `json:"data"`
+	ETo              float64   `json:"eto"`
+	EToEstimada      bool      `json:"eto_estimada"`
+	Kc               float64   `json:"kc"`
+	ETc              float64   `json:"etc"`
+	ETr              float64   `json:"etr"` // ETc reduzida pelo estresse hídrico
+	Chuva            float64   `json:"chuva"`
+	ChuvaEfetiva     float64   `json:"chuva_efetiva"`
+	Irrigacao        float64   `json:"irrigacao"` // lâmina bruta
+	IrrigacaoLiquida float64   `json:"irrigacao_liquida"`
+	Drenagem         float64   `json:"drenagem"`
+	Deplecao         float64   `json:"deplecao"`
+	CTA              float64   `json:"cta"`
+	AFD              float64   `json:"afd"`
+}
+
+// Armazenamento é a água disponível restante na zona radicular (mm)
+func (d DiaBalanco) Armazenamento() float64 {
+	return d.CTA - d.Deplecao
+}
+
+// PercentualCTA é o armazenamento em % da capacidade total
+func (d DiaBalanco) PercentualCTA() float64 {
+	if d.CTA == 0 {
+		return 0
+	}
+	return d.Armazenamento() / d.CTA * 100
+}
+
+// Estresse indica se a depleção passou da água facilmente disponível
+func (d DiaBalanco) Estresse() bool {
+	return d.Deplecao > d.AFD
+}
+
+// fracaoCiclo é a fração do ciclo (plantio à colheita prevista) na data
+func fracaoCiclo(s models.Safra, data time.Time) float64 {
+	inicio := inicioCiclo(s)
+	ciclo := s.ColheitaPrevista.Sub(inicio).Hours()
+	if ciclo <= 0 {
+		return 0
+	}
+	return math.Max(0, math.Min(1, data.Sub(inicio).Hours()/ciclo))
+}
+
+// BalancoHidrico calcula o balanço hídrico diário da safra (FAO-56, método
+// do coeficiente único), do plantio até a data informada ou a colheita,
+// partindo do solo na capacidade de campo. Dias sem leitura meteorológica
+// usam a média da ET0 dos dias anteriores.
+func BalancoHidrico(safra models.Safra, cfg models.ConfigIrrigacao, clima []models.ClimaDiario, chuvas []models.Chuva, irrigacoes []models.Irrigacao, ate time.Time) []DiaBalanco {
+	cta, afd := cfg.CTA(), cfg.AFD()
+	if cta <= 0 {
+		return nil
+	}
+	eficiencia := cfg.Eficiencia / 100
+	if eficiencia <= 0 || eficiencia > 1 {
+		eficiencia = 1
+	}
+
+	climaDia := make(map[time.Time]models.ClimaDiario)
+	for _, c := range clima {
+		if c.PropriedadeID == safra.PropriedadeID {
+			climaDia[inicioDoDia(c.Data)] = c
+		}
+	}
+	chuvaDia := make(map[time.Time]float64)
+	for _, c := range chuvas {
+		if c.PropriedadeID == safra.PropriedadeID {
+			chuvaDia[inicioDoDia(c.Data)] += c.Milimetros
+		}
+	}
+	irrigacaoDia := make(map[time.Time]float64)
+	for _, i := range irrigacoes {
+		if i.SafraID == safra.ID {
+			irrigacaoDia[inicioDoDia(i.Data)] += i.Lamina
+		}
+	}
+
+	fim := inicioDoDia(ate)
+	if safra.ColheitaReal != nil && safra.ColheitaReal.Before(fim) {
+		fim = *safra.ColheitaReal
+	} else if safra.ColheitaPrevista.Before(fim) {
+		fim = safra.ColheitaPrevista
+	}
+
+	var dias []DiaBalanco
+	var recentes []float64
+	deplecao := 0.0
+	for dia := inicioDoDia(inicioCiclo(safra)); !dia.After(fim); dia = dia.AddDate(0, 0, 1) {
+		d := DiaBalanco{Data: dia, CTA: cta, AFD: afd}
+
+		eto, ok := 0.0, false
+		if c, existe := climaDia[dia]; existe {
+			eto, ok = ETo(c, cfg.Latitude, cfg.Altitude)
+		}
+		if ok {
+			recentes = append(recentes, eto)
+			if len(recentes) > diasMediaETo {
+				recentes = recentes[1:]
+			}
+		} else {
+			d.EToEstimada = true
+			eto = etoPadrao
+			if len(recentes) > 0 {
+				var soma float64
+				for _, v := range recentes {
+					soma += v
+				}
+				eto = soma / float64(len(recentes))
+			}
+		}
+		d.ETo = eto
+		d.Kc = KcCultura(safra.Cultura, fracaoCiclo(safra, dia))
+		d.ETc = d.ETo * d.Kc
+
+		// Coeficiente de estresse: a evapotranspiração cai linearmente
+		// quando a depleção passa da água facilmente disponível
+		ks := 1.0
+		if deplecao > afd && cta > afd {
+			ks = math.Max(0, (cta-deplecao)/(cta-afd))
+		}
+		d.ETr = d.ETc * ks
+
+		d.Chuva = chuvaDia[dia]
+		if d.Chuva > chuvaMinimaEfetiva {
+			d.ChuvaEfetiva = d.Chuva
+		}
+		d.Irrigacao = irrigacaoDia[dia]
+		d.IrrigacaoLiquida = d.Irrigacao * eficiencia
+
+		deplecao += d.ETr - d.ChuvaEfetiva - d.IrrigacaoLiquida
+		if deplecao < 0 {
+			d.Drenagem = -deplecao
+			deplecao = 0
+		}
+		d.Deplecao = math.Min(cta, deplecao)
+		deplecao = d.Deplecao
+		dias = append(dias, d)
+	}
+	return dias
+}
+
+// RecomendacaoIrrigacao é a próxima irrigação sugerida pelo balanço
+type RecomendacaoIrrigacao struct {
+	Disponivel    bool      `json:"disponivel"`
+	Data          time.Time `json:"data"`
+	DiasAte       int       `json:"dias_ate"`
+	Imediata      bool      `json:"imediata"`
+	AposColheita  bool      `json:"apos_colheita"` // não há necessidade até o fim do ciclo
+	LaminaLiquida float64   `json:"lamina_liquida"`
+	LaminaBruta   float64   `json:"lamina_bruta"`
+	Parcelas      int       `json:"parcelas"` // irrigações para aplicar a lâmina no limite do sistema
+	ETcMedia      float64   `json:"etc_media"`
+}
+
+// RecomendarIrrigacao projeta, pela ETc média dos últimos dias, quando a
+// depleção atingirá a água facilmente disponível e sugere repor a zona
+// radicular à capacidade de campo, convertendo para lâmina bruta pela
+// eficiência do sistema
+func RecomendarIrrigacao(safra models.Safra, cfg models.ConfigIrrigacao, balanco []DiaBalanco, hoje time.Time) RecomendacaoIrrigacao {
+	var r RecomendacaoIrrigacao
+	if len(balanco) == 0 {
+		return r
+	}
+	r.Disponivel = true
+	atual := balanco[len(balanco)-1]
+
+	inicio := len(balanco) - 5
+	if inicio < 0 {
+		inicio = 0
+	}
+	for _, d := range balanco[inicio:] {
+		r.ETcMedia += d.ETc
+	}
+	r.ETcMedia /= float64(len(balanco) - inicio)
+
+	hoje = inicioDoDia(hoje)
+	if atual.Deplecao >= atual.AFD {
+		r.Imediata = true
+		r.Data = hoje
+		r.LaminaLiquida = atual.Deplecao
+	} else {
+		if r.ETcMedia <= 0 {
+			r.AposColheita = true
+			return r
+		}
+		r.DiasAte = int(math.Ceil((atual.AFD - atual.Deplecao) / r.ETcMedia))
+		r.Data = hoje.AddDate(0, 0, r.DiasAte)
+		r.LaminaLiquida = atual.AFD
+		if safra.ColheitaReal != nil || r.Data.After(safra.ColheitaPrevista) {
+			r.AposColheita = true
+		}
+	}
+
+	eficiencia := cfg.Eficiencia / 100
+	if eficiencia <= 0 || eficiencia > 1 {
+		eficiencia = 1
+	}
+	r.LaminaBruta = r.LaminaLiquida / eficiencia
+	r.Parcelas = 1
+	if cfg.LaminaMaxima > 0 && r.LaminaBruta > cfg.LaminaMaxima {
+		r.Parcelas = int(math.Ceil(r.LaminaBruta / cfg.LaminaMaxima))
+	}
+	return r
+}
+
+// ResumoBalanco totaliza o balanço hídrico da safra até a data calculada
+type ResumoBalanco struct {
+	ETc           float64 `json:"etc"`
+	ETr           float64 `json:"etr"`
+	Chuva         float64 `json:"chuva"`
+	ChuvaEfetiva  float64 `json:"chuva_efetiva"`
+	Irrigacao     float64 `json:"irrigacao"`
+	Irrigacoes    int     `json:"irrigacoes"`
+	Drenagem      float64 `json:"drenagem"`
+	DiasEstresse  int     `json:"dias_estresse"`
+	DiasEstimados int     `json:"dias_estimados"` // dias sem leitura meteorológica
+}
+
+// ResumirBalanco soma as entradas e saídas de água do balanço
+func ResumirBalanco(dias []DiaBalanco) ResumoBalanco {
+	var r ResumoBalanco
+	for _, d := range dias {
+		r.ETc += d.ETc
+		r.ETr += d.ETr
+		r.Chuva += d.Chuva
+		r.ChuvaEfetiva += d.ChuvaEfetiva
+		r.Irrigacao += d.Irrigacao
+		r.Drenagem += d.Drenagem
+		if d.Irrigacao > 0 {
+			r.Irrigacoes++
+		}
+		if d.Estresse() {
+			r.DiasEstresse++
+		}
+		if d.EToEstimada {
+			r.DiasEstimados++
+		}
+	}
+	return r
+}
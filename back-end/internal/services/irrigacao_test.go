@@ -0,0 +1,159 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+func TestRadiacaoExtraterrestre(t *testing.T) {
+	casos := []struct {
+		nome     string
+		latitude float64
+		data     time.Time
+		ra       float64
+	}{
+		// FAO-56, exemplo 8: 20° S em 3 de setembro
+		{"20° S em 3 de setembro", -20, time.Date(2026, 9, 3, 0, 0, 0, 0, time.UTC), 32.2},
+		// FAO-56, exemplo 18: Bruxelas (50°48' N) em 6 de julho
+		{"Bruxelas em 6 de julho", 50.8, time.Date(2026, 7, 6, 0, 0, 0, 0, time.UTC), 41.09},
+	}
+	for _, c := range casos {
+		if ra := RadiacaoExtraterrestre(c.latitude, c.data); math.Abs(ra-c.ra) > 0.05 {
+			t.Errorf("%s: Ra = %.2f MJ/m²/dia, esperado %.2f", c.nome, ra, c.ra)
+		}
+	}
+}
+
+func TestETo(t *testing.T) {
+	julho := time.Date(2026, 7, 6, 0, 0, 0, 0, time.UTC)
+	// FAO-56, exemplo 18 (Bruxelas, 100 m): Tmax 21,5 °C, Tmin 12,3 °C,
+	// u2 = 2,078 m/s, Rs = 22,07 MJ/m²/dia e ea = 1,409 kPa, informada aqui
+	// pela umidade média equivalente
+	umidade := 1.409 / ((pressaoVapor(21.5) + pressaoVapor(12.3)) / 2) * 100
+	bruxelas := models.ClimaDiario{Data: julho, TempMaxima: 21.5, TempMinima: 12.3, UmidadeRelativa: umidade, VentoMS: 2.078, Radiacao: 22.07}
+
+	casos := []struct {
+		nome       string
+		dia        models.ClimaDiario
+		eto        float64
+		tolerancia float64
+		ok         bool
+	}{
+		{"Penman-Monteith do exemplo 18", bruxelas, 3.9, 0.05, true},
+		// Hargreaves-Samani: 0,0023 × (16,9 + 17,8) × √9,2 × 0,408 × 41,09
+		{"Hargreaves sem umidade", models.ClimaDiario{Data: julho, TempMaxima: 21.5, TempMinima: 12.3}, 4.058, 0.005, true},
+		{"ET0 informada pela estação", models.ClimaDiario{Data: julho, TempMaxima: 21.5, TempMinima: 12.3, ETOInformada: 5.2}, 5.2, 0, true},
+		{"sem temperaturas", models.ClimaDiario{Data: julho, UmidadeRelativa: 70}, 0, 0, false},
+		{"máxima abaixo da mínima", models.ClimaDiario{Data: julho, TempMaxima: 10, TempMinima: 15}, 0, 0, false},
+	}
+	for _, c := range casos {
+		eto, ok := ETo(c.dia, 50.8, 100)
+		if ok != c.ok || math.Abs(eto-c.eto) > c.tolerancia {
+			t.Errorf("%s: ET0 = %.3f (%v), esperado %.3f (%v)", c.nome, eto, ok, c.eto, c.ok)
+		}
+	}
+}
+
+func TestBalancoHidrico(t *testing.T) {
+	dia := func(d int) time.Time { return time.Date(2026, 10, 1+d, 0, 0, 0, 0, time.UTC) }
+	// Soja de 100 dias: Kc inicial 0,4 nos primeiros 15 dias
+	safra := models.Safra{ID: 1, PropriedadeID: 1, Cultura: "Soja", PlantioPrevisto: dia(0), ColheitaPrevista: dia(100)}
+	// CTA = 1 mm/cm × 50 cm = 50 mm; AFD = 25 mm
+	cfg := models.ConfigIrrigacao{Eficiencia: 80, AguaDisponivel: 1, ProfundidadeRaiz: 50, FatorDeplecao: 0.5}
+	clima := func(eto float64, semLeitura ...int) []models.ClimaDiario {
+		falta := make(map[int]bool)
+		for _, d := range semLeitura {
+			falta[d] = true
+		}
+		var lista []models.ClimaDiario
+		for d := 0; d < 15; d++ {
+			if !falta[d] {
+				lista = append(lista, models.ClimaDiario{PropriedadeID: 1, Data: dia(d), ETOInformada: eto})
+			}
+		}
+		return lista
+	}
+
+	casos := []struct {
+		nome       string
+		clima      []models.ClimaDiario
+		chuvas     []models.Chuva
+		irrigacoes []models.Irrigacao
+		ate        int
+		deplecao   []float64
+		drenagem   float64
+		estimados  int
+	}{
+		// ETc = 5 × 0,4 = 2 mm/dia; 1,5 mm fica na interceptação; 12,5 mm
+		// brutos a 80% repõem 10 mm e drenam 2 mm
+		{"chuva, irrigação e drenagem", clima(5, 2),
+			[]models.Chuva{{PropriedadeID: 1, Data: dia(3), Milimetros: 1.5}, {PropriedadeID: 1, Data: dia(5), Milimetros: 10}, {PropriedadeID: 2, Data: dia(6), Milimetros: 50}},
+			[]models.Irrigacao{{SafraID: 1, Data: dia(8), Lamina: 12.5}, {SafraID: 2, Data: dia(9), Lamina: 100}},
+			9, []float64{2, 4, 6, 8, 10, 2, 4, 6, 0, 2}, 2, 1},
+		// ETc = 4 mm/dia: no 8º dia a depleção de 28 mm passa a AFD e
+		// Ks = (50 − 28) / (50 − 25) = 0,88
+		{"estresse hídrico", clima(10), nil, nil,
+			7, []float64{4, 8, 12, 16, 20, 24, 28, 28 + 4*0.88}, 0, 0},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			dias := BalancoHidrico(safra, cfg, c.clima, c.chuvas, c.irrigacoes, dia(c.ate))
+			if len(dias) != len(c.deplecao) {
+				t.Fatalf("dias = %d, esperado %d", len(dias), len(c.deplecao))
+			}
+			for i, d := range dias {
+				if math.Abs(d.Deplecao-c.deplecao[i]) > 1e-9 {
+					t.Errorf("dia %d: depleção %.2f mm, esperado %.2f mm", i, d.Deplecao, c.deplecao[i])
+				}
+			}
+			r := ResumirBalanco(dias)
+			if math.Abs(r.Drenagem-c.drenagem) > 1e-9 || r.DiasEstimados != c.estimados {
+				t.Errorf("drenagem %.2f mm, %d dias estimados; esperado %.2f mm, %d", r.Drenagem, r.DiasEstimados, c.drenagem, c.estimados)
+			}
+			// Fechamento: saídas − entradas = depleção final
+			var liquida float64
+			for _, d := range dias {
+				liquida += d.IrrigacaoLiquida
+			}
+			if fechamento := r.ETr - r.ChuvaEfetiva - liquida + r.Drenagem; math.Abs(fechamento-dias[len(dias)-1].Deplecao) > 1e-9 {
+				t.Errorf("balanço não fecha: %.3f mm, depleção final %.3f mm", fechamento, dias[len(dias)-1].Deplecao)
+			}
+		})
+	}
+}
+
+func TestRecomendarIrrigacao(t *testing.T) {
+	hoje := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	safra := models.Safra{ColheitaPrevista: hoje.AddDate(0, 0, 90)}
+	cfg := models.ConfigIrrigacao{Eficiencia: 80, LaminaMaxima: 20}
+	balanco := func(deplecao float64) []DiaBalanco {
+		var dias []DiaBalanco
+		for i := 0; i < 5; i++ {
+			dias = append(dias, DiaBalanco{ETc: 4, CTA: 50, AFD: 25, Deplecao: deplecao})
+		}
+		return dias
+	}
+
+	casos := []struct {
+		nome     string
+		deplecao float64
+		imediata bool
+		diasAte  int
+		bruta    float64
+		parcelas int
+	}{
+		// Faltam 15 mm para a AFD a 4 mm/dia: 4 dias; repõe 25 mm líquidos
+		{"dentro da água facilmente disponível", 10, false, 4, 31.25, 2},
+		{"depleção acima da AFD", 30, true, 0, 37.5, 2},
+		{"depleção igual à AFD", 25, true, 0, 31.25, 2},
+	}
+	for _, c := range casos {
+		r := RecomendarIrrigacao(safra, cfg, balanco(c.deplecao), hoje)
+		if r.Imediata != c.imediata || r.DiasAte != c.diasAte || math.Abs(r.LaminaBruta-c.bruta) > 1e-9 || r.Parcelas != c.parcelas {
+			t.Errorf("%s: %+v", c.nome, r)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// Limites de qualidade do leite
+const (
+	LimiteCCSVaca   = 200.0 // mil células/mL; acima indica mastite subclínica
+	LimiteCCSTanque = 500.0 // mil células/mL (IN 76/2018)
+	LimiteCBTTanque = 300.0 // mil UFC/mL (IN 76/2018)
+	DiasLactacao    = 305
+)
+
+// CurvaWood representa a curva de lactação y(t) = a·t^b·e^(−c·t)
+type CurvaWood struct {
+	A float64 `json:"a"`
+	B float64 `json:"b"`
+	C float64 `json:"c"`
+}
+
+// Producao estima os litros/dia no dia em lactação informado
+func (c CurvaWood) Producao(del float64) float64 {
+	if del <= 0 {
+		return 0
+	}
+	return c.A * math.Pow(del, c.B) * math.Exp(-c.C*del)
+}
+
+// Pico retorna o dia e a produção no pico da lactação
+func (c CurvaWood) Pico() (dia, litros float64) {
+	if c.C <= 0 {
+		return 0, 0
+	}
+	dia = c.B / c.C
+	return dia, c.Producao(dia)
+}
+
+// PontoLactacao é uma pesagem posicionada em dias em lactação (DEL)
+type PontoLactacao struct {
+	DEL    int     `json:"del"`
+	Litros float64 `json:"litros"`
+}
+
+// AjustarCurvaWood ajusta a curva de Wood por mínimos quadrados na forma
+// linearizada ln y = ln a + b·ln t − c·t. Exige ao menos três pesagens em
+// dias distintos e rejeita ajustes sem formato de lactação (b ou c ≤ 0).
+func AjustarCurvaWood(pontos []PontoLactacao) (CurvaWood, bool) {
+	var m [3][3]float64
+	var v [3]float64
+	dias := make(map[int]bool)
+	for _, p := range pontos {
+		if p.DEL <= 0 || p.Litros <= 0 {
+			continue
+		}
+		dias[p.DEL] = true
+		x := [3]float64{1, math.Log(float64(p.DEL)), -float64(p.DEL)}
+		y := math.Log(p.Litros)
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				m[i][j] += x[i] * x[j]
+			}
+			v[i] += x[i] * y
+		}
+	}
+	if len(dias) < 3 {
+		return CurvaWood{}, false
+	}
+
+	coef, ok := resolverSistemaLinear([][]float64{m[0][:], m[1][:], m[2][:]}, v[:])
+	if !ok {
+		return CurvaWood{}, false
+	}
+	curva := CurvaWood{A: math.Exp(coef[0]), B: coef[1], C: coef[2]}
+	if curva.B <= 0 || curva.C <= 0 {
+		return CurvaWood{}, false
+	}
+	return curva, true
+}
+
+// Producao305Intervalos calcula a produção em 305 dias pelo método dos
+// intervalos entre controles (ICAR): a primeira pesagem vale desde o parto,
+// trapézios entre pesagens e a última projetada até o dia 305.
+func Producao305Intervalos(pontos []PontoLactacao) float64 {
+	var validos []PontoLactacao
+	for _, p := range pontos {
+		if p.DEL > 0 && p.DEL <= DiasLactacao {
+			validos = append(validos, p)
+		}
+	}
+	if len(validos) == 0 {
+		return 0
+	}
+	sort.Slice(validos, func(i, j int) bool { return validos[i].DEL < validos[j].DEL })
+
+	total := validos[0].Litros * float64(validos[0].DEL)
+	for i := 1; i < len(validos); i++ {
+		intervalo := float64(validos[i].DEL - validos[i-1].DEL)
+		total += intervalo * (validos[i].Litros + validos[i-1].Litros) / 2
+	}
+	ultimo := validos[len(validos)-1]
+	total += ultimo.Litros * float64(DiasLactacao-ultimo.DEL)
+	return total
+}
+
+// Lactacao resume a lactação corrente de uma vaca
+type Lactacao struct {
+	AnimalID       int             `json:"animal_id"`
+	Identificacao  string          `json:"identificacao"`
+	DataParto      time.Time       `json:"data_parto"`
+	DELAtual       int             `json:"del_atual"`
+	Pontos         []PontoLactacao `json:"pontos"`
+	Curva          CurvaWood       `json:"curva"`
+	CurvaAjustada  bool            `json:"curva_ajustada"`
+	Producao305    float64         `json:"producao_305"`
+	PicoDia        float64         `json:"pico_dia"`
+	PicoLitros     float64         `json:"pico_litros"`
+	UltimaProducao float64         `json:"ultima_producao"`
+	UltimaCCS      float64         `json:"ultima_ccs"`
+	CCSAlta        bool            `json:"ccs_alta"`
+}
+
+// AnalisarLactacao usa os controles posteriores ao parto para ajustar a curva
+// e estimar a produção corrigida para 305 dias. Sem ajuste possível, recai
+// no método dos intervalos.
+func AnalisarLactacao(animal models.Animal, parto time.Time, controles []models.ControleLeiteiro, referencia time.Time) Lactacao {
+	l := Lactacao{
+		AnimalID:      animal.ID,
+		Identificacao: animal.Identificacao,
+		DataParto:     parto,
+		DELAtual:      int(inicioDoDia(referencia).Sub(parto).Hours() / 24),
+	}
+
+	sort.Slice(controles, func(i, j int) bool { return controles[i].Data.Before(controles[j].Data) })
+	for _, c := range controles {
+		if c.AnimalID != animal.ID || !c.Data.After(parto) {
+			continue
+		}
+		del := int(c.Data.Sub(parto).Hours() / 24)
+		l.Pontos = append(l.Pontos, PontoLactacao{DEL: del, Litros: c.Litros})
+		l.UltimaProducao = c.Litros
+		if c.CCS > 0 {
+			l.UltimaCCS = c.CCS
+		}
+	}
+	l.CCSAlta = l.UltimaCCS > LimiteCCSVaca
+
+	l.Curva, l.CurvaAjustada = AjustarCurvaWood(l.Pontos)
+	if l.CurvaAjustada {
+		for dia := 1; dia <= DiasLactacao; dia++ {
+			l.Producao305 += l.Curva.Producao(float64(dia))
+		}
+		l.PicoDia, l.PicoLitros = l.Curva.Pico()
+	} else {
+		l.Producao305 = Producao305Intervalos(l.Pontos)
+	}
+	return l
+}
+
+// ResumoRebanhoLeiteiro consolida os indicadores do rebanho em lactação
+type ResumoRebanhoLeiteiro struct {
+	VacasLactacao    int     `json:"vacas_lactacao"`
+	ProducaoDiaria   float64 `json:"producao_diaria"` // soma das últimas pesagens
+	MediaPorVaca     float64 `json:"media_por_vaca"`
+	DELMedio         float64 `json:"del_medio"`
+	Producao305Media float64 `json:"producao_305_media"`
+	VacasCCSAlta     int     `json:"vacas_ccs_alta"`
+}
+
+func ResumirRebanhoLeiteiro(lactacoes []Lactacao) ResumoRebanhoLeiteiro {
+	var r ResumoRebanhoLeiteiro
+	var somaDEL, soma305 float64
+	for _, l := range lactacoes {
+		if len(l.Pontos) == 0 {
+			continue
+		}
+		r.VacasLactacao++
+		r.ProducaoDiaria += l.UltimaProducao
+		somaDEL += float64(l.DELAtual)
+		soma305 += l.Producao305
+		if l.CCSAlta {
+			r.VacasCCSAlta++
+		}
+	}
+	if r.VacasLactacao > 0 {
+		n := float64(r.VacasLactacao)
+		r.MediaPorVaca = r.ProducaoDiaria / n
+		r.DELMedio = somaDEL / n
+		r.Producao305Media = soma305 / n
+	}
+	return r
+}
+
+// AvaliacaoTanque indica se a análise do tanque está fora dos limites legais
+type AvaliacaoTanque struct {
+	Analise  models.AnaliseTanque `json:"analise"`
+	CCSAcima bool                 `json:"ccs_acima"`
+	CBTAcima bool                 `json:"cbt_acima"`
+}
+
+func AvaliarTanque(analises []models.AnaliseTanque) []AvaliacaoTanque {
+	avaliacoes := make([]AvaliacaoTanque, 0, len(analises))
+	for _, a := range analises {
+		avaliacoes = append(avaliacoes, AvaliacaoTanque{
+			Analise:  a,
+			CCSAcima: a.CCS > LimiteCCSTanque,
+			CBTAcima: a.CBT > LimiteCBTTanque,
+		})
+	}
+	return avaliacoes
+}
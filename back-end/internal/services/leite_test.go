@@ -0,0 +1,67 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAjustarCurvaWood(t *testing.T) {
+	// Curva de referência a=15, b=0,2, c=0,004: pico em b/c = 50 dias, com
+	// a·50^0,2·e^−0,2 = 26,855 L/dia
+	referencia := CurvaWood{A: 15, B: 0.2, C: 0.004}
+	amostrar := func(dias ...int) []PontoLactacao {
+		var pontos []PontoLactacao
+		for _, d := range dias {
+			pontos = append(pontos, PontoLactacao{DEL: d, Litros: referencia.Producao(float64(d))})
+		}
+		return pontos
+	}
+
+	casos := []struct {
+		nome   string
+		pontos []PontoLactacao
+		ok     bool
+	}{
+		{"controles mensais", amostrar(10, 40, 70, 100, 130, 160, 190, 220, 250, 280), true},
+		{"três controles", amostrar(15, 60, 200), true},
+		{"dois dias distintos", amostrar(30, 30, 60), false},
+		{"produção crescente sem pico", []PontoLactacao{{10, 10}, {50, 20}, {100, 40}}, false},
+		{"pesagens inválidas ignoradas", append(amostrar(20, 90, 180), PontoLactacao{0, 30}, PontoLactacao{40, 0}), true},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			curva, ok := AjustarCurvaWood(c.pontos)
+			if ok != c.ok {
+				t.Fatalf("ok = %v, esperado %v (%+v)", ok, c.ok, curva)
+			}
+			if !ok {
+				return
+			}
+			if math.Abs(curva.A-referencia.A) > 1e-6 || math.Abs(curva.B-referencia.B) > 1e-9 || math.Abs(curva.C-referencia.C) > 1e-9 {
+				t.Errorf("curva = %+v, esperado %+v", curva, referencia)
+			}
+			dia, litros := curva.Pico()
+			if math.Abs(dia-50) > 1e-6 || math.Abs(litros-26.8551) > 1e-3 {
+				t.Errorf("pico = dia %.2f com %.4f L, esperado dia 50 com 26,8551 L", dia, litros)
+			}
+		})
+	}
+}
+
+func TestProducao305Intervalos(t *testing.T) {
+	casos := []struct {
+		nome   string
+		pontos []PontoLactacao
+		litros float64
+	}{
+		// 20·10 + 90·(20+25)/2 + 200·(25+15)/2 + 15·5
+		{"três controles", []PontoLactacao{{100, 25}, {10, 20}, {300, 15}}, 200 + 2025 + 4000 + 75},
+		{"um controle", []PontoLactacao{{30, 20}}, 20 * 305},
+		{"fora da lactação", []PontoLactacao{{0, 20}, {400, 10}}, 0},
+	}
+	for _, c := range casos {
+		if v := Producao305Intervalos(c.pontos); math.Abs(v-c.litros) > 1e-9 {
+			t.Errorf("%s: %.2f L, esperado %.2f L", c.nome, v, c.litros)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"encoding/json"
+	"hash/fnv"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// ColecaoGeoJSON é um FeatureCollection (RFC 7946)
+type ColecaoGeoJSON struct {
+	Type     string          `json:"type"`
+	Features []FeicaoGeoJSON `json:"features"`
+}
+
+// FeicaoGeoJSON é um Feature com geometria já serializada
+type FeicaoGeoJSON struct {
+	Type       string          `json:"type"`
+	Geometry   json.RawMessage `json:"geometry"`
+	Properties map[string]any  `json:"properties"`
+}
+
+// NovaColecao cria a coleção vazia (features nunca nulo, como exige o padrão)
+func NovaColecao() ColecaoGeoJSON {
+	return ColecaoGeoJSON{Type: "FeatureCollection", Features: []FeicaoGeoJSON{}}
+}
+
+// AdicionarPoligono inclui o anel como Polygon
+func (c *ColecaoGeoJSON) AdicionarPoligono(anel []models.Ponto, propriedades map[string]any) {
+	c.Features = append(c.Features, FeicaoGeoJSON{
+		Type:       "Feature",
+		Geometry:   json.RawMessage(PoligonoGeoJSON(anel)),
+		Properties: propriedades,
+	})
+}
+
+// AdicionarPonto inclui a posição como Point
+func (c *ColecaoGeoJSON) AdicionarPonto(p models.Ponto, propriedades map[string]any) {
+	geometria, _ := json.Marshal(map[string]any{
+		"type":        "Point",
+		"coordinates": [2]float64{arredondarGrau(p.Longitude), arredondarGrau(p.Latitude)},
+	})
+	c.Features = append(c.Features, FeicaoGeoJSON{Type: "Feature", Geometry: geometria, Properties: propriedades})
+}
+
+// Parâmetros de fertilidade mapeados (chaves usadas na API e nos filtros)
+const (
+	ParametroPH        = "ph"
+	ParametroFosforo   = "fosforo"
+	ParametroPotassio  = "potassio"
+	ParametroSaturacao = "v"
+)
+
+// ParametroSolo descreve um parâmetro de fertilidade e os limites superiores
+// das classes de interpretação (muito baixo, baixo, médio e adequado; acima
+// do último é alto)
+type ParametroSolo struct {
+	Chave   string     `json:"chave"`
+	Nome    string     `json:"nome"`
+	Unidade string     `json:"unidade"`
+	Casas   int        `json:"casas"`
+	Limites [4]float64 `json:"limites"`
+}
+
+// ParametrosSolo segue faixas de interpretação usuais para solos de
+// Cerrado (Sousa & Lobato), com P em Mehlich-1 para argila de 36 a 60%
+var ParametrosSolo = []ParametroSolo{
+	{Chave: ParametroPH, Nome: "pH (CaCl₂)", Casas: 1, Limites: [4]float64{4.4, 4.8, 5.5, 6.0}},
+	{Chave: ParametroFosforo, Nome: "Fósforo", Unidade: "mg/dm³", Casas: 1, Limites: [4]float64{3, 6, 8, 12}},
+	{Chave: ParametroPotassio, Nome: "Potássio", Unidade: "cmolc/dm³", Casas: 2, Limites: [4]float64{0.04, 0.08, 0.13, 0.20}},
+	{Chave: ParametroSaturacao, Nome: "Saturação por bases", Unidade: "V%", Casas: 0, Limites: [4]float64{20, 35, 50, 70}},
+}
+
+// ClassesFertilidade são as classes de interpretação, da mais baixa à mais alta
+var ClassesFertilidade = []string{"Muito baixo", "Baixo", "Médio", "Adequado", "Alto"}
+
+// CoresFertilidade acompanha ClassesFertilidade (vermelho a verde escuro)
+var CoresFertilidade = []string{"#d73027", "#fc8d59", "#fee08b", "#91cf60", "#1a9850"}
+
+// BuscarParametroSolo localiza o parâmetro pela chave
+func BuscarParametroSolo(chave string) (ParametroSolo, bool) {
+	for _, p := range ParametrosSolo {
+		if p.Chave == chave {
+			return p, true
+		}
+	}
+	return ParametroSolo{}, false
+}
+
+// Classe retorna o índice da classe de interpretação do valor
+func (p ParametroSolo) Classe(valor float64) int {
+	for i, limite := range p.Limites {
+		if valor <= limite {
+			return i
+		}
+	}
+	return len(p.Limites)
+}
+
+// Faixa descreve os limites da classe i, como "≤ 4,4" ou "4,4 – 4,8"
+func (p ParametroSolo) Faixa(i int) string {
+	switch {
+	case i == 0:
+		return "≤ " + FormatarDecimal(p.Limites[0], p.Casas)
+	case i >= len(p.Limites):
+		return "> " + FormatarDecimal(p.Limites[len(p.Limites)-1], p.Casas)
+	}
+	return FormatarDecimal(p.Limites[i-1], p.Casas) + " – " + FormatarDecimal(p.Limites[i], p.Casas)
+}
+
+// ValorParametro extrai o parâmetro da análise de solo
+func ValorParametro(a models.AnaliseSolo, chave string) float64 {
+	switch chave {
+	case ParametroPH:
+		return a.PH
+	case ParametroFosforo:
+		return a.Fosforo
+	case ParametroPotassio:
+		return a.Potassio
+	case ParametroSaturacao:
+		return a.SaturacaoBases()
+	}
+	return 0
+}
+
+// coresCultura fixa a cor das culturas mais comuns no mapa
+var coresCultura = map[string]string{
+	"Soja":           "#2ca25f",
+	"Milho":          "#fec44f",
+	"Milho safrinha": "#fe9929",
+	"Feijão":         "#8c6bb1",
+	"Algodão":        "#9ecae1",
+	"Trigo":          "#d95f0e",
+	"Sorgo":          "#a6761d",
+	"Arroz":          "#66c2a4",
+	"Café":           "#6f4e37",
+	"Cana-de-açúcar": "#1b7837",
+	"Girassol":       "#ffd92f",
+	"Aveia":          "#bababa",
+}
+
+var paletaExtra = []string{"#e7298a", "#7570b3", "#1f78b4", "#b2df8a", "#fb9a99", "#cab2d6"}
+
+// CorCultura dá uma cor estável para a cultura; culturas fora da lista
+// recebem uma cor da paleta extra conforme o nome
+func CorCultura(cultura string) string {
+	if cultura == "" {
+		return "#adb5bd"
+	}
+	if cor, ok := coresCultura[cultura]; ok {
+		return cor
+	}
+	h := fnv.New32a()
+	h.Write([]byte(chaveProduto(cultura)))
+	return paletaExtra[h.Sum32()%uint32(len(paletaExtra))]
+}
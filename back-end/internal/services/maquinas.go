@@ -0,0 +1,215 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// Antecedência dos lembretes: o plano fica "próximo" quando falta até 10%
+// do intervalo de uso ou até 15 dias do intervalo de calendário
+const (
+	AntecedenciaManutencaoFracao = 0.10
+	AntecedenciaManutencaoDias   = 15
+)
+
+// JanelaTaxaUsoDias é o histórico de leituras usado para estimar o uso
+// diário da máquina e projetar a data dos vencimentos por horímetro
+const JanelaTaxaUsoDias = 90
+
+// Situações do plano de manutenção
+const (
+	ManutencaoEmDia   = "em dia"
+	ManutencaoProxima = "próxima"
+	ManutencaoVencida = "vencida"
+)
+
+// SituacaoManutencao é o vencimento de um plano de manutenção. DataPrevista
+// é a data do calendário ou a projetada pelo ritmo de uso, a que vier
+// primeiro; fica zerada quando o plano é só por uso e a máquina não tem
+// leituras recentes.
+type SituacaoManutencao struct {
+	Maquina      models.Maquina         `json:"maquina"`
+	Plano        models.PlanoManutencao `json:"plano"`
+	VenceMedidor float64                `json:"vence_medidor"`
+	FaltaMedidor float64                `json:"falta_medidor"` // negativo quando vencido
+	VenceData    time.Time              `json:"vence_data"`
+	FaltaDias    int                    `json:"falta_dias"` // negativo quando vencido
+	DataPrevista time.Time              `json:"data_prevista"`
+	Projetada    bool                   `json:"projetada"` // data estimada pelo uso
+	Situacao     string                 `json:"situacao"`
+}
+
+// PorMedidor indica plano com intervalo de horas ou quilômetros
+func (s SituacaoManutencao) PorMedidor() bool {
+	return s.Plano.IntervaloMedidor > 0
+}
+
+// PorCalendario indica plano com intervalo em dias
+func (s SituacaoManutencao) PorCalendario() bool {
+	return s.Plano.IntervaloDias > 0
+}
+
+// TextoMedidor resume o vencimento por uso (ex.: "faltam 20 h")
+func (s SituacaoManutencao) TextoMedidor() string {
+	if s.FaltaMedidor <= 0 {
+		return fmt.Sprintf("%s %s em atraso", FormatarDecimal(-s.FaltaMedidor, 0), s.Maquina.Unidade)
+	}
+	return fmt.Sprintf("faltam %s %s", FormatarDecimal(s.FaltaMedidor, 0), s.Maquina.Unidade)
+}
+
+// TextoCalendario resume o vencimento por data (ex.: "vence 02/11/2026")
+func (s SituacaoManutencao) TextoCalendario() string {
+	if s.FaltaDias < 0 {
+		return "venceu " + s.VenceData.Format("02/01/2006")
+	}
+	return "vence " + s.VenceData.Format("02/01/2006")
+}
+
+// Cor é a classe Bootstrap da situação
+func (s SituacaoManutencao) Cor() string {
+	switch s.Situacao {
+	case ManutencaoVencida:
+		return "danger"
+	case ManutencaoProxima:
+		return "warning"
+	}
+	return "success"
+}
+
+// TaxaUsoDiaria estima o uso médio por dia (h ou km) a partir das leituras
+// dos últimos 90 dias. Máquinas com histórico mais curto usam o período
+// desde a primeira leitura, com mínimo de 14 dias para não superestimar.
+func TaxaUsoDiaria(leituras []models.LeituraMedidor, hoje time.Time) float64 {
+	hoje = inicioDoDia(hoje)
+	limite := hoje.AddDate(0, 0, -JanelaTaxaUsoDias)
+	inicio := hoje
+	total := 0.0
+	for _, l := range leituras {
+		if l.Data.Before(limite) || l.Data.After(hoje) {
+			continue
+		}
+		total += l.Incremento
+		if l.Data.Before(inicio) {
+			inicio = l.Data
+		}
+	}
+	if total <= 0 {
+		return 0
+	}
+	dias := hoje.Sub(inicio).Hours() / 24
+	if dias < 14 {
+		dias = 14
+	}
+	return total / dias
+}
+
+// AvaliarPlano calcula o vencimento do plano para a leitura atual da máquina
+func AvaliarPlano(m models.Maquina, p models.PlanoManutencao, taxaDiaria float64, hoje time.Time) SituacaoManutencao {
+	hoje = inicioDoDia(hoje)
+	s := SituacaoManutencao{Maquina: m, Plano: p, Situacao: ManutencaoEmDia}
+	piorar := func(situacao string) {
+		if situacao == ManutencaoVencida || (situacao == ManutencaoProxima && s.Situacao == ManutencaoEmDia) {
+			s.Situacao = situacao
+		}
+	}
+
+	if p.IntervaloMedidor > 0 {
+		s.VenceMedidor = p.UltimoMedidor + p.IntervaloMedidor
+		s.FaltaMedidor = s.VenceMedidor - m.Medidor
+		switch {
+		case s.FaltaMedidor <= 0:
+			piorar(ManutencaoVencida)
+			s.DataPrevista = hoje
+		case s.FaltaMedidor <= p.IntervaloMedidor*AntecedenciaManutencaoFracao:
+			piorar(ManutencaoProxima)
+		}
+		if s.FaltaMedidor > 0 && taxaDiaria > 0 {
+			s.DataPrevista = hoje.AddDate(0, 0, int(math.Ceil(s.FaltaMedidor/taxaDiaria)))
+			s.Projetada = true
+		}
+	}
+
+	if p.IntervaloDias > 0 {
+		s.VenceData = inicioDoDia(p.UltimaData).AddDate(0, 0, p.IntervaloDias)
+		s.FaltaDias = int(s.VenceData.Sub(hoje).Hours() / 24)
+		switch {
+		case s.FaltaDias < 0:
+			piorar(ManutencaoVencida)
+		case s.FaltaDias <= AntecedenciaManutencaoDias:
+			piorar(ManutencaoProxima)
+		}
+		if s.DataPrevista.IsZero() || s.VenceData.Before(s.DataPrevista) {
+			s.DataPrevista = s.VenceData
+			s.Projetada = false
+		}
+	}
+	return s
+}
+
+// AvaliarManutencoes avalia os planos ativos de todas as máquinas, com os
+// vencidos primeiro e depois pela data prevista
+func AvaliarManutencoes(maquinas []models.Maquina, planos []models.PlanoManutencao, leituras []models.LeituraMedidor, hoje time.Time) []SituacaoManutencao {
+	leiturasPorMaquina := map[int][]models.LeituraMedidor{}
+	for _, l := range leituras {
+		leiturasPorMaquina[l.MaquinaID] = append(leiturasPorMaquina[l.MaquinaID], l)
+	}
+	porID := map[int]models.Maquina{}
+	for _, m := range maquinas {
+		porID[m.ID] = m
+	}
+
+	var situacoes []SituacaoManutencao
+	for _, p := range planos {
+		m, ok := porID[p.MaquinaID]
+		if !ok || !p.Ativo {
+			continue
+		}
+		situacoes = append(situacoes, AvaliarPlano(m, p, TaxaUsoDiaria(leiturasPorMaquina[m.ID], hoje), hoje))
+	}
+
+	ordem := map[string]int{ManutencaoVencida: 0, ManutencaoProxima: 1, ManutencaoEmDia: 2}
+	sort.SliceStable(situacoes, func(i, j int) bool {
+		a, b := situacoes[i], situacoes[j]
+		if ordem[a.Situacao] != ordem[b.Situacao] {
+			return ordem[a.Situacao] < ordem[b.Situacao]
+		}
+		if a.DataPrevista.IsZero() != b.DataPrevista.IsZero() {
+			return !a.DataPrevista.IsZero()
+		}
+		return a.DataPrevista.Before(b.DataPrevista)
+	})
+	return situacoes
+}
+
+// LembretesManutencao filtra os planos vencidos ou próximos do vencimento
+func LembretesManutencao(situacoes []SituacaoManutencao) []SituacaoManutencao {
+	var lembretes []SituacaoManutencao
+	for _, s := range situacoes {
+		if s.Situacao != ManutencaoEmDia {
+			lembretes = append(lembretes, s)
+		}
+	}
+	return lembretes
+}
+
+// HorasAplicacao converte a área aplicada em horas de máquina pela
+// capacidade operacional (ha/h). Retorna 0 sem capacidade cadastrada.
+func HorasAplicacao(area, capacidade float64) float64 {
+	if capacidade <= 0 || area <= 0 {
+		return 0
+	}
+	return math.Round(area/capacidade*10) / 10
+}
+
+// CustoManutencoes soma o custo dos serviços registrados
+func CustoManutencoes(manutencoes []models.Manutencao) float64 {
+	total := 0.0
+	for _, m := range manutencoes {
+		total += m.Custo
+	}
+	return total
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// Fração do nível de ação a partir da qual a ocorrência fica em atenção
+const fracaoAtencaoMIP = 0.7
+
+// Situação da ocorrência em relação ao nível de ação
+const (
+	SituacaoSemNivel = "Sem nível"
+	SituacaoAbaixo   = "Abaixo do nível"
+	SituacaoAtencao  = "Atenção"
+	SituacaoAcao     = "Nível de ação"
+)
+
+// EstadiosMonitoramento são os estádios aceitos no monitoramento e nos
+// níveis de ação
+var EstadiosMonitoramento = []string{EstadioVegetativo, EstadioFlorescimento, EstadioEnchimento, EstadioMaturacao}
+
+// NiveisAcaoPadrao são níveis de controle de referência (Embrapa e
+// literatura de MIP) para iniciar o cadastro; devem ser ajustados à região
+func NiveisAcaoPadrao() []models.NivelAcao {
+	nivel := func(cultura, alvo, tipo, medida string, limite float64, estadio, obs string) models.NivelAcao {
+		return models.NivelAcao{Cultura: cultura, Alvo: alvo, Tipo: tipo, Medida: medida, Limite: limite, Estadio: estadio, Observacoes: obs, Ativo: true}
+	}
+	return []models.NivelAcao{
+		nivel("Soja", "Lagartas desfolhadoras", models.AlvoPraga, models.MedidaContagem, 20, "", "Lagartas maiores que 1,5 cm por pano de batida"),
+		nivel("Soja", "Percevejos", models.AlvoPraga, models.MedidaContagem, 2, EstadioEnchimento, "Adultos e ninfas > 0,5 cm por pano de batida (1 para sementes)"),
+		nivel("Soja", "Desfolha", models.AlvoPraga, models.MedidaPercentual, 30, EstadioVegetativo, ""),
+		nivel("Soja", "Desfolha", models.AlvoPraga, models.MedidaPercentual, 15, EstadioFlorescimento, ""),
+		nivel("Soja", "Desfolha", models.AlvoPraga, models.MedidaPercentual, 15, EstadioEnchimento, ""),
+		nivel("Soja", "Ferrugem-asiática", models.AlvoDoenca, models.MedidaPercentual, 1, "", "Incidência de plantas com sintomas; aplicar aos primeiros sinais"),
+		nivel("Milho", "Lagarta-do-cartucho", models.AlvoPraga, models.MedidaPercentual, 20, EstadioVegetativo, "Plantas com folhas raspadas"),
+		nivel("Milho", "Percevejo-barriga-verde", models.AlvoPraga, models.MedidaContagem, 1, EstadioVegetativo, "Por metro de linha, até V4"),
+		nivel("Algodão", "Bicudo", models.AlvoPraga, models.MedidaPercentual, 5, "", "Botões florais com orifício de alimentação ou oviposição"),
+		nivel("Feijão", "Mosca-branca", models.AlvoPraga, models.MedidaContagem, 2, EstadioVegetativo, "Adultos por folíolo"),
+	}
+}
+
+// NivelAplicavel devolve o nível de ação ativo da cultura, alvo e medida,
+// preferindo o cadastrado para o estádio informado ao válido no ciclo todo
+func NivelAplicavel(niveis []models.NivelAcao, cultura, alvo, medida, estadio string) (models.NivelAcao, bool) {
+	var geral models.NivelAcao
+	achouGeral := false
+	for _, n := range niveis {
+		if !n.Ativo || !strings.EqualFold(n.Cultura, cultura) || !strings.EqualFold(n.Alvo, alvo) || n.Medida != medida {
+			continue
+		}
+		if n.Estadio == estadio && estadio != "" {
+			return n, true
+		}
+		if n.Estadio == "" && !achouGeral {
+			geral, achouGeral = n, true
+		}
+	}
+	return geral, achouGeral
+}
+
+// AvaliacaoOcorrencia é a ocorrência comparada ao nível de ação aplicável
+type AvaliacaoOcorrencia struct {
+	Ocorrencia models.OcorrenciaPraga `json:"ocorrencia"`
+	Nivel      models.NivelAcao       `json:"nivel"`
+	TemNivel   bool                   `json:"tem_nivel"`
+	Situacao   string                 `json:"situacao"`
+}
+
+// PercentualNivel é a média observada em relação ao nível de ação
+func (a AvaliacaoOcorrencia) PercentualNivel() float64 {
+	if !a.TemNivel || a.Nivel.Limite == 0 {
+		return 0
+	}
+	return a.Ocorrencia.Media() / a.Nivel.Limite * 100
+}
+
+// AvaliarOcorrencias classifica as ocorrências do monitoramento pelo nível
+// de ação da cultura no estádio observado
+func AvaliarOcorrencias(m models.Monitoramento, cultura string, niveis []models.NivelAcao) []AvaliacaoOcorrencia {
+	avaliacoes := make([]AvaliacaoOcorrencia, 0, len(m.Ocorrencias))
+	for _, o := range m.Ocorrencias {
+		a := AvaliacaoOcorrencia{Ocorrencia: o, Situacao: SituacaoSemNivel}
+		a.Nivel, a.TemNivel = NivelAplicavel(niveis, cultura, o.Alvo, o.Medida, m.Estadio)
+		if a.TemNivel {
+			switch media := o.Media(); {
+			case media >= a.Nivel.Limite:
+				a.Situacao = SituacaoAcao
+			case media >= a.Nivel.Limite*fracaoAtencaoMIP:
+				a.Situacao = SituacaoAtencao
+			default:
+				a.Situacao = SituacaoAbaixo
+			}
+		}
+		avaliacoes = append(avaliacoes, a)
+	}
+	return avaliacoes
+}
+
+// AlertaMIP é um alvo que atingiu o nível de ação no último monitoramento
+// da safra, com a sugestão de agendar uma consulta
+type AlertaMIP struct {
+	Safra            models.Safra         `json:"safra"`
+	Monitoramento    models.Monitoramento `json:"monitoramento"`
+	Avaliacao        AvaliacaoOcorrencia  `json:"avaliacao"`
+	ConsultaAgendada bool                 `json:"consulta_agendada"`
+	DataSugerida     time.Time            `json:"data_sugerida"`
+}
+
+// ObservacoesConsulta é o texto sugerido para a consulta de controle
+func (a AlertaMIP) ObservacoesConsulta() string {
+	o := a.Avaliacao.Ocorrencia
+	return fmt.Sprintf("Monitoramento MIP de %s (%s): %s com %s %s, nível de ação %s.",
+		a.Monitoramento.Data.Format("02/01/2006"), a.Monitoramento.Estadio, o.Alvo,
+		FormatarDecimal(o.Media(), 1), models.UnidadeMedida(o.Medida), FormatarDecimal(a.Avaliacao.Nivel.Limite, 1))
+}
+
+// AlertasMIP verifica, nas safras não colhidas, o último monitoramento de
+// cada alvo e gera alerta quando a média atinge o nível de ação. Indica se
+// já há consulta da safra marcada a partir da data do monitoramento.
+func AlertasMIP(safras []models.Safra, monitoramentos []models.Monitoramento, consultas []models.Consulta, niveis []models.NivelAcao, hoje time.Time) []AlertaMIP {
+	porSafra := make(map[int]models.Safra)
+	for _, s := range safras {
+		if s.ColheitaReal == nil {
+			porSafra[s.ID] = s
+		}
+	}
+
+	// Último monitoramento de cada alvo por safra
+	type chaveAlvo struct {
+		safra int
+		alvo  string
+	}
+	ultimos := make(map[chaveAlvo]AlertaMIP)
+	var ordem []chaveAlvo
+	for _, m := range monitoramentos {
+		safra, ok := porSafra[m.SafraID]
+		if !ok {
+			continue
+		}
+		for _, a := range AvaliarOcorrencias(m, safra.Cultura, niveis) {
+			k := chaveAlvo{m.SafraID, strings.ToLower(a.Ocorrencia.Alvo)}
+			atual, existe := ultimos[k]
+			if existe && !m.Data.After(atual.Monitoramento.Data) {
+				continue
+			}
+			if !existe {
+				ordem = append(ordem, k)
+			}
+			ultimos[k] = AlertaMIP{Safra: safra, Monitoramento: m, Avaliacao: a}
+		}
+	}
+
+	amanha := inicioDoDia(hoje).AddDate(0, 0, 1)
+	var alertas []AlertaMIP
+	for _, k := range ordem {
+		a := ultimos[k]
+		if a.Avaliacao.Situacao != SituacaoAcao {
+			continue
+		}
+		for _, c := range consultas {
+			if c.SafraID == a.Safra.ID && !c.DataConsulta.Before(a.Monitoramento.Data) {
+				a.ConsultaAgendada = true
+			}
+		}
+		a.DataSugerida = amanha
+		alertas = append(alertas, a)
+	}
+	sort.SliceStable(alertas, func(i, j int) bool {
+		if alertas[i].ConsultaAgendada != alertas[j].ConsultaAgendada {
+			return !alertas[i].ConsultaAgendada
+		}
+		return alertas[i].Monitoramento.Data.After(alertas[j].Monitoramento.Data)
+	})
+	return alertas
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+func TestAvaliarOcorrencias(t *testing.T) {
+	niveis := NiveisAcaoPadrao()
+	contagem := func(alvo string, total float64, panos int) models.OcorrenciaPraga {
+		return models.OcorrenciaPraga{Alvo: alvo, Medida: models.MedidaContagem, Valor: total, Pontos: panos}
+	}
+	percentual := func(alvo string, valor float64) models.OcorrenciaPraga {
+		return models.OcorrenciaPraga{Alvo: alvo, Medida: models.MedidaPercentual, Valor: valor}
+	}
+
+	casos := []struct {
+		nome       string
+		estadio    string
+		ocorrencia models.OcorrenciaPraga
+		situacao   string
+		limite     float64
+	}{
+		{"percevejos no enchimento", EstadioEnchimento, contagem("Percevejos", 30, 10), SituacaoAcao, 2},
+		{"percevejos fora do estádio do nível", EstadioVegetativo, contagem("Percevejos", 30, 10), SituacaoSemNivel, 0},
+		// Atenção a partir de 70% do nível: 14 lagartas por pano para nível 20
+		{"lagartas no limite da atenção", EstadioVegetativo, contagem("Lagartas desfolhadoras", 140, 10), SituacaoAtencao, 20},
+		{"lagartas abaixo da atenção", EstadioVegetativo, contagem("Lagartas desfolhadoras", 139, 10), SituacaoAbaixo, 20},
+		{"desfolha de 20% no vegetativo", EstadioVegetativo, percentual("Desfolha", 20), SituacaoAbaixo, 30},
+		{"desfolha de 20% no florescimento", EstadioFlorescimento, percentual("Desfolha", 20), SituacaoAcao, 15},
+		{"alvo sem diferenciar maiúsculas", EstadioMaturacao, percentual("ferrugem-asiática", 1), SituacaoAcao, 1},
+		{"medida diferente da do nível", EstadioEnchimento, percentual("Percevejos", 50), SituacaoSemNivel, 0},
+	}
+	for _, c := range casos {
+		m := models.Monitoramento{Estadio: c.estadio, Ocorrencias: []models.OcorrenciaPraga{c.ocorrencia}}
+		a := AvaliarOcorrencias(m, "Soja", niveis)[0]
+		if a.Situacao != c.situacao || a.Nivel.Limite != c.limite {
+			t.Errorf("%s: %s com nível %.1f, esperado %s com nível %.1f", c.nome, a.Situacao, a.Nivel.Limite, c.situacao, c.limite)
+		}
+	}
+}
+
+func TestAlertasMIP(t *testing.T) {
+	dia := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	colhida := dia(20)
+	safras := []models.Safra{
+		{ID: 1, Cultura: "Soja"},
+		{ID: 2, Cultura: "Soja"},
+		{ID: 3, Cultura: "Soja", ColheitaReal: &colhida},
+	}
+	percevejos := func(total float64) []models.OcorrenciaPraga {
+		return []models.OcorrenciaPraga{{Alvo: "Percevejos", Medida: models.MedidaContagem, Valor: total, Pontos: 10}}
+	}
+	monitoramentos := []models.Monitoramento{
+		// Safra 1: o último monitoramento já está abaixo do nível
+		{ID: 1, SafraID: 1, Data: dia(5), Estadio: EstadioEnchimento, Ocorrencias: percevejos(40)},
+		{ID: 2, SafraID: 1, Data: dia(12), Estadio: EstadioEnchimento, Ocorrencias: percevejos(5)},
+		// Safra 2: nível de ação com consulta marcada depois do monitoramento
+		{ID: 3, SafraID: 2, Data: dia(10), Estadio: EstadioEnchimento, Ocorrencias: percevejos(25)},
+		{ID: 4, SafraID: 2, Data: dia(11), Estadio: EstadioFlorescimento, Ocorrencias: []models.OcorrenciaPraga{
+			{Alvo: "Desfolha", Medida: models.MedidaPercentual, Valor: 18},
+		}},
+		// Safra 3: já colhida
+		{ID: 5, SafraID: 3, Data: dia(14), Estadio: EstadioEnchimento, Ocorrencias: percevejos(60)},
+	}
+	consultas := []models.Consulta{{SafraID: 2, DataConsulta: dia(11)}}
+
+	alertas := AlertasMIP(safras, monitoramentos, consultas, NiveisAcaoPadrao(), dia(15))
+	if len(alertas) != 2 {
+		t.Fatalf("alertas = %d, esperado 2", len(alertas))
+	}
+	for _, a := range alertas {
+		if a.Safra.ID != 2 || !a.ConsultaAgendada {
+			t.Errorf("alerta da safra %d, monitoramento %d, consulta agendada %v", a.Safra.ID, a.Monitoramento.ID, a.ConsultaAgendada)
+		}
+		if !a.DataSugerida.Equal(dia(16)) {
+			t.Errorf("data sugerida = %s, esperado 16/01", a.DataSugerida.Format("02/01"))
+		}
+	}
+	// Mais recente primeiro
+	if alertas[0].Monitoramento.ID != 4 || alertas[1].Monitoramento.ID != 3 {
+		t.Errorf("ordem = %d, %d; esperado 4, 3", alertas[0].Monitoramento.ID, alertas[1].Monitoramento.ID)
+	}
+
+	// Sem consulta marcada, o alerta vem antes dos já agendados
+	monitoramentos = append(monitoramentos, models.Monitoramento{ID: 6, SafraID: 1, Data: dia(13), Estadio: EstadioEnchimento, Ocorrencias: percevejos(20)})
+	alertas = AlertasMIP(safras, monitoramentos, consultas, NiveisAcaoPadrao(), dia(15))
+	if len(alertas) != 3 || alertas[0].Safra.ID != 1 || alertas[0].ConsultaAgendada || !alertas[1].ConsultaAgendada {
+		t.Fatalf("alertas = %+v", alertas)
+	}
+}
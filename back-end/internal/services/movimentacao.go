@@ -0,0 +1,223 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// AplicarMovimentacao devolve o novo estoque por categoria após a GTA.
+// Saídas acima do saldo disponível são rejeitadas.
+func AplicarMovimentacao(estoque map[string]int, mov models.Movimentacao) (map[string]int, error) {
+	novo := make(map[string]int, len(estoque))
+	for categoria, qtd := range estoque {
+		novo[categoria] = qtd
+	}
+
+	for _, item := range mov.Itens {
+		if item.Quantidade <= 0 {
+			continue
+		}
+		switch mov.Tipo {
+		case models.MovimentacaoEntrada:
+			novo[item.Categoria] += item.Quantidade
+		case models.MovimentacaoSaida:
+			if novo[item.Categoria] < item.Quantidade {
+				return nil, fmt.Errorf("saldo insuficiente em %s: %d cabeças em estoque, saída de %d",
+					item.Categoria, novo[item.Categoria], item.Quantidade)
+			}
+			novo[item.Categoria] -= item.Quantidade
+		default:
+			return nil, fmt.Errorf("tipo de movimentação inválido: %q", mov.Tipo)
+		}
+	}
+	return novo, nil
+}
+
+// CategoriaGTA enquadra o animal do cadastro na estratificação da GTA pelo
+// sexo e pelos meses completos de idade na data. Sem sexo ou nascimento
+// informados não há como enquadrá-lo.
+func CategoriaGTA(a models.Animal, data time.Time) (string, bool) {
+	if a.DataNascimento == nil || (a.Sexo != "M" && a.Sexo != "F") {
+		return "", false
+	}
+	nasc := *a.DataNascimento
+	meses := (data.Year()-nasc.Year())*12 + int(data.Month()) - int(nasc.Month())
+	if data.Day() < nasc.Day() {
+		meses--
+	}
+	if meses < 0 {
+		return "", false
+	}
+
+	faixa := 0 // posição do macho da faixa em models.CategoriasGTA
+	switch {
+	case meses <= 12:
+	case meses <= 24:
+		faixa = 2
+	case meses <= 36:
+		faixa = 4
+	default:
+		faixa = 6
+	}
+	if a.Sexo == "F" {
+		faixa++
+	}
+	return models.CategoriasGTA[faixa], true
+}
+
+// EstoquePorAnimais conta os animais ativos do cadastro por categoria da GTA,
+// para conferir ou abrir o saldo do rebanho. Devolve também quantos ficaram
+// sem categoria por falta de sexo ou nascimento.
+func EstoquePorAnimais(animais []models.Animal, data time.Time) (map[string]int, int) {
+	estoque := make(map[string]int)
+	semCategoria := 0
+	for _, a := range animais {
+		if !a.Ativo {
+			continue
+		}
+		if c, ok := CategoriaGTA(a, data); ok {
+			estoque[c]++
+		} else {
+			semCategoria++
+		}
+	}
+	return estoque, semCategoria
+}
+
+// Layout do arquivo de rastreabilidade (largura fixa, ASCII, uma linha por registro):
+//
+//	Registro 0 – cabeçalho
+//	  1   tipo "0"
+//	  14  CPF/CNPJ do produtor (somente dígitos, zeros à esquerda)
+//	  60  nome da propriedade
+//	  2   UF da propriedade
+//	  8   data inicial (AAAAMMDD)
+//	  8   data final (AAAAMMDD)
+//	  8   data de geração (AAAAMMDD)
+//
+//	Registro 1 – detalhe (uma linha por categoria de cada GTA)
+//	  1   tipo "1"
+//	  2   UF emissora da GTA
+//	  3   série da GTA
+//	  12  número da GTA (zeros à esquerda)
+//	  8   data do trânsito (AAAAMMDD)
+//	  1   sentido: E (entrada) ou S (saída)
+//	  20  finalidade
+//	  60  origem
+//	  60  destino
+//	  2   código da categoria (posição em models.CategoriasGTA, 01–08)
+//	  30  descrição da categoria
+//	  6   quantidade de cabeças
+//
+//	Registro 9 – trailer
+//	  1   tipo "9"
+//	  6   quantidade de registros de detalhe
+//	  8   total de cabeças movimentadas
+const (
+	RegistroCabecalho = "0"
+	RegistroDetalhe   = "1"
+	RegistroTrailer   = "9"
+)
+
+// ExportarRastreabilidade gera o arquivo de largura fixa com as movimentações
+// da propriedade no período
+func ExportarRastreabilidade(cpfCnpj string, propriedade models.Propriedade, movs []models.Movimentacao, inicio, fim, geracao time.Time) string {
+	var b strings.Builder
+
+	b.WriteString(RegistroCabecalho)
+	b.WriteString(campoNumerico(somenteDigitos(cpfCnpj), 14))
+	b.WriteString(campoTexto(propriedade.Nome, 60))
+	b.WriteString(campoTexto(propriedade.Estado, 2))
+	b.WriteString(inicio.Format("20060102"))
+	b.WriteString(fim.Format("20060102"))
+	b.WriteString(geracao.Format("20060102"))
+	b.WriteString("\r\n")
+
+	registros, cabecas := 0, 0
+	for _, m := range movs {
+		sentido := "E"
+		if m.Tipo == models.MovimentacaoSaida {
+			sentido = "S"
+		}
+		for _, item := range m.Itens {
+			if item.Quantidade <= 0 {
+				continue
+			}
+			b.WriteString(RegistroDetalhe)
+			b.WriteString(campoTexto(m.GTAUF, 2))
+			b.WriteString(campoTexto(m.GTASerie, 3))
+			b.WriteString(campoNumerico(somenteDigitos(m.GTANumero), 12))
+			b.WriteString(m.Data.Format("20060102"))
+			b.WriteString(sentido)
+			b.WriteString(campoTexto(m.Finalidade, 20))
+			b.WriteString(campoTexto(m.Origem, 60))
+			b.WriteString(campoTexto(m.Destino, 60))
+			b.WriteString(campoNumerico(fmt.Sprint(codigoCategoriaGTA(item.Categoria)), 2))
+			b.WriteString(campoTexto(item.Categoria, 30))
+			b.WriteString(campoNumerico(fmt.Sprint(item.Quantidade), 6))
+			b.WriteString("\r\n")
+			registros++
+			cabecas += item.Quantidade
+		}
+	}
+
+	b.WriteString(RegistroTrailer)
+	b.WriteString(campoNumerico(fmt.Sprint(registros), 6))
+	b.WriteString(campoNumerico(fmt.Sprint(cabecas), 8))
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+func codigoCategoriaGTA(categoria string) int {
+	for i, c := range models.CategoriasGTA {
+		if c == categoria {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+var acentos = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "ê", "e", "è", "e", "í", "i", "ó", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ü", "u", "ç", "c", "ñ", "n",
+	"Á", "A", "À", "A", "Â", "A", "Ã", "A", "Ä", "A",
+	"É", "E", "Ê", "E", "È", "E", "Í", "I", "Ó", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+	"Ú", "U", "Ü", "U", "Ç", "C", "Ñ", "N",
+)
+
+// campoTexto converte para ASCII maiúsculo e ajusta à largura com espaços à direita
+func campoTexto(s string, largura int) string {
+	s = strings.ToUpper(acentos.Replace(strings.TrimSpace(s)))
+	ascii := make([]byte, 0, largura)
+	for _, r := range s {
+		if len(ascii) == largura {
+			break
+		}
+		if r < 32 || r > 126 {
+			r = ' '
+		}
+		ascii = append(ascii, byte(r))
+	}
+	return string(ascii) + strings.Repeat(" ", largura-len(ascii))
+}
+
+// campoNumerico alinha à direita com zeros, truncando à esquerda se exceder
+func campoNumerico(s string, largura int) string {
+	if len(s) > largura {
+		return s[len(s)-largura:]
+	}
+	return strings.Repeat("0", largura-len(s)) + s
+}
+
+func somenteDigitos(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
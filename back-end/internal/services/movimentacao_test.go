@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+func TestCategoriaGTA(t *testing.T) {
+	data := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	nascido := func(ano int, mes time.Month, dia int) *time.Time {
+		n := time.Date(ano, mes, dia, 0, 0, 0, 0, time.UTC)
+		return &n
+	}
+
+	casos := []struct {
+		nome      string
+		animal    models.Animal
+		categoria string
+		ok        bool
+	}{
+		{"bezerro", models.Animal{Sexo: "M", DataNascimento: nascido(2026, 4, 1)}, "Machos 0-12 meses", true},
+		{"12 meses completos", models.Animal{Sexo: "F", DataNascimento: nascido(2025, 10, 10)}, "Fêmeas 0-12 meses", true},
+		{"véspera dos 13 meses", models.Animal{Sexo: "F", DataNascimento: nascido(2025, 9, 11)}, "Fêmeas 0-12 meses", true},
+		{"13 meses completos", models.Animal{Sexo: "F", DataNascimento: nascido(2025, 9, 10)}, "Fêmeas 13-24 meses", true},
+		{"garrote", models.Animal{Sexo: "M", DataNascimento: nascido(2024, 3, 1)}, "Machos 25-36 meses", true},
+		{"vaca", models.Animal{Sexo: "F", DataNascimento: nascido(2020, 1, 1)}, "Fêmeas acima de 36 meses", true},
+		{"sem nascimento", models.Animal{Sexo: "F"}, "", false},
+		{"sem sexo", models.Animal{DataNascimento: nascido(2022, 1, 1)}, "", false},
+		{"nascido depois da data", models.Animal{Sexo: "M", DataNascimento: nascido(2026, 11, 1)}, "", false},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			categoria, ok := CategoriaGTA(c.animal, data)
+			if categoria != c.categoria || ok != c.ok {
+				t.Errorf("CategoriaGTA = %q, %v; esperado %q, %v", categoria, ok, c.categoria, c.ok)
+			}
+		})
+	}
+}
+
+func TestEstoquePorAnimais(t *testing.T) {
+	data := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+	nasc := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	animais := []models.Animal{
+		{Sexo: "F", DataNascimento: &nasc, Ativo: true},
+		{Sexo: "F", DataNascimento: &nasc, Ativo: true},
+		{Sexo: "M", DataNascimento: &nasc, Ativo: true},
+		{Sexo: "F", DataNascimento: &nasc}, // baixado
+		{Sexo: "F", Ativo: true},
+	}
+
+	estoque, semCategoria := EstoquePorAnimais(animais, data)
+	if estoque["Fêmeas acima de 36 meses"] != 2 || estoque["Machos acima de 36 meses"] != 1 || len(estoque) != 2 {
+		t.Errorf("estoque = %v", estoque)
+	}
+	if semCategoria != 1 {
+		t.Errorf("semCategoria = %d, esperado 1", semCategoria)
+	}
+
+	// O saldo aberto pelo cadastro comporta a saída dos animais contados
+	mov := models.Movimentacao{Tipo: models.MovimentacaoSaida, Itens: []models.MovimentacaoCategoria{
+		{Categoria: "Fêmeas acima de 36 meses", Quantidade: 2},
+	}}
+	novo, err := AplicarMovimentacao(estoque, mov)
+	if err != nil || novo["Fêmeas acima de 36 meses"] != 0 || estoque["Fêmeas acima de 36 meses"] != 2 {
+		t.Errorf("AplicarMovimentacao = %v, %v; estoque original %v", novo, err, estoque)
+	}
+}
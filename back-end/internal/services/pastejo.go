@@ -0,0 +1,129 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// Forrageira traz o período de descanso sugerido para o manejo rotacionado
+type Forrageira struct {
+	Nome         string `json:"nome"`
+	DiasDescanso int    `json:"dias_descanso"`
+}
+
+// Forrageiras é a referência inicial de períodos de descanso; o consultor
+// pode ajustar o valor no plano conforme a época do ano e a adubação
+var Forrageiras = []Forrageira{
+	{"Brachiaria brizantha cv. Marandu", 28},
+	{"Brachiaria brizantha cv. Xaraés", 28},
+	{"Brachiaria decumbens", 30},
+	{"Panicum maximum cv. Mombaça", 35},
+	{"Panicum maximum cv. Tanzânia", 30},
+	{"Panicum maximum cv. Zuri", 30},
+	{"Cynodon cv. Tifton 85", 25},
+	{"Pennisetum purpureum (capim-elefante)", 40},
+}
+
+// DiasOcupacao calcula o período de ocupação de cada piquete para um único
+// lote: PO = PD / (n − 1), arredondado para cima e com mínimo de 1 dia
+func DiasOcupacao(diasDescanso, piquetes int) int {
+	if piquetes < 2 || diasDescanso <= 0 {
+		return 1
+	}
+	po := int(math.Ceil(float64(diasDescanso) / float64(piquetes-1)))
+	if po < 1 {
+		po = 1
+	}
+	return po
+}
+
+// TaxaLotacao retorna UA/ha na área total do módulo e a pressão instantânea
+// no piquete ocupado (UA/ha do piquete médio)
+func TaxaLotacao(ua float64, piquetes []models.Piquete) (media, instantanea float64) {
+	var area float64
+	for _, p := range piquetes {
+		area += p.AreaHectares
+	}
+	if area == 0 || len(piquetes) == 0 {
+		return 0, 0
+	}
+	media = ua / area
+	instantanea = ua / (area / float64(len(piquetes)))
+	return media, instantanea
+}
+
+// GerarEscalaPastejo distribui o lote pelos piquetes em sequência, ciclo
+// após ciclo, a partir da data de início do plano
+func GerarEscalaPastejo(plano models.PlanoPastejo, piquetes []models.Piquete) []models.OcupacaoPiquete {
+	ordenados := append([]models.Piquete(nil), piquetes...)
+	sort.Slice(ordenados, func(i, j int) bool { return ordenados[i].Numero < ordenados[j].Numero })
+
+	ciclos := plano.Ciclos
+	if ciclos < 1 {
+		ciclos = 1
+	}
+	ocupacao := plano.DiasOcupacao
+	if ocupacao < 1 {
+		ocupacao = DiasOcupacao(plano.DiasDescanso, len(ordenados))
+	}
+
+	var escala []models.OcupacaoPiquete
+	entrada := plano.DataInicio
+	for ciclo := 1; ciclo <= ciclos; ciclo++ {
+		for _, p := range ordenados {
+			saida := entrada.AddDate(0, 0, ocupacao)
+			escala = append(escala, models.OcupacaoPiquete{
+				PlanoID:         plano.ID,
+				PiqueteID:       p.ID,
+				PiqueteNumero:   p.Numero,
+				Lote:            plano.Lote,
+				Ciclo:           ciclo,
+				EntradaPrevista: entrada,
+				SaidaPrevista:   saida,
+			})
+			entrada = saida
+		}
+	}
+	return escala
+}
+
+// AlertaPastejo sinaliza um lote que permaneceu no piquete além dos dias de
+// ocupação planejados
+type AlertaPastejo struct {
+	Ocupacao     models.OcupacaoPiquete `json:"ocupacao"`
+	SaidaDevida  time.Time              `json:"saida_devida"`
+	DiasExcesso  int                    `json:"dias_excesso"`
+	AindaOcupado bool                   `json:"ainda_ocupado"`
+}
+
+// VerificarPermanencia compara a permanência real de cada lote com os dias
+// de ocupação planejados, contados da entrada real: atrasos na entrada não
+// viram excesso e entradas antecipadas não escondem a permanência longa.
+// Lotes ainda no piquete contam o excesso até a data de referência.
+func VerificarPermanencia(ocupacoes []models.OcupacaoPiquete, referencia time.Time) []AlertaPastejo {
+	dia := inicioDoDia(referencia)
+	var alertas []AlertaPastejo
+	for _, o := range ocupacoes {
+		if o.EntradaReal == nil {
+			continue
+		}
+		fim := dia
+		if o.SaidaReal != nil {
+			fim = *o.SaidaReal
+		}
+		devida := o.EntradaReal.Add(o.SaidaPrevista.Sub(o.EntradaPrevista))
+		excesso := int(fim.Sub(devida).Hours() / 24)
+		if excesso > 0 {
+			alertas = append(alertas, AlertaPastejo{
+				Ocupacao:     o,
+				SaidaDevida:  devida,
+				DiasExcesso:  excesso,
+				AindaOcupado: o.SaidaReal == nil,
+			})
+		}
+	}
+	return alertas
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Gerador mínimo de PDF (A4, Helvetica) para os documentos emitidos pelo
+// sistema. Os textos são convertidos para WinAnsiEncoding, que cobre os
+// acentos do português. As coordenadas são em pontos a partir do topo.
+const (
+	larguraA4 = 595.0
+	alturaA4  = 842.0
+)
+
+type documentoPDF struct {
+	paginas []*bytes.Buffer
+	atual   *bytes.Buffer
+}
+
+func novoDocumentoPDF() *documentoPDF {
+	d := &documentoPDF{}
+	d.novaPagina()
+	return d
+}
+
+func (d *documentoPDF) novaPagina() {
+	d.atual = &bytes.Buffer{}
+	d.paginas = append(d.paginas, d.atual)
+}
+
+func (d *documentoPDF) texto(x, y, tamanho float64, negrito bool, s string) {
+	fonte := "F1"
+	if negrito {
+		fonte = "F2"
+	}
+	fmt.Fprintf(d.atual, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", fonte, tamanho, x, alturaA4-y, escaparPDF(s))
+}
+
+// textoCentralizado escreve o texto centralizado na largura da página
+func (d *documentoPDF) textoCentralizado(y, tamanho float64, negrito bool, s string) {
+	d.texto((larguraA4-larguraTexto(s, tamanho, negrito))/2, y, tamanho, negrito, s)
+}
+
+func (d *documentoPDF) linha(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.atual, "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, alturaA4-y1, x2, alturaA4-y2)
+}
+
+func (d *documentoPDF) retangulo(x, y, largura, altura float64) {
+	fmt.Fprintf(d.atual, "0.5 w %.2f %.2f %.2f %.2f re S\n", x, alturaA4-y-altura, largura, altura)
+}
+
+// poligono traça o contorno fechado com a espessura e o tom de cinza do
+// traço (0 preto, 1 branco)
+func (d *documentoPDF) poligono(pontos [][2]float64, espessura, cinza float64) {
+	if len(pontos) < 2 {
+		return
+	}
+	fmt.Fprintf(d.atual, "q %.2f w %.2f G", espessura, cinza)
+	for i, p := range pontos {
+		operador := "l"
+		if i == 0 {
+			operador = "m"
+		}
+		fmt.Fprintf(d.atual, " %.2f %.2f %s", p[0], alturaA4-p[1], operador)
+	}
+	d.atual.WriteString(" s Q\n")
+}
+
+// circulo desenha um círculo preenchido, aproximado por quatro curvas de Bézier
+func (d *documentoPDF) circulo(x, y, raio float64) {
+	k := 0.5523 * raio
+	y = alturaA4 - y
+	fmt.Fprintf(d.atual, "%.2f %.2f m %.2f %.2f %.2f %.2f %.2f %.2f c %.2f %.2f %.2f %.2f %.2f %.2f c "+
+		"%.2f %.2f %.2f %.2f %.2f %.2f c %.2f %.2f %.2f %.2f %.2f %.2f c f\n",
+		x+raio, y,
+		x+raio, y+k, x+k, y+raio, x, y+raio,
+		x-k, y+raio, x-raio, y+k, x-raio, y,
+		x-raio, y-k, x-k, y-raio, x, y-raio,
+		x+k, y-raio, x+raio, y-k, x+raio, y)
+}
+
+// bytes monta o arquivo com catálogo, páginas, fontes e tabela xref
+func (d *documentoPDF) bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+	objeto := func(conteudo string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), conteudo)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	n := len(d.paginas)
+	kids := make([]string, n)
+	for i := range d.paginas {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objeto("<< /Type /Catalog /Pages 2 0 R >>")
+	objeto(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), n))
+	objeto("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	objeto("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, p := range d.paginas {
+		objeto(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", larguraA4, alturaA4, 6+2*i))
+		objeto(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.Len(), p.String()))
+	}
+
+	inicioXref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, inicioXref)
+	return out.Bytes()
+}
+
+// winAnsi traz os caracteres de 0x80–0x9F do cp1252 usados em textos
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92,
+	'“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+}
+
+// escaparPDF converte para WinAnsi e escapa os delimitadores de string do PDF
+func escaparPDF(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x80 && r >= 0x20:
+			b.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			b.WriteByte(byte(r))
+		default:
+			if c, ok := winAnsi[r]; ok {
+				b.WriteByte(c)
+			} else {
+				b.WriteByte('?')
+			}
+		}
+	}
+	return b.String()
+}
+
+// Larguras da Helvetica (1/1000 em) para os caracteres ASCII 32–126
+var larguraHelvetica = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// larguraTexto estima a largura em pontos; letras acentuadas usam a largura
+// média das minúsculas e o negrito é aproximado com 5% a mais
+func larguraTexto(s string, tamanho float64, negrito bool) float64 {
+	var total int
+	for _, r := range s {
+		if r >= 32 && r <= 126 {
+			total += larguraHelvetica[r-32]
+		} else {
+			total += 556
+		}
+	}
+	l := float64(total) * tamanho / 1000
+	if negrito {
+		l *= 1.05
+	}
+	return l
+}
+
+// quebrarTexto divide o texto em linhas que cabem na largura informada
+func quebrarTexto(s string, largura, tamanho float64, negrito bool) []string {
+	var linhas []string
+	for _, paragrafo := range strings.Split(s, "\n") {
+		var atual string
+		for _, palavra := range strings.Fields(paragrafo) {
+			candidata := palavra
+			if atual != "" {
+				candidata = atual + " " + palavra
+			}
+			if atual != "" && larguraTexto(candidata, tamanho, negrito) > largura {
+				linhas = append(linhas, atual)
+				atual = palavra
+				continue
+			}
+			atual = candidata
+		}
+		linhas = append(linhas, atual)
+	}
+	return linhas
+}
@@ -0,0 +1,595 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// Tipos de prescrição em taxa variável
+const (
+	PrescricaoCalcario = "calcario"
+	PrescricaoFosforo  = "fosforo"
+	PrescricaoPotassio = "potassio"
+)
+
+// ParametrosPrescricao são as escolhas do consultor para a recomendação.
+// Na calagem valem VDesejada, PRNT e Profundidade; na adubação, Doses
+// (kg/ha de P₂O₅ ou K₂O por classe de interpretação, da mais baixa à mais
+// alta) e Teor do nutriente no produto. Passo arredonda as doses, o que
+// define as zonas.
+type ParametrosPrescricao struct {
+	Tipo          string
+	Produto       string
+	VDesejada     float64 // %
+	PRNT          float64 // %
+	Profundidade  float64 // cm
+	Doses         [5]float64
+	Teor          float64 // % do nutriente no produto
+	Passo         float64 // t/ha na calagem, kg/ha na adubação
+	PrecoTonelada float64 // R$/t do produto
+}
+
+// ParametrosPrescricaoPadrao traz valores usuais para o Cerrado: V% de 60
+// com calcário de PRNT 80 incorporado a 20 cm, MAP (52% de P₂O₅) e KCl
+// (60% de K₂O) com doses de correção gradual nas classes mais baixas
+func ParametrosPrescricaoPadrao(tipo string) ParametrosPrescricao {
+	switch tipo {
+	case PrescricaoFosforo:
+		return ParametrosPrescricao{Tipo: tipo, Produto: "MAP", Doses: [5]float64{120, 100, 80, 60, 30}, Teor: 52, Passo: 10}
+	case PrescricaoPotassio:
+		return ParametrosPrescricao{Tipo: tipo, Produto: "KCl", Doses: [5]float64{100, 80, 60, 40, 20}, Teor: 60, Passo: 10}
+	}
+	return ParametrosPrescricao{Tipo: PrescricaoCalcario, Produto: "Calcário dolomítico", VDesejada: 60, PRNT: 80, Profundidade: 20, Passo: 0.5}
+}
+
+// Validar confere as faixas aceitas para o tipo de prescrição
+func (p ParametrosPrescricao) Validar() error {
+	if p.Passo <= 0 {
+		return errors.New("informe o arredondamento das doses")
+	}
+	if p.PrecoTonelada < 0 {
+		return errors.New("o preço não pode ser negativo")
+	}
+	switch p.Tipo {
+	case PrescricaoCalcario:
+		if p.VDesejada <= 0 || p.VDesejada > 90 {
+			return errors.New("a saturação por bases desejada deve ficar entre 1 e 90%")
+		}
+		if p.PRNT <= 0 || p.PRNT > 150 {
+			return errors.New("o PRNT deve ficar entre 1 e 150%")
+		}
+		if p.Profundidade <= 0 || p.Profundidade > 60 {
+			return errors.New("a profundidade de incorporação deve ficar entre 1 e 60 cm")
+		}
+	case PrescricaoFosforo, PrescricaoPotassio:
+		if p.Teor <= 0 || p.Teor > 100 {
+			return errors.New("o teor do nutriente no produto deve ficar entre 1 e 100%")
+		}
+		for _, d := range p.Doses {
+			if d < 0 {
+				return errors.New("as doses por classe não podem ser negativas")
+			}
+		}
+	default:
+		return errors.New("tipo de prescrição inválido")
+	}
+	return nil
+}
+
+// Unidade é a unidade de dose do produto: calcário em t/ha, adubos em kg/ha
+func (p ParametrosPrescricao) Unidade() string {
+	if p.Tipo == PrescricaoCalcario {
+		return "t"
+	}
+	return "kg"
+}
+
+// NecessidadeCalagem calcula a dose de calcário (t/ha) pelo método da
+// saturação por bases: NC = (V2 − V1) · T / PRNT, corrigida pela camada
+// incorporada (a fórmula vale para 0–20 cm)
+func NecessidadeCalagem(v1, ctc, v2, prnt, profundidade float64) float64 {
+	if v1 >= v2 || prnt <= 0 {
+		return 0
+	}
+	return (v2 - v1) * ctc / prnt * profundidade / 20
+}
+
+// ZonaPrescricao é o conjunto de células com a mesma dose
+type ZonaPrescricao struct {
+	Codigo int     `json:"codigo"`
+	Dose   float64 `json:"dose"` // unidade da prescrição por ha
+	AreaHa float64 `json:"area_ha"`
+	Total  float64 `json:"total"` // unidade da prescrição
+	Custo  float64 `json:"custo"` // R$
+	Cor    string  `json:"cor"`
+}
+
+// Prescricao é o mapa de aplicação em taxa variável: cada célula da malha
+// interpolada recebe o índice da zona (ou -1 fora do talhão)
+type Prescricao struct {
+	Parametros ParametrosPrescricao
+	Mapa       MapaInterpolado
+	Celulas    []int
+	Zonas      []ZonaPrescricao
+	Avisos     []string
+}
+
+// AreaHa soma a área das zonas
+func (p Prescricao) AreaHa() float64 {
+	total := 0.0
+	for _, z := range p.Zonas {
+		total += z.AreaHa
+	}
+	return total
+}
+
+// Total soma o produto de todas as zonas
+func (p Prescricao) Total() float64 {
+	total := 0.0
+	for _, z := range p.Zonas {
+		total += z.Total
+	}
+	return total
+}
+
+// Custo soma o custo do produto de todas as zonas
+func (p Prescricao) Custo() float64 {
+	total := 0.0
+	for _, z := range p.Zonas {
+		total += z.Custo
+	}
+	return total
+}
+
+// DoseMedia é o total dividido pela área do talhão
+func (p Prescricao) DoseMedia() float64 {
+	if area := p.AreaHa(); area > 0 {
+		return p.Total() / area
+	}
+	return 0
+}
+
+// kgPorUnidade converte a unidade da prescrição em kg
+func (p Prescricao) kgPorUnidade() float64 {
+	if p.Parametros.Unidade() == "t" {
+		return 1000
+	}
+	return 1
+}
+
+// coresDose vão do amarelo claro (menor dose) ao marrom (maior dose); a
+// zona sem aplicação fica cinza
+var coresDose = []string{"#ffffd4", "#fed98e", "#fe9929", "#d95f0e", "#993404"}
+
+const corSemAplicacao = "#ced4da"
+
+// montarPrescricao arredonda a dose de cada célula ao passo e agrupa as
+// células de mesma dose em zonas, numeradas da menor para a maior dose
+func montarPrescricao(p ParametrosPrescricao, mapa MapaInterpolado, doses []float64) Prescricao {
+	pr := Prescricao{Parametros: p, Mapa: mapa, Celulas: make([]int, len(doses)), Avisos: mapa.Avisos}
+	arredondadas := make(map[float64]bool)
+	for i, d := range doses {
+		if math.IsNaN(d) {
+			continue
+		}
+		doses[i] = math.Round(d/p.Passo) * p.Passo
+		arredondadas[doses[i]] = true
+	}
+	valores := make([]float64, 0, len(arredondadas))
+	for d := range arredondadas {
+		valores = append(valores, d)
+	}
+	sort.Float64s(valores)
+
+	indice := make(map[float64]int, len(valores))
+	comDose := 0
+	for i, d := range valores {
+		indice[d] = i
+		z := ZonaPrescricao{Codigo: i + 1, Dose: d, Cor: corSemAplicacao}
+		if d > 0 {
+			comDose++
+		}
+		pr.Zonas = append(pr.Zonas, z)
+	}
+	// As cores das doses positivas se espalham pela paleta
+	for i := range pr.Zonas {
+		if pr.Zonas[i].Dose <= 0 {
+			continue
+		}
+		posicao := i - (len(pr.Zonas) - comDose)
+		if comDose > 1 {
+			pr.Zonas[i].Cor = coresDose[posicao*(len(coresDose)-1)/(comDose-1)]
+		} else {
+			pr.Zonas[i].Cor = coresDose[len(coresDose)-1]
+		}
+	}
+
+	for i, d := range doses {
+		if math.IsNaN(d) {
+			pr.Celulas[i] = -1
+			continue
+		}
+		z := &pr.Zonas[indice[d]]
+		pr.Celulas[i] = indice[d]
+		z.AreaHa += mapa.AreaCelulaHa
+		z.Total += d * mapa.AreaCelulaHa
+	}
+	for i := range pr.Zonas {
+		pr.Zonas[i].Custo = pr.Zonas[i].Total * pr.kgPorUnidade() / 1000 * p.PrecoTonelada
+	}
+	if comDose == 0 {
+		pr.Avisos = append(pr.Avisos, "Nenhuma célula do talhão precisa do produto com estes parâmetros.")
+	}
+	return pr
+}
+
+// PrescreverCalagem calcula a dose de calcário em cada célula a partir dos
+// mapas de V% e de CTC, que devem ter sido interpolados na mesma malha
+func PrescreverCalagem(v, ctc MapaInterpolado, p ParametrosPrescricao) (Prescricao, error) {
+	if err := p.Validar(); err != nil {
+		return Prescricao{}, err
+	}
+	if len(v.Valores) != len(ctc.Valores) {
+		return Prescricao{}, errors.New("os mapas de V% e de CTC não têm a mesma malha")
+	}
+	doses := make([]float64, len(v.Valores))
+	for i, sat := range v.Valores {
+		if math.IsNaN(sat) || math.IsNaN(ctc.Valores[i]) {
+			doses[i] = math.NaN()
+			continue
+		}
+		doses[i] = NecessidadeCalagem(sat, ctc.Valores[i], p.VDesejada, p.PRNT, p.Profundidade)
+	}
+	pr := montarPrescricao(p, v, doses)
+	for _, aviso := range ctc.Avisos {
+		pr.Avisos = append(pr.Avisos, "CTC: "+aviso)
+	}
+	return pr, nil
+}
+
+// PrescreverAdubacao aplica a dose de nutriente da classe de interpretação
+// de cada célula e a converte em produto pelo teor
+func PrescreverAdubacao(m MapaInterpolado, parametro ParametroSolo, p ParametrosPrescricao) (Prescricao, error) {
+	if err := p.Validar(); err != nil {
+		return Prescricao{}, err
+	}
+	doses := make([]float64, len(m.Valores))
+	for i, v := range m.Valores {
+		if math.IsNaN(v) {
+			doses[i] = math.NaN()
+			continue
+		}
+		doses[i] = p.Doses[parametro.Classe(v)] / (p.Teor / 100)
+	}
+	return montarPrescricao(p, m, doses), nil
+}
+
+// PNG desenha as zonas com as cores da legenda
+func (p Prescricao) PNG() []byte {
+	cores := make([]color.NRGBA, len(p.Zonas))
+	for i, z := range p.Zonas {
+		cores[i] = corHex(z.Cor)
+	}
+	return desenharPNG(p.Mapa.Colunas, p.Mapa.Linhas, func(i int) (color.NRGBA, bool) {
+		if p.Celulas[i] < 0 {
+			return color.NRGBA{}, false
+		}
+		return cores[p.Celulas[i]], true
+	})
+}
+
+// retangulosZona cobre as células da zona com retângulos: as sequências de
+// células de cada linha são unidas às da linha de baixo quando têm as
+// mesmas colunas. Cada retângulo é {oeste, sul, leste, norte} em graus.
+func (p Prescricao) retangulosZona(zona int) [][4]float64 {
+	m := p.Mapa
+	passoLat := (m.Norte - m.Sul) / float64(m.Linhas)
+	passoLon := (m.Leste - m.Oeste) / float64(m.Colunas)
+	type faixa struct{ de, ate int }
+	var retangulos [][4]float64
+	abertos := map[faixa]int{} // faixa → índice do retângulo que a linha anterior estendeu
+	for l := 0; l < m.Linhas; l++ {
+		atuais := map[faixa]int{}
+		for c := 0; c < m.Colunas; {
+			if p.Celulas[l*m.Colunas+c] != zona {
+				c++
+				continue
+			}
+			inicio := c
+			for c < m.Colunas && p.Celulas[l*m.Colunas+c] == zona {
+				c++
+			}
+			f := faixa{inicio, c}
+			sul := m.Norte - float64(l+1)*passoLat
+			if i, ok := abertos[f]; ok {
+				retangulos[i][1] = sul
+				atuais[f] = i
+				continue
+			}
+			retangulos = append(retangulos, [4]float64{
+				m.Oeste + float64(inicio)*passoLon, sul, m.Oeste + float64(c)*passoLon, m.Norte - float64(l)*passoLat,
+			})
+			atuais[f] = len(retangulos) - 1
+		}
+		abertos = atuais
+	}
+	return retangulos
+}
+
+// nomeUnidade descreve a unidade de dose, como "t/ha"
+func (p Prescricao) nomeUnidade() string {
+	return p.Parametros.Unidade() + "/ha"
+}
+
+// campoDBF é um atributo da tabela do Shapefile
+type campoDBF struct {
+	nome    string
+	tipo    byte // 'C' texto, 'N' número
+	tamanho int
+	casas   int
+}
+
+// escreverDBF monta a tabela dBASE III com os registros em UTF-8 (o .cpg
+// do pacote informa a codificação)
+func escreverDBF(campos []campoDBF, registros [][]any) []byte {
+	var b bytes.Buffer
+	tamanhoRegistro := 1
+	for _, c := range campos {
+		tamanhoRegistro += c.tamanho
+	}
+	hoje := time.Now()
+	cabecalho := make([]byte, 32)
+	cabecalho[0] = 0x03
+	cabecalho[1], cabecalho[2], cabecalho[3] = byte(hoje.Year()-1900), byte(hoje.Month()), byte(hoje.Day())
+	binary.LittleEndian.PutUint32(cabecalho[4:], uint32(len(registros)))
+	binary.LittleEndian.PutUint16(cabecalho[8:], uint16(32+32*len(campos)+1))
+	binary.LittleEndian.PutUint16(cabecalho[10:], uint16(tamanhoRegistro))
+	b.Write(cabecalho)
+	for _, c := range campos {
+		descritor := make([]byte, 32)
+		copy(descritor, c.nome)
+		descritor[11] = c.tipo
+		descritor[16] = byte(c.tamanho)
+		descritor[17] = byte(c.casas)
+		b.Write(descritor)
+	}
+	b.WriteByte(0x0D)
+	for _, r := range registros {
+		b.WriteByte(' ')
+		for i, c := range campos {
+			var texto string
+			switch v := r[i].(type) {
+			case float64:
+				texto = fmt.Sprintf("%*.*f", c.tamanho, c.casas, v)
+			case int:
+				texto = fmt.Sprintf("%*d", c.tamanho, v)
+			case string:
+				texto = v
+				for len(texto) > c.tamanho {
+					_, n := utf8.DecodeLastRuneInString(texto)
+					texto = texto[:len(texto)-n]
+				}
+				texto += strings.Repeat(" ", c.tamanho-len(texto))
+			}
+			b.WriteString(texto[:c.tamanho])
+		}
+	}
+	b.WriteByte(0x1A)
+	return b.Bytes()
+}
+
+// prjWGS84 é o sistema de coordenadas geográficas do Shapefile exportado
+const prjWGS84 = `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`
+
+// cabecalhoSHP escreve o cabeçalho comum ao .shp e ao .shx
+func cabecalhoSHP(b *bytes.Buffer, tamanhoBytes int, caixa [4]float64) {
+	cabecalho := make([]byte, 100)
+	binary.BigEndian.PutUint32(cabecalho[0:], 9994)
+	binary.BigEndian.PutUint32(cabecalho[24:], uint32(tamanhoBytes/2))
+	binary.LittleEndian.PutUint32(cabecalho[28:], 1000)
+	binary.LittleEndian.PutUint32(cabecalho[32:], 5) // polígono
+	for i, v := range caixa {
+		binary.LittleEndian.PutUint64(cabecalho[36+8*i:], math.Float64bits(v))
+	}
+	b.Write(cabecalho)
+}
+
+// ExportarShapefilePrescricao gera o pacote .zip com o Shapefile das zonas
+// (um polígono de várias partes por zona, em WGS 84) e o resumo em CSV.
+// Os atributos trazem a dose na unidade do produto (DOSE) e em kg/ha
+// (DOSE_KG), que é o campo lido pela maioria dos controladores.
+func ExportarShapefilePrescricao(nome string, p Prescricao) ([]byte, error) {
+	var shp, shx bytes.Buffer
+	var registros [][]any
+	caixaTotal := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	var conteudos [][]byte
+	for i, z := range p.Zonas {
+		retangulos := p.retangulosZona(i)
+		if len(retangulos) == 0 {
+			continue
+		}
+		caixa := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+		for _, r := range retangulos {
+			caixa[0], caixa[1] = math.Min(caixa[0], r[0]), math.Min(caixa[1], r[1])
+			caixa[2], caixa[3] = math.Max(caixa[2], r[2]), math.Max(caixa[3], r[3])
+		}
+		for k := 0; k < 2; k++ {
+			caixaTotal[k] = math.Min(caixaTotal[k], caixa[k])
+			caixaTotal[k+2] = math.Max(caixaTotal[k+2], caixa[k+2])
+		}
+		// Cada retângulo é um anel externo em sentido horário
+		conteudo := make([]byte, 44+4*len(retangulos)+16*5*len(retangulos))
+		binary.LittleEndian.PutUint32(conteudo[0:], 5)
+		for k, v := range caixa {
+			binary.LittleEndian.PutUint64(conteudo[4+8*k:], math.Float64bits(v))
+		}
+		binary.LittleEndian.PutUint32(conteudo[36:], uint32(len(retangulos)))
+		binary.LittleEndian.PutUint32(conteudo[40:], uint32(5*len(retangulos)))
+		pos := 44 + 4*len(retangulos)
+		for k, r := range retangulos {
+			binary.LittleEndian.PutUint32(conteudo[44+4*k:], uint32(5*k))
+			for _, v := range [5][2]float64{{r[0], r[1]}, {r[0], r[3]}, {r[2], r[3]}, {r[2], r[1]}, {r[0], r[1]}} {
+				binary.LittleEndian.PutUint64(conteudo[pos:], math.Float64bits(v[0]))
+				binary.LittleEndian.PutUint64(conteudo[pos+8:], math.Float64bits(v[1]))
+				pos += 16
+			}
+		}
+		conteudos = append(conteudos, conteudo)
+		registros = append(registros, []any{z.Codigo, z.Dose, z.Dose * p.kgPorUnidade(), p.nomeUnidade(), z.AreaHa, z.Total, z.Custo, p.Parametros.Produto})
+	}
+	if len(conteudos) == 0 {
+		return nil, errors.New("a prescrição não tem zonas para exportar")
+	}
+
+	tamanhoSHP := 100
+	for _, c := range conteudos {
+		tamanhoSHP += 8 + len(c)
+	}
+	cabecalhoSHP(&shp, tamanhoSHP, caixaTotal)
+	cabecalhoSHP(&shx, 100+8*len(conteudos), caixaTotal)
+	deslocamento := 100
+	for i, c := range conteudos {
+		var indice [8]byte
+		binary.BigEndian.PutUint32(indice[0:], uint32(i+1))
+		binary.BigEndian.PutUint32(indice[4:], uint32(len(c)/2))
+		shp.Write(indice[:])
+		shp.Write(c)
+		binary.BigEndian.PutUint32(indice[0:], uint32(deslocamento/2))
+		shx.Write(indice[:])
+		deslocamento += 8 + len(c)
+	}
+
+	dbf := escreverDBF([]campoDBF{
+		{"ZONA", 'N', 4, 0},
+		{"DOSE", 'N', 12, 3},
+		{"DOSE_KG", 'N', 12, 1},
+		{"UNIDADE", 'C', 8, 0},
+		{"AREA_HA", 'N', 12, 2},
+		{"TOTAL", 'N', 14, 2},
+		{"CUSTO", 'N', 14, 2},
+		{"PRODUTO", 'C', 60, 0},
+	}, registros)
+
+	return compactar(map[string][]byte{
+		nome + ".shp":        shp.Bytes(),
+		nome + ".shx":        shx.Bytes(),
+		nome + ".dbf":        dbf,
+		nome + ".prj":        []byte(prjWGS84),
+		nome + ".cpg":        []byte("UTF-8"),
+		nome + "_resumo.csv": ResumoPrescricaoCSV(p),
+	})
+}
+
+// compactar monta o .zip com os arquivos em ordem alfabética
+func compactar(arquivos map[string][]byte) ([]byte, error) {
+	nomes := make([]string, 0, len(arquivos))
+	for n := range arquivos {
+		nomes = append(nomes, n)
+	}
+	sort.Strings(nomes)
+	var b bytes.Buffer
+	z := zip.NewWriter(&b)
+	for _, n := range nomes {
+		w, err := z.Create(n)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(arquivos[n]); err != nil {
+			return nil, err
+		}
+	}
+	if err := z.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// ResumoPrescricaoCSV lista dose, área, total de produto e custo por zona,
+// com separador ";" e vírgula decimal para abrir direto na planilha
+func ResumoPrescricaoCSV(p Prescricao) []byte {
+	var b bytes.Buffer
+	u := p.Parametros.Unidade()
+	b.WriteString("\ufeff")
+	fmt.Fprintf(&b, "Zona;Dose (%s/ha);Área (ha);Total (%s);Custo (R$)\n", u, u)
+	casas := 0
+	if u == "t" {
+		casas = 2
+	}
+	for _, z := range p.Zonas {
+		fmt.Fprintf(&b, "%d;%s;%s;%s;%s\n", z.Codigo, FormatarDecimal(z.Dose, casas), FormatarDecimal(z.AreaHa, 2),
+			FormatarDecimal(z.Total, casas), FormatarDecimal(z.Custo, 2))
+	}
+	fmt.Fprintf(&b, "Total;%s;%s;%s;%s\n", FormatarDecimal(p.DoseMedia(), casas), FormatarDecimal(p.AreaHa(), 2),
+		FormatarDecimal(p.Total(), casas), FormatarDecimal(p.Custo(), 2))
+	return b.Bytes()
+}
+
+// TarefaISOXML identifica cliente, fazenda e talhão na tarefa do ISOBUS
+type TarefaISOXML struct {
+	Cliente     string
+	Propriedade string
+	Talhao      string
+	Descricao   string
+}
+
+// ExportarISOXML gera o TASKDATA (ISO 11783-10, versão 3) com a tarefa
+// planejada do talhão: uma zona de tratamento (TZN) por dose, com a taxa
+// prevista em mg/m² (DDI 6), e a malha tipo 1 em GRD00001.BIN, um byte
+// por célula com o código da zona, do sul para o norte e de oeste para
+// leste. A zona 0 cobre as células fora do talhão com taxa zero.
+func ExportarISOXML(t TarefaISOXML, anel []models.Ponto, p Prescricao) ([]byte, error) {
+	m := p.Mapa
+	if len(p.Zonas) == 0 || len(p.Zonas) > 254 {
+		return nil, errors.New("a prescrição precisa ter de 1 a 254 zonas para o ISOBUS")
+	}
+	malha := make([]byte, 0, m.Colunas*m.Linhas)
+	for l := m.Linhas - 1; l >= 0; l-- {
+		for c := 0; c < m.Colunas; c++ {
+			malha = append(malha, byte(p.Celulas[l*m.Colunas+c]+1))
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<ISO11783_TaskData VersionMajor="3" VersionMinor="3" ManagementSoftwareManufacturer="AGR Consulta Pec" ManagementSoftwareVersion="1.0" DataTransferOrigin="1">` + "\n")
+	fmt.Fprintf(&b, "<CTR A=\"CTR1\" B=\"%s\"/>\n", escaparXML(t.Cliente))
+	fmt.Fprintf(&b, "<FRM A=\"FRM1\" B=\"%s\" I=\"CTR1\"/>\n", escaparXML(t.Propriedade))
+	fmt.Fprintf(&b, "<PFD A=\"PFD1\" C=\"%s\" D=\"%d\" E=\"CTR1\" F=\"FRM1\">\n", escaparXML(t.Talhao), int(math.Round(AreaGeodesica(anel)*10000)))
+	b.WriteString("<PLN A=\"1\"><LSG A=\"1\">")
+	for _, pt := range anel {
+		fmt.Fprintf(&b, "<PNT A=\"2\" C=\"%.9f\" D=\"%.9f\"/>", pt.Latitude, pt.Longitude)
+	}
+	if len(anel) > 0 && anel[0] != anel[len(anel)-1] {
+		fmt.Fprintf(&b, "<PNT A=\"2\" C=\"%.9f\" D=\"%.9f\"/>", anel[0].Latitude, anel[0].Longitude)
+	}
+	b.WriteString("</LSG></PLN>\n</PFD>\n")
+	fmt.Fprintf(&b, "<PDT A=\"PDT1\" B=\"%s\"/>\n", escaparXML(p.Parametros.Produto))
+	fmt.Fprintf(&b, "<TSK A=\"TSK1\" B=\"%s\" C=\"CTR1\" D=\"FRM1\" E=\"PFD1\" G=\"1\">\n", escaparXML(t.Descricao))
+	b.WriteString("<TZN A=\"0\" B=\"Fora do talhão\"><PDV A=\"0006\" B=\"0\" C=\"PDT1\"/></TZN>\n")
+	for _, z := range p.Zonas {
+		// 1 kg/ha = 100 mg/m²
+		taxa := int64(math.Round(z.Dose * p.kgPorUnidade() * 100))
+		fmt.Fprintf(&b, "<TZN A=\"%d\" B=\"Zona %d - %s %s\"><PDV A=\"0006\" B=\"%d\" C=\"PDT1\"/></TZN>\n",
+			z.Codigo, z.Codigo, FormatarDecimal(z.Dose, 2), p.nomeUnidade(), taxa)
+	}
+	fmt.Fprintf(&b, "<GRD A=\"%.9f\" B=\"%.9f\" C=\"%.9f\" D=\"%.9f\" E=\"%d\" F=\"%d\" G=\"GRD00001\" H=\"%d\" I=\"1\"/>\n",
+		m.Sul, m.Oeste, (m.Norte-m.Sul)/float64(m.Linhas), (m.Leste-m.Oeste)/float64(m.Colunas), m.Colunas, m.Linhas, len(malha))
+	b.WriteString("</TSK>\n</ISO11783_TaskData>\n")
+
+	return compactar(map[string][]byte{
+		"TASKDATA/TASKDATA.XML": b.Bytes(),
+		"TASKDATA/GRD00001.BIN": malha,
+		"resumo.csv":            ResumoPrescricaoCSV(p),
+	})
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"math"
+	"testing"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// prescricaoTeste monta uma prescrição de calcário em malha 3 × 3 de
+// 0,001° com uma zona sem aplicação e outra de 2,5 t/ha:
+//
+//	0 0 1
+//	0 0 1
+//	- 1 1
+func prescricaoTeste() Prescricao {
+	mapa := MapaInterpolado{Oeste: -47.930, Leste: -47.927, Sul: -15.783, Norte: -15.780, Colunas: 3, Linhas: 3, AreaCelulaHa: 1}
+	return Prescricao{
+		Parametros: ParametrosPrescricaoPadrao(PrescricaoCalcario),
+		Mapa:       mapa,
+		Celulas:    []int{0, 0, 1, 0, 0, 1, -1, 1, 1},
+		Zonas: []ZonaPrescricao{
+			{Codigo: 1, Dose: 0, AreaHa: 4},
+			{Codigo: 2, Dose: 2.5, AreaHa: 4, Total: 10},
+		},
+	}
+}
+
+func TestExportarShapefilePrescricao(t *testing.T) {
+	p := prescricaoTeste()
+	pacote, err := ExportarShapefilePrescricao("prescricao", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arquivos, err := descompactar(pacote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, nome := range []string{"prescricao.shp", "prescricao.shx", "prescricao.dbf", "prescricao.prj", "prescricao.cpg", "prescricao_resumo.csv"} {
+		if arquivos[nome] == nil {
+			t.Fatalf("%s ausente no pacote", nome)
+		}
+	}
+
+	// O cabeçalho declara o tamanho em palavras de 16 bits e o .shx aponta
+	// para o início de cada registro
+	shp, shx := arquivos["prescricao.shp"], arquivos["prescricao.shx"]
+	if int(binary.BigEndian.Uint32(shp[24:]))*2 != len(shp) || int(binary.BigEndian.Uint32(shx[24:]))*2 != len(shx) {
+		t.Errorf("tamanho declarado: shp %d de %d bytes, shx %d de %d", binary.BigEndian.Uint32(shp[24:])*2, len(shp), binary.BigEndian.Uint32(shx[24:])*2, len(shx))
+	}
+	for i := 0; 100+8*i < len(shx); i++ {
+		deslocamento := int(binary.BigEndian.Uint32(shx[100+8*i:])) * 2
+		if numero := binary.BigEndian.Uint32(shp[deslocamento:]); numero != uint32(i+1) {
+			t.Errorf("índice %d aponta para o registro %d", i+1, numero)
+		}
+	}
+
+	// Zona 1: um retângulo de 2 × 2 células; zona 2: a coluna leste das
+	// duas linhas de cima e a faixa de baixo
+	geometrias, err := lerSHP(shp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	esperados := [][][4]float64{
+		{{-47.930, -15.782, -47.928, -15.780}},
+		{{-47.928, -15.782, -47.927, -15.780}, {-47.929, -15.783, -47.927, -15.782}},
+	}
+	if len(geometrias) != len(esperados) {
+		t.Fatalf("registros = %d, esperado %d", len(geometrias), len(esperados))
+	}
+	for i, g := range geometrias {
+		if len(g.poligonos) != len(esperados[i]) {
+			t.Fatalf("zona %d: %d partes, esperado %d (%s)", i+1, len(g.poligonos), len(esperados[i]), g.erro)
+		}
+		for k, r := range esperados[i] {
+			anel := g.poligonos[k].externo
+			oeste, sul, leste, norte := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+			for _, c := range anel {
+				oeste, sul = math.Min(oeste, c[0]), math.Min(sul, c[1])
+				leste, norte = math.Max(leste, c[0]), math.Max(norte, c[1])
+			}
+			if len(anel) != 5 || anel[0] != anel[4] ||
+				math.Abs(oeste-r[0]) > 1e-9 || math.Abs(sul-r[1]) > 1e-9 || math.Abs(leste-r[2]) > 1e-9 || math.Abs(norte-r[3]) > 1e-9 {
+				t.Errorf("zona %d, parte %d: %v, esperado %v", i+1, k+1, anel, r)
+			}
+		}
+	}
+
+	atributos, err := lerDBF(arquivos["prescricao.dbf"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(atributos) != 2 {
+		t.Fatalf("registros do .dbf = %d", len(atributos))
+	}
+	z := atributos[1]
+	if z["ZONA"] != "2" || z["DOSE"] != "2.500" || z["DOSE_KG"] != "2500.0" || z["UNIDADE"] != "t/ha" ||
+		z["AREA_HA"] != "4.00" || z["TOTAL"] != "10.00" || z["PRODUTO"] != "Calcário dolomítico" {
+		t.Errorf("atributos da zona 2 = %v", z)
+	}
+
+	// O pacote volta pela importação de limites como duas feições válidas
+	imp, err := ImportarGeometria("prescricao.zip", pacote, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imp.Formato != FormatoShapefile || imp.Projecao != (Projecao{}).String() || imp.Validas() != 2 {
+		t.Errorf("importação: %s, %s, %d feições válidas", imp.Formato, imp.Projecao, imp.Validas())
+	}
+
+	if _, err := ExportarShapefilePrescricao("vazia", Prescricao{Mapa: p.Mapa, Celulas: make([]int, 9)}); err == nil {
+		t.Error("prescrição sem zonas exportada")
+	}
+}
+
+// taskDataTeste é o trecho do TASKDATA.XML conferido no teste
+type taskDataTeste struct {
+	XMLName xml.Name `xml:"ISO11783_TaskData"`
+	CTR     struct {
+		B string `xml:"B,attr"`
+	} `xml:"CTR"`
+	PFD struct {
+		D   int `xml:"D,attr"`
+		PNT []struct {
+			C float64 `xml:"C,attr"`
+			D float64 `xml:"D,attr"`
+		} `xml:"PLN>LSG>PNT"`
+	} `xml:"PFD"`
+	TSK struct {
+		TZN []struct {
+			A   int `xml:"A,attr"`
+			PDV struct {
+				A string `xml:"A,attr"`
+				B int64  `xml:"B,attr"`
+			} `xml:"PDV"`
+		} `xml:"TZN"`
+		GRD struct {
+			A, B, C, D float64 `xml:",attr"`
+			E, F, H    int     `xml:",attr"`
+			G          string  `xml:",attr"`
+			I          int     `xml:",attr"`
+		} `xml:"GRD"`
+	} `xml:"TSK"`
+}
+
+func TestExportarISOXML(t *testing.T) {
+	p := prescricaoTeste()
+	anel := []models.Ponto{
+		{Latitude: -15.780, Longitude: -47.930},
+		{Latitude: -15.780, Longitude: -47.927},
+		{Latitude: -15.783, Longitude: -47.927},
+	}
+	pacote, err := ExportarISOXML(TarefaISOXML{Cliente: "Silva & Filhos", Propriedade: "Fazenda <Boa Vista>", Talhao: "T1", Descricao: "Calagem"}, anel, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arquivos, err := descompactar(pacote)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tarefa taskDataTeste
+	if err := xml.Unmarshal(arquivos["TASKDATA/TASKDATA.XML"], &tarefa); err != nil {
+		t.Fatalf("TASKDATA.XML inválido: %v", err)
+	}
+	if tarefa.CTR.B != "Silva & Filhos" {
+		t.Errorf("cliente = %q", tarefa.CTR.B)
+	}
+	if want := int(math.Round(AreaGeodesica(anel) * 10000)); tarefa.PFD.D != want {
+		t.Errorf("área do talhão = %d m², esperado %d", tarefa.PFD.D, want)
+	}
+	if pnt := tarefa.PFD.PNT; len(pnt) != 4 || pnt[0] != pnt[3] {
+		t.Errorf("limite do talhão não fechado: %v", pnt)
+	}
+
+	// Zona 0 fora do talhão; 2,5 t/ha = 2.500 kg/ha = 250.000 mg/m²
+	taxas := map[int]int64{0: 0, 1: 0, 2: 250000}
+	if len(tarefa.TSK.TZN) != len(taxas) {
+		t.Fatalf("zonas de tratamento = %d, esperado %d", len(tarefa.TSK.TZN), len(taxas))
+	}
+	for _, z := range tarefa.TSK.TZN {
+		if taxa, ok := taxas[z.A]; !ok || z.PDV.A != "0006" || z.PDV.B != taxa {
+			t.Errorf("TZN %d: DDI %s taxa %d, esperado 0006 e %d", z.A, z.PDV.A, z.PDV.B, taxa)
+		}
+	}
+
+	g := tarefa.TSK.GRD
+	if math.Abs(g.A+15.783) > 1e-9 || math.Abs(g.B+47.930) > 1e-9 || math.Abs(g.C-0.001) > 1e-9 || math.Abs(g.D-0.001) > 1e-9 ||
+		g.E != 3 || g.F != 3 || g.G != "GRD00001" || g.H != 9 || g.I != 1 {
+		t.Errorf("GRD = %+v", g)
+	}
+	// Malha do sul para o norte, de oeste para leste, com o código da zona
+	if bin := arquivos["TASKDATA/GRD00001.BIN"]; !bytes.Equal(bin, []byte{0, 2, 2, 1, 1, 2, 1, 1, 2}) {
+		t.Errorf("GRD00001.BIN = %v", bin)
+	}
+
+	if _, err := ExportarISOXML(TarefaISOXML{}, anel, Prescricao{Mapa: p.Mapa}); err == nil {
+		t.Error("prescrição sem zonas exportada")
+	}
+}
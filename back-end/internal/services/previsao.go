@@ -0,0 +1,359 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// Estádios da cultura, registrados no monitoramento ou estimados pela fração
+// decorrida do ciclo
+const (
+	EstadioPrePlantio    = "Pré-plantio"
+	EstadioVegetativo    = "Vegetativo"
+	EstadioFlorescimento = "Florescimento"
+	EstadioEnchimento    = "Enchimento de grãos"
+	EstadioMaturacao     = "Maturação"
+	EstadioColhida       = "Colhida"
+)
+
+// Demanda hídrica de referência do ciclo completo, em mm
+var demandaHidricaCiclo = map[string]float64{
+	"Soja": 450, "Milho": 550, "Milho safrinha": 400, "Feijão": 300,
+	"Algodão": 650, "Trigo": 350, "Sorgo": 350, "Arroz": 600,
+	"Girassol": 400, "Aveia": 300,
+}
+
+const (
+	demandaHidricaPadrao = 450.0
+	// Safras colhidas consideradas na média histórica
+	safrasHistoricoPrevisao = 5
+	// Coeficiente de variação usado sem histórico suficiente (ao menos 3 safras)
+	cvPadraoPrevisao = 0.15
+	cvSemHistorico   = 0.20
+	// Quantil normal do intervalo de 80%
+	zIntervaloPrevisao = 1.2816
+)
+
+// PrevisaoColheita é a produtividade e o volume esperados de uma safra, com
+// faixa de confiança de 80%
+type PrevisaoColheita struct {
+	Safra             models.Safra `json:"safra"`
+	Estadio           string       `json:"estadio"`
+	EstadioRegistrado bool         `json:"estadio_registrado"` // visto no monitoramento, não estimado
+	Progresso         float64      `json:"progresso"`          // fração do ciclo decorrida (0 a 1)
+	SafrasHistorico   int          `json:"safras_historico"`
+	ProdutividadeBase float64      `json:"produtividade_base"` // média histórica ou meta
+	ChuvaAcumulada    float64      `json:"chuva_acumulada"`
+	ChuvaEsperada     float64      `json:"chuva_esperada"`
+	TemChuva          bool         `json:"tem_chuva"`
+	DiasSemRegistro   int          `json:"dias_sem_registro"` // fora da conta do déficit hídrico
+	FatorChuva        float64      `json:"fator_chuva"`
+	Produtividade     float64      `json:"produtividade"` // na unidade da safra
+	Minima            float64      `json:"minima"`
+	Maxima            float64      `json:"maxima"`
+	AreaColhida       float64      `json:"area_colhida"`
+	ProducaoColhida   float64      `json:"producao_colhida"`
+	Producao          float64      `json:"producao"` // sacas ou toneladas
+	ProducaoMinima    float64      `json:"producao_minima"`
+	ProducaoMaxima    float64      `json:"producao_maxima"`
+}
+
+// Disponivel indica se houve base (histórico ou meta) para a previsão
+func (p PrevisaoColheita) Disponivel() bool {
+	return p.Produtividade > 0
+}
+
+// EstadioEstimado indica que o estádio da cultura veio do calendário, sem
+// monitoramento que o confirme
+func (p PrevisaoColheita) EstadioEstimado() bool {
+	_, emCampo := faixasEstadio[p.Estadio]
+	return emCampo && !p.EstadioRegistrado
+}
+
+// PercentualCiclo é a fração do ciclo decorrida, em %
+func (p PrevisaoColheita) PercentualCiclo() float64 {
+	return p.Progresso * 100
+}
+
+// PercentualChuva é a chuva acumulada em relação à esperada até hoje
+func (p PrevisaoColheita) PercentualChuva() float64 {
+	if p.ChuvaEsperada == 0 {
+		return 0
+	}
+	return p.ChuvaAcumulada / p.ChuvaEsperada * 100
+}
+
+// kgUnidadeSafra é o peso de uma unidade de produção da safra (saca ou tonelada)
+func kgUnidadeSafra(s models.Safra) float64 {
+	if s.Unidade == models.UnidadeToneladaHa {
+		return 1000
+	}
+	return models.KgSaca
+}
+
+// Toneladas é o volume previsto em toneladas, para a logística
+func (p PrevisaoColheita) Toneladas() float64 {
+	return p.Producao * kgUnidadeSafra(p.Safra) / 1000
+}
+
+// ToneladasMinima é o limite inferior do volume em toneladas
+func (p PrevisaoColheita) ToneladasMinima() float64 {
+	return p.ProducaoMinima * kgUnidadeSafra(p.Safra) / 1000
+}
+
+// ToneladasMaxima é o limite superior do volume em toneladas
+func (p PrevisaoColheita) ToneladasMaxima() float64 {
+	return p.ProducaoMaxima * kgUnidadeSafra(p.Safra) / 1000
+}
+
+// inicioCiclo é o plantio realizado ou, sem ele, o previsto
+func inicioCiclo(s models.Safra) time.Time {
+	if s.PlantioReal != nil {
+		return *s.PlantioReal
+	}
+	return s.PlantioPrevisto
+}
+
+// ProgressoCiclo é a fração do ciclo (plantio à colheita prevista) decorrida
+func ProgressoCiclo(s models.Safra, hoje time.Time) float64 {
+	if s.ColheitaReal != nil {
+		return 1
+	}
+	inicio := inicioCiclo(s)
+	ciclo := s.ColheitaPrevista.Sub(inicio).Hours()
+	if ciclo <= 0 {
+		return 0
+	}
+	decorrido := inicioDoDia(hoje).Sub(inicio).Hours()
+	return math.Max(0, math.Min(1, decorrido/ciclo))
+}
+
+// EstadioSafra estima o estádio da cultura pela fração do ciclo decorrida
+func EstadioSafra(s models.Safra, hoje time.Time) string {
+	if s.ColheitaReal != nil {
+		return EstadioColhida
+	}
+	if inicioDoDia(hoje).Before(inicioCiclo(s)) {
+		return EstadioPrePlantio
+	}
+	switch p := ProgressoCiclo(s, hoje); {
+	case p < 0.4:
+		return EstadioVegetativo
+	case p < 0.6:
+		return EstadioFlorescimento
+	case p < 0.9:
+		return EstadioEnchimento
+	default:
+		return EstadioMaturacao
+	}
+}
+
+// faixasEstadio é a fração do ciclo em que cada estádio costuma ocorrer,
+// a mesma usada por EstadioSafra
+var faixasEstadio = map[string][2]float64{
+	EstadioVegetativo:    {0, 0.4},
+	EstadioFlorescimento: {0.4, 0.6},
+	EstadioEnchimento:    {0.6, 0.9},
+	EstadioMaturacao:     {0.9, 1},
+}
+
+// EstadioMonitorado é o estádio do monitoramento mais recente da safra até
+// hoje, quando algum foi registrado
+func EstadioMonitorado(s models.Safra, monitoramentos []models.Monitoramento, hoje time.Time) (string, bool) {
+	var ultimo models.Monitoramento
+	for _, m := range monitoramentos {
+		if m.SafraID != s.ID || m.Data.After(hoje) {
+			continue
+		}
+		if _, ok := faixasEstadio[m.Estadio]; ok && (ultimo.ID == 0 || m.Data.After(ultimo.Data)) {
+			ultimo = m
+		}
+	}
+	return ultimo.Estadio, ultimo.ID != 0
+}
+
+// fatorChuva reduz a produtividade pelo déficit hídrico acumulado, com peso
+// maior quanto mais avançado o ciclo (florescimento e enchimento de grãos),
+// e penaliza levemente o excesso de chuva
+func fatorChuva(razao, progresso float64) float64 {
+	switch {
+	case razao < 1:
+		sensibilidade := 0.3 + 0.5*progresso
+		return math.Max(0.4, 1-sensibilidade*(1-razao))
+	case razao > 1.6:
+		return math.Max(0.85, 1-0.1*(razao-1.6))
+	default:
+		return 1
+	}
+}
+
+// PreverColheita estima a produtividade da safra a partir da média das
+// últimas safras colhidas da mesma cultura no talhão (ou da meta, sem
+// histórico), ajustada pela chuva registrada desde o plantio em relação à
+// demanda hídrica esperada até o estádio atual. O estádio visto no último
+// monitoramento prevalece sobre o estimado pelo calendário e limita a fração
+// do ciclo à faixa do estádio. A faixa usa a variabilidade histórica e se
+// estreita com o avanço do ciclo. Com colheita parcial, a área já colhida
+// entra com a produção apurada; com a colheita encerrada, a previsão é o
+// realizado. Dias sem registro de chuva ficam fora da conta: a demanda
+// esperada é proporcional aos dias lidos, e os demais são informados em
+// DiasSemRegistro.
+func PreverColheita(safra models.Safra, produtividades []ProdutividadeSafra, colheitas []models.Colheita, chuvas []models.Chuva, monitoramentos []models.Monitoramento, hoje time.Time) PrevisaoColheita {
+	p := PrevisaoColheita{
+		Safra:      safra,
+		Estadio:    EstadioSafra(safra, hoje),
+		Progresso:  ProgressoCiclo(safra, hoje),
+		FatorChuva: 1,
+	}
+	if p.Estadio != EstadioColhida {
+		if estadio, ok := EstadioMonitorado(safra, monitoramentos, hoje); ok {
+			faixa := faixasEstadio[estadio]
+			p.Estadio, p.EstadioRegistrado = estadio, true
+			p.Progresso = math.Max(faixa[0], math.Min(faixa[1], p.Progresso))
+		}
+	}
+
+	// Colheita encerrada: data realizada ou cargas cobrindo toda a área
+	apurada := CalcularProdutividade(safra, colheitas)
+	if apurada.Cargas > 0 && (safra.ColheitaReal != nil || apurada.AreaColhida >= safra.AreaHectares) {
+		p.Estadio, p.Progresso = EstadioColhida, 1
+		p.Produtividade, p.Minima, p.Maxima = apurada.Produtividade, apurada.Produtividade, apurada.Produtividade
+		p.AreaColhida, p.ProducaoColhida = apurada.AreaColhida, apurada.Producao
+		p.Producao, p.ProducaoMinima, p.ProducaoMaxima = apurada.Producao, apurada.Producao, apurada.Producao
+		return p
+	}
+
+	// Histórico do talhão: safras anteriores colhidas da mesma cultura e unidade
+	var historico []ProdutividadeSafra
+	for _, h := range produtividades {
+		s := h.Safra
+		if h.Cargas == 0 || s.ID == safra.ID || s.TalhaoID != safra.TalhaoID || s.Cultura != safra.Cultura ||
+			s.Unidade != safra.Unidade || !s.PlantioPrevisto.Before(safra.PlantioPrevisto) {
+			continue
+		}
+		historico = append(historico, h)
+	}
+	sort.Slice(historico, func(i, j int) bool {
+		return historico[i].Safra.PlantioPrevisto.After(historico[j].Safra.PlantioPrevisto)
+	})
+	if len(historico) > safrasHistoricoPrevisao {
+		historico = historico[:safrasHistoricoPrevisao]
+	}
+	p.SafrasHistorico = len(historico)
+
+	cv := cvSemHistorico
+	if len(historico) > 0 {
+		var soma, somaQuadrados float64
+		for _, h := range historico {
+			soma += h.Produtividade
+			somaQuadrados += h.Produtividade * h.Produtividade
+		}
+		n := float64(len(historico))
+		p.ProdutividadeBase = soma / n
+		cv = cvPadraoPrevisao
+		if len(historico) >= 3 && p.ProdutividadeBase > 0 {
+			variancia := (somaQuadrados - soma*soma/n) / (n - 1)
+			cv = math.Max(0.05, math.Sqrt(math.Max(0, variancia))/p.ProdutividadeBase)
+		}
+	} else {
+		p.ProdutividadeBase = safra.ProdutividadeAlvo
+	}
+	if p.ProdutividadeBase <= 0 {
+		return p
+	}
+
+	// Chuva desde o plantio até hoje (ou a colheita prevista)
+	inicio := inicioCiclo(safra)
+	fim := inicioDoDia(hoje)
+	if safra.ColheitaPrevista.Before(fim) {
+		fim = safra.ColheitaPrevista
+	}
+	var lidas []models.Chuva
+	for _, c := range chuvas {
+		if c.PropriedadeID == safra.PropriedadeID && !c.Data.Before(inicio) && !c.Data.After(fim) {
+			p.ChuvaAcumulada += c.Milimetros
+			p.TemChuva = true
+			lidas = append(lidas, c)
+		}
+	}
+	demanda, ok := demandaHidricaCiclo[safra.Cultura]
+	if !ok {
+		demanda = demandaHidricaPadrao
+	}
+	p.ChuvaEsperada = demanda * p.Progresso
+	if dias := int(inicioDoDia(fim).Sub(inicioDoDia(inicio)).Hours()/24) + 1; p.TemChuva && dias > 0 {
+		p.DiasSemRegistro = len(DiasSemChuvaRegistrada(lidas, inicio, fim))
+		p.ChuvaEsperada *= float64(dias-p.DiasSemRegistro) / float64(dias)
+	}
+	if p.TemChuva && p.ChuvaEsperada > 0 {
+		p.FatorChuva = fatorChuva(p.ChuvaAcumulada/p.ChuvaEsperada, p.Progresso)
+	}
+
+	p.Produtividade = p.ProdutividadeBase * p.FatorChuva
+	margem := zIntervaloPrevisao * cv * (1 - 0.6*p.Progresso) * p.Produtividade
+	p.Minima = math.Max(0, p.Produtividade-margem)
+	p.Maxima = p.Produtividade + margem
+
+	// Colheita em andamento: a área colhida entra com a produção apurada
+	restante := safra.AreaHectares
+	if apurada.Cargas > 0 && apurada.AreaColhida < safra.AreaHectares {
+		p.AreaColhida, p.ProducaoColhida = apurada.AreaColhida, apurada.Producao
+		restante -= apurada.AreaColhida
+	}
+	p.Producao = p.ProducaoColhida + p.Produtividade*restante
+	p.ProducaoMinima = p.ProducaoColhida + p.Minima*restante
+	p.ProducaoMaxima = p.ProducaoColhida + p.Maxima*restante
+	return p
+}
+
+// ResumoPrevisao consolida o volume previsto de uma cultura por mês de
+// colheita, para o planejamento de transporte e armazenagem
+type ResumoPrevisao struct {
+	Mes       time.Time `json:"mes"`
+	Cultura   string    `json:"cultura"`
+	Safras    int       `json:"safras"`
+	Area      float64   `json:"area"`
+	Toneladas float64   `json:"toneladas"`
+	Minima    float64   `json:"minima"`
+	Maxima    float64   `json:"maxima"`
+}
+
+// ConsolidarPrevisoes soma as previsões por mês da colheita prevista e
+// cultura, em ordem cronológica
+func ConsolidarPrevisoes(previsoes []PrevisaoColheita) []ResumoPrevisao {
+	type chave struct {
+		mes     time.Time
+		cultura string
+	}
+	indice := make(map[chave]int)
+	var resumo []ResumoPrevisao
+	for _, p := range previsoes {
+		if !p.Disponivel() {
+			continue
+		}
+		c := p.Safra.ColheitaPrevista
+		k := chave{time.Date(c.Year(), c.Month(), 1, 0, 0, 0, 0, time.UTC), p.Safra.Cultura}
+		i, ok := indice[k]
+		if !ok {
+			i = len(resumo)
+			indice[k] = i
+			resumo = append(resumo, ResumoPrevisao{Mes: k.mes, Cultura: k.cultura})
+		}
+		r := &resumo[i]
+		r.Safras++
+		r.Area += p.Safra.AreaHectares
+		r.Toneladas += p.Toneladas()
+		r.Minima += p.ToneladasMinima()
+		r.Maxima += p.ToneladasMaxima()
+	}
+	sort.SliceStable(resumo, func(i, j int) bool {
+		if !resumo[i].Mes.Equal(resumo[j].Mes) {
+			return resumo[i].Mes.Before(resumo[j].Mes)
+		}
+		return resumo[i].Cultura < resumo[j].Cultura
+	})
+	return resumo
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+func TestPreverColheitaChuvaEEstadio(t *testing.T) {
+	dia := func(d int) time.Time { return time.Date(2026, 1, 1+d, 0, 0, 0, 0, time.UTC) }
+	safra := models.Safra{
+		ID: 1, PropriedadeID: 1, TalhaoID: 1, Cultura: "Soja", Unidade: "sc/ha",
+		AreaHectares: 10, ProdutividadeAlvo: 60,
+		PlantioPrevisto: dia(0), ColheitaPrevista: dia(120),
+	}
+	hoje := dia(60) // metade do ciclo: 61 dias com o do plantio
+	porDia := demandaHidricaCiclo["Soja"] * 0.5 / 61
+
+	chuvas := func(passo int, mm float64) []models.Chuva {
+		var lista []models.Chuva
+		for d := 0; d <= 60; d += passo {
+			lista = append(lista, models.Chuva{PropriedadeID: 1, Data: dia(d), Milimetros: mm})
+		}
+		return lista
+	}
+
+	casos := []struct {
+		nome           string
+		chuvas         []models.Chuva
+		monitoramentos []models.Monitoramento
+		estadio        string
+		registrado     bool
+		progresso      float64
+		semRegistro    int
+		fator          float64
+	}{
+		{"todos os dias lidos", chuvas(1, porDia), nil, EstadioFlorescimento, false, 0.5, 0, 1},
+		// Dias alternados com a chuva diária esperada: as falhas não viram seca
+		{"leituras em dias alternados", chuvas(2, porDia), nil, EstadioFlorescimento, false, 0.5, 30, 1},
+		// Metade da chuva nos dias lidos: déficit de 50% com sensibilidade 0,3+0,5·0,5
+		{"déficit nos dias lidos", chuvas(1, porDia/2), nil, EstadioFlorescimento, false, 0.5, 0, 1 - 0.55*0.5},
+		{"estádio do monitoramento", chuvas(1, porDia), []models.Monitoramento{
+			{ID: 1, SafraID: 1, Data: dia(50), Estadio: EstadioVegetativo},
+			{ID: 2, SafraID: 1, Data: dia(58), Estadio: EstadioEnchimento},
+			{ID: 3, SafraID: 1, Data: dia(70), Estadio: EstadioMaturacao}, // futuro
+			{ID: 4, SafraID: 2, Data: dia(59), Estadio: EstadioMaturacao}, // outra safra
+		}, EstadioEnchimento, true, 0.6, 0, 0},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			p := PreverColheita(safra, nil, nil, c.chuvas, c.monitoramentos, hoje)
+			if p.Estadio != c.estadio || p.EstadioRegistrado != c.registrado {
+				t.Errorf("estádio = %s (registrado %v), esperado %s (%v)", p.Estadio, p.EstadioRegistrado, c.estadio, c.registrado)
+			}
+			if math.Abs(p.Progresso-c.progresso) > 1e-9 {
+				t.Errorf("progresso = %.3f, esperado %.3f", p.Progresso, c.progresso)
+			}
+			if p.DiasSemRegistro != c.semRegistro {
+				t.Errorf("dias sem registro = %d, esperado %d", p.DiasSemRegistro, c.semRegistro)
+			}
+			if c.fator > 0 && math.Abs(p.FatorChuva-c.fator) > 1e-6 {
+				t.Errorf("fator de chuva = %.4f, esperado %.4f", p.FatorChuva, c.fator)
+			}
+		})
+	}
+}
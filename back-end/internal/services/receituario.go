@@ -0,0 +1,151 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// PrecaucoesReceituario são as orientações gerais impressas em todo receituário
+var PrecaucoesReceituario = []string{
+	"Leia atentamente o rótulo e a bula e siga as instruções do fabricante.",
+	"Utilize os equipamentos de proteção individual (EPI) indicados durante o preparo da calda e a aplicação.",
+	"Não aplique com ventos acima de 10 km/h, temperatura acima de 30 °C ou umidade relativa abaixo de 55%.",
+	"Respeite o intervalo de reentrada e o intervalo de segurança (carência) antes da colheita.",
+	"Faça a tríplice lavagem das embalagens vazias e devolva-as no local indicado na nota fiscal em até um ano da compra (Lei nº 7.802/1989).",
+}
+
+// GerarReceituarioPDF monta o receituário agronômico em PDF (A4) com os
+// dados do produtor, da propriedade, os produtos recomendados, as
+// precauções e o campo de assinatura do engenheiro agrônomo
+func GerarReceituarioPDF(r models.Receituario) []byte {
+	const (
+		margem  = 40.0
+		largura = larguraA4 - 2*margem
+		limite  = alturaA4 - 60
+	)
+	d := novoDocumentoPDF()
+	y := 0.0
+
+	cabecalho := func(continuacao bool) {
+		if continuacao {
+			d.texto(margem, 40, 9, true, "RECEITUÁRIO AGRONÔMICO Nº "+r.Identificacao()+" (continuação)")
+			d.linha(margem, 46, larguraA4-margem, 46)
+			y = 64
+			return
+		}
+		d.textoCentralizado(50, 16, true, "RECEITUÁRIO AGRONÔMICO")
+		d.textoCentralizado(68, 10, false, fmt.Sprintf("Nº %s • Emitido em %s",
+			r.Identificacao(), r.DataEmissao.Format("02/01/2006")))
+		d.linha(margem, 78, larguraA4-margem, 78)
+		y = 98
+	}
+	garantir := func(altura float64) {
+		if y+altura > limite {
+			d.novaPagina()
+			cabecalho(true)
+		}
+	}
+	secao := func(titulo string) {
+		garantir(40)
+		y += 6
+		d.texto(margem, y, 11, true, titulo)
+		d.linha(margem, y+4, larguraA4-margem, y+4)
+		y += 18
+	}
+	paragrafo := func(recuo, tamanho float64, negrito bool, s string) {
+		for _, l := range quebrarTexto(s, largura-recuo, tamanho, negrito) {
+			garantir(tamanho + 4)
+			d.texto(margem+recuo, y, tamanho, negrito, l)
+			y += tamanho + 4
+		}
+	}
+	campo := func(rotulo, valor string) {
+		if strings.TrimSpace(valor) == "" {
+			valor = "—"
+		}
+		paragrafo(0, 10, false, rotulo+": "+valor)
+	}
+
+	cabecalho(false)
+
+	secao("1. Produtor")
+	campo("Nome", r.ClienteNome)
+	campo("CPF/CNPJ", r.ClienteDocumento)
+	campo("Endereço", r.ClienteEndereco)
+
+	secao("2. Propriedade e cultura")
+	campo("Propriedade", r.PropriedadeNome)
+	campo("Município/UF", strings.Trim(r.Municipio+"/"+r.Estado, "/"))
+	campo("Cultura", r.Cultura)
+	campo("Área a tratar", FormatarDecimal(r.AreaHectares, 2)+" ha")
+
+	if strings.TrimSpace(r.Diagnostico) != "" {
+		secao("3. Diagnóstico")
+		paragrafo(0, 10, false, r.Diagnostico)
+	}
+
+	secao("4. Produtos recomendados")
+	for i, p := range r.Produtos {
+		garantir(90)
+		titulo := fmt.Sprintf("%d. %s", i+1, p.Produto)
+		if p.PrincipioAtivo != "" {
+			titulo += " (" + p.PrincipioAtivo + ")"
+		}
+		paragrafo(0, 10, true, titulo)
+		unidadeTotal := strings.TrimSuffix(p.UnidadeDose, "/ha")
+		linhas := []string{
+			"Alvo (praga, doença ou planta daninha): " + p.Praga,
+			fmt.Sprintf("Registro MAPA: %s • Classe toxicológica: %s", vazio(p.RegistroMAPA), vazio(p.ClasseToxicologica)),
+			fmt.Sprintf("Dose: %s %s • Volume de calda: %s L/ha • Quantidade total: %s %s",
+				FormatarDecimal(p.Dose, 2), p.UnidadeDose, FormatarDecimal(p.VolumeCalda, 0),
+				FormatarDecimal(p.Dose*r.AreaHectares, 2), unidadeTotal),
+			"Modo de aplicação: " + vazio(p.ModoAplicacao),
+			fmt.Sprintf("Número máximo de aplicações: %d", p.NumeroAplicacoes),
+			fmt.Sprintf("Intervalo de reentrada: %d horas • Intervalo de segurança (carência): %d dias",
+				p.IntervaloReentrada, p.CarenciaDias),
+		}
+		if p.Observacoes != "" {
+			linhas = append(linhas, "Observações: "+p.Observacoes)
+		}
+		for _, l := range linhas {
+			paragrafo(12, 9.5, false, l)
+		}
+		y += 6
+	}
+
+	secao("5. Precauções")
+	for _, p := range PrecaucoesReceituario {
+		paragrafo(0, 9, false, "• "+p)
+	}
+
+	// Assinatura
+	garantir(110)
+	y += 30
+	local := strings.Trim(r.Municipio+"/"+r.Estado, "/")
+	d.texto(margem, y, 10, false, fmt.Sprintf("%s, %s", vazio(local), r.DataEmissao.Format("02/01/2006")))
+	y += 50
+	centro := larguraA4 / 2
+	d.linha(centro-140, y, centro+140, y)
+	d.textoCentralizado(y+14, 10, true, r.AgronomoNome)
+	d.textoCentralizado(y+28, 9, false, "Engenheiro(a) Agrônomo(a) • CREA nº "+r.CREA)
+
+	// Rodapé com numeração das páginas
+	total := len(d.paginas)
+	for i, p := range d.paginas {
+		d.atual = p
+		d.linha(margem, alturaA4-40, larguraA4-margem, alturaA4-40)
+		d.texto(margem, alturaA4-28, 8, false, "Receituário agronômico nº "+r.Identificacao())
+		pagina := fmt.Sprintf("Página %d de %d", i+1, total)
+		d.texto(larguraA4-margem-larguraTexto(pagina, 8, false), alturaA4-28, 8, false, pagina)
+	}
+	return d.bytes()
+}
+
+func vazio(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "—"
+	}
+	return s
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// IndicadoresReprodutivos resume o desempenho de uma estação de monta
+type IndicadoresReprodutivos struct {
+	FemeasExpostas  int     `json:"femeas_expostas"`
+	FemeasPrenhes   int     `json:"femeas_prenhes"`
+	Inseminacoes    int     `json:"inseminacoes"`
+	Diagnosticadas  int     `json:"diagnosticadas"`
+	Concepcoes      int     `json:"concepcoes"`
+	TaxaPrenhez     float64 `json:"taxa_prenhez"`     // % das fêmeas expostas que ficaram prenhes
+	TaxaConcepcao   float64 `json:"taxa_concepcao"`   // % das coberturas diagnosticadas que resultaram em prenhez
+	IntervaloPartos float64 `json:"intervalo_partos"` // média em dias entre partos consecutivos
+	Intervalos      int     `json:"intervalos"`
+}
+
+// CalcularIndicadoresReprodutivos considera apenas as coberturas dentro da
+// janela da estação. Os diagnósticos são associados à cobertura que os
+// originou; o intervalo entre partos usa todo o histórico informado.
+func CalcularIndicadoresReprodutivos(estacao models.EstacaoMonta, inseminacoes []models.Inseminacao, diagnosticos []models.DiagnosticoGestacao, partos []models.Parto) IndicadoresReprodutivos {
+	var ind IndicadoresReprodutivos
+
+	naEstacao := make(map[int]models.Inseminacao)
+	expostas := make(map[int]bool)
+	for _, ins := range inseminacoes {
+		if ins.Data.Before(estacao.DataInicio) || ins.Data.After(estacao.DataFim) {
+			continue
+		}
+		naEstacao[ins.ID] = ins
+		expostas[ins.AnimalID] = true
+	}
+	ind.Inseminacoes = len(naEstacao)
+	ind.FemeasExpostas = len(expostas)
+
+	diagnosticadas := make(map[int]bool)
+	prenhes := make(map[int]bool)
+	concepcoes := make(map[int]bool)
+	for _, d := range diagnosticos {
+		ins, ok := naEstacao[d.InseminacaoID]
+		if !ok {
+			continue
+		}
+		diagnosticadas[ins.ID] = true
+		if d.Resultado == models.DiagnosticoPrenhe {
+			concepcoes[ins.ID] = true
+			prenhes[ins.AnimalID] = true
+		}
+	}
+	ind.Diagnosticadas = len(diagnosticadas)
+	ind.Concepcoes = len(concepcoes)
+	ind.FemeasPrenhes = len(prenhes)
+
+	ind.TaxaPrenhez = percentual(ind.FemeasPrenhes, ind.FemeasExpostas)
+	ind.TaxaConcepcao = percentual(ind.Concepcoes, ind.Diagnosticadas)
+	ind.IntervaloPartos, ind.Intervalos = IntervaloEntrePartos(partos)
+
+	return ind
+}
+
+// IntervaloEntrePartos retorna a média em dias entre partos consecutivos de
+// cada vaca e o número de intervalos usados no cálculo.
+func IntervaloEntrePartos(partos []models.Parto) (float64, int) {
+	porAnimal := make(map[int][]time.Time)
+	for _, p := range partos {
+		porAnimal[p.AnimalID] = append(porAnimal[p.AnimalID], p.Data)
+	}
+
+	var soma float64
+	var n int
+	for _, datas := range porAnimal {
+		sort.Slice(datas, func(i, j int) bool { return datas[i].Before(datas[j]) })
+		for i := 1; i < len(datas); i++ {
+			soma += datas[i].Sub(datas[i-1]).Hours() / 24
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	return soma / float64(n), n
+}
+
+// TarefaAgendada é uma etapa do protocolo já posicionada no calendário
+type TarefaAgendada struct {
+	Dia    int       `json:"dia"`
+	Data   time.Time `json:"data"`
+	Tarefa string    `json:"tarefa"`
+}
+
+// AgendaProtocolo distribui as etapas do protocolo a partir do D0 informado
+func AgendaProtocolo(protocolo models.ProtocoloIATF, d0 time.Time) []TarefaAgendada {
+	agenda := make([]TarefaAgendada, 0, len(protocolo.Etapas))
+	for _, e := range protocolo.Etapas {
+		agenda = append(agenda, TarefaAgendada{
+			Dia:    e.Dia,
+			Data:   d0.AddDate(0, 0, e.Dia),
+			Tarefa: e.Tarefa,
+		})
+	}
+	sort.SliceStable(agenda, func(i, j int) bool { return agenda[i].Dia < agenda[j].Dia })
+	return agenda
+}
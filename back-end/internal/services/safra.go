@@ -0,0 +1,90 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// RotuloSafra devolve o ano agrícola (julho a junho) da data de plantio,
+// no formato 2025/26. A segunda safra plantada em fevereiro pertence ao
+// mesmo ano agrícola da safra de verão.
+func RotuloSafra(plantio time.Time) string {
+	ano := plantio.Year()
+	if plantio.Month() < time.July {
+		ano--
+	}
+	return fmt.Sprintf("%d/%02d", ano, (ano+1)%100)
+}
+
+// SobreposicaoSafra retorna a safra do mesmo talhão cujo período previsto
+// (plantio a colheita) se sobrepõe ao da nova safra
+func SobreposicaoSafra(nova models.Safra, existentes []models.Safra) (models.Safra, bool) {
+	for _, s := range existentes {
+		if s.ID == nova.ID || s.TalhaoID != nova.TalhaoID {
+			continue
+		}
+		if nova.PlantioPrevisto.Before(s.ColheitaPrevista) && s.PlantioPrevisto.Before(nova.ColheitaPrevista) {
+			return s, true
+		}
+	}
+	return models.Safra{}, false
+}
+
+// ResumoSafra agrega o planejamento de uma cultura em um ano agrícola
+type ResumoSafra struct {
+	Rotulo           string  `json:"rotulo"`
+	Cultura          string  `json:"cultura"`
+	Unidade          string  `json:"unidade"`
+	Talhoes          int     `json:"talhoes"`
+	AreaHectares     float64 `json:"area_hectares"`
+	ProducaoPrevista float64 `json:"producao_prevista"`
+	Planejadas       int     `json:"planejadas"`
+	EmAndamento      int     `json:"em_andamento"`
+	Colhidas         int     `json:"colhidas"`
+}
+
+// ProdutividadeMedia é a meta média ponderada pela área
+func (r ResumoSafra) ProdutividadeMedia() float64 {
+	if r.AreaHectares == 0 {
+		return 0
+	}
+	return r.ProducaoPrevista / r.AreaHectares
+}
+
+// ResumirSafras agrupa as safras por ano agrícola e cultura, do ano mais
+// recente para o mais antigo
+func ResumirSafras(safras []models.Safra) []ResumoSafra {
+	indice := make(map[string]int)
+	var resumos []ResumoSafra
+	for _, s := range safras {
+		chave := s.Rotulo + "|" + s.Cultura + "|" + s.Unidade
+		i, ok := indice[chave]
+		if !ok {
+			i = len(resumos)
+			indice[chave] = i
+			resumos = append(resumos, ResumoSafra{Rotulo: s.Rotulo, Cultura: s.Cultura, Unidade: s.Unidade})
+		}
+		r := &resumos[i]
+		r.Talhoes++
+		r.AreaHectares += s.AreaHectares
+		r.ProducaoPrevista += s.ProducaoPrevista()
+		switch s.Situacao() {
+		case models.SafraColhida:
+			r.Colhidas++
+		case models.SafraEmAndamento:
+			r.EmAndamento++
+		default:
+			r.Planejadas++
+		}
+	}
+	sort.SliceStable(resumos, func(i, j int) bool {
+		if resumos[i].Rotulo != resumos[j].Rotulo {
+			return resumos[i].Rotulo > resumos[j].Rotulo
+		}
+		return resumos[i].Cultura < resumos[j].Cultura
+	})
+	return resumos
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"AGR_Consulta-Pec/back-end/internal/models"
+)
+
+// Carencia indica até quando um animal ou lote fica impedido de abate ou
+// venda de leite por causa de uma aplicação
+type Carencia struct {
+	Aplicacao     models.AplicacaoSanitaria `json:"aplicacao"`
+	LiberaAbate   time.Time                 `json:"libera_abate"`
+	LiberaLeite   time.Time                 `json:"libera_leite"`
+	BloqueiaAbate bool                      `json:"bloqueia_abate"`
+	BloqueiaLeite bool                      `json:"bloqueia_leite"`
+}
+
+// CalcularCarencia devolve as datas de liberação da aplicação em relação à data de referência
+func CalcularCarencia(a models.AplicacaoSanitaria, referencia time.Time) Carencia {
+	c := Carencia{
+		Aplicacao:   a,
+		LiberaAbate: a.Data.AddDate(0, 0, a.CarenciaAbate),
+		LiberaLeite: a.Data.AddDate(0, 0, a.CarenciaLeite),
+	}
+	c.BloqueiaAbate = a.CarenciaAbate > 0 && referencia.Before(c.LiberaAbate)
+	c.BloqueiaLeite = a.CarenciaLeite > 0 && referencia.Before(c.LiberaLeite)
+	return c
+}
+
+// CarenciasAtivas filtra as aplicações que ainda bloqueiam abate ou leite na
+// data de referência, ordenadas pela liberação mais distante
+func CarenciasAtivas(aplicacoes []models.AplicacaoSanitaria, referencia time.Time) []Carencia {
+	var ativas []Carencia
+	for _, a := range aplicacoes {
+		c := CalcularCarencia(a, referencia)
+		if c.BloqueiaAbate || c.BloqueiaLeite {
+			ativas = append(ativas, c)
+		}
+	}
+	sort.Slice(ativas, func(i, j int) bool { return ativas[i].LiberaAbate.After(ativas[j].LiberaAbate) })
+	return ativas
+}
+
+// LiberacaoAbate retorna a data a partir da qual o animal pode ser vendido
+// para abate, considerando aplicações individuais e as feitas no seu lote.
+// Retorna false quando não há carência vigente.
+func LiberacaoAbate(animal models.Animal, aplicacoes []models.AplicacaoSanitaria, referencia time.Time) (time.Time, bool) {
+	var libera time.Time
+	for _, a := range aplicacoes {
+		doAnimal := a.AnimalID != 0 && a.AnimalID == animal.ID
+		doLote := a.AnimalID == 0 && a.Lote != "" && a.Lote == animal.Lote && a.PropriedadeID == animal.PropriedadeID
+		if !doAnimal && !doLote {
+			continue
+		}
+		c := CalcularCarencia(a, referencia)
+		if c.BloqueiaAbate && c.LiberaAbate.After(libera) {
+			libera = c.LiberaAbate
+		}
+	}
+	return libera, !libera.IsZero()
+}
+
+// ProximaDose gera o evento de reforço da aplicação, quando o produto exige
+func ProximaDose(a models.AplicacaoSanitaria) (models.EventoSanitario, bool) {
+	if a.IntervaloDose <= 0 {
+		return models.EventoSanitario{}, false
+	}
+	return models.EventoSanitario{
+		PropriedadeID: a.PropriedadeID,
+		AnimalID:      a.AnimalID,
+		Lote:          a.Lote,
+		Tipo:          a.Tipo,
+		Alvo:          a.Alvo,
+		Produto:       a.Produto,
+		DataPrevista:  a.Data.AddDate(0, 0, a.IntervaloDose),
+		OrigemID:      a.ID,
+	}, true
+}
+
+// CumpreEvento indica se a aplicação realizada atende ao evento previsto:
+// mesmo alvo e tipo, mesmo animal ou lote
+func CumpreEvento(e models.EventoSanitario, a models.AplicacaoSanitaria) bool {
+	if e.RealizadoEm != nil || e.PropriedadeID != a.PropriedadeID {
+		return false
+	}
+	if e.Tipo != a.Tipo || e.Alvo != a.Alvo {
+		return false
+	}
+	return e.AnimalID == a.AnimalID && e.Lote == a.Lote
+}
+
+// EventosAtrasados retorna os eventos pendentes com data prevista anterior ao
+// dia de referência (doses previstas para hoje ainda não estão atrasadas)
+func EventosAtrasados(eventos []models.EventoSanitario, referencia time.Time) []models.EventoSanitario {
+	dia := inicioDoDia(referencia)
+	var atrasados []models.EventoSanitario
+	for _, e := range eventos {
+		if e.RealizadoEm == nil && e.DataPrevista.Before(dia) {
+			atrasados = append(atrasados, e)
+		}
+	}
+	return atrasados
+}